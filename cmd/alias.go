@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/aliases"
+)
+
+// newAliasCmd creates the alias subcommand for managing query aliases
+func (app *App) newAliasCmd() *cobra.Command {
+	aliasCmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Manage query aliases",
+		Long: `Aliases turn common query shapes into one-word commands.
+The {input} placeholder is replaced with the rest of the command line
+(or piped input); templates without it get the input appended.
+
+  perplexity alias add wtf "explain this error: {input}"
+  perplexity wtf "segmentation fault (core dumped)"
+  cat build.log | perplexity wtf`,
+	}
+
+	aliasCmd.AddCommand(&cobra.Command{
+		Use:          "add <name> <template>",
+		Short:        "Add or replace an alias",
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if sub, _, err := cmd.Root().Find([]string{name}); err == nil && sub != cmd.Root() {
+				return fmt.Errorf("alias %q conflicts with the %s command", name, sub.Name())
+			}
+			if err := app.aliases.Add(name, args[1]); err != nil {
+				return err
+			}
+			if err := app.aliases.Save(); err != nil {
+				return err
+			}
+			fmt.Printf("Alias %s added.\n", name)
+			return nil
+		},
+	})
+
+	aliasCmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List aliases",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			names := app.aliases.Names()
+			if len(names) == 0 {
+				fmt.Println("No aliases defined.")
+				return
+			}
+			for _, name := range names {
+				template, _ := app.aliases.Get(name)
+				fmt.Printf("  %-16s %s\n", name, template)
+			}
+		},
+	})
+
+	aliasCmd.AddCommand(&cobra.Command{
+		Use:          "remove <name>",
+		Aliases:      []string{"rm"},
+		Short:        "Remove an alias",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !app.aliases.Remove(args[0]) {
+				return fmt.Errorf("alias %q not found", args[0])
+			}
+			if err := app.aliases.Save(); err != nil {
+				return err
+			}
+			fmt.Printf("Alias %s removed.\n", args[0])
+			return nil
+		},
+	})
+
+	return aliasCmd
+}
+
+// lookupAlias returns the alias template if the first argument names an alias
+func (app *App) lookupAlias(args []string) (string, bool) {
+	if app.aliases == nil || len(args) == 0 {
+		return "", false
+	}
+	return app.aliases.Get(args[0])
+}
+
+// validateArgs accepts a single query argument, or an alias followed by any number of words
+func (app *App) validateArgs(cmd *cobra.Command, args []string) error {
+	if _, ok := app.lookupAlias(args); ok {
+		return nil
+	}
+	return invalidInput(cobra.MaximumNArgs(1)(cmd, args))
+}
+
+// expandAlias replaces an alias invocation with its expanded query.
+// input is the query read from the remaining arguments or stdin.
+func expandAlias(template string, words []string, input string) string {
+	if len(words) > 0 {
+		input = strings.Join(words, " ")
+	}
+	return aliases.Expand(template, input)
+}
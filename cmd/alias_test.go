@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/aliases"
+)
+
+func TestValidateArgs(t *testing.T) {
+	app := NewApp()
+	app.aliases = aliases.NewStore()
+	app.aliases.Add("wtf", "explain this error: {input}")
+
+	cmd := &cobra.Command{}
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"no args", nil, false},
+		{"single query", []string{"what is go"}, false},
+		{"multiple words without alias", []string{"what", "is", "go"}, true},
+		{"alias with words", []string{"wtf", "segmentation", "fault"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := app.validateArgs(cmd, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateArgs(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpandAlias(t *testing.T) {
+	template := "explain this error: {input}"
+
+	if got := expandAlias(template, []string{"segmentation", "fault"}, ""); got != "explain this error: segmentation fault" {
+		t.Errorf("expandAlias() with words = %q", got)
+	}
+	if got := expandAlias(template, nil, "piped error"); got != "explain this error: piped error" {
+		t.Errorf("expandAlias() with stdin = %q", got)
+	}
+}
+
+func TestLookupAliasWithoutStore(t *testing.T) {
+	app := NewApp()
+	if _, ok := app.lookupAlias([]string{"wtf"}); ok {
+		t.Error("lookupAlias() should not match without an alias store")
+	}
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/aliases"
+	"github.com/quocvuong92/perplexity-cli/internal/backup"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+	"github.com/quocvuong92/perplexity-cli/internal/prompts"
+)
+
+// backupItems returns the files covered by export-all and import-all: the
+// config file, aliases, saved system prompts, and the conversation history
+// and archive, which also hold the usage of each conversation
+func (app *App) backupItems() []backup.Item {
+	items := []backup.Item{
+		{Name: config.ConfigFileName, Path: config.GetConfigPath()},
+		{Name: aliases.AliasesFileName, Path: aliases.NewStore().Path()},
+		{Name: prompts.PromptsDirName, Path: prompts.NewLibrary().Dir(), Dir: true},
+		{Name: history.HistoryFileName, Path: history.NewHistory().Path()},
+		{Name: history.ArchiveFileName, Path: history.NewArchive().Path()},
+	}
+	available := items[:0]
+	for _, item := range items {
+		if item.Path != "" {
+			available = append(available, item)
+		}
+	}
+	return available
+}
+
+// newExportAllCmd creates the export-all command, writing a backup archive
+func (app *App) newExportAllCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "export-all <file.tar.gz>",
+		Short: "Back up the config, aliases, prompts and conversations into one archive",
+		Long: `Write the config file, aliases, saved system prompts, and the conversation
+history and archive into a gzipped tar archive, to back them up or move them
+to another machine with import-all.`,
+		Example:      `  perplexity export-all ~/perplexity-backup.tar.gz`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return err
+			}
+			written, err := backup.Create(f, app.backupItems())
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(args[0])
+				return fmt.Errorf("failed to write backup: %w", err)
+			}
+
+			if len(written) == 0 {
+				fmt.Printf("Nothing to back up yet; %s is empty.\n", args[0])
+				return nil
+			}
+			for _, item := range written {
+				fmt.Printf("  %s\n", item.Path)
+			}
+			fmt.Printf("Backed up %d files to %s.\n", len(written), args[0])
+			return nil
+		},
+	}
+}
+
+// newImportAllCmd creates the import-all command, restoring a backup archive
+func (app *App) newImportAllCmd() *cobra.Command {
+	var yes bool
+	cmd := &cobra.Command{
+		Use:   "import-all <file.tar.gz>",
+		Short: "Restore the config, aliases, prompts and conversations from an export-all archive",
+		Long: `Restore the files of an archive written by export-all. Conversations are
+merged into the existing history and archive, so none is lost. The config
+file, aliases and saved system prompts replace the existing ones, which are
+kept with a .bak suffix. The files are listed before asking for confirmation.`,
+		Example:      `  perplexity import-all ~/perplexity-backup.tar.gz`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return invalidInput(err)
+			}
+			files, err := backup.Read(f, app.backupItems())
+			f.Close()
+			if err != nil {
+				return invalidInput(err)
+			}
+			if len(files) == 0 {
+				return invalidInput(fmt.Errorf("%s holds no perplexity-cli data", args[0]))
+			}
+
+			for _, file := range files {
+				action := "new"
+				switch {
+				case file.Name == history.HistoryFileName || file.Name == history.ArchiveFileName:
+					action = "merged"
+				case fileExists(file.Path):
+					action = "replaces the existing file"
+				}
+				fmt.Printf("  %s (%s)\n", file.Path, action)
+			}
+			if !yes && !confirm(fmt.Sprintf("Import %d files?", len(files))) {
+				fmt.Println("Nothing imported.")
+				return nil
+			}
+
+			for _, file := range files {
+				if err := app.importFile(file); err != nil {
+					return err
+				}
+			}
+			fmt.Printf("Imported %d files from %s.\n", len(files), args[0])
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Import without asking for confirmation")
+	return cmd
+}
+
+// importFile restores a file from a backup archive, merging conversations
+// into the existing history
+func (app *App) importFile(file backup.File) error {
+	var hist *history.History
+	switch file.Name {
+	case history.HistoryFileName:
+		hist = app.newHistory()
+	case history.ArchiveFileName:
+		hist = history.NewArchive()
+	}
+	if hist != nil {
+		conversations, err := history.Parse(file.Data)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s from backup: %w", file.Name, err)
+		}
+		if err := hist.Load(); err != nil {
+			return err
+		}
+		hist.Merge(conversations)
+		return hist.Save()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file.Path), 0700); err != nil {
+		return err
+	}
+	if existing, err := os.ReadFile(file.Path); err == nil {
+		if err := os.WriteFile(file.Path+".bak", existing, 0600); err != nil {
+			return fmt.Errorf("failed to keep a copy of %s: %w", file.Path, err)
+		}
+	}
+	if err := os.WriteFile(file.Path, file.Data, 0600); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", file.Path, err)
+	}
+	return nil
+}
+
+// fileExists reports whether a file exists at path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
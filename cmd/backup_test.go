@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/aliases"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+	"github.com/quocvuong92/perplexity-cli/internal/prompts"
+)
+
+// setDataPaths points the config, aliases and history files into dir
+func setDataPaths(t *testing.T, dir string) {
+	t.Helper()
+	t.Setenv(config.EnvConfigPath, filepath.Join(dir, "config.yaml"))
+	t.Setenv(aliases.EnvAliasesPath, filepath.Join(dir, "aliases.json"))
+	t.Setenv(history.EnvHistoryPath, filepath.Join(dir, "history.json"))
+	t.Setenv(prompts.EnvPromptsDir, filepath.Join(dir, "prompts"))
+}
+
+func TestExportAndImportAll(t *testing.T) {
+	source := t.TempDir()
+	setDataPaths(t, source)
+	if err := os.WriteFile(filepath.Join(source, "config.yaml"), []byte("model: sonar-pro\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := prompts.NewLibrary().Save("pirate", "Answer as a pirate."); err != nil {
+		t.Fatal(err)
+	}
+	hist := history.NewHistory()
+	hist.AddConversation("backed-up", "sonar", []history.Message{{Role: "user", Content: "Hi"}})
+	if err := hist.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	cmd := NewApp().newExportAllCmd()
+	cmd.SetArgs([]string{archive})
+	var err error
+	output := captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("export-all error = %v", err)
+	}
+	if !strings.Contains(output, "Backed up 3 files") {
+		t.Errorf("output %q should report the config, prompt and history", output)
+	}
+
+	// Another machine, with a config and a conversation of its own
+	target := t.TempDir()
+	setDataPaths(t, target)
+	if err := os.WriteFile(filepath.Join(target, "config.yaml"), []byte("model: sonar\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	local := history.NewHistory()
+	local.AddConversation("local", "sonar", []history.Message{{Role: "user", Content: "Here"}})
+	if err := local.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	stubReadLine(t, "n")
+	cmd = NewApp().newImportAllCmd()
+	cmd.SetArgs([]string{archive})
+	output = captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if err != nil || !strings.Contains(output, "Nothing imported") {
+		t.Errorf("declined import-all = %q, %v", output, err)
+	}
+	if !strings.Contains(output, "config.yaml (replaces the existing file)") || !strings.Contains(output, "pirate.md (new)") || !strings.Contains(output, "history.json (merged)") {
+		t.Errorf("output %q should list the files to import", output)
+	}
+
+	cmd = NewApp().newImportAllCmd()
+	cmd.SetArgs([]string{archive, "--yes"})
+	output = captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("import-all error = %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(target, "config.yaml")); string(data) != "model: sonar-pro\n" {
+		t.Errorf("restored config = %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(target, "config.yaml.bak")); string(data) != "model: sonar\n" {
+		t.Errorf("config backup = %q, want the replaced config", data)
+	}
+	if prompt, err := prompts.NewLibrary().Load("pirate"); err != nil || prompt != "Answer as a pirate." {
+		t.Errorf("restored prompt = %q, %v", prompt, err)
+	}
+	merged := history.NewHistory()
+	if err := merged.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if ids := conversationIDs(merged.Conversations); !slices.Contains(ids, "local") || !slices.Contains(ids, "backed-up") {
+		t.Errorf("history = %q, want both conversations", ids)
+	}
+
+	cmd = NewApp().newImportAllCmd()
+	cmd.SetArgs([]string{filepath.Join(target, "config.yaml")})
+	captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if exitCode(err) != ExitInvalidInput {
+		t.Errorf("import-all of a non-archive error = %v, want invalid input", err)
+	}
+}
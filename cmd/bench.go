@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/bench"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+)
+
+// newBenchCmd creates the bench subcommand for measuring model performance
+func (app *App) newBenchCmd() *cobra.Command {
+	var (
+		modelList  string
+		promptFile string
+		runs       int
+		csvOutput  bool
+	)
+
+	benchCmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Benchmark models over a set of prompts",
+		Long: `Send every prompt in a file to each model and report the average latency,
+time to first byte, generation speed, token usage and estimated cost per model.
+
+The prompt file holds one prompt per line; blank lines and lines starting
+with # are skipped. Use - to read prompts from stdin. Models are benchmarked
+one after another so they do not compete for bandwidth or rate limits.
+
+  perplexity bench --models sonar,sonar-pro --prompt-file prompts.txt
+  perplexity bench --models sonar,sonar-pro --prompt-file prompts.txt --csv > bench.csv`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if runs < 1 {
+				return fmt.Errorf("--runs must be at least 1")
+			}
+
+			app.loadModels(false)
+			if err := app.validateConfig(); err != nil {
+				return err
+			}
+			models, err := app.parseModelList(modelList)
+			if err != nil {
+				return err
+			}
+			prompts, err := readPromptFile(promptFile)
+			if err != nil {
+				return err
+			}
+			if prompts, err = app.cleanPrompts(prompts); err != nil {
+				return err
+			}
+
+			ctx, cancel := newSignalContext()
+			defer cancel()
+
+			samples := app.runBench(ctx, models, prompts, runs)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			summaries := bench.Summarize(models, samples)
+			if csvOutput {
+				return bench.WriteCSV(cmd.OutOrStdout(), summaries)
+			}
+			bench.WriteTable(cmd.OutOrStdout(), summaries)
+			return nil
+		},
+	}
+
+	benchCmd.Flags().StringVar(&modelList, "models", "", "Comma-separated models to benchmark")
+	benchCmd.Flags().StringVar(&promptFile, "prompt-file", "", "File with one prompt per line (- for stdin)")
+	benchCmd.Flags().IntVar(&runs, "runs", 1, "Number of times each prompt is sent to each model")
+	benchCmd.Flags().BoolVar(&csvOutput, "csv", false, "Write the summary as CSV instead of a table")
+	_ = benchCmd.MarkFlagRequired("models")
+	_ = benchCmd.MarkFlagRequired("prompt-file")
+	_ = benchCmd.MarkFlagFilename("prompt-file")
+
+	return benchCmd
+}
+
+// readPromptFile reads the benchmark prompts from path, or from stdin if path is "-"
+func readPromptFile(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open prompt file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	return bench.ReadPrompts(r)
+}
+
+// cleanPrompts sanitizes and validates each benchmark prompt as a query would be
+func (app *App) cleanPrompts(prompts []string) ([]string, error) {
+	cleaned := make([]string, len(prompts))
+	for i, prompt := range prompts {
+		c, err := app.cleanPrompt(prompt)
+		if err != nil {
+			return nil, fmt.Errorf("prompt %d: %w", i+1, err)
+		}
+		cleaned[i] = c
+	}
+	return cleaned, nil
+}
+
+// runBench sends each prompt to each model runs times, one request at a time
+func (app *App) runBench(ctx context.Context, models, prompts []string, runs int) []bench.Sample {
+	total := len(models) * len(prompts) * runs
+	samples := make([]bench.Sample, 0, total)
+
+	sp := display.NewSpinner("Benchmarking...")
+	sp.Start()
+	defer sp.Stop()
+
+	for _, model := range models {
+		cfg := *app.cfg
+		cfg.Model = model
+		client := app.newClientFor(&cfg)
+
+		for run := 0; run < runs; run++ {
+			for _, prompt := range prompts {
+				if ctx.Err() != nil {
+					return samples
+				}
+				sp.UpdateMessage(fmt.Sprintf("Benchmarking %s (%d/%d)...", model, len(samples)+1, total))
+
+				messages := []api.Message{
+					{Role: "system", Content: cfg.GetSystemPrompt()},
+					{Role: "user", Content: prompt},
+				}
+				samples = append(samples, measureRequest(ctx, client, model, messages))
+			}
+		}
+	}
+
+	return samples
+}
+
+// measureRequest streams a single request, recording the time to the first
+// content chunk and to the end of the response
+func measureRequest(ctx context.Context, client *api.Client, model string, messages []api.Message) bench.Sample {
+	sample := bench.Sample{Model: model}
+	var final *api.ChatResponse
+
+	start := time.Now()
+	err := client.QueryStreamWithHistoryContext(ctx, slices.Clone(messages),
+		func(content string) {
+			if sample.TTFB == 0 {
+				sample.TTFB = time.Since(start)
+			}
+		},
+		func(resp *api.ChatResponse) {
+			final = resp
+		},
+	)
+	sample.Latency = time.Since(start)
+	sample.Err = err
+	if sample.TTFB == 0 {
+		sample.TTFB = sample.Latency
+	}
+
+	if final != nil {
+		sample.PromptTokens = final.Usage.PromptTokens
+		sample.CompletionTokens = final.Usage.CompletionTokens
+	}
+	return sample
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/bench"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+func TestReadPromptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.txt")
+	if err := os.WriteFile(path, []byte("first\n# skipped\nsecond\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	prompts, err := readPromptFile(path)
+	if err != nil {
+		t.Fatalf("readPromptFile() error = %v", err)
+	}
+	if !slices.Equal(prompts, []string{"first", "second"}) {
+		t.Errorf("readPromptFile() = %q", prompts)
+	}
+
+	if _, err := readPromptFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("readPromptFile() should fail for a missing file")
+	}
+}
+
+func TestCleanPrompts(t *testing.T) {
+	app := &App{cfg: &config.Config{}}
+
+	prompts, err := app.cleanPrompts([]string{"fir\x00st", "second"})
+	if err != nil {
+		t.Fatalf("cleanPrompts() error = %v", err)
+	}
+	if !slices.Equal(prompts, []string{"first", "second"}) {
+		t.Errorf("cleanPrompts() = %q, want control characters removed", prompts)
+	}
+
+	if _, err := app.cleanPrompts([]string{"first", "\x00"}); err == nil {
+		t.Error("cleanPrompts() should reject a prompt that is empty once cleaned")
+	}
+}
+
+func TestRunBench(t *testing.T) {
+	server := createMockStreamServer(t, []string{"Hello", " world"}, &api.ChatResponse{
+		Usage: api.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+	})
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", APIURL: server.URL, Model: "sonar-pro"}
+	app := &App{cfg: cfg}
+
+	var samples []bench.Sample
+	captureOutput(func() {
+		samples = app.runBench(context.Background(), []string{"sonar", "sonar-pro"}, []string{"a", "b"}, 2)
+	})
+
+	if len(samples) != 8 {
+		t.Fatalf("runBench() returned %d samples, want 8", len(samples))
+	}
+	for _, s := range samples {
+		if s.Err != nil {
+			t.Fatalf("sample error = %v", s.Err)
+		}
+		if s.TTFB <= 0 || s.Latency < s.TTFB {
+			t.Errorf("sample timings TTFB=%v Latency=%v are inconsistent", s.TTFB, s.Latency)
+		}
+		if s.PromptTokens != 10 || s.CompletionTokens != 2 {
+			t.Errorf("sample usage = %d/%d, want 10/2", s.PromptTokens, s.CompletionTokens)
+		}
+	}
+	if samples[0].Model != "sonar" || samples[7].Model != "sonar-pro" {
+		t.Errorf("models should be benchmarked in order, got %s..%s", samples[0].Model, samples[7].Model)
+	}
+}
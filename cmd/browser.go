@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+)
+
+// openURL opens a URL in the default browser. It is a variable so tests can replace it.
+var openURL = openBrowser
+
+// openBrowser launches the system's default browser for an http(s) URL
+func openBrowser(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("not a web URL: %s", rawURL)
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", rawURL)
+	case "windows":
+		// "start" would need cmd.exe, which mangles URLs containing &
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", rawURL)
+	default:
+		if _, err := exec.LookPath("xdg-open"); err != nil {
+			return fmt.Errorf("xdg-open not found. Install xdg-utils or open the URL manually: %s", rawURL)
+		}
+		cmd = exec.Command("xdg-open", rawURL)
+	}
+
+	// Don't wait for the browser; some openers only return once it exits
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
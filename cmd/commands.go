@@ -4,15 +4,22 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mattn/go-runewidth"
 
 	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/clipboard"
+	"github.com/quocvuong92/perplexity-cli/internal/codeblock"
 	"github.com/quocvuong92/perplexity-cli/internal/config"
 	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/export"
 	"github.com/quocvuong92/perplexity-cli/internal/history"
+	"github.com/quocvuong92/perplexity-cli/internal/prompts"
 )
 
 // handleCommand processes slash commands in interactive mode.
@@ -38,22 +45,46 @@ func (s *InteractiveSession) handleCommand(input string) bool {
 		return s.cmdHistory()
 	case "/search":
 		return s.cmdSearch(parts)
+	case "/share":
+		return s.cmdShare(parts)
+	case "/tag":
+		return s.cmdTag(parts)
+	case "/pin":
+		return s.cmdPin(parts, true)
+	case "/unpin":
+		return s.cmdPin(parts, false)
+	case "/archive":
+		return s.cmdArchive(parts)
 	case "/delete":
 		return s.cmdDelete(parts)
 	case "/system":
 		return s.cmdSystem(parts)
 	case "/copy":
-		return s.cmdCopy()
+		return s.cmdCopy(parts)
+	case "/paste":
+		return s.cmdPaste()
+	case "/open":
+		return s.cmdOpen(parts)
+	case "/code":
+		return s.cmdCode(parts)
+	case "/run":
+		return s.cmdRun(parts)
 	case "/resume":
 		return s.cmdResume(parts)
 	case "/model", "/m":
 		return s.cmdModel(parts)
+	case "/compare":
+		return s.cmdCompare(parts)
+	case "/translate":
+		return s.cmdTranslate(parts)
+	case "/reasoning":
+		return s.cmdReasoning(parts)
 	default:
-		fmt.Printf("Unknown command: %s\n", cmd)
-		fmt.Println("Type /help for available commands")
+		if c, ok := s.customCommand(cmd); ok {
+			return s.cmdCustom(c, parts)
+		}
+		return s.unknownCommand(cmd, parts)
 	}
-
-	return false
 }
 
 func (s *InteractiveSession) cmdExit() bool {
@@ -64,11 +95,13 @@ func (s *InteractiveSession) cmdExit() bool {
 
 func (s *InteractiveSession) cmdClear() bool {
 	s.setMessages([]api.Message{
-		{Role: "system", Content: config.DefaultSystemMessage},
+		{Role: "system", Content: s.app.cfg.GetSystemPrompt()},
 	})
 	s.conversationID = uuid.New().String()
+	s.usage = history.Usage{}
 	s.lastUserInput = ""
 	s.lastResponse = ""
+	s.lastCitations = nil
 	fmt.Println("Conversation cleared.")
 	return false
 }
@@ -96,22 +129,15 @@ func (s *InteractiveSession) cmdRetry() bool {
 	fmt.Println()
 
 	response, citations, err := s.sendInteractiveMessage()
-	if err != nil {
-		if err == context.Canceled {
-			s.removeLastMessage()
-			return false
-		}
+	if err != nil && err != context.Canceled {
 		msg, hint := display.FormatNetworkError(err)
 		display.ShowFriendlyError(msg, hint)
-		s.removeLastMessage()
-		return false
 	}
-
-	if response == "" {
-		response = config.FailedResponsePlaceholder
+	if s.appendResponse(response, err).Unanswered() {
+		s.lastCitations = nil
+		return false
 	}
-	s.lastResponse = response
-	s.appendMessage(api.Message{Role: "assistant", Content: response})
+	s.lastCitations = citations
 
 	if s.app.cfg.Citations && len(citations) > 0 {
 		fmt.Println()
@@ -121,43 +147,58 @@ func (s *InteractiveSession) cmdRetry() bool {
 	return false
 }
 
-func (s *InteractiveSession) cmdExport(parts []string) bool {
-	messages := s.getMessages()
-	if len(messages) <= 1 {
-		fmt.Println("No conversation to export.")
-		return false
+// exportFormatFlag selects the /export format, e.g. /export --format obsidian
+const exportFormatFlag = "--format"
+
+// parseExportArgs splits /export arguments into the format and filename
+func parseExportArgs(args string) (format, filename string, err error) {
+	fields := strings.Fields(args)
+	for i := 0; i < len(fields); i++ {
+		if fields[i] != exportFormatFlag {
+			filename = strings.Join(fields[i:], " ")
+			break
+		}
+		if i+1 == len(fields) {
+			return "", "", fmt.Errorf("%s needs one of: %s", exportFormatFlag, strings.Join(export.Formats, ", "))
+		}
+		i++
+		format = strings.ToLower(fields[i])
+	}
+	if format != "" && !slices.Contains(export.Formats, format) {
+		return "", "", fmt.Errorf("%w: got %s", export.ErrInvalidFormat, format)
 	}
+	return format, filename, nil
+}
 
-	filename := fmt.Sprintf("conversation-%s.md", time.Now().Format("2006-01-02-150405"))
+func (s *InteractiveSession) cmdExport(parts []string) bool {
+	var args string
 	if len(parts) > 1 {
-		filename = strings.TrimSpace(parts[1])
-		if !strings.HasSuffix(filename, ".md") {
-			filename += ".md"
-		}
+		args = parts[1]
+	}
+	format, filename, err := parseExportArgs(args)
+	if err != nil {
+		display.ShowError(err.Error())
+		return false
 	}
 
-	var content strings.Builder
-	content.WriteString("# Conversation Export\n\n")
-	content.WriteString(fmt.Sprintf("**Date:** %s\n", time.Now().Format("2006-01-02 15:04:05")))
-	content.WriteString(fmt.Sprintf("**Model:** %s\n\n", s.app.cfg.Model))
-	content.WriteString("---\n\n")
+	conv := s.currentConversation()
+	if len(conv.Messages) <= 1 {
+		fmt.Println("No conversation to export.")
+		return false
+	}
 
-	for _, msg := range messages {
-		if msg.Role == "system" {
-			continue
-		}
-		if msg.Role == "user" {
-			content.WriteString("## You\n\n")
-			content.WriteString(msg.Content)
-			content.WriteString("\n\n")
-		} else if msg.Role == "assistant" {
-			content.WriteString("## Assistant\n\n")
-			content.WriteString(msg.Content)
-			content.WriteString("\n\n")
-		}
+	ext := export.Extension(format)
+	if filename == "" {
+		filename = fmt.Sprintf("conversation-%s%s", time.Now().Format("2006-01-02-150405"), ext)
+	} else if !strings.HasSuffix(filename, ext) {
+		filename += ext
 	}
 
-	if err := os.WriteFile(filename, []byte(content.String()), 0600); err != nil {
+	content, err := export.Render(format, conv)
+	if err == nil {
+		err = os.WriteFile(filename, []byte(content), 0600)
+	}
+	if err != nil {
 		display.ShowError(fmt.Sprintf("Failed to export conversation: %v", err))
 	} else {
 		fmt.Printf("Conversation exported to %s\n", filename)
@@ -170,17 +211,46 @@ func (s *InteractiveSession) cmdHelp() bool {
 	fmt.Printf("  %-24s %s\n", "/exit, /quit, /q", "Exit interactive mode")
 	fmt.Printf("  %-24s %s\n", "/clear, /c", "Clear conversation history")
 	fmt.Printf("  %-24s %s\n", "/retry, /r", "Retry last message")
-	fmt.Printf("  %-24s %s\n", "/copy", "Copy last response to clipboard")
+	fmt.Printf("  %-24s %s\n", "/copy [md|plain|html]", "Copy last response to clipboard as markdown, plain text or HTML")
+	fmt.Printf("  %-24s %s\n", "/copy citations", "Copy the citation URLs of the last response")
+	fmt.Printf("  %-24s %s\n", "/copy sources-md", "Copy the citations as markdown links")
+	fmt.Printf("  %-24s %s\n", "/paste", "Send the clipboard as a message, or add it to a multiline message")
+	fmt.Printf("  %-24s %s\n", `""" or /multiline`, "Start a multiline message, ended by a line with the same fence")
+	fmt.Printf("  %-24s %s\n", "/open <n>", "Open citation n of last response in browser")
+	fmt.Printf("  %-24s %s\n", "/code [n]", "List code blocks of last response, or copy block n")
+	fmt.Printf("  %-24s %s\n", "/code save <n> <path>", "Save code block n to a file")
+	fmt.Printf("  %-24s %s\n", "/run <n>", "Run shell code block n after confirmation")
 	fmt.Printf("  %-24s %s\n", "/export [filename]", "Export conversation to markdown file")
+	fmt.Printf("  %-24s %s\n", "/export --format <f>", "Export for Obsidian (obsidian) or Notion (notion html, csv)")
+	fmt.Printf("  %-24s %s\n", "/share [n]", "Upload the conversation (or n from /history) as a gist or paste")
 	fmt.Printf("  %-24s %s\n", "/system [prompt|reset]", "Show/set system prompt")
+	fmt.Printf("  %-24s %s\n", "/system save|load <name>", "Save the system prompt to the library, or load one")
+	fmt.Printf("  %-24s %s\n", "/system list", "List the saved system prompts")
 	fmt.Printf("  %-24s %s\n", "/citations [on|off]", "Toggle or set citations display")
+	fmt.Printf("  %-24s %s\n", "/reasoning [level|off]", "Show/set reasoning effort (low, medium, high)")
 	fmt.Printf("  %-24s %s\n", "/history", "Show recent conversations")
 	fmt.Printf("  %-24s %s\n", "/search <keyword>", "Search conversations by keyword")
+	fmt.Printf("  %-24s %s\n", "/search --regex <re>", "Search by regular expression, with --role to scope to user or assistant")
+	fmt.Printf("  %-24s %s\n", "/search ... --tag <tag>", "Filter a search by --tag, --model or --since (e.g. 7d)")
 	fmt.Printf("  %-24s %s\n", "/resume [n]", "Resume conversation (n=index from /history)")
 	fmt.Printf("  %-24s %s\n", "/delete <n>", "Delete conversation (n=index from /history)")
+	fmt.Printf("  %-24s %s\n", "/delete <n>-<m>|all", "Delete a range or all conversations after confirmation")
+	fmt.Printf("  %-24s %s\n", "/delete --search <word>", "Delete conversations containing a keyword")
+	fmt.Printf("  %-24s %s\n", "/tag [tag|-tag]...", "Show, add or remove (-tag) tags of the conversation")
+	fmt.Printf("  %-24s %s\n", "/archive <n>|<n>-<m>", "Move conversations out of /history into the archive")
+	fmt.Printf("  %-24s %s\n", "/pin [n], /unpin <n|pN>", "Pin a conversation (default: current) at the top of /history")
 	fmt.Printf("  %-24s %s\n", "/model <name>, /m <name>", "Switch model")
 	fmt.Printf("  %-24s %s\n", "/model, /m", "Show current model")
+	fmt.Printf("  %-24s %s\n", "/compare <models> [text]", "Compare models on a prompt (default: last message)")
+	fmt.Printf("  %-24s %s\n", "/translate <lang> [text]", "Translate text (default: last response)")
 	fmt.Printf("  %-24s %s\n", "/help, /h", "Show this help")
+
+	if len(s.app.cfg.Commands) > 0 {
+		fmt.Println("\nCustom commands:")
+		for _, c := range s.app.cfg.Commands {
+			fmt.Printf("  %-24s %s\n", "/"+c.Name, customDescription(c))
+		}
+	}
 	fmt.Println()
 	return false
 }
@@ -221,17 +291,20 @@ func (s *InteractiveSession) cmdHistory() bool {
 		return false
 	}
 
+	if pinned := s.history.PinnedConversations(); len(pinned) > 0 {
+		fmt.Println("\nPinned conversations:")
+		for i, conv := range pinned {
+			fmt.Printf("  %s%d. [%s] %s\n", pinnedRefPrefix, i+1, conv.UpdatedAt.Format("2006-01-02 15:04"), conv.Title())
+		}
+	}
+
 	fmt.Println("\nRecent conversations:")
 	for i, conv := range conversations {
-		msgCount := len(conv.Messages) - 1
-		if msgCount < 0 {
-			msgCount = 0
-		}
-		fmt.Printf("  %d. [%s] %s (%d messages)\n",
+		fmt.Printf("  %d. [%s] %s (%s)\n",
 			i+1,
 			conv.UpdatedAt.Format("2006-01-02 15:04"),
 			conv.Model,
-			msgCount,
+			conversationStats(conv),
 		)
 	}
 	fmt.Println()
@@ -245,73 +318,192 @@ func (s *InteractiveSession) cmdSearch(parts []string) bool {
 	}
 
 	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
-		fmt.Println("Usage: /search <keyword>")
+		fmt.Println("Usage: /search <keyword> | --regex <pattern> [--role <role>] [--model <model>] [--since <7d|date>] [--tag <tag>]")
 		return false
 	}
 
-	keyword := strings.TrimSpace(parts[1])
-	results := s.history.SearchConversations(keyword)
+	q, err := parseSearchArgs(parts[1], time.Now())
+	if err != nil {
+		display.ShowError(err.Error())
+		return false
+	}
+	results, err := s.history.Search(q)
+	if err != nil {
+		display.ShowError(err.Error())
+		return false
+	}
 	if len(results) == 0 {
-		fmt.Printf("No conversations found containing '%s'.\n", keyword)
+		fmt.Printf("No conversations found %s.\n", describeQuery(q))
 		return false
 	}
 
-	fmt.Printf("\nConversations containing '%s':\n", keyword)
-	for i, conv := range results {
-		msgCount := len(conv.Messages) - 1
-		if msgCount < 0 {
-			msgCount = 0
+	fmt.Printf("\nConversations %s:\n", describeQuery(q))
+	printSearchResults(results, s.app.shouldUseColor())
+	return false
+}
+
+// cmdDelete deletes conversations by index, range, keyword or all of them.
+// Deleting more than one conversation asks for confirmation.
+func (s *InteractiveSession) cmdDelete(parts []string) bool {
+	if s.history == nil {
+		fmt.Println("History not available.")
+		return false
+	}
+
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		fmt.Println("Usage: /delete <n>|<from>-<to>|all|--search <keyword> (n=index from /history)")
+		return false
+	}
+
+	selector := strings.TrimSpace(parts[1])
+	selected, err := selectConversations(s.history, selector)
+	if err != nil {
+		display.ShowError(err.Error())
+		return false
+	}
+
+	single := len(selected) == 1 && !strings.ContainsAny(selector, " ,-") && selector != "all"
+	if single {
+		if s.history.DeleteConversations([]string{selected[0].ID}) == 1 {
+			if err := s.history.Save(); err != nil {
+				display.ShowError(fmt.Sprintf("Failed to save history: %v", err))
+			} else {
+				fmt.Printf("Conversation %s deleted.\n", selector)
+			}
 		}
-		fmt.Printf("  %d. [%s] %s (%d messages)\n",
-			i+1,
-			conv.UpdatedAt.Format("2006-01-02 15:04"),
-			conv.Model,
-			msgCount,
-		)
+		return false
+	}
+
+	if _, err := deleteConversations(s.history, selected, true); err != nil {
+		display.ShowError(err.Error())
 	}
-	fmt.Println()
 	return false
 }
 
-func (s *InteractiveSession) cmdDelete(parts []string) bool {
+// cmdPin pins or unpins the conversation with the given /history index, or
+// the current conversation, saving it first
+func (s *InteractiveSession) cmdPin(parts []string, pin bool) bool {
 	if s.history == nil {
 		fmt.Println("History not available.")
 		return false
 	}
 
-	if len(parts) < 2 {
-		fmt.Println("Usage: /delete <n> (n=index from /history)")
+	var conv *history.ConversationEntry
+	if len(parts) > 1 && strings.TrimSpace(parts[1]) != "" {
+		ref := strings.TrimSpace(parts[1])
+		if conv = findConversation(s.history, ref); conv == nil {
+			display.ShowError(fmt.Sprintf("Invalid conversation index: %s", ref))
+			return false
+		}
+	} else {
+		s.saveHistory()
+		if conv = s.history.GetConversation(s.conversationID); conv == nil {
+			fmt.Println("Send a message first; only saved conversations can be pinned.")
+			return false
+		}
+	}
+
+	conv.Pinned = pin
+	title := conv.Title()
+	if err := s.history.Save(); err != nil {
+		display.ShowError(fmt.Sprintf("Failed to save history: %v", err))
 		return false
 	}
+	if pin {
+		fmt.Printf("Pinned: %s\n", title)
+	} else {
+		fmt.Printf("Unpinned: %s\n", title)
+	}
+	return false
+}
 
-	indexStr := strings.TrimSpace(parts[1])
-	index := 0
-	if _, err := fmt.Sscanf(indexStr, "%d", &index); err != nil {
-		display.ShowError(fmt.Sprintf("Invalid index: %s", indexStr))
+// cmdTag shows the tags of the current conversation, or adds tags to it.
+// Tags prefixed with "-" are removed.
+func (s *InteractiveSession) cmdTag(parts []string) bool {
+	if s.history == nil {
+		fmt.Println("History not available.")
 		return false
 	}
 
-	if s.history.DeleteConversation(index) {
-		if err := s.history.Save(); err != nil {
-			display.ShowError(fmt.Sprintf("Failed to save history: %v", err))
+	conv := s.history.GetConversation(s.conversationID)
+	if conv == nil {
+		fmt.Println("Send a message first; only saved conversations can be tagged.")
+		return false
+	}
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		if len(conv.Tags) == 0 {
+			fmt.Println("No tags. Usage: /tag <tag>... (-<tag> removes a tag)")
 		} else {
-			fmt.Printf("Conversation %d deleted.\n", index)
+			fmt.Printf("Tags:%s\n", formatTags(conv.Tags))
 		}
+		return false
+	}
+
+	for _, tag := range strings.Fields(strings.ToLower(parts[1])) {
+		if name, ok := strings.CutPrefix(tag, "-"); ok {
+			conv.Tags = slices.DeleteFunc(conv.Tags, func(t string) bool { return t == strings.TrimPrefix(name, "#") })
+			continue
+		}
+		if tag = strings.TrimPrefix(tag, "#"); tag != "" && !conv.HasTag(tag) {
+			conv.Tags = append(conv.Tags, tag)
+		}
+	}
+	if err := s.history.Save(); err != nil {
+		display.ShowError(fmt.Sprintf("Failed to save history: %v", err))
+		return false
+	}
+	if len(conv.Tags) == 0 {
+		fmt.Println("No tags.")
 	} else {
-		display.ShowError(fmt.Sprintf("Invalid conversation index: %d", index))
+		fmt.Printf("Tags:%s\n", formatTags(conv.Tags))
 	}
 	return false
 }
 
+// cmdArchive moves conversations by index, range or keyword into the
+// archive, which /history and /search leave out
+func (s *InteractiveSession) cmdArchive(parts []string) bool {
+	if s.history == nil {
+		fmt.Println("History not available.")
+		return false
+	}
+
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		fmt.Println("Usage: /archive <n>|<from>-<to>|all|--search <keyword> (n=index from /history)")
+		return false
+	}
+
+	selected, err := selectConversations(s.history, parts[1])
+	if err != nil {
+		display.ShowError(err.Error())
+		return false
+	}
+	if len(selected) == 0 {
+		fmt.Println("No conversations to archive.")
+		return false
+	}
+
+	archived, err := archiveConversations(s.history, history.NewArchive(), selected)
+	if err != nil {
+		display.ShowError(err.Error())
+		return false
+	}
+	fmt.Printf("Archived %s. List them with: perplexity history list --archived\n", conversationCount(archived))
+	return false
+}
+
 func (s *InteractiveSession) cmdSystem(parts []string) bool {
 	if len(parts) > 1 {
 		newPrompt := strings.TrimSpace(parts[1])
+		action, name, _ := strings.Cut(newPrompt, " ")
 		if newPrompt == "" {
 			fmt.Println("Usage: /system <prompt> or /system to show current")
+		} else if action == "save" || action == "load" || newPrompt == "list" {
+			s.systemLibrary(action, strings.TrimSpace(name))
 		} else if newPrompt == "reset" {
 			s.messagesMu.Lock()
 			if len(s.messages) > 0 && s.messages[0].Role == "system" {
-				s.messages[0].Content = config.DefaultSystemMessage
+				s.messages[0].Content = s.app.cfg.GetSystemPrompt()
 			}
 			s.messagesMu.Unlock()
 			fmt.Println("System prompt reset to default.")
@@ -335,20 +527,299 @@ func (s *InteractiveSession) cmdSystem(parts []string) bool {
 	return false
 }
 
-func (s *InteractiveSession) cmdCopy() bool {
+// systemLibrary saves the current system prompt to the prompt library,
+// loads one from it, or lists the saved ones
+func (s *InteractiveSession) systemLibrary(action, name string) {
+	library := prompts.NewLibrary()
+	if action == "list" {
+		names, err := library.Names()
+		if err != nil {
+			display.ShowError(err.Error())
+			return
+		}
+		if len(names) == 0 {
+			fmt.Println("No saved system prompts. Save the current one with /system save <name>.")
+			return
+		}
+		fmt.Println("Saved system prompts:")
+		for _, name := range names {
+			fmt.Printf("  %s\n", name)
+		}
+		return
+	}
+	if name == "" {
+		fmt.Printf("Usage: /system %s <name>\n", action)
+		return
+	}
+
+	if action == "save" {
+		if err := library.Save(name, s.systemPrompt()); err != nil {
+			display.ShowError(err.Error())
+			return
+		}
+		fmt.Printf("System prompt saved as %s.\n", name)
+		return
+	}
+
+	prompt, err := library.Load(name)
+	if err != nil {
+		display.ShowError(err.Error())
+		return
+	}
+	s.messagesMu.Lock()
+	if len(s.messages) > 0 && s.messages[0].Role == "system" {
+		s.messages[0].Content = prompt
+	} else {
+		s.messages = append([]api.Message{{Role: "system", Content: prompt}}, s.messages...)
+	}
+	s.messagesMu.Unlock()
+	fmt.Printf("System prompt loaded from %s.\n", name)
+}
+
+// systemPrompt returns the system prompt of the conversation
+func (s *InteractiveSession) systemPrompt() string {
+	s.messagesMu.RLock()
+	defer s.messagesMu.RUnlock()
+	if len(s.messages) > 0 && s.messages[0].Role == "system" {
+		return s.messages[0].Content
+	}
+	return s.app.cfg.GetSystemPrompt()
+}
+
+func (s *InteractiveSession) cmdCopy(parts []string) bool {
 	if s.lastResponse == "" {
 		fmt.Println("No response to copy.")
 		return false
 	}
 
-	if err := copyToClipboard(s.lastResponse); err != nil {
+	arg := ""
+	if len(parts) > 1 {
+		arg = strings.ToLower(strings.TrimSpace(parts[1]))
+	}
+
+	switch arg {
+	case "", "md":
+		copyText(s.lastResponse, "Response")
+	case "plain":
+		copyText(display.MarkdownToPlain(s.lastResponse), "Response")
+	case "html":
+		html, err := display.MarkdownToHTML(display.LinkCitationMarkers(s.lastResponse, s.lastCitations))
+		if err != nil {
+			display.ShowError(fmt.Sprintf("Failed to convert the response to HTML: %v", err))
+			return false
+		}
+		backend, err := clipboard.CopyHTML(html)
+		reportCopy(backend, err, "Response as HTML")
+	case "citations", "sources-md":
+		if len(s.lastCitations) == 0 {
+			fmt.Println("No citations in the last response.")
+			return false
+		}
+		if arg == "citations" {
+			copyText(display.CitationList(s.lastCitations), "Citations")
+		} else {
+			copyText(display.CitationLinks(s.lastCitations), "Citation links")
+		}
+	default:
+		fmt.Println("Usage: /copy [md|plain|html|citations|sources-md]")
+	}
+	return false
+}
+
+// cmdPaste sends the clipboard's contents as a message
+func (s *InteractiveSession) cmdPaste() bool {
+	text, ok := readClipboard()
+	if !ok {
+		return false
+	}
+	fmt.Printf("Sending %s from the clipboard.\n", describeLines(text))
+	for input := text; input != ""; {
+		input = s.chat(input)
+	}
+	return false
+}
+
+// pasteIntoBuffer appends the clipboard's contents to the multiline message being written
+func (s *InteractiveSession) pasteIntoBuffer() {
+	text, ok := readClipboard()
+	if !ok {
+		return
+	}
+	s.inputBuffer = append(s.inputBuffer, strings.Split(text, "\n")...)
+	end := "a line without \\"
+	if s.fence != "" {
+		end = s.fence
+	}
+	fmt.Printf("Added %s from the clipboard. End the message with %s.\n", describeLines(text), end)
+}
+
+// readClipboard returns the clipboard's text, or false after telling the
+// user why there is none
+func readClipboard() (string, bool) {
+	text, err := clipboard.Paste()
+	if err != nil {
+		display.ShowError(fmt.Sprintf("Failed to read the clipboard: %v", err))
+		return "", false
+	}
+	if strings.TrimSpace(text) == "" {
+		fmt.Println("The clipboard is empty.")
+		return "", false
+	}
+	return text, true
+}
+
+// describeLines returns "1 line" or "n lines" for text
+func describeLines(text string) string {
+	if n := strings.Count(text, "\n") + 1; n > 1 {
+		return fmt.Sprintf("%d lines", n)
+	}
+	return "1 line"
+}
+
+// copyText copies text to the clipboard and tells the user how it went.
+// what names the copied text, e.g. "Response".
+func copyText(text, what string) {
+	backend, err := clipboard.Copy(text)
+	reportCopy(backend, err, what)
+}
+
+// reportCopy tells the user whether copying what to the clipboard worked
+func reportCopy(backend string, err error, what string) {
+	if err != nil {
 		display.ShowError(fmt.Sprintf("Failed to copy to clipboard: %v", err))
+		return
+	}
+	if backend == clipboard.OSC52 {
+		// The terminal gives no confirmation that it supports OSC52
+		fmt.Printf("%s sent to the terminal clipboard (OSC52).\n", what)
+		return
+	}
+	fmt.Printf("%s copied to clipboard.\n", what)
+}
+
+func (s *InteractiveSession) cmdOpen(parts []string) bool {
+	if len(s.lastCitations) == 0 {
+		fmt.Println("No citations in the last response.")
+		return false
+	}
+	if len(parts) < 2 {
+		fmt.Printf("Usage: /open <n> (1-%d)\n", len(s.lastCitations))
+		return false
+	}
+
+	var n int
+	if _, err := fmt.Sscanf(strings.TrimSpace(parts[1]), "%d", &n); err != nil || n < 1 || n > len(s.lastCitations) {
+		fmt.Printf("Invalid citation number. Use 1-%d.\n", len(s.lastCitations))
+		return false
+	}
+
+	citation := s.lastCitations[n-1]
+	if err := openURL(citation); err != nil {
+		display.ShowError(err.Error())
+		return false
+	}
+	fmt.Printf("Opened %s\n", citation)
+	return false
+}
+
+func (s *InteractiveSession) cmdCode(parts []string) bool {
+	blocks := codeblock.Extract(s.lastResponse)
+	if len(blocks) == 0 {
+		fmt.Println("No code blocks in the last response.")
+		return false
+	}
+
+	args := ""
+	if len(parts) > 1 {
+		args = strings.TrimSpace(parts[1])
+	}
+	if args == "save" || strings.HasPrefix(args, "save ") {
+		return s.saveCodeBlock(blocks, strings.TrimSpace(strings.TrimPrefix(args, "save")))
+	}
+
+	if args == "" {
+		fmt.Println("\nCode blocks:")
+		for i, block := range blocks {
+			fmt.Printf("  %d. %s\n", i+1, describeCodeBlock(block))
+		}
+		fmt.Println("Use /code <n> to copy a block, or /code save <n> <path> to save it.")
+		fmt.Println()
+		return false
+	}
+
+	n, ok := parseCodeBlockNumber(args, len(blocks))
+	if !ok {
+		return false
+	}
+
+	copyText(blocks[n-1].Code, fmt.Sprintf("Code block %d", n))
+	return false
+}
+
+// saveCodeBlock writes a code block to a file. args is "<n> <path>"; a path
+// without an extension gets one guessed from the block's language. An
+// existing file is only overwritten after confirmation.
+func (s *InteractiveSession) saveCodeBlock(blocks []codeblock.Block, args string) bool {
+	fields := strings.SplitN(args, " ", 2)
+	if len(fields) < 2 || strings.TrimSpace(fields[1]) == "" {
+		fmt.Println("Usage: /code save <n> <path>")
+		return false
+	}
+
+	n, ok := parseCodeBlockNumber(fields[0], len(blocks))
+	if !ok {
+		return false
+	}
+	block := blocks[n-1]
+
+	path := strings.TrimSpace(fields[1])
+	if filepath.Ext(path) == "" {
+		path += codeblock.Extension(block.Language)
+	}
+	if _, err := os.Stat(path); err == nil && !confirm(fmt.Sprintf("%s exists. Overwrite it?", path)) {
+		fmt.Println("Cancelled.")
+		return false
+	}
+
+	// Scripts with a shebang line are saved ready to run
+	var mode os.FileMode = 0600
+	if strings.HasPrefix(block.Code, "#!") {
+		mode = 0700
+	}
+
+	if err := os.WriteFile(path, []byte(block.Code+"\n"), mode); err != nil {
+		display.ShowError(fmt.Sprintf("Failed to save code block: %v", err))
 	} else {
-		fmt.Println("Response copied to clipboard.")
+		fmt.Printf("Code block %d saved to %s\n", n, path)
 	}
 	return false
 }
 
+// parseCodeBlockNumber parses a 1-based code block number, printing a message if it is invalid
+func parseCodeBlockNumber(arg string, count int) (int, bool) {
+	var n int
+	if _, err := fmt.Sscanf(arg, "%d", &n); err != nil || n < 1 || n > count {
+		fmt.Printf("Invalid code block number. Use 1-%d.\n", count)
+		return 0, false
+	}
+	return n, true
+}
+
+// describeCodeBlock summarises a code block as its language, size and first line
+func describeCodeBlock(block codeblock.Block) string {
+	language := block.Language
+	if language == "" {
+		language = "text"
+	}
+	lines := strings.Split(block.Code, "\n")
+	unit := "lines"
+	if len(lines) == 1 {
+		unit = "line"
+	}
+	first := runewidth.Truncate(strings.TrimSpace(lines[0]), 50, "…")
+	return fmt.Sprintf("%s (%d %s): %s", language, len(lines), unit, first)
+}
+
 func (s *InteractiveSession) cmdResume(parts []string) bool {
 	if s.history == nil {
 		fmt.Println("History not available.")
@@ -365,33 +836,24 @@ func (s *InteractiveSession) cmdResume(parts []string) bool {
 	var conv *history.ConversationEntry
 	if len(parts) > 1 {
 		indexStr := strings.TrimSpace(parts[1])
-		index := 0
-		if _, err := fmt.Sscanf(indexStr, "%d", &index); err != nil || index < 1 || index > len(conversations) {
-			fmt.Printf("Invalid conversation index: %s (use 1-%d)\n", indexStr, len(conversations))
-			return false
+		if conv = pinnedConversation(s.history, indexStr); conv == nil {
+			index := 0
+			if _, err := fmt.Sscanf(indexStr, "%d", &index); err != nil || index < 1 || index > len(conversations) {
+				fmt.Printf("Invalid conversation index: %s (use 1-%d)\n", indexStr, len(conversations))
+				return false
+			}
+			conv = &conversations[index-1]
 		}
-		conv = &conversations[index-1]
 	} else {
 		conv = &conversations[len(conversations)-1]
 	}
 
-	// Convert history.Message to api.Message, filtering out failed responses
-	newMessages := make([]api.Message, 0, len(conv.Messages))
-	for i, msg := range conv.Messages {
-		if msg.Role == "assistant" && msg.Content == config.FailedResponsePlaceholder {
-			if len(newMessages) > 0 && newMessages[len(newMessages)-1].Role == "user" {
-				newMessages = newMessages[:len(newMessages)-1]
-			}
-			continue
-		}
-		newMessages = append(newMessages, api.Message{
-			Role:    msg.Role,
-			Content: conv.Messages[i].Content,
-		})
-	}
-	s.setMessages(newMessages)
+	// Unanswered exchanges are kept, so they can be retried; they are left
+	// out of requests
+	s.setMessages(s.resumeSettings(conv, conversationMessages(conv)))
 
 	s.conversationID = conv.ID
+	s.usage = conv.Usage
 	msgCount := len(conv.Messages) - 1
 	if msgCount < 0 {
 		msgCount = 0
@@ -403,15 +865,20 @@ func (s *InteractiveSession) cmdResume(parts []string) bool {
 
 	// Display the conversation history
 	messages := s.getMessages()
+	now := time.Now()
 	for _, msg := range messages {
 		if msg.Role == "system" {
 			continue
 		}
 		if msg.Role == "user" {
-			fmt.Printf("You:\n%s\n\n", msg.Content)
+			fmt.Printf("%s\n%s\n\n", messageLabel("You", msg.Time, now), msg.Content)
+		}
+		if msg.Role == "assistant" && history.ResponseStatus(msg.Status).Unanswered() {
+			fmt.Printf("%s\n(no answer: %s)\n\n", messageLabel("Assistant", msg.Time, now), msg.Status)
+			continue
 		}
 		if msg.Role == "assistant" && msg.Content != "" {
-			fmt.Printf("Assistant:\n")
+			fmt.Println(messageLabel("Assistant", msg.Time, now))
 			if s.app.cfg.Render {
 				display.ShowContentRendered(msg.Content)
 			} else {
@@ -423,6 +890,110 @@ func (s *InteractiveSession) cmdResume(parts []string) bool {
 
 	fmt.Println("--- End of conversation history ---")
 	fmt.Println()
+	s.resumeRetry(messages)
+	return false
+}
+
+// resumeRetry offers to retry the last message of a resumed conversation if
+// its response was unanswered or truncated
+func (s *InteractiveSession) resumeRetry(messages []api.Message) {
+	n := len(messages)
+	if n < 2 || messages[n-1].Role != "assistant" || messages[n-2].Role != "user" {
+		return
+	}
+	status := history.ResponseStatus(messages[n-1].Status)
+	if !status.Unanswered() && status != history.StatusTruncated {
+		return
+	}
+	s.lastUserInput = messages[n-2].Content
+	fmt.Printf("The last response was %s; use /retry to ask again.\n\n", status)
+}
+
+// resumeSettings applies the model and system prompt of a resumed
+// conversation as set by resume_settings, restoring them unless the session's
+// are kept. Returns the messages to resume with.
+func (s *InteractiveSession) resumeSettings(conv *history.ConversationEntry, messages []api.Message) []api.Message {
+	current := s.app.cfg.GetSystemPrompt()
+	if existing := s.getMessages(); len(existing) > 0 && existing[0].Role == "system" {
+		current = existing[0].Content
+	}
+	if len(messages) == 0 || messages[0].Role != "system" {
+		// Stored without a system prompt: the session's applies
+		messages = append([]api.Message{{Role: "system", Content: current}}, messages...)
+	}
+
+	model := s.app.cfg.ResolveModel(conv.Model)
+	switchModel := conv.Model != "" && model != s.app.cfg.Model && s.app.cfg.IsValidModel(model)
+	restorePrompt := messages[0].Content != current
+	if !switchModel && !restorePrompt {
+		return messages
+	}
+
+	restore := true
+	switch s.app.cfg.ResumeSettings {
+	case config.ResumeSettingsKeep:
+		restore = false
+	case config.ResumeSettingsAsk:
+		var settings []string
+		if switchModel {
+			settings = append(settings, "model "+model)
+		}
+		if restorePrompt {
+			settings = append(settings, "system prompt")
+		}
+		restore = confirm(fmt.Sprintf("Restore the conversation's %s?", strings.Join(settings, " and ")))
+	}
+
+	if !restore {
+		if switchModel {
+			fmt.Printf("Continuing with model %s; this conversation used %s.\n", s.app.cfg.Model, model)
+		}
+		messages[0].Content = current
+		return messages
+	}
+	if switchModel {
+		fmt.Printf("Switched to model %s, used by this conversation (was %s).\n", model, s.app.cfg.Model)
+		s.app.cfg.Model = model
+	}
+	if restorePrompt {
+		fmt.Printf("Restored the conversation's system prompt: %s\n", messages[0].Content)
+	}
+	return messages
+}
+
+// messageLabel returns the heading of a replayed message, with how long ago
+// it was written when known
+func messageLabel(speaker string, t, now time.Time) string {
+	if t.IsZero() {
+		return speaker + ":"
+	}
+	return fmt.Sprintf("%s (%s):", speaker, display.RelativeTime(t, now))
+}
+
+func (s *InteractiveSession) cmdReasoning(parts []string) bool {
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		effort := s.app.cfg.ReasoningEffort
+		if effort == "" {
+			effort = "default"
+		}
+		fmt.Printf("Reasoning effort: %s\n", effort)
+		if !config.IsReasoningModel(s.app.cfg.Model) {
+			fmt.Printf("Note: %s is not a reasoning model, so the setting is not sent.\n", s.app.cfg.Model)
+		}
+		return false
+	}
+
+	arg := strings.ToLower(strings.TrimSpace(parts[1]))
+	switch {
+	case arg == "off" || arg == "default":
+		s.app.cfg.ReasoningEffort = ""
+		fmt.Println("Reasoning effort reset to the API default.")
+	case slices.Contains(config.ReasoningEfforts, arg):
+		s.app.cfg.ReasoningEffort = arg
+		fmt.Printf("Reasoning effort set to %s.\n", arg)
+	default:
+		fmt.Printf("Invalid argument: %s. Use low, medium, high or off.\n", arg)
+	}
 	return false
 }
 
@@ -431,17 +1002,17 @@ func (s *InteractiveSession) cmdModel(parts []string) bool {
 		newModel := strings.TrimSpace(parts[1])
 		if newModel == "" {
 			fmt.Printf("Current model: %s\n", s.app.cfg.Model)
-			fmt.Printf("Available: %s\n", config.GetAvailableModelsString())
-		} else if !config.ValidateModel(newModel) {
+			fmt.Printf("Available: %s\n", s.app.cfg.GetModelsString())
+		} else if resolved := s.app.cfg.ResolveModel(newModel); !s.app.cfg.IsValidModel(resolved) {
 			fmt.Printf("Invalid model: %s\n", newModel)
-			fmt.Printf("Available: %s\n", config.GetAvailableModelsString())
+			fmt.Printf("Available: %s\n", s.app.cfg.GetModelsString())
 		} else {
-			s.app.cfg.Model = newModel
+			s.app.cfg.Model = resolved
 			fmt.Printf("Switched to model: %s\n", s.app.cfg.Model)
 		}
 	} else {
 		fmt.Printf("Current model: %s\n", s.app.cfg.Model)
-		fmt.Printf("Available: %s\n", config.GetAvailableModelsString())
+		fmt.Printf("Available: %s\n", s.app.cfg.GetModelsString())
 	}
 	return false
 }
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"io"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/quocvuong92/perplexity-cli/internal/api"
 	"github.com/quocvuong92/perplexity-cli/internal/config"
 	"github.com/quocvuong92/perplexity-cli/internal/history"
+	"github.com/quocvuong92/perplexity-cli/internal/prompts"
 )
 
 func captureOutput(f func()) string {
@@ -51,7 +55,11 @@ func captureStdoutOnly(f func()) string {
 	return buf.String()
 }
 
-func newTestSessionWithHistory() *InteractiveSession {
+// newTestSessionWithHistory returns a session holding two conversations, in
+// a history file of its own
+func newTestSessionWithHistory(t *testing.T) *InteractiveSession {
+	t.Helper()
+	t.Setenv(history.EnvHistoryPath, filepath.Join(t.TempDir(), "history.json"))
 	cfg := &config.Config{
 		Model:     "sonar-pro",
 		Citations: true,
@@ -91,6 +99,8 @@ func TestCmdHelp(t *testing.T) {
 		"/clear",
 		"/retry",
 		"/copy",
+		"/open",
+		"/code",
 		"/export",
 		"/system",
 		"/citations",
@@ -98,6 +108,7 @@ func TestCmdHelp(t *testing.T) {
 		"/search",
 		"/resume",
 		"/delete",
+		"/archive",
 		"/model",
 		"/help",
 	}
@@ -137,7 +148,7 @@ func TestCmdClear(t *testing.T) {
 }
 
 func TestCmdHistory(t *testing.T) {
-	session := newTestSessionWithHistory()
+	session := newTestSessionWithHistory(t)
 
 	output := captureOutput(func() {
 		session.cmdHistory()
@@ -165,7 +176,7 @@ func TestCmdHistoryEmpty(t *testing.T) {
 }
 
 func TestCmdSearch(t *testing.T) {
-	session := newTestSessionWithHistory()
+	session := newTestSessionWithHistory(t)
 
 	output := captureOutput(func() {
 		session.cmdSearch([]string{"/search", "Go"})
@@ -174,10 +185,13 @@ func TestCmdSearch(t *testing.T) {
 	if !strings.Contains(output, "Conversations containing") {
 		t.Error("Search should show results header")
 	}
+	if !strings.Contains(output, "2 matches") || !strings.Contains(output, "user: What is Go?") {
+		t.Errorf("Search output %q should show the match count and a snippet", output)
+	}
 }
 
 func TestCmdSearchNoResults(t *testing.T) {
-	session := newTestSessionWithHistory()
+	session := newTestSessionWithHistory(t)
 
 	output := captureOutput(func() {
 		session.cmdSearch([]string{"/search", "nonexistent"})
@@ -189,7 +203,7 @@ func TestCmdSearchNoResults(t *testing.T) {
 }
 
 func TestCmdSearchNoKeyword(t *testing.T) {
-	session := newTestSessionWithHistory()
+	session := newTestSessionWithHistory(t)
 
 	output := captureOutput(func() {
 		session.cmdSearch([]string{"/search"})
@@ -296,6 +310,51 @@ func TestCmdModelInvalid(t *testing.T) {
 	}
 }
 
+func TestCmdModelAlias(t *testing.T) {
+	session := newTestSession()
+	session.app.cfg.ModelAliases = map[string]string{"deep": "sonar-deep-research"}
+
+	captureOutput(func() {
+		session.cmdModel([]string{"/model", "deep"})
+	})
+
+	if session.app.cfg.Model != "sonar-deep-research" {
+		t.Errorf("Model should be resolved to 'sonar-deep-research', got %q", session.app.cfg.Model)
+	}
+}
+
+func TestCmdReasoning(t *testing.T) {
+	session := newTestSession()
+	session.app.cfg.Model = "sonar-reasoning-pro"
+
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"high", "high"},
+		{"Medium", "medium"},
+		{"extreme", "medium"},
+		{"off", ""},
+	}
+
+	for _, tt := range tests {
+		captureOutput(func() {
+			session.cmdReasoning([]string{"/reasoning", tt.arg})
+		})
+		if session.app.cfg.ReasoningEffort != tt.want {
+			t.Errorf("after /reasoning %s, ReasoningEffort = %q, want %q", tt.arg, session.app.cfg.ReasoningEffort, tt.want)
+		}
+	}
+
+	session.app.cfg.Model = "sonar"
+	output := captureOutput(func() {
+		session.cmdReasoning([]string{"/reasoning"})
+	})
+	if !strings.Contains(output, "Reasoning effort: default") || !strings.Contains(output, "not a reasoning model") {
+		t.Errorf("unexpected /reasoning output %q", output)
+	}
+}
+
 func TestCmdSystem(t *testing.T) {
 	session := newTestSession()
 
@@ -324,6 +383,49 @@ func TestCmdSystemSet(t *testing.T) {
 	}
 }
 
+func TestCmdSystemLibrary(t *testing.T) {
+	t.Setenv(prompts.EnvPromptsDir, t.TempDir())
+	session := newTestSession()
+
+	output := captureOutput(func() {
+		session.cmdSystem([]string{"/system", "list"})
+	})
+	if !strings.Contains(output, "No saved system prompts") {
+		t.Errorf("output %q should say no prompts are saved", output)
+	}
+
+	session.messages[0].Content = "Answer as a pirate."
+	output = captureOutput(func() {
+		session.cmdSystem([]string{"/system", "save pirate"})
+		session.cmdSystem([]string{"/system", "reset"})
+		session.cmdSystem([]string{"/system", "list"})
+	})
+	if !strings.Contains(output, "saved as pirate") || !strings.Contains(output, "  pirate\n") {
+		t.Errorf("output %q should confirm the prompt was saved and list it", output)
+	}
+	if session.messages[0].Content != config.DefaultSystemMessage {
+		t.Fatalf("system prompt = %q, want it reset", session.messages[0].Content)
+	}
+
+	output = captureOutput(func() {
+		session.cmdSystem([]string{"/system", "load pirate"})
+	})
+	if session.messages[0].Content != "Answer as a pirate." || !strings.Contains(output, "loaded from pirate") {
+		t.Errorf("system prompt = %q, output %q, want the saved prompt loaded", session.messages[0].Content, output)
+	}
+
+	output = captureOutput(func() {
+		session.cmdSystem([]string{"/system", "load parrot"})
+		session.cmdSystem([]string{"/system", "save"})
+	})
+	if !strings.Contains(output, "no saved system prompt") || !strings.Contains(output, "Usage: /system save <name>") {
+		t.Errorf("output %q should report the missing prompt and the missing name", output)
+	}
+	if session.messages[0].Content != "Answer as a pirate." {
+		t.Errorf("system prompt = %q, want it unchanged by a failed load", session.messages[0].Content)
+	}
+}
+
 func TestCmdSystemReset(t *testing.T) {
 	session := newTestSession()
 	session.messages[0].Content = "Custom prompt"
@@ -345,7 +447,7 @@ func TestCmdCopy(t *testing.T) {
 	session.lastResponse = ""
 
 	output := captureOutput(func() {
-		session.cmdCopy()
+		session.cmdCopy([]string{"/copy"})
 	})
 
 	if !strings.Contains(output, "No response to copy") {
@@ -353,6 +455,228 @@ func TestCmdCopy(t *testing.T) {
 	}
 }
 
+func TestCmdPasteUnavailable(t *testing.T) {
+	// The local clipboard cannot be read over SSH, whatever tools are installed
+	t.Setenv("SSH_CONNECTION", "10.0.0.1 22 10.0.0.2 22")
+	session := newTestSession()
+
+	output := captureOutput(func() {
+		session.executor("/paste")
+	})
+	if !strings.Contains(output, "Failed to read the clipboard") {
+		t.Errorf("output %q should explain the clipboard error", output)
+	}
+	if session.getMessageCount() != 1 {
+		t.Error("/paste should not send a message when the clipboard cannot be read")
+	}
+
+	captureOutput(func() {
+		session.executor("line 1\\")
+		session.executor("/paste")
+	})
+	if len(session.inputBuffer) != 1 {
+		t.Errorf("inputBuffer = %q, want the multiline message kept as it was", session.inputBuffer)
+	}
+}
+
+func TestDescribeLines(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"one", "1 line"},
+		{"one\ntwo\nthree", "3 lines"},
+	}
+	for _, tt := range tests {
+		if got := describeLines(tt.text); got != tt.want {
+			t.Errorf("describeLines(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCmdCopyCitations(t *testing.T) {
+	tests := []struct {
+		name      string
+		citations []string
+		args      string
+		want      string
+	}{
+		{"no citations", nil, "citations", "No citations in the last response"},
+		{"no citations markdown", nil, "sources-md", "No citations in the last response"},
+		{"unknown format", []string{"https://a.example"}, "pdf", "Usage: /copy [md|plain|html|citations|sources-md]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newTestSession()
+			session.lastResponse = "Answer[1]"
+			session.lastCitations = tt.citations
+
+			output := captureOutput(func() {
+				session.cmdCopy([]string{"/copy", tt.args})
+			})
+			if !strings.Contains(output, tt.want) {
+				t.Errorf("output %q should contain %q", output, tt.want)
+			}
+		})
+	}
+}
+
+func TestCmdOpen(t *testing.T) {
+	var opened []string
+	oldOpenURL := openURL
+	openURL = func(url string) error {
+		opened = append(opened, url)
+		return nil
+	}
+	defer func() { openURL = oldOpenURL }()
+
+	session := newTestSession()
+	session.lastCitations = []string{"https://example.com/a", "https://example.com/b"}
+
+	tests := []struct {
+		name   string
+		parts  []string
+		want   string
+		opened string
+	}{
+		{"no index", []string{"/open"}, "Usage: /open <n> (1-2)", ""},
+		{"not a number", []string{"/open", "x"}, "Invalid citation number", ""},
+		{"out of range", []string{"/open", "3"}, "Invalid citation number", ""},
+		{"valid", []string{"/open", "2"}, "Opened https://example.com/b", "https://example.com/b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened = nil
+			output := captureOutput(func() {
+				session.cmdOpen(tt.parts)
+			})
+			if !strings.Contains(output, tt.want) {
+				t.Errorf("output = %q, want %q", output, tt.want)
+			}
+			if tt.opened == "" && len(opened) > 0 {
+				t.Errorf("opened %v, want nothing", opened)
+			}
+			if tt.opened != "" && (len(opened) != 1 || opened[0] != tt.opened) {
+				t.Errorf("opened %v, want %s", opened, tt.opened)
+			}
+		})
+	}
+}
+
+func TestCmdOpenNoCitations(t *testing.T) {
+	session := newTestSession()
+
+	output := captureOutput(func() {
+		session.cmdOpen([]string{"/open", "1"})
+	})
+
+	if !strings.Contains(output, "No citations") {
+		t.Error("Should show no citations message")
+	}
+}
+
+func TestOpenBrowserRejectsNonWebURL(t *testing.T) {
+	for _, u := range []string{"file:///etc/passwd", "javascript:alert(1)", "not a url"} {
+		if err := openBrowser(u); err == nil {
+			t.Errorf("openBrowser(%q) should fail", u)
+		}
+	}
+}
+
+func TestCmdCode(t *testing.T) {
+	session := newTestSession()
+
+	output := captureOutput(func() {
+		session.cmdCode([]string{"/code"})
+	})
+	if !strings.Contains(output, "No code blocks") {
+		t.Errorf("output = %q, want no code blocks message", output)
+	}
+
+	session.lastResponse = "Try:\n\n```bash\necho hi\n```\n\n```\nfirst\nsecond\n```"
+
+	output = captureOutput(func() {
+		session.cmdCode([]string{"/code"})
+	})
+	for _, want := range []string{"1. bash (1 line): echo hi", "2. text (2 lines): first"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output = %q, want %q", output, want)
+		}
+	}
+
+	output = captureOutput(func() {
+		session.cmdCode([]string{"/code", "3"})
+	})
+	if !strings.Contains(output, "Invalid code block number. Use 1-2.") {
+		t.Errorf("output = %q, want invalid number message", output)
+	}
+}
+
+func TestCmdCodeSave(t *testing.T) {
+	session := newTestSession()
+	session.lastResponse = "```bash\n#!/bin/sh\necho hi\n```\n\n```json\n{}\n```"
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		args string
+		want string
+		path string
+		code string
+	}{
+		{"missing path", "save 1", "Usage: /code save <n> <path>", "", ""},
+		{"invalid number", "save 5 out", "Invalid code block number", "", ""},
+		{"guesses extension", "save 2 " + filepath.Join(dir, "config"), "saved to", filepath.Join(dir, "config.json"), "{}\n"},
+		{"keeps extension", "save 1 " + filepath.Join(dir, "hello.bash"), "saved to", filepath.Join(dir, "hello.bash"), "#!/bin/sh\necho hi\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := captureOutput(func() {
+				session.cmdCode([]string{"/code", tt.args})
+			})
+			if !strings.Contains(output, tt.want) {
+				t.Errorf("output = %q, want %q", output, tt.want)
+			}
+			if tt.path == "" {
+				return
+			}
+			data, err := os.ReadFile(tt.path)
+			if err != nil {
+				t.Fatalf("code block not saved: %v", err)
+			}
+			if string(data) != tt.code {
+				t.Errorf("saved %q, want %q", data, tt.code)
+			}
+		})
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, "hello.bash")); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0100 == 0 {
+		t.Error("script with a shebang should be executable")
+	}
+
+	// An existing file is only overwritten after confirmation
+	existing := filepath.Join(dir, "existing.json")
+	if err := os.WriteFile(existing, []byte("kept"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	stubReadLine(t, "n", "y")
+	output := captureOutput(func() {
+		session.cmdCode([]string{"/code", "save 2 " + existing})
+	})
+	if data, _ := os.ReadFile(existing); !strings.Contains(output, "Cancelled.") || string(data) != "kept" {
+		t.Errorf("declined overwrite: output = %q, file = %q, want the file kept", output, data)
+	}
+	captureOutput(func() {
+		session.cmdCode([]string{"/code", "save 2 " + existing})
+	})
+	if data, _ := os.ReadFile(existing); string(data) != "{}\n" {
+		t.Errorf("confirmed overwrite: file = %q, want the code block", data)
+	}
+}
+
 func TestCmdRetryNoInput(t *testing.T) {
 	session := newTestSession()
 	session.lastUserInput = ""
@@ -367,7 +691,7 @@ func TestCmdRetryNoInput(t *testing.T) {
 }
 
 func TestCmdDelete(t *testing.T) {
-	session := newTestSessionWithHistory()
+	session := newTestSessionWithHistory(t)
 	initialCount := len(session.history.Conversations)
 
 	output := captureOutput(func() {
@@ -383,19 +707,19 @@ func TestCmdDelete(t *testing.T) {
 }
 
 func TestCmdDeleteInvalid(t *testing.T) {
-	session := newTestSessionWithHistory()
+	session := newTestSessionWithHistory(t)
 
 	output := captureOutput(func() {
 		session.cmdDelete([]string{"/delete", "999"})
 	})
 
-	if !strings.Contains(output, "Invalid") {
+	if !strings.Contains(output, "invalid conversation index: 999") {
 		t.Error("Should show invalid index message")
 	}
 }
 
 func TestCmdDeleteNoIndex(t *testing.T) {
-	session := newTestSessionWithHistory()
+	session := newTestSessionWithHistory(t)
 
 	output := captureOutput(func() {
 		session.cmdDelete([]string{"/delete"})
@@ -549,6 +873,60 @@ func TestCmdExportAddsExtension(t *testing.T) {
 	}
 }
 
+func TestCmdExportFormat(t *testing.T) {
+	session := newTestSession()
+	session.messages = append(session.messages,
+		api.Message{Role: "user", Content: "What is Go?"},
+		api.Message{Role: "assistant", Content: "A language[1].", Citations: []string{"https://go.dev"}},
+	)
+
+	tempFile := filepath.Join(t.TempDir(), "note")
+	output := captureOutput(func() {
+		session.cmdExport([]string{"/export", "--format obsidian " + tempFile})
+	})
+	if !strings.Contains(output, "exported to "+tempFile+".md") {
+		t.Errorf("output %q should show the export with its extension", output)
+	}
+	content, err := os.ReadFile(tempFile + ".md")
+	if err != nil {
+		t.Fatalf("Failed to read exported file: %v", err)
+	}
+	if !strings.HasPrefix(string(content), "---\ntitle: What is Go?\n") || !strings.Contains(string(content), "[^1-1]: https://go.dev") {
+		t.Errorf("export %q should be an Obsidian note with citation footnotes", content)
+	}
+
+	output = captureOutput(func() {
+		session.cmdExport([]string{"/export", "--format pdf"})
+	})
+	if !strings.Contains(output, "export format must be") {
+		t.Errorf("output %q should reject the format", output)
+	}
+}
+
+func TestParseExportArgs(t *testing.T) {
+	tests := []struct {
+		args         string
+		wantFormat   string
+		wantFilename string
+		wantErr      bool
+	}{
+		{"", "", "", false},
+		{"notes.md", "", "notes.md", false},
+		{"--format notion", "notion", "", false},
+		{"--format CSV my research", "csv", "my research", false},
+		{"--format", "", "", true},
+		{"--format docx out", "", "", true},
+	}
+
+	for _, tt := range tests {
+		format, filename, err := parseExportArgs(tt.args)
+		if format != tt.wantFormat || filename != tt.wantFilename || (err != nil) != tt.wantErr {
+			t.Errorf("parseExportArgs(%q) = %q, %q, %v, want %q, %q, error %v",
+				tt.args, format, filename, err, tt.wantFormat, tt.wantFilename, tt.wantErr)
+		}
+	}
+}
+
 func TestCmdResumeNoHistory(t *testing.T) {
 	session := newTestSession()
 	session.history = nil
@@ -576,7 +954,7 @@ func TestCmdResumeEmptyHistory(t *testing.T) {
 }
 
 func TestCmdResumeWithIndex(t *testing.T) {
-	session := newTestSessionWithHistory()
+	session := newTestSessionWithHistory(t)
 
 	output := captureOutput(func() {
 		session.cmdResume([]string{"/resume", "1"})
@@ -591,7 +969,7 @@ func TestCmdResumeWithIndex(t *testing.T) {
 }
 
 func TestCmdResumeInvalidIndex(t *testing.T) {
-	session := newTestSessionWithHistory()
+	session := newTestSessionWithHistory(t)
 
 	output := captureOutput(func() {
 		session.cmdResume([]string{"/resume", "999"})
@@ -603,7 +981,7 @@ func TestCmdResumeInvalidIndex(t *testing.T) {
 }
 
 func TestCmdResumeNonNumericIndex(t *testing.T) {
-	session := newTestSessionWithHistory()
+	session := newTestSessionWithHistory(t)
 
 	output := captureOutput(func() {
 		session.cmdResume([]string{"/resume", "abc"})
@@ -615,7 +993,7 @@ func TestCmdResumeNonNumericIndex(t *testing.T) {
 }
 
 func TestCmdResumeLatest(t *testing.T) {
-	session := newTestSessionWithHistory()
+	session := newTestSessionWithHistory(t)
 
 	output := captureOutput(func() {
 		session.cmdResume([]string{"/resume"})
@@ -738,13 +1116,13 @@ func TestCmdDeleteNilHistory(t *testing.T) {
 }
 
 func TestCmdDeleteNonNumeric(t *testing.T) {
-	session := newTestSessionWithHistory()
+	session := newTestSessionWithHistory(t)
 
 	output := captureOutput(func() {
 		session.cmdDelete([]string{"/delete", "abc"})
 	})
 
-	if !strings.Contains(output, "Invalid index") {
+	if !strings.Contains(output, "invalid index: abc") {
 		t.Error("Should show invalid index message")
 	}
 }
@@ -808,3 +1186,118 @@ func TestHandleCommandShortcuts(t *testing.T) {
 		t.Error("/r should trigger retry")
 	}
 }
+
+func TestCmdPin(t *testing.T) {
+	session := newTestSession()
+	session.history = newTestHistory(t, 12)
+
+	output := captureOutput(func() {
+		session.cmdPin([]string{"/pin"}, true)
+	})
+	if !strings.Contains(output, "Send a message first") {
+		t.Errorf("output %q should ask for a saved conversation", output)
+	}
+
+	// The first of the recent 10 is the third conversation
+	output = captureOutput(func() {
+		session.cmdPin([]string{"/pin", "1"}, true)
+	})
+	if !session.history.GetConversation("id3").Pinned || !strings.Contains(output, "Pinned: Question") {
+		t.Errorf("output %q, want id3 pinned", output)
+	}
+
+	session.history.AddConversation("id13", "sonar", []history.Message{{Role: "user", Content: "Newer"}})
+	output = captureOutput(func() {
+		session.cmdHistory()
+	})
+	if !strings.Contains(output, "Pinned conversations:") || !strings.Contains(output, "  p1. [") {
+		t.Errorf("history output %q should list the pinned conversation", output)
+	}
+	if conv := findConversation(session.history, "P1"); conv == nil || conv.ID != "id3" {
+		t.Errorf("findConversation(P1) = %v, want id3", conv)
+	}
+
+	output = captureOutput(func() {
+		session.cmdResume([]string{"/resume", "p1"})
+	})
+	if session.conversationID != "id3" || !strings.Contains(output, "Resumed conversation") {
+		t.Errorf("resume p1 output %q, conversation %q, want id3", output, session.conversationID)
+	}
+
+	captureOutput(func() {
+		session.cmdPin([]string{"/unpin", "p1"}, false)
+	})
+	saved := history.NewHistory()
+	if err := saved.Load(); err != nil || len(saved.PinnedConversations()) != 0 {
+		t.Errorf("saved pinned = %+v, err %v, want none", saved.PinnedConversations(), err)
+	}
+	if conv := findConversation(session.history, "p1"); conv != nil {
+		t.Errorf("findConversation(p1) = %v after unpinning, want nil", conv.ID)
+	}
+}
+
+func TestCmdResumeShowsMessageTimes(t *testing.T) {
+	session := newTestSession()
+	session.history = history.NewHistory()
+	session.history.AddConversation("conv", "sonar", []history.Message{
+		{Role: "user", Content: "Old question"},
+		{Role: "assistant", Content: "Old answer"},
+		{Role: "user", Content: "New question", Time: time.Now().Add(-3 * time.Hour)},
+		{Role: "assistant", Content: "New answer", Time: time.Now().Add(-3 * time.Hour)},
+	})
+
+	output := captureOutput(func() {
+		session.cmdResume([]string{"/resume"})
+	})
+	for _, want := range []string{"You:\nOld question", "Assistant:\n", "You (3 hours ago):\nNew question", "Assistant (3 hours ago):\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("resume output %q should contain %q", output, want)
+		}
+	}
+}
+
+func TestCmdResumeSettings(t *testing.T) {
+	stored := []history.Message{
+		{Role: "system", Content: "Answer as a pirate."},
+		{Role: "user", Content: "Hello"},
+		{Role: "assistant", Content: "Ahoy"},
+	}
+
+	tests := []struct {
+		name       string
+		mode       string
+		answers    []string
+		messages   []history.Message
+		wantModel  string
+		wantPrompt string
+	}{
+		{"restore", "", nil, stored, "sonar", "Answer as a pirate."},
+		{"keep", config.ResumeSettingsKeep, nil, stored, "sonar-pro", config.DefaultSystemMessage},
+		{"ask yes", config.ResumeSettingsAsk, []string{"y"}, stored, "sonar", "Answer as a pirate."},
+		{"ask no", config.ResumeSettingsAsk, []string{"n"}, stored, "sonar-pro", config.DefaultSystemMessage},
+		{"no stored prompt", "", nil, stored[1:], "sonar", config.DefaultSystemMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubReadLine(t, tt.answers...)
+			session := newTestSession()
+			session.app.cfg.ResumeSettings = tt.mode
+			session.history.AddConversation("conv", "sonar", tt.messages)
+
+			output := captureOutput(func() {
+				session.cmdResume([]string{"/resume"})
+			})
+			if session.app.cfg.Model != tt.wantModel {
+				t.Errorf("model = %q, want %q (output %q)", session.app.cfg.Model, tt.wantModel, output)
+			}
+			messages := session.getMessages()
+			if len(messages) != 3 || messages[0].Role != "system" || messages[0].Content != tt.wantPrompt {
+				t.Errorf("messages = %+v, want the system prompt %q and the exchange", messages, tt.wantPrompt)
+			}
+			if tt.wantModel == "sonar" && !strings.Contains(output, "Switched to model sonar") {
+				t.Errorf("output %q should note the model switch", output)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+)
+
+// maxCommitDiff limits the characters of the staged diff sent to write a
+// commit message, so large changes stay within the context window
+const maxCommitDiff = 30000
+
+// commitPrompt asks for a conventional-commit message of the diff that follows
+const commitPrompt = `You write git commit messages in the Conventional Commits format.
+Reply with only the commit message for the staged diff below, without code fences or commentary:
+- a subject line "type(scope): summary" of at most 72 characters, where type is one of
+  feat, fix, docs, style, refactor, perf, test, build, ci or chore, the scope is optional,
+  and the summary is in the imperative mood without a final period
+- if the change needs explaining, a blank line and a body wrapped at 72 characters that
+  says what changed and why, not how
+- a "BREAKING CHANGE:" footer only if the change breaks existing behavior`
+
+// newCommitCmd creates the commit subcommand for writing commit messages
+func (app *App) newCommitCmd() *cobra.Command {
+	var (
+		model string
+		apply bool
+	)
+
+	commitCmd := &cobra.Command{
+		Use:   "commit",
+		Short: "Write a conventional-commit message for the staged changes",
+		Long: `Send the staged diff of the git repository in the current directory and print
+a commit message for it in the Conventional Commits format. With --apply, the
+message is committed with git commit -F - instead of printed.
+
+Diffs longer than 30000 characters are truncated.
+
+  perplexity commit
+  perplexity commit --apply`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := newSignalContext()
+			defer cancel()
+
+			diff, err := stagedDiff(ctx)
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(diff) == "" {
+				return invalidInput(fmt.Errorf("no staged changes; stage them with git add first"))
+			}
+
+			cfg, err := app.taskConfig(model, diff)
+			if err != nil {
+				return err
+			}
+
+			message, err := commitMessage(ctx, app.newClientFor(cfg), diff)
+			if err != nil {
+				return err
+			}
+			if apply {
+				return gitCommit(ctx, message)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), message)
+			return nil
+		},
+	}
+
+	commitCmd.Flags().StringVarP(&model, "model", "m", app.cfg.Model, "Model writing the commit message")
+	commitCmd.Flags().BoolVar(&apply, "apply", false, "Commit the staged changes with the message instead of printing it")
+	return commitCmd
+}
+
+// commitMessage asks for a commit message of a diff
+func commitMessage(ctx context.Context, client *api.Client, diff string) (string, error) {
+	if runes := []rune(diff); len(runes) > maxCommitDiff {
+		diff = string(runes[:maxCommitDiff]) + "\n[diff truncated]"
+	}
+
+	resp, err := queryWithSpinner(ctx, client, "Writing commit message...", []api.Message{
+		{Role: "system", Content: commitPrompt},
+		{Role: "user", Content: diff},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	message := cleanCommitMessage(resp.GetContent())
+	if message == "" {
+		return "", fmt.Errorf("the model returned an empty commit message")
+	}
+	return message, nil
+}
+
+// cleanCommitMessage removes reasoning, citation markers and code fences
+// around a generated commit message
+func cleanCommitMessage(content string) string {
+	_, message := display.SplitThinking(content)
+	message = strings.TrimSpace(display.StripCitationMarkers(message))
+	if strings.HasPrefix(message, "```") {
+		// Drop the opening fence with its language, and the closing fence
+		_, message, _ = strings.Cut(message, "\n")
+		message = strings.TrimSuffix(strings.TrimSpace(message), "```")
+	}
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stagedDiff returns the staged changes of the repository in the current directory
+func stagedDiff(ctx context.Context) (string, error) {
+	return runGit(ctx, nil, "diff", "--staged", "--no-color", "--no-ext-diff")
+}
+
+// gitCommit commits the staged changes with message, showing git's output
+func gitCommit(ctx context.Context, message string) error {
+	out, err := runGit(ctx, strings.NewReader(message+"\n"), "commit", "-F", "-")
+	fmt.Fprint(os.Stdout, out)
+	return err
+}
+
+// runGit runs a git command in the current directory and returns its output,
+// with its stderr in the error
+func runGit(ctx context.Context, stdin io.Reader, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.String(), fmt.Errorf("git %s failed: %s", args[0], msg)
+		}
+		return stdout.String(), fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+func TestCleanCommitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"plain", "feat(cli): add commit command\n", "feat(cli): add commit command"},
+		{"fenced", "```text\nfix: handle empty diff  \n\nExplain why.\n```", "fix: handle empty diff\n\nExplain why."},
+		{"thinking", "<think>Looks like a fix.</think>\nfix: retry on timeouts[1]", "fix: retry on timeouts"},
+		{"empty", "  ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanCommitMessage(tt.content); got != tt.want {
+				t.Errorf("cleanCommitMessage(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	var sent api.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "docs: describe commit command"}}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", APIURL: server.URL, Model: "sonar"}
+	app := &App{cfg: cfg}
+	diff := strings.Repeat("+", maxCommitDiff+10)
+
+	var message string
+	var err error
+	captureOutput(func() {
+		message, err = commitMessage(context.Background(), app.newClient(), diff)
+	})
+	if err != nil {
+		t.Fatalf("commitMessage() error = %v", err)
+	}
+	if message != "docs: describe commit command" {
+		t.Errorf("commitMessage() = %q", message)
+	}
+	if len(sent.Messages) != 2 || sent.Messages[0].Content != commitPrompt {
+		t.Fatalf("request messages = %+v, want the commit prompt and the diff", sent.Messages)
+	}
+	if !strings.HasSuffix(sent.Messages[1].Content, "[diff truncated]") {
+		t.Error("a diff over maxCommitDiff should be sent truncated")
+	}
+}
+
+func TestStagedDiffAndCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	ctx := context.Background()
+	if _, err := runGit(ctx, nil, "init", "--quiet"); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := stagedDiff(ctx)
+	if err != nil || diff != "" {
+		t.Fatalf("stagedDiff() = %q, %v with nothing staged", diff, err)
+	}
+
+	if err := os.WriteFile("notes.txt", []byte("hello\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(ctx, nil, "add", "notes.txt"); err != nil {
+		t.Fatal(err)
+	}
+	diff, err = stagedDiff(ctx)
+	if err != nil || !strings.Contains(diff, "+hello") {
+		t.Fatalf("stagedDiff() = %q, %v, want the staged file", diff, err)
+	}
+
+	captureOutput(func() {
+		err = gitCommit(ctx, "docs: add notes\n\nKeep notes in the repository.")
+	})
+	if err != nil {
+		t.Fatalf("gitCommit() error = %v", err)
+	}
+	log, err := runGit(ctx, nil, "log", "-1", "--format=%B")
+	if err != nil || strings.TrimSpace(log) != "docs: add notes\n\nKeep notes in the repository." {
+		t.Errorf("committed message = %q, %v", log, err)
+	}
+}
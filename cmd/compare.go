@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/pool"
+	"github.com/quocvuong92/perplexity-cli/internal/pricing"
+)
+
+// compareResult holds one model's response in a model comparison
+type compareResult struct {
+	model    string
+	resp     *api.ChatResponse
+	duration time.Duration
+	err      error
+}
+
+// parseModelList splits a comma-separated model list, resolving aliases,
+// removing duplicates and rejecting unknown models. The auto model is
+// rejected too, since its results could not be told apart from those of the
+// model it picks.
+func (app *App) parseModelList(list string) ([]string, error) {
+	var models []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		model := app.cfg.ResolveModel(name)
+		if model == config.AutoModel {
+			return nil, fmt.Errorf("%w: %s picks a model per query; list the models to compare instead", config.ErrInvalidModel, name)
+		}
+		if !app.cfg.IsValidModel(model) {
+			return nil, fmt.Errorf("%w: %s. Available models: %s", config.ErrInvalidModel, name, app.cfg.GetModelsString())
+		}
+		if !slices.Contains(models, model) {
+			models = append(models, model)
+		}
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no models given")
+	}
+	return models, nil
+}
+
+// parseCompareModels parses the models to compare; at least two distinct models are required
+func (app *App) parseCompareModels(list string) ([]string, error) {
+	models, err := app.parseModelList(list)
+	if err != nil {
+		return nil, err
+	}
+	if len(models) < 2 {
+		return nil, fmt.Errorf("at least two different models are required to compare, e.g. sonar,sonar-pro")
+	}
+	return models, nil
+}
+
+// compareModels sends the same messages to each model concurrently, on up
+// to the configured number of workers. Every model gets its own client and
+// config copy, so key rotation in one request does not affect the others.
+func (app *App) compareModels(ctx context.Context, models []string, messages []api.Message) []compareResult {
+	results := make([]compareResult, len(models))
+	errs := pool.Run(ctx, len(models), app.poolOptions(), func(ctx context.Context, i int) error {
+		cfg := *app.cfg
+		cfg.Model = models[i]
+		client := app.newPooledClient(&cfg)
+
+		start := time.Now()
+		resp, err := client.QueryWithHistoryContext(ctx, slices.Clone(messages))
+		results[i] = compareResult{model: models[i], resp: resp, duration: time.Since(start), err: err}
+		return err
+	})
+
+	// Models not started before cancellation have no result yet
+	for i, err := range errs {
+		if results[i].model == "" {
+			results[i] = compareResult{model: models[i], err: err}
+		}
+	}
+	return results
+}
+
+// showComparison displays the responses side by side, followed by the per-model usage and cost
+func (app *App) showComparison(results []compareResult) {
+	columns := make([]display.Column, len(results))
+	rows := make([]display.ComparisonRow, len(results))
+
+	for i, r := range results {
+		columns[i].Title = r.model
+		rows[i] = display.ComparisonRow{Model: r.model, Duration: r.duration, Err: r.err}
+
+		if r.err != nil {
+			msg, _ := display.FormatNetworkError(r.err)
+			columns[i].Body = "Error: " + msg
+			continue
+		}
+
+		var body strings.Builder
+		_, answer := app.splitContent(r.resp.GetContent(), r.resp.Citations)
+		body.WriteString(answer)
+		if app.cfg.Citations && len(r.resp.Citations) > 0 {
+			body.WriteString("\n\nCitations:")
+			for n, citation := range r.resp.Citations {
+				fmt.Fprintf(&body, "\n%d. %s", n+1, citation)
+			}
+		}
+		columns[i].Body = body.String()
+
+		rows[i].PromptTokens = r.resp.Usage.PromptTokens
+		rows[i].CompletionTokens = r.resp.Usage.CompletionTokens
+		if cost, estimated, ok := responseCost(r.resp, r.model); ok {
+			rows[i].Cost = pricing.Format(cost)
+			if estimated {
+				rows[i].Cost = "~" + rows[i].Cost
+			}
+		}
+	}
+
+	display.ShowColumns(columns, display.TerminalWidth())
+	display.ShowComparison(rows)
+}
+
+// runCompare sends a single query to several models and compares the responses
+func (app *App) runCompare(ctx context.Context, models []string, query string) error {
+	messages := []api.Message{
+		{Role: "system", Content: app.cfg.GetSystemPrompt()},
+		{Role: "user", Content: query},
+	}
+
+	sp := display.NewSpinner(fmt.Sprintf("Comparing %d models...", len(models)))
+	sp.Start()
+	results := app.compareModels(ctx, models, messages)
+	sp.Stop()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	app.showComparison(results)
+
+	// Fail only if no model answered
+	for _, r := range results {
+		if r.err == nil {
+			return nil
+		}
+	}
+	return results[0].err
+}
+
+// cmdCompare sends a prompt, or the last message when none is given, to several
+// models and compares the responses. The conversation is left unchanged.
+func (s *InteractiveSession) cmdCompare(parts []string) bool {
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		fmt.Println("Usage: /compare <model,model,...> [prompt]")
+		return false
+	}
+
+	args := strings.SplitN(strings.TrimSpace(parts[1]), " ", 2)
+	models, err := s.app.parseCompareModels(args[0])
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	messages := s.getMessages()
+	if len(args) > 1 && strings.TrimSpace(args[1]) != "" {
+		prompt, err := s.app.cleanPrompt(args[1])
+		if err != nil {
+			display.ShowError(err.Error())
+			return false
+		}
+		messages = append(messages, api.Message{Role: "user", Content: prompt})
+	} else {
+		if s.lastUserInput == "" {
+			fmt.Println("No previous message to compare. Usage: /compare <model,model,...> [prompt]")
+			return false
+		}
+		// Re-ask the last message
+		if len(messages) > 0 && messages[len(messages)-1].Role == "assistant" {
+			messages = messages[:len(messages)-1]
+		}
+	}
+	messages = withoutUnanswered(messages)
+
+	ctx := s.interruptCtx.Start()
+	defer s.interruptCtx.Stop()
+
+	sp := display.NewSpinner(fmt.Sprintf("Comparing %d models...", len(models)))
+	sp.Start()
+	results := s.app.compareModels(ctx, models, messages)
+	sp.Stop()
+
+	if ctx.Err() != nil {
+		return false
+	}
+	fmt.Println()
+	s.app.showComparison(results)
+	return false
+}
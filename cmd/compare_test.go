@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+// createCompareServer answers each request with the requested model name
+func createCompareServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if req.Model == "sonar-reasoning" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"bad request"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "answer from " + req.Model}}},
+			Usage:   api.Usage{PromptTokens: 1000, CompletionTokens: 1000, TotalTokens: 2000},
+		})
+	}))
+}
+
+func TestParseCompareModels(t *testing.T) {
+	app := &App{cfg: &config.Config{ModelAliases: map[string]string{"fast": "sonar", "smart": config.AutoModel}}}
+
+	tests := []struct {
+		name    string
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{"two models", "sonar,sonar-pro", []string{"sonar", "sonar-pro"}, false},
+		{"spaces and alias", " fast , sonar-pro ,", []string{"sonar", "sonar-pro"}, false},
+		{"duplicates via alias", "fast,sonar", nil, true},
+		{"single model", "sonar", nil, true},
+		{"unknown model", "sonar,gpt-4", nil, true},
+		{"auto model", "auto,sonar", nil, true},
+		{"alias to auto model", "smart,sonar", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := app.parseCompareModels(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCompareModels(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseCompareModels(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCompare(t *testing.T) {
+	server := createCompareServer(t)
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", APIURL: server.URL, Model: "sonar-pro"}
+	app := &App{cfg: cfg}
+
+	output := captureStdoutOnly(func() {
+		app.runCompare(context.Background(), []string{"sonar", "sonar-pro", "sonar-reasoning"}, "test query")
+	})
+
+	for _, want := range []string{"answer from sonar", "answer from sonar-pro", "Comparison", "$0.0020", "$0.0180", "failed"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output should contain %q:\n%s", want, output)
+		}
+	}
+	if cfg.Model != "sonar-pro" {
+		t.Errorf("comparison should not change the configured model, got %q", cfg.Model)
+	}
+}
+
+func TestCmdCompare(t *testing.T) {
+	server := createCompareServer(t)
+	defer server.Close()
+
+	session := newTestSession()
+	session.app.cfg.APIURL = server.URL
+	session.app.cfg.APIKey = "test-key"
+	session.interruptCtx = NewInterruptibleContext()
+
+	output := captureOutput(func() {
+		session.cmdCompare([]string{"/compare", "sonar,sonar-pro"})
+	})
+	if !strings.Contains(output, "No previous message") {
+		t.Errorf("expected no previous message note, got %q", output)
+	}
+
+	before := session.getMessageCount()
+	output = captureOutput(func() {
+		session.cmdCompare([]string{"/compare", "sonar,sonar-pro what is go?"})
+	})
+	if !strings.Contains(output, "answer from sonar-pro") {
+		t.Errorf("output should contain compared responses, got %q", output)
+	}
+	if session.getMessageCount() != before {
+		t.Error("comparison should leave the conversation unchanged")
+	}
+}
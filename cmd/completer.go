@@ -1,12 +1,16 @@
 package cmd
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/elk-language/go-prompt"
 	istrings "github.com/elk-language/go-prompt/strings"
 
-	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/export"
+	"github.com/quocvuong92/perplexity-cli/internal/fileref"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+	"github.com/quocvuong92/perplexity-cli/internal/prompts"
 )
 
 // completer provides auto-completion suggestions for slash commands.
@@ -17,6 +21,16 @@ func (s *InteractiveSession) completer(d prompt.Document) ([]prompt.Suggest, ist
 	w := d.GetWordBeforeCursor()
 	startIndex := endIndex - istrings.RuneCountInString(w)
 
+	// @path - suggest files to reference
+	if partial, ok := fileref.Token(text); ok {
+		return pathSuggestions(partial, "@"), startIndex, endIndex
+	}
+
+	// /export [--format <f>] <path> and /code save <n> <path> - suggest files and directories
+	if partial, ok := pathArgument(text); ok {
+		return pathSuggestions(partial, ""), startIndex, endIndex
+	}
+
 	// Only show suggestions when input starts with "/"
 	if len(text) == 0 || text[0] != '/' {
 		return []prompt.Suggest{}, startIndex, endIndex
@@ -27,13 +41,16 @@ func (s *InteractiveSession) completer(d prompt.Document) ([]prompt.Suggest, ist
 	// /model <name> - suggest available models
 	if strings.HasPrefix(textLower, "/model ") || strings.HasPrefix(textLower, "/m ") {
 		var suggestions []prompt.Suggest
-		for _, model := range config.AvailableModels {
+		for _, model := range s.app.cfg.GetModels() {
 			desc := ""
 			if model == s.app.cfg.Model {
 				desc = "(current)"
 			}
 			suggestions = append(suggestions, prompt.Suggest{Text: model, Description: desc})
 		}
+		for _, alias := range s.app.cfg.GetModelAliasNames() {
+			suggestions = append(suggestions, prompt.Suggest{Text: alias, Description: "-> " + s.app.cfg.ModelAliases[alias]})
+		}
 		return prompt.FilterHasPrefix(suggestions, w, true), startIndex, endIndex
 	}
 
@@ -46,14 +63,118 @@ func (s *InteractiveSession) completer(d prompt.Document) ([]prompt.Suggest, ist
 		return prompt.FilterHasPrefix(suggestions, w, true), startIndex, endIndex
 	}
 
-	// /system - suggest reset option
-	if strings.HasPrefix(textLower, "/system ") {
+	// /reasoning - suggest effort levels
+	if strings.HasPrefix(textLower, "/reasoning ") {
+		suggestions := []prompt.Suggest{
+			{Text: "low", Description: "Faster, cheaper answers"},
+			{Text: "medium", Description: "Balanced depth and latency"},
+			{Text: "high", Description: "Deepest reasoning"},
+			{Text: "off", Description: "Use the API default"},
+		}
+		return prompt.FilterHasPrefix(suggestions, w, true), startIndex, endIndex
+	}
+
+	// /translate <lang> - suggest target languages
+	if words := strings.Fields(textLower); strings.HasPrefix(textLower, "/translate ") && len(words) <= 2 {
+		return prompt.FilterHasPrefix(languageSuggestions(), w, true), startIndex, endIndex
+	}
+
+	// /export --format - suggest export formats
+	if words := strings.Fields(textLower); strings.HasPrefix(textLower, "/export --format ") && len(words) <= 3 {
+		suggestions := []prompt.Suggest{
+			{Text: export.FormatMarkdown, Description: "Markdown document (default)"},
+			{Text: export.FormatObsidian, Description: "Markdown note with front matter"},
+			{Text: export.FormatNotion, Description: "HTML page for Notion import"},
+			{Text: export.FormatCSV, Description: "CSV for a Notion database"},
+		}
+		return prompt.FilterHasPrefix(suggestions, w, true), startIndex, endIndex
+	}
+
+	// /copy - suggest what to copy
+	if strings.HasPrefix(textLower, "/copy ") {
+		suggestions := []prompt.Suggest{
+			{Text: "md", Description: "Response as markdown (default)"},
+			{Text: "plain", Description: "Response without markdown syntax"},
+			{Text: "html", Description: "Response as HTML for rich-text editors"},
+			{Text: "citations", Description: "Citation URLs of the last response"},
+			{Text: "sources-md", Description: "Citations as markdown links"},
+		}
+		return prompt.FilterHasPrefix(suggestions, w, true), startIndex, endIndex
+	}
+
+	// /resume, /delete, /archive, /share and /pin - suggest the conversations listed by /history
+	if strings.HasPrefix(textLower, "/resume ") || strings.HasPrefix(textLower, "/delete ") || strings.HasPrefix(textLower, "/archive ") ||
+		strings.HasPrefix(textLower, "/share ") || strings.HasPrefix(textLower, "/pin ") || strings.HasPrefix(textLower, "/unpin ") {
+		return prompt.FilterHasPrefix(s.conversationSuggestions(), w, true), startIndex, endIndex
+	}
+
+	// /system load and /system save - suggest the saved prompts
+	if words := strings.Fields(textLower); (strings.HasPrefix(textLower, "/system load ") || strings.HasPrefix(textLower, "/system save ")) && len(words) <= 3 {
+		return prompt.FilterHasPrefix(savedPromptSuggestions(), w, true), startIndex, endIndex
+	}
+
+	// /system - suggest reset and the prompt library
+	if words := strings.Fields(textLower); strings.HasPrefix(textLower, "/system ") && len(words) <= 2 {
 		suggestions := []prompt.Suggest{
 			{Text: "reset", Description: "Reset to default system prompt"},
+			{Text: "save", Description: "Save the system prompt under a name"},
+			{Text: "load", Description: "Load a saved system prompt"},
+			{Text: "list", Description: "List the saved system prompts"},
 		}
 		return prompt.FilterHasPrefix(suggestions, w, true), startIndex, endIndex
 	}
 
+	return prompt.FilterHasPrefix(s.commandSuggestions(), w, true), startIndex, endIndex
+}
+
+// conversationSuggestions returns the indexes of the conversations listed by
+// /history with their date and title, most recent first
+func (s *InteractiveSession) conversationSuggestions() []prompt.Suggest {
+	if s.history == nil {
+		return nil
+	}
+	var suggestions []prompt.Suggest
+	for i, conv := range s.history.PinnedConversations() {
+		suggestions = append(suggestions, prompt.Suggest{Text: pinnedRefPrefix + strconv.Itoa(i+1), Description: conversationDescription(conv)})
+	}
+	conversations := s.history.GetRecentConversations(10)
+	for i := len(conversations) - 1; i >= 0; i-- {
+		suggestions = append(suggestions, prompt.Suggest{Text: strconv.Itoa(i + 1), Description: conversationDescription(conversations[i])})
+	}
+	return suggestions
+}
+
+// savedPromptSuggestions returns the names of the saved system prompts
+func savedPromptSuggestions() []prompt.Suggest {
+	names, _ := prompts.NewLibrary().Names()
+	suggestions := make([]prompt.Suggest, 0, len(names))
+	for _, name := range names {
+		suggestions = append(suggestions, prompt.Suggest{Text: name, Description: "Saved system prompt"})
+	}
+	return suggestions
+}
+
+// languageSuggestions returns the target languages of /translate
+func languageSuggestions() []prompt.Suggest {
+	suggestions := make([]prompt.Suggest, len(languages))
+	for i, lang := range languages {
+		suggestions[i] = prompt.Suggest{Text: lang.Code, Description: lang.Name}
+	}
+	return suggestions
+}
+
+// conversationDescription describes a conversation by its date and title
+func conversationDescription(conv history.ConversationEntry) string {
+	description := conv.UpdatedAt.Format("2006-01-02 15:04")
+	if title := conv.Title(); title != "" {
+		description += " " + title
+	}
+	return description
+}
+
+// commandSuggestions returns the slash commands offered by the completer,
+// including the user-defined ones
+func (s *InteractiveSession) commandSuggestions() []prompt.Suggest {
 	// Build citations status for description
 	citationsStatus := "off"
 	if s.app.cfg.Citations {
@@ -65,11 +186,20 @@ func (s *InteractiveSession) completer(d prompt.Document) ([]prompt.Suggest, ist
 		// Most used commands first
 		{Text: "/model", Description: "Show/switch model (current: " + s.app.cfg.Model + ")"},
 		{Text: "/system", Description: "Show/set system prompt"},
+		{Text: "/compare", Description: "Compare models on a prompt"},
+		{Text: "/translate", Description: "Translate text or the last response"},
+		{Text: "/reasoning", Description: "Show/set reasoning effort"},
 		{Text: "/citations", Description: "Toggle citations display (current: " + citationsStatus + ")"},
 		{Text: "/clear", Description: "Clear conversation history"},
 		{Text: "/retry", Description: "Retry last message"},
 		{Text: "/copy", Description: "Copy last response to clipboard"},
-		{Text: "/export", Description: "Export conversation to markdown"},
+		{Text: "/paste", Description: "Send or add the clipboard contents"},
+		{Text: "/multiline", Description: "Write a multiline message, ended by /multiline"},
+		{Text: "/open", Description: "Open a citation of the last response in the browser"},
+		{Text: "/code", Description: "List or copy code blocks of the last response"},
+		{Text: "/run", Description: "Run a shell code block of the last response"},
+		{Text: "/export", Description: "Export conversation to markdown, Obsidian or Notion"},
+		{Text: "/share", Description: "Upload conversation as a gist or paste"},
 		{Text: "/help", Description: "Show all available commands"},
 		{Text: "/exit", Description: "Exit interactive mode"},
 
@@ -78,6 +208,10 @@ func (s *InteractiveSession) completer(d prompt.Document) ([]prompt.Suggest, ist
 		{Text: "/search", Description: "Search conversations by keyword"},
 		{Text: "/resume", Description: "Resume conversation by index"},
 		{Text: "/delete", Description: "Delete conversation by index"},
+		{Text: "/archive", Description: "Archive conversation by index"},
+		{Text: "/tag", Description: "Tag the conversation for /search --tag"},
+		{Text: "/pin", Description: "Pin a conversation at the top of /history"},
+		{Text: "/unpin", Description: "Unpin a conversation"},
 
 		// Aliases
 		{Text: "/q", Description: "Exit (alias)"},
@@ -86,6 +220,9 @@ func (s *InteractiveSession) completer(d prompt.Document) ([]prompt.Suggest, ist
 		{Text: "/h", Description: "Help (alias)"},
 		{Text: "/m", Description: "Model (alias)"},
 	}
+	for _, c := range s.app.cfg.Commands {
+		suggestions = append(suggestions, prompt.Suggest{Text: "/" + c.Name, Description: customDescription(c)})
+	}
 
-	return prompt.FilterHasPrefix(suggestions, w, true), startIndex, endIndex
+	return suggestions
 }
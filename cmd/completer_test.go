@@ -7,6 +7,7 @@ import (
 	"github.com/quocvuong92/perplexity-cli/internal/api"
 	"github.com/quocvuong92/perplexity-cli/internal/config"
 	"github.com/quocvuong92/perplexity-cli/internal/history"
+	"github.com/quocvuong92/perplexity-cli/internal/prompts"
 )
 
 func TestToLower(t *testing.T) {
@@ -70,3 +71,47 @@ func newTestSession() *InteractiveSession {
 // which has complex internal state. The helper functions (toLower, hasPrefix)
 // are tested above. Integration testing of the completer is better done
 // through manual testing or end-to-end tests.
+
+func TestConversationSuggestions(t *testing.T) {
+	session := newTestSessionWithHistory(t)
+
+	suggestions := session.conversationSuggestions()
+	if len(suggestions) != 2 {
+		t.Fatalf("conversationSuggestions() = %+v, want 2", suggestions)
+	}
+	if suggestions[0].Text != "2" || !strings.HasSuffix(suggestions[0].Description, "What is Go?") {
+		t.Errorf("first suggestion = %+v, want the most recent conversation", suggestions[0])
+	}
+	if suggestions[1].Text != "1" || !strings.HasSuffix(suggestions[1].Description, "Hello") {
+		t.Errorf("second suggestion = %+v, want the oldest conversation", suggestions[1])
+	}
+
+	session.history.Conversations[0].Pinned = true
+	suggestions = session.conversationSuggestions()
+	if len(suggestions) != 3 || suggestions[0].Text != "p1" || !strings.HasSuffix(suggestions[0].Description, "Hello") {
+		t.Errorf("conversationSuggestions() = %+v, want the pinned conversation first", suggestions)
+	}
+
+	session.history = nil
+	if got := session.conversationSuggestions(); got != nil {
+		t.Errorf("conversationSuggestions() = %+v, want none without history", got)
+	}
+}
+
+func TestSavedPromptSuggestions(t *testing.T) {
+	t.Setenv(prompts.EnvPromptsDir, t.TempDir())
+	if got := savedPromptSuggestions(); len(got) != 0 {
+		t.Errorf("savedPromptSuggestions() = %+v, want none", got)
+	}
+
+	library := prompts.NewLibrary()
+	for _, name := range []string{"reviewer", "pirate"} {
+		if err := library.Save(name, "Prompt of "+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := savedPromptSuggestions()
+	if len(got) != 2 || got[0].Text != "pirate" || got[1].Text != "reviewer" {
+		t.Errorf("savedPromptSuggestions() = %+v, want pirate and reviewer", got)
+	}
+}
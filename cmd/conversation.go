@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+	"github.com/quocvuong92/perplexity-cli/internal/logging"
+	"github.com/quocvuong92/perplexity-cli/internal/pricing"
+)
+
+// conversationMessages converts the messages of a stored conversation
+func conversationMessages(conv *history.ConversationEntry) []api.Message {
+	messages := make([]api.Message, len(conv.Messages))
+	for i, msg := range conv.Messages {
+		messages[i] = api.Message{Role: msg.Role, Content: msg.Content, Citations: msg.Citations, Time: msg.Time, Status: string(msg.Status)}
+	}
+	return messages
+}
+
+// toAPIMessages converts a stored conversation to API messages, dropping
+// unanswered exchanges
+func toAPIMessages(conv *history.ConversationEntry) []api.Message {
+	return withoutUnanswered(conversationMessages(conv))
+}
+
+// withoutUnanswered drops the responses whose status is unanswered together
+// with the message that prompted them, so roles keep alternating
+func withoutUnanswered(messages []api.Message) []api.Message {
+	kept := make([]api.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == "assistant" && history.ResponseStatus(msg.Status).Unanswered() {
+			if len(kept) > 0 && kept[len(kept)-1].Role == "user" {
+				kept = kept[:len(kept)-1]
+			}
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	return kept
+}
+
+// finishReasonLength is the finish reason of an answer cut off at the token limit
+const finishReasonLength = "length"
+
+// responseStatus returns the status an assistant response is stored with,
+// from its answer, the reason the model stopped and the error of its request.
+// A complete answer has no status.
+func responseStatus(answer, finishReason string, err error) history.ResponseStatus {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return history.StatusCancelled
+	case err != nil:
+		return history.StatusFailed
+	case answer == "":
+		return history.StatusEmpty
+	case finishReason == finishReasonLength:
+		return history.StatusTruncated
+	}
+	return ""
+}
+
+// newConversationRef is the --conversation value that starts a new stored conversation
+const newConversationRef = "new"
+
+// loadConversation loads the stored conversation a one-shot query continues,
+// selected with --continue or --conversation
+func (app *App) loadConversation() error {
+	if !app.continueLast && app.conversationRef == "" {
+		return nil
+	}
+	if app.continueLast && app.conversationRef != "" {
+		return fmt.Errorf("--continue and --conversation cannot be combined")
+	}
+	if app.watch > 0 || app.compare != "" {
+		return fmt.Errorf("--continue and --conversation cannot be combined with --watch or --compare")
+	}
+
+	hist := app.newHistory()
+	if err := hist.Load(); err != nil {
+		return err
+	}
+
+	var conv *history.ConversationEntry
+	switch {
+	case app.continueLast:
+		conv = hist.GetLastConversation()
+		if conv == nil {
+			return fmt.Errorf("no conversation to continue")
+		}
+	case app.conversationRef == newConversationRef:
+		conv = &history.ConversationEntry{
+			ID:       uuid.New().String(),
+			Model:    app.cfg.Model,
+			Messages: []history.Message{{Role: "system", Content: app.cfg.GetSystemPrompt()}},
+		}
+	default:
+		conv = findConversation(hist, app.conversationRef)
+		if conv == nil {
+			return fmt.Errorf("conversation %q not found. Use an ID, an index from /history or %q", app.conversationRef, newConversationRef)
+		}
+	}
+
+	app.history = hist
+	app.conversation = conv
+	return nil
+}
+
+// findConversation looks a conversation up by ID, or by its index in the
+// recent conversations listed by /history
+func findConversation(hist *history.History, ref string) *history.ConversationEntry {
+	if conv := hist.GetConversation(ref); conv != nil {
+		return conv
+	}
+	if conv := pinnedConversation(hist, ref); conv != nil {
+		return conv
+	}
+
+	recent := hist.GetRecentConversations(10)
+	index, err := strconv.Atoi(ref)
+	if err != nil || index < 1 || index > len(recent) {
+		return nil
+	}
+	return hist.GetConversation(recent[index-1].ID)
+}
+
+// pinnedRefPrefix starts references to pinned conversations, e.g. p1 for the
+// first one listed by /history
+const pinnedRefPrefix = "p"
+
+// pinnedConversation returns the pinned conversation referenced as p<n>, or
+// nil if ref is not such a reference
+func pinnedConversation(hist *history.History, ref string) *history.ConversationEntry {
+	n, ok := strings.CutPrefix(strings.ToLower(ref), pinnedRefPrefix)
+	if !ok {
+		return nil
+	}
+	pinned := hist.PinnedConversations()
+	index, err := strconv.Atoi(n)
+	if err != nil || index < 1 || index > len(pinned) {
+		return nil
+	}
+	return hist.GetConversation(pinned[index-1].ID)
+}
+
+// queryMessages returns the messages to send for a one-shot query: the
+// --messages-json messages as given, or the continued conversation (or the
+// system prompt) followed by the query
+func (app *App) queryMessages(query string) []api.Message {
+	if app.messages != nil {
+		return app.messages
+	}
+
+	var messages []api.Message
+	if app.conversation != nil {
+		messages = toAPIMessages(app.conversation)
+	}
+	if len(messages) == 0 || messages[0].Role != "system" {
+		messages = append([]api.Message{{Role: "system", Content: app.cfg.GetSystemPrompt()}}, messages...)
+	}
+	return append(messages, api.Message{Role: "user", Content: query})
+}
+
+// responseUsage returns the token usage of a response, if any, with its cost
+// as reported by the API or estimated for the model that answered
+func (app *App) responseUsage(resp *api.ChatResponse) history.Usage {
+	if resp == nil {
+		return history.Usage{}
+	}
+	usage := history.Usage{PromptTokens: resp.Usage.PromptTokens, CompletionTokens: resp.Usage.CompletionTokens}
+	usage.Cost, _, _ = responseCost(resp, app.cfg.Model)
+	return usage
+}
+
+// responseCost returns the cost of a response: the total reported by the API,
+// or else an estimate from the token prices of the model that answered,
+// defaulting to model. ok is false if neither is known.
+func responseCost(resp *api.ChatResponse, model string) (cost float64, estimated, ok bool) {
+	if resp.Usage.Cost != nil {
+		return resp.Usage.Cost.TotalCost, false, true
+	}
+	if resp.Model != "" {
+		model = resp.Model
+	}
+	cost, ok = pricing.Estimate(model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	return cost, true, ok
+}
+
+// saveConversation appends a one-shot exchange to the selected conversation,
+// adding its usage, and prints its ID
+func (app *App) saveConversation(query, answer string, citations []string, resp *api.ChatResponse) {
+	if app.conversation == nil {
+		return
+	}
+
+	var finishReason string
+	if resp != nil {
+		finishReason = resp.FinishReason()
+	}
+	now := time.Now()
+	messages := append(app.conversation.Messages,
+		history.Message{Role: "user", Content: query, Time: now},
+		history.Message{Role: "assistant", Content: answer, Citations: citations, Time: now,
+			Status: responseStatus(answer, finishReason, nil)},
+	)
+	if !app.history.UpdateConversation(app.conversation.ID, messages) {
+		app.history.AddConversation(app.conversation.ID, app.conversation.Model, messages)
+	}
+	total := app.conversation.Usage
+	total.Add(app.responseUsage(resp))
+	app.history.GetConversation(app.conversation.ID).Usage = total
+	if err := app.history.Save(); err != nil {
+		logging.Warn("Failed to save history", logging.Err(err))
+		display.ShowWarning(fmt.Sprintf("Could not save history: %v", err))
+		return
+	}
+	if app.events != nil {
+		app.events.emit(streamEvent{Type: "conversation", ID: app.conversation.ID})
+		return
+	}
+	// Printed even in quiet mode so scripts can capture the ID
+	fmt.Fprintf(os.Stderr, "Conversation: %s\n", app.conversation.ID)
+}
@@ -0,0 +1,365 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+)
+
+func TestToAPIMessages(t *testing.T) {
+	conv := &history.ConversationEntry{Messages: []history.Message{
+		{Role: "system", Content: "Be brief"},
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Status: history.StatusFailed},
+		{Role: "user", Content: "second"},
+		{Role: "assistant", Content: "answer"},
+	}}
+
+	conv.Messages[4].Time = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	got := toAPIMessages(conv)
+	want := []string{"Be brief", "second", "answer"}
+	if len(got) != len(want) {
+		t.Fatalf("toAPIMessages() = %+v, want %d messages", got, len(want))
+	}
+	for i, content := range want {
+		if got[i].Content != content {
+			t.Errorf("message %d = %q, want %q", i, got[i].Content, content)
+		}
+	}
+	if !got[2].Time.Equal(conv.Messages[4].Time) {
+		t.Errorf("answer time = %v, want %v", got[2].Time, conv.Messages[4].Time)
+	}
+}
+
+func TestResponseStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		answer       string
+		finishReason string
+		err          error
+		want         history.ResponseStatus
+	}{
+		{"complete", "answer", "stop", nil, ""},
+		{"empty", "", "stop", nil, history.StatusEmpty},
+		{"truncated", "partial", "length", nil, history.StatusTruncated},
+		{"failed", "", "", errors.New("connection refused"), history.StatusFailed},
+		{"cancelled", "partial", "", context.Canceled, history.StatusCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := responseStatus(tt.answer, tt.finishReason, tt.err); got != tt.want {
+				t.Errorf("responseStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResumeUnanswered(t *testing.T) {
+	session := newTestSessionWithHistory(t)
+	session.history.AddConversation("id3", "sonar-pro", []history.Message{
+		{Role: "system", Content: "Be helpful"},
+		{Role: "user", Content: "Hello"},
+		{Role: "assistant", Content: "Hi there!"},
+		{Role: "user", Content: "Tell me more"},
+		{Role: "assistant", Status: history.StatusCancelled},
+	})
+
+	output := captureOutput(func() {
+		session.cmdResume([]string{"/resume"})
+	})
+
+	for _, want := range []string{"(no answer: cancelled)", "The last response was cancelled; use /retry"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output = %q, want %q", output, want)
+		}
+	}
+	if session.lastUserInput != "Tell me more" {
+		t.Errorf("lastUserInput = %q, want the unanswered message", session.lastUserInput)
+	}
+	// The unanswered exchange is kept for /retry, but not sent
+	if n := len(session.getMessages()); n != 5 {
+		t.Errorf("resumed %d messages, want 5", n)
+	}
+	if got := session.requestMessages(); len(got) != 3 || got[2].Content != "Hi there!" {
+		t.Errorf("requestMessages() = %+v, want the answered exchange only", got)
+	}
+}
+
+func TestQueryMessages(t *testing.T) {
+	app := &App{cfg: &config.Config{SystemPrompt: "Be brief"}}
+
+	got := app.queryMessages("hello")
+	if len(got) != 2 || got[0].Content != "Be brief" || got[1].Content != "hello" {
+		t.Errorf("queryMessages() = %+v, want system prompt and query", got)
+	}
+
+	app.conversation = &history.ConversationEntry{Messages: []history.Message{
+		{Role: "user", Content: "earlier"},
+		{Role: "assistant", Content: "reply"},
+	}}
+	got = app.queryMessages("follow-up")
+	if len(got) != 4 || got[0].Role != "system" || got[1].Content != "earlier" || got[3].Content != "follow-up" {
+		t.Errorf("queryMessages() = %+v, want system prompt, conversation and query", got)
+	}
+}
+
+func TestContinueConversation(t *testing.T) {
+	t.Setenv(history.EnvHistoryPath, filepath.Join(t.TempDir(), "history.json"))
+
+	app := &App{cfg: &config.Config{APIKey: "test-key", Model: "sonar"}, continueLast: true}
+	if err := app.loadConversation(); err == nil {
+		t.Error("loadConversation() should fail without history")
+	}
+
+	hist := history.NewHistory()
+	hist.AddConversation("conv-1", "sonar", []history.Message{
+		{Role: "system", Content: "Be brief"},
+		{Role: "user", Content: "What is Go?"},
+		{Role: "assistant", Content: "A language."},
+	})
+	hist.Conversations[0].Usage = history.Usage{PromptTokens: 100, CompletionTokens: 50, Cost: 0.0002}
+	if err := hist.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	var sent api.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Model:   "sonar-pro",
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "Robert Griesemer, Rob Pike and Ken Thompson."}}},
+			Usage:   api.Usage{PromptTokens: 1000, CompletionTokens: 1000, TotalTokens: 2000},
+		})
+	}))
+	defer server.Close()
+
+	if err := app.loadConversation(); err != nil {
+		t.Fatalf("loadConversation() error = %v", err)
+	}
+	app.client = api.NewClient(app.cfg)
+	app.client.SetBaseURL(server.URL)
+
+	captureOutput(func() {
+		app.runNormal(context.Background(), "Who made it?")
+	})
+
+	if len(sent.Messages) != 4 || sent.Messages[1].Content != "What is Go?" || sent.Messages[3].Content != "Who made it?" {
+		t.Errorf("sent messages = %+v, want the conversation followed by the query", sent.Messages)
+	}
+
+	saved := history.NewHistory()
+	if err := saved.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	conv := saved.GetConversation("conv-1")
+	if conv == nil || len(conv.Messages) != 5 {
+		t.Fatalf("saved conversation = %+v, want 5 messages", conv)
+	}
+	if conv.Messages[4].Content != "Robert Griesemer, Rob Pike and Ken Thompson." {
+		t.Errorf("last saved message = %q, want the answer", conv.Messages[4].Content)
+	}
+	// sonar-pro costs $3 and $15 per million prompt and completion tokens
+	if conv.Usage.PromptTokens != 1100 || conv.Usage.CompletionTokens != 1050 || math.Abs(conv.Usage.Cost-0.0182) > 1e-9 {
+		t.Errorf("saved usage = %+v, want the query added, priced for the model that answered", conv.Usage)
+	}
+	if conv.Messages[3].Time.IsZero() || conv.Messages[4].Time.IsZero() {
+		t.Errorf("saved messages = %+v, want the new ones timestamped", conv.Messages[3:])
+	}
+}
+
+func TestFindConversation(t *testing.T) {
+	hist := history.NewHistory()
+	for _, id := range []string{"aaa", "bbb", "ccc"} {
+		hist.AddConversation(id, "sonar", nil)
+	}
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"bbb", "bbb"},
+		{"1", "aaa"},
+		{"3", "ccc"},
+		{"4", ""},
+		{"0", ""},
+		{"zzz", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			conv := findConversation(hist, tt.ref)
+			got := ""
+			if conv != nil {
+				got = conv.ID
+			}
+			if got != tt.want {
+				t.Errorf("findConversation(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewConversation(t *testing.T) {
+	t.Setenv(history.EnvHistoryPath, filepath.Join(t.TempDir(), "history.json"))
+
+	app := &App{cfg: &config.Config{Model: "sonar"}, conversationRef: "new"}
+	if err := app.loadConversation(); err != nil {
+		t.Fatalf("loadConversation() error = %v", err)
+	}
+
+	output := captureOutput(func() {
+		app.saveConversation("hello", "hi", []string{"https://example.com"}, &api.ChatResponse{
+			Choices: []api.StreamChoice{{FinishReason: "length"}},
+			Usage:   api.Usage{PromptTokens: 10, CompletionTokens: 5},
+		})
+	})
+	if !strings.Contains(output, "Conversation: "+app.conversation.ID) {
+		t.Errorf("output = %q, want the conversation ID", output)
+	}
+
+	saved := history.NewHistory()
+	if err := saved.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	conv := saved.GetConversation(app.conversation.ID)
+	if conv == nil || len(conv.Messages) != 3 || conv.Messages[0].Role != "system" {
+		t.Fatalf("saved conversation = %+v, want system prompt and exchange", conv)
+	}
+	if citations := conv.Messages[2].Citations; len(citations) != 1 || citations[0] != "https://example.com" {
+		t.Errorf("saved citations = %q, want those of the answer", citations)
+	}
+	if conv.Messages[2].Status != history.StatusTruncated {
+		t.Errorf("saved status = %q, want the answer marked truncated", conv.Messages[2].Status)
+	}
+	if conv.Usage.TotalTokens() != 15 {
+		t.Errorf("saved usage = %+v, want that of the exchange", conv.Usage)
+	}
+
+	// The new conversation can then be targeted by ID
+	next := &App{cfg: &config.Config{Model: "sonar"}, conversationRef: conv.ID}
+	if err := next.loadConversation(); err != nil {
+		t.Errorf("loadConversation() by ID error = %v", err)
+	}
+
+	both := &App{cfg: &config.Config{}, conversationRef: "new", continueLast: true}
+	if err := both.loadConversation(); err == nil {
+		t.Error("loadConversation() should reject --continue with --conversation")
+	}
+}
+
+func TestSessionUsage(t *testing.T) {
+	t.Setenv(history.EnvHistoryPath, filepath.Join(t.TempDir(), "history.json"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "Done."}}},
+			Usage:   api.Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500},
+		})
+	}))
+	defer server.Close()
+
+	session := newTestSession()
+	session.history = history.NewHistory()
+	session.app.cfg.Model = "sonar"
+	session.client = api.NewClient(session.app.cfg)
+	session.client.SetBaseURL(server.URL)
+	session.interruptCtx = NewInterruptibleContext()
+
+	captureOutput(func() {
+		session.chat("First")
+		session.chat("Second")
+		session.saveHistory()
+	})
+
+	saved := history.NewHistory()
+	if err := saved.Load(); err != nil {
+		t.Fatal(err)
+	}
+	conv := saved.GetConversation(session.conversationID)
+	if conv == nil || conv.Usage.PromptTokens != 2000 || conv.Usage.CompletionTokens != 1000 || math.Abs(conv.Usage.Cost-0.003) > 1e-9 {
+		t.Fatalf("saved conversation = %+v, want the usage of both requests", conv)
+	}
+
+	output := captureOutput(func() {
+		session.cmdHistory()
+	})
+	if !strings.Contains(output, "(4 messages, 3000 tokens, $0.0030)") {
+		t.Errorf("history output %q should show the tokens and cost of the conversation", output)
+	}
+
+	// Resuming continues from the saved totals, and a new conversation starts afresh
+	captureOutput(func() {
+		session.cmdClear()
+	})
+	if session.usage != (history.Usage{}) {
+		t.Errorf("usage after /clear = %+v, want none", session.usage)
+	}
+	captureOutput(func() {
+		session.cmdResume([]string{"/resume"})
+	})
+	if session.usage != conv.Usage {
+		t.Errorf("usage after /resume = %+v, want %+v", session.usage, conv.Usage)
+	}
+}
+
+func TestResponseCost(t *testing.T) {
+	tests := []struct {
+		name          string
+		resp          *api.ChatResponse
+		model         string
+		wantCost      float64
+		wantEstimated bool
+		wantOK        bool
+	}{
+		{
+			name:     "reported",
+			resp:     &api.ChatResponse{Model: "sonar-pro", Usage: api.Usage{PromptTokens: 1000, CompletionTokens: 1000, Cost: &api.Cost{TotalCost: 0.0231}}},
+			wantCost: 0.0231,
+			wantOK:   true,
+		},
+		{
+			name:          "estimated for the answering model",
+			resp:          &api.ChatResponse{Model: "sonar-pro", Usage: api.Usage{PromptTokens: 1000, CompletionTokens: 1000}},
+			model:         "sonar",
+			wantCost:      0.018,
+			wantEstimated: true,
+			wantOK:        true,
+		},
+		{
+			name:          "estimated for the configured model",
+			resp:          &api.ChatResponse{Usage: api.Usage{PromptTokens: 1000, CompletionTokens: 1000}},
+			model:         "sonar",
+			wantCost:      0.002,
+			wantEstimated: true,
+			wantOK:        true,
+		},
+		{
+			name:          "unknown price",
+			resp:          &api.ChatResponse{Model: "custom", Usage: api.Usage{PromptTokens: 1000}},
+			wantEstimated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost, estimated, ok := responseCost(tt.resp, tt.model)
+			if math.Abs(cost-tt.wantCost) > 1e-9 || estimated != tt.wantEstimated || ok != tt.wantOK {
+				t.Errorf("responseCost() = %v, %v, %v, want %v, %v, %v", cost, estimated, ok, tt.wantCost, tt.wantEstimated, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/crash"
+	"github.com/quocvuong92/perplexity-cli/internal/logging"
+)
+
+// issuesURL is where users are asked to report crashes
+const issuesURL = "https://github.com/quocvuong92/perplexity-cli/issues"
+
+// recoverPanic turns a panic into a crash report and a short message instead
+// of a raw Go stack trace. It must be deferred directly.
+func (app *App) recoverPanic() {
+	if r := recover(); r != nil {
+		os.Exit(app.reportPanic(os.Stderr, r, debug.Stack()))
+	}
+}
+
+// reportPanic saves a crash report for a recovered panic, tells the user
+// where to find it and returns the exit code
+func (app *App) reportPanic(w io.Writer, value any, stack []byte) int {
+	report := crash.Report{
+		Time:    time.Now(),
+		Version: Version,
+		Args:    redactArgs(os.Args),
+		Panic:   value,
+		Stack:   stack,
+		Config:  app.cfg.Redacted(),
+		Logs:    logging.Recent(),
+	}
+
+	fmt.Fprintf(w, "Error: perplexity crashed unexpectedly: %v\n", value)
+	path, err := crash.Save(crash.Dir(), report)
+	if err != nil {
+		// Without a report file, the stack trace is the only clue left
+		fmt.Fprintf(w, "Could not save a crash report (%v). Stack trace:\n%s\n", err, stack)
+	} else {
+		fmt.Fprintf(w, "A crash report was saved to %s\n", path)
+	}
+	fmt.Fprintf(w, "Please report this at %s and attach the report.\n", issuesURL)
+	return ExitError
+}
+
+// redactArgs returns the command line with the values of secret flags hidden
+func redactArgs(args []string) []string {
+	const hidden = "[REDACTED]"
+
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i := 0; i < len(redacted); i++ {
+		arg := redacted[i]
+		switch {
+		case arg == "--":
+			// Everything after is the query
+			return redacted
+		case arg == "--api-key" || arg == "-a":
+			if i+1 < len(redacted) {
+				redacted[i+1] = hidden
+				i++
+			}
+		case strings.HasPrefix(arg, "--api-key="):
+			redacted[i] = "--api-key=" + hidden
+		case strings.HasPrefix(arg, "-a") && !strings.HasPrefix(arg, "--"):
+			redacted[i] = "-a" + hidden
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/crash"
+)
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no secrets", []string{"perplexity", "-m", "sonar", "hello"}, "perplexity -m sonar hello"},
+		{"long flag", []string{"perplexity", "--api-key", "pplx-secret", "hello"}, "perplexity --api-key [REDACTED] hello"},
+		{"long flag with value", []string{"perplexity", "--api-key=pplx-secret"}, "perplexity --api-key=[REDACTED]"},
+		{"short flag", []string{"perplexity", "-a", "pplx-secret"}, "perplexity -a [REDACTED]"},
+		{"short flag with value", []string{"perplexity", "-applx-secret"}, "perplexity -a[REDACTED]"},
+		{"after terminator", []string{"perplexity", "--", "-a", "question"}, "perplexity -- -a question"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(redactArgs(tt.args), " "); got != tt.want {
+				t.Errorf("redactArgs(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportPanic(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(crash.EnvCrashDir, dir)
+
+	app := NewApp()
+	app.cfg.APIKey = "pplx-secret-key-1234"
+	app.cfg.APIKeys = []string{app.cfg.APIKey}
+
+	var out bytes.Buffer
+	code := app.reportPanic(&out, "boom", []byte("goroutine 1 [running]:\n"))
+	if code != ExitError {
+		t.Errorf("reportPanic() = %d, want %d", code, ExitError)
+	}
+	if !strings.Contains(out.String(), "crashed unexpectedly: boom") {
+		t.Errorf("output %q should explain the crash", out.String())
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "crash-*.txt"))
+	if len(files) != 1 {
+		t.Fatalf("crash reports = %v, want one", files)
+	}
+	if !strings.Contains(out.String(), files[0]) {
+		t.Errorf("output %q should name the report %s", out.String(), files[0])
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if strings.Contains(string(data), "pplx-secret-key-1234") {
+		t.Error("crash report should not contain the API key")
+	}
+	if !strings.Contains(string(data), "****1234") {
+		t.Error("crash report should contain the masked API key")
+	}
+}
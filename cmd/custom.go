@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/aliases"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/shell"
+	"github.com/quocvuong92/perplexity-cli/internal/tools"
+)
+
+// EnvCommandInput is the environment variable holding the arguments of a
+// user-defined command
+const EnvCommandInput = "PERPLEXITY_COMMAND_INPUT"
+
+// customCommand returns the user-defined command named by a slash command
+func (s *InteractiveSession) customCommand(name string) (config.CommandConfig, bool) {
+	name = strings.TrimPrefix(name, "/")
+	for _, c := range s.app.cfg.Commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return config.CommandConfig{}, false
+}
+
+// customDescription describes a user-defined command for help and completion
+func customDescription(c config.CommandConfig) string {
+	if c.Description != "" {
+		return c.Description
+	}
+	if c.Prompt != "" {
+		return "Send: " + strings.Join(strings.Fields(c.Prompt), " ")
+	}
+	return "Send the output of: " + c.Command
+}
+
+// cmdCustom runs a user-defined command: it sends the command's prompt with
+// the arguments filled in, or the output of its external command
+func (s *InteractiveSession) cmdCustom(c config.CommandConfig, parts []string) bool {
+	var input string
+	if len(parts) > 1 {
+		input = strings.TrimSpace(parts[1])
+	}
+
+	if c.Prompt != "" {
+		s.send(aliases.Expand(c.Prompt, input))
+		return false
+	}
+
+	output, err := s.runCustomCommand(c, input)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			display.ShowError(err.Error())
+		}
+		return false
+	}
+	if strings.TrimSpace(output) == "" {
+		fmt.Printf("/%s produced no output to send.\n", c.Name)
+		return false
+	}
+	s.send(output)
+	return false
+}
+
+// runCustomCommand runs the external command of a user-defined command and
+// returns its output. The arguments are passed on stdin and in
+// EnvCommandInput, never interpolated into the command line.
+func (s *InteractiveSession) runCustomCommand(c config.CommandConfig, input string) (string, error) {
+	timeout := tools.DefaultTimeout
+	if c.Timeout > 0 {
+		timeout = time.Duration(c.Timeout) * time.Second
+	}
+	ctx := s.interruptCtx.Start()
+	defer s.interruptCtx.Stop()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var out, stderr bytes.Buffer
+	cmd := shell.Command(ctx, c.Command)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Env = append(os.Environ(), EnvCommandInput+"="+input)
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return "", context.Canceled
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return "", fmt.Errorf("/%s timed out after %v", c.Name, timeout)
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("/%s failed: %v: %s", c.Name, err, msg)
+		}
+		return "", fmt.Errorf("/%s failed: %v", c.Name, err)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+)
+
+// newChatTestSession returns a session whose client talks to a test server,
+// and the last request the server received
+func newChatTestSession(t *testing.T) (*InteractiveSession, *api.ChatRequest) {
+	t.Helper()
+	t.Setenv(history.EnvHistoryPath, filepath.Join(t.TempDir(), "history.json"))
+
+	sent := &api.ChatRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(sent)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "Done."}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	session := newTestSession()
+	session.client = api.NewClient(session.app.cfg)
+	session.client.SetBaseURL(server.URL)
+	session.interruptCtx = NewInterruptibleContext()
+	return session, sent
+}
+
+func TestCustomPromptCommand(t *testing.T) {
+	session, sent := newChatTestSession(t)
+	session.app.cfg.Commands = []config.CommandConfig{
+		{Name: "review", Prompt: "Review this change: {input}"},
+	}
+
+	captureOutput(func() {
+		session.handleCommand("/Review the retry loop")
+	})
+	if len(sent.Messages) != 2 || sent.Messages[1].Content != "Review this change: the retry loop" {
+		t.Errorf("sent messages = %+v, want the expanded prompt", sent.Messages)
+	}
+}
+
+func TestCustomExternalCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	session, sent := newChatTestSession(t)
+	session.app.cfg.Commands = []config.CommandConfig{
+		{Name: "shout", Command: `tr a-z A-Z; echo " $` + EnvCommandInput + `"`},
+		{Name: "fail", Command: "echo broken >&2; exit 3"},
+	}
+
+	captureOutput(func() {
+		session.handleCommand("/shout hello")
+	})
+	if len(sent.Messages) != 2 || sent.Messages[1].Content != "HELLO hello" {
+		t.Errorf("sent messages = %+v, want the command output", sent.Messages)
+	}
+
+	c, _ := session.customCommand("/fail")
+	if _, err := session.runCustomCommand(c, ""); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("runCustomCommand() error = %v, want the command's stderr", err)
+	}
+}
+
+func TestCustomCommandHelpAndCompletion(t *testing.T) {
+	session := newTestSession()
+	session.app.cfg.Commands = []config.CommandConfig{
+		{Name: "standup", Description: "Summarize yesterday's commits", Command: "git log"},
+		{Name: "tldr", Prompt: "Summarize:\n{input}"},
+	}
+
+	output := captureOutput(func() {
+		session.cmdHelp()
+	})
+	if !strings.Contains(output, "/standup") || !strings.Contains(output, "Summarize yesterday's commits") {
+		t.Errorf("help %q should list the custom commands", output)
+	}
+
+	if got := customDescription(session.app.cfg.Commands[1]); got != "Send: Summarize: {input}" {
+		t.Errorf("customDescription() = %q", got)
+	}
+	if _, ok := session.customCommand("/missing"); ok {
+		t.Error("customCommand() should not find an undefined command")
+	}
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+)
+
+// debugDumpName returns the name of the --debug-dump file for a run started at t
+func debugDumpName(t time.Time) string {
+	return "perplexity-dump-" + t.Format("20060102-150405") + ".txt"
+}
+
+// openDebugDump creates the --debug-dump file in the current directory, which
+// clients created afterwards record to. Queries run without a dump if the
+// file cannot be created.
+func (app *App) openDebugDump() {
+	if !app.debugDump {
+		return
+	}
+	name := debugDumpName(time.Now())
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		display.ShowWarning(fmt.Sprintf("Could not create debug dump: %v", err))
+		return
+	}
+	// Writes go straight to the file, so it is complete even if the process
+	// exits without closing it
+	app.dump = api.NewDump(f)
+	if !app.cfg.Quiet {
+		fmt.Fprintf(os.Stderr, "Note: Saving requests and responses to %s (API keys are redacted)\n", name)
+	}
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDebugDumpName(t *testing.T) {
+	got := debugDumpName(time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC))
+	if got != "perplexity-dump-20260304-050607.txt" {
+		t.Errorf("debugDumpName() = %s, want a timestamped name", got)
+	}
+}
+
+func TestOpenDebugDump(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	app := NewApp()
+	app.cfg.Quiet = true
+	app.openDebugDump()
+	if app.dump != nil {
+		t.Fatal("openDebugDump() should do nothing without --debug-dump")
+	}
+
+	app.debugDump = true
+	app.openDebugDump()
+	if app.dump == nil {
+		t.Fatal("openDebugDump() should create the dump with --debug-dump")
+	}
+	files, _ := filepath.Glob("perplexity-dump-*.txt")
+	if len(files) != 1 {
+		t.Fatalf("dump files = %v, want one", files)
+	}
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("dump permissions = %v, want 0600", info.Mode().Perm())
+	}
+}
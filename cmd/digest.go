@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/pool"
+)
+
+// digestPrompt asks for the news of the topic in the message that follows
+const digestPrompt = `You write a news digest. For the topic and period given, report the most important
+news, releases and developments as a markdown list of at most 7 items, most important first.
+Start each item with a short bold headline, follow it with one or two sentences, and cite
+sources with [n] markers. Do not add an introduction or conclusion. If there is no notable
+news in the period, reply with a single sentence saying so.`
+
+// recencyPeriods describes the search recencies in digest queries
+var recencyPeriods = map[string]string{
+	"hour":  "the past hour",
+	"day":   "the past 24 hours",
+	"week":  "the past week",
+	"month": "the past month",
+	"year":  "the past year",
+}
+
+// digestSection is the news of one topic of a digest
+type digestSection struct {
+	Topic     string
+	Content   string
+	Citations []string
+	Err       error
+}
+
+// newDigestCmd creates the digest subcommand for news digests
+func (app *App) newDigestCmd() *cobra.Command {
+	var (
+		topicList  string
+		recency    string
+		model      string
+		outputFile string
+	)
+
+	digestCmd := &cobra.Command{
+		Use:   "digest",
+		Short: "Assemble a markdown news digest of several topics",
+		Long: `Search the news of each topic, limited to sources from a recent period, and
+assemble the answers into a single markdown digest with the sources of each
+topic. Topics are searched concurrently, up to --concurrency at a time. The
+digest is still written if some topics fail, but the command then exits with
+an error, so it suits a daily cron job.
+
+  perplexity digest --topics "golang,kubernetes" --recency day
+  perplexity digest --topics "rust,webassembly" --recency week -o digest.md`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			topics := parseTopics(topicList)
+			if len(topics) == 0 {
+				return invalidInput(fmt.Errorf("--topics needs at least one topic"))
+			}
+
+			app.cfg.SearchRecency = recency
+			cfg, err := app.taskConfig(model, "")
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := newSignalContext()
+			defer cancel()
+
+			sections := runDigest(ctx, app.pooledClients(cfg), topics, recency, app.poolOptions())
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			digest := formatDigest(time.Now(), recency, sections)
+
+			if outputFile != "" {
+				if err := os.WriteFile(outputFile, []byte(digest), 0600); err != nil {
+					return fmt.Errorf("failed to write digest: %w", err)
+				}
+				if !app.cfg.Quiet {
+					fmt.Fprintf(os.Stderr, "Digest saved to %s\n", outputFile)
+				}
+			} else {
+				io.WriteString(cmd.OutOrStdout(), digest)
+			}
+
+			if failed := countFailed(sections); failed > 0 {
+				return fmt.Errorf("%d of %d topics failed", failed, len(sections))
+			}
+			return nil
+		},
+	}
+
+	digestCmd.Flags().StringVar(&topicList, "topics", "", "Comma-separated topics of the digest")
+	digestCmd.Flags().StringVar(&recency, "recency", "day", "Only use sources from the past: "+strings.Join(config.SearchRecencies, ", "))
+	digestCmd.Flags().StringVarP(&model, "model", "m", app.cfg.Model, "Model searching the news")
+	digestCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write the digest to a file instead of stdout")
+	_ = digestCmd.MarkFlagRequired("topics")
+	_ = digestCmd.MarkFlagFilename("output", "md")
+	_ = digestCmd.RegisterFlagCompletionFunc("recency", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return config.SearchRecencies, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return digestCmd
+}
+
+// parseTopics splits a comma-separated topic list, dropping blanks and duplicates
+func parseTopics(list string) []string {
+	var topics []string
+	for _, topic := range strings.Split(list, ",") {
+		if topic = strings.TrimSpace(topic); topic != "" && !slices.Contains(topics, topic) {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// runDigest searches the news of the topics concurrently. Failed topics
+// are kept with their error.
+func runDigest(ctx context.Context, newClient clientFactory, topics []string, recency string, opts pool.Options) []digestSection {
+	sections := make([]digestSection, len(topics))
+	errs := runPooled(ctx, len(topics), opts, "Searching topics", func(ctx context.Context, i int) error {
+		resp, err := newClient().QueryWithHistoryContext(ctx, []api.Message{
+			{Role: "system", Content: digestPrompt},
+			{Role: "user", Content: fmt.Sprintf("Topic: %s\nPeriod: %s", topics[i], recencyPeriods[recency])},
+		})
+		if err != nil {
+			return err
+		}
+		_, sections[i].Content = display.SplitThinking(resp.GetContent())
+		sections[i].Citations = resp.Citations
+		return nil
+	})
+	for i, err := range errs {
+		sections[i].Topic = topics[i]
+		sections[i].Err = err
+	}
+	return sections
+}
+
+// formatDigest assembles the markdown digest of the sections. Citation
+// markers link to their sources, which are listed under each topic.
+func formatDigest(now time.Time, recency string, sections []digestSection) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# News digest, %s\n\n", now.Format("2006-01-02"))
+	fmt.Fprintf(&b, "News from %s.\n", recencyPeriods[recency])
+
+	for _, section := range sections {
+		fmt.Fprintf(&b, "\n## %s\n\n", section.Topic)
+		if section.Err != nil {
+			fmt.Fprintf(&b, "_Failed to get the news: %v_\n", section.Err)
+			continue
+		}
+		b.WriteString(strings.TrimSpace(display.LinkCitationMarkers(section.Content, section.Citations)) + "\n")
+		if len(section.Citations) > 0 {
+			b.WriteString("\nSources:\n\n")
+			for i, citation := range section.Citations {
+				fmt.Fprintf(&b, "%d. %s\n", i+1, citation)
+			}
+		}
+	}
+	return b.String()
+}
+
+// countFailed returns the number of sections that failed
+func countFailed(sections []digestSection) int {
+	failed := 0
+	for _, section := range sections {
+		if section.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/pool"
+)
+
+func TestParseTopics(t *testing.T) {
+	got := parseTopics(" golang, kubernetes,,golang ,rust ")
+	if want := []string{"golang", "kubernetes", "rust"}; !slices.Equal(got, want) {
+		t.Errorf("parseTopics() = %q, want %q", got, want)
+	}
+	if got := parseTopics(" , "); got != nil {
+		t.Errorf("parseTopics() of blanks = %q, want none", got)
+	}
+}
+
+func TestRunDigest(t *testing.T) {
+	var mu sync.Mutex
+	var requests []api.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+		if strings.Contains(req.Messages[1].Content, "broken") {
+			http.Error(w, `{"error": {"message": "bad topic"}}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices:   []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "- **Go 1.25 released** [1]"}}},
+			Citations: []string{"https://go.dev/blog"},
+		})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", APIURL: server.URL, Model: "sonar", SearchRecency: "day"}
+	app := &App{cfg: cfg}
+
+	var sections []digestSection
+	captureOutput(func() {
+		sections = runDigest(context.Background(), app.pooledClients(app.cfg), []string{"golang", "broken"}, "day", pool.Options{Workers: 2})
+	})
+	// Topics are searched concurrently, in any order
+	slices.SortFunc(requests, func(a, b api.ChatRequest) int { return strings.Compare(b.Messages[1].Content, a.Messages[1].Content) })
+	if len(requests) != 2 || requests[0].SearchRecencyFilter != "day" || requests[0].Messages[1].Content != "Topic: golang\nPeriod: the past 24 hours" {
+		t.Fatalf("requests = %+v, want one per topic with the recency filter", requests)
+	}
+	if len(sections) != 2 || sections[0].Err != nil || sections[1].Err == nil {
+		t.Fatalf("sections = %+v, want the second topic failed", sections)
+	}
+	if countFailed(sections) != 1 {
+		t.Errorf("countFailed() = %d, want 1", countFailed(sections))
+	}
+}
+
+func TestFormatDigest(t *testing.T) {
+	now := time.Date(2026, 10, 16, 7, 0, 0, 0, time.UTC)
+	digest := formatDigest(now, "week", []digestSection{
+		{Topic: "golang", Content: "- **Go 1.25 released** [1]\n", Citations: []string{"https://go.dev/blog"}},
+		{Topic: "kubernetes", Err: errors.New("timeout")},
+	})
+
+	want := `# News digest, 2026-10-16
+
+News from the past week.
+
+## golang
+
+- **Go 1.25 released** [[1]](https://go.dev/blog)
+
+Sources:
+
+1. https://go.dev/blog
+
+## kubernetes
+
+_Failed to get the news: timeout_
+`
+	if digest != want {
+		t.Errorf("formatDigest() =\n%s\nwant\n%s", digest, want)
+	}
+}
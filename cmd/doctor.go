@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/quocvuong92/perplexity-cli/internal/clipboard"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+	"github.com/quocvuong92/perplexity-cli/internal/validation"
+)
+
+// doctorTimeout bounds each network check of the doctor command
+const doctorTimeout = 5 * time.Second
+
+// checkStatus is the outcome of a doctor check
+type checkStatus int
+
+const (
+	checkOK checkStatus = iota
+	checkWarn
+	checkFail
+)
+
+func (s checkStatus) String() string {
+	switch s {
+	case checkWarn:
+		return "warn"
+	case checkFail:
+		return "FAIL"
+	default:
+		return "ok"
+	}
+}
+
+// checkResult is what a doctor check found and, if it is not ok, how to fix it
+type checkResult struct {
+	Name   string
+	Status checkStatus
+	Detail string
+	Fix    string
+}
+
+// newDoctorCmd creates the doctor subcommand for diagnosing the environment
+func (app *App) newDoctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the API key, network, clipboard, terminal and history file",
+		Long: `Check that everything the CLI depends on is set up, and print how to fix
+what is not: the API key, DNS and TLS reachability of the API, the clipboard
+tool, terminal capabilities and whether the history file can be written.
+
+The command exits with an error if any check fails. Warnings only affect
+optional features.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := newSignalContext()
+			defer cancel()
+
+			results := []checkResult{
+				checkAPIKeys(app.configuredKeys()),
+				checkReachability(ctx, app.cfg.APIURL),
+				checkClipboard(),
+				checkTerminal(),
+				checkHistory(history.NewHistory().Path()),
+			}
+			if failed := writeDoctorReport(cmd.OutOrStdout(), results); failed > 0 {
+				return fmt.Errorf("%d of %d checks failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+}
+
+// writeDoctorReport prints the results with fixes and returns the number of failed checks
+func writeDoctorReport(w io.Writer, results []checkResult) int {
+	var warned, failed int
+	for _, r := range results {
+		fmt.Fprintf(w, "%-6s %s: %s\n", "["+r.Status.String()+"]", r.Name, r.Detail)
+		if r.Status != checkOK && r.Fix != "" {
+			fmt.Fprintf(w, "       Fix: %s\n", r.Fix)
+		}
+		switch r.Status {
+		case checkWarn:
+			warned++
+		case checkFail:
+			failed++
+		}
+	}
+
+	fmt.Fprintln(w)
+	if warned == 0 && failed == 0 {
+		fmt.Fprintln(w, "All checks passed.")
+	} else {
+		fmt.Fprintf(w, "%d failed, %d warnings.\n", failed, warned)
+	}
+	return failed
+}
+
+// configuredKeys returns the API keys from the environment, or else the
+// stored key
+func (app *App) configuredKeys() []string {
+	if keys := config.GetAPIKeysFromEnv(); len(keys) > 0 {
+		return keys
+	}
+	if key, err := app.cfg.StoredAPIKey(); err == nil && key != "" {
+		return []string{key}
+	}
+	return nil
+}
+
+// checkAPIKeys checks that API keys are set and look like Perplexity keys
+func checkAPIKeys(keys []string) checkResult {
+	result := checkResult{Name: "API key"}
+	if len(keys) == 0 {
+		result.Status = checkFail
+		result.Detail = "no API key set"
+		result.Fix = fmt.Sprintf("Run perplexity init, or create a key at %s and export %s=pplx-...", apiKeyURL, config.EnvAPIKey)
+		return result
+	}
+
+	results, _ := validation.ValidateAPIKeys(keys)
+	masked := make([]string, len(keys))
+	for i, key := range keys {
+		masked[i] = config.MaskKey(key)
+	}
+	result.Detail = fmt.Sprintf("%d set (%s)", len(keys), strings.Join(masked, ", "))
+
+	for i, r := range results {
+		switch {
+		case !r.Valid:
+			result.Status = checkFail
+			result.Detail = fmt.Sprintf("key %d (%s) is invalid: %v", i+1, masked[i], r.Error)
+			result.Fix = fmt.Sprintf("Check %s or %s for typos, quotes or stray whitespace", config.EnvAPIKeys, config.EnvAPIKey)
+			return result
+		case r.Warning != "" && result.Status == checkOK:
+			result.Status = checkWarn
+			result.Detail = fmt.Sprintf("key %d (%s): %s", i+1, masked[i], r.Warning)
+			result.Fix = "Perplexity keys start with pplx-; make sure this is not a key for another service"
+		}
+	}
+	return result
+}
+
+// checkReachability checks that the API host resolves and accepts TLS connections
+func checkReachability(ctx context.Context, apiURL string) checkResult {
+	result := checkResult{Name: "API reachability"}
+
+	u, err := url.Parse(apiURL)
+	if err != nil || u.Hostname() == "" {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("invalid API URL %q", apiURL)
+		result.Fix = "Fix the API URL in your config, e.g. " + config.DefaultAPIURL
+		return result
+	}
+	host := u.Hostname()
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("cannot resolve %s: %v", host, err)
+		result.Fix = "Check your internet connection and DNS settings"
+		return result
+	}
+
+	start := time.Now()
+	address := net.JoinHostPort(host, port)
+	var conn net.Conn
+	if u.Scheme == "http" {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = (&tls.Dialer{Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("%s resolves to %s, but connecting failed: %v", host, addrs[0], err)
+		result.Fix = fmt.Sprintf("Check that no firewall or proxy blocks %s, and that your system clock and CA certificates are up to date", address)
+		var certErr *tls.CertificateVerificationError
+		if errors.As(err, &certErr) {
+			result.Fix = "The certificate could not be verified: update your CA certificates, or check for a proxy that intercepts TLS"
+		}
+		return result
+	}
+	_ = conn.Close()
+
+	result.Detail = fmt.Sprintf("%s resolves to %s, connected in %v", host, addrs[0], time.Since(start).Round(time.Millisecond))
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		result.Detail += " (" + tls.VersionName(tlsConn.ConnectionState().Version) + ")"
+	}
+	return result
+}
+
+// checkClipboard checks that /copy can reach the clipboard
+func checkClipboard() checkResult {
+	result := checkResult{Name: "Clipboard"}
+	backend, err := clipboard.Available()
+	if err != nil {
+		result.Status = checkWarn
+		result.Detail = err.Error()
+		var clipErr *clipboard.Error
+		if errors.As(err, &clipErr) {
+			result.Detail = clipErr.Message + "; /copy will not work"
+			result.Fix = clipErr.Hint
+		}
+		return result
+	}
+	result.Detail = "using " + backend
+	if backend == clipboard.OSC52 {
+		result.Detail += " terminal escape sequences (the terminal must support them)"
+	}
+	return result
+}
+
+// checkTerminal checks whether output goes to a terminal with colors
+func checkTerminal() checkResult {
+	result := checkResult{Name: "Terminal"}
+	termName := os.Getenv("TERM")
+
+	switch {
+	case !term.IsTerminal(int(os.Stdout.Fd())):
+		result.Status = checkWarn
+		result.Detail = "stdout is not a terminal, so colors and spinners are off"
+		result.Fix = "This is expected when output is piped; run in a terminal for colored output"
+	case !term.IsTerminal(int(os.Stdin.Fd())):
+		result.Status = checkWarn
+		result.Detail = "stdin is not a terminal, so interactive mode is unavailable"
+		result.Fix = "Run perplexity -i directly in a terminal, without piping input"
+	case termName == "dumb":
+		result.Status = checkWarn
+		result.Detail = "TERM=dumb, so colors and rendering are limited"
+		result.Fix = "Set TERM to a capable terminal type, e.g. export TERM=xterm-256color"
+	default:
+		colors := "colors on"
+		if os.Getenv("NO_COLOR") != "" {
+			colors = "colors off (NO_COLOR is set)"
+		}
+		result.Detail = fmt.Sprintf("TERM=%s, %d columns, %s", termName, display.TerminalWidth(), colors)
+	}
+	return result
+}
+
+// checkHistory checks that the history file can be written
+func checkHistory(path string) checkResult {
+	result := checkResult{Name: "History file"}
+	fix := fmt.Sprintf("Make the directory writable, or set %s to a writable location", history.EnvHistoryPath)
+
+	if path == "" {
+		result.Status = checkFail
+		result.Detail = "no home directory to store history in"
+		result.Fix = fmt.Sprintf("Set %s to a writable location", history.EnvHistoryPath)
+		return result
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("cannot create %s: %v", dir, err)
+		result.Fix = fix
+		return result
+	}
+
+	// Opening the file for writing without truncating leaves existing history intact
+	_, statErr := os.Stat(path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("cannot write %s: %v", path, err)
+		result.Fix = fix
+		return result
+	}
+	_ = f.Close()
+	if os.IsNotExist(statErr) {
+		_ = os.Remove(path)
+	}
+
+	result.Detail = path + " is writable"
+	return result
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckAPIKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   []string
+		want   checkStatus
+		detail string
+	}{
+		{"none", nil, checkFail, "no API key set"},
+		{"valid", []string{"pplx-1234567890abcdef1234"}, checkOK, "1 set (****1234)"},
+		{"too short", []string{"pplx-1234567890abcdef1234", "pplx-1"}, checkFail, "key 2 (******) is invalid"},
+		{"other prefix", []string{"sk-1234567890abcdef12345678"}, checkWarn, "does not start with 'pplx-'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkAPIKeys(tt.keys)
+			if got.Status != tt.want {
+				t.Errorf("checkAPIKeys() status = %v, want %v (%s)", got.Status, tt.want, got.Detail)
+			}
+			if !strings.Contains(got.Detail, tt.detail) {
+				t.Errorf("checkAPIKeys() detail = %q, want it to contain %q", got.Detail, tt.detail)
+			}
+			if got.Status != checkOK && got.Fix == "" {
+				t.Error("checkAPIKeys() should suggest a fix")
+			}
+			for _, key := range tt.keys {
+				if len(key) > 8 && strings.Contains(got.Detail, key) {
+					t.Errorf("checkAPIKeys() detail %q should not contain the key", got.Detail)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckReachability(t *testing.T) {
+	t.Run("reachable", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		got := checkReachability(context.Background(), server.URL+"/chat/completions")
+		if got.Status != checkOK {
+			t.Errorf("checkReachability() = %+v, want ok", got)
+		}
+	})
+
+	t.Run("invalid URL", func(t *testing.T) {
+		got := checkReachability(context.Background(), "not a url")
+		if got.Status != checkFail || got.Fix == "" {
+			t.Errorf("checkReachability() = %+v, want a failure with a fix", got)
+		}
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		url := server.URL
+		server.Close()
+
+		got := checkReachability(context.Background(), url)
+		if got.Status != checkFail || !strings.Contains(got.Detail, "connecting failed") {
+			t.Errorf("checkReachability() = %+v, want a connection failure", got)
+		}
+	})
+}
+
+func TestCheckHistory(t *testing.T) {
+	t.Run("writable", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data", "history.json")
+		got := checkHistory(path)
+		if got.Status != checkOK {
+			t.Errorf("checkHistory() = %+v, want ok", got)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Error("checkHistory() should not leave an empty history file behind")
+		}
+	})
+
+	t.Run("existing history", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.json")
+		if err := os.WriteFile(path, []byte(`{"conversations":[]}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if got := checkHistory(path); got.Status != checkOK {
+			t.Errorf("checkHistory() = %+v, want ok", got)
+		}
+		data, _ := os.ReadFile(path)
+		if string(data) != `{"conversations":[]}` {
+			t.Errorf("checkHistory() changed the history file to %q", data)
+		}
+	})
+
+	t.Run("directory is a file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		got := checkHistory(filepath.Join(file, "history.json"))
+		if got.Status != checkFail || got.Fix == "" {
+			t.Errorf("checkHistory() = %+v, want a failure with a fix", got)
+		}
+	})
+
+	t.Run("no path", func(t *testing.T) {
+		if got := checkHistory(""); got.Status != checkFail {
+			t.Errorf("checkHistory() = %+v, want a failure", got)
+		}
+	})
+}
+
+func TestWriteDoctorReport(t *testing.T) {
+	var out bytes.Buffer
+	failed := writeDoctorReport(&out, []checkResult{
+		{Name: "API key", Status: checkOK, Detail: "1 set"},
+		{Name: "Clipboard", Status: checkWarn, Detail: "no clipboard tool found", Fix: "Install xclip"},
+		{Name: "History file", Status: checkFail, Detail: "cannot write", Fix: "Set PERPLEXITY_HISTORY_PATH"},
+	})
+
+	if failed != 1 {
+		t.Errorf("writeDoctorReport() = %d, want 1 failed check", failed)
+	}
+	want := "[ok]   API key: 1 set\n" +
+		"[warn] Clipboard: no clipboard tool found\n" +
+		"       Fix: Install xclip\n" +
+		"[FAIL] History file: cannot write\n" +
+		"       Fix: Set PERPLEXITY_HISTORY_PATH\n" +
+		"\n1 failed, 1 warnings.\n"
+	if out.String() != want {
+		t.Errorf("writeDoctorReport() output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
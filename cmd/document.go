@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/document"
+	"github.com/quocvuong92/perplexity-cli/internal/logging"
+	"github.com/quocvuong92/perplexity-cli/internal/tokens"
+)
+
+// docMapPrompt asks for a summary of one part of a long document
+const docMapPrompt = `You summarize one part of a longer document. Keep its key points, facts, figures,
+names, dates and conclusions, in the order they appear, as a concise markdown list.
+Use only the text given, not other sources, and do not add an introduction.`
+
+// docPrompt asks for a summary of a document, or of the summaries of its parts
+const docPrompt = `You summarize documents. The text below is either the whole document or, for a
+long one, summaries of its consecutive parts. Use only that text, not other sources.
+Start with a one-paragraph overview of the whole document, then give its key points as
+a markdown list, and end with any conclusions, decisions or open questions it states.`
+
+// newDocCmd creates the doc subcommand for summarizing local documents
+func (app *App) newDocCmd() *cobra.Command {
+	var model string
+
+	docCmd := &cobra.Command{
+		Use:   "doc <file>",
+		Short: "Summarize a local document (PDF, Word, HTML, markdown or text)",
+		Long: `Extract the text of a local document and summarize it. PDF, Word (.docx) and
+HTML files are converted to text; other files are read as plain text, such as
+markdown. PDF text is extracted with pdftotext from poppler if it is installed,
+and with a basic built-in reader otherwise.
+
+A document too long for the model's context window is split into parts that
+are summarized concurrently, and the summaries are then combined into one.
+
+  perplexity doc report.pdf
+  perplexity doc -r -m sonar-pro design.docx`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			text, err := document.Extract(args[0])
+			if err != nil {
+				return invalidInput(err)
+			}
+
+			cfg, err := app.taskConfig(model, text)
+			if err != nil {
+				return err
+			}
+			if app.cfg.Render {
+				if err := display.InitRenderer(app.cfg.Theme); err != nil {
+					logging.Warn("Failed to initialize renderer", logging.Err(err))
+				}
+			}
+
+			maxTokens := chunkTokens(cfg.Model)
+			if n := tokens.Estimate(text); n > maxTokens && !app.cfg.Quiet {
+				fmt.Fprintf(os.Stderr, "Note: The document is about %d tokens; summarizing it in parts of up to %d tokens\n", n, maxTokens)
+			}
+
+			ctx, cancel := newSignalContext()
+			defer cancel()
+
+			resp, err := mapReduce(ctx, app.pooledClients(cfg), text, docMapPrompt, docPrompt, maxTokens, app.poolOptions())
+			if err != nil {
+				return err
+			}
+			app.showResponse(resp)
+			return nil
+		},
+	}
+
+	docCmd.Flags().StringVarP(&model, "model", "m", app.cfg.Model, "Model summarizing the document")
+	docCmd.Flags().BoolVarP(&app.cfg.Render, "render", "r", app.cfg.Render, "Render markdown with colors and formatting")
+	return docCmd
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+)
+
+func TestDocCmd(t *testing.T) {
+	var sent api.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "The plan ships in May."}}},
+		})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "plan.md")
+	if err := os.WriteFile(path, []byte("# Plan\n\nShip in May.\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp()
+	app.cfg.APIURL = server.URL
+	app.cfg.APIKey = "pplx-" + strings.Repeat("a", 40)
+	app.cfg.Model = "sonar"
+	cmd := app.newDocCmd()
+	cmd.SetArgs([]string{path})
+
+	var err error
+	out := captureOutput(func() { err = cmd.Execute() })
+	if err != nil {
+		t.Fatalf("doc error = %v", err)
+	}
+	if !strings.Contains(out, "The plan ships in May.") {
+		t.Errorf("doc output = %q, want the summary", out)
+	}
+	if len(sent.Messages) != 2 || sent.Messages[0].Content != docPrompt || sent.Messages[1].Content != "# Plan\n\nShip in May." {
+		t.Errorf("request messages = %+v, want the document with the summary prompt", sent.Messages)
+	}
+
+	cmd = app.newDocCmd()
+	cmd.SetArgs([]string{filepath.Join(t.TempDir(), "missing.pdf")})
+	captureOutput(func() { err = cmd.Execute() })
+	var inputErr *inputError
+	if !errors.As(err, &inputErr) {
+		t.Errorf("doc of a missing file error = %v, want invalid input", err)
+	}
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/retry"
+	"github.com/quocvuong92/perplexity-cli/internal/validation"
+)
+
+// Exit codes, so scripts can branch on the class of error
+const (
+	ExitOK           = 0
+	ExitError        = 1   // Any other error
+	ExitInvalidInput = 2   // Invalid flags, arguments, query or config
+	ExitAuth         = 3   // Missing, malformed or rejected API key
+	ExitRateLimited  = 4   // Rate limited or out of credits
+	ExitNetwork      = 5   // Connection failure, timeout or server error
+	ExitCancelled    = 130 // Interrupted with Ctrl+C
+)
+
+// inputError marks an error caused by invalid user input
+type inputError struct {
+	err error
+}
+
+func (e *inputError) Error() string {
+	return e.err.Error()
+}
+
+func (e *inputError) Unwrap() error {
+	return e.err
+}
+
+// invalidInput wraps err so that it exits with ExitInvalidInput
+func invalidInput(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &inputError{err: err}
+}
+
+// flagError reports flag parsing errors as invalid input
+func flagError(cmd *cobra.Command, err error) error {
+	return invalidInput(err)
+}
+
+// exitCode maps an error to the exit code for its class
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	if errors.Is(err, config.ErrAPIKeyNotFound) || errors.Is(err, config.ErrInvalidAPIKey) {
+		return ExitAuth
+	}
+	var inputErr *inputError
+	if errors.As(err, &inputErr) {
+		return ExitInvalidInput
+	}
+	if errors.Is(err, context.Canceled) {
+		return ExitCancelled
+	}
+	if errors.Is(err, validation.ErrContextTooLong) {
+		return ExitInvalidInput
+	}
+
+	var apiErr *api.APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+			return ExitAuth
+		case apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusPaymentRequired:
+			return ExitRateLimited
+		case apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusUnprocessableEntity:
+			return ExitInvalidInput
+		case apiErr.StatusCode >= 500:
+			return ExitNetwork
+		}
+		return ExitError
+	}
+
+	var netErr net.Error
+	var urlErr *url.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) || errors.As(err, &urlErr) || retry.IsRetryableError(err) {
+		return ExitNetwork
+	}
+	return ExitError
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/validation"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, ExitOK},
+		{"generic", errors.New("boom"), ExitError},
+		{"invalid input", invalidInput(errors.New("bad flag")), ExitInvalidInput},
+		{"missing API key", invalidInput(config.ErrAPIKeyNotFound), ExitAuth},
+		{"malformed API key", invalidInput(fmt.Errorf("%w: %w", config.ErrInvalidAPIKey, validation.ErrAPIKeyTooShort)), ExitAuth},
+		{"cancelled", context.Canceled, ExitCancelled},
+		{"context too long", fmt.Errorf("%w: about 200000 tokens", validation.ErrContextTooLong), ExitInvalidInput},
+		{"unauthorized", &api.APIError{StatusCode: http.StatusUnauthorized}, ExitAuth},
+		{"forbidden", &api.APIError{StatusCode: http.StatusForbidden}, ExitAuth},
+		{"rate limited", &api.APIError{StatusCode: http.StatusTooManyRequests}, ExitRateLimited},
+		{"no more keys", fmt.Errorf("%w (no more API keys available)", &api.APIError{StatusCode: http.StatusTooManyRequests}), ExitRateLimited},
+		{"bad request", &api.APIError{StatusCode: http.StatusBadRequest}, ExitInvalidInput},
+		{"server error", &api.APIError{StatusCode: http.StatusBadGateway}, ExitNetwork},
+		{"other status", &api.APIError{StatusCode: http.StatusNotFound}, ExitError},
+		{"deadline", context.DeadlineExceeded, ExitNetwork},
+		{"connection", &url.Error{Op: "Post", URL: "https://api.perplexity.ai", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, ExitNetwork},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunNormalReturnsTypedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"message":"invalid api key"}}`))
+	}))
+	defer server.Close()
+
+	app := NewApp()
+	app.cfg.APIKey = "test-key"
+	app.cfg.Quiet = true
+	app.client = app.newClient()
+	app.client.SetBaseURL(server.URL)
+
+	var err error
+	captureOutput(func() {
+		err = app.runNormal(context.Background(), "test query")
+	})
+	if got := exitCode(err); got != ExitAuth {
+		t.Errorf("exitCode(%v) = %d, want %d", err, got, ExitAuth)
+	}
+}
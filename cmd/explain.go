@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/logging"
+	"github.com/quocvuong92/perplexity-cli/internal/shell"
+)
+
+// explainPrompt asks for an explanation of the shell command that follows
+const explainPrompt = `You explain shell commands to a developer. For the command given:
+1. Say in one sentence what the whole command does.
+2. Break it down part by part: each program, subcommand, flag, argument, pipe and
+   redirection, as a markdown list.
+3. If any part can delete or overwrite data, change permissions, run code downloaded
+   from the internet, affect remote systems or the whole machine, or is otherwise risky,
+   add a "Risks" section saying what could go wrong and a safer alternative.
+Do not run the command or suggest unrelated commands. Be concise.`
+
+// newExplainCmd creates the explain subcommand for explaining shell commands
+func (app *App) newExplainCmd() *cobra.Command {
+	var model string
+
+	explainCmd := &cobra.Command{
+		Use:   "explain -- <command...>",
+		Short: "Explain what a shell command does and flag risky operations",
+		Long: `Explain a shell command part by part, flagging operations that can delete data,
+change permissions or run downloaded code. Put the command after -- so its
+flags are not taken for flags of perplexity; it does not need to be quoted.
+The command is only explained, never run.
+
+  perplexity explain -- tar -xzvf archive.tar.gz -C /opt
+  perplexity explain -- find . -name '*.log' -mtime +7 -delete
+  perplexity explain -r -- 'curl -fsSL https://example.com/install.sh | sh'`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			command := explainCommand(args)
+
+			cfg, err := app.taskConfig(model, command)
+			if err != nil {
+				return err
+			}
+			if app.cfg.Render {
+				if err := display.InitRenderer(app.cfg.Theme); err != nil {
+					logging.Warn("Failed to initialize renderer", logging.Err(err))
+				}
+			}
+
+			ctx, cancel := newSignalContext()
+			defer cancel()
+
+			for _, reason := range shell.Risks(command) {
+				display.ShowWarning("this command " + reason)
+			}
+			resp, err := explainShellCommand(ctx, app.newClientFor(cfg), command)
+			if err != nil {
+				return err
+			}
+			app.showResponse(resp)
+			return nil
+		},
+	}
+
+	explainCmd.Flags().StringVarP(&model, "model", "m", app.cfg.Model, "Model explaining the command")
+	explainCmd.Flags().BoolVarP(&app.cfg.Render, "render", "r", app.cfg.Render, "Render markdown with colors and formatting")
+	return explainCmd
+}
+
+// explainCommand returns the command line to explain. A single argument is
+// taken as the whole command line; several are joined with shell quoting.
+func explainCommand(args []string) string {
+	if len(args) == 1 {
+		return args[0]
+	}
+	return shell.Join(args)
+}
+
+// explainShellCommand asks for an explanation of a command line
+func explainShellCommand(ctx context.Context, client *api.Client, command string) (*api.ChatResponse, error) {
+	return queryWithSpinner(ctx, client, "Explaining...", []api.Message{
+		{Role: "system", Content: explainPrompt},
+		{Role: "user", Content: "Command:\n" + command},
+	})
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+func TestExplainCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"ls -la | wc -l"}, "ls -la | wc -l"},
+		{[]string{"find", ".", "-name", "*.log", "-delete"}, "find . -name '*.log' -delete"},
+	}
+
+	for _, tt := range tests {
+		if got := explainCommand(tt.args); got != tt.want {
+			t.Errorf("explainCommand(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestExplainShellCommand(t *testing.T) {
+	var sent api.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "Lists files."}}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", APIURL: server.URL, Model: "sonar"}
+	app := &App{cfg: cfg}
+
+	var resp *api.ChatResponse
+	var err error
+	captureOutput(func() {
+		resp, err = explainShellCommand(context.Background(), app.newClient(), "ls -la")
+	})
+	if err != nil {
+		t.Fatalf("explainShellCommand() error = %v", err)
+	}
+	if resp.GetContent() != "Lists files." {
+		t.Errorf("explainShellCommand() content = %q", resp.GetContent())
+	}
+	if len(sent.Messages) != 2 || sent.Messages[0].Content != explainPrompt || sent.Messages[1].Content != "Command:\nls -la" {
+		t.Errorf("request messages = %+v, want the explain prompt and the command", sent.Messages)
+	}
+}
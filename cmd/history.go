@@ -0,0 +1,658 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/export"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+	"github.com/quocvuong92/perplexity-cli/internal/pricing"
+)
+
+// recentConversations is the number of conversations listed by /history,
+// which index arguments refer to
+const recentConversations = 10
+
+// newHistoryCmd creates the history subcommand for managing stored conversations
+func (app *App) newHistoryCmd() *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List, search, export, share, sync, merge, delete and prune stored conversations",
+		Long: `Manage the conversations stored by interactive mode and --conversation.
+Indexes are those listed by "history list" and /history. Conversations
+moved away with /archive are listed by "history list --archived".
+
+  perplexity history list
+  perplexity history delete 2-5
+  perplexity history delete --search kubernetes`,
+	}
+
+	var archived bool
+	listCmd := &cobra.Command{
+		Use:          "list",
+		Aliases:      []string{"ls"},
+		Short:        "List recent conversations, or the archived ones",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if archived {
+				archive := history.NewArchive()
+				if err := archive.Load(); err != nil {
+					return err
+				}
+				if len(archive.Conversations) == 0 {
+					fmt.Println("No archived conversations.")
+					return nil
+				}
+				printConversations(archive.Conversations)
+				return nil
+			}
+
+			hist := app.newHistory()
+			if err := hist.Load(); err != nil {
+				return err
+			}
+			conversations := hist.GetRecentConversations(recentConversations)
+			if len(conversations) == 0 {
+				fmt.Println("No conversation history.")
+				return nil
+			}
+			if pinned := hist.PinnedConversations(); len(pinned) > 0 {
+				fmt.Print("\nPinned:")
+				printIndexedConversations(pinned, pinnedRefPrefix)
+				fmt.Print("Recent:")
+			}
+			printConversations(conversations)
+			return nil
+		},
+	}
+	listCmd.Flags().BoolVar(&archived, "archived", false, "List the conversations archived with /archive")
+	historyCmd.AddCommand(listCmd)
+
+	var opts searchOptions
+	searchCmd := &cobra.Command{
+		Use:   "search [keyword]",
+		Short: "Search stored conversations by keyword or regular expression",
+		Long: `Search the stored conversations, best matches first, with a snippet of
+each match. Filters narrow the search, and can be used without a keyword
+to list every conversation passing them.`,
+		Example: `  perplexity history search "nil map"
+  perplexity history search --regex "panic: .*" --role user
+  perplexity history search kubernetes --model sonar-pro --since 7d
+  perplexity history search --tag work`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.keyword = args
+			q, err := opts.query(time.Now())
+			if err != nil {
+				return invalidInput(err)
+			}
+			hist := app.newHistory()
+			if err := hist.Load(); err != nil {
+				return err
+			}
+			results, err := hist.Search(q)
+			if err != nil {
+				return invalidInput(err)
+			}
+			if len(results) == 0 {
+				fmt.Printf("No conversations found %s.\n", describeQuery(q))
+				return nil
+			}
+			fmt.Printf("\nConversations %s:\n", describeQuery(q))
+			printSearchResults(results, app.shouldUseColor())
+			return nil
+		},
+	}
+	searchCmd.Flags().StringVar(&opts.regex, "regex", "", "Search by regular expression instead of keyword")
+	searchCmd.Flags().StringVar(&opts.role, "role", "", "Only search messages of this role: user, assistant or system")
+	searchCmd.Flags().StringVar(&opts.model, "model", "", "Only search conversations with this model")
+	searchCmd.Flags().StringVar(&opts.since, "since", "", "Only search conversations updated within this age (e.g. 7d) or since a date")
+	searchCmd.Flags().StringVar(&opts.tag, "tag", "", "Only search conversations tagged with /tag")
+	historyCmd.AddCommand(searchCmd)
+
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Copy conversations to and from the history_sync remote",
+		Long: `Sync the history with the git repository or WebDAV file set under
+history_sync in the config file. Both directions merge the two histories:
+conversations missing on one side are added, and a conversation changed on
+both sides keeps its most recent version, so nothing is lost.`,
+	}
+	syncCmd.AddCommand(&cobra.Command{
+		Use:          "push",
+		Short:        "Merge the remote history and upload the result",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.syncHistory(true)
+		},
+	})
+	syncCmd.AddCommand(&cobra.Command{
+		Use:          "pull",
+		Short:        "Merge the remote history into the local one",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.syncHistory(false)
+		},
+	})
+	historyCmd.AddCommand(syncCmd)
+
+	historyCmd.AddCommand(&cobra.Command{
+		Use:   "merge <file>",
+		Short: "Import the conversations of another history file, such as a backup",
+		Long: `Merge another history file into the history. Conversations missing from the
+history are added, and those in both keep their most recent version. Copies of
+the same conversation under different IDs are then removed, keeping the most
+recently updated one, and the history is sorted by update time. Retention
+limits still apply when saving.`,
+		Example:      `  perplexity history merge ~/backup/conversation-history.json`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conversations, err := history.ReadFile(args[0])
+			if err != nil {
+				return invalidInput(err)
+			}
+			hist := app.newHistory()
+			if err := hist.Load(); err != nil {
+				return err
+			}
+
+			merged := hist.Merge(conversations)
+			duplicates := hist.Dedupe()
+			pruned := hist.Prune(time.Now())
+			if err := hist.Save(); err != nil {
+				return fmt.Errorf("failed to save history: %w", err)
+			}
+			fmt.Printf("Merged %s from %s: %d added or updated, %d removed as duplicates, %d now in history.\n",
+				conversationCount(len(conversations)), args[0], merged, duplicates, len(hist.Conversations))
+			if pruned > 0 {
+				fmt.Printf("%s pruned to the retention limits; raise history_max_entries to keep more.\n", conversationCount(pruned))
+			}
+			return nil
+		},
+	})
+
+	var shareYes bool
+	shareCmd := &cobra.Command{
+		Use:   "share <n|id>",
+		Short: "Upload a conversation as a GitHub gist or paste and print its URL",
+		Long: `Export a conversation to markdown and upload it to a secret GitHub gist,
+using share.gist_token from the config file or GITHUB_TOKEN, or to the paste
+service at share.paste_url. The conversation is given by its index from
+"history list" or its ID.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hist := app.newHistory()
+			if err := hist.Load(); err != nil {
+				return err
+			}
+			conv := findConversation(hist, args[0])
+			if conv == nil {
+				return invalidInput(fmt.Errorf("conversation %q not found. Use an ID or an index from \"history list\"", args[0]))
+			}
+			url, err := app.shareConversation(conv, !shareYes)
+			if err != nil {
+				return err
+			}
+			if url == "" {
+				fmt.Println("Nothing shared.")
+				return nil
+			}
+			fmt.Println(url)
+			return nil
+		},
+	}
+	shareCmd.Flags().BoolVarP(&shareYes, "yes", "y", false, "Upload without asking for confirmation")
+	historyCmd.AddCommand(shareCmd)
+
+	var exportFormat, exportOutput string
+	exportCmd := &cobra.Command{
+		Use:   "export <n|id>",
+		Short: "Export a conversation as markdown, an Obsidian note, or Notion HTML or CSV",
+		Long: `Export a conversation, given by its index from "history list" or its ID, to
+stdout or the --output file. Formats:
+
+  markdown  markdown document (default)
+  obsidian  markdown note with front matter holding its title, dates, model and tags
+  notion    HTML page for Notion's HTML import
+  csv       one row per question, for importing into a Notion database
+
+The citations of each answer become footnotes, or the Sources column in CSV.`,
+		Example: `  perplexity history export 1 --format obsidian -o ~/vault/research.md
+  perplexity history export 2 --format csv > research.csv`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(export.Formats, exportFormat) {
+				return invalidInput(fmt.Errorf("%w: got %s", export.ErrInvalidFormat, exportFormat))
+			}
+			hist := app.newHistory()
+			if err := hist.Load(); err != nil {
+				return err
+			}
+			conv := findConversation(hist, args[0])
+			if conv == nil {
+				return invalidInput(fmt.Errorf("conversation %q not found. Use an ID or an index from \"history list\"", args[0]))
+			}
+			content, err := export.Render(exportFormat, conv)
+			if err != nil {
+				return err
+			}
+			if exportOutput == "" {
+				fmt.Print(content)
+				return nil
+			}
+			if err := os.WriteFile(exportOutput, []byte(content), 0600); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Conversation exported to %s\n", exportOutput)
+			return nil
+		},
+	}
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", export.FormatMarkdown, "Export format: "+strings.Join(export.Formats, ", "))
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write the export to a file instead of stdout")
+	_ = exportCmd.MarkFlagFilename("output")
+	_ = exportCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(export.Formats, cobra.ShellCompDirectiveNoFileComp))
+	historyCmd.AddCommand(exportCmd)
+
+	var search string
+	var yes bool
+	deleteCmd := &cobra.Command{
+		Use:     "delete [<n>|<from>-<to>|all]...",
+		Aliases: []string{"rm"},
+		Short:   "Delete conversations by index, range, or keyword",
+		Example: `  perplexity history delete 3
+  perplexity history delete 1 4-6
+  perplexity history delete all --yes`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			selector := strings.Join(args, " ")
+			switch {
+			case search != "" && selector != "":
+				return invalidInput(fmt.Errorf("indexes cannot be combined with --search"))
+			case search != "":
+				selector = searchSelector + " " + search
+			case selector == "":
+				return invalidInput(fmt.Errorf("give indexes, a range, \"all\" or --search"))
+			}
+
+			hist := app.newHistory()
+			if err := hist.Load(); err != nil {
+				return err
+			}
+			selected, err := selectConversations(hist, selector)
+			if err != nil {
+				return invalidInput(err)
+			}
+			_, err = deleteConversations(hist, selected, !yes)
+			return err
+		},
+	}
+	deleteCmd.Flags().StringVar(&search, "search", "", "Delete the conversations containing this keyword")
+	deleteCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Delete without asking for confirmation")
+	historyCmd.AddCommand(deleteCmd)
+
+	var olderThan string
+	var keep int
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove conversations beyond the retention limits",
+		Long: `Remove the conversations older than history_retention, and all but the
+most recent history_max_entries, as is done whenever the history is saved.
+--older-than and --keep override the config file limits.`,
+		Example: `  perplexity history prune --older-than 30d
+  perplexity history prune --keep 100`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			retention := app.historyRetention()
+			if cmd.Flags().Changed("older-than") {
+				age, err := config.ParseAge(olderThan)
+				if err != nil {
+					return invalidInput(err)
+				}
+				retention.MaxAge = age
+			}
+			if cmd.Flags().Changed("keep") {
+				if keep < 1 {
+					return invalidInput(fmt.Errorf("--keep must be at least 1"))
+				}
+				retention.MaxEntries = keep
+			}
+
+			// Loaded without limits, so the pruned conversations can be counted
+			hist := history.NewHistory()
+			hist.SetRetention(history.Retention{MaxEntries: -1})
+			if err := hist.Load(); err != nil {
+				return err
+			}
+			hist.SetRetention(retention)
+			pruned := hist.Prune(time.Now())
+			if pruned == 0 {
+				fmt.Println("No conversations to prune.")
+				return nil
+			}
+			if err := hist.Save(); err != nil {
+				return fmt.Errorf("failed to save history: %w", err)
+			}
+			fmt.Printf("Pruned %s, %d left.\n", conversationCount(pruned), len(hist.Conversations))
+			return nil
+		},
+	}
+	pruneCmd.Flags().StringVar(&olderThan, "older-than", "", "Remove conversations not updated for this long, e.g. 90d, 2w or 36h")
+	pruneCmd.Flags().IntVar(&keep, "keep", 0, "Keep only this many of the most recent conversations")
+	historyCmd.AddCommand(pruneCmd)
+
+	return historyCmd
+}
+
+// syncTimeout bounds a history sync, including git clones
+const syncTimeout = 2 * time.Minute
+
+// syncDir is the directory, next to the history file, holding the clone of
+// a git sync remote
+const syncDir = "sync"
+
+// syncBackend returns the backend of the history_sync remote
+func (app *App) syncBackend(hist *history.History) (history.SyncBackend, error) {
+	remote := app.cfg.HistorySync
+	switch {
+	case remote.Git != "":
+		return history.NewGitBackend(remote.Git, filepath.Join(filepath.Dir(hist.Path()), syncDir)), nil
+	case remote.WebDAV != "":
+		password := remote.Password
+		if env := os.Getenv(config.EnvSyncPassword); env != "" {
+			password = env
+		}
+		return history.NewWebDAVBackend(remote.WebDAV, remote.Username, password), nil
+	}
+	return nil, fmt.Errorf("no sync remote: set history_sync.git or history_sync.webdav in %s", config.GetConfigPath())
+}
+
+// syncHistory merges the remote history into the local one and saves it,
+// then uploads the merged history when push is set
+func (app *App) syncHistory(push bool) error {
+	hist := app.newHistory()
+	if err := hist.Load(); err != nil {
+		return err
+	}
+	backend, err := app.syncBackend(hist)
+	if err != nil {
+		return invalidInput(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	var merged int
+	if push {
+		merged, err = hist.Push(ctx, backend)
+	} else {
+		merged, err = hist.Pull(ctx, backend)
+	}
+	if err != nil {
+		return err
+	}
+	if err := hist.Save(); err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+
+	if push {
+		fmt.Printf("Pushed %s to %s", conversationCount(len(hist.Conversations)), backend.Name())
+		if merged > 0 {
+			fmt.Printf(", after merging %s from it", conversationCount(merged))
+		}
+		fmt.Println(".")
+	} else if merged == 0 {
+		fmt.Printf("History is up to date with %s.\n", backend.Name())
+	} else {
+		fmt.Printf("Merged %s from %s.\n", conversationCount(merged), backend.Name())
+	}
+	return nil
+}
+
+// newHistory returns the conversation history with the configured retention
+func (app *App) newHistory() *history.History {
+	hist := history.NewHistory()
+	hist.SetRetention(app.historyRetention())
+	return hist
+}
+
+// historyRetention returns the retention limits of the config
+func (app *App) historyRetention() history.Retention {
+	return history.Retention{MaxAge: app.cfg.HistoryRetention, MaxEntries: app.cfg.HistoryMaxEntries}
+}
+
+// searchSelector selects the conversations containing a keyword for deletion
+const searchSelector = "--search"
+
+// selectConversations returns the conversations chosen by a /delete
+// selector: "all", "--search" with the arguments of /search, or indexes from
+// /history and ranges of them such as "1 3-5", and pinned conversations
+// such as "p1"
+func selectConversations(hist *history.History, selector string) ([]history.ConversationEntry, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "all" {
+		return append([]history.ConversationEntry(nil), hist.Conversations...), nil
+	}
+	if args, ok := strings.CutPrefix(selector, searchSelector); ok {
+		if strings.TrimSpace(args) == "" {
+			return nil, fmt.Errorf("%s needs a keyword", searchSelector)
+		}
+		q, err := parseSearchArgs(args, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		results, err := hist.Search(q)
+		if err != nil {
+			return nil, err
+		}
+		var selected []history.ConversationEntry
+		for _, result := range results {
+			selected = append(selected, result.Conversation)
+		}
+		return selected, nil
+	}
+
+	recent := hist.GetRecentConversations(recentConversations)
+	var selected []history.ConversationEntry
+	seen := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(selector, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if conv := pinnedConversation(hist, field); conv != nil {
+			if !seen[conv.ID] {
+				seen[conv.ID] = true
+				selected = append(selected, *conv)
+			}
+			continue
+		}
+		from, to, err := parseIndexRange(field, len(recent))
+		if err != nil {
+			return nil, err
+		}
+		for i := from; i <= to; i++ {
+			if !seen[recent[i-1].ID] {
+				seen[recent[i-1].ID] = true
+				selected = append(selected, recent[i-1])
+			}
+		}
+	}
+	return selected, nil
+}
+
+// parseIndexRange parses an index or a range of indexes such as "2-5",
+// each between 1 and count
+func parseIndexRange(field string, count int) (int, int, error) {
+	first, last, isRange := strings.Cut(field, "-")
+	from, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid index: %s", field)
+	}
+	to := from
+	if isRange {
+		if to, err = strconv.Atoi(last); err != nil {
+			return 0, 0, fmt.Errorf("invalid range: %s", field)
+		}
+	}
+	if from < 1 || to > count || from > to {
+		if count == 0 {
+			return 0, 0, fmt.Errorf("invalid conversation index: %s (no conversations)", field)
+		}
+		return 0, 0, fmt.Errorf("invalid conversation index: %s (use 1-%d)", field, count)
+	}
+	return from, to, nil
+}
+
+// deleteConversations deletes the selected conversations and saves the
+// history. With ask set, the conversations are listed and only deleted
+// after confirmation. It returns how many were deleted.
+func deleteConversations(hist *history.History, selected []history.ConversationEntry, ask bool) (int, error) {
+	if len(selected) == 0 {
+		fmt.Println("No conversations to delete.")
+		return 0, nil
+	}
+	if ask {
+		printConversations(selected)
+		if !confirm(fmt.Sprintf("Delete %s?", conversationCount(len(selected)))) {
+			fmt.Println("Nothing deleted.")
+			return 0, nil
+		}
+	}
+
+	ids := make([]string, len(selected))
+	for i, conv := range selected {
+		ids[i] = conv.ID
+	}
+	deleted := hist.DeleteConversations(ids)
+	if err := hist.Save(); err != nil {
+		return 0, fmt.Errorf("failed to save history: %w", err)
+	}
+	fmt.Printf("Deleted %s.\n", conversationCount(deleted))
+	return deleted, nil
+}
+
+// archiveConversations moves the selected conversations from the history to
+// the archive and returns how many were moved. The archive is saved first, so
+// a failure never loses a conversation.
+func archiveConversations(hist, archive *history.History, selected []history.ConversationEntry) (int, error) {
+	if len(selected) == 0 {
+		return 0, nil
+	}
+	if err := archive.Load(); err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, len(selected))
+	for i, conv := range selected {
+		ids[i] = conv.ID
+	}
+	// Replace earlier archived copies of the same conversations
+	archive.DeleteConversations(ids)
+	archive.Conversations = append(archive.Conversations, selected...)
+	if err := archive.Save(); err != nil {
+		return 0, fmt.Errorf("failed to save archive: %w", err)
+	}
+
+	archived := hist.DeleteConversations(ids)
+	if err := hist.Save(); err != nil {
+		return 0, fmt.Errorf("failed to save history: %w", err)
+	}
+	return archived, nil
+}
+
+// printConversations lists conversations with their date, model and title
+func printConversations(conversations []history.ConversationEntry) {
+	printIndexedConversations(conversations, "")
+}
+
+// printIndexedConversations lists conversations with their index after
+// prefix, e.g. p1 for pinned conversations
+func printIndexedConversations(conversations []history.ConversationEntry, prefix string) {
+	fmt.Println()
+	for i, conv := range conversations {
+		fmt.Printf("  %s%d. [%s] %s (%s) %s\n",
+			prefix,
+			i+1,
+			conv.UpdatedAt.Format("2006-01-02 15:04"),
+			conv.Model,
+			conversationStats(conv),
+			conv.Title(),
+		)
+	}
+	fmt.Println()
+}
+
+// conversationStats describes the size of a conversation: its messages and,
+// once recorded, its total tokens and estimated cost
+func conversationStats(conv history.ConversationEntry) string {
+	stats := fmt.Sprintf("%d messages", max(len(conv.Messages)-1, 0))
+	if tokens := conv.Usage.TotalTokens(); tokens > 0 {
+		stats += fmt.Sprintf(", %d tokens", tokens)
+	}
+	if conv.Usage.Cost > 0 {
+		stats += ", " + pricing.Format(conv.Usage.Cost)
+	}
+	return stats
+}
+
+// printSearchResults lists search results, best first, with the number of
+// matches and a snippet of the first match, highlighted when color is set
+func printSearchResults(results []history.SearchResult, color bool) {
+	for i, result := range results {
+		conv := result.Conversation
+		messages := fmt.Sprintf("%d messages", max(len(conv.Messages)-1, 0))
+		switch result.Matches {
+		case 0:
+		case 1:
+			messages += ", 1 match"
+		default:
+			messages += fmt.Sprintf(", %d matches", result.Matches)
+		}
+		fmt.Printf("  %d. [%s] %s (%s)%s\n",
+			i+1,
+			conv.UpdatedAt.Format("2006-01-02 15:04"),
+			conv.Model,
+			messages,
+			formatTags(conv.Tags),
+		)
+
+		if result.Matches == 0 {
+			// Found by filters alone, so there is no snippet
+			continue
+		}
+		match := result.Snippet.Match
+		if color {
+			match = colorBold + colorCyan + match + colorReset
+		}
+		fmt.Printf("     %s: %s%s%s\n", result.Role, result.Snippet.Before, match, result.Snippet.After)
+	}
+	fmt.Println()
+}
+
+// formatTags formats the tags of a conversation as " #a #b"
+func formatTags(tags []string) string {
+	var b strings.Builder
+	for _, tag := range tags {
+		b.WriteString(" #" + tag)
+	}
+	return b.String()
+}
+
+// conversationCount formats a number of conversations
+func conversationCount(n int) string {
+	if n == 1 {
+		return "1 conversation"
+	}
+	return fmt.Sprintf("%d conversations", n)
+}
@@ -0,0 +1,413 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+)
+
+// newTestHistory returns a history of n conversations, with IDs id1 to idn
+// updated a minute apart, and the keyword "go" in the even ones
+func newTestHistory(t *testing.T, n int) *history.History {
+	t.Helper()
+	t.Setenv(history.EnvHistoryPath, filepath.Join(t.TempDir(), "history.json"))
+	hist := history.NewHistory()
+	start := time.Now().Add(-time.Duration(n) * time.Minute)
+	for i := 1; i <= n; i++ {
+		content := "Question"
+		if i%2 == 0 {
+			content = "Question about Go"
+		}
+		hist.AddConversation(fmt.Sprintf("id%d", i), "sonar", []history.Message{{Role: "user", Content: content}})
+		hist.Conversations[i-1].UpdatedAt = start.Add(time.Duration(i) * time.Minute)
+	}
+	return hist
+}
+
+// conversationIDs returns the IDs of conversations
+func conversationIDs(conversations []history.ConversationEntry) []string {
+	var ids []string
+	for _, conv := range conversations {
+		ids = append(ids, conv.ID)
+	}
+	return ids
+}
+
+func TestSelectConversations(t *testing.T) {
+	// 12 conversations: /history lists id3 to id12, with id1 pinned
+	hist := newTestHistory(t, 12)
+	hist.Conversations[0].Pinned = true
+
+	tests := []struct {
+		selector string
+		want     []string
+		wantErr  string
+	}{
+		{selector: "1", want: []string{"id3"}},
+		{selector: "2-4", want: []string{"id4", "id5", "id6"}},
+		{selector: "1, 3-4 3", want: []string{"id3", "id5", "id6"}},
+		{selector: "p1 1 p1", want: []string{"id1", "id3"}},
+		{selector: "p2", wantErr: "invalid index"},
+		{selector: "all", want: []string{"id1", "id2", "id3", "id4", "id5", "id6", "id7", "id8", "id9", "id10", "id11", "id12"}},
+		{selector: "--search GO", want: []string{"id12", "id10", "id8", "id6", "id4", "id2"}},
+		{selector: "--search", wantErr: "needs a keyword"},
+		{selector: "0", wantErr: "use 1-10"},
+		{selector: "4-2", wantErr: "invalid conversation index"},
+		{selector: "9-11", wantErr: "invalid conversation index"},
+		{selector: "2-x", wantErr: "invalid range"},
+		{selector: "x", wantErr: "invalid index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			got, err := selectConversations(hist, tt.selector)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("selectConversations() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectConversations() error = %v", err)
+			}
+			if ids := conversationIDs(got); !slices.Equal(ids, tt.want) {
+				t.Errorf("selectConversations() = %q, want %q", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestCmdDeleteRange(t *testing.T) {
+	session := newTestSession()
+	session.history = newTestHistory(t, 5)
+
+	stubReadLine(t, "n")
+	output := captureOutput(func() {
+		session.cmdDelete([]string{"/delete", "2-4"})
+	})
+	if len(session.history.Conversations) != 5 || !strings.Contains(output, "Nothing deleted") {
+		t.Errorf("declined deletion removed conversations: %q", output)
+	}
+
+	stubReadLine(t, "y")
+	output = captureOutput(func() {
+		session.cmdDelete([]string{"/delete", "2-4"})
+	})
+	if ids := conversationIDs(session.history.Conversations); !slices.Equal(ids, []string{"id1", "id5"}) {
+		t.Errorf("conversations = %q, want id1 and id5", ids)
+	}
+	if !strings.Contains(output, "Deleted 3 conversations") {
+		t.Errorf("output %q should report the deletion", output)
+	}
+
+	saved := history.NewHistory()
+	if err := saved.Load(); err != nil || len(saved.Conversations) != 2 {
+		t.Errorf("saved history has %d conversations, err %v, want 2", len(saved.Conversations), err)
+	}
+}
+
+func TestCmdDeleteSearch(t *testing.T) {
+	session := newTestSession()
+	session.history = newTestHistory(t, 4)
+
+	stubReadLine(t, "yes")
+	captureOutput(func() {
+		session.cmdDelete([]string{"/delete", "--search go"})
+	})
+	if ids := conversationIDs(session.history.Conversations); !slices.Equal(ids, []string{"id1", "id3"}) {
+		t.Errorf("conversations = %q, want id1 and id3", ids)
+	}
+
+	output := captureOutput(func() {
+		session.cmdDelete([]string{"/delete", "--search missing"})
+	})
+	if !strings.Contains(output, "No conversations to delete") {
+		t.Errorf("output %q should report that nothing matched", output)
+	}
+}
+
+func TestHistoryPruneCmd(t *testing.T) {
+	hist := newTestHistory(t, 6)
+	for i := range hist.Conversations {
+		hist.Conversations[i].UpdatedAt = time.Now().Add(-time.Duration(5-i) * 24 * time.Hour)
+	}
+	if err := hist.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp()
+	app.cfg.HistoryMaxEntries = 4
+	cmd := app.newHistoryCmd()
+	cmd.SetArgs([]string{"prune", "--older-than", "3d"})
+	var err error
+	output := captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(output, "Pruned 3 conversations, 3 left") {
+		t.Errorf("output %q should report the pruned conversations", output)
+	}
+
+	saved := history.NewHistory()
+	if err := saved.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if ids := conversationIDs(saved.Conversations); !slices.Equal(ids, []string{"id4", "id5", "id6"}) {
+		t.Errorf("saved conversations = %q, want id4 to id6", ids)
+	}
+
+	cmd.SetArgs([]string{"prune", "--keep", "0"})
+	captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if exitCode(err) != ExitInvalidInput {
+		t.Errorf("Execute() error = %v, want invalid input", err)
+	}
+}
+
+func TestHistoryPruneCmdBeyondDefaultLimit(t *testing.T) {
+	const total = history.DefaultMaxEntries + 10
+	hist := newTestHistory(t, total)
+	hist.SetRetention(history.Retention{MaxEntries: -1})
+	if err := hist.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp()
+	app.cfg.HistoryMaxEntries = -1
+	cmd := app.newHistoryCmd()
+	cmd.SetArgs([]string{"prune", "--keep", strconv.Itoa(total - 5)})
+	var err error
+	output := captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := fmt.Sprintf("Pruned 5 conversations, %d left", total-5); !strings.Contains(output, want) {
+		t.Errorf("output %q, want %q", output, want)
+	}
+
+	saved := history.NewHistory()
+	saved.SetRetention(history.Retention{MaxEntries: -1})
+	if err := saved.Load(); err != nil || len(saved.Conversations) != total-5 {
+		t.Errorf("saved history has %d conversations, err %v, want %d", len(saved.Conversations), err, total-5)
+	}
+}
+
+func TestCmdArchive(t *testing.T) {
+	session := newTestSession()
+	session.history = newTestHistory(t, 4)
+	if err := session.history.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	output := captureOutput(func() {
+		session.cmdArchive([]string{"/archive", "2-3"})
+	})
+	if !strings.Contains(output, "Archived 2 conversations") {
+		t.Errorf("output %q should report the archived conversations", output)
+	}
+	if ids := conversationIDs(session.history.Conversations); !slices.Equal(ids, []string{"id1", "id4"}) {
+		t.Errorf("history = %q, want id1 and id4", ids)
+	}
+	if results := session.history.SearchConversations("Go"); len(results) != 1 || results[0].Conversation.ID != "id4" {
+		t.Errorf("search found %d results, want only id4", len(results))
+	}
+
+	archive := history.NewArchive()
+	if err := archive.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if ids := conversationIDs(archive.Conversations); !slices.Equal(ids, []string{"id2", "id3"}) {
+		t.Errorf("archive = %q, want id2 and id3", ids)
+	}
+
+	saved := history.NewHistory()
+	if err := saved.Load(); err != nil || len(saved.Conversations) != 2 {
+		t.Errorf("saved history has %d conversations, err %v, want 2", len(saved.Conversations), err)
+	}
+
+	cmd := NewApp().newHistoryCmd()
+	cmd.SetArgs([]string{"list", "--archived"})
+	output = captureOutput(func() {
+		if err := cmd.Execute(); err != nil {
+			t.Errorf("Execute() error = %v", err)
+		}
+	})
+	if !strings.Contains(output, "Question about Go") || strings.Count(output, "[") != 2 {
+		t.Errorf("list --archived output %q should list the two archived conversations", output)
+	}
+}
+
+func TestHistoryListPinned(t *testing.T) {
+	hist := newTestHistory(t, 2)
+	hist.Conversations[0].Pinned = true
+	if err := hist.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewApp().newHistoryCmd()
+	cmd.SetArgs([]string{"list"})
+	output := captureOutput(func() {
+		if err := cmd.Execute(); err != nil {
+			t.Errorf("Execute() error = %v", err)
+		}
+	})
+	pinned, recent, ok := strings.Cut(output, "Recent:")
+	if !ok || !strings.Contains(pinned, "Pinned:") || !strings.Contains(pinned, "  p1. [") || !strings.Contains(recent, "  2. [") {
+		t.Errorf("list output %q should list the pinned conversation before the recent ones", output)
+	}
+}
+
+func TestHistorySyncCmd(t *testing.T) {
+	hist := newTestHistory(t, 2)
+	if err := hist.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	remote := []byte(`{"conversations":[{"id":"remote","model":"sonar","messages":[]}]}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			remote, _ = io.ReadAll(r.Body)
+			return
+		}
+		w.Write(remote)
+	}))
+	defer server.Close()
+
+	app := NewApp()
+	app.cfg.HistorySync.WebDAV = server.URL
+	cmd := app.newHistoryCmd()
+	cmd.SetArgs([]string{"sync", "push"})
+	var err error
+	output := captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(output, "Pushed 3 conversations") || !strings.Contains(output, "after merging 1 conversation") {
+		t.Errorf("output %q should report the push", output)
+	}
+
+	saved := history.NewHistory()
+	if err := saved.Load(); err != nil || saved.GetConversation("remote") == nil {
+		t.Errorf("the remote conversation should be saved locally, err %v", err)
+	}
+	if !strings.Contains(string(remote), `"id1"`) {
+		t.Errorf("remote %s should hold the local conversations", remote)
+	}
+
+	cmd = NewApp().newHistoryCmd()
+	cmd.SetArgs([]string{"sync", "pull"})
+	captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if exitCode(err) != ExitInvalidInput {
+		t.Errorf("Execute() error = %v, want invalid input without a remote", err)
+	}
+}
+
+func TestHistoryExportCmd(t *testing.T) {
+	hist := newTestHistory(t, 2)
+	hist.Conversations[1].Tags = []string{"golang"}
+	if err := hist.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "note.md")
+	cmd := NewApp().newHistoryCmd()
+	cmd.SetArgs([]string{"export", "id2", "--format", "obsidian", "-o", out})
+	var err error
+	captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "---\ntitle: Question about Go") || !strings.Contains(string(data), "    - golang\n") {
+		t.Errorf("exported note %q should start with front matter holding the tags", data)
+	}
+
+	cmd = NewApp().newHistoryCmd()
+	cmd.SetArgs([]string{"export", "id1", "--format", "csv"})
+	output := captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if err != nil || !strings.HasPrefix(output, "Question,Answer,Sources,Model,Date,Tags\n") {
+		t.Errorf("Execute() = %q, %v, want CSV on stdout", output, err)
+	}
+
+	cmd = NewApp().newHistoryCmd()
+	cmd.SetArgs([]string{"export", "id1", "--format", "pdf"})
+	captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if exitCode(err) != ExitInvalidInput {
+		t.Errorf("Execute() error = %v, want invalid input", err)
+	}
+}
+
+func TestHistoryMergeCmd(t *testing.T) {
+	hist := newTestHistory(t, 2)
+	if err := hist.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A backup holding a copy of id1 under another ID, and a conversation of its own
+	backup := filepath.Join(t.TempDir(), "backup.json")
+	data := fmt.Sprintf(`{"conversations": [
+		{"id": "copy", "messages": [{"role": "user", "content": "Question"}], "updated_at": %q},
+		{"id": "old", "messages": [{"role": "user", "content": "Restored"}], "updated_at": %q}
+	]}`, time.Now().Add(-time.Hour).Format(time.RFC3339), time.Now().Add(-2*time.Hour).Format(time.RFC3339))
+	if err := os.WriteFile(backup, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewApp().newHistoryCmd()
+	cmd.SetArgs([]string{"merge", backup})
+	var err error
+	output := captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(output, "2 added or updated, 1 removed as duplicates, 3 now in history") {
+		t.Errorf("output %q should report the merge", output)
+	}
+
+	saved := history.NewHistory()
+	if err := saved.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if ids := conversationIDs(saved.Conversations); !slices.Equal(ids, []string{"old", "id1", "id2"}) {
+		t.Errorf("saved conversations = %q, want the restored one first and the copy removed", ids)
+	}
+
+	cmd = NewApp().newHistoryCmd()
+	cmd.SetArgs([]string{"merge", filepath.Join(t.TempDir(), "missing.json")})
+	captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if exitCode(err) != ExitInvalidInput {
+		t.Errorf("Execute() error = %v, want invalid input", err)
+	}
+}
@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/keyring"
+	"github.com/quocvuong92/perplexity-cli/internal/validation"
+)
+
+const (
+	// apiKeyURL is where Perplexity API keys are created
+	apiKeyURL = "https://www.perplexity.ai/settings/api"
+	// keyCheckModel is the model the API key check is sent to, the cheapest
+	keyCheckModel = "sonar"
+	// keyCheckTimeout bounds the API key check
+	keyCheckTimeout = 30 * time.Second
+	// keyAttempts is how many times a key is asked for before giving up
+	keyAttempts = 3
+)
+
+// Where perplexity init stores the API key, besides the config's stores
+const keyStoreEnv = "env"
+
+// errNoKeyEntered is returned when the user enters an empty API key
+var errNoKeyEntered = errors.New("no API key entered")
+
+// canPromptForKey reports whether an API key can be asked for: stdin and
+// stdout are a terminal. It is a variable so tests can replace it.
+var canPromptForKey = func() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// readSecret reads a line of user input without echoing it. It is a variable
+// so tests can replace it.
+var readSecret = func() (string, error) {
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	return string(b), err
+}
+
+// checkAPIKey sends a minimal request with key to check that the API accepts
+// it. It is a variable so tests can replace it.
+var checkAPIKey = func(ctx context.Context, cfg *config.Config, key string) error {
+	check := *cfg
+	check.APIKey = key
+	check.APIKeys = []string{key}
+	check.CurrentKeyIndex = 0
+	check.Model = keyCheckModel
+	check.Timeout = keyCheckTimeout
+	check.Retry.MaxRetries = 0
+	check.RateLimit = 0
+	_, err := api.NewClient(&check).QueryContext(ctx, "Reply with OK.")
+	return err
+}
+
+// newInitCmd creates the init subcommand, a wizard setting up the API key and default model
+func (app *App) newInitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "init",
+		Short: "Set up the API key and default model",
+		Long: `Set up the CLI: paste an API key, which is checked with a short request
+to the API (billed as one request to sonar), choose where to keep it (the
+system keyring, the config file or an environment variable you set yourself)
+and choose the default model.
+
+Running it again replaces the stored key and default model.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !term.IsTerminal(int(os.Stdin.Fd())) {
+				return invalidInput(errors.New("init asks for the API key and needs a terminal"))
+			}
+			ctx, cancel := newSignalContext()
+			defer cancel()
+			return app.runInit(ctx)
+		},
+	}
+}
+
+// runInit runs the setup wizard
+func (app *App) runInit(ctx context.Context) error {
+	if source := app.keySource(); source != "" {
+		fmt.Printf("An API key is already set up (%s).\n", source)
+		if !confirm("Set up a new one?") {
+			return nil
+		}
+	}
+
+	fmt.Printf("Create an API key at %s if you do not have one yet.\n", apiKeyURL)
+	key, err := app.promptAPIKey(ctx, true)
+	if err != nil {
+		return err
+	}
+	if err := app.storeAPIKey(chooseKeyStore(keyStoreOptions()), key); err != nil {
+		return err
+	}
+
+	model := app.chooseModel()
+	path := config.GetConfigPath()
+	if err := config.SetFileValues(path, map[string]string{"model": model}); err != nil {
+		return err
+	}
+	app.cfg.Model = model
+	fmt.Printf("Default model set to %s in %s.\n", model, path)
+
+	fmt.Println("\nAll set. Try: perplexity \"What is new in Go?\"")
+	return nil
+}
+
+// keySource describes where the API key a run would use comes from, or
+// returns "" if there is none
+func (app *App) keySource() string {
+	if os.Getenv(config.EnvAPIKeys) != "" {
+		return config.EnvAPIKeys
+	}
+	if os.Getenv(config.EnvAPIKey) != "" {
+		return config.EnvAPIKey
+	}
+	if key, err := app.cfg.StoredAPIKey(); err == nil && key != "" {
+		if app.cfg.APIKeyStore == config.APIKeyStoreKeyring {
+			return "keyring"
+		}
+		return "config file"
+	}
+	return ""
+}
+
+// validateConfig validates the config. If no API key is set up and the user
+// is at a terminal, it asks for one and offers to store it instead of
+// failing with ErrAPIKeyNotFound.
+func (app *App) validateConfig() error {
+	err := app.cfg.Validate()
+	if !errors.Is(err, config.ErrAPIKeyNotFound) || !canPromptForKey() {
+		return err
+	}
+
+	fmt.Printf("No API key is set up. Paste one to continue, or press Enter to quit (create one at %s).\n", apiKeyURL)
+	key, promptErr := app.promptAPIKey(context.Background(), false)
+	if errors.Is(promptErr, errNoKeyEntered) {
+		return err
+	}
+	if promptErr != nil {
+		return promptErr
+	}
+
+	app.cfg.FileAPIKey = key
+	app.cfg.APIKeyStore = ""
+	if confirm("Save the key for next time?") {
+		// Setting the environment is left to perplexity init
+		options := slices.DeleteFunc(keyStoreOptions(), func(o keyStoreOption) bool { return o.store == keyStoreEnv })
+		if err := app.storeAPIKey(chooseKeyStore(options), key); err != nil {
+			display.ShowWarning(fmt.Sprintf("The key is only used this time: %v", err))
+		}
+	}
+	return app.cfg.Validate()
+}
+
+// promptAPIKey asks for an API key without echoing it, and with check, checks
+// it with the API. A key the API cannot be reached to check is only used if
+// the user agrees.
+func (app *App) promptAPIKey(ctx context.Context, check bool) (string, error) {
+	for range keyAttempts {
+		fmt.Print("API key (input is hidden): ")
+		key, err := readSecret()
+		if err != nil {
+			return "", fmt.Errorf("failed to read the API key: %w", err)
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return "", invalidInput(errNoKeyEntered)
+		}
+		if result := validation.ValidateAPIKey(key); !result.Valid {
+			display.ShowError(fmt.Sprintf("invalid API key: %v", result.Error))
+			continue
+		}
+		if !check {
+			return key, nil
+		}
+
+		sp := display.NewSpinner("Checking the key...")
+		sp.Start()
+		err = checkAPIKey(ctx, app.cfg, key)
+		sp.Stop()
+
+		var apiErr *api.APIError
+		switch {
+		case err == nil:
+			fmt.Printf("Key %s works.\n", config.MaskKey(key))
+			return key, nil
+		case ctx.Err() != nil:
+			return "", ctx.Err()
+		case errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden):
+			display.ShowError(fmt.Sprintf("the API rejected the key: %v", err))
+		case errors.As(err, &apiErr):
+			// The key was accepted but cannot be used right now, e.g. for lack of credit
+			display.ShowWarning(fmt.Sprintf("The key was accepted, but the request failed: %v", err))
+			return key, nil
+		default:
+			display.ShowWarning(fmt.Sprintf("Could not check the key: %v", err))
+			if confirm("Use it anyway?") {
+				return key, nil
+			}
+		}
+	}
+	return "", invalidInput(fmt.Errorf("no working API key after %d attempts", keyAttempts))
+}
+
+// keyStoreOption is a place the API key can be stored
+type keyStoreOption struct {
+	store string
+	label string
+}
+
+// keyStoreOptions lists the places the API key can be stored, the
+// recommended one first
+func keyStoreOptions() []keyStoreOption {
+	var options []keyStoreOption
+	if name, err := keyring.Available(); err == nil {
+		options = append(options, keyStoreOption{config.APIKeyStoreKeyring, fmt.Sprintf("System keyring: %s (recommended)", name)})
+	}
+	return append(options,
+		keyStoreOption{config.APIKeyStoreConfig, fmt.Sprintf("Config file (%s, readable only by you)", config.GetConfigPath())},
+		keyStoreOption{keyStoreEnv, "Environment variable (you set it yourself)"},
+	)
+}
+
+// chooseKeyStore asks which of options to store the API key in, defaulting
+// to the first
+func chooseKeyStore(options []keyStoreOption) string {
+	fmt.Println("Where should the key be stored?")
+	for i, option := range options {
+		fmt.Printf("  %d) %s\n", i+1, option.label)
+	}
+	for {
+		fmt.Print("Choice [1]: ")
+		answer, err := readLine()
+		answer = strings.TrimSpace(answer)
+		if err != nil || answer == "" {
+			return options[0].store
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return options[n-1].store
+		}
+		fmt.Printf("Enter a number from 1 to %d.\n", len(options))
+	}
+}
+
+// storeAPIKey saves key in store and uses it for this run. For the
+// environment, it prints how to set the variable instead.
+func (app *App) storeAPIKey(store, key string) error {
+	path := config.GetConfigPath()
+	switch store {
+	case config.APIKeyStoreKeyring:
+		if err := keyring.Set(key); err != nil {
+			return err
+		}
+		// The key is no longer kept in the config file
+		if err := config.SetFileValues(path, map[string]string{"api_key_store": config.APIKeyStoreKeyring, "api_key": ""}); err != nil {
+			return err
+		}
+		fmt.Println("Key saved to the keyring.")
+	case config.APIKeyStoreConfig:
+		if err := config.SetFileValues(path, map[string]string{"api_key": key, "api_key_store": ""}); err != nil {
+			return err
+		}
+		fmt.Printf("Key saved to %s.\n", path)
+	default:
+		fmt.Println(envInstructions())
+	}
+	app.cfg.FileAPIKey = key
+	app.cfg.APIKeyStore = ""
+	return nil
+}
+
+// envInstructions tells how to set the API key environment variable for
+// the shells of the operating system
+func envInstructions() string {
+	if runtime.GOOS == "windows" {
+		return fmt.Sprintf("Set the key for new terminals with:\n  setx %s \"<your key>\"\nand in PowerShell for this one with:\n  $env:%s = \"<your key>\"",
+			config.EnvAPIKey, config.EnvAPIKey)
+	}
+	return fmt.Sprintf("Add this line to your shell's startup file (~/.bashrc, ~/.zshrc, ...):\n  export %s=\"<your key>\"",
+		config.EnvAPIKey)
+}
+
+// chooseModel asks for the default model by number or name, defaulting to
+// the current one
+func (app *App) chooseModel() string {
+	current := app.cfg.ResolveModel(app.cfg.Model)
+	models := app.cfg.GetModels()
+	fmt.Println("Which model should be used by default?")
+	for i, model := range models {
+		fmt.Printf("  %d) %s\n", i+1, model)
+	}
+	for {
+		fmt.Printf("Model [%s]: ", current)
+		answer, err := readLine()
+		answer = strings.TrimSpace(answer)
+		if err != nil || answer == "" {
+			return current
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(models) {
+			return models[n-1]
+		}
+		if model := app.cfg.ResolveModel(answer); app.cfg.IsValidModel(model) {
+			return model
+		}
+		fmt.Printf("Enter a number from 1 to %d or a model name.\n", len(models))
+	}
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+// stubReadSecret answers hidden prompts with the given lines in order
+func stubReadSecret(t *testing.T, answers ...string) {
+	t.Helper()
+	old := readSecret
+	t.Cleanup(func() { readSecret = old })
+	readSecret = func() (string, error) {
+		if len(answers) == 0 {
+			return "", errors.New("no more input")
+		}
+		answer := answers[0]
+		answers = answers[1:]
+		return answer, nil
+	}
+}
+
+// stubCheckAPIKey makes the API answer the key check of each key with its error
+func stubCheckAPIKey(t *testing.T, results map[string]error) {
+	t.Helper()
+	old := checkAPIKey
+	t.Cleanup(func() { checkAPIKey = old })
+	checkAPIKey = func(ctx context.Context, cfg *config.Config, key string) error {
+		return results[key]
+	}
+}
+
+func TestPromptAPIKey(t *testing.T) {
+	const (
+		good     = "pplx-good-key-123456789"
+		rejected = "pplx-rejected-123456789"
+		broke    = "pplx-no-credit-12345678"
+		offline  = "pplx-offline-1234567890"
+	)
+	stubCheckAPIKey(t, map[string]error{
+		rejected: &api.APIError{StatusCode: 401, Message: "Invalid API key"},
+		broke:    &api.APIError{StatusCode: 402, Message: "Insufficient credits"},
+		offline:  errors.New("dial tcp: no such host"),
+	})
+
+	tests := []struct {
+		name    string
+		secrets []string
+		lines   []string
+		want    string
+		wantErr bool
+	}{
+		{"working key", []string{good}, nil, good, false},
+		{"whitespace trimmed", []string{"  " + good + " "}, nil, good, false},
+		{"malformed key asked again", []string{"sk-openai-key", good}, nil, good, false},
+		{"rejected key asked again", []string{rejected, good}, nil, good, false},
+		{"key without credit kept", []string{broke}, nil, broke, false},
+		{"unchecked key kept if confirmed", []string{offline}, []string{"y"}, offline, false},
+		{"unchecked key asked again", []string{offline, good}, []string{"n"}, good, false},
+		{"no key", []string{""}, nil, "", true},
+		{"too many attempts", []string{rejected, rejected, rejected, good}, nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubReadSecret(t, tt.secrets...)
+			stubReadLine(t, tt.lines...)
+			app := NewApp()
+			got, err := app.promptAPIKey(context.Background(), true)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("promptAPIKey() = %q, %v, want %q (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStoreAPIKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), config.ConfigFileName)
+	t.Setenv(config.EnvConfigPath, path)
+	t.Setenv(config.EnvAPIKeys, "")
+	t.Setenv(config.EnvAPIKey, "")
+	const key = "pplx-stored-key-123456789"
+
+	// The environment is left to the user
+	app := NewApp()
+	if err := app.storeAPIKey(keyStoreEnv, key); err != nil {
+		t.Fatalf("storeAPIKey(env) error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("config file written for the env store: %v", err)
+	}
+
+	if err := app.storeAPIKey(config.APIKeyStoreConfig, key); err != nil {
+		t.Fatalf("storeAPIKey(config) error = %v", err)
+	}
+	fc, err := config.LoadFileConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.NewConfig()
+	cfg.ApplyFile(fc)
+	if err := cfg.Validate(); err != nil || cfg.APIKey != key {
+		t.Errorf("Validate() error = %v, APIKey = %q, want the stored key", err, cfg.APIKey)
+	}
+	if source := app.keySource(); source != "config file" {
+		t.Errorf("keySource() = %q, want the config file", source)
+	}
+}
+
+func TestChooseModel(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers []string
+		want    string
+	}{
+		{"default", []string{""}, config.DefaultModel},
+		{"by number", []string{"4"}, config.AvailableModels[3]},
+		{"by name", []string{"sonar-reasoning"}, "sonar-reasoning"},
+		{"by alias", []string{"quick"}, "sonar"},
+		{"asked again", []string{"gpt-4", "99", "sonar"}, "sonar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubReadLine(t, tt.answers...)
+			app := NewApp()
+			app.cfg.ModelAliases = map[string]string{"quick": "sonar"}
+			if got := app.chooseModel(); got != tt.want {
+				t.Errorf("chooseModel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfigPromptsForKey(t *testing.T) {
+	t.Setenv(config.EnvAPIKeys, "")
+	t.Setenv(config.EnvAPIKey, "")
+	const key = "pplx-pasted-key-123456789"
+
+	// The choice of the config file among the stores offered
+	configChoice := ""
+	for i, option := range keyStoreOptions() {
+		if option.store == config.APIKeyStoreConfig {
+			configChoice = strconv.Itoa(i + 1)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		terminal bool
+		secrets  []string
+		lines    []string
+		wantErr  error
+		wantSave bool
+	}{
+		{"not a terminal", false, []string{key}, nil, config.ErrAPIKeyNotFound, false},
+		{"nothing pasted", true, []string{""}, nil, config.ErrAPIKeyNotFound, false},
+		{"used this time", true, []string{key}, []string{"n"}, nil, false},
+		{"saved", true, []string{key}, []string{"y", configChoice}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), config.ConfigFileName)
+			t.Setenv(config.EnvConfigPath, path)
+			old := canPromptForKey
+			t.Cleanup(func() { canPromptForKey = old })
+			canPromptForKey = func() bool { return tt.terminal }
+			stubReadSecret(t, tt.secrets...)
+			stubReadLine(t, tt.lines...)
+
+			app := NewApp()
+			err := app.validateConfig()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("validateConfig() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && app.cfg.APIKey != key {
+				t.Errorf("APIKey = %q, want the pasted key", app.cfg.APIKey)
+			}
+			fc, err := config.LoadFileConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved := fc.APIKey == key; saved != tt.wantSave {
+				t.Errorf("key saved to the config file = %v, want %v", saved, tt.wantSave)
+			}
+		})
+	}
+}
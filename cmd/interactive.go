@@ -8,16 +8,17 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/elk-language/go-prompt"
 	"github.com/google/uuid"
 
 	"github.com/quocvuong92/perplexity-cli/internal/api"
-	"github.com/quocvuong92/perplexity-cli/internal/config"
 	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/fileref"
 	"github.com/quocvuong92/perplexity-cli/internal/history"
-	"github.com/quocvuong92/perplexity-cli/internal/retry"
-	"github.com/quocvuong92/perplexity-cli/internal/validation"
+	"github.com/quocvuong92/perplexity-cli/internal/logging"
+	"github.com/quocvuong92/perplexity-cli/internal/tools"
 )
 
 // ANSI color codes for banner
@@ -30,6 +31,9 @@ const (
 	colorDim    = "\033[2m"
 )
 
+// multilineFence starts and ends a fenced multiline message
+const multilineFence = `"""`
+
 // showBanner displays the ASCII art banner for interactive mode
 func showBanner(model string) {
 	fmt.Println()
@@ -45,7 +49,7 @@ func showBanner(model string) {
 	fmt.Printf("        %s╭────────────────────────────────── Tips ───────────────────────────────────╮%s\n", colorDim, colorReset)
 	fmt.Printf("        %s│                                                                           │%s\n", colorDim, colorReset)
 	fmt.Printf("        %s│        Type /help for commands, use Ctrl+D to quit the session            │%s\n", colorDim, colorReset)
-	fmt.Printf("        %s│                End a line with \\ for multiline input                      │%s\n", colorDim, colorReset)
+	fmt.Printf("        %s│             Type \"\"\" or end a line with \\ for multiline input             │%s\n", colorDim, colorReset)
 	fmt.Printf("        %s│                                                                           │%s\n", colorDim, colorReset)
 	fmt.Printf("        %s╰───────────────────────────────────────────────────────────────────────────╯%s\n", colorDim, colorReset)
 	fmt.Println()
@@ -118,11 +122,22 @@ type InteractiveSession struct {
 	messagesMu     sync.RWMutex // Protects messages slice
 	exitFlag       bool
 	inputBuffer    []string
+	fence          string // Line that ends the fenced multiline message being written, "" if none
+	paste          *pasteReader
 	history        *history.History
 	conversationID string
 	interruptCtx   *InterruptibleContext
 	lastUserInput  string
 	lastResponse   string
+	lastCitations  []string
+	lastRelated    []string      // Related questions returned with the last response
+	lastFinish     string        // Why the model stopped the last response, such as "length"
+	usage          history.Usage // Usage of the conversation, since it started when resumed
+	// generatedTitles holds the titles generated in the background by
+	// conversation ID, until they are saved with the history
+	generatedTitles map[string]string
+	titlesMu        sync.Mutex
+	titles          sync.WaitGroup // Title requests in progress
 }
 
 // runInteractive starts the interactive chat mode
@@ -135,35 +150,28 @@ func (app *App) runInteractive(useColor bool) {
 		fmt.Println()
 	}
 
-	hist := history.NewHistory()
+	hist := app.newHistory()
 	if err := hist.Load(); err != nil {
-		fmt.Fprintf(os.Stderr, "Note: Could not load history: %v\n", err)
+		logging.Warn("Failed to load history", logging.Err(err))
+		display.ShowWarning(fmt.Sprintf("Could not load history: %v", err))
 	}
 
-	client := api.NewClient(app.cfg)
-
 	session := &InteractiveSession{
 		app:    app,
-		client: client,
+		client: app.newClient(),
 		messages: []api.Message{
-			{Role: "system", Content: config.DefaultSystemMessage},
+			{Role: "system", Content: app.cfg.GetSystemPrompt()},
 		},
 		exitFlag:       false,
 		history:        hist,
 		conversationID: uuid.New().String(),
 		interruptCtx:   NewInterruptibleContext(),
+		paste:          newPasteReader(prompt.NewStdinReader(), os.Stdout),
 	}
 
-	session.client.SetKeyRotationCallback(func(fromIndex, toIndex int, totalKeys int) {
-		display.ShowKeyRotation(fromIndex, toIndex, totalKeys)
-	})
-
-	session.client.SetRetryCallback(func(info retry.RetryInfo) {
-		display.ShowRetry(info.Attempt+1, info.MaxRetries, info.NextBackoff)
-	})
-
 	p := prompt.New(
 		session.executor,
+		prompt.WithReader(session.paste),
 		prompt.WithCompleter(session.completer),
 		prompt.WithPrefix("> "),
 		prompt.WithTitle("Perplexity CLI"),
@@ -214,16 +222,13 @@ func (s *InteractiveSession) saveHistory() {
 		return
 	}
 
+	// Wait briefly for a title of the conversation being generated
+	s.waitForTitles(titleSaveWait)
+
 	s.messagesMu.RLock()
 	msgCount := len(s.messages)
 	if msgCount > 1 {
-		historyMessages := make([]history.Message, msgCount)
-		for i, msg := range s.messages {
-			historyMessages[i] = history.Message{
-				Role:    msg.Role,
-				Content: msg.Content,
-			}
-		}
+		historyMessages := toHistoryMessages(s.messages)
 		s.messagesMu.RUnlock()
 
 		if !s.history.UpdateConversation(s.conversationID, historyMessages) {
@@ -233,21 +238,96 @@ func (s *InteractiveSession) saveHistory() {
 				historyMessages,
 			)
 		}
+		conv := s.history.GetConversation(s.conversationID)
+		conv.Usage = s.usage
+		if title := s.generatedTitle(s.conversationID); title != "" {
+			conv.GeneratedTitle = title
+		}
 		if err := s.history.Save(); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Could not save history: %v\n", err)
+			logging.Warn("Failed to save history", logging.Err(err))
+			display.ShowWarning(fmt.Sprintf("Could not save history: %v", err))
 		}
 	} else {
 		s.messagesMu.RUnlock()
 	}
 }
 
-// appendMessage safely appends a message to the messages slice
+// toHistoryMessages converts messages for storage in the history
+func toHistoryMessages(messages []api.Message) []history.Message {
+	historyMessages := make([]history.Message, len(messages))
+	for i, msg := range messages {
+		historyMessages[i] = history.Message{
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Citations: msg.Citations,
+			Time:      msg.Time,
+			Status:    history.ResponseStatus(msg.Status),
+		}
+	}
+	return historyMessages
+}
+
+// currentConversation returns the conversation being held, as it would be
+// stored in the history, with the tags it was given
+func (s *InteractiveSession) currentConversation() *history.ConversationEntry {
+	now := time.Now()
+	conv := &history.ConversationEntry{
+		ID:        s.conversationID,
+		Model:     s.app.cfg.Model,
+		Messages:  toHistoryMessages(s.getMessages()),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Usage:     s.usage,
+	}
+	if s.history != nil {
+		if stored := s.history.GetConversation(s.conversationID); stored != nil {
+			conv.CreatedAt, conv.Tags = stored.CreatedAt, stored.Tags
+			conv.GeneratedTitle = stored.GeneratedTitle
+		}
+	}
+	if title := s.generatedTitle(s.conversationID); title != "" {
+		conv.GeneratedTitle = title
+	}
+	return conv
+}
+
+// appendMessage safely appends a message to the messages slice, timestamped
+// now unless it has a time
 func (s *InteractiveSession) appendMessage(msg api.Message) {
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
 	s.messagesMu.Lock()
 	s.messages = append(s.messages, msg)
 	s.messagesMu.Unlock()
 }
 
+// appendResponse adds a response to the conversation, with the status its
+// answer and the error of its request give it, and makes it the last
+// response. A truncated answer is reported. Returns the status.
+func (s *InteractiveSession) appendResponse(response string, err error) history.ResponseStatus {
+	status := responseStatus(response, s.lastFinish, err)
+	s.appendMessage(api.Message{Role: "assistant", Content: response, Status: string(status)})
+	s.lastResponse = response
+	if status.Unanswered() {
+		s.lastResponse = ""
+	}
+	if status == history.StatusTruncated {
+		display.ShowWarning("The answer was cut off at the token limit")
+	}
+	return status
+}
+
+// setLastCitations records the citations of the last message, the answer
+// they were returned with
+func (s *InteractiveSession) setLastCitations(citations []string) {
+	s.messagesMu.Lock()
+	if n := len(s.messages); n > 0 && s.messages[n-1].Role == "assistant" {
+		s.messages[n-1].Citations = citations
+	}
+	s.messagesMu.Unlock()
+}
+
 // removeLastMessage safely removes the last message from the messages slice
 func (s *InteractiveSession) removeLastMessage() {
 	s.messagesMu.Lock()
@@ -280,11 +360,46 @@ func (s *InteractiveSession) setMessages(msgs []api.Message) {
 	s.messagesMu.Unlock()
 }
 
+// requestMessages returns the messages to send, with tool instructions
+// appended to the system prompt when tools are configured
+func (s *InteractiveSession) requestMessages() []api.Message {
+	messages := withoutUnanswered(s.getMessages())
+	toolPrompt := tools.SystemPrompt(s.app.cfg.Tools)
+	if toolPrompt != "" && len(messages) > 0 && messages[0].Role == "system" {
+		messages[0].Content = messages[0].Content + "\n\n" + toolPrompt
+	}
+	return messages
+}
+
 // executor handles the execution of each input line
 func (s *InteractiveSession) executor(input string) {
 	if s.exitFlag {
 		return
 	}
+	pasted := s.paste != nil && s.paste.takePasted()
+
+	// Fenced multiline input: every line up to the closing fence is part of
+	// the message, so pasted code keeps its backslashes and slash lines
+	if s.fence != "" {
+		s.fencedLine(input)
+		return
+	}
+	if fence := strings.TrimSpace(input); len(s.inputBuffer) == 0 && (fence == multilineFence || fence == "/multiline") {
+		s.fence = fence
+		fmt.Printf("Multiline mode: end the message with a line containing only %s\n", fence)
+		fmt.Print("... ")
+		return
+	}
+
+	// A pasted block of lines is one message, even if it starts with / or
+	// ends with \
+	if pasted && strings.Contains(input, "\n") {
+		s.inputBuffer = append(s.inputBuffer, input)
+		input = strings.TrimSpace(strings.Join(s.inputBuffer, "\n"))
+		s.inputBuffer = nil
+		s.send(input)
+		return
+	}
 
 	// Handle multiline input
 	if strings.HasSuffix(input, "\\") {
@@ -294,6 +409,13 @@ func (s *InteractiveSession) executor(input string) {
 		return
 	}
 
+	// /paste on a continuation line adds the clipboard to the message being written
+	if len(s.inputBuffer) > 0 && strings.TrimSpace(input) == "/paste" {
+		s.pasteIntoBuffer()
+		fmt.Print("... ")
+		return
+	}
+
 	if len(s.inputBuffer) > 0 {
 		s.inputBuffer = append(s.inputBuffer, input)
 		input = strings.Join(s.inputBuffer, "\n")
@@ -313,47 +435,162 @@ func (s *InteractiveSession) executor(input string) {
 		return
 	}
 
-	// Validate and sanitize the input
-	input = validation.SanitizePrompt(input)
-	result := validation.ValidatePrompt(input)
-	if !result.Valid {
-		display.ShowError(result.Error.Error())
+	s.send(input)
+}
+
+// fencedLine adds a line to the fenced multiline message, or sends the
+// message when the line is the closing fence
+func (s *InteractiveSession) fencedLine(input string) {
+	switch strings.TrimSpace(input) {
+	case s.fence:
+		message := strings.Join(s.inputBuffer, "\n")
+		s.inputBuffer = nil
+		s.fence = ""
+		if strings.TrimSpace(message) != "" {
+			s.send(message)
+		}
 		return
+	case "/paste":
+		s.pasteIntoBuffer()
+	default:
+		s.inputBuffer = append(s.inputBuffer, input)
+	}
+	fmt.Print("... ")
+}
+
+// expandFileRefs adds the contents of the files referenced with @path to a
+// message, relative to the working directory
+func expandFileRefs(input string) (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	expanded, files, missing, err := fileref.Expand(input, dir)
+	if err != nil {
+		return "", err
+	}
+	for _, path := range missing {
+		display.ShowWarning(fmt.Sprintf("No file %s; @%s is sent as written", path, path))
+	}
+	for _, f := range files {
+		fmt.Printf("Attached %s (%s)\n", f.Path, fileref.FormatSize(f.Size))
+	}
+	return expanded, nil
+}
+
+// send expands a message typed by the user and sends it, continuing with
+// any related question picked after the answer
+func (s *InteractiveSession) send(input string) {
+	input, ok := s.expandInput(input)
+	for ok && input != "" {
+		input = s.chat(input)
+	}
+}
+
+// expandInput runs the !{command} placeholders of a message, if enabled,
+// and adds the files it references with @path. Returns false if the
+// message should not be sent.
+func (s *InteractiveSession) expandInput(input string) (string, bool) {
+	input, ok := s.interpolateCommands(input)
+	if !ok {
+		return "", false
+	}
+	input, err := expandFileRefs(input)
+	if err != nil {
+		display.ShowError(err.Error())
+		return "", false
+	}
+	return input, true
+}
+
+// chat sends a user message and shows the response. Returns the related
+// question picked to ask next, or "" to return to the prompt.
+func (s *InteractiveSession) chat(input string) string {
+	input, err := s.app.cleanPrompt(input)
+	if err != nil {
+		display.ShowError(err.Error())
+		return ""
 	}
-	input = result.Cleaned
 
-	// Regular chat
 	s.lastUserInput = input
 	s.appendMessage(api.Message{Role: "user", Content: input})
 	fmt.Println()
 
 	response, citations, err := s.sendInteractiveMessage()
-	if err != nil {
-		if err == context.Canceled {
-			s.removeLastMessage()
-			return
-		}
+	if err != nil && err != context.Canceled {
 		msg, hint := display.FormatNetworkError(err)
 		display.ShowFriendlyError(msg, hint)
-
-		// On network error, we keep the user message but add a placeholder response
-		// so that roles continue to alternate for future requests/retries.
-		s.lastResponse = config.FailedResponsePlaceholder
-		s.appendMessage(api.Message{Role: "assistant", Content: s.lastResponse})
-		return
+	}
+	if s.appendResponse(response, err).Unanswered() {
+		s.lastCitations = nil
+		return ""
 	}
 
-	if response == "" {
-		response = config.FailedResponsePlaceholder
+	if len(s.app.cfg.Tools) > 0 {
+		response, citations = s.runToolCalls(response, citations)
+		s.lastResponse = response
 	}
-	s.lastResponse = response
-	s.appendMessage(api.Message{Role: "assistant", Content: response})
+	s.lastCitations = citations
+	s.setLastCitations(citations)
+	s.startTitle()
 
 	if s.app.cfg.Citations && len(citations) > 0 {
 		fmt.Println()
 		display.ShowCitations(citations)
 	}
 	fmt.Println()
+
+	return s.pickRelatedQuestion()
+}
+
+// runToolCalls executes the tools requested by the assistant, feeding each
+// observation back until it answers without a tool call. Returns the final
+// response and its citations.
+func (s *InteractiveSession) runToolCalls(response string, citations []string) (string, []string) {
+	for i := 0; i < tools.MaxIterations; i++ {
+		call, ok := tools.ParseCall(response)
+		if !ok {
+			return response, citations
+		}
+
+		var observation string
+		tool, found := tools.Find(s.app.cfg.Tools, call.Name)
+		if !found {
+			observation = tools.FormatObservation(call.Name, "", fmt.Errorf("unknown tool %q", call.Name))
+		} else {
+			display.ShowToolCall(tool.Name, call.Input)
+			ctx := s.interruptCtx.Start()
+			output, err := tools.Run(ctx, tool, call.Input)
+			cancelled := ctx.Err() == context.Canceled
+			s.interruptCtx.Stop()
+			if cancelled {
+				return response, citations
+			}
+			observation = tools.FormatObservation(tool.Name, output, err)
+		}
+
+		s.appendMessage(api.Message{Role: "user", Content: observation})
+		fmt.Println()
+
+		next, nextCitations, err := s.sendInteractiveMessage()
+		if err != nil {
+			s.removeLastMessage()
+			if err != context.Canceled {
+				msg, hint := display.FormatNetworkError(err)
+				display.ShowFriendlyError(msg, hint)
+			}
+			return response, citations
+		}
+		if s.appendResponse(next, nil).Unanswered() {
+			return "", nil
+		}
+		response, citations = next, nextCitations
+	}
+
+	if _, ok := tools.ParseCall(response); ok {
+		display.ShowError(fmt.Sprintf("Stopped after %d tool calls", tools.MaxIterations))
+	}
+	return response, citations
 }
 
 // sendInteractiveMessage sends a message and returns the response
@@ -362,62 +599,97 @@ func (s *InteractiveSession) sendInteractiveMessage() (string, []string, error)
 	defer s.interruptCtx.Stop()
 
 	// Get a copy of messages for thread-safe access
-	messages := s.getMessages()
+	messages := s.requestMessages()
+	s.lastRelated = nil
+	s.lastFinish = ""
 
 	if s.app.cfg.Stream {
 		var fullContent strings.Builder
 		var citations []string
+		var images []api.Image
 		firstChunk := true
-
-		sp := display.NewSpinner("Thinking...")
-		sp.Start()
+		wait := newWaitIndicator(s.client, s.app.cfg.Model, "Thinking...")
+		thinkFilter := s.app.newContentWriter(wait.Stop)
+		wait.Start()
 
 		err := s.client.QueryStreamWithHistoryContext(ctx, messages,
 			func(content string) {
 				if firstChunk {
 					firstChunk = false
-					sp.Stop()
+					startReasoningStage(wait)
 				}
 				fullContent.WriteString(content)
-				fmt.Print(content)
+				thinkFilter.Write(content)
 			},
 			func(resp *api.ChatResponse) {
 				if resp != nil {
 					citations = resp.Citations
+					images = resp.Images
+					s.lastRelated = resp.RelatedQuestions
+					s.lastFinish = resp.FinishReason()
+					s.usage.Add(s.app.responseUsage(resp))
 				}
 			},
 		)
+		wait.Stop()
+		thinkFilter.Flush()
 
+		// Reasoning is not kept in the conversation, and the answer is kept
+		// with its citation markers as returned, whatever their display
+		_, raw := display.SplitThinking(fullContent.String())
 		if err != nil {
+			// What was received before an interruption is kept with it
+			if err == context.Canceled {
+				fmt.Println()
+				return raw, nil, err
+			}
 			return "", nil, err
 		}
 
 		if s.app.cfg.Render {
+			_, answer := s.app.splitContent(fullContent.String(), citations)
 			fmt.Println("\n---")
-			display.ShowContentRendered(fullContent.String())
-			return fullContent.String(), citations, nil
+			display.ShowContentRendered(answer)
+		} else {
+			fmt.Println()
 		}
-		fmt.Println()
-		return fullContent.String(), citations, nil
+		s.showImages(images)
+		return raw, citations, nil
 	}
 
 	// Non-streaming
-	sp := display.NewSpinner("Thinking...")
-	sp.Start()
+	wait := newWaitIndicator(s.client, s.app.cfg.Model, "Thinking...")
+	wait.Start()
 
 	resp, err := s.client.QueryWithHistoryContext(ctx, messages)
-	sp.Stop()
+	wait.Stop()
 
 	if err != nil {
 		return "", nil, err
 	}
 
-	content := resp.GetContent()
+	s.lastRelated = resp.RelatedQuestions
+	s.lastFinish = resp.FinishReason()
+	s.usage.Add(s.app.responseUsage(resp))
+	thinking, content := s.app.splitContent(resp.GetContent(), resp.Citations)
+	if !s.app.cfg.HideThinking {
+		display.ShowThinking(thinking, s.app.shouldUseColor())
+	}
 	if s.app.cfg.Render {
 		display.ShowContentRendered(content)
 	} else {
 		display.ShowContent(content)
 	}
+	s.showImages(resp.Images)
+
+	_, raw := display.SplitThinking(resp.GetContent())
+	return raw, resp.Citations, nil
+}
 
-	return content, resp.Citations, nil
+// showImages lists the images returned with a response, if requested
+func (s *InteractiveSession) showImages(images []api.Image) {
+	if s.app.cfg.Images && len(images) > 0 {
+		fmt.Println()
+		display.ShowImages(imageLinks(images))
+	}
 }
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -207,6 +208,56 @@ func TestExecutorMultilineInput(t *testing.T) {
 	}
 }
 
+func TestExecutorFencedMultilineInput(t *testing.T) {
+	for _, fence := range []string{`"""`, "/multiline"} {
+		t.Run(fence, func(t *testing.T) {
+			session := newTestSession()
+			initialMsgCount := len(session.messages)
+
+			captureOutput(func() {
+				session.executor(fence)
+				session.executor("func main() {")
+				session.executor(`	fmt.Println("a\\b") \`)
+				session.executor("/help")
+				session.executor("}")
+			})
+			want := []string{"func main() {", `	fmt.Println("a\\b") \`, "/help", "}"}
+			if !slices.Equal(session.inputBuffer, want) {
+				t.Errorf("inputBuffer = %q, want %q", session.inputBuffer, want)
+			}
+			if session.fence != fence {
+				t.Errorf("fence = %q, want %q", session.fence, fence)
+			}
+
+			// Closing an empty block sends nothing
+			session.inputBuffer = nil
+			captureOutput(func() {
+				session.executor("  " + fence)
+			})
+			if session.fence != "" || session.inputBuffer != nil {
+				t.Errorf("fence = %q, inputBuffer = %q, want the block closed", session.fence, session.inputBuffer)
+			}
+			if len(session.messages) != initialMsgCount {
+				t.Error("An empty fenced block should not send a message")
+			}
+		})
+	}
+}
+
+func TestExecutorFenceAfterContinuation(t *testing.T) {
+	session := newTestSession()
+
+	// A fence inside a backslash continuation is an ordinary line
+	session.executor("line 1\\")
+	session.executor(`"""\`)
+	if session.fence != "" {
+		t.Errorf("fence = %q, want none", session.fence)
+	}
+	if len(session.inputBuffer) != 2 {
+		t.Errorf("Expected 2 lines in buffer, got %d", len(session.inputBuffer))
+	}
+}
+
 func TestExecutorExitFlag(t *testing.T) {
 	session := newTestSession()
 	session.exitFlag = true
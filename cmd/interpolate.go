@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/shell"
+	"github.com/quocvuong92/perplexity-cli/internal/tools"
+)
+
+// errInterpolationCancelled aborts a message whose commands were interrupted
+var errInterpolationCancelled = errors.New("cancelled")
+
+// interpolateCommands replaces the !{command} placeholders of a message with
+// the commands' output, after the user confirms running them. Without
+// --shell-interpolation the message is sent as typed. Returns false if the
+// message should not be sent.
+func (s *InteractiveSession) interpolateCommands(input string) (string, bool) {
+	if !s.app.cfg.ShellInterpolation {
+		return input, true
+	}
+	commands := shell.Placeholders(input)
+	if len(commands) == 0 {
+		return input, true
+	}
+
+	fmt.Println("\nThe message runs these commands:")
+	for _, command := range commands {
+		fmt.Printf("  %s\n", command)
+	}
+	if !confirm("Run them and insert their output?") {
+		fmt.Println("Cancelled.")
+		return "", false
+	}
+
+	output, err := shell.Interpolate(input, s.runInterpolated)
+	if err != nil {
+		if !errors.Is(err, errInterpolationCancelled) {
+			display.ShowError(err.Error())
+		}
+		return "", false
+	}
+	return output, true
+}
+
+// runInterpolated runs a placeholder's command and returns its output as it
+// goes into the message. A command that fails still has its output inserted,
+// since explaining an error is what placeholders are most used for.
+func (s *InteractiveSession) runInterpolated(command string) (string, error) {
+	ctx := s.interruptCtx.Start()
+	defer s.interruptCtx.Stop()
+	ctx, cancel := context.WithTimeout(ctx, tools.DefaultTimeout)
+	defer cancel()
+
+	var out bytes.Buffer
+	cmd := shell.Command(ctx, command)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return "", errInterpolationCancelled
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return "", fmt.Errorf("command %q timed out after %v", command, tools.DefaultTimeout)
+	case err != nil:
+		display.ShowWarning(fmt.Sprintf("Command %q failed: %v", command, err))
+	}
+	return formatInterpolated(out.String()), nil
+}
+
+// formatInterpolated formats command output for a message: a single line is
+// inserted as is, and several lines as a code block
+func formatInterpolated(output string) string {
+	output = strings.TrimRight(output, "\n")
+	if len(output) > tools.MaxOutputBytes {
+		output = output[:tools.MaxOutputBytes] + "\n[output truncated]"
+	}
+	if !strings.Contains(output, "\n") {
+		return output
+	}
+	fence := "```"
+	for strings.Contains(output, fence) {
+		fence += "`"
+	}
+	return "\n" + fence + "\n" + output + "\n" + fence + "\n"
+}
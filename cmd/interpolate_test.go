@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestInterpolateCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		session := newTestSession()
+		got, ok := session.interpolateCommands("explain !{echo hi}")
+		if !ok || got != "explain !{echo hi}" {
+			t.Errorf("interpolateCommands() = %q, %v, want the message unchanged", got, ok)
+		}
+	})
+
+	t.Run("confirmed", func(t *testing.T) {
+		session := newTestSession()
+		session.app.cfg.ShellInterpolation = true
+		session.interruptCtx = NewInterruptibleContext()
+		stubReadLine(t, "y")
+
+		var got string
+		var ok bool
+		output := captureOutput(func() {
+			got, ok = session.interpolateCommands("explain !{echo hi} and !{printf 'a\\nb'; exit 1}")
+		})
+		if !strings.Contains(output, "echo hi") {
+			t.Errorf("output %q should list the commands", output)
+		}
+		want := "explain hi and \n```\na\nb\n```\n"
+		if !ok || got != want {
+			t.Errorf("interpolateCommands() = %q, %v, want %q", got, ok, want)
+		}
+	})
+
+	t.Run("declined", func(t *testing.T) {
+		session := newTestSession()
+		session.app.cfg.ShellInterpolation = true
+		stubReadLine(t, "n")
+
+		var ok bool
+		captureOutput(func() {
+			_, ok = session.interpolateCommands("explain !{echo hi}")
+		})
+		if ok {
+			t.Error("interpolateCommands() should not send the message when declined")
+		}
+	})
+}
+
+func TestFormatInterpolated(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"single line", "main.go:3: undefined: x\n", "main.go:3: undefined: x"},
+		{"empty", "", ""},
+		{"several lines", "a\nb\n", "\n```\na\nb\n```\n"},
+		{"fenced output", "```\nx\n```", "\n````\n```\nx\n```\n````\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatInterpolated(tt.output); got != tt.want {
+				t.Errorf("formatInterpolated(%q) = %q, want %q", tt.output, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+)
+
+// Output formats for one-shot queries
+const (
+	formatText  = "text"  // Plain or rendered markdown
+	formatJSONL = "jsonl" // One JSON event per line
+)
+
+// streamEvent is a line of --format jsonl output
+type streamEvent struct {
+	Type      string     `json:"type"` // delta, citations, related_questions, usage, conversation, error or done
+	Content   string     `json:"content,omitempty"`
+	Citations []string   `json:"citations,omitempty"`
+	Questions []string   `json:"questions,omitempty"`
+	Usage     *api.Usage `json:"usage,omitempty"`
+	ID        string     `json:"id,omitempty"`
+	Message   string     `json:"message,omitempty"`
+}
+
+// eventWriter writes stream events as JSON lines
+type eventWriter struct {
+	enc *json.Encoder
+}
+
+func newEventWriter(w io.Writer) *eventWriter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &eventWriter{enc: enc}
+}
+
+// emit writes one event
+func (w *eventWriter) emit(event streamEvent) {
+	_ = w.enc.Encode(event)
+}
+
+// validateFormat checks the --format flag
+func (app *App) validateFormat() error {
+	switch app.format {
+	case "", formatText:
+		return nil
+	case formatJSONL:
+		if app.compare != "" || app.watch > 0 || app.extractCode {
+			return fmt.Errorf("--format jsonl cannot be combined with --compare, --watch or --extract-code")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid format %q (use text or jsonl)", app.format)
+	}
+}
+
+// runJSONL streams a single query, writing each delta, the citations, related
+// questions and usage as JSON lines on stdout
+func (app *App) runJSONL(ctx context.Context, query string) error {
+	events := newEventWriter(os.Stdout)
+	app.events = events
+
+	var finalResp *api.ChatResponse
+	var fullContent strings.Builder
+	err := app.client.QueryStreamWithHistoryContext(ctx, app.queryMessages(query),
+		func(content string) {
+			fullContent.WriteString(content)
+			events.emit(streamEvent{Type: "delta", Content: content})
+		},
+		func(resp *api.ChatResponse) {
+			finalResp = resp
+		},
+	)
+	if err != nil {
+		msg, _ := display.FormatNetworkError(err)
+		events.emit(streamEvent{Type: "error", Message: msg})
+		return err
+	}
+
+	var citations []string
+	if finalResp != nil {
+		citations = finalResp.Citations
+		if len(finalResp.Citations) > 0 {
+			events.emit(streamEvent{Type: "citations", Citations: finalResp.Citations})
+		}
+		if len(finalResp.RelatedQuestions) > 0 {
+			events.emit(streamEvent{Type: "related_questions", Questions: finalResp.RelatedQuestions})
+		}
+		if finalResp.Usage.TotalTokens > 0 {
+			events.emit(streamEvent{Type: "usage", Usage: &finalResp.Usage})
+		}
+	}
+
+	_, answer := app.splitContent(fullContent.String(), citations)
+	if app.cfg.OutputFile != "" {
+		if err := os.WriteFile(app.cfg.OutputFile, []byte(answer), 0600); err != nil {
+			display.ShowError(fmt.Sprintf("Failed to save output: %v", err))
+		}
+	}
+	// History keeps the answer as returned, with its citation markers
+	_, raw := display.SplitThinking(fullContent.String())
+	app.saveConversation(query, raw, citations, finalResp)
+
+	events.emit(streamEvent{Type: "done"})
+	return nil
+}
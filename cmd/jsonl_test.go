@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+func TestValidateFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		app     App
+		wantErr bool
+	}{
+		{"default", App{}, false},
+		{"text", App{format: formatText}, false},
+		{"jsonl", App{format: formatJSONL}, false},
+		{"unknown", App{format: "xml"}, true},
+		{"jsonl with compare", App{format: formatJSONL, compare: "sonar,sonar-pro"}, true},
+		{"jsonl with watch", App{format: formatJSONL, watch: time.Hour}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.app.validateFormat()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunJSONL(t *testing.T) {
+	server := createMockStreamServer(t, []string{"Hello", " world"}, &api.ChatResponse{
+		Citations: []string{"https://example.com"},
+		Usage:     api.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5},
+	})
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", Model: "sonar"}
+	app := &App{cfg: cfg, format: formatJSONL}
+	app.client = api.NewClient(cfg)
+	app.client.SetBaseURL(server.URL)
+
+	output := captureStdoutOnly(func() {
+		app.runJSONL(context.Background(), "test query")
+	})
+
+	var types []string
+	var content strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var event streamEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("line %q is not JSON: %v", line, err)
+		}
+		types = append(types, event.Type)
+		switch event.Type {
+		case "delta":
+			content.WriteString(event.Content)
+		case "citations":
+			if len(event.Citations) != 1 {
+				t.Errorf("citations = %v, want 1", event.Citations)
+			}
+		case "usage":
+			if event.Usage == nil || event.Usage.TotalTokens != 5 {
+				t.Errorf("usage = %+v, want 5 total tokens", event.Usage)
+			}
+		}
+	}
+
+	want := "delta,delta,citations,usage,done"
+	if got := strings.Join(types, ","); got != want {
+		t.Errorf("event types = %s, want %s", got, want)
+	}
+	if content.String() != "Hello world" {
+		t.Errorf("content = %q, want %q", content.String(), "Hello world")
+	}
+}
+
+func TestRunJSONLError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": {"message": "Internal server error"}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", Model: "sonar"}
+	app := &App{cfg: cfg, format: formatJSONL}
+	app.client = api.NewClient(cfg)
+	app.client.SetBaseURL(server.URL)
+
+	output := captureStdoutOnly(func() {
+		app.runJSONL(context.Background(), "test query")
+	})
+	if !strings.Contains(output, `"type":"error"`) {
+		t.Errorf("output = %q, want an error event", output)
+	}
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/chunk"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/tokens"
+	"github.com/quocvuong92/perplexity-cli/internal/validation"
+)
+
+// longInputMapPrompt asks for a summary of one part of a long piped input
+const longInputMapPrompt = `You condense one part of a long input so that requests about the whole input can
+still be answered. Keep its key points, facts, figures, names, dates, code identifiers,
+questions and instructions, in the order they appear, as a concise markdown list. Use
+only the text given, not other sources, and do not add an introduction.`
+
+// refinePrompt asks to update a running summary with the next part of a long input
+const refinePrompt = `You keep a running summary of a long input that arrives in parts. Update the
+summary given with the next part, keeping the key points, facts, figures, names, dates,
+code identifiers, questions and instructions of all parts so far, in order, as a concise
+markdown list. Use only the text given and reply with the updated summary only.`
+
+// condensedQuery frames the summaries of an oversized input as the query
+const condensedQuery = `The input below was too long to send at once (about %d tokens), so it was condensed
+into summaries of its consecutive parts. Respond to the input as a whole based on them.
+
+%s`
+
+// truncatedQuery frames the start of an oversized input as the query
+const truncatedQuery = `The input below was too long to send at once and was cut after about %d of its %d
+tokens. Respond to it based on this start.
+
+%s`
+
+// condenseInput shortens piped input over the prompt length limit with the
+// configured chunk strategy and returns the validated query to send instead.
+// The chunks are summarized with the query's model.
+func (app *App) condenseInput(ctx context.Context, text string) (string, error) {
+	cfg, err := app.taskConfig(app.cfg.Model, text)
+	if err != nil {
+		return "", err
+	}
+
+	maxTokens := app.cfg.ChunkTokens
+	if maxTokens == 0 {
+		maxTokens = chunkTokens(cfg.Model)
+	}
+	strategy := app.cfg.ChunkStrategy
+	if strategy == "" {
+		strategy = config.ChunkStrategyMapReduce
+	}
+	total := tokens.Estimate(text)
+	if !app.cfg.Quiet {
+		fmt.Fprintf(os.Stderr, "Note: The input is about %d tokens, over the prompt limit; shortening it with the %s strategy\n", total, strategy)
+	}
+
+	var query string
+	switch strategy {
+	case config.ChunkStrategyTruncate:
+		kept := truncateRunes(text, validation.MaxPromptLength-utf8.RuneCountInString(truncatedQuery)-20)
+		query = fmt.Sprintf(truncatedQuery, tokens.Estimate(kept), total, kept)
+	case config.ChunkStrategyRefine:
+		summary, err := refineChunks(ctx, app.newClientFor(cfg), text, maxTokens)
+		if err != nil {
+			return "", err
+		}
+		query = fmt.Sprintf(condensedQuery, total, summary)
+	default:
+		summaries := text
+		for round := 0; round < maxReduceRounds; round++ {
+			if summaries, err = summarizeChunks(ctx, app.pooledClients(cfg), summaries, longInputMapPrompt, maxTokens, app.poolOptions()); err != nil {
+				return "", err
+			}
+			query = fmt.Sprintf(condensedQuery, total, summaries)
+			if utf8.RuneCountInString(query) <= validation.MaxPromptLength {
+				break
+			}
+		}
+	}
+
+	query, err = app.cleanPrompt(query)
+	if err != nil {
+		return "", fmt.Errorf("the input is still too long after shortening it; try a smaller --chunk-size or --chunk-strategy truncate: %w", err)
+	}
+	return query, nil
+}
+
+// refineChunks splits text into chunks of maxTokens and builds a summary of
+// them by updating it with each chunk in turn
+func refineChunks(ctx context.Context, client *api.Client, text string, maxTokens int) (string, error) {
+	chunks := chunk.Split(text, maxTokens)
+	var summary string
+	for i, part := range chunks {
+		input := part
+		if summary != "" {
+			input = "Summary so far:\n" + summary + "\n\nNext part:\n" + part
+		}
+		resp, err := queryWithSpinner(ctx, client, fmt.Sprintf("Refining part %d/%d...", i+1, len(chunks)), []api.Message{
+			{Role: "system", Content: refinePrompt},
+			{Role: "user", Content: input},
+		})
+		if err != nil {
+			return "", err
+		}
+		summary = cleanSummary(resp.GetContent())
+	}
+	return summary, nil
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/chunk"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/validation"
+)
+
+func TestCondenseInput(t *testing.T) {
+	var mu sync.Mutex
+	var requests []api.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "- key point [1]"}}},
+		})
+	}))
+	defer server.Close()
+
+	paragraph := strings.Repeat("lorem ipsum dolor sit amet ", 400)
+	text := strings.TrimSpace(strings.Repeat(paragraph+"\n\n", 12))
+	if utf8.RuneCountInString(text) <= validation.MaxPromptLength {
+		t.Fatal("test input should be over the prompt length limit")
+	}
+
+	chunks := len(chunk.Split(text, 10000))
+	if chunks < 2 {
+		t.Fatalf("test input split into %d chunks, want several", chunks)
+	}
+
+	condense := func(strategy string) string {
+		t.Helper()
+		requests = nil
+		app := NewApp()
+		app.cfg.APIURL = server.URL
+		app.cfg.APIKey = "pplx-" + strings.Repeat("a", 40)
+		app.cfg.Model = "sonar"
+		app.cfg.Quiet = true
+		app.cfg.Concurrency = 4
+		app.cfg.ChunkTokens = 10000
+		app.cfg.ChunkStrategy = strategy
+
+		var query string
+		var err error
+		captureOutput(func() { query, err = app.condenseInput(context.Background(), text) })
+		if err != nil {
+			t.Fatalf("condenseInput(%s) error = %v", strategy, err)
+		}
+		return query
+	}
+
+	query := condense("")
+	if len(requests) != chunks || requests[0].Messages[0].Content != longInputMapPrompt {
+		t.Fatalf("map-reduce sent %d requests, want one summary per chunk", len(requests))
+	}
+	if !strings.HasPrefix(query, "The input below was too long") || !strings.Contains(query, fmt.Sprintf("Part %d of %d:\n- key point", chunks, chunks)) {
+		t.Errorf("map-reduce query = %q, want the numbered summaries", query)
+	}
+
+	query = condense(config.ChunkStrategyRefine)
+	if len(requests) != chunks || requests[0].Messages[0].Content != refinePrompt {
+		t.Fatalf("refine sent %d requests, want one per chunk", len(requests))
+	}
+	if !strings.HasPrefix(requests[1].Messages[1].Content, "Summary so far:\n- key point\n\nNext part:\nlorem") {
+		t.Errorf("refine request = %q, want the running summary with the next part", requests[1].Messages[1].Content)
+	}
+	if !strings.HasSuffix(query, "\n\n- key point") {
+		t.Errorf("refine query = %q, want the final summary", query)
+	}
+
+	query = condense(config.ChunkStrategyTruncate)
+	if len(requests) != 0 {
+		t.Errorf("truncate sent %d requests, want none", len(requests))
+	}
+	if n := utf8.RuneCountInString(query); n > validation.MaxPromptLength || !strings.Contains(query, "was cut after about") {
+		t.Errorf("truncate query has %d characters, want the start of the input within the limit", n)
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/chunk"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/pool"
+	"github.com/quocvuong92/perplexity-cli/internal/tokens"
+	"github.com/quocvuong92/perplexity-cli/internal/validation"
+)
+
+const (
+	// defaultChunkTokens is the chunk size for models with an unknown context window
+	defaultChunkTokens = 32000
+	// maxReduceRounds limits how often the summaries of the chunks are
+	// summarized again when they are still too long to combine
+	maxReduceRounds = 3
+)
+
+// chunkTokens returns the tokens of text sent in one request to model: half
+// its context window, leaving room for the prompt, the answer and the error
+// of the estimate
+func chunkTokens(model string) int {
+	if window, ok := validation.ContextWindows[model]; ok {
+		return window / 2
+	}
+	return defaultChunkTokens
+}
+
+// mapReduce answers over a text that may be too long for one request. A text
+// that fits is sent with reducePrompt as is. A longer one is split into
+// chunks that are each summarized with mapPrompt, concurrently with opts, and
+// the summaries are combined with reducePrompt, after summarizing them again
+// while they are still too long.
+func mapReduce(ctx context.Context, newClient clientFactory, text, mapPrompt, reducePrompt string, maxTokens int, opts pool.Options) (*api.ChatResponse, error) {
+	for round := 0; tokens.Estimate(text) > maxTokens && round < maxReduceRounds; round++ {
+		summaries, err := summarizeChunks(ctx, newClient, text, mapPrompt, maxTokens, opts)
+		if err != nil {
+			return nil, err
+		}
+		text = summaries
+	}
+
+	if err := opts.Limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return queryWithSpinner(ctx, newClient(), "Summarizing...", []api.Message{
+		{Role: "system", Content: reducePrompt},
+		{Role: "user", Content: text},
+	})
+}
+
+// summarizeChunks splits text into chunks of maxTokens, summarizes each with
+// mapPrompt on a worker pool and returns the numbered summaries. The failed
+// chunks are all reported.
+func summarizeChunks(ctx context.Context, newClient clientFactory, text, mapPrompt string, maxTokens int, opts pool.Options) (string, error) {
+	chunks := chunk.Split(text, maxTokens)
+	summaries := make([]string, len(chunks))
+	errs := runPooled(ctx, len(chunks), opts, "Summarizing parts", func(ctx context.Context, i int) error {
+		resp, err := newClient().QueryWithHistoryContext(ctx, []api.Message{
+			{Role: "system", Content: mapPrompt},
+			{Role: "user", Content: chunks[i]},
+		})
+		if err != nil {
+			return err
+		}
+		summaries[i] = fmt.Sprintf("Part %d of %d:\n%s", i+1, len(chunks), cleanSummary(resp.GetContent()))
+		return nil
+	})
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if err := pool.Join(errs, func(i int) string { return fmt.Sprintf("part %d of %d", i+1, len(chunks)) }); err != nil {
+		return "", err
+	}
+	return strings.Join(summaries, "\n\n"), nil
+}
+
+// cleanSummary removes reasoning and citation markers from a summary
+func cleanSummary(content string) string {
+	_, answer := display.SplitThinking(content)
+	return strings.TrimSpace(display.StripCitationMarkers(answer))
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/pool"
+)
+
+func TestChunkTokens(t *testing.T) {
+	if got := chunkTokens("sonar-pro"); got != 100000 {
+		t.Errorf("chunkTokens(sonar-pro) = %d, want half of its context window", got)
+	}
+	if got := chunkTokens("custom-model"); got != defaultChunkTokens {
+		t.Errorf("chunkTokens() of an unknown model = %d, want %d", got, defaultChunkTokens)
+	}
+}
+
+func TestMapReduce(t *testing.T) {
+	var mu sync.Mutex
+	var systems []string
+	var final string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		defer mu.Unlock()
+		systems = append(systems, req.Messages[0].Content)
+		answer := "short summary"
+		if req.Messages[0].Content == "reduce" {
+			final = req.Messages[1].Content
+			answer = "final answer"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: answer}}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", APIURL: server.URL, Model: "sonar"}
+	app := &App{cfg: cfg}
+	run := func(text string) string {
+		t.Helper()
+		systems, final = nil, ""
+		var resp *api.ChatResponse
+		var err error
+		captureOutput(func() {
+			resp, err = mapReduce(context.Background(), app.pooledClients(app.cfg), text, "map", "reduce", 100, pool.Options{Workers: 4})
+		})
+		if err != nil {
+			t.Fatalf("mapReduce() error = %v", err)
+		}
+		return resp.GetContent()
+	}
+
+	if got := run("A short document."); got != "final answer" || len(systems) != 1 || final != "A short document." {
+		t.Errorf("mapReduce() of a short text sent %q with %q, want one reduce request", systems, final)
+	}
+
+	paragraph := strings.Repeat("lorem ipsum dolor sit amet ", 12)
+	if got := run(strings.Repeat(paragraph+"\n\n", 3)); got != "final answer" {
+		t.Errorf("mapReduce() = %q, want the reduced answer", got)
+	}
+	if len(systems) != 4 || systems[0] != "map" || systems[3] != "reduce" {
+		t.Errorf("mapReduce() of a long text sent %q, want a map request per chunk then a reduce", systems)
+	}
+	if !strings.HasPrefix(final, "Part 1 of 3:\nshort summary\n\nPart 2 of 3:") {
+		t.Errorf("reduce input = %q, want the numbered summaries", final)
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+)
+
+// messageRoles lists the roles accepted in --messages-json input
+var messageRoles = []string{"system", "user", "assistant"}
+
+// loadMessages reads the --messages-json messages, which are sent verbatim
+// instead of a query. Returns the last user message as the query; exits on error.
+func (app *App) loadMessages(args []string) string {
+	if len(args) > 0 {
+		display.ShowError("a query cannot be given with --messages-json")
+		os.Exit(ExitInvalidInput)
+	}
+	if app.continueLast || app.conversationRef != "" || app.compare != "" {
+		display.ShowError("--messages-json cannot be combined with --continue, --conversation or --compare")
+		os.Exit(ExitInvalidInput)
+	}
+
+	messages, err := readMessagesJSON(app.messagesJSON, os.Stdin)
+	if err != nil {
+		display.ShowError(err.Error())
+		os.Exit(ExitInvalidInput)
+	}
+	app.messages = messages
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+// readMessagesJSON reads a messages array from a file, or from stdin if path is "-"
+func readMessagesJSON(path string, stdin io.Reader) ([]api.Message, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read messages: %w", err)
+	}
+	return parseMessagesJSON(data)
+}
+
+// parseMessagesJSON parses an OpenAI-style messages array, either bare or
+// as the "messages" field of a request body
+func parseMessagesJSON(data []byte) ([]api.Message, error) {
+	var messages []api.Message
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		var body struct {
+			Messages []api.Message `json:"messages"`
+		}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return nil, fmt.Errorf("failed to parse messages: %w", err)
+		}
+		messages = body.Messages
+	} else if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("failed to parse messages: %w", err)
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no messages given")
+	}
+	for i, msg := range messages {
+		if !slices.Contains(messageRoles, msg.Role) {
+			return nil, fmt.Errorf("message %d: invalid role %q (use system, user or assistant)", i+1, msg.Role)
+		}
+	}
+	return messages, nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+func TestParseMessagesJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"array", `[{"role":"system","content":"Be brief"},{"role":"user","content":"hi"}]`, 2, false},
+		{"request body", `{"model":"sonar","messages":[{"role":"user","content":"hi"}]}`, 1, false},
+		{"empty array", `[]`, 0, true},
+		{"invalid role", `[{"role":"tool","content":"x"}]`, 0, true},
+		{"invalid json", `[{"role":`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMessagesJSON([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMessagesJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("parseMessagesJSON() = %d messages, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMessagesJSON(t *testing.T) {
+	input := `[{"role":"user","content":"from stdin"}]`
+	got, err := readMessagesJSON("-", strings.NewReader(input))
+	if err != nil || len(got) != 1 || got[0].Content != "from stdin" {
+		t.Errorf("readMessagesJSON(-) = %+v, %v", got, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "messages.json")
+	if err := os.WriteFile(path, []byte(input), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err = readMessagesJSON(path, nil)
+	if err != nil || len(got) != 1 {
+		t.Errorf("readMessagesJSON(file) = %+v, %v", got, err)
+	}
+
+	if _, err := readMessagesJSON(filepath.Join(t.TempDir(), "missing.json"), nil); err == nil {
+		t.Error("readMessagesJSON() should fail for a missing file")
+	}
+}
+
+func TestQueryMessagesVerbatim(t *testing.T) {
+	messages := []api.Message{
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: "reply"},
+		{Role: "user", Content: "second"},
+	}
+	app := &App{cfg: &config.Config{SystemPrompt: "ignored"}, messages: messages}
+
+	got := app.queryMessages("second")
+	if len(got) != 3 || got[0].Content != "first" {
+		t.Errorf("queryMessages() = %+v, want the messages unchanged", got)
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/logging"
+	"github.com/quocvuong92/perplexity-cli/internal/models"
+)
+
+// loadModels adds the cached provider model list to the accepted models.
+// The cache is refreshed when refresh is set, or when listing models with a stale cache;
+// normal queries never wait on the network for it.
+func (app *App) loadModels(refresh bool) {
+	cache := models.NewCache()
+	if err := cache.Load(); err != nil {
+		logging.Warn("Failed to load model cache", logging.Err(err))
+	}
+
+	if refresh || (app.listModels && cache.IsStale(models.CacheTTL)) {
+		if err := app.fetchModels(cache); err != nil {
+			if refresh {
+				display.ShowWarning(err.Error())
+			} else {
+				logging.Debug("Using built-in model list", logging.Err(err))
+			}
+		}
+	}
+
+	app.cfg.RemoteModels = cache.Models
+}
+
+// fetchModels fetches the model list from the configured models URL and saves it to the cache.
+// The API key is only sent to the host of the API endpoint, never to a third-party manifest.
+func (app *App) fetchModels(cache *models.Cache) error {
+	var apiKey string
+	if sameHost(app.cfg.ModelsURL, app.cfg.APIURL) {
+		apiKey = app.cfg.APIKey
+		if apiKey == "" {
+			if keys := config.GetAPIKeysFromEnv(); len(keys) > 0 {
+				apiKey = keys[0]
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), models.FetchTimeout)
+	defer cancel()
+
+	fetched, err := models.Fetch(ctx, http.DefaultClient, app.cfg.ModelsURL, apiKey)
+	if err != nil {
+		return fmt.Errorf("could not refresh models from %s: %w", app.cfg.ModelsURL, err)
+	}
+
+	cache.Update(fetched, app.cfg.ModelsURL)
+	if err := cache.Save(); err != nil {
+		return err
+	}
+	logging.Debug("Refreshed model list", logging.Int("count", len(fetched)))
+	return nil
+}
+
+// sameHost reports whether the URLs a and b have the same scheme and host
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Host != "" && strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host)
+}
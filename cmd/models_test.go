@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/models"
+)
+
+func TestLoadModels(t *testing.T) {
+	t.Setenv(models.EnvCachePath, filepath.Join(t.TempDir(), models.CacheFileName))
+	t.Setenv(config.EnvAPIKey, "")
+	t.Setenv(config.EnvAPIKeys, "")
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"models":["sonar-ultra"]}`))
+	}))
+	defer server.Close()
+
+	app := NewApp()
+	app.cfg.ModelsURL = server.URL
+
+	// A plain query never fetches
+	app.loadModels(false)
+	if requests != 0 || len(app.cfg.RemoteModels) != 0 {
+		t.Fatalf("loadModels(false) fetched %d times, RemoteModels = %v", requests, app.cfg.RemoteModels)
+	}
+
+	// Listing with an empty cache fetches and caches
+	app.listModels = true
+	app.loadModels(false)
+	if requests != 1 || !slices.Equal(app.cfg.RemoteModels, []string{"sonar-ultra"}) {
+		t.Fatalf("loadModels() fetched %d times, RemoteModels = %v", requests, app.cfg.RemoteModels)
+	}
+
+	// A fresh cache is reused
+	app = NewApp()
+	app.cfg.ModelsURL = server.URL
+	app.listModels = true
+	app.loadModels(false)
+	if requests != 1 {
+		t.Errorf("loadModels() should use the fresh cache, fetched %d times", requests)
+	}
+	if !app.cfg.IsValidModel("sonar-ultra") {
+		t.Error("cached model should be accepted")
+	}
+
+	// Refresh always fetches
+	app.loadModels(true)
+	if requests != 2 {
+		t.Errorf("loadModels(true) should fetch, fetched %d times", requests)
+	}
+}
+
+func TestFetchModelsSendsKeyOnlyToAPIHost(t *testing.T) {
+	t.Setenv(models.EnvCachePath, filepath.Join(t.TempDir(), models.CacheFileName))
+
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"models":["sonar-ultra"]}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		apiURL   string
+		wantAuth string
+	}{
+		{"foreign host", config.DefaultAPIURL, ""},
+		{"API host", server.URL + "/chat/completions", "Bearer pplx-test-key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth = ""
+			app := NewApp()
+			app.cfg.APIKey = "pplx-test-key"
+			app.cfg.APIURL = tt.apiURL
+			app.cfg.ModelsURL = server.URL + "/models.json"
+			if err := app.fetchModels(models.NewCache()); err != nil {
+				t.Fatalf("fetchModels() error = %v", err)
+			}
+			if auth != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", auth, tt.wantAuth)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/elk-language/go-prompt"
+)
+
+// Bracketed paste mode makes the terminal wrap pasted text in these markers,
+// so it can be told apart from typed keys
+const (
+	bracketedPasteOn    = "\x1b[?2004h"
+	bracketedPasteOff   = "\x1b[?2004l"
+	bracketedPasteStart = "\x1b[200~"
+	bracketedPasteEnd   = "\x1b[201~"
+)
+
+// errNoInput tells the prompt that a read produced nothing to handle yet
+var errNoInput = errors.New("no input")
+
+// pasteReader wraps the prompt's terminal reader with bracketed paste
+// support. Without it, a newline in pasted text can reach the prompt as an
+// Enter key and send each line of a stack trace as its own message. A paste
+// is instead inserted into the prompt as text, to be sent with Enter as one
+// message.
+type pasteReader struct {
+	prompt.Reader
+	out io.Writer // Terminal to switch bracketed paste mode on
+
+	pasting bool
+	paste   []byte   // Text of the paste being read
+	chunks  [][]byte // Input ready to be returned, one chunk per read
+	pasted  atomic.Bool
+}
+
+// newPasteReader wraps r, switching bracketed paste mode on in out while reading
+func newPasteReader(r prompt.Reader, out io.Writer) *pasteReader {
+	return &pasteReader{Reader: r, out: out}
+}
+
+// Open starts reading and switches bracketed paste mode on
+func (r *pasteReader) Open() error {
+	if err := r.Reader.Open(); err != nil {
+		return err
+	}
+	_, _ = io.WriteString(r.out, bracketedPasteOn)
+	return nil
+}
+
+// Close switches bracketed paste mode off, so the shell and commands run
+// from the prompt get plain pastes again, and stops reading
+func (r *pasteReader) Close() error {
+	_, _ = io.WriteString(r.out, bracketedPasteOff)
+	return r.Reader.Close()
+}
+
+// Read returns typed keys as they are and a finished paste as plain text.
+// Pastes longer than buf are returned over several reads.
+func (r *pasteReader) Read(buf []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		raw := make([]byte, len(buf))
+		n, err := r.Reader.Read(raw)
+		if n == 0 {
+			if err == nil {
+				err = errNoInput
+			}
+			return 0, err
+		}
+		r.scan(raw[:n])
+	}
+	if len(r.chunks) == 0 {
+		return 0, errNoInput
+	}
+
+	chunk := r.chunks[0]
+	n := copy(buf, chunk)
+	if n < len(chunk) {
+		// Leave more than a key sequence for the next read, so the rest of
+		// the paste can't be mistaken for a key
+		n = len(buf) / 2
+		r.chunks[0] = chunk[n:]
+	} else {
+		r.chunks = r.chunks[1:]
+	}
+	return n, nil
+}
+
+// scan splits input into typed keys and pastes
+func (r *pasteReader) scan(data []byte) {
+	for len(data) > 0 {
+		if !r.pasting {
+			i := bytes.Index(data, []byte(bracketedPasteStart))
+			if i < 0 {
+				r.chunks = append(r.chunks, data)
+				return
+			}
+			if i > 0 {
+				r.chunks = append(r.chunks, data[:i])
+			}
+			r.pasting = true
+			data = data[i+len(bracketedPasteStart):]
+			continue
+		}
+
+		// The end marker may be split over two reads
+		r.paste = append(r.paste, data...)
+		i := bytes.Index(r.paste, []byte(bracketedPasteEnd))
+		if i < 0 {
+			return
+		}
+		data = r.paste[i+len(bracketedPasteEnd):]
+		r.finishPaste(r.paste[:i])
+	}
+}
+
+// finishPaste queues a paste as text, without its trailing newline, and
+// marks it for the executor
+func (r *pasteReader) finishPaste(text []byte) {
+	r.pasting = false
+	r.paste = nil
+
+	s := strings.ReplaceAll(string(text), "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return
+	}
+	r.chunks = append(r.chunks, []byte(s))
+	r.pasted.Store(true)
+}
+
+// takePasted reports whether text was pasted since the last call
+func (r *pasteReader) takePasted() bool {
+	return r.pasted.Swap(false)
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/elk-language/go-prompt"
+)
+
+// fakeTerminalReader returns one scripted read per call, split if it is
+// longer than the buffer
+type fakeTerminalReader struct {
+	reads  []string
+	opened bool
+}
+
+func (f *fakeTerminalReader) Open() error {
+	f.opened = true
+	return nil
+}
+
+func (f *fakeTerminalReader) Close() error {
+	f.opened = false
+	return nil
+}
+
+func (f *fakeTerminalReader) GetWinSize() *prompt.WinSize {
+	return &prompt.WinSize{Row: prompt.DefRowCount, Col: prompt.DefColCount}
+}
+
+func (f *fakeTerminalReader) Read(buf []byte) (int, error) {
+	if len(f.reads) == 0 {
+		return 0, errNoInput
+	}
+	n := copy(buf, f.reads[0])
+	if f.reads[0] = f.reads[0][n:]; f.reads[0] == "" {
+		f.reads = f.reads[1:]
+	}
+	return n, nil
+}
+
+// readAll reads from r until it has no more input
+func readAll(r *pasteReader, size int) []string {
+	var got []string
+	buf := make([]byte, size)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			if n := len(r.Reader.(*fakeTerminalReader).reads); n == 0 {
+				return got
+			}
+			continue
+		}
+		got = append(got, string(buf[:n]))
+	}
+}
+
+func TestPasteReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		reads  []string
+		want   []string
+		pasted bool
+	}{
+		{
+			name:  "typed keys",
+			reads: []string{"a", "\n", "\x1b[A"},
+			want:  []string{"a", "\n", "\x1b[A"},
+		},
+		{
+			name:   "paste",
+			reads:  []string{"\x1b[200~panic: boom\r\n\r\ngoroutine 1:\r\n\x1b[201~"},
+			want:   []string{"panic: boom\n\ngoroutine 1:"},
+			pasted: true,
+		},
+		{
+			name:   "paste over several reads",
+			reads:  []string{"\x1b[200~line 1\n", "line 2\n\x1b[20", "1~"},
+			want:   []string{"line 1\nline 2"},
+			pasted: true,
+		},
+		{
+			name:   "keys around a paste",
+			reads:  []string{"x\x1b[200~pasted\x1b[201~\n"},
+			want:   []string{"x", "pasted", "\n"},
+			pasted: true,
+		},
+		{
+			name:  "empty paste",
+			reads: []string{"\x1b[200~\n\x1b[201~"},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newPasteReader(&fakeTerminalReader{reads: tt.reads}, &bytes.Buffer{})
+			got := readAll(r, 1024)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("reads = %q, want %q", got, tt.want)
+			}
+			if pasted := r.takePasted(); pasted != tt.pasted {
+				t.Errorf("takePasted() = %v, want %v", pasted, tt.pasted)
+			}
+			if r.takePasted() {
+				t.Error("takePasted() should reset after it is read")
+			}
+		})
+	}
+}
+
+func TestPasteReaderLongPaste(t *testing.T) {
+	text := strings.Repeat("0123456789\n", 20)
+	r := newPasteReader(&fakeTerminalReader{reads: []string{bracketedPasteStart + text + bracketedPasteEnd}}, &bytes.Buffer{})
+
+	got := readAll(r, 64)
+	if len(got) < 2 {
+		t.Fatalf("reads = %q, want the paste split over several reads", got)
+	}
+	for _, chunk := range got {
+		if prompt.GetKey([]byte(chunk)) != prompt.NotDefined {
+			t.Errorf("chunk %q would be read as a key", chunk)
+		}
+	}
+	if joined := strings.Join(got, ""); joined != strings.TrimRight(text, "\n") {
+		t.Errorf("paste = %q, want %q", joined, text)
+	}
+}
+
+func TestPasteReaderMode(t *testing.T) {
+	var out bytes.Buffer
+	terminal := &fakeTerminalReader{}
+	r := newPasteReader(terminal, &out)
+
+	if err := r.Open(); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if !terminal.opened || out.String() != bracketedPasteOn {
+		t.Errorf("Open() wrote %q, want bracketed paste mode on", out.String())
+	}
+
+	out.Reset()
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if terminal.opened || out.String() != bracketedPasteOff {
+		t.Errorf("Close() wrote %q, want bracketed paste mode off", out.String())
+	}
+
+	if _, err := r.Read(make([]byte, 8)); !errors.Is(err, errNoInput) {
+		t.Errorf("Read() error = %v, want errNoInput", err)
+	}
+}
+
+func TestExecutorPastedCommand(t *testing.T) {
+	session, sent := newChatTestSession(t)
+	session.paste = newPasteReader(&fakeTerminalReader{}, &bytes.Buffer{})
+	session.paste.pasted.Store(true)
+
+	// A pasted block starting with / would otherwise run as a command
+	trace := "/usr/lib/go/src/runtime/map.go:770\npanic: assignment to entry in nil map"
+	output := captureOutput(func() {
+		session.executor(trace + "\n")
+	})
+	if strings.Contains(output, "Unknown command") {
+		t.Errorf("pasted lines should not run as a command, got %q", output)
+	}
+	if len(sent.Messages) != 2 || sent.Messages[1].Content != trace {
+		t.Errorf("sent messages = %+v, want the paste as one message", sent.Messages)
+	}
+	if session.paste.takePasted() {
+		t.Error("executor should take the paste flag")
+	}
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elk-language/go-prompt"
+)
+
+// maxPathCompletions limits the paths suggested for a partial path
+const maxPathCompletions = 50
+
+// pathArguments maps the commands taking a file path to the position of the
+// path among their words
+var pathArguments = map[string]int{
+	"/export":          1,
+	"/export --format": 3,
+	"/code save":       3,
+}
+
+// pathArgument returns the partial path being typed when the cursor is on
+// the path argument of a command
+func pathArgument(text string) (string, bool) {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return "", false
+	}
+	if strings.HasSuffix(text, " ") {
+		words = append(words, "")
+	}
+
+	command := strings.ToLower(words[0])
+	if (command == "/code" || command == "/export") && len(words) > 2 {
+		command += " " + strings.ToLower(words[1])
+	}
+	if pos, ok := pathArguments[command]; ok && pos == len(words)-1 {
+		return words[pos], true
+	}
+	return "", false
+}
+
+// completePath returns the paths that complete a partial path, relative to
+// dir, with a trailing slash on directories. Hidden files are only
+// suggested once the partial name starts with a dot.
+func completePath(partial, dir string) []string {
+	base, prefix := filepath.Split(partial)
+	search := base
+	if rest, ok := strings.CutPrefix(base, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			search = filepath.Join(home, rest)
+		}
+	}
+	if !filepath.IsAbs(search) {
+		search = filepath.Join(dir, search)
+	}
+
+	entries, err := os.ReadDir(search)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		if entry.IsDir() {
+			name += "/"
+		}
+		if paths = append(paths, base+name); len(paths) == maxPathCompletions {
+			break
+		}
+	}
+	return paths
+}
+
+// pathSuggestions suggests the paths completing a partial path in the
+// current directory, each prefixed with prefix
+func pathSuggestions(partial, prefix string) []prompt.Suggest {
+	var suggestions []prompt.Suggest
+	for _, path := range completePath(partial, ".") {
+		suggestions = append(suggestions, prompt.Suggest{Text: prefix + path})
+	}
+	return suggestions
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPathArgument(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   string
+		wantOK bool
+	}{
+		{"/export ", "", true},
+		{"/export notes/ch", "notes/ch", true},
+		{"/EXPORT out.md", "out.md", true},
+		{"/export", "", false},
+		{"/export out.md ", "", false},
+		{"/export --format obsidian ", "", true},
+		{"/export --format notion pa", "pa", true},
+		{"/export --format ", "", false},
+		{"/code save 2 scr", "scr", true},
+		{"/code save 2 ", "", true},
+		{"/code save 2", "", false},
+		{"/code 2 ", "", false},
+		{"/model so", "", false},
+		{"hello ", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := pathArgument(tt.text)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("pathArgument(%q) = %q, %v, want %q, %v", tt.text, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCompletePath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"cmd/root.go", "cmd/run.go", "config.yaml", ".env", "README.md", "cmd/.hidden", "internal/x.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		partial string
+		want    []string
+	}{
+		{"c", []string{"cmd/", "config.yaml"}},
+		{"cmd/r", []string{"cmd/root.go", "cmd/run.go"}},
+		{"cmd/", []string{"cmd/root.go", "cmd/run.go"}},
+		{"cmd/.", []string{"cmd/.hidden"}},
+		{".e", []string{".env"}},
+		{"missing/", nil},
+	}
+
+	for _, tt := range tests {
+		if got := completePath(tt.partial, dir); !slices.Equal(got, tt.want) {
+			t.Errorf("completePath(%q) = %q, want %q", tt.partial, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/pool"
+	"github.com/quocvuong92/perplexity-cli/internal/ratelimit"
+)
+
+// poolOptions returns the options of concurrent requests: the configured
+// concurrency, with the configured rate limit shared by all requests
+func (app *App) poolOptions() pool.Options {
+	return pool.Options{
+		Workers: app.cfg.Concurrency,
+		Limiter: ratelimit.NewLimiter(app.cfg.RateLimit),
+	}
+}
+
+// newPooledClient creates a client for requests paced by the pool options,
+// so it does not limit the rate itself as well
+func (app *App) newPooledClient(cfg *config.Config) *api.Client {
+	unlimited := *cfg
+	unlimited.RateLimit = 0
+	return app.newClientFor(&unlimited)
+}
+
+// clientFactory creates the client of an item run on a worker pool. Each
+// item needs a client of its own, since a client and its config keep the
+// key rotation state of one request at a time.
+type clientFactory func() *api.Client
+
+// pooledClients returns a clientFactory of clients made by newPooledClient,
+// each with its own copy of cfg
+func (app *App) pooledClients(cfg *config.Config) clientFactory {
+	return func() *api.Client { return app.newPooledClient(cfg) }
+}
+
+// runPooled runs fn for n items with the pool options, showing a spinner
+// with status and, for several items, the number finished
+func runPooled(ctx context.Context, n int, opts pool.Options, status string, fn func(ctx context.Context, i int) error) []error {
+	sp := display.NewSpinner(status + "...")
+	if n > 1 {
+		sp.UpdateMessage(fmt.Sprintf("%s 0/%d...", status, n))
+		opts.Progress = func(finished, total int) {
+			sp.UpdateMessage(fmt.Sprintf("%s %d/%d...", status, finished, total))
+		}
+	}
+	sp.Start()
+	defer sp.Stop()
+	return pool.Run(ctx, n, opts, fn)
+}
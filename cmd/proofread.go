@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/chunk"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/textdiff"
+)
+
+// maxProofreadChunkTokens is the size of the parts of a text proofread in
+// one request. The whole part comes back corrected, so it is bound by the
+// answer length of the models rather than their context window.
+const maxProofreadChunkTokens = 3000
+
+// proofreadPrompt asks for the corrected version of the text that follows
+const proofreadPrompt = `You are a careful proofreader. Correct the spelling, grammar, punctuation and
+obvious typos of the text the user sends, and nothing else: do not rephrase correct
+sentences, change the meaning, tone or terminology, or reorder content. Keep the line
+breaks, markdown, code, URLs and names exactly as they are. Reply with the full corrected
+text only, without explanations, quotes, code fences or sources. If there is nothing to
+correct, reply with the text unchanged.`
+
+// newProofreadCmd creates the proofread subcommand for correcting text files
+func (app *App) newProofreadCmd() *cobra.Command {
+	var (
+		model        string
+		write        bool
+		outputFile   string
+		contextLines int
+	)
+
+	proofreadCmd := &cobra.Command{
+		Use:   "proofread <file>",
+		Short: "Correct the spelling and grammar of a text file and show the changes as a diff",
+		Long: `Correct the spelling, grammar and punctuation of a text or markdown file and
+show the corrections as a unified diff against the original, with the changed
+words highlighted on a terminal. The file is left unchanged unless --write is
+given; --output saves the corrected text to another file instead. Long files
+are proofread in parts.
+
+  perplexity proofread README.md
+  perplexity proofread -w docs/guide.md
+  perplexity proofread notes.txt -o notes.fixed.txt`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			if write && outputFile != "" {
+				return invalidInput(fmt.Errorf("--write and --output cannot be used together"))
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return invalidInput(err)
+			}
+			if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+				return invalidInput(fmt.Errorf("%s is not a UTF-8 text file", path))
+			}
+			original := string(data)
+			if strings.TrimSpace(original) == "" {
+				return invalidInput(fmt.Errorf("%s is empty", path))
+			}
+
+			cfg, err := app.taskConfig(model, original)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := newSignalContext()
+			defer cancel()
+
+			corrected, err := proofreadText(ctx, app.newClientFor(cfg), original)
+			if err != nil {
+				return err
+			}
+
+			changed, err := textdiff.Unified(cmd.OutOrStdout(), path, path+" (corrected)", original, corrected, contextLines, app.shouldUseColor())
+			if err != nil {
+				return err
+			}
+			if !changed {
+				if !app.cfg.Quiet {
+					fmt.Fprintln(os.Stderr, "No corrections needed.")
+				}
+				return nil
+			}
+
+			target := outputFile
+			if write {
+				target = path
+			}
+			if target != "" {
+				if err := os.WriteFile(target, []byte(corrected), 0600); err != nil {
+					return fmt.Errorf("failed to write corrected text: %w", err)
+				}
+				if !app.cfg.Quiet {
+					fmt.Fprintf(os.Stderr, "Corrected text saved to %s\n", target)
+				}
+			}
+			return nil
+		},
+	}
+
+	proofreadCmd.Flags().StringVarP(&model, "model", "m", app.cfg.Model, "Model proofreading the text")
+	proofreadCmd.Flags().BoolVarP(&write, "write", "w", false, "Write the corrections back to the file")
+	proofreadCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write the corrected text to another file")
+	proofreadCmd.Flags().IntVarP(&contextLines, "context", "U", textdiff.DefaultContext, "Unchanged lines shown around each change")
+	_ = proofreadCmd.MarkFlagFilename("output")
+	return proofreadCmd
+}
+
+// proofreadText returns the corrected version of text. A long text is
+// proofread in parts that are joined by blank lines. The line ending of the
+// text is kept.
+func proofreadText(ctx context.Context, client *api.Client, text string) (string, error) {
+	parts := chunk.Split(text, maxProofreadChunkTokens)
+	corrected := make([]string, len(parts))
+	for i, part := range parts {
+		status := "Proofreading..."
+		if len(parts) > 1 {
+			status = fmt.Sprintf("Proofreading part %d of %d...", i+1, len(parts))
+		}
+		resp, err := queryWithSpinner(ctx, client, status, []api.Message{
+			{Role: "system", Content: proofreadPrompt},
+			{Role: "user", Content: part},
+		})
+		if err != nil {
+			return "", err
+		}
+		corrected[i] = cleanProofread(resp.GetContent(), part)
+	}
+
+	result := strings.Join(corrected, "\n\n")
+	if strings.HasSuffix(text, "\n") {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// cleanProofread removes reasoning from a corrected text, and the citation
+// markers and code fence around it unless the original has them too
+func cleanProofread(content, original string) string {
+	_, text := display.SplitThinking(content)
+	if display.StripCitationMarkers(original) == original {
+		text = display.StripCitationMarkers(text)
+	}
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") && !strings.HasPrefix(original, "```") {
+		// Drop the opening fence with its language, and the closing fence
+		_, text, _ = strings.Cut(text, "\n")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+)
+
+func TestCleanProofread(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		original string
+		want     string
+	}{
+		{"plain", "It's a nice day.\n", "Its a nice day.", "It's a nice day."},
+		{"thinking and markers", "<think>one typo</think>\nIt's a nice day.[1]", "Its a nice day.", "It's a nice day."},
+		{"markers of the original", "See note [1].", "See note [1].", "See note [1]."},
+		{"fenced", "```markdown\n# Title\n```", "# Titel", "# Title"},
+		{"fence of the original", "```go\nx := 1\n```", "```go\nx := 1\n```", "```go\nx := 1\n```"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanProofread(tt.content, tt.original); got != tt.want {
+				t.Errorf("cleanProofread() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProofreadCmd(t *testing.T) {
+	var sent api.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "# Notes\n\nIt's a nice day.\nSee you tomorrow."}}},
+		})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "notes.md")
+	original := "# Notes\n\nIts a nice day.\nSee you tomorrow.\n"
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp()
+	app.noColor = true
+	app.cfg.APIURL = server.URL
+	app.cfg.APIKey = "pplx-" + strings.Repeat("a", 40)
+	app.cfg.Model = "sonar"
+
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := app.newProofreadCmd()
+		cmd.SetArgs(args)
+		var out strings.Builder
+		cmd.SetOut(&out)
+		var err error
+		captureOutput(func() { err = cmd.Execute() })
+		return out.String(), err
+	}
+
+	out, err := run(path)
+	if err != nil {
+		t.Fatalf("proofread error = %v", err)
+	}
+	for _, want := range []string{"--- " + path + "\n", "@@ -1,4 +1,4 @@\n", "-Its a nice day.\n+It's a nice day.\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("proofread output = %q, want it to contain %q", out, want)
+		}
+	}
+	if sent.Messages[0].Content != proofreadPrompt || sent.Messages[1].Content != strings.TrimSpace(original) {
+		t.Errorf("request messages = %+v, want the file with the proofreading prompt", sent.Messages)
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Errorf("proofread without --write changed the file to %q", data)
+	}
+
+	if _, err := run("-w", path); err != nil {
+		t.Fatalf("proofread -w error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "# Notes\n\nIt's a nice day.\nSee you tomorrow.\n" {
+		t.Errorf("proofread -w wrote %q, want the corrected text", data)
+	}
+
+	out, err = run(path)
+	if err != nil || out != "" {
+		t.Errorf("proofread of a correct file = %q, %v, want no diff", out, err)
+	}
+
+	_, err = run("-w", "-o", "out.md", path)
+	var inputErr *inputError
+	if !errors.As(err, &inputErr) {
+		t.Errorf("proofread -w -o error = %v, want invalid input", err)
+	}
+}
@@ -2,32 +2,127 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/codeblock"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
 	"github.com/quocvuong92/perplexity-cli/internal/display"
 )
 
+// errNoCodeBlocks is returned by --extract-code when the response has no code blocks
+var errNoCodeBlocks = errors.New("no code blocks in the response")
+
+// newContentWriter returns the writer streamed content is printed through. It sets
+// reasoning apart and strips citation markers, as configured. beforeOutput is
+// called before anything is printed.
+func (app *App) newContentWriter(beforeOutput func()) *display.ThinkingFilter {
+	var out io.Writer = &beforeWriteWriter{w: os.Stdout, before: beforeOutput}
+	if app.cfg.HideCitationMarkers {
+		out = display.NewCitationMarkerWriter(out)
+	}
+	return display.NewThinkingFilter(out, !app.cfg.HideThinking, app.shouldUseColor())
+}
+
+// splitContent separates the reasoning from the answer in a response, and
+// strips or links the answer's citation markers as configured
+func (app *App) splitContent(content string, citations []string) (thinking, answer string) {
+	thinking, answer = display.SplitThinking(content)
+	if app.cfg.HideCitationMarkers {
+		thinking = display.StripCitationMarkers(thinking)
+		answer = display.StripCitationMarkers(answer)
+		return thinking, answer
+	}
+
+	switch app.cfg.CitationStyle {
+	case config.CitationStyleLinks:
+		answer = display.LinkCitationMarkers(answer, citations)
+	case config.CitationStyleFootnotes:
+		answer = display.FootnoteCitationMarkers(answer, citations)
+	}
+	return thinking, answer
+}
+
 // runNormal executes a single query in non-streaming mode
-func (app *App) runNormal(ctx context.Context, query string) {
-	sp := display.NewSpinner("Waiting for response...")
-	sp.Start()
+func (app *App) runNormal(ctx context.Context, query string) error {
+	resp, err := app.queryNormal(ctx, query)
+	if err != nil {
+		return err
+	}
+	app.showResponse(resp)
+	_, answer := display.SplitThinking(resp.GetContent())
+	app.saveConversation(query, answer, resp.Citations, resp)
+	return nil
+}
 
-	resp, err := app.client.QueryContext(ctx, query)
-	sp.Stop()
+// queryNormal sends a non-streaming query with a spinner, reporting any error.
+// Returns the context's error if the query was cancelled.
+func (app *App) queryNormal(ctx context.Context, query string) (*api.ChatResponse, error) {
+	wait := newWaitIndicator(app.client, app.cfg.Model, "Waiting for response...")
+	return queryWithIndicator(ctx, app.client, wait, app.queryMessages(query))
+}
+
+// queryWithSpinner sends messages with client, showing a spinner with status
+// and reporting any error. Returns the context's error if the query was cancelled.
+func queryWithSpinner(ctx context.Context, client *api.Client, status string, messages []api.Message) (*api.ChatResponse, error) {
+	return queryWithIndicator(ctx, client, display.NewSpinner(status), messages)
+}
+
+// queryWithIndicator sends messages with client, showing wait until the
+// response arrives, and reports any error like queryWithSpinner
+func queryWithIndicator(ctx context.Context, client *api.Client, wait waitIndicator, messages []api.Message) (*api.ChatResponse, error) {
+	wait.Start()
+	resp, err := client.QueryWithHistoryContext(ctx, messages)
+	wait.Stop()
 
 	if err != nil {
 		if ctx.Err() != nil {
-			return
+			return nil, ctx.Err()
 		}
 		msg, hint := display.FormatNetworkError(err)
 		display.ShowFriendlyError(msg, hint)
-		return
+		return nil, err
 	}
+	return resp, nil
+}
 
-	content := resp.GetContent()
+// imageLinks converts the images of a response for display
+func imageLinks(images []api.Image) []display.ImageLink {
+	links := make([]display.ImageLink, len(images))
+	for i, image := range images {
+		links[i] = display.ImageLink{URL: image.ImageURL, Source: image.OriginURL}
+	}
+	return links
+}
+
+// displayUsage returns the usage of a response for display, with its cost
+func (app *App) displayUsage(resp *api.ChatResponse) display.Usage {
+	usage := display.Usage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		TotalTokens:      resp.Usage.TotalTokens,
+		CitationTokens:   resp.Usage.CitationTokens,
+		ReasoningTokens:  resp.Usage.ReasoningTokens,
+		SearchQueries:    resp.Usage.NumSearchQueries,
+		SearchContext:    resp.Usage.SearchContextSize,
+		KeyIndex:         resp.KeyIndex,
+		KeyCount:         app.cfg.GetKeyCount(),
+	}
+	usage.Cost, usage.CostEstimated, usage.CostKnown = responseCost(resp, app.cfg.Model)
+	return usage
+}
+
+// showResponse displays a non-streaming response with its citations and
+// usage as configured, and saves it if an output file is set
+func (app *App) showResponse(resp *api.ChatResponse) {
+	thinking, content := app.splitContent(resp.GetContent(), resp.Citations)
+	if !app.cfg.HideThinking {
+		display.ShowThinking(thinking, app.shouldUseColor())
+	}
 
 	if app.cfg.Render {
 		display.ShowContentRendered(content)
@@ -39,60 +134,75 @@ func (app *App) runNormal(ctx context.Context, query string) {
 		display.ShowCitations(resp.Citations)
 	}
 
+	if app.cfg.Images && len(resp.Images) > 0 {
+		display.ShowImages(imageLinks(resp.Images))
+	}
+
+	if app.cfg.RelatedQuestions && len(resp.RelatedQuestions) > 0 {
+		display.ShowRelatedQuestions(resp.RelatedQuestions)
+	}
+
 	if app.cfg.Usage {
-		display.ShowUsage(resp.GetUsageMap())
+		display.ShowUsage(app.displayUsage(resp))
 	}
 
 	// Save to file if output flag is set
 	if app.cfg.OutputFile != "" {
 		if err := os.WriteFile(app.cfg.OutputFile, []byte(content), 0600); err != nil {
 			display.ShowError(fmt.Sprintf("Failed to save output: %v", err))
-		} else {
+		} else if !app.cfg.Quiet {
 			fmt.Fprintf(os.Stderr, "Response saved to %s\n", app.cfg.OutputFile)
 		}
 	}
 }
 
 // runStream executes a single query in streaming mode
-func (app *App) runStream(ctx context.Context, query string) {
+func (app *App) runStream(ctx context.Context, query string) error {
 	var finalResp *api.ChatResponse
 	var fullContent strings.Builder
 	firstChunk := true
+	wait := newWaitIndicator(app.client, app.cfg.Model, "Waiting for response...")
+	thinkFilter := app.newContentWriter(wait.Stop)
+	wait.Start()
 
-	sp := display.NewSpinner("Waiting for response...")
-	sp.Start()
-
-	err := app.client.QueryStreamContext(ctx, query,
+	err := app.client.QueryStreamWithHistoryContext(ctx, app.queryMessages(query),
 		func(content string) {
 			if firstChunk {
 				firstChunk = false
-				sp.Stop()
+				startReasoningStage(wait)
 			}
 
 			fullContent.WriteString(content)
-			fmt.Print(content)
+			thinkFilter.Write(content)
 		},
 		func(resp *api.ChatResponse) {
 			finalResp = resp
 		},
 	)
 
-	sp.Stop()
+	wait.Stop()
+	thinkFilter.Flush()
 
 	if err != nil {
 		if ctx.Err() != nil {
 			fmt.Println()
-			return
+			return ctx.Err()
 		}
 		msg, hint := display.FormatNetworkError(err)
 		display.ShowFriendlyError(msg, hint)
-		return
+		return err
 	}
 
+	var citations []string
+	if finalResp != nil {
+		citations = finalResp.Citations
+	}
+	_, answer := app.splitContent(fullContent.String(), citations)
+
 	if app.cfg.Render {
 		fmt.Println("\n---")
 		// Render collected content
-		display.ShowContentRendered(fullContent.String())
+		display.ShowContentRendered(answer)
 	} else {
 		fmt.Println() // newline after streaming content
 	}
@@ -103,18 +213,67 @@ func (app *App) runStream(ctx context.Context, query string) {
 			display.ShowCitations(finalResp.Citations)
 		}
 
+		if app.cfg.Images && len(finalResp.Images) > 0 {
+			fmt.Println()
+			display.ShowImages(imageLinks(finalResp.Images))
+		}
+
+		if app.cfg.RelatedQuestions && len(finalResp.RelatedQuestions) > 0 {
+			fmt.Println()
+			display.ShowRelatedQuestions(finalResp.RelatedQuestions)
+		}
+
 		if app.cfg.Usage {
 			fmt.Println()
-			display.ShowUsage(finalResp.GetUsageMap())
+			display.ShowUsage(app.displayUsage(finalResp))
 		}
 	}
 
 	// Save to file if output flag is set
 	if app.cfg.OutputFile != "" {
-		if err := os.WriteFile(app.cfg.OutputFile, []byte(fullContent.String()), 0600); err != nil {
+		if err := os.WriteFile(app.cfg.OutputFile, []byte(answer), 0600); err != nil {
+			display.ShowError(fmt.Sprintf("Failed to save output: %v", err))
+		} else if !app.cfg.Quiet {
+			fmt.Fprintf(os.Stderr, "Response saved to %s\n", app.cfg.OutputFile)
+		}
+	}
+
+	// History keeps the answer as returned, with its citation markers
+	_, raw := display.SplitThinking(fullContent.String())
+	app.saveConversation(query, raw, citations, finalResp)
+	return nil
+}
+
+// runExtractCode executes a single query and prints only the code blocks of
+// the response, separated by blank lines
+func (app *App) runExtractCode(ctx context.Context, query string) error {
+	resp, err := app.queryNormal(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	_, answer := display.SplitThinking(resp.GetContent())
+	app.saveConversation(query, answer, resp.Citations, resp)
+
+	blocks := codeblock.Extract(answer)
+	if len(blocks) == 0 {
+		display.ShowError("No code blocks in the response")
+		return errNoCodeBlocks
+	}
+
+	codes := make([]string, len(blocks))
+	for i, block := range blocks {
+		codes[i] = block.Code
+	}
+	code := strings.Join(codes, "\n\n") + "\n"
+	fmt.Print(code)
+
+	if app.cfg.OutputFile != "" {
+		if err := os.WriteFile(app.cfg.OutputFile, []byte(code), 0600); err != nil {
 			display.ShowError(fmt.Sprintf("Failed to save output: %v", err))
-		} else {
+		} else if !app.cfg.Quiet {
 			fmt.Fprintf(os.Stderr, "Response saved to %s\n", app.cfg.OutputFile)
 		}
 	}
+	return nil
 }
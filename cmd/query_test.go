@@ -7,11 +7,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/quocvuong92/perplexity-cli/internal/api"
 	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
 )
 
 func createMockServer(t *testing.T, response *api.ChatResponse) *httptest.Server {
@@ -69,6 +71,8 @@ func TestRunNormal(t *testing.T) {
 			PromptTokens:     10,
 			CompletionTokens: 20,
 			TotalTokens:      30,
+			NumSearchQueries: 2,
+			Cost:             &api.Cost{TotalCost: 0.0051},
 		},
 	}
 
@@ -95,6 +99,140 @@ func TestRunNormal(t *testing.T) {
 	if !strings.Contains(output, "This is a test response") {
 		t.Error("Output should contain response content")
 	}
+	if !strings.Contains(output, "Searches: 2") || !strings.Contains(output, "Cost: $0.0051") {
+		t.Errorf("Output should contain the searches and the reported cost, got:\n%s", output)
+	}
+}
+
+func TestRunNormalThinking(t *testing.T) {
+	mockResponse := &api.ChatResponse{
+		Choices: []api.StreamChoice{
+			{Message: api.Message{Role: "assistant", Content: "<think>secret reasoning</think>Visible answer"}},
+		},
+	}
+
+	server := createMockServer(t, mockResponse)
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", Model: "sonar-reasoning", HideThinking: true}
+	app := &App{cfg: cfg}
+	app.client = api.NewClient(cfg)
+	app.client.SetBaseURL(server.URL)
+
+	output := captureOutput(func() {
+		app.runNormal(context.Background(), "test query")
+	})
+	if strings.Contains(output, "secret reasoning") || strings.Contains(output, "<think>") {
+		t.Errorf("reasoning should be hidden, got %q", output)
+	}
+	if !strings.Contains(output, "Visible answer") {
+		t.Errorf("answer should be shown, got %q", output)
+	}
+
+	cfg.HideThinking = false
+	app.noColor = true
+	output = captureOutput(func() {
+		app.runNormal(context.Background(), "test query")
+	})
+	if !strings.Contains(output, "Thinking:\nsecret reasoning") {
+		t.Errorf("reasoning should be shown apart from the answer, got %q", output)
+	}
+}
+
+func TestRunStreamNoCitationMarkers(t *testing.T) {
+	server := createMockStreamServer(t, []string{"Go was released[", "1] in 2009 [2].", " It is fast[3]."}, nil)
+	defer server.Close()
+
+	tempFile := filepath.Join(t.TempDir(), "answer.md")
+	cfg := &config.Config{APIKey: "test-key", Model: "sonar", HideCitationMarkers: true, OutputFile: tempFile}
+	app := &App{cfg: cfg}
+	app.client = api.NewClient(cfg)
+	app.client.SetBaseURL(server.URL)
+
+	output := captureStdoutOnly(func() {
+		app.runStream(context.Background(), "test query")
+	})
+
+	want := "Go was released in 2009. It is fast."
+	if !strings.Contains(output, want) {
+		t.Errorf("output = %q, want markers removed", output)
+	}
+	saved, err := os.ReadFile(tempFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != want {
+		t.Errorf("saved output = %q, want %q", saved, want)
+	}
+}
+
+func TestRunNormalCitationFootnotes(t *testing.T) {
+	mockResponse := &api.ChatResponse{
+		Choices: []api.StreamChoice{
+			{Message: api.Message{Role: "assistant", Content: "Go was released in 2009[1]."}},
+		},
+		Citations: []string{"https://go.dev/doc/faq"},
+	}
+
+	server := createMockServer(t, mockResponse)
+	defer server.Close()
+
+	tempFile := filepath.Join(t.TempDir(), "answer.md")
+	cfg := &config.Config{APIKey: "test-key", Model: "sonar", CitationStyle: config.CitationStyleFootnotes, OutputFile: tempFile}
+	app := &App{cfg: cfg}
+	app.client = api.NewClient(cfg)
+	app.client.SetBaseURL(server.URL)
+
+	captureOutput(func() {
+		app.runNormal(context.Background(), "test query")
+	})
+
+	saved, err := os.ReadFile(tempFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Go was released in 2009[^1].\n\n[^1]: https://go.dev/doc/faq"
+	if string(saved) != want {
+		t.Errorf("saved output = %q, want %q", saved, want)
+	}
+}
+
+func TestRunNormalCitationStyleKeepsHistoryRaw(t *testing.T) {
+	t.Setenv(history.EnvHistoryPath, filepath.Join(t.TempDir(), "history.json"))
+
+	mockResponse := &api.ChatResponse{
+		Choices: []api.StreamChoice{
+			{Message: api.Message{Role: "assistant", Content: "Go was released in 2009[1]."}},
+		},
+		Citations: []string{"https://go.dev/doc/faq"},
+	}
+
+	server := createMockServer(t, mockResponse)
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", Model: "sonar", CitationStyle: config.CitationStyleLinks}
+	app := &App{cfg: cfg, conversationRef: "new"}
+	app.client = api.NewClient(cfg)
+	app.client.SetBaseURL(server.URL)
+	if err := app.loadConversation(); err != nil {
+		t.Fatalf("loadConversation() error = %v", err)
+	}
+
+	captureOutput(func() {
+		app.runNormal(context.Background(), "test query")
+	})
+
+	saved := history.NewHistory()
+	if err := saved.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	conv := saved.GetConversation(app.conversation.ID)
+	if conv == nil || len(conv.Messages) == 0 {
+		t.Fatalf("saved conversation = %+v, want the exchange", conv)
+	}
+	if got := conv.Messages[len(conv.Messages)-1].Content; got != "Go was released in 2009[1]." {
+		t.Errorf("saved answer = %q, want the raw markers", got)
+	}
 }
 
 func TestRunNormalWithOutputFile(t *testing.T) {
@@ -389,3 +527,59 @@ func TestRunStreamNoCitations(t *testing.T) {
 		t.Error("Should not show citations when disabled")
 	}
 }
+
+func TestRunExtractCode(t *testing.T) {
+	mockResponse := &api.ChatResponse{
+		Choices: []api.StreamChoice{
+			{Message: api.Message{Role: "assistant", Content: "Install it:\n\n```bash\nbrew install go\n```\n\nThen run:\n\n```go\nfmt.Println(\"hi\")\n```"}},
+		},
+	}
+
+	server := createMockServer(t, mockResponse)
+	defer server.Close()
+
+	tempFile := filepath.Join(t.TempDir(), "code.txt")
+	cfg := &config.Config{APIKey: "test-key", Model: "sonar", OutputFile: tempFile}
+	app := &App{cfg: cfg}
+	app.client = api.NewClient(cfg)
+	app.client.SetBaseURL(server.URL)
+
+	output := captureStdoutOnly(func() {
+		app.runExtractCode(context.Background(), "test query")
+	})
+
+	want := "brew install go\n\nfmt.Println(\"hi\")\n"
+	if output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+	saved, err := os.ReadFile(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if string(saved) != want {
+		t.Errorf("saved = %q, want %q", saved, want)
+	}
+}
+
+func TestRunExtractCodeNoBlocks(t *testing.T) {
+	mockResponse := &api.ChatResponse{
+		Choices: []api.StreamChoice{
+			{Message: api.Message{Role: "assistant", Content: "No code here."}},
+		},
+	}
+
+	server := createMockServer(t, mockResponse)
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", Model: "sonar"}
+	app := &App{cfg: cfg}
+	app.client = api.NewClient(cfg)
+	app.client.SetBaseURL(server.URL)
+
+	output := captureOutput(func() {
+		app.runExtractCode(context.Background(), "test query")
+	})
+	if !strings.Contains(output, "No code blocks") {
+		t.Errorf("output = %q, want no code blocks error", output)
+	}
+}
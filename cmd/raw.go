@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+)
+
+// validateRaw checks that --raw is not combined with other output modes
+func (app *App) validateRaw() error {
+	if !app.raw {
+		return nil
+	}
+	if app.cfg.Interactive || app.compare != "" || app.watch > 0 || app.extractCode || app.format == formatJSONL {
+		return fmt.Errorf("--raw cannot be combined with --interactive, --compare, --watch, --extract-code or --format jsonl")
+	}
+	return nil
+}
+
+// runRaw sends a single query and prints the response body exactly as the
+// API returned it: the response JSON, or the SSE frames in stream mode
+func (app *App) runRaw(ctx context.Context, query string) error {
+	app.client.SetRawOutput(os.Stdout)
+
+	var resp *api.ChatResponse
+	var content strings.Builder
+	var err error
+	if app.cfg.Stream {
+		err = app.client.QueryStreamWithHistoryContext(ctx, app.queryMessages(query),
+			func(chunk string) { content.WriteString(chunk) },
+			func(r *api.ChatResponse) { resp = r },
+		)
+	} else {
+		resp, err = app.client.QueryWithHistoryContext(ctx, app.queryMessages(query))
+		if err == nil {
+			content.WriteString(resp.GetContent())
+		}
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		msg, hint := display.FormatNetworkError(err)
+		display.ShowFriendlyError(msg, hint)
+		return err
+	}
+
+	var citations []string
+	if resp != nil {
+		citations = resp.Citations
+	}
+	_, answer := display.SplitThinking(content.String())
+	app.saveConversation(query, answer, citations, resp)
+	return nil
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+func TestValidateRaw(t *testing.T) {
+	tests := []struct {
+		name    string
+		app     App
+		wantErr bool
+	}{
+		{"off", App{cfg: &config.Config{Interactive: true}, compare: "sonar,sonar-pro"}, false},
+		{"raw", App{cfg: &config.Config{}, raw: true}, false},
+		{"raw with stream", App{cfg: &config.Config{Stream: true}, raw: true}, false},
+		{"raw with interactive", App{cfg: &config.Config{Interactive: true}, raw: true}, true},
+		{"raw with compare", App{cfg: &config.Config{}, raw: true, compare: "sonar,sonar-pro"}, true},
+		{"raw with jsonl", App{cfg: &config.Config{}, raw: true, format: formatJSONL}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.app.validateRaw()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRaw() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunRaw(t *testing.T) {
+	const body = `{"id":"r1","model":"sonar","choices":[{"message":{"role":"assistant","content":"Hi"}}],"unknown_field":{"kept":true}}`
+	frames := "data: {\"id\":\"r2\",\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n" +
+		"data: {\"id\":\"r2\",\"choices\":[{\"delta\":{\"content\":\" there\"}}],\"usage\":{\"total_tokens\":3}}\n\n" +
+		"data: [DONE]\n\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "text/event-stream" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(frames))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		name   string
+		stream bool
+		want   string
+	}{
+		{"json", false, body},
+		{"sse", true, frames},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{APIKey: "test-key", Model: "sonar", Stream: tt.stream}
+			app := &App{cfg: cfg, raw: true}
+			app.client = api.NewClient(cfg)
+			app.client.SetBaseURL(server.URL)
+
+			var err error
+			output := captureStdoutOnly(func() {
+				err = app.runRaw(context.Background(), "test query")
+			})
+			if err != nil {
+				t.Fatalf("runRaw() error = %v", err)
+			}
+			if output != tt.want {
+				t.Errorf("runRaw() printed %q, want the body as received %q", output, tt.want)
+			}
+			if strings.Contains(output, "## ") {
+				t.Error("runRaw() should not format the response")
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+)
+
+// maxRelatedChoices is the number of related questions that can be picked with a single key
+const maxRelatedChoices = 9
+
+// errNotTerminal is returned when a keystroke is requested without a terminal
+var errNotTerminal = errors.New("stdin is not a terminal")
+
+// readKey reads a single keystroke. It is a variable so tests can replace it.
+var readKey = readKeystroke
+
+// readKeystroke reads one key from the terminal without waiting for Enter
+func readKeystroke() (byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return 0, errNotTerminal
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = term.Restore(fd, state) }()
+
+	var buf [1]byte
+	if _, err := os.Stdin.Read(buf[:]); err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+// pickRelatedQuestion shows the related questions of the last response as a
+// numbered menu and returns the one selected with a number key, or "" if
+// another key is pressed.
+func (s *InteractiveSession) pickRelatedQuestion() string {
+	if !s.app.cfg.RelatedQuestions || len(s.lastRelated) == 0 {
+		return ""
+	}
+
+	questions := s.lastRelated
+	if len(questions) > maxRelatedChoices {
+		questions = questions[:maxRelatedChoices]
+	}
+	display.ShowRelatedQuestions(questions)
+
+	fmt.Printf("Press 1-%d to ask, any other key to continue: ", len(questions))
+	key, err := readKey()
+	fmt.Println()
+	if err != nil {
+		return ""
+	}
+
+	n := int(key) - '0'
+	if n < 1 || n > len(questions) {
+		return ""
+	}
+	question := questions[n-1]
+	fmt.Printf("> %s\n", question)
+	return question
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPickRelatedQuestion(t *testing.T) {
+	oldReadKey := readKey
+	defer func() { readKey = oldReadKey }()
+
+	related := []string{"What is Go?", "Who made Go?"}
+
+	tests := []struct {
+		name    string
+		enabled bool
+		related []string
+		key     byte
+		keyErr  error
+		want    string
+	}{
+		{"picks first", true, related, '1', nil, "What is Go?"},
+		{"picks second", true, related, '2', nil, "Who made Go?"},
+		{"out of range", true, related, '3', nil, ""},
+		{"other key", true, related, '\r', nil, ""},
+		{"read error", true, related, 0, errNotTerminal, ""},
+		{"disabled", false, related, '1', nil, ""},
+		{"no questions", true, nil, '1', nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readKey = func() (byte, error) {
+				return tt.key, tt.keyErr
+			}
+			session := newTestSession()
+			session.app.cfg.RelatedQuestions = tt.enabled
+			session.lastRelated = tt.related
+
+			var got string
+			output := captureOutput(func() {
+				got = session.pickRelatedQuestion()
+			})
+
+			if got != tt.want {
+				t.Errorf("pickRelatedQuestion() = %q, want %q", got, tt.want)
+			}
+			if tt.enabled && len(tt.related) > 0 && !strings.Contains(output, "Press 1-2") {
+				t.Errorf("output = %q, want key prompt", output)
+			}
+		})
+	}
+}
+
+func TestPickRelatedQuestionLimit(t *testing.T) {
+	oldReadKey := readKey
+	defer func() { readKey = oldReadKey }()
+	readKey = func() (byte, error) { return 0, errors.New("no key") }
+
+	session := newTestSession()
+	session.app.cfg.RelatedQuestions = true
+	for i := 0; i < maxRelatedChoices+3; i++ {
+		session.lastRelated = append(session.lastRelated, "question")
+	}
+
+	output := captureOutput(func() {
+		session.pickRelatedQuestion()
+	})
+
+	if !strings.Contains(output, "Press 1-9") || strings.Contains(output, "10. question") {
+		t.Errorf("output = %q, want at most %d questions", output, maxRelatedChoices)
+	}
+}
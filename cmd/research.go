@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+)
+
+// reasoningStage is the research stage once the model streams its reasoning
+const reasoningStage = "Analyzing sources"
+
+// waitIndicator shows that a query is in progress until its answer is printed
+type waitIndicator interface {
+	Start()
+	Stop()
+	UpdateMessage(message string)
+}
+
+// newWaitIndicator returns a spinner with status, or for deep research models
+// the multi-stage research progress, which is kept up to date with the number
+// of searches client reports while streaming. With several API keys, the
+// spinner names the key the request is sent with.
+func newWaitIndicator(client *api.Client, model, status string) waitIndicator {
+	if !config.IsDeepResearchModel(model) {
+		client.SetSearchCallback(nil)
+		return display.NewSpinner(keyStatus(client, status))
+	}
+	progress := display.NewResearchProgress("Researching", display.DefaultHeartbeat)
+	client.SetSearchCallback(progress.UpdateSearches)
+	return progress
+}
+
+// startReasoningStage moves the research progress to reasoningStage once
+// the answer starts streaming. The spinner of other models keeps its status.
+func startReasoningStage(wait waitIndicator) {
+	if progress, ok := wait.(*display.ResearchProgress); ok {
+		progress.UpdateMessage(reasoningStage)
+	}
+}
+
+// keyStatus adds the API key client sends its next request with to a status,
+// when there are several
+func keyStatus(client *api.Client, status string) string {
+	if index, count := client.CurrentKey(); count > 1 {
+		return fmt.Sprintf("%s [key %d/%d]", status, index, count)
+	}
+	return status
+}
+
+// beforeWriteWriter calls before once, ahead of the first write to w. It
+// keeps the wait indicator up while streamed reasoning is hidden.
+type beforeWriteWriter struct {
+	w      io.Writer
+	before func()
+	once   sync.Once
+}
+
+// Write implements io.Writer
+func (b *beforeWriteWriter) Write(p []byte) (int, error) {
+	b.once.Do(b.before)
+	return b.w.Write(p)
+}
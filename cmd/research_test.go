@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+)
+
+func TestNewWaitIndicator(t *testing.T) {
+	client := api.NewClient(&config.Config{APIKey: "test-key"})
+	if _, ok := newWaitIndicator(client, "sonar-pro", "Waiting...").(*display.Spinner); !ok {
+		t.Error("newWaitIndicator() of sonar-pro should be a spinner")
+	}
+	if _, ok := newWaitIndicator(client, "sonar-deep-research", "Waiting...").(*display.ResearchProgress); !ok {
+		t.Error("newWaitIndicator() of sonar-deep-research should be the research progress")
+	}
+}
+
+// recordingIndicator records the messages a wait indicator is updated with
+type recordingIndicator struct {
+	messages []string
+}
+
+func (r *recordingIndicator) Start()                       {}
+func (r *recordingIndicator) Stop()                        {}
+func (r *recordingIndicator) UpdateMessage(message string) { r.messages = append(r.messages, message) }
+
+func TestStartReasoningStage(t *testing.T) {
+	spinner := &recordingIndicator{}
+	startReasoningStage(spinner)
+	if len(spinner.messages) != 0 {
+		t.Errorf("startReasoningStage() updated a spinner with %q, want its status kept", spinner.messages)
+	}
+}
+
+func TestKeyStatus(t *testing.T) {
+	single := api.NewClient(&config.Config{APIKey: "key1", APIKeys: []string{"key1"}})
+	if got := keyStatus(single, "Waiting..."); got != "Waiting..." {
+		t.Errorf("keyStatus() with one key = %q, want the status unchanged", got)
+	}
+
+	several := api.NewClient(&config.Config{APIKey: "key2", APIKeys: []string{"key1", "key2"}, CurrentKeyIndex: 1})
+	if got := keyStatus(several, "Waiting..."); got != "Waiting... [key 2/2]" {
+		t.Errorf("keyStatus() with several keys = %q, want the key named", got)
+	}
+}
+
+func TestBeforeWriteWriter(t *testing.T) {
+	var out strings.Builder
+	calls := 0
+	w := &beforeWriteWriter{w: &out, before: func() { calls++ }}
+
+	// Hidden reasoning writes nothing, so the indicator stays up
+	filter := display.NewThinkingFilter(w, false, false)
+	filter.Write("<think>searching")
+	if calls != 0 {
+		t.Fatalf("before called %d times for hidden reasoning, want 0", calls)
+	}
+
+	filter.Write("</think>The answer")
+	filter.Write(" continues")
+	filter.Flush()
+	if calls != 1 || out.String() != "The answer continues" {
+		t.Errorf("before called %d times, output %q, want once ahead of the answer", calls, out.String())
+	}
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/pool"
+	"github.com/quocvuong92/perplexity-cli/internal/review"
+)
+
+// maxReviewChunkTokens limits the estimated tokens of the diff sent in one
+// review request, well within every model's context window so the answer
+// has room too
+const maxReviewChunkTokens = 16000
+
+// reviewPrompt asks for review comments on the diff that follows as JSON
+const reviewPrompt = `You are a senior engineer reviewing a code change given as a unified diff.
+Point out bugs, security problems, unhandled errors, race conditions, missing tests and
+unclear code in the changed lines. Do not comment on code the diff does not change, and
+do not praise the change.
+
+Reply with only a JSON array, without code fences or commentary. Each element is an object:
+{"file": "<path as in the diff, without a/ or b/>", "line": <line number in the new file, or 0>,
+"severity": "error" | "warning" | "info", "message": "<the problem and how to fix it>"}
+Use "error" for bugs and security problems, "warning" for likely problems and "info" for
+suggestions. Reply with [] if there is nothing to point out.`
+
+// newReviewCmd creates the review subcommand for reviewing diffs
+func (app *App) newReviewCmd() *cobra.Command {
+	var (
+		model      string
+		rangeArg   string
+		jsonOutput bool
+	)
+
+	reviewCmd := &cobra.Command{
+		Use:   "review",
+		Short: "Review a diff and list comments by file, line and severity",
+		Long: `Review a unified diff read from stdin, or the changes of a git revision range
+with --range, and list the comments as "file:line: severity: message" lines.
+Severities are error, warning and info. --json writes the comments as a JSON
+array of objects with file, line, severity and message, for use in CI.
+
+Large diffs are reviewed in chunks, keeping the changes of a file together.
+
+  git diff | perplexity review
+  perplexity review --range main...HEAD
+  perplexity review --range HEAD~3..HEAD --json > review.json`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := newSignalContext()
+			defer cancel()
+
+			diff, err := readReviewDiff(ctx, cmd.InOrStdin(), rangeArg)
+			if err != nil {
+				return err
+			}
+
+			cfg, err := app.taskConfig(model, diff)
+			if err != nil {
+				return err
+			}
+
+			comments, err := reviewDiff(ctx, app.pooledClients(cfg), diff, app.poolOptions())
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return review.WriteJSON(cmd.OutOrStdout(), comments)
+			}
+			review.WriteText(cmd.OutOrStdout(), comments)
+			return nil
+		},
+	}
+
+	reviewCmd.Flags().StringVarP(&model, "model", "m", app.cfg.Model, "Model reviewing the diff")
+	reviewCmd.Flags().StringVar(&rangeArg, "range", "", "Review the changes of a git revision range, e.g. main...HEAD, instead of stdin")
+	reviewCmd.Flags().BoolVar(&jsonOutput, "json", false, "Write the comments as a JSON array")
+	return reviewCmd
+}
+
+// readReviewDiff returns the diff of a git revision range, or the diff piped
+// to stdin if no range is given
+func readReviewDiff(ctx context.Context, stdin io.Reader, rangeArg string) (string, error) {
+	var diff string
+	if rangeArg != "" {
+		out, err := runGit(ctx, nil, "diff", "--no-color", "--no-ext-diff", rangeArg)
+		if err != nil {
+			return "", invalidInput(err)
+		}
+		diff = out
+	} else {
+		if f, ok := stdin.(*os.File); ok {
+			if stat, err := f.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+				return "", invalidInput(fmt.Errorf("pipe a diff to stdin or give a revision range with --range"))
+			}
+		}
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		diff = string(data)
+	}
+
+	if strings.TrimSpace(diff) == "" {
+		return "", invalidInput(fmt.Errorf("the diff is empty"))
+	}
+	return diff, nil
+}
+
+// reviewDiff reviews the chunks of a diff concurrently and returns the
+// comments sorted by file and line
+func reviewDiff(ctx context.Context, newClient clientFactory, diff string, opts pool.Options) ([]review.Comment, error) {
+	chunks := review.Split(diff, maxReviewChunkTokens)
+	chunkComments := make([][]review.Comment, len(chunks))
+	errs := runPooled(ctx, len(chunks), opts, "Reviewing", func(ctx context.Context, i int) error {
+		resp, err := newClient().QueryWithHistoryContext(ctx, []api.Message{
+			{Role: "system", Content: reviewPrompt},
+			{Role: "user", Content: chunks[i]},
+		})
+		if err != nil {
+			return err
+		}
+		_, content := display.SplitThinking(resp.GetContent())
+		chunkComments[i], err = review.Parse(display.StripCitationMarkers(content))
+		return err
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err := pool.Join(errs, func(i int) string { return fmt.Sprintf("part %d of %d", i+1, len(chunks)) }); err != nil {
+		return nil, err
+	}
+
+	var comments []review.Comment
+	for _, c := range chunkComments {
+		comments = append(comments, c...)
+	}
+	review.Sort(comments)
+	return comments, nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/pool"
+	"github.com/quocvuong92/perplexity-cli/internal/review"
+)
+
+func TestReadReviewDiff(t *testing.T) {
+	ctx := context.Background()
+	diff, err := readReviewDiff(ctx, strings.NewReader("--- a\n+++ b\n"), "")
+	if err != nil || diff != "--- a\n+++ b\n" {
+		t.Errorf("readReviewDiff() = %q, %v, want the piped diff", diff, err)
+	}
+
+	_, err = readReviewDiff(ctx, strings.NewReader(" \n"), "")
+	var inputErr *inputError
+	if !errors.As(err, &inputErr) {
+		t.Errorf("readReviewDiff() of an empty diff error = %v, want invalid input", err)
+	}
+}
+
+func TestReviewDiff(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		requests.Add(1)
+		if req.Messages[0].Content != reviewPrompt {
+			t.Errorf("system message = %q, want the review prompt", req.Messages[0].Content)
+		}
+		file := "b.go"
+		if strings.Contains(req.Messages[1].Content, "a/a.go") {
+			file = "a.go"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: fmt.Sprintf(
+				"```json\n[{\"file\": %q, \"line\": 1, \"severity\": \"warning\", \"message\": \"Check this\"}]\n```", file)}}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", APIURL: server.URL, Model: "sonar"}
+	app := &App{cfg: cfg}
+	long := strings.Repeat("+word word word\n", maxReviewChunkTokens)
+	diff := "diff --git a/b.go b/b.go\n--- a/b.go\n+++ b/b.go\n@@ -1 +1 @@\n" + long +
+		"diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n+x := 1\n"
+
+	var comments []review.Comment
+	var err error
+	captureOutput(func() {
+		comments, err = reviewDiff(context.Background(), app.pooledClients(app.cfg), diff, pool.Options{Workers: 2})
+	})
+	if err != nil {
+		t.Fatalf("reviewDiff() error = %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("reviewDiff() sent %d requests, want one per chunk", requests.Load())
+	}
+	if len(comments) != 2 || comments[0].File != "a.go" || comments[1].File != "b.go" {
+		t.Errorf("reviewDiff() = %+v, want the comments of both chunks sorted by file", comments)
+	}
+}
@@ -2,18 +2,23 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/quocvuong92/perplexity-cli/internal/aliases"
 	"github.com/quocvuong92/perplexity-cli/internal/api"
 	"github.com/quocvuong92/perplexity-cli/internal/config"
 	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+	"github.com/quocvuong92/perplexity-cli/internal/hooks"
 	"github.com/quocvuong92/perplexity-cli/internal/logging"
 	"github.com/quocvuong92/perplexity-cli/internal/retry"
 	"github.com/quocvuong92/perplexity-cli/internal/validation"
@@ -23,11 +28,35 @@ var Version = "dev"
 
 // App holds the application state
 type App struct {
-	cfg        *config.Config
-	client     *api.Client
-	verbose    bool
-	listModels bool
-	noColor    bool
+	cfg              *config.Config
+	client           *api.Client
+	aliases          *aliases.Store
+	verbose          bool
+	listModels       bool
+	refreshModels    bool
+	noColor          bool
+	preset           string
+	compare          string
+	extractCode      bool
+	estimate         bool
+	noSanitize       bool
+	oversizedInput   bool // Piped input is over the prompt length limit and is shortened before sending
+	debugDump        bool
+	dump             *api.Dump // Shared by all clients with --debug-dump
+	watch            time.Duration
+	watchChanges     bool
+	continueLast     bool
+	conversationRef  string
+	messagesJSON     string
+	messages         []api.Message // Messages from --messages-json, sent verbatim
+	format           string
+	raw              bool                       // Print the unmodified API response
+	events           *eventWriter               // Set while writing --format jsonl output
+	history          *history.History           // History holding conversation, if continuing one
+	conversation     *history.ConversationEntry // Stored conversation a one-shot query continues
+	temperature      float64
+	frequencyPenalty float64
+	presencePenalty  float64
 }
 
 // NewApp creates a new App instance with default configuration
@@ -40,6 +69,31 @@ func NewApp() *App {
 // Execute runs the root command
 func Execute() {
 	app := NewApp()
+	defer app.recoverPanic()
+
+	// Move the files of earlier versions to the XDG base directories once
+	migrations, err := config.MigrateDirs()
+	for _, m := range migrations {
+		fmt.Fprintf(os.Stderr, "Note: Moved %s to %s\n", m.From, m.To)
+	}
+	if err != nil {
+		display.ShowWarning(err.Error())
+	}
+
+	// Load the config file, its selected profile and the project file before
+	// defining flags so flag defaults reflect them
+	wd, _ := os.Getwd()
+	layers, err := config.LoadLayers(config.GetConfigPath(), wd)
+	if err != nil {
+		display.ShowError(err.Error())
+		os.Exit(ExitInvalidInput)
+	}
+	app.cfg.ApplyLayers(layers)
+
+	app.aliases = aliases.NewStore()
+	if err := app.aliases.Load(); err != nil {
+		display.ShowWarning(err.Error())
+	}
 
 	rootCmd := &cobra.Command{
 		Use:   "perplexity [query]",
@@ -49,74 +103,295 @@ for the Perplexity API, allowing users to quickly ask questions
 and receive answers directly from the terminal.
 
 Output is in markdown format for easy copying.`,
-		Args: cobra.MaximumNArgs(1),
+		Args: app.validateArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			display.SetQuiet(app.cfg.Quiet)
+			if err := app.initLogging(); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			return nil
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			app.run(cmd, args)
 		},
 	}
 
 	rootCmd.Flags().BoolVarP(&app.verbose, "verbose", "v", false, "Enable debug mode")
-	rootCmd.Flags().BoolVarP(&app.cfg.Usage, "usage", "u", false, "Show token usage statistics")
-	rootCmd.Flags().BoolVarP(&app.cfg.Citations, "citations", "c", false, "Show citations")
-	rootCmd.Flags().BoolVarP(&app.cfg.Stream, "stream", "s", false, "Stream output in real-time")
-	rootCmd.Flags().BoolVarP(&app.cfg.Render, "render", "r", false, "Render markdown with colors and formatting")
+	rootCmd.PersistentFlags().StringVar(&app.cfg.LogLevel, "log-level", app.cfg.LogLevel, "Log to stderr at this level: debug, info, warn or error (--verbose implies debug)")
+	rootCmd.PersistentFlags().IntVar(&app.cfg.Concurrency, "concurrency", app.cfg.Concurrency, "Concurrent requests of --compare and the batch commands such as digest (0 for the default of 4)")
+	rootCmd.PersistentFlags().StringVar(&app.cfg.LogFormat, "log-format", app.cfg.LogFormat, "Log format: text, or json for log collectors")
+	rootCmd.Flags().BoolVarP(&app.cfg.Usage, "usage", "u", app.cfg.Usage, "Show token usage statistics")
+	rootCmd.Flags().BoolVarP(&app.cfg.Citations, "citations", "c", app.cfg.Citations, "Show citations")
+	rootCmd.Flags().BoolVarP(&app.cfg.Stream, "stream", "s", app.cfg.Stream, "Stream output in real-time")
+	rootCmd.Flags().BoolVarP(&app.cfg.Render, "render", "r", app.cfg.Render, "Render markdown with colors and formatting")
+	rootCmd.Flags().StringVar(&app.cfg.Theme, "theme", app.cfg.Theme, "Markdown rendering theme: auto, dark, light, dracula or notty")
+	rootCmd.Flags().StringVar(&app.cfg.CitationStyle, "citation-style", app.cfg.CitationStyle,
+		"How inline [n] citation markers are shown: markers, links or footnotes")
+	rootCmd.Flags().BoolVar(&app.cfg.HideCitationMarkers, "no-citation-markers", app.cfg.HideCitationMarkers, "Remove inline [n] citation markers from responses")
+	rootCmd.Flags().BoolVar(&app.cfg.RelatedQuestions, "related-questions", app.cfg.RelatedQuestions, "Request related questions and offer them after each answer")
+	rootCmd.Flags().BoolVar(&app.cfg.ShellInterpolation, "shell-interpolation", app.cfg.ShellInterpolation,
+		"In interactive mode, run !{command} placeholders in messages after confirmation and insert their output")
+	rootCmd.Flags().BoolVar(&app.cfg.Images, "images", app.cfg.Images, "Request images and list them after the answer")
+	rootCmd.Flags().StringSliceVar(&app.cfg.ImageDomains, "image-domains", app.cfg.ImageDomains,
+		"With --images, only return images from these comma-separated domains (prefix with - to exclude)")
+	rootCmd.Flags().StringSliceVar(&app.cfg.ImageFormats, "image-formats", app.cfg.ImageFormats,
+		"With --images, only return images in these comma-separated formats, e.g. png,jpg")
+	rootCmd.Flags().BoolVar(&app.cfg.HideThinking, "no-thinking", app.cfg.HideThinking, "Hide the reasoning of reasoning models")
+	rootCmd.PersistentFlags().BoolVarP(&app.cfg.Quiet, "quiet", "q", app.cfg.Quiet, "Suppress the spinner and informational notes; only the answer and errors are shown")
 	rootCmd.Flags().BoolVarP(&app.cfg.Interactive, "interactive", "i", false, "Interactive chat mode")
 	rootCmd.Flags().StringVarP(&app.cfg.APIKey, "api-key", "a", "", "API key (defaults to PERPLEXITY_API_KEYS or PERPLEXITY_API_KEY env var)")
-	rootCmd.Flags().StringVarP(&app.cfg.Model, "model", "m", config.DefaultModel,
-		fmt.Sprintf("Model to use. Available: %s", config.GetAvailableModelsString()))
+	rootCmd.Flags().StringVarP(&app.cfg.Model, "model", "m", app.cfg.Model,
+		fmt.Sprintf("Model or model alias to use. Available: %s", app.cfg.GetModelsString()))
+	rootCmd.Flags().DurationVar(&app.cfg.Timeout, "timeout", app.cfg.Timeout, "Time limit for a query, including the whole stream (e.g. 30s, 5m; 0 for none)")
+	rootCmd.Flags().IntVar(&app.cfg.Retry.MaxRetries, "max-retries", app.cfg.Retry.MaxRetries, "Retries of a request after a network error (0 to fail fast)")
+	rootCmd.Flags().DurationVar(&app.cfg.Retry.InitialBackoff, "retry-backoff", app.cfg.Retry.InitialBackoff, "Wait before the first retry, doubled for each further retry")
+	rootCmd.Flags().DurationVar(&app.cfg.Retry.MaxBackoff, "retry-max-backoff", app.cfg.Retry.MaxBackoff, "Longest wait between retries")
 	rootCmd.Flags().StringVarP(&app.cfg.OutputFile, "output", "o", "", "Save response to file")
+	rootCmd.Flags().StringVar(&app.preset, "preset", "",
+		fmt.Sprintf("Task preset bundling system prompt, model and settings. Available: %s", strings.Join(app.cfg.GetPresetNames(), ", ")))
+	rootCmd.Flags().Float64Var(&app.temperature, "temperature", 0, "Sampling temperature, at least 0 and below 2")
+	rootCmd.Flags().Float64Var(&app.frequencyPenalty, "frequency-penalty", 0, "Penalize tokens by how often they already appear (-2 to 2)")
+	rootCmd.Flags().Float64Var(&app.presencePenalty, "presence-penalty", 0, "Penalize tokens that already appear, encouraging new topics (-2 to 2)")
+	rootCmd.Flags().IntVar(&app.cfg.TopK, "top-k", app.cfg.TopK, "Sample only from the k most likely tokens (0 to disable)")
+	rootCmd.Flags().StringVar(&app.cfg.ReasoningEffort, "reasoning-effort", app.cfg.ReasoningEffort,
+		"Reasoning effort for reasoning models: low, medium or high")
+	rootCmd.Flags().StringVar(&app.cfg.SearchContext, "search-context", app.cfg.SearchContext,
+		"How much web search context to retrieve: low (cheapest), medium or high")
+	rootCmd.Flags().StringVar(&app.cfg.Location, "location", app.cfg.Location, "Localize search results to \"latitude,longitude\", e.g. \"52.52,13.40\"")
+	rootCmd.Flags().StringVar(&app.cfg.Country, "country", app.cfg.Country, "Localize search results to a country by its two-letter code, e.g. DE")
+	rootCmd.Flags().BoolVar(&app.estimate, "estimate", false, "Print the estimated prompt tokens and input cost instead of sending the query")
+	rootCmd.Flags().BoolVar(&app.extractCode, "extract-code", false, "Print only the code blocks of the response")
+	rootCmd.Flags().BoolVar(&app.continueLast, "continue", false, "Continue the most recent conversation from history with a follow-up query")
+	rootCmd.Flags().StringVar(&app.format, "format", formatText, "Output format: text, or jsonl for one JSON event per line")
+	rootCmd.Flags().BoolVar(&app.raw, "raw", false, "Print the unmodified API response JSON, or the raw SSE frames with --stream")
+	rootCmd.Flags().StringVar(&app.messagesJSON, "messages-json", "", "Send a JSON messages array read from a file (- for stdin) instead of a query")
+	rootCmd.Flags().StringVar(&app.conversationRef, "conversation", "", "Continue a stored conversation by ID or /history index, or \"new\" to start one")
+	rootCmd.Flags().DurationVar(&app.watch, "watch", 0, "Re-run the query at this interval (e.g. 30m, 1h) until interrupted")
+	rootCmd.Flags().BoolVar(&app.watchChanges, "watch-changes", false, "With --watch, only show answers that differ materially from the previous run")
+	rootCmd.Flags().StringVar(&app.compare, "compare", "", "Send the query to several comma-separated models and compare the responses")
 	rootCmd.Flags().BoolVar(&app.listModels, "list-models", false, "List available models")
+	rootCmd.Flags().BoolVar(&app.refreshModels, "refresh-models", false, "Refresh the cached model list from the models endpoint and list it")
+	rootCmd.Flags().IntVar(&app.cfg.ChunkTokens, "chunk-size", app.cfg.ChunkTokens, "Tokens per chunk when shortening piped input over the prompt limit (0 for half the model's context window)")
+	rootCmd.Flags().StringVar(&app.cfg.ChunkStrategy, "chunk-strategy", app.cfg.ChunkStrategy,
+		"How piped input over the prompt limit is shortened: map-reduce (default), refine, truncate or reject")
+	rootCmd.Flags().BoolVar(&app.noSanitize, "no-sanitize", false, "Send prompts as given, without stripping control characters or limiting their length")
+	rootCmd.Flags().BoolVar(&app.debugDump, "debug-dump", false, "Save the exact requests and raw responses to a timestamped file for bug reports")
 	rootCmd.Flags().BoolVar(&app.noColor, "no-color", false, "Disable colored output")
+	_ = rootCmd.MarkFlagFilename("output")
+	_ = rootCmd.MarkFlagFilename("messages-json", "json")
 	rootCmd.Version = Version
 
+	rootCmd.SetFlagErrorFunc(flagError)
+	rootCmd.AddCommand(app.newAliasCmd())
+	rootCmd.AddCommand(app.newBenchCmd())
+	rootCmd.AddCommand(app.newTokensCmd())
+	rootCmd.AddCommand(app.newCommitCmd())
+	rootCmd.AddCommand(app.newReviewCmd())
+	rootCmd.AddCommand(app.newExplainCmd())
+	rootCmd.AddCommand(app.newURLCmd())
+	rootCmd.AddCommand(app.newDocCmd())
+	rootCmd.AddCommand(app.newDigestCmd())
+	rootCmd.AddCommand(app.newTranslateCmd())
+	rootCmd.AddCommand(app.newProofreadCmd())
+	rootCmd.AddCommand(app.newScriptCmd())
+	rootCmd.AddCommand(app.newInitCmd())
+	rootCmd.AddCommand(app.newDoctorCmd())
+	rootCmd.AddCommand(app.newHistoryCmd())
+	rootCmd.AddCommand(app.newExportAllCmd())
+	rootCmd.AddCommand(app.newImportAllCmd())
+
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// initLogging sends logs to stderr at debug level with --verbose, or at the
+// configured log level. Without either, logs are discarded. An invalid log
+// level or format is an invalid input error.
+func (app *App) initLogging() error {
+	if err := app.cfg.ValidateLogging(); err != nil {
+		return invalidInput(err)
+	}
+	cfg := logging.Config{Output: io.Discard, Format: app.cfg.LogFormat}
+	switch {
+	case app.verbose:
+		cfg.Output = os.Stderr
+		cfg.Verbose = true
+	case app.cfg.LogLevel != "":
+		level, err := logging.ParseLevel(app.cfg.LogLevel)
+		if err != nil {
+			return invalidInput(err)
+		}
+		cfg.Level = level
+		cfg.Output = os.Stderr
+	}
+	logging.Init(cfg)
+
+	if app.cfg.Profile != "" || app.cfg.ProjectFile != "" {
+		logging.Debug("Config applied", logging.String("profile", app.cfg.Profile), logging.String("project_file", app.cfg.ProjectFile))
 	}
+	return nil
 }
 
 func (app *App) run(cmd *cobra.Command, args []string) {
-	// Initialize structured logging
-	if app.verbose {
-		logging.Init(logging.Config{
-			Level:   logging.LevelDebug,
-			Output:  os.Stderr,
-			Verbose: true,
-		})
-	} else {
-		logging.Init(logging.Config{
-			Output: io.Discard,
-		})
+	if app.preset != "" {
+		if err := app.applyPreset(cmd); err != nil {
+			display.ShowError(err.Error())
+			os.Exit(ExitInvalidInput)
+		}
 	}
 
+	if cmd.Flags().Changed("temperature") {
+		app.cfg.Temperature = &app.temperature
+	}
+	if cmd.Flags().Changed("frequency-penalty") {
+		app.cfg.FrequencyPenalty = &app.frequencyPenalty
+	}
+	if cmd.Flags().Changed("presence-penalty") {
+		app.cfg.PresencePenalty = &app.presencePenalty
+	}
+	app.cfg.OutputFile = config.ExpandHome(app.cfg.OutputFile)
+
+	app.loadModels(app.refreshModels)
+
 	// Handle --list-models flag (doesn't require API key)
-	if app.listModels {
-		display.ShowModels(config.AvailableModels, app.cfg.Model)
+	if app.listModels || app.refreshModels {
+		display.ShowModels(app.cfg.GetModels(), app.cfg.ResolveModel(app.cfg.Model))
+		display.ShowModelAliases(app.cfg.GetModelAliasNames(), app.cfg.ModelAliases)
 		return
 	}
 
-	if err := app.cfg.Validate(); err != nil {
+	if err := app.validateConfig(); err != nil {
 		display.ShowError(err.Error())
-		os.Exit(1)
+		os.Exit(exitCode(invalidInput(err)))
+	}
+
+	if !app.cfg.Quiet && app.cfg.ReasoningEffort != "" && app.cfg.Model != config.AutoModel && !config.IsReasoningModel(app.cfg.Model) {
+		fmt.Fprintf(os.Stderr, "Note: Reasoning effort only applies to reasoning models, not %s\n", app.cfg.Model)
+	}
+
+	if err := app.validateFormat(); err != nil {
+		display.ShowError(err.Error())
+		os.Exit(ExitInvalidInput)
+	}
+
+	if err := app.validateRaw(); err != nil {
+		display.ShowError(err.Error())
+		os.Exit(ExitInvalidInput)
+	}
+
+	if err := app.validateWatch(); err != nil {
+		display.ShowError(err.Error())
+		os.Exit(ExitInvalidInput)
+	}
+
+	if err := app.loadConversation(); err != nil {
+		display.ShowError(err.Error())
+		os.Exit(ExitInvalidInput)
+	}
+
+	var compareModels []string
+	if app.compare != "" {
+		models, err := app.parseCompareModels(app.compare)
+		if err != nil {
+			display.ShowError(err.Error())
+			os.Exit(ExitInvalidInput)
+		}
+		compareModels = models
 	}
 
 	// Initialize markdown renderer if render flag is set
 	if app.cfg.Render {
-		if err := display.InitRenderer(); err != nil {
+		if err := display.InitRenderer(app.cfg.Theme); err != nil {
 			logging.Warn("Failed to initialize renderer", logging.Err(err))
 		}
 	}
 
 	// Interactive mode
 	if app.cfg.Interactive {
+		app.openDebugDump()
 		app.runInteractive(app.shouldUseColor())
 		return
 	}
 
+	var query string
+	if app.messagesJSON != "" {
+		query = app.loadMessages(args)
+	} else {
+		query = app.readQuery(cmd, args)
+	}
+
+	logging.Debug("Processing query",
+		logging.String("query", query),
+		logging.String("model", app.cfg.Model),
+		logging.Bool("stream", app.cfg.Stream),
+	)
+
+	if app.estimate {
+		app.showEstimate(query)
+		return
+	}
+
+	app.openDebugDump()
+	app.client = app.newClient()
+
+	logging.Debug("Sending request to API")
+
+	ctx, cancel := newSignalContext()
+	defer cancel()
+
+	var err error
+	if app.oversizedInput {
+		if query, err = app.condenseInput(ctx, query); err != nil {
+			if ctx.Err() == nil {
+				display.ShowError(err.Error())
+			}
+			cancel()
+			os.Exit(exitCode(err))
+		}
+	}
+
+	if len(compareModels) > 0 {
+		err = app.runCompare(ctx, compareModels, query)
+	} else if app.raw {
+		err = app.runRaw(ctx, query)
+	} else if app.format == formatJSONL {
+		err = app.runJSONL(ctx, query)
+	} else if app.extractCode {
+		err = app.runExtractCode(ctx, query)
+	} else if app.watch > 0 {
+		app.runWatch(ctx, query)
+	} else if app.cfg.Stream {
+		err = app.runStream(ctx, query)
+	} else {
+		err = app.runNormal(ctx, query)
+	}
+
+	if err != nil {
+		logging.Debug("Query failed", logging.Err(err))
+		cancel()
+		os.Exit(exitCode(err))
+	}
+}
+
+// readQuery returns the validated query from the arguments, an alias or
+// piped stdin. Shows help and exits if there is none. Piped input over the
+// prompt length limit is returned as is and marked to be shortened, unless
+// the chunk strategy rejects it.
+func (app *App) readQuery(cmd *cobra.Command, args []string) string {
+	// An alias as the first argument takes the remaining words (or stdin) as its input
+	aliasTemplate, isAlias := app.lookupAlias(args)
+	if isAlias {
+		args = args[1:]
+	}
+
 	// Get query from args or stdin (pipe)
 	var query string
-	if len(args) > 0 {
+	piped := false
+	if len(args) > 0 && !isAlias {
 		query = args[0]
-	} else {
+	} else if len(args) == 0 {
 		// Check if there's input from pipe
 		stat, _ := os.Stdin.Stat()
 		if (stat.Mode() & os.ModeCharDevice) == 0 {
@@ -127,46 +402,55 @@ func (app *App) run(cmd *cobra.Command, args []string) {
 				os.Exit(1)
 			}
 			query = strings.TrimSpace(string(data))
+			piped = true
 		}
 	}
 
+	if isAlias {
+		query = expandAlias(aliasTemplate, args, query)
+		logging.Debug("Expanded alias", logging.String("query", query))
+	}
+
 	// Require query
 	if query == "" {
 		_ = cmd.Help()
-		os.Exit(1)
+		os.Exit(ExitInvalidInput)
 	}
 
-	// Validate and sanitize the query
-	query = validation.SanitizePrompt(query)
-	result := validation.ValidatePrompt(query)
-	if !result.Valid {
-		display.ShowError(result.Error.Error())
-		os.Exit(1)
+	cleaned, err := app.cleanPrompt(query)
+	if errors.Is(err, validation.ErrPromptTooLong) && piped && app.cfg.ChunkStrategy != config.ChunkStrategyReject {
+		// Shortened by condenseInput once the client is set up
+		app.oversizedInput = true
+		return strings.TrimSpace(validation.SanitizePrompt(query))
 	}
-	query = result.Cleaned
-
-	logging.Debug("Processing query",
-		logging.String("query", query),
-		logging.String("model", app.cfg.Model),
-		logging.Bool("stream", app.cfg.Stream),
-	)
-
-	app.client = api.NewClient(app.cfg)
-
-	// Set up key rotation callback to notify user
-	app.client.SetKeyRotationCallback(func(fromIndex, toIndex int, totalKeys int) {
-		display.ShowKeyRotation(fromIndex, toIndex, totalKeys)
-	})
+	if err != nil {
+		display.ShowError(err.Error())
+		os.Exit(ExitInvalidInput)
+	}
+	return cleaned
+}
 
-	// Set up retry callback to notify user of network retries
-	app.client.SetRetryCallback(func(info retry.RetryInfo) {
-		display.ShowRetry(info.Attempt+1, info.MaxRetries, info.NextBackoff)
-	})
+// cleanPrompt sanitizes and validates a prompt. With --no-sanitize, control
+// characters are kept and the length is not limited; only empty prompts are rejected.
+func (app *App) cleanPrompt(prompt string) (string, error) {
+	if app.noSanitize {
+		cleaned := strings.TrimSpace(prompt)
+		if cleaned == "" {
+			return "", validation.ErrEmptyPrompt
+		}
+		return cleaned, nil
+	}
 
-	logging.Debug("Sending request to API")
+	result := validation.ValidatePrompt(validation.SanitizePrompt(prompt))
+	if !result.Valid {
+		return "", result.Error
+	}
+	return result.Cleaned, nil
+}
 
+// newSignalContext returns a context that is cancelled on SIGINT or SIGTERM
+func newSignalContext() (context.Context, context.CancelFunc) {
 	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -176,11 +460,112 @@ func (app *App) run(cmd *cobra.Command, args []string) {
 		cancel()
 	}()
 
-	if app.cfg.Stream {
-		app.runStream(ctx, query)
-	} else {
-		app.runNormal(ctx, query)
+	return ctx, cancel
+}
+
+// applyPreset applies the selected preset. Settings given explicitly
+// on the command line take precedence over the preset.
+func (app *App) applyPreset(cmd *cobra.Command) error {
+	preset, err := app.cfg.GetPreset(app.preset)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Applying preset", logging.String("preset", app.preset))
+
+	if preset.SystemPrompt != "" {
+		app.cfg.SystemPrompt = preset.SystemPrompt
+	}
+	if preset.Model != "" && !cmd.Flags().Changed("model") {
+		app.cfg.Model = preset.Model
+	}
+	if preset.Temperature != nil && !cmd.Flags().Changed("temperature") {
+		app.cfg.Temperature = preset.Temperature
+	}
+	if preset.Citations != nil && !cmd.Flags().Changed("citations") {
+		app.cfg.Citations = *preset.Citations
+	}
+	return nil
+}
+
+// newClient creates an API client with the user notification callbacks and hooks wired up
+func (app *App) newClient() *api.Client {
+	return app.newClientFor(app.cfg)
+}
+
+// taskConfig validates the app config and returns a copy for a task
+// subcommand querying model with input, without related questions or images
+func (app *App) taskConfig(model, input string) (*config.Config, error) {
+	app.loadModels(false)
+	if err := app.validateConfig(); err != nil {
+		return nil, invalidInput(err)
 	}
+	cfg := *app.cfg
+	cfg.Model = app.cfg.ResolveModel(model)
+	if cfg.Model == config.AutoModel {
+		cfg.Model, _ = cfg.SelectModel(input)
+	}
+	cfg.RelatedQuestions = false
+	cfg.Images = false
+	return &cfg, nil
+}
+
+// newClientFor creates a client like newClient, using cfg instead of the app config
+func (app *App) newClientFor(cfg *config.Config) *api.Client {
+	client := api.NewClient(cfg)
+	client.SetRetryConfig(cfg.Retry)
+	if app.dump != nil {
+		client.SetDump(app.dump)
+	}
+
+	// Set up key rotation callback to notify user
+	client.SetKeyRotationCallback(func(fromIndex, toIndex int, totalKeys int) {
+		display.ShowKeyRotation(fromIndex, toIndex, totalKeys)
+	})
+
+	// Remember the key that worked for the next run, if persist_key_index is set
+	client.SetKeySuccessCallback(func() {
+		if err := cfg.SaveWorkingKey(); err != nil {
+			logging.Debug("Failed to save the working API key", logging.Err(err))
+		}
+	})
+
+	// Keep the requests each key has left, for the weighted key strategy
+	client.SetKeyQuotaCallback(func(index, remaining int) {
+		if err := cfg.RecordKeyQuota(index, remaining); err != nil {
+			logging.Debug("Failed to save the API key quota", logging.Err(err))
+		}
+	})
+
+	// Set up retry callback to notify user of network and server error retries
+	client.SetRetryCallback(func(info retry.RetryInfo) {
+		reason := "Network error"
+		var apiErr *api.APIError
+		if retry.IsServerError(info.Error) && errors.As(info.Error, &apiErr) {
+			reason = fmt.Sprintf("Server error %d", apiErr.StatusCode)
+		}
+		display.ShowRetry(reason, info.Attempt+1, info.MaxRetries, info.NextBackoff)
+	})
+
+	client.SetContextWarningCallback(func(message string) {
+		display.ShowWarning(message)
+	})
+
+	client.SetModelSelectedCallback(func(model, reason string) {
+		logging.Debug("Auto-selected model", logging.String("model", model), logging.String("reason", reason))
+		display.ShowModelSelected(model, reason)
+	})
+
+	if runner := hooks.NewRunner(cfg); runner != nil {
+		client.SetPreQueryHook(runner.PreQuery)
+		client.SetPostResponseHook(func(ctx context.Context, messages []api.Message, content string, resp *api.ChatResponse) {
+			if err := runner.PostResponse(ctx, messages, content, resp); err != nil {
+				display.ShowWarning(err.Error())
+			}
+		})
+	}
+
+	return client
 }
 
 // shouldUseColor determines if colored output should be used
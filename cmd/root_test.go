@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"os"
 	"strings"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/quocvuong92/perplexity-cli/internal/config"
 	"github.com/quocvuong92/perplexity-cli/internal/logging"
+	"github.com/quocvuong92/perplexity-cli/internal/validation"
 )
 
 func TestNewApp(t *testing.T) {
@@ -149,3 +151,101 @@ func TestVerboseFlag(t *testing.T) {
 
 	// If we get here without panic, verbose initialization worked
 }
+
+func TestInitLoggingRejectsInvalidSettings(t *testing.T) {
+	logging.ResetForTesting()
+	t.Cleanup(logging.ResetForTesting)
+
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		wantErr bool
+	}{
+		{"defaults", "", "", false},
+		{"valid", "warn", "json", false},
+		{"invalid level", "verbose", "", true},
+		{"invalid format", "", "xml", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := NewApp()
+			app.cfg.LogLevel = tt.level
+			app.cfg.LogFormat = tt.format
+			err := app.initLogging()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("initLogging() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && exitCode(err) != ExitInvalidInput {
+				t.Errorf("exitCode() = %d, want %d", exitCode(err), ExitInvalidInput)
+			}
+		})
+	}
+}
+
+func TestApplyPreset(t *testing.T) {
+	app := NewApp()
+	app.preset = "eli5"
+
+	cmd := &cobra.Command{}
+	cmd.Flags().StringVarP(&app.cfg.Model, "model", "m", app.cfg.Model, "")
+	cmd.Flags().BoolVarP(&app.cfg.Citations, "citations", "c", false, "")
+	if err := cmd.ParseFlags([]string{"--model", "sonar-pro"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.applyPreset(cmd); err != nil {
+		t.Fatalf("applyPreset() error = %v", err)
+	}
+
+	preset := config.BuiltinPresets["eli5"]
+	if app.cfg.SystemPrompt != preset.SystemPrompt {
+		t.Error("Preset should set the system prompt")
+	}
+	if app.cfg.Model != "sonar-pro" {
+		t.Errorf("Explicit --model should win over preset, got %s", app.cfg.Model)
+	}
+	if app.cfg.Temperature == nil || *app.cfg.Temperature != *preset.Temperature {
+		t.Error("Preset should set the temperature")
+	}
+
+	app.preset = "unknown"
+	if err := app.applyPreset(cmd); err == nil {
+		t.Error("applyPreset() should fail for unknown preset")
+	}
+}
+
+func TestCleanPrompt(t *testing.T) {
+	long := strings.Repeat("a", validation.MaxPromptLength+1)
+
+	tests := []struct {
+		name       string
+		noSanitize bool
+		prompt     string
+		want       string
+		wantErr    error
+	}{
+		{"trims", false, "  What is Go?\n", "What is Go?", nil},
+		{"strips control characters", false, "What\x00 is\x1b Go?", "What is Go?", nil},
+		{"empty", false, " \n", "", validation.ErrEmptyPrompt},
+		{"too long", false, long, "", validation.ErrPromptTooLong},
+		{"no-sanitize keeps control characters", true, "What\x1b[1m is Go?", "What\x1b[1m is Go?", nil},
+		{"no-sanitize has no length limit", true, long, long, nil},
+		{"no-sanitize rejects empty", true, " ", "", validation.ErrEmptyPrompt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := NewApp()
+			app.noSanitize = tt.noSanitize
+			got, err := app.cleanPrompt(tt.prompt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("cleanPrompt() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("cleanPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/quocvuong92/perplexity-cli/internal/codeblock"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/shell"
+	"github.com/quocvuong92/perplexity-cli/internal/tools"
+)
+
+// stdinReader buffers user input for readLine. It is shared so that input
+// buffered past one line, such as answers pasted at once, is not lost.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// readLine reads a line of user input. It is a variable so tests can replace it.
+var readLine = func() (string, error) {
+	return stdinReader.ReadString('\n')
+}
+
+// confirm asks a yes/no question, defaulting to no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := readLine()
+	if err != nil {
+		fmt.Println()
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// cmdRun runs a shell code block of the last response after confirmation,
+// and offers to send its output back to the assistant
+func (s *InteractiveSession) cmdRun(parts []string) bool {
+	blocks := codeblock.Extract(s.lastResponse)
+	if len(blocks) == 0 {
+		fmt.Println("No code blocks in the last response.")
+		return false
+	}
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		fmt.Printf("Usage: /run <n> (1-%d)\n", len(blocks))
+		return false
+	}
+
+	n, ok := parseCodeBlockNumber(strings.TrimSpace(parts[1]), len(blocks))
+	if !ok {
+		return false
+	}
+	block := blocks[n-1]
+	if !codeblock.IsShell(block.Language) {
+		fmt.Printf("Code block %d is %s, not a shell script.\n", n, block.Language)
+		return false
+	}
+
+	fmt.Printf("\nCode block %d:\n\n%s\n\n", n, block.Code)
+	if !confirm("Run this in a shell?") {
+		fmt.Println("Cancelled.")
+		return false
+	}
+	fmt.Println()
+
+	output, cancelled, runErr := s.runShell(block.Code)
+	if cancelled {
+		return false
+	}
+	if runErr != nil {
+		display.ShowWarning(fmt.Sprintf("Command failed: %v", runErr))
+	}
+	fmt.Println()
+
+	if !confirm("Send the output to the assistant?") {
+		return false
+	}
+	fmt.Println()
+
+	for input := formatRunOutput(block.Code, output, runErr); input != ""; {
+		input = s.chat(input)
+	}
+	return false
+}
+
+// runShell runs code through the system shell, printing its output as it is
+// produced. Ctrl+C stops the command.
+func (s *InteractiveSession) runShell(code string) (output string, cancelled bool, err error) {
+	ctx := s.interruptCtx.Start()
+	defer s.interruptCtx.Stop()
+
+	var out bytes.Buffer
+	cmd := shell.Command(ctx, code)
+	cmd.Stdout = io.MultiWriter(os.Stdout, &out)
+	cmd.Stderr = io.MultiWriter(os.Stderr, &out)
+
+	err = cmd.Run()
+	return out.String(), ctx.Err() == context.Canceled, err
+}
+
+// formatRunOutput builds the message reporting a command's output to the assistant
+func formatRunOutput(code, output string, runErr error) string {
+	output = strings.TrimSpace(output)
+	if len(output) > tools.MaxOutputBytes {
+		output = output[:tools.MaxOutputBytes] + "\n[output truncated]"
+	}
+	if output == "" {
+		output = "(no output)"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "I ran:\n\n```sh\n%s\n```\n\n", code)
+	if runErr != nil {
+		fmt.Fprintf(&b, "It failed (%v) with this output:\n\n", runErr)
+	} else {
+		b.WriteString("Output:\n\n")
+	}
+	fmt.Fprintf(&b, "```\n%s\n```", output)
+	return b.String()
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// stubReadLine answers prompts with the given lines in order
+func stubReadLine(t *testing.T, answers ...string) {
+	t.Helper()
+	old := readLine
+	t.Cleanup(func() { readLine = old })
+	readLine = func() (string, error) {
+		if len(answers) == 0 {
+			return "", errors.New("no more input")
+		}
+		answer := answers[0]
+		answers = answers[1:]
+		return answer + "\n", nil
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   bool
+	}{
+		{"y", true},
+		{"YES", true},
+		{"n", false},
+		{"", false},
+		{"maybe", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			stubReadLine(t, tt.answer)
+			var got bool
+			captureOutput(func() {
+				got = confirm("Proceed?")
+			})
+			if got != tt.want {
+				t.Errorf("confirm() with %q = %v, want %v", tt.answer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLineKeepsBufferedInput(t *testing.T) {
+	old := stdinReader
+	t.Cleanup(func() { stdinReader = old })
+	stdinReader = bufio.NewReader(strings.NewReader("y\nn\n"))
+
+	// Both answers arrive in one read, and the second must not be lost
+	if !confirm("First?") {
+		t.Error("confirm() = false for the first answer, want true")
+	}
+	if answer, err := readLine(); err != nil || answer != "n\n" {
+		t.Errorf("readLine() = %q, %v, want the second answer", answer, err)
+	}
+}
+
+func TestCmdRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("code blocks use sh syntax")
+	}
+
+	session := newTestSession()
+	session.interruptCtx = NewInterruptibleContext()
+	session.lastResponse = "```bash\necho ran-it\n```\n\n```python\nprint(1)\n```"
+
+	t.Run("usage", func(t *testing.T) {
+		output := captureOutput(func() {
+			session.cmdRun([]string{"/run"})
+		})
+		if !strings.Contains(output, "Usage: /run <n> (1-2)") {
+			t.Errorf("output = %q, want usage", output)
+		}
+	})
+
+	t.Run("not a shell script", func(t *testing.T) {
+		output := captureOutput(func() {
+			session.cmdRun([]string{"/run", "2"})
+		})
+		if !strings.Contains(output, "Code block 2 is python, not a shell script.") {
+			t.Errorf("output = %q, want shell-only message", output)
+		}
+	})
+
+	t.Run("declined", func(t *testing.T) {
+		stubReadLine(t, "n")
+		output := captureOutput(func() {
+			session.cmdRun([]string{"/run", "1"})
+		})
+		if !strings.Contains(output, "Cancelled.") || strings.Count(output, "ran-it") != 1 {
+			t.Errorf("output = %q, want cancelled without running", output)
+		}
+	})
+
+	t.Run("runs without sending output", func(t *testing.T) {
+		stubReadLine(t, "y", "n")
+		count := session.getMessageCount()
+		output := captureOutput(func() {
+			session.cmdRun([]string{"/run", "1"})
+		})
+		if strings.Count(output, "ran-it") != 2 {
+			t.Errorf("output = %q, want code and command output", output)
+		}
+		if session.getMessageCount() != count {
+			t.Error("output should not be added to the conversation when declined")
+		}
+	})
+}
+
+func TestFormatRunOutput(t *testing.T) {
+	got := formatRunOutput("ls", "a.txt\n", nil)
+	if !strings.Contains(got, "```sh\nls\n```") || !strings.Contains(got, "Output:\n\n```\na.txt\n```") {
+		t.Errorf("formatRunOutput() = %q", got)
+	}
+
+	got = formatRunOutput("false", "", errors.New("exit status 1"))
+	if !strings.Contains(got, "It failed (exit status 1)") || !strings.Contains(got, "(no output)") {
+		t.Errorf("formatRunOutput() = %q, want failure and no output", got)
+	}
+}
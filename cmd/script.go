@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/script"
+)
+
+// newScriptCmd creates the script subcommand for running scripted conversations
+func (app *App) newScriptCmd() *cobra.Command {
+	var (
+		model      string
+		vars       []string
+		jsonOutput bool
+	)
+
+	scriptCmd := &cobra.Command{
+		Use:   "script <file.yaml>",
+		Short: "Run a scripted multi-turn conversation and check its responses",
+		Long: `Send the user turns of a YAML script as one conversation and report each
+response with the outcome of its assertions, for reproducible research
+protocols and prompt regression checks. {name} placeholders in the prompts
+and the system prompt are replaced with the script's vars, which --var
+overrides. The command exits with an error if an assertion or request fails.
+
+  name: Go generics
+  model: sonar-pro
+  system: Answer in three sentences at most.
+  vars:
+    version: "1.18"
+  turns:
+    - prompt: What did Go {version} add?
+      expect:
+        contains: [generics]
+        min_citations: 1
+    - prompt: Show a generic Map function.
+      expect:
+        matches: 'func Map\['
+        not_contains: ["I'm not sure"]
+
+  perplexity script generics.yaml
+  perplexity script generics.yaml --var version=1.21 --json > report.json`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		ValidArgsFunction: func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+			return []string{"yaml", "yml"}, cobra.ShellCompDirectiveFilterFileExt
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sc, err := script.Load(args[0])
+			if err != nil {
+				return invalidInput(err)
+			}
+			overrides, err := script.ParseVars(vars)
+			if err != nil {
+				return invalidInput(err)
+			}
+			if err := sc.Expand(overrides); err != nil {
+				return invalidInput(err)
+			}
+
+			if !cmd.Flags().Changed("model") {
+				model = cmp.Or(sc.Model, model)
+			}
+			cfg, err := app.taskConfig(model, sc.Turns[0].Prompt)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := newSignalContext()
+			defer cancel()
+
+			report := runScript(ctx, app.newClientFor(cfg), sc, cmp.Or(sc.System, app.cfg.GetSystemPrompt()))
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			report.Model = cfg.Model
+
+			if jsonOutput {
+				if err := script.WriteJSON(cmd.OutOrStdout(), report); err != nil {
+					return err
+				}
+			} else {
+				script.WriteText(cmd.OutOrStdout(), report)
+			}
+
+			if last := report.Turns[len(report.Turns)-1]; last.Error != "" {
+				return fmt.Errorf("turn %d failed: %s", len(report.Turns), last.Error)
+			}
+			if report.Failed > 0 {
+				return fmt.Errorf("%d of %d assertions failed", report.Failed, report.Passed+report.Failed)
+			}
+			return nil
+		},
+	}
+
+	scriptCmd.Flags().StringVarP(&model, "model", "m", app.cfg.Model, "Model answering the turns (default: the script's model)")
+	scriptCmd.Flags().StringArrayVar(&vars, "var", nil, "Set a template variable as name=value (repeatable)")
+	scriptCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the report as JSON")
+	return scriptCmd
+}
+
+// runScript sends the turns of a script as one conversation and checks each
+// response. A failed request ends the run, as later turns depend on it.
+func runScript(ctx context.Context, client *api.Client, sc *script.Script, systemPrompt string) *script.Report {
+	report := &script.Report{Name: sc.Name}
+	messages := []api.Message{{Role: "system", Content: systemPrompt}}
+	for i, turn := range sc.Turns {
+		messages = append(messages, api.Message{Role: "user", Content: turn.Prompt})
+		resp, err := queryWithSpinner(ctx, client, fmt.Sprintf("Turn %d/%d...", i+1, len(sc.Turns)), messages)
+		if err != nil {
+			report.Add(script.TurnResult{Prompt: turn.Prompt, Error: err.Error()})
+			break
+		}
+
+		_, answer := display.SplitThinking(resp.GetContent())
+		messages = append(messages, api.Message{Role: "assistant", Content: answer})
+		report.Add(script.TurnResult{
+			Prompt:    turn.Prompt,
+			Response:  answer,
+			Citations: resp.Citations,
+			Checks:    turn.Expect.Check(answer, len(resp.Citations)),
+		})
+	}
+	return report
+}
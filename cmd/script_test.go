@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+)
+
+func TestScriptCmd(t *testing.T) {
+	var requests []api.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		answer := "Go 1.18 added generics [1]."
+		if len(req.Messages) > 2 {
+			answer = "func Map[T, U any](s []T, f func(T) U) []U"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices:   []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: answer}}},
+			Citations: []string{"https://go.dev/blog/intro-generics"},
+		})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "generics.yaml")
+	data := `name: Generics
+model: sonar-pro
+system: Be brief.
+turns:
+  - prompt: What did Go {version} add?
+    expect:
+      contains: [generics]
+      min_citations: 1
+  - prompt: Show a generic Map function.
+    expect:
+      matches: 'func Map\['
+      not_contains: [iterators]
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp()
+	app.cfg.APIURL = server.URL
+	app.cfg.APIKey = "pplx-" + strings.Repeat("a", 40)
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		requests = nil
+		cmd := app.newScriptCmd()
+		cmd.SetArgs(args)
+		var out strings.Builder
+		cmd.SetOut(&out)
+		var err error
+		captureOutput(func() { err = cmd.Execute() })
+		return out.String(), err
+	}
+
+	out, err := run(path, "--var", "version=1.18")
+	if err != nil {
+		t.Fatalf("script error = %v\n%s", err, out)
+	}
+	if len(requests) != 2 || requests[0].Model != "sonar-pro" || requests[0].Messages[0].Content != "Be brief." {
+		t.Fatalf("requests = %+v, want two turns with the script's model and system prompt", requests)
+	}
+	if got := requests[1].Messages; len(got) != 4 || got[1].Content != "What did Go 1.18 add?" || got[2].Role != "assistant" {
+		t.Errorf("second turn messages = %+v, want the conversation so far", got)
+	}
+	if !strings.Contains(out, "[ok]   contains \"generics\"") || !strings.HasSuffix(out, "Generics: 2 turns, 4 of 4 assertions passed.\n") {
+		t.Errorf("script output = %q, want the passed assertions", out)
+	}
+
+	out, err = run(path, "--var", "version=1.18", "-m", "sonar", "--json")
+	if err != nil || requests[0].Model != "sonar" || !strings.Contains(out, `"passed": 4`) {
+		t.Errorf("script -m sonar --json = %q, %v, want the JSON report with the flag's model", out, err)
+	}
+
+	if _, err := run(path); !errors.As(err, new(*inputError)) {
+		t.Errorf("script with an undefined variable error = %v, want invalid input", err)
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Replace(data, "[iterators]", "[func]", 1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := run(path, "--var", "version=1.18"); err == nil || err.Error() != "1 of 4 assertions failed" || !strings.Contains(out, "[FAIL] does not contain \"func\"") {
+		t.Errorf("script with a failed assertion = %q, %v", out, err)
+	}
+}
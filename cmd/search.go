@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+)
+
+// searchRoles lists the message roles a search can be scoped to
+var searchRoles = []string{"user", "assistant", "system"}
+
+// searchOptions holds the options of /search and history search as given
+type searchOptions struct {
+	keyword []string
+	regex   string
+	role    string
+	model   string
+	since   string
+	tag     string
+}
+
+// query validates the options and returns the search they describe
+func (o searchOptions) query(now time.Time) (history.Query, error) {
+	q := history.Query{
+		Text:  strings.Join(o.keyword, " "),
+		Role:  strings.ToLower(o.role),
+		Model: o.model,
+		Tag:   strings.ToLower(o.tag),
+	}
+	if o.regex != "" {
+		if q.Text != "" {
+			return history.Query{}, fmt.Errorf("give either a keyword or --regex, not both")
+		}
+		q.Text, q.Regex = o.regex, true
+	}
+	if q.Role != "" && !slices.Contains(searchRoles, q.Role) {
+		return history.Query{}, fmt.Errorf("invalid role %q: use %s", o.role, strings.Join(searchRoles, ", "))
+	}
+	if o.since != "" {
+		since, err := parseSince(o.since, now)
+		if err != nil {
+			return history.Query{}, err
+		}
+		q.Since = since
+	}
+	if q.Text == "" && q.Model == "" && q.Since.IsZero() && q.Tag == "" {
+		return history.Query{}, fmt.Errorf("give a keyword, --regex <pattern> or a filter")
+	}
+	return q, nil
+}
+
+// parseSearchArgs parses the arguments of /search: a keyword, or a regular
+// expression given with --regex, and the --role, --model, --since and --tag
+// filters. Quoted arguments may contain spaces.
+func parseSearchArgs(args string, now time.Time) (history.Query, error) {
+	words, err := splitArgs(args)
+	if err != nil {
+		return history.Query{}, err
+	}
+
+	var o searchOptions
+	options := map[string]*string{
+		"--regex": &o.regex,
+		"--role":  &o.role,
+		"--model": &o.model,
+		"--since": &o.since,
+		"--tag":   &o.tag,
+	}
+	for i := 0; i < len(words); i++ {
+		value, ok := options[words[i]]
+		if !ok {
+			o.keyword = append(o.keyword, words[i])
+			continue
+		}
+		if i+1 == len(words) {
+			return history.Query{}, fmt.Errorf("%s needs a value", words[i])
+		}
+		i++
+		*value = words[i]
+	}
+	return o.query(now)
+}
+
+// parseSince parses the --since filter: an age such as 7d, or a date
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if date, err := time.ParseInLocation(time.DateOnly, since, time.Local); err == nil {
+		return date, nil
+	}
+	age, err := config.ParseAge(since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: use an age such as 7d or 12h, or a date such as 2026-01-31", since)
+	}
+	return now.Add(-age), nil
+}
+
+// describeQuery describes what a search looked for, e.g. "containing 'go'"
+func describeQuery(q history.Query) string {
+	description := fmt.Sprintf("containing '%s'", q.Text)
+	if q.Regex {
+		description = fmt.Sprintf("matching /%s/", q.Text)
+	}
+	if q.Text == "" {
+		description = "matching the filters"
+	}
+	if q.Role != "" {
+		description += " in " + q.Role + " messages"
+	}
+	return description
+}
+
+// splitArgs splits command arguments on whitespace. Text in single or double
+// quotes is kept together, without the quotes.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+)
+
+func TestParseSearchArgs(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		args    string
+		want    history.Query
+		wantErr string
+	}{
+		{args: "nil map", want: history.Query{Text: "nil map"}},
+		{args: `--regex "panic: .*"`, want: history.Query{Text: "panic: .*", Regex: true}},
+		{args: "--role User timeout", want: history.Query{Text: "timeout", Role: "user"}},
+		{args: `--regex 'x\d+' --role assistant`, want: history.Query{Text: `x\d+`, Regex: true, Role: "assistant"}},
+		{args: "--role", wantErr: "needs a value"},
+		{args: "--role tool timeout", wantErr: "invalid role"},
+		{args: "--regex a b", wantErr: "not both"},
+		{args: "--role user", wantErr: "give a keyword"},
+		{args: "go --model sonar-pro --since 7d --tag Work", want: history.Query{Text: "go", Model: "sonar-pro", Since: now.Add(-7 * 24 * time.Hour), Tag: "work"}},
+		{args: "--since 2026-01-31", want: history.Query{Since: time.Date(2026, 1, 31, 0, 0, 0, 0, time.Local)}},
+		{args: "--since lately", wantErr: "invalid --since"},
+		{args: `--regex "panic`, wantErr: "unterminated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			got, err := parseSearchArgs(tt.args, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseSearchArgs() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSearchArgs() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseSearchArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+	}{
+		{"", nil},
+		{"  a  b ", []string{"a", "b"}},
+		{`--regex "a b" c`, []string{"--regex", "a b", "c"}},
+		{`'a b'c "say 'hi'"`, []string{"a bc", "say 'hi'"}},
+		{`""`, []string{""}},
+	}
+
+	for _, tt := range tests {
+		got, err := splitArgs(tt.s)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, %v, want %q", tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func TestCmdSearchRegex(t *testing.T) {
+	session := newTestSessionWithHistory(t)
+
+	output := captureOutput(func() {
+		session.cmdSearch([]string{"/search", `--regex "^What" --role user`})
+	})
+	if !strings.Contains(output, "matching /^What/ in user messages") || !strings.Contains(output, "What is Go?") {
+		t.Errorf("output %q should list the matching conversation", output)
+	}
+
+	output = captureOutput(func() {
+		session.cmdSearch([]string{"/search", "--regex ("})
+	})
+	if !strings.Contains(output, "invalid regular expression") {
+		t.Errorf("output %q should report the invalid pattern", output)
+	}
+}
+
+func TestCmdSearchFilters(t *testing.T) {
+	session := newTestSession()
+	session.history = newTestHistory(t, 4)
+	session.history.Conversations[1].Model = "sonar-pro"
+	session.history.Conversations[3].Tags = []string{"work"}
+
+	output := captureOutput(func() {
+		session.cmdSearch([]string{"/search", "go --model sonar-pro"})
+	})
+	if !strings.Contains(output, "sonar-pro (0 messages, 1 match)") || strings.Count(output, "sonar") != 1 {
+		t.Errorf("output %q should list only the sonar-pro conversation", output)
+	}
+
+	output = captureOutput(func() {
+		session.cmdSearch([]string{"/search", "--tag work"})
+	})
+	if !strings.Contains(output, "matching the filters") || !strings.Contains(output, "#work") || strings.Count(output, "sonar") != 1 {
+		t.Errorf("output %q should list only the tagged conversation", output)
+	}
+}
+
+func TestCmdTag(t *testing.T) {
+	session := newTestSession()
+	session.history = newTestHistory(t, 1)
+
+	output := captureOutput(func() {
+		session.cmdTag([]string{"/tag", "work"})
+	})
+	if !strings.Contains(output, "Send a message first") {
+		t.Errorf("output %q should ask for a saved conversation", output)
+	}
+
+	session.conversationID = "id1"
+	output = captureOutput(func() {
+		session.cmdTag([]string{"/tag", "Work #infra work"})
+	})
+	if tags := session.history.Conversations[0].Tags; !slices.Equal(tags, []string{"work", "infra"}) || !strings.Contains(output, "Tags: #work #infra") {
+		t.Errorf("tags = %q, output %q, want work and infra", tags, output)
+	}
+
+	captureOutput(func() {
+		session.cmdTag([]string{"/tag", "-work"})
+	})
+	saved := history.NewHistory()
+	if err := saved.Load(); err != nil || !slices.Equal(saved.Conversations[0].Tags, []string{"infra"}) {
+		t.Errorf("saved tags = %q, err %v, want infra", saved.Conversations[0].Tags, err)
+	}
+}
+
+func TestHistorySearchCmd(t *testing.T) {
+	hist := newTestHistory(t, 3)
+	if err := hist.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewApp().newHistoryCmd()
+	cmd.SetArgs([]string{"search", "about", "--since", "1h"})
+	var err error
+	output := captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(output, "Conversations containing 'about'") || !strings.Contains(output, "Question about Go") {
+		t.Errorf("output %q should list the matching conversation", output)
+	}
+
+	cmd = NewApp().newHistoryCmd()
+	cmd.SetArgs([]string{"search"})
+	captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if exitCode(err) != ExitInvalidInput {
+		t.Errorf("Execute() error = %v, want invalid input", err)
+	}
+}
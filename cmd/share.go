@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/export"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+	"github.com/quocvuong92/perplexity-cli/internal/share"
+)
+
+// uploader returns the service conversations are shared to
+func (app *App) uploader() (share.Uploader, error) {
+	cfg := app.cfg.Share
+	if cfg.PasteURL != "" {
+		return share.NewPaste(cfg.PasteURL, cfg.PasteField), nil
+	}
+	token := cfg.GistToken
+	if token == "" {
+		token = os.Getenv(share.EnvGitHubToken)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("nowhere to share to: set share.gist_token (or %s) for GitHub gists, or share.paste_url for a paste service", share.EnvGitHubToken)
+	}
+	return share.NewGist(token, cfg.Public), nil
+}
+
+// shareConversation uploads a conversation as markdown and returns its URL.
+// With ask set, the upload only happens after confirmation.
+func (app *App) shareConversation(conv *history.ConversationEntry, ask bool) (string, error) {
+	uploader, err := app.uploader()
+	if err != nil {
+		return "", err
+	}
+	if ask && !confirm(fmt.Sprintf("Upload the conversation to %s?", uploader.Name())) {
+		return "", nil
+	}
+
+	filename := fmt.Sprintf("conversation-%s.md", conv.UpdatedAt.Format("2006-01-02-150405"))
+	return uploader.Upload(context.Background(), filename, export.Markdown(conv))
+}
+
+// cmdShare uploads the current conversation, or the one with the given
+// /history index, and prints its URL
+func (s *InteractiveSession) cmdShare(parts []string) bool {
+	conv := s.currentConversation()
+	if len(parts) > 1 && strings.TrimSpace(parts[1]) != "" {
+		if s.history == nil {
+			fmt.Println("History not available.")
+			return false
+		}
+		ref := strings.TrimSpace(parts[1])
+		if conv = findConversation(s.history, ref); conv == nil {
+			display.ShowError(fmt.Sprintf("Invalid conversation index: %s", ref))
+			return false
+		}
+	}
+	if len(conv.Messages) <= 1 {
+		fmt.Println("No conversation to share.")
+		return false
+	}
+
+	url, err := s.app.shareConversation(conv, true)
+	switch {
+	case err != nil:
+		display.ShowError(err.Error())
+	case url == "":
+		fmt.Println("Nothing shared.")
+	default:
+		fmt.Printf("Shared at %s\n", url)
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/share"
+)
+
+// newPasteServer returns a paste service answering with a fixed URL, and
+// the last document posted to it
+func newPasteServer(t *testing.T) (*httptest.Server, *string) {
+	t.Helper()
+	posted := new(string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		*posted = string(data)
+		w.Write([]byte("https://paste.example.com/abc\n"))
+	}))
+	t.Cleanup(server.Close)
+	return server, posted
+}
+
+func TestCmdShare(t *testing.T) {
+	server, posted := newPasteServer(t)
+	session := newTestSessionWithHistory(t)
+	session.app.cfg.Share.PasteURL = server.URL
+	session.messages = append(session.messages,
+		api.Message{Role: "user", Content: "What is Go?"},
+		api.Message{Role: "assistant", Content: "A language."},
+	)
+
+	stubReadLine(t, "n")
+	output := captureOutput(func() {
+		session.cmdShare([]string{"/share"})
+	})
+	if !strings.Contains(output, "Nothing shared") || *posted != "" {
+		t.Errorf("declined share uploaded %q: %q", *posted, output)
+	}
+
+	stubReadLine(t, "y")
+	output = captureOutput(func() {
+		session.cmdShare([]string{"/share"})
+	})
+	if !strings.Contains(output, "Shared at https://paste.example.com/abc") {
+		t.Errorf("output %q should show the URL", output)
+	}
+	if !strings.Contains(*posted, "## You\n\nWhat is Go?") || strings.Contains(*posted, config.DefaultSystemMessage) {
+		t.Errorf("posted %q should be the conversation without its system prompt", *posted)
+	}
+
+	// A conversation from /history
+	stubReadLine(t, "y")
+	captureOutput(func() {
+		session.cmdShare([]string{"/share", "2"})
+	})
+	if !strings.Contains(*posted, "Go is a programming language") || !strings.Contains(*posted, "**Model:** sonar\n") {
+		t.Errorf("posted %q should be the second stored conversation", *posted)
+	}
+}
+
+func TestHistoryShareCmd(t *testing.T) {
+	server, posted := newPasteServer(t)
+	hist := newTestHistory(t, 2)
+	if err := hist.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp()
+	app.cfg.Share.PasteURL = server.URL
+	cmd := app.newHistoryCmd()
+	cmd.SetArgs([]string{"share", "id2", "--yes"})
+	var err error
+	output := captureOutput(func() {
+		err = cmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if strings.TrimSpace(output) != "https://paste.example.com/abc" || !strings.Contains(*posted, "Question about Go") {
+		t.Errorf("output %q, posted %q, want the URL of the second conversation", output, *posted)
+	}
+}
+
+func TestUploader(t *testing.T) {
+	t.Setenv(share.EnvGitHubToken, "")
+	app := NewApp()
+	if _, err := app.uploader(); err == nil || !strings.Contains(err.Error(), "gist_token") {
+		t.Errorf("uploader() error = %v, want a configuration hint", err)
+	}
+
+	t.Setenv(share.EnvGitHubToken, "token")
+	if u, err := app.uploader(); err != nil || u.Name() != "a secret GitHub gist" {
+		t.Errorf("uploader() = %v, %v, want a secret gist", u, err)
+	}
+
+	app.cfg.Share.PasteURL = "https://paste.rs/"
+	if u, err := app.uploader(); err != nil || u.Name() != "https://paste.rs/" {
+		t.Errorf("uploader() = %v, %v, want the paste service", u, err)
+	}
+}
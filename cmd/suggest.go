@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxCommandSuggestions limits the commands suggested for an unknown one
+const maxCommandSuggestions = 3
+
+// unknownCommand reports an unknown slash command and suggests the closest
+// ones. A single suggestion can be run in its place after confirmation.
+func (s *InteractiveSession) unknownCommand(cmd string, parts []string) bool {
+	fmt.Printf("Unknown command: %s\n", cmd)
+
+	var names []string
+	for _, suggestion := range s.commandSuggestions() {
+		names = append(names, suggestion.Text)
+	}
+	matches := closestCommands(cmd, names)
+
+	switch len(matches) {
+	case 0:
+		fmt.Println("Type /help for available commands")
+	case 1:
+		if confirm(fmt.Sprintf("Did you mean %s?", matches[0])) {
+			parts[0] = matches[0]
+			return s.handleCommand(strings.Join(parts, " "))
+		}
+	default:
+		fmt.Printf("Did you mean %s?\n", strings.Join(matches, " or "))
+	}
+	return false
+}
+
+// closestCommands returns the commands nearest to an unknown one: those it
+// starts, or failing that those within a few typos of it. Single-letter
+// aliases are left out, as nearly any short typo is close to one of them.
+func closestCommands(cmd string, commands []string) []string {
+	var prefixed []string
+	best := maxTypos(cmd) + 1
+	var nearest []string
+	for _, command := range commands {
+		if len(command) <= 2 || slices.Contains(prefixed, command) || slices.Contains(nearest, command) {
+			continue
+		}
+		if strings.HasPrefix(command, cmd) {
+			prefixed = append(prefixed, command)
+			continue
+		}
+		switch d := levenshtein(cmd, command); {
+		case d < best:
+			best = d
+			nearest = []string{command}
+		case d == best:
+			nearest = append(nearest, command)
+		}
+	}
+
+	matches := nearest
+	if len(prefixed) > 0 {
+		matches = prefixed
+	}
+	if len(matches) > maxCommandSuggestions {
+		matches = matches[:maxCommandSuggestions]
+	}
+	return matches
+}
+
+// maxTypos returns how many edits away a command may be to be suggested
+func maxTypos(cmd string) int {
+	if len(cmd) <= 4 {
+		return 1
+	}
+	return 2
+}
+
+// levenshtein returns the number of single-character insertions, deletions
+// and substitutions needed to turn a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"export", "export", 0},
+		{"expor", "export", 1},
+		{"hlep", "help", 2},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"héllo", "hello", 1},
+	}
+
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosestCommands(t *testing.T) {
+	commands := []string{"/model", "/export", "/exit", "/help", "/history", "/retry", "/resume", "/reasoning", "/q", "/c"}
+
+	tests := []struct {
+		cmd  string
+		want []string
+	}{
+		{"/expor", []string{"/export"}},
+		{"/hlep", []string{"/help"}},
+		{"/modle", []string{"/model"}},
+		{"/re", []string{"/retry", "/resume", "/reasoning"}},
+		{"/hist", []string{"/history"}},
+		{"/x", nil},
+		{"/unknown", nil},
+	}
+
+	for _, tt := range tests {
+		if got := closestCommands(tt.cmd, commands); !slices.Equal(got, tt.want) {
+			t.Errorf("closestCommands(%q) = %q, want %q", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	t.Run("corrected", func(t *testing.T) {
+		session := newTestSession()
+		stubReadLine(t, "y")
+
+		var exit bool
+		output := captureOutput(func() {
+			exit = session.handleCommand("/exti")
+		})
+		if !exit {
+			t.Errorf("handleCommand() = false, want /exit to run; output %q", output)
+		}
+	})
+
+	t.Run("declined", func(t *testing.T) {
+		session := newTestSession()
+		stubReadLine(t, "n")
+
+		var exit bool
+		output := captureOutput(func() {
+			exit = session.handleCommand("/exti")
+		})
+		if exit || !strings.Contains(output, "Unknown command: /exti") {
+			t.Errorf("handleCommand() = %v, output %q, want the command not run", exit, output)
+		}
+	})
+
+	t.Run("several matches", func(t *testing.T) {
+		session := newTestSession()
+		output := captureOutput(func() {
+			session.handleCommand("/re")
+		})
+		if !strings.Contains(output, "Did you mean /reasoning or /retry or /resume?") {
+			t.Errorf("output %q should list the matches", output)
+		}
+	})
+}
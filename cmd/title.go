@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/logging"
+)
+
+const (
+	// titleModel is the cheap model generating conversation titles
+	titleModel = "sonar"
+	// titleTimeout limits the request for a title
+	titleTimeout = 15 * time.Second
+	// maxTitleWords limits the length of a generated title
+	maxTitleWords = 5
+	// maxTitleInput limits the characters of the question and answer sent to
+	// generate a title
+	maxTitleInput = 2000
+)
+
+// titleSaveWait limits how long saving the history waits for a title being
+// generated. A title arriving later is stored with the next save. It is a
+// variable so tests can shorten it.
+var titleSaveWait = 2 * time.Second
+
+// titlePrompt asks for a title of the conversation that follows
+const titlePrompt = "Reply with only a title of at most 5 words for the conversation below, " +
+	"without quotes, markdown or a final period."
+
+// startTitle generates a title for the current conversation in the
+// background after its first exchange, if enabled. The title is stored with
+// the conversation when the history is saved.
+func (s *InteractiveSession) startTitle() {
+	if !s.app.cfg.AutoTitle {
+		return
+	}
+	messages := s.getMessages()
+	if len(messages) != 3 || messages[1].Role != "user" || messages[2].Role != "assistant" {
+		return
+	}
+
+	id := s.conversationID
+	question, answer := messages[1].Content, messages[2].Content
+	client := s.app.newTitleClient()
+	s.titles.Add(1)
+	go func() {
+		defer s.titles.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), titleTimeout)
+		defer cancel()
+
+		title, err := generateTitle(ctx, client, question, answer)
+		if err != nil || title == "" {
+			logging.Debug("Failed to generate a conversation title", logging.Err(err))
+			return
+		}
+		s.titlesMu.Lock()
+		if s.generatedTitles == nil {
+			s.generatedTitles = make(map[string]string)
+		}
+		s.generatedTitles[id] = title
+		s.titlesMu.Unlock()
+	}()
+}
+
+// waitForTitles waits up to timeout for the titles being generated
+func (s *InteractiveSession) waitForTitles(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		s.titles.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// generatedTitle returns the title generated for a conversation, "" if none
+func (s *InteractiveSession) generatedTitle(id string) string {
+	s.titlesMu.Lock()
+	defer s.titlesMu.Unlock()
+	return s.generatedTitles[id]
+}
+
+// newTitleClient creates a client querying titleModel. Title requests run in
+// the background, so it has none of the notices of newClient.
+func (app *App) newTitleClient() *api.Client {
+	cfg := *app.cfg
+	cfg.Model = titleModel
+	cfg.RelatedQuestions = false
+	cfg.Images = false
+	client := api.NewClient(&cfg)
+	client.SetRetryConfig(cfg.Retry)
+	return client
+}
+
+// generateTitle asks for a short title of a question and its answer
+func generateTitle(ctx context.Context, client *api.Client, question, answer string) (string, error) {
+	resp, err := client.QueryWithHistoryContext(ctx, []api.Message{
+		{Role: "system", Content: titlePrompt},
+		{Role: "user", Content: "Question: " + truncateRunes(question, maxTitleInput) +
+			"\n\nAnswer: " + truncateRunes(answer, maxTitleInput)},
+	})
+	if err != nil {
+		return "", err
+	}
+	_, content := display.SplitThinking(resp.GetContent())
+	return cleanTitle(display.StripCitationMarkers(content)), nil
+}
+
+// cleanTitle keeps the first line of a generated title, without markdown,
+// quotes or final punctuation, and at most maxTitleWords words
+func cleanTitle(title string) string {
+	const decoration = " \t\"'`*#_.:“”"
+	line, _, _ := strings.Cut(strings.TrimSpace(title), "\n")
+	line = strings.TrimPrefix(strings.Trim(line, decoration), "Title:")
+	words := strings.Fields(strings.Trim(line, decoration))
+	if len(words) > maxTitleWords {
+		words = words[:maxTitleWords]
+	}
+	return strings.TrimRight(strings.Join(words, " "), ".,;:")
+}
+
+// truncateRunes shortens s to at most n runes
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
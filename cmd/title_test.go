@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+)
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Go Concurrency Basics", "Go Concurrency Basics"},
+		{"  \"Go Concurrency Basics.\"\n", "Go Concurrency Basics"},
+		{"**Title: Kubernetes Pod Restarts**", "Kubernetes Pod Restarts"},
+		{"Retry Backoff In Go Clients Explained", "Retry Backoff In Go Clients"},
+		{"Rust Lifetimes,\nsecond line", "Rust Lifetimes"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := cleanTitle(tt.title); got != tt.want {
+				t.Errorf("cleanTitle(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartTitle(t *testing.T) {
+	t.Setenv(history.EnvHistoryPath, filepath.Join(t.TempDir(), "history.json"))
+
+	var sent api.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "\"Goroutines Versus Threads.\""}}},
+		})
+	}))
+	defer server.Close()
+
+	session := newTestSession()
+	session.history = history.NewHistory()
+	session.conversationID = "conv"
+	session.app.cfg.APIURL = server.URL
+	session.app.cfg.APIKey = "test-key"
+	session.app.cfg.Model = "sonar-pro"
+	session.setMessages([]api.Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "How do goroutines differ from threads?"},
+		{Role: "assistant", Content: "They are scheduled by the Go runtime."},
+	})
+
+	session.startTitle()
+	session.titles.Wait()
+	if got := session.generatedTitle("conv"); got != "" {
+		t.Errorf("generatedTitle() = %q with auto titles disabled, want none", got)
+	}
+
+	session.app.cfg.AutoTitle = true
+	session.startTitle()
+	session.titles.Wait()
+	if sent.Model != titleModel || len(sent.Messages) != 2 || sent.Messages[0].Content != titlePrompt {
+		t.Errorf("title request = %+v, want the title prompt sent to %s", sent, titleModel)
+	}
+	if got := session.currentConversation().Title(); got != "Goroutines Versus Threads" {
+		t.Errorf("current conversation title = %q, want the generated title", got)
+	}
+
+	session.saveHistory()
+	saved := history.NewHistory()
+	if err := saved.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if conv := saved.GetConversation("conv"); conv == nil || conv.Title() != "Goroutines Versus Threads" {
+		t.Errorf("saved conversation = %+v, want the generated title", conv)
+	}
+
+	// Only the first exchange is titled
+	session.conversationID = "next"
+	session.appendMessage(api.Message{Role: "user", Content: "And channels?"})
+	session.appendMessage(api.Message{Role: "assistant", Content: "They connect goroutines."})
+	session.startTitle()
+	session.titles.Wait()
+	if got := session.generatedTitle("next"); got != "" {
+		t.Errorf("generatedTitle() = %q after a later exchange, want none", got)
+	}
+}
+
+func TestSaveHistoryDoesNotWaitForSlowTitle(t *testing.T) {
+	t.Setenv(history.EnvHistoryPath, filepath.Join(t.TempDir(), "history.json"))
+	old := titleSaveWait
+	t.Cleanup(func() { titleSaveWait = old })
+	titleSaveWait = 10 * time.Millisecond
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "Slow Title"}}},
+		})
+	}))
+	defer server.Close()
+
+	session := newTestSession()
+	session.history = history.NewHistory()
+	session.conversationID = "conv"
+	session.app.cfg.APIURL = server.URL
+	session.app.cfg.APIKey = "test-key"
+	session.app.cfg.AutoTitle = true
+	session.setMessages([]api.Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "How do goroutines differ from threads?"},
+		{Role: "assistant", Content: "They are scheduled by the Go runtime."},
+	})
+	session.startTitle()
+
+	start := time.Now()
+	session.saveHistory()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("saveHistory() took %v waiting for the title", elapsed)
+	}
+	if conv := session.history.GetConversation("conv"); conv == nil || conv.GeneratedTitle != "" {
+		t.Errorf("saved conversation = %+v, want it saved without a title", conv)
+	}
+
+	// The title arriving later is stored with the next save
+	close(release)
+	session.titles.Wait()
+	session.saveHistory()
+	if conv := session.history.GetConversation("conv"); conv == nil || conv.GeneratedTitle != "Slow Title" {
+		t.Errorf("saved conversation = %+v, want the late title", conv)
+	}
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/pricing"
+	"github.com/quocvuong92/perplexity-cli/internal/tokens"
+)
+
+// newTokensCmd creates the tokens subcommand for estimating prompt sizes
+func (app *App) newTokensCmd() *cobra.Command {
+	var model string
+
+	tokensCmd := &cobra.Command{
+		Use:   "tokens [text]",
+		Short: "Estimate the tokens and input cost of a text",
+		Long: `Estimate how many tokens a text uses and what it costs as a prompt, without
+sending it. The text is read from the argument or piped stdin. Counts are
+estimates and usually within 10-15% of what the API reports.
+
+  perplexity tokens "What is quantum computing?"
+  cat report.md | perplexity tokens -m sonar-pro`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var text string
+			if len(args) > 0 {
+				text = args[0]
+			} else {
+				data, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return fmt.Errorf("failed to read from stdin: %w", err)
+				}
+				text = string(data)
+			}
+			if strings.TrimSpace(text) == "" {
+				return invalidInput(fmt.Errorf("no text given"))
+			}
+
+			app.loadModels(false)
+			model = app.cfg.ResolveModel(model)
+			if model == config.AutoModel {
+				model, _ = app.cfg.SelectModel(text)
+			}
+			showTokenEstimate(cmd.OutOrStdout(), tokens.Estimate(text), model)
+			return nil
+		},
+	}
+
+	tokensCmd.Flags().StringVarP(&model, "model", "m", app.cfg.Model, "Model whose price the cost is estimated with")
+	return tokensCmd
+}
+
+// showEstimate prints the estimated prompt tokens and input cost of a query
+// instead of sending it
+func (app *App) showEstimate(query string) {
+	model := app.cfg.Model
+	if model == config.AutoModel {
+		model, _ = app.cfg.SelectModel(query)
+	}
+	showTokenEstimate(os.Stdout, estimateMessages(app.queryMessages(query)), model)
+}
+
+// estimateMessages returns the estimated prompt tokens of a request
+func estimateMessages(messages []api.Message) int {
+	contents := make([]string, len(messages))
+	for i, msg := range messages {
+		contents[i] = msg.Content
+	}
+	return tokens.EstimateMessages(contents)
+}
+
+// showTokenEstimate prints a token count and, if the model's price is known, its input cost
+func showTokenEstimate(w io.Writer, count int, model string) {
+	fmt.Fprintf(w, "Tokens: ~%d\n", count)
+	if cost, ok := pricing.Estimate(model, count, 0); ok {
+		fmt.Fprintf(w, "Input cost: ~%s with %s (excluding output and search fees)\n", pricing.Format(cost), model)
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/tokens"
+)
+
+func TestTokensCmd(t *testing.T) {
+	t.Run("argument", func(t *testing.T) {
+		app := NewApp()
+		cmd := app.newTokensCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs([]string{"-m", "sonar", "What is Go?"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if !strings.Contains(out.String(), "Tokens: ~4") {
+			t.Errorf("output %q should contain the token count", out.String())
+		}
+		if !strings.Contains(out.String(), "with sonar") {
+			t.Errorf("output %q should contain the input cost", out.String())
+		}
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		app := NewApp()
+		cmd := app.newTokensCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetIn(strings.NewReader("internationalization"))
+		cmd.SetArgs([]string{})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if !strings.Contains(out.String(), "Tokens: ~5") {
+			t.Errorf("output %q should contain the token count", out.String())
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		app := NewApp()
+		cmd := app.newTokensCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetIn(strings.NewReader("  \n"))
+		cmd.SetArgs([]string{})
+		if err := cmd.Execute(); exitCode(err) != ExitInvalidInput {
+			t.Errorf("Execute() error = %v, want invalid input", err)
+		}
+	})
+}
+
+func TestShowTokenEstimateUnknownModel(t *testing.T) {
+	var out bytes.Buffer
+	showTokenEstimate(&out, 42, "sonar-ultra")
+	if out.String() != "Tokens: ~42\n" {
+		t.Errorf("output = %q, want only the token count for a model without a price", out.String())
+	}
+}
+
+func TestShowEstimate(t *testing.T) {
+	app := NewApp()
+	app.cfg.Model = "sonar-pro"
+
+	output := captureStdoutOnly(func() {
+		app.showEstimate("What is Go?")
+	})
+
+	want := tokens.EstimateMessages([]string{app.cfg.GetSystemPrompt(), "What is Go?"})
+	if !strings.Contains(output, fmt.Sprintf("Tokens: ~%d", want)) {
+		t.Errorf("showEstimate() output %q should count the system prompt and query (%d tokens)", output, want)
+	}
+}
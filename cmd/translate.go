@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+)
+
+// translatePrompt asks for a translation into the language given by %s
+const translatePrompt = `You are a professional translator. Translate the text the user sends into %s.
+Keep its meaning, tone and formatting, including markdown, code, URLs and names that
+should not be translated. Reply with the translation only, without notes, quotes,
+explanations or sources. If the text is already in %[1]s, return it unchanged.`
+
+// language is a target language of translations
+type language struct {
+	Code string
+	Name string
+}
+
+// languages lists the target languages of translations by ISO 639-1 code
+var languages = []language{
+	{"ar", "Arabic"},
+	{"bn", "Bengali"},
+	{"cs", "Czech"},
+	{"da", "Danish"},
+	{"de", "German"},
+	{"el", "Greek"},
+	{"en", "English"},
+	{"es", "Spanish"},
+	{"fa", "Persian"},
+	{"fi", "Finnish"},
+	{"fr", "French"},
+	{"he", "Hebrew"},
+	{"hi", "Hindi"},
+	{"hu", "Hungarian"},
+	{"id", "Indonesian"},
+	{"it", "Italian"},
+	{"ja", "Japanese"},
+	{"ko", "Korean"},
+	{"ms", "Malay"},
+	{"nl", "Dutch"},
+	{"no", "Norwegian"},
+	{"pl", "Polish"},
+	{"pt", "Portuguese"},
+	{"ro", "Romanian"},
+	{"ru", "Russian"},
+	{"sv", "Swedish"},
+	{"th", "Thai"},
+	{"tl", "Filipino"},
+	{"tr", "Turkish"},
+	{"uk", "Ukrainian"},
+	{"vi", "Vietnamese"},
+	{"zh", "Chinese (Simplified)"},
+	{"zh-tw", "Chinese (Traditional)"},
+}
+
+// resolveLanguage finds a target language by code or English name, ignoring case
+func resolveLanguage(s string) (language, error) {
+	s = strings.TrimSpace(s)
+	for _, lang := range languages {
+		if strings.EqualFold(s, lang.Code) || strings.EqualFold(s, lang.Name) {
+			return lang, nil
+		}
+	}
+	return language{}, fmt.Errorf("unknown language: %s. Available languages: %s", s, languageCodes())
+}
+
+// languageCodes returns the codes of the target languages as a comma-separated list
+func languageCodes() string {
+	codes := make([]string, len(languages))
+	for i, lang := range languages {
+		codes[i] = lang.Code
+	}
+	return strings.Join(codes, ", ")
+}
+
+// newTranslateCmd creates the translate subcommand for translating text
+func (app *App) newTranslateCmd() *cobra.Command {
+	var (
+		target string
+		model  string
+	)
+
+	translateCmd := &cobra.Command{
+		Use:   "translate --to <language> [text]",
+		Short: "Translate text into another language",
+		Long: `Translate text into the target language, given by its code or English name.
+The text is read from the argument or piped stdin, and only the translation is
+printed, so the output can be piped or redirected.
+
+  perplexity translate --to fr "Where is the train station?"
+  cat README.md | perplexity translate --to ja > README.ja.md`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lang, err := resolveLanguage(target)
+			if err != nil {
+				return invalidInput(err)
+			}
+
+			var text string
+			if len(args) > 0 {
+				text = args[0]
+			} else {
+				stdin := cmd.InOrStdin()
+				if f, ok := stdin.(*os.File); ok {
+					if stat, err := f.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+						return invalidInput(fmt.Errorf("give the text to translate as an argument or pipe it to stdin"))
+					}
+				}
+				data, err := io.ReadAll(stdin)
+				if err != nil {
+					return fmt.Errorf("failed to read from stdin: %w", err)
+				}
+				text = string(data)
+			}
+			if strings.TrimSpace(text) == "" {
+				return invalidInput(fmt.Errorf("no text given"))
+			}
+
+			cfg, err := app.taskConfig(model, text)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := newSignalContext()
+			defer cancel()
+
+			translation, err := translateText(ctx, app.newClientFor(cfg), text, lang)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), translation)
+			return nil
+		},
+	}
+
+	translateCmd.Flags().StringVar(&target, "to", "", "Target language code or name, e.g. fr or French")
+	translateCmd.Flags().StringVarP(&model, "model", "m", app.cfg.Model, "Model translating the text")
+	_ = translateCmd.MarkFlagRequired("to")
+	_ = translateCmd.RegisterFlagCompletionFunc("to", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		completions := make([]string, len(languages))
+		for i, lang := range languages {
+			completions[i] = lang.Code + "\t" + lang.Name
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	})
+	return translateCmd
+}
+
+// translateText translates text into a language, returning the translation
+// without thinking or citation markers
+func translateText(ctx context.Context, client *api.Client, text string, lang language) (string, error) {
+	resp, err := queryWithSpinner(ctx, client, "Translating...", []api.Message{
+		{Role: "system", Content: fmt.Sprintf(translatePrompt, lang.Name)},
+		{Role: "user", Content: text},
+	})
+	if err != nil {
+		return "", err
+	}
+	_, translation := display.SplitThinking(resp.GetContent())
+	return strings.TrimSpace(display.StripCitationMarkers(translation)), nil
+}
+
+// cmdTranslate translates a text, or the last response when none is given.
+// The conversation is left unchanged.
+func (s *InteractiveSession) cmdTranslate(parts []string) bool {
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		fmt.Println("Usage: /translate <language> [text]")
+		return false
+	}
+
+	args := strings.SplitN(strings.TrimSpace(parts[1]), " ", 2)
+	lang, err := resolveLanguage(args[0])
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	text := s.lastResponse
+	if len(args) > 1 && strings.TrimSpace(args[1]) != "" {
+		text = args[1]
+	} else if text == "" {
+		fmt.Println("No response to translate. Usage: /translate <language> [text]")
+		return false
+	}
+
+	ctx := s.interruptCtx.Start()
+	defer s.interruptCtx.Stop()
+
+	translation, err := translateText(ctx, s.client, text, lang)
+	if err != nil {
+		// The error was shown by queryWithSpinner
+		return false
+	}
+	fmt.Println()
+	display.ShowContent(translation)
+	return false
+}
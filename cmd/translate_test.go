@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+func TestResolveLanguage(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"fr", "French"},
+		{"FR", "French"},
+		{" german ", "German"},
+		{"zh-TW", "Chinese (Traditional)"},
+	}
+	for _, tt := range tests {
+		lang, err := resolveLanguage(tt.input)
+		if err != nil || lang.Name != tt.want {
+			t.Errorf("resolveLanguage(%q) = %+v, %v, want %s", tt.input, lang, err, tt.want)
+		}
+	}
+	if _, err := resolveLanguage("klingon"); err == nil || !strings.Contains(err.Error(), "Available languages: ar,") {
+		t.Errorf("resolveLanguage(klingon) error = %v, want the available languages", err)
+	}
+}
+
+// createTranslateServer returns a mock API server answering every request
+// with a translation, recording the last request
+func createTranslateServer(t *testing.T, sent *api.ChatRequest) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(sent)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "<think>easy</think>\nOù est la gare ? [1]\n"}}},
+		})
+	}))
+}
+
+func TestTranslateText(t *testing.T) {
+	var sent api.ChatRequest
+	server := createTranslateServer(t, &sent)
+	defer server.Close()
+
+	app := &App{cfg: &config.Config{APIKey: "test-key", APIURL: server.URL, Model: "sonar"}}
+	var got string
+	var err error
+	captureOutput(func() {
+		got, err = translateText(context.Background(), app.newClient(), "Where is the train station?", language{"fr", "French"})
+	})
+	if err != nil {
+		t.Fatalf("translateText() error = %v", err)
+	}
+	if got != "Où est la gare ?" {
+		t.Errorf("translateText() = %q, want the translation without thinking or markers", got)
+	}
+	if len(sent.Messages) != 2 || !strings.Contains(sent.Messages[0].Content, "into French.") || sent.Messages[1].Content != "Where is the train station?" {
+		t.Errorf("request messages = %+v, want the text with the French prompt", sent.Messages)
+	}
+}
+
+func TestTranslateCmd(t *testing.T) {
+	var sent api.ChatRequest
+	server := createTranslateServer(t, &sent)
+	defer server.Close()
+
+	app := NewApp()
+	app.cfg.APIURL = server.URL
+	app.cfg.APIKey = "pplx-" + strings.Repeat("a", 40)
+	app.cfg.Model = "sonar"
+
+	cmd := app.newTranslateCmd()
+	cmd.SetIn(strings.NewReader("Where is the train station?\n"))
+	cmd.SetArgs([]string{"--to", "French"})
+	var out strings.Builder
+	cmd.SetOut(&out)
+	var err error
+	captureOutput(func() { err = cmd.Execute() })
+	if err != nil {
+		t.Fatalf("translate error = %v", err)
+	}
+	if out.String() != "Où est la gare ?\n" {
+		t.Errorf("translate output = %q, want only the translation", out.String())
+	}
+
+	cmd = app.newTranslateCmd()
+	cmd.SetArgs([]string{"--to", "klingon", "hello"})
+	captureOutput(func() { err = cmd.Execute() })
+	var inputErr *inputError
+	if !errors.As(err, &inputErr) {
+		t.Errorf("translate to an unknown language error = %v, want invalid input", err)
+	}
+}
+
+func TestCmdTranslate(t *testing.T) {
+	var sent api.ChatRequest
+	server := createTranslateServer(t, &sent)
+	defer server.Close()
+
+	session := newTestSession()
+	session.app.cfg.APIURL = server.URL
+	session.app.cfg.APIKey = "test-key"
+	session.client = session.app.newClient()
+	session.interruptCtx = NewInterruptibleContext()
+
+	output := captureOutput(func() { session.cmdTranslate([]string{"/translate", "fr"}) })
+	if !strings.Contains(output, "No response to translate") {
+		t.Errorf("expected no response note, got %q", output)
+	}
+
+	output = captureOutput(func() { session.cmdTranslate([]string{"/translate", "xx hello"}) })
+	if !strings.Contains(output, "unknown language: xx") {
+		t.Errorf("expected unknown language error, got %q", output)
+	}
+
+	session.lastResponse = "Where is the train station?"
+	before := session.getMessageCount()
+	output = captureOutput(func() { session.cmdTranslate([]string{"/translate", "fr"}) })
+	if !strings.Contains(output, "Où est la gare ?") || sent.Messages[1].Content != "Where is the train station?" {
+		t.Errorf("expected the last response translated, got %q", output)
+	}
+	if session.getMessageCount() != before {
+		t.Error("translation should leave the conversation unchanged")
+	}
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/logging"
+	"github.com/quocvuong92/perplexity-cli/internal/webpage"
+)
+
+const (
+	// maxPageChars limits the characters of page text sent for a summary,
+	// leaving room for the prompt within the prompt length limit
+	maxPageChars = 60000
+	// fetchTimeout limits downloading a page
+	fetchTimeout = 30 * time.Second
+)
+
+// urlPrompt asks for a summary of the page text that follows
+const urlPrompt = `You summarize web pages. Use only the page text given below, not other sources,
+even if you could search for more. Start with a one-sentence overview, then give the key
+points as a markdown list. Cite the page as [1] after each point it supports, and quote it
+briefly where the exact wording matters. If the text is cut off, summarize what is there.`
+
+// newURLCmd creates the url subcommand for summarizing a web page
+func (app *App) newURLCmd() *cobra.Command {
+	var model string
+
+	urlCmd := &cobra.Command{
+		Use:   "url <link>",
+		Short: "Summarize a web page",
+		Long: `Fetch a web page, extract its readable text and ask for a summary of it with
+citations. Unlike asking about the page in a query, where the model searches the
+web, this guarantees the summary is of the exact page given.
+
+Pages longer than 60000 characters are truncated.
+
+  perplexity url https://go.dev/blog/go1.24
+  perplexity url -r -m sonar-pro https://example.com/article`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := newSignalContext()
+			defer cancel()
+
+			page, err := fetchPage(ctx, args[0])
+			if err != nil {
+				return err
+			}
+
+			cfg, err := app.taskConfig(model, page.Text)
+			if err != nil {
+				return err
+			}
+			if app.cfg.Render {
+				if err := display.InitRenderer(app.cfg.Theme); err != nil {
+					logging.Warn("Failed to initialize renderer", logging.Err(err))
+				}
+			}
+
+			if !app.cfg.Quiet && utf8.RuneCountInString(page.Text) > maxPageChars {
+				fmt.Fprintf(os.Stderr, "Note: The page is long; only its first %d characters are summarized\n", maxPageChars)
+			}
+			resp, err := summarizePage(ctx, app.newClientFor(cfg), page)
+			if err != nil {
+				return err
+			}
+			app.cfg.Citations = true
+			app.showResponse(resp)
+			return nil
+		},
+	}
+
+	urlCmd.Flags().StringVarP(&model, "model", "m", app.cfg.Model, "Model summarizing the page")
+	urlCmd.Flags().BoolVarP(&app.cfg.Render, "render", "r", app.cfg.Render, "Render markdown with colors and formatting")
+	return urlCmd
+}
+
+// fetchPage downloads a page with a spinner and checks it has readable text
+func fetchPage(ctx context.Context, link string) (*webpage.Page, error) {
+	sp := display.NewSpinner("Fetching page...")
+	sp.Start()
+	fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	page, err := webpage.Fetch(fetchCtx, http.DefaultClient, link)
+	cancel()
+	sp.Stop()
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, invalidInput(err)
+	}
+	if page.Text == "" {
+		return nil, invalidInput(fmt.Errorf("no readable text found on %s", page.URL))
+	}
+	return page, nil
+}
+
+// summarizePage asks for a summary of a page, truncating long pages. The
+// response cites the page itself, not search results.
+func summarizePage(ctx context.Context, client *api.Client, page *webpage.Page) (*api.ChatResponse, error) {
+	text := page.Text
+	if truncated := truncateRunes(text, maxPageChars); truncated != text {
+		text = truncated + "\n[page truncated]"
+	}
+
+	content := "URL: " + page.URL + "\n"
+	if page.Title != "" {
+		content += "Title: " + page.Title + "\n"
+	}
+	resp, err := queryWithSpinner(ctx, client, "Summarizing...", []api.Message{
+		{Role: "system", Content: urlPrompt},
+		{Role: "user", Content: content + "\n" + text},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Citations = []string{page.URL}
+	return resp, nil
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/webpage"
+)
+
+func TestFetchPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/empty" {
+			w.Write([]byte("<html><body><nav>Menu</nav></body></html>"))
+			return
+		}
+		w.Write([]byte("<html><head><title>Post</title></head><body><p>Readable text.</p></body></html>"))
+	}))
+	defer server.Close()
+
+	var page *webpage.Page
+	var err, emptyErr error
+	captureOutput(func() {
+		page, err = fetchPage(context.Background(), server.URL+"/post")
+		_, emptyErr = fetchPage(context.Background(), server.URL+"/empty")
+	})
+	if err != nil {
+		t.Fatalf("fetchPage() error = %v", err)
+	}
+	if page.Title != "Post" || page.Text != "Readable text." {
+		t.Errorf("fetchPage() = %+v", page)
+	}
+	var inputErr *inputError
+	if !errors.As(emptyErr, &inputErr) {
+		t.Errorf("fetchPage() of a page without text error = %v, want invalid input", emptyErr)
+	}
+}
+
+func TestSummarizePage(t *testing.T) {
+	var sent api.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices:   []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: "A post [1]."}}},
+			Citations: []string{"https://search.example/result"},
+		})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", APIURL: server.URL, Model: "sonar"}
+	app := &App{cfg: cfg}
+	page := &webpage.Page{URL: "https://example.com/post", Title: "Post", Text: strings.Repeat("x", maxPageChars+1)}
+
+	var resp *api.ChatResponse
+	var err error
+	captureOutput(func() {
+		resp, err = summarizePage(context.Background(), app.newClient(), page)
+	})
+	if err != nil {
+		t.Fatalf("summarizePage() error = %v", err)
+	}
+	if !slices.Equal(resp.Citations, []string{"https://example.com/post"}) {
+		t.Errorf("citations = %q, want only the page", resp.Citations)
+	}
+	user := sent.Messages[1].Content
+	if sent.Messages[0].Content != urlPrompt || !strings.HasPrefix(user, "URL: https://example.com/post\nTitle: Post\n") ||
+		!strings.HasSuffix(user, "[page truncated]") {
+		t.Errorf("request messages = %+v, want the page truncated after its URL and title", sent.Messages)
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/watch"
+)
+
+// validateWatch checks the --watch and --watch-changes flags
+func (app *App) validateWatch() error {
+	if app.watch == 0 {
+		if app.watchChanges {
+			return fmt.Errorf("--watch-changes requires --watch")
+		}
+		return nil
+	}
+	if app.watch < watch.MinInterval {
+		return fmt.Errorf("watch interval must be at least %v, got %v", watch.MinInterval, app.watch)
+	}
+	if app.compare != "" || app.extractCode {
+		return fmt.Errorf("--watch cannot be combined with --compare or --extract-code")
+	}
+	return nil
+}
+
+// runWatch re-runs the query at the watch interval until interrupted. With
+// --watch-changes, an answer is only shown when it differs materially from
+// the previous one.
+func (app *App) runWatch(ctx context.Context, query string) {
+	var previous string
+	for {
+		if app.watchChanges {
+			previous = app.watchOnce(ctx, query, previous)
+		} else {
+			fmt.Printf("## %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+			// A failed run is reported and retried at the next interval
+			if app.cfg.Stream {
+				_ = app.runStream(ctx, query)
+			} else {
+				_ = app.runNormal(ctx, query)
+			}
+			fmt.Println()
+		}
+
+		next := time.Now().Add(app.watch)
+		if !app.cfg.Quiet {
+			fmt.Fprintf(os.Stderr, "Note: Next run at %s (Ctrl+C to stop)\n", next.Format("15:04:05"))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(app.watch):
+		}
+	}
+}
+
+// watchOnce runs the query and shows the answer if it changed materially
+// from previous. Returns the answer to compare the next run against.
+func (app *App) watchOnce(ctx context.Context, query, previous string) string {
+	resp, err := app.queryNormal(ctx, query)
+	if err != nil {
+		return previous
+	}
+
+	_, answer := app.splitContent(resp.GetContent(), resp.Citations)
+	now := time.Now().Format("2006-01-02 15:04:05")
+	if previous != "" && !watch.Changed(previous, answer) {
+		if !app.cfg.Quiet {
+			fmt.Fprintf(os.Stderr, "Note: No material change at %s\n", now)
+		}
+		return previous
+	}
+
+	if previous != "" && !app.cfg.Quiet {
+		// Ring the terminal bell so a changed answer is noticed
+		fmt.Fprintln(os.Stderr, "\aNote: The answer has changed")
+	}
+	fmt.Printf("## %s\n\n", now)
+	app.showResponse(resp)
+	fmt.Println()
+	return answer
+}
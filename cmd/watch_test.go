@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+func TestValidateWatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		app     App
+		wantErr bool
+	}{
+		{"not watching", App{}, false},
+		{"valid interval", App{watch: time.Hour, watchChanges: true}, false},
+		{"interval too short", App{watch: time.Second}, true},
+		{"changes without watch", App{watchChanges: true}, true},
+		{"with compare", App{watch: time.Hour, compare: "sonar,sonar-pro"}, true},
+		{"with extract code", App{watch: time.Hour, extractCode: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.app.validateWatch()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateWatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWatchOnce(t *testing.T) {
+	answers := []string{
+		"Go 1.24 is the latest release[1].",
+		"Go 1.24 is the latest release [2].",
+		"Go 1.25 shipped today with a new garbage collector.",
+	}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&api.ChatResponse{
+			Choices: []api.StreamChoice{{Message: api.Message{Role: "assistant", Content: answers[calls]}}},
+		})
+		calls++
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIKey: "test-key", Model: "sonar"}
+	app := &App{cfg: cfg, watch: time.Hour, watchChanges: true}
+	app.client = api.NewClient(cfg)
+	app.client.SetBaseURL(server.URL)
+
+	var previous string
+	wantShown := []bool{true, false, true}
+	for i, shown := range wantShown {
+		output := captureStdoutOnly(func() {
+			previous = app.watchOnce(context.Background(), "latest Go release", previous)
+		})
+		if got := strings.Contains(output, "Go 1.2"); got != shown {
+			t.Errorf("run %d shown = %v, want %v (output %q)", i+1, got, shown, output)
+		}
+	}
+	if !strings.Contains(previous, "Go 1.25") {
+		t.Errorf("previous = %q, want the last changed answer", previous)
+	}
+}
@@ -0,0 +1,168 @@
+// Package aliases provides user-defined query aliases, which turn common
+// query shapes into one-word commands.
+package aliases
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+const (
+	// AliasesFileName is the name of the aliases file
+	AliasesFileName = "aliases.json"
+	// EnvAliasesPath is the environment variable for a custom aliases path
+	EnvAliasesPath = "PERPLEXITY_ALIASES_PATH"
+	// Placeholder is replaced with the user input when an alias is expanded
+	Placeholder = "{input}"
+)
+
+// ErrInvalidName is returned when an alias name is not a single word
+var ErrInvalidName = errors.New("alias name must be a single word of letters, digits, '-' or '_'")
+
+// Store manages alias persistence
+type Store struct {
+	Aliases map[string]string `json:"aliases"`
+	path    string
+}
+
+// NewStore creates a new alias store
+func NewStore() *Store {
+	return &Store{
+		Aliases: make(map[string]string),
+		path:    getAliasesPath(),
+	}
+}
+
+// getAliasesPath returns the path to the aliases file
+func getAliasesPath() string {
+	if customPath := os.Getenv(EnvAliasesPath); customPath != "" {
+		return customPath
+	}
+	dir := config.GetConfigDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, AliasesFileName)
+}
+
+// Path returns the path of the aliases file (empty if unavailable)
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Load reads the aliases from disk
+func (s *Store) Load() error {
+	if s.path == "" {
+		return fmt.Errorf("aliases path not available")
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read aliases: %w", err)
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return fmt.Errorf("failed to parse aliases: %w", err)
+	}
+	if s.Aliases == nil {
+		s.Aliases = make(map[string]string)
+	}
+
+	return nil
+}
+
+// Save writes the aliases to disk
+func (s *Store) Save() error {
+	if s.path == "" {
+		return fmt.Errorf("aliases path not available")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create aliases directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal aliases: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write aliases: %w", err)
+	}
+
+	return nil
+}
+
+// Add creates or replaces an alias
+func (s *Store) Add(name, template string) error {
+	if !isValidName(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("alias template cannot be empty")
+	}
+	s.Aliases[name] = template
+	return nil
+}
+
+// Remove deletes an alias. Returns false if it does not exist.
+func (s *Store) Remove(name string) bool {
+	if _, ok := s.Aliases[name]; !ok {
+		return false
+	}
+	delete(s.Aliases, name)
+	return true
+}
+
+// Get returns the template for an alias
+func (s *Store) Get(name string) (string, bool) {
+	template, ok := s.Aliases[name]
+	return template, ok
+}
+
+// Names returns the sorted alias names
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.Aliases))
+	for name := range s.Aliases {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Expand substitutes the input into the template. Templates without
+// a placeholder get the input appended after a blank line.
+func Expand(template, input string) string {
+	input = strings.TrimSpace(input)
+	if strings.Contains(template, Placeholder) {
+		return strings.ReplaceAll(template, Placeholder, input)
+	}
+	if input == "" {
+		return template
+	}
+	return template + "\n\n" + input
+}
+
+// isValidName checks that an alias name is a single word
+func isValidName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package aliases
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestStore(t *testing.T) *Store {
+	s := NewStore()
+	s.path = filepath.Join(t.TempDir(), AliasesFileName)
+	return s
+}
+
+func TestAddAndGet(t *testing.T) {
+	s := newTestStore(t)
+
+	if err := s.Add("wtf", "explain this error: {input}"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	template, ok := s.Get("wtf")
+	if !ok || template != "explain this error: {input}" {
+		t.Errorf("Get() = %q, %v", template, ok)
+	}
+
+	invalid := []string{"", "two words", "semi;colon", "path/like"}
+	for _, name := range invalid {
+		if err := s.Add(name, "template"); err == nil {
+			t.Errorf("Add(%q) should fail", name)
+		}
+	}
+	if err := s.Add("empty", "  "); err == nil {
+		t.Error("Add() should reject empty template")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	s := newTestStore(t)
+	s.Add("tldr", "summarize: {input}")
+
+	if !s.Remove("tldr") {
+		t.Error("Remove() should return true for existing alias")
+	}
+	if s.Remove("tldr") {
+		t.Error("Remove() should return false for missing alias")
+	}
+}
+
+func TestNames(t *testing.T) {
+	s := newTestStore(t)
+	s.Add("zeta", "z")
+	s.Add("alpha", "a")
+
+	names := s.Names()
+	if len(names) != 2 || names[0] != "alpha" || names[1] != "zeta" {
+		t.Errorf("Names() = %v, want sorted names", names)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	s := newTestStore(t)
+	s.Add("wtf", "explain this error: {input}")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		t.Fatalf("aliases file not created: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("aliases file permissions = %v, want 0600", info.Mode().Perm())
+	}
+
+	loaded := NewStore()
+	loaded.path = s.path
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, ok := loaded.Get("wtf"); !ok {
+		t.Error("Loaded store should contain saved alias")
+	}
+
+	missing := NewStore()
+	missing.path = filepath.Join(t.TempDir(), "missing.json")
+	if err := missing.Load(); err != nil {
+		t.Errorf("Load() of missing file error = %v, want nil", err)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		input    string
+		want     string
+	}{
+		{"placeholder", "explain this error: {input}", "segfault", "explain this error: segfault"},
+		{"repeated placeholder", "{input} vs {input}", "go", "go vs go"},
+		{"no placeholder", "summarize the following", "some text", "summarize the following\n\nsome text"},
+		{"no placeholder no input", "latest tech news", "", "latest tech news"},
+		{"input trimmed", "q: {input}", "  spaced \n", "q: spaced"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Expand(tt.template, tt.input); got != tt.want {
+				t.Errorf("Expand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
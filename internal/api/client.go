@@ -3,37 +3,93 @@ package api
 import (
 	"bufio"
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/logging"
 	"github.com/quocvuong92/perplexity-cli/internal/ratelimit"
 	"github.com/quocvuong92/perplexity-cli/internal/retry"
+	"github.com/quocvuong92/perplexity-cli/internal/validation"
 )
 
 // Message represents a chat message
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content,omitempty"`
+	// Citations of an assistant message, kept for history and exports;
+	// never sent to the API
+	Citations []string `json:"-"`
+	// Time the message was sent or received, kept for history; never sent
+	// to the API
+	Time time.Time `json:"-"`
+	// Status of an assistant message that is not a complete answer, as
+	// stored in the history; never sent to the API
+	Status string `json:"-"`
 }
 
 // ChatRequest represents the API request payload
 type ChatRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
-	Stream   bool      `json:"stream,omitempty"`
+	Model                  string            `json:"model"`
+	Messages               []Message         `json:"messages"`
+	Stream                 bool              `json:"stream,omitempty"`
+	Temperature            *float64          `json:"temperature,omitempty"`
+	FrequencyPenalty       *float64          `json:"frequency_penalty,omitempty"`
+	PresencePenalty        *float64          `json:"presence_penalty,omitempty"`
+	TopK                   int               `json:"top_k,omitempty"`
+	ReasoningEffort        string            `json:"reasoning_effort,omitempty"`
+	ReturnRelatedQuestions bool              `json:"return_related_questions,omitempty"`
+	ReturnImages           bool              `json:"return_images,omitempty"`
+	ImageDomainFilter      []string          `json:"image_domain_filter,omitempty"`
+	ImageFormatFilter      []string          `json:"image_format_filter,omitempty"`
+	SearchRecencyFilter    string            `json:"search_recency_filter,omitempty"`
+	WebSearchOptions       *WebSearchOptions `json:"web_search_options,omitempty"`
+}
+
+// WebSearchOptions controls the web search done for a query
+type WebSearchOptions struct {
+	SearchContextSize string        `json:"search_context_size,omitempty"`
+	UserLocation      *UserLocation `json:"user_location,omitempty"`
+}
+
+// UserLocation makes search results relevant to where the user is
+type UserLocation struct {
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Country   string   `json:"country,omitempty"` // ISO 3166-1 alpha-2 code
 }
 
 // Usage represents token usage statistics
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens      int    `json:"prompt_tokens"`
+	CompletionTokens  int    `json:"completion_tokens"`
+	TotalTokens       int    `json:"total_tokens"`
+	CitationTokens    int    `json:"citation_tokens,omitempty"`     // Tokens of the cited sources, on some models
+	ReasoningTokens   int    `json:"reasoning_tokens,omitempty"`    // Reasoning of deep research
+	NumSearchQueries  int    `json:"num_search_queries,omitempty"`  // Web searches run for the response
+	SearchContextSize string `json:"search_context_size,omitempty"` // Search depth used: low, medium or high
+	Cost              *Cost  `json:"cost,omitempty"`                // Reported by the API, nil if not
+}
+
+// Cost is the cost of a request in US dollars, as reported by the API
+type Cost struct {
+	InputTokensCost     float64 `json:"input_tokens_cost"`
+	OutputTokensCost    float64 `json:"output_tokens_cost"`
+	CitationTokensCost  float64 `json:"citation_tokens_cost,omitempty"`
+	ReasoningTokensCost float64 `json:"reasoning_tokens_cost,omitempty"`
+	SearchQueriesCost   float64 `json:"search_queries_cost,omitempty"`
+	RequestCost         float64 `json:"request_cost,omitempty"` // Per-request search fee
+	TotalCost           float64 `json:"total_cost"`
 }
 
 // Delta represents streaming delta content
@@ -51,9 +107,25 @@ type StreamChoice struct {
 
 // ChatResponse represents the API response
 type ChatResponse struct {
-	Choices   []StreamChoice `json:"choices"`
-	Usage     Usage          `json:"usage"`
-	Citations []string       `json:"citations"`
+	ID               string         `json:"id,omitempty"`      // Response ID, for support requests
+	Created          int64          `json:"created,omitempty"` // Unix time the response was created
+	Model            string         `json:"model,omitempty"`   // Model that answered
+	Choices          []StreamChoice `json:"choices"`
+	Usage            Usage          `json:"usage"`
+	Citations        []string       `json:"citations"`
+	RelatedQuestions []string       `json:"related_questions,omitempty"`
+	Images           []Image        `json:"images,omitempty"`
+	// KeyIndex is the 1-based index of the API key that served the
+	// response, among the configured keys
+	KeyIndex int `json:"-"`
+}
+
+// Image is an image returned with a response
+type Image struct {
+	ImageURL  string `json:"image_url"`
+	OriginURL string `json:"origin_url,omitempty"` // Page the image was found on
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
 }
 
 // ErrorResponse represents an API error
@@ -74,22 +146,43 @@ func (e *APIError) Error() string {
 	return e.Message
 }
 
-// Client is the Perplexity API client
-type Client struct {
-	httpClient    *http.Client
-	config        *config.Config
-	retryConfig   retry.Config
-	rateLimiter   *ratelimit.Limiter
-	onKeyRotation func(fromIndex, toIndex int, totalKeys int) // Callback when key is rotated
-	onRetry       func(info retry.RetryInfo)                  // Callback when retrying
+// HTTPStatus returns the status code, so transient server errors are retried
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
 }
 
-// NewClient creates a new API client
+// PreQueryHook can inspect or rewrite the messages before a request is sent.
+// Returning an error aborts the request.
+type PreQueryHook func(ctx context.Context, messages []Message) ([]Message, error)
+
+// PostResponseHook is called with the full response content after a request completes.
+// resp may be nil for streaming responses that carried no final metadata.
+type PostResponseHook func(ctx context.Context, messages []Message, content string, resp *ChatResponse)
+
+// Client is the Perplexity API client
+type Client struct {
+	httpClient      *http.Client
+	config          *config.Config
+	retryConfig     retry.Config
+	rateLimiter     *ratelimit.Limiter
+	onKeyRotation   func(fromIndex, toIndex int, totalKeys int) // Callback when key is rotated
+	onKeySuccess    func()                                      // Callback when a request succeeds with one of several keys
+	onKeyQuota      func(index, remaining int)                  // Callback when the requests left to one of several keys are known
+	onRetry         func(info retry.RetryInfo)                  // Callback when retrying
+	onModelSelected func(model, reason string)                  // Callback when the auto model picks a model
+	onContextWarn   func(message string)                        // Callback when a request nears the context window
+	onSearches      func(queries int)                           // Callback when a streamed chunk reports the searches so far
+	onPreQuery      PreQueryHook                                // Hook before each query
+	onPostResponse  PostResponseHook                            // Hook after each successful query
+	dump            *Dump                                       // Records requests and raw responses (nil = off)
+	rawOutput       io.Writer                                   // Receives the raw response bodies (nil = off)
+}
+
+// NewClient creates a new API client. The configured timeout bounds each query
+// as a whole, including retries and the full stream, rather than each request.
 func NewClient(cfg *config.Config) *Client {
 	return &Client{
-		httpClient: &http.Client{
-			Timeout: cfg.Timeout,
-		},
+		httpClient:  &http.Client{},
 		config:      cfg,
 		retryConfig: retry.DefaultConfig(),
 		rateLimiter: ratelimit.NewLimiter(cfg.RateLimit),
@@ -101,16 +194,69 @@ func (c *Client) SetKeyRotationCallback(callback func(fromIndex, toIndex int, to
 	c.onKeyRotation = callback
 }
 
+// SetKeySuccessCallback sets a callback function to be called when a request
+// succeeds with one of several keys, with the config's current key
+func (c *Client) SetKeySuccessCallback(callback func()) {
+	c.onKeySuccess = callback
+}
+
+// SetKeyQuotaCallback sets a callback function to be called with the
+// requests one of several keys has left, by its index in the configured keys:
+// as reported by the rate limit headers of a response, or 0 when the key
+// failed and was rotated away from
+func (c *Client) SetKeyQuotaCallback(callback func(index, remaining int)) {
+	c.onKeyQuota = callback
+}
+
 // SetRetryCallback sets a callback function to be called before each retry attempt
 func (c *Client) SetRetryCallback(callback func(info retry.RetryInfo)) {
 	c.onRetry = callback
 }
 
+// SetModelSelectedCallback sets a callback function to be called when the auto model picks a model
+func (c *Client) SetModelSelectedCallback(callback func(model, reason string)) {
+	c.onModelSelected = callback
+}
+
+// SetContextWarningCallback sets a callback function to be called when a request
+// is close to the model's context window
+func (c *Client) SetContextWarningCallback(callback func(message string)) {
+	c.onContextWarn = callback
+}
+
+// SetSearchCallback sets a callback function to be called with the number of
+// searches run so far whenever a streamed chunk reports it
+func (c *Client) SetSearchCallback(callback func(queries int)) {
+	c.onSearches = callback
+}
+
+// SetPreQueryHook sets a hook called with the messages before each query
+func (c *Client) SetPreQueryHook(hook PreQueryHook) {
+	c.onPreQuery = hook
+}
+
+// SetPostResponseHook sets a hook called with the response after each successful query
+func (c *Client) SetPostResponseHook(hook PostResponseHook) {
+	c.onPostResponse = hook
+}
+
 // SetRetryConfig sets the retry configuration
 func (c *Client) SetRetryConfig(cfg retry.Config) {
 	c.retryConfig = cfg
 }
 
+// SetDump records every request and raw response to d
+func (c *Client) SetDump(d *Dump) {
+	c.dump = d
+}
+
+// SetRawOutput writes the body of each successful response to w exactly as
+// received: the JSON of a response, or the SSE frames of a stream as they
+// arrive
+func (c *Client) SetRawOutput(w io.Writer) {
+	c.rawOutput = w
+}
+
 // SetBaseURL sets the API URL (useful for testing with mock servers)
 func (c *Client) SetBaseURL(url string) {
 	c.config.APIURL = url
@@ -134,6 +280,38 @@ func (c *Client) shouldRotateKey(statusCode int, errorMsg string) bool {
 	return false
 }
 
+// CurrentKey returns the 1-based index of the API key the next request is
+// sent with, and the number of keys configured
+func (c *Client) CurrentKey() (index, count int) {
+	return c.config.CurrentKeyIndex + 1, c.config.GetKeyCount()
+}
+
+// keySucceeded ends the rotation after a request succeeded with the current
+// key, and reports the key
+func (c *Client) keySucceeded() {
+	c.config.ResetKeyRotation()
+	if c.onKeySuccess != nil {
+		c.onKeySuccess()
+	}
+}
+
+// remainingHeaders are the response headers reporting the requests a key has left
+var remainingHeaders = []string{"X-RateLimit-Remaining-Requests", "X-RateLimit-Remaining"}
+
+// reportQuota reports the requests the current key has left, if the
+// response headers tell and there are several keys
+func (c *Client) reportQuota(header http.Header) {
+	if c.onKeyQuota == nil || c.config.GetKeyCount() <= 1 {
+		return
+	}
+	for _, name := range remainingHeaders {
+		if remaining, err := strconv.Atoi(strings.TrimSpace(header.Get(name))); err == nil {
+			c.onKeyQuota(c.config.CurrentKeyIndex, remaining)
+			return
+		}
+	}
+}
+
 // rotateKey attempts to switch to the next available API key
 func (c *Client) rotateKey() error {
 	oldIndex := c.config.CurrentKeyIndex
@@ -141,6 +319,16 @@ func (c *Client) rotateKey() error {
 	if err != nil {
 		return err
 	}
+	logging.Debug("Rotated API key",
+		logging.Int("from", oldIndex+1),
+		logging.Int("to", c.config.CurrentKeyIndex+1),
+		logging.Int("keys", c.config.GetKeyCount()),
+	)
+
+	// The failed key is not preferred until it reports requests left again
+	if c.onKeyQuota != nil {
+		c.onKeyQuota(oldIndex, 0)
+	}
 
 	// Call the rotation callback if set
 	if c.onKeyRotation != nil {
@@ -157,48 +345,7 @@ func (c *Client) Query(message string) (*ChatResponse, error) {
 
 // QueryContext sends a query to the Perplexity API with context support (non-streaming)
 func (c *Client) QueryContext(ctx context.Context, message string) (*ChatResponse, error) {
-	return c.queryWithRetry(ctx, message)
-}
-
-// queryWithRetry performs the query with automatic key rotation on failure
-func (c *Client) queryWithRetry(ctx context.Context, message string) (*ChatResponse, error) {
-	// If only one key, no retry needed
-	if c.config.GetKeyCount() <= 1 {
-		return c.doQuery(ctx, message)
-	}
-
-	for {
-		resp, err := c.doQuery(ctx, message)
-		if err == nil {
-			c.config.ResetKeyRotation()
-			return resp, nil
-		}
-
-		// Check if context was cancelled
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
-		}
-
-		// Check if we should rotate keys
-		apiErr, ok := err.(*APIError)
-		if !ok || !c.shouldRotateKey(apiErr.StatusCode, apiErr.Message) {
-			return nil, err
-		}
-
-		// Try to rotate to next key
-		if rotateErr := c.rotateKey(); rotateErr != nil {
-			return nil, fmt.Errorf("%v (no more API keys available)", err)
-		}
-	}
-}
-
-// doQuery performs a single query attempt
-func (c *Client) doQuery(ctx context.Context, message string) (*ChatResponse, error) {
-	messages := []Message{
-		{Role: "system", Content: config.DefaultSystemMessage},
-		{Role: "user", Content: message},
-	}
-	return c.doQueryWithHistory(ctx, messages)
+	return c.QueryWithHistoryContext(ctx, c.singleMessage(message))
 }
 
 // QueryStream sends a streaming query to the Perplexity API
@@ -208,52 +355,82 @@ func (c *Client) QueryStream(message string, onChunk func(content string), onDon
 
 // QueryStreamContext sends a streaming query to the Perplexity API with context support
 func (c *Client) QueryStreamContext(ctx context.Context, message string, onChunk func(content string), onDone func(resp *ChatResponse)) error {
-	return c.queryStreamWithRetry(ctx, message, onChunk, onDone)
+	return c.QueryStreamWithHistoryContext(ctx, c.singleMessage(message), onChunk, onDone)
 }
 
-// queryStreamWithRetry performs the streaming query with automatic key rotation on failure
-// Note: Key rotation only happens before streaming starts (on HTTP errors).
-// Once streaming begins successfully, mid-stream errors are not retried to avoid duplicate content.
-func (c *Client) queryStreamWithRetry(ctx context.Context, message string, onChunk func(content string), onDone func(resp *ChatResponse)) error {
-	// If only one key, no retry needed
-	if c.config.GetKeyCount() <= 1 {
-		return c.doQueryStream(ctx, message, onChunk, onDone)
+// singleMessage builds the message list for a one-shot query
+func (c *Client) singleMessage(message string) []Message {
+	return []Message{
+		{Role: "system", Content: c.config.GetSystemPrompt()},
+		{Role: "user", Content: message},
 	}
+}
 
-	for {
-		err := c.doQueryStream(ctx, message, onChunk, onDone)
-		if err == nil {
-			c.config.ResetKeyRotation()
-			return nil
-		}
+// newRequest builds the request payload for model from the current configuration
+func (c *Client) newRequest(messages []Message, model string, stream bool) ChatRequest {
+	req := ChatRequest{
+		Model:                  model,
+		Messages:               messages,
+		Stream:                 stream,
+		Temperature:            c.config.Temperature,
+		FrequencyPenalty:       c.config.FrequencyPenalty,
+		PresencePenalty:        c.config.PresencePenalty,
+		TopK:                   c.config.TopK,
+		ReturnRelatedQuestions: c.config.RelatedQuestions,
+		ReturnImages:           c.config.Images,
+		ImageDomainFilter:      c.config.ImageDomains,
+		ImageFormatFilter:      c.config.ImageFormats,
+		SearchRecencyFilter:    c.config.SearchRecency,
+	}
+	// Other models reject the reasoning effort field
+	if config.IsReasoningModel(req.Model) {
+		req.ReasoningEffort = c.config.ReasoningEffort
+	}
+	opts := WebSearchOptions{
+		SearchContextSize: c.config.SearchContext,
+		UserLocation:      c.userLocation(),
+	}
+	if opts != (WebSearchOptions{}) {
+		req.WebSearchOptions = &opts
+	}
+	return req
+}
 
-		// Check if context was cancelled
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
+// userLocation returns the configured location, or nil if none is set
+func (c *Client) userLocation() *UserLocation {
+	lat, lon, hasCoordinates := c.config.Coordinates()
+	if !hasCoordinates && c.config.Country == "" {
+		return nil
+	}
+	location := &UserLocation{Country: c.config.Country}
+	if hasCoordinates {
+		location.Latitude = &lat
+		location.Longitude = &lon
+	}
+	return location
+}
 
-		// Check if we should rotate keys
-		// Only APIError (HTTP status errors) trigger rotation
-		// Mid-stream errors (io errors, parse errors) don't trigger rotation
-		apiErr, ok := err.(*APIError)
-		if !ok || !c.shouldRotateKey(apiErr.StatusCode, apiErr.Message) {
-			return err
-		}
+// selectModel returns the configured model, or for the auto model the one
+// chosen for the last user message. It is called once per query, so that
+// retries with another key keep the model and its note is shown once.
+func (c *Client) selectModel(messages []Message) string {
+	if c.config.Model != config.AutoModel {
+		return c.config.Model
+	}
 
-		// Try to rotate to next key
-		if rotateErr := c.rotateKey(); rotateErr != nil {
-			return fmt.Errorf("%v (no more API keys available)", err)
+	var prompt string
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			prompt = messages[i].Content
+			break
 		}
 	}
-}
 
-// doQueryStream performs a single streaming query attempt
-func (c *Client) doQueryStream(ctx context.Context, message string, onChunk func(content string), onDone func(resp *ChatResponse)) error {
-	messages := []Message{
-		{Role: "system", Content: config.DefaultSystemMessage},
-		{Role: "user", Content: message},
+	model, reason := c.config.SelectModel(prompt)
+	if c.onModelSelected != nil {
+		c.onModelSelected(model, reason)
 	}
-	return c.doQueryStreamWithHistory(ctx, messages, onChunk, onDone)
+	return model
 }
 
 // GetContent extracts the content from the response
@@ -267,13 +444,32 @@ func (r *ChatResponse) GetContent() string {
 	return ""
 }
 
-// GetUsageMap returns usage as a map for display
+// FinishReason returns why the model stopped, such as "stop" or "length"
+func (r *ChatResponse) FinishReason() string {
+	if len(r.Choices) > 0 {
+		return r.Choices[0].FinishReason
+	}
+	return ""
+}
+
+// GetUsageMap returns usage as a map for display. The counts only some
+// models report are included when set.
 func (r *ChatResponse) GetUsageMap() map[string]int {
-	return map[string]int{
+	usage := map[string]int{
 		"prompt_tokens":     r.Usage.PromptTokens,
 		"completion_tokens": r.Usage.CompletionTokens,
 		"total_tokens":      r.Usage.TotalTokens,
 	}
+	for key, n := range map[string]int{
+		"citation_tokens":    r.Usage.CitationTokens,
+		"reasoning_tokens":   r.Usage.ReasoningTokens,
+		"num_search_queries": r.Usage.NumSearchQueries,
+	} {
+		if n > 0 {
+			usage[key] = n
+		}
+	}
+	return usage
 }
 
 // QueryWithHistory sends a query with message history (for interactive mode)
@@ -283,18 +479,107 @@ func (c *Client) QueryWithHistory(messages []Message) (*ChatResponse, error) {
 
 // QueryWithHistoryContext sends a query with message history and context support
 func (c *Client) QueryWithHistoryContext(ctx context.Context, messages []Message) (*ChatResponse, error) {
-	return c.queryWithHistoryRetry(ctx, messages)
+	messages, err := c.preQuery(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	model := c.selectModel(messages)
+	if err := c.checkContextSize(messages, model); err != nil {
+		return nil, err
+	}
+
+	timeoutCtx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.queryWithHistoryRetry(timeoutCtx, messages, model)
+	if err != nil {
+		return nil, c.timeoutError(timeoutCtx, err)
+	}
+
+	if c.onPostResponse != nil {
+		c.onPostResponse(ctx, messages, resp.GetContent(), resp)
+	}
+	return resp, nil
+}
+
+// checkContextSize rejects requests that likely exceed the context window
+// of model before they are sent, and warns about requests close to it
+func (c *Client) checkContextSize(messages []Message, model string) error {
+	contents := make([]string, len(messages))
+	for i, msg := range messages {
+		contents[i] = msg.Content
+	}
+
+	result := validation.ValidateContext(model, contents)
+	if !result.Valid {
+		return result.Error
+	}
+	if result.Warning != "" && c.onContextWarn != nil {
+		c.onContextWarn(result.Warning)
+	}
+	return nil
+}
+
+// requestLogger returns a logger that tags each line with the request ID and
+// the request's model, and with the API key it is sent with when there are several
+func (c *Client) requestLogger(id string, req ChatRequest) *slog.Logger {
+	attrs := []any{
+		logging.String("request_id", id),
+		logging.String("model", req.Model),
+		logging.Bool("stream", req.Stream),
+	}
+	if index, count := c.CurrentKey(); count > 1 {
+		attrs = append(attrs, logging.Int("key", index), logging.Int("keys", count))
+	}
+	log := logging.With(attrs...)
+	log.Debug("Sending request", logging.Int("messages", len(req.Messages)))
+	return log
+}
+
+// logResponseMetadata logs the metadata Perplexity support asks for to
+// identify a response: its ID, model, creation time and finish reason
+func logResponseMetadata(log *slog.Logger, id, model string, created int64, finishReason string) {
+	attrs := []any{logging.String("response_id", id), logging.String("response_model", model)}
+	if created > 0 {
+		attrs = append(attrs, logging.String("created", time.Unix(created, 0).UTC().Format(time.RFC3339)))
+	}
+	log.Debug("Response metadata", append(attrs, logging.String("finish_reason", finishReason))...)
+}
+
+// withTimeout returns a context that expires after the configured timeout.
+// A zero timeout means no limit.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.config.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.config.Timeout)
 }
 
-func (c *Client) queryWithHistoryRetry(ctx context.Context, messages []Message) (*ChatResponse, error) {
+// timeoutError reports err as a timeout if the query's deadline has passed
+func (c *Client) timeoutError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("request timeout after %v exceeded: %w", c.config.Timeout, context.DeadlineExceeded)
+	}
+	return err
+}
+
+// preQuery runs the pre-query hook, if set, returning the messages to send
+func (c *Client) preQuery(ctx context.Context, messages []Message) ([]Message, error) {
+	if c.onPreQuery == nil {
+		return messages, nil
+	}
+	return c.onPreQuery(ctx, messages)
+}
+
+func (c *Client) queryWithHistoryRetry(ctx context.Context, messages []Message, model string) (*ChatResponse, error) {
 	if c.config.GetKeyCount() <= 1 {
-		return c.doQueryWithHistory(ctx, messages)
+		return c.doQueryWithHistory(ctx, messages, model)
 	}
 
 	for {
-		resp, err := c.doQueryWithHistory(ctx, messages)
+		resp, err := c.doQueryWithHistory(ctx, messages, model)
 		if err == nil {
-			c.config.ResetKeyRotation()
+			c.keySucceeded()
 			return resp, nil
 		}
 
@@ -309,28 +594,30 @@ func (c *Client) queryWithHistoryRetry(ctx context.Context, messages []Message)
 		}
 
 		if rotateErr := c.rotateKey(); rotateErr != nil {
-			return nil, fmt.Errorf("%v (no more API keys available)", err)
+			return nil, fmt.Errorf("%w (no more API keys available)", err)
 		}
 	}
 }
 
-func (c *Client) doQueryWithHistory(ctx context.Context, messages []Message) (*ChatResponse, error) {
+func (c *Client) doQueryWithHistory(ctx context.Context, messages []Message, model string) (*ChatResponse, error) {
 	if err := c.rateLimiter.Wait(ctx); err != nil {
 		return nil, err
 	}
 
-	reqBody := ChatRequest{
-		Model:    c.config.Model,
-		Messages: messages,
-		Stream:   false,
-	}
+	reqBody := c.newRequest(messages, model, false)
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	id := logging.NewRequestID()
+	log := c.requestLogger(id, reqBody)
+	dump := c.dump.begin(id)
+	defer dump.end()
+	start := time.Now()
 	var chatResp *ChatResponse
+	var rawBody []byte
 
 	err = retry.Do(ctx, c.retryConfig, func() error {
 		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.APIURL, bytes.NewBuffer(jsonData))
@@ -341,17 +628,23 @@ func (c *Client) doQueryWithHistory(ctx context.Context, messages []Message) (*C
 		req.Header.Set("Accept", "application/json")
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+		dump.request(req, jsonData)
 
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
+			dump.fail(err)
 			return fmt.Errorf("failed to send request: %w", err)
 		}
 		defer func() { _ = resp.Body.Close() }()
+		log.Debug("Received response", logging.Int("status", resp.StatusCode), logging.Duration("elapsed", time.Since(start)))
+		c.reportQuota(resp.Header)
 
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
+			dump.fail(err)
 			return fmt.Errorf("failed to read response: %w", err)
 		}
+		dump.response(resp, body)
 
 		if resp.StatusCode != http.StatusOK {
 			var errResp ErrorResponse
@@ -371,13 +664,28 @@ func (c *Client) doQueryWithHistory(ctx context.Context, messages []Message) (*C
 		}
 
 		chatResp = &parsed
+		chatResp.KeyIndex = c.config.CurrentKeyIndex + 1
+		rawBody = body
 		return nil
 	}, c.onRetry)
 
 	if err != nil {
+		log.Warn("Request failed", logging.Err(err), logging.Duration("elapsed", time.Since(start)))
 		return nil, err
 	}
 
+	log.Info("Request completed",
+		logging.Duration("elapsed", time.Since(start)),
+		logging.Int("prompt_tokens", chatResp.Usage.PromptTokens),
+		logging.Int("completion_tokens", chatResp.Usage.CompletionTokens),
+	)
+	logResponseMetadata(log, chatResp.ID, chatResp.Model, chatResp.Created, chatResp.FinishReason())
+
+	if c.rawOutput != nil {
+		if _, err := c.rawOutput.Write(rawBody); err != nil {
+			return nil, fmt.Errorf("failed to write raw response: %w", err)
+		}
+	}
 	return chatResp, nil
 }
 
@@ -388,18 +696,55 @@ func (c *Client) QueryStreamWithHistory(messages []Message, onChunk func(content
 
 // QueryStreamWithHistoryContext sends a streaming query with message history and context support
 func (c *Client) QueryStreamWithHistoryContext(ctx context.Context, messages []Message, onChunk func(content string), onDone func(resp *ChatResponse)) error {
-	return c.queryStreamWithHistoryRetry(ctx, messages, onChunk, onDone)
+	messages, err := c.preQuery(ctx, messages)
+	if err != nil {
+		return err
+	}
+	model := c.selectModel(messages)
+	if err := c.checkContextSize(messages, model); err != nil {
+		return err
+	}
+
+	timeoutCtx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if c.onPostResponse == nil {
+		err := c.queryStreamWithHistoryRetry(timeoutCtx, messages, model, onChunk, onDone)
+		return c.timeoutError(timeoutCtx, err)
+	}
+
+	// Collect the streamed content for the post-response hook
+	var content strings.Builder
+	var finalResp *ChatResponse
+	err = c.queryStreamWithHistoryRetry(timeoutCtx, messages, model,
+		func(chunk string) {
+			content.WriteString(chunk)
+			onChunk(chunk)
+		},
+		func(resp *ChatResponse) {
+			finalResp = resp
+			if onDone != nil {
+				onDone(resp)
+			}
+		},
+	)
+	if err != nil {
+		return c.timeoutError(timeoutCtx, err)
+	}
+
+	c.onPostResponse(ctx, messages, content.String(), finalResp)
+	return nil
 }
 
-func (c *Client) queryStreamWithHistoryRetry(ctx context.Context, messages []Message, onChunk func(content string), onDone func(resp *ChatResponse)) error {
+func (c *Client) queryStreamWithHistoryRetry(ctx context.Context, messages []Message, model string, onChunk func(content string), onDone func(resp *ChatResponse)) error {
 	if c.config.GetKeyCount() <= 1 {
-		return c.doQueryStreamWithHistory(ctx, messages, onChunk, onDone)
+		return c.doQueryStreamWithHistory(ctx, messages, model, onChunk, onDone)
 	}
 
 	for {
-		err := c.doQueryStreamWithHistory(ctx, messages, onChunk, onDone)
+		err := c.doQueryStreamWithHistory(ctx, messages, model, onChunk, onDone)
 		if err == nil {
-			c.config.ResetKeyRotation()
+			c.keySucceeded()
 			return nil
 		}
 
@@ -414,27 +759,29 @@ func (c *Client) queryStreamWithHistoryRetry(ctx context.Context, messages []Mes
 		}
 
 		if rotateErr := c.rotateKey(); rotateErr != nil {
-			return fmt.Errorf("%v (no more API keys available)", err)
+			return fmt.Errorf("%w (no more API keys available)", err)
 		}
 	}
 }
 
-func (c *Client) doQueryStreamWithHistory(ctx context.Context, messages []Message, onChunk func(content string), onDone func(resp *ChatResponse)) error {
+func (c *Client) doQueryStreamWithHistory(ctx context.Context, messages []Message, model string, onChunk func(content string), onDone func(resp *ChatResponse)) error {
 	if err := c.rateLimiter.Wait(ctx); err != nil {
 		return err
 	}
 
-	reqBody := ChatRequest{
-		Model:    c.config.Model,
-		Messages: messages,
-		Stream:   true,
-	}
+	reqBody := c.newRequest(messages, model, true)
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	id := logging.NewRequestID()
+	log := c.requestLogger(id, reqBody)
+	dump := c.dump.begin(id)
+	defer dump.end()
+	start := time.Now()
+
 	// Use retry logic for the initial connection
 	var resp *http.Response
 	err = retry.Do(ctx, c.retryConfig, func() error {
@@ -446,15 +793,20 @@ func (c *Client) doQueryStreamWithHistory(ctx context.Context, messages []Messag
 		req.Header.Set("Accept", "text/event-stream")
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+		dump.request(req, jsonData)
 
 		resp, err = c.httpClient.Do(req)
 		if err != nil {
+			dump.fail(err)
 			return fmt.Errorf("failed to send request: %w", err)
 		}
+		log.Debug("Received response", logging.Int("status", resp.StatusCode), logging.Duration("elapsed", time.Since(start)))
+		c.reportQuota(resp.Header)
 
 		if resp.StatusCode != http.StatusOK {
 			body, _ := io.ReadAll(resp.Body)
 			_ = resp.Body.Close()
+			dump.response(resp, body)
 			var errResp ErrorResponse
 			errMsg := fmt.Sprintf("status code %d", resp.StatusCode)
 			if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
@@ -470,6 +822,7 @@ func (c *Client) doQueryStreamWithHistory(ctx context.Context, messages []Messag
 	}, c.onRetry)
 
 	if err != nil {
+		log.Warn("Request failed", logging.Err(err), logging.Duration("elapsed", time.Since(start)))
 		return err
 	}
 	defer func() {
@@ -478,22 +831,37 @@ func (c *Client) doQueryStreamWithHistory(ctx context.Context, messages []Messag
 		}
 	}()
 
+	dump.response(resp, nil)
+
 	var finalResp *ChatResponse
+	var meta ChatResponse // Metadata of the chunks, which the final one may lack
 	reader := bufio.NewReader(resp.Body)
+	chunks := 0
 
 	for {
 		// Check if context is cancelled
 		select {
 		case <-ctx.Done():
+			log.Debug("Stream cancelled", logging.Int("chunks", chunks), logging.Duration("elapsed", time.Since(start)))
 			return ctx.Err()
 		default:
 		}
 
 		line, err := reader.ReadString('\n')
+		if line != "" {
+			dump.line(line)
+			if c.rawOutput != nil {
+				if _, err := io.WriteString(c.rawOutput, line); err != nil {
+					return fmt.Errorf("failed to write raw response: %w", err)
+				}
+			}
+		}
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
+			dump.fail(err)
+			log.Warn("Stream failed", logging.Err(err), logging.Int("chunks", chunks), logging.Duration("elapsed", time.Since(start)))
 			return fmt.Errorf("failed to read stream: %w", err)
 		}
 
@@ -508,6 +876,12 @@ func (c *Client) doQueryStreamWithHistory(ctx context.Context, messages []Messag
 
 		data := strings.TrimPrefix(line, "data: ")
 		if data == "[DONE]" {
+			if c.rawOutput != nil {
+				// Pass on the rest of the body, such as the blank line ending the frame
+				if _, err := io.Copy(c.rawOutput, reader); err != nil {
+					return fmt.Errorf("failed to write raw response: %w", err)
+				}
+			}
 			break
 		}
 
@@ -515,16 +889,53 @@ func (c *Client) doQueryStreamWithHistory(ctx context.Context, messages []Messag
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			continue
 		}
+		meta.ID = cmp.Or(chunk.ID, meta.ID)
+		meta.Model = cmp.Or(chunk.Model, meta.Model)
+		meta.Created = cmp.Or(chunk.Created, meta.Created)
+		if reason := chunk.FinishReason(); reason != "" {
+			meta.Choices = []StreamChoice{{FinishReason: reason}}
+		}
 
 		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			if chunks == 0 {
+				log.Debug("First chunk", logging.Duration("elapsed", time.Since(start)))
+			}
+			chunks++
 			onChunk(chunk.Choices[0].Delta.Content)
 		}
 
-		if len(chunk.Citations) > 0 || chunk.Usage.TotalTokens > 0 {
+		if c.onSearches != nil && chunk.Usage.NumSearchQueries > 0 {
+			c.onSearches(chunk.Usage.NumSearchQueries)
+		}
+
+		if len(chunk.Citations) > 0 || len(chunk.RelatedQuestions) > 0 || len(chunk.Images) > 0 || chunk.Usage.TotalTokens > 0 {
 			finalResp = &chunk
 		}
 	}
 
+	completed := []any{logging.Int("chunks", chunks), logging.Duration("elapsed", time.Since(start))}
+	if finalResp != nil {
+		completed = append(completed,
+			logging.Int("prompt_tokens", finalResp.Usage.PromptTokens),
+			logging.Int("completion_tokens", finalResp.Usage.CompletionTokens),
+		)
+	}
+	log.Info("Stream completed", completed...)
+	logResponseMetadata(log, meta.ID, meta.Model, meta.Created, meta.FinishReason())
+
+	if finalResp != nil {
+		finalResp.ID = cmp.Or(finalResp.ID, meta.ID)
+		finalResp.Model = cmp.Or(finalResp.Model, meta.Model)
+		finalResp.Created = cmp.Or(finalResp.Created, meta.Created)
+		finalResp.KeyIndex = c.config.CurrentKeyIndex + 1
+		if reason := meta.FinishReason(); reason != "" && finalResp.FinishReason() == "" {
+			if len(finalResp.Choices) == 0 {
+				finalResp.Choices = []StreamChoice{{}}
+			}
+			finalResp.Choices[0].FinishReason = reason
+		}
+	}
+
 	if onDone != nil && finalResp != nil {
 		onDone(finalResp)
 	}
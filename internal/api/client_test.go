@@ -1,15 +1,22 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/logging"
+	"github.com/quocvuong92/perplexity-cli/internal/retry"
+	"github.com/quocvuong92/perplexity-cli/internal/validation"
 )
 
 func TestNewClient(t *testing.T) {
@@ -109,6 +116,14 @@ func TestChatResponseGetUsageMap(t *testing.T) {
 	if usage["total_tokens"] != 150 {
 		t.Errorf("total_tokens = %d, want 150", usage["total_tokens"])
 	}
+	if _, ok := usage["num_search_queries"]; ok {
+		t.Error("num_search_queries should be left out when not reported")
+	}
+
+	resp.Usage.NumSearchQueries = 3
+	if got := resp.GetUsageMap()["num_search_queries"]; got != 3 {
+		t.Errorf("num_search_queries = %d, want 3", got)
+	}
 }
 
 func TestAPIError(t *testing.T) {
@@ -247,6 +262,90 @@ func TestQueryWithContext(t *testing.T) {
 	}
 }
 
+func TestQueryTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// Stream slower than the timeout allows
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":\"x\"}}]}\n\n")
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}))
+	defer server.Close()
+
+	newClient := func(timeout time.Duration) *Client {
+		return NewClient(&config.Config{
+			APIURL:  server.URL,
+			APIKey:  "test-key",
+			APIKeys: []string{"test-key"},
+			Model:   "sonar-pro",
+			Timeout: timeout,
+		})
+	}
+
+	t.Run("stream exceeds timeout", func(t *testing.T) {
+		err := newClient(120*time.Millisecond).QueryStreamWithHistory([]Message{{Role: "user", Content: "Test"}}, func(string) {}, nil)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("QueryStreamWithHistory() error = %v, want deadline exceeded", err)
+		}
+	})
+
+	t.Run("zero timeout waits for the stream", func(t *testing.T) {
+		var content strings.Builder
+		err := newClient(0).QueryStreamWithHistory([]Message{{Role: "user", Content: "Test"}}, func(chunk string) {
+			content.WriteString(chunk)
+		}, nil)
+		if err != nil {
+			t.Fatalf("QueryStreamWithHistory() error = %v", err)
+		}
+		if content.String() != strings.Repeat("x", 10) {
+			t.Errorf("content = %q, want 10 chunks", content.String())
+		}
+	})
+}
+
+func TestQueryContextSize(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		json.NewEncoder(w).Encode(ChatResponse{Choices: []StreamChoice{{Message: Message{Content: "ok"}}}})
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{
+		APIURL:  server.URL,
+		APIKey:  "test-key",
+		APIKeys: []string{"test-key"},
+		Model:   "sonar",
+	})
+	var warning string
+	client.SetContextWarningCallback(func(message string) {
+		warning = message
+	})
+
+	_, err := client.QueryWithHistory([]Message{{Role: "user", Content: strings.Repeat("word ", 130000)}})
+	if !errors.Is(err, validation.ErrContextTooLong) {
+		t.Errorf("QueryWithHistory() error = %v, want ErrContextTooLong", err)
+	}
+	if requests != 0 {
+		t.Errorf("requests = %d, want the oversized request not to be sent", requests)
+	}
+
+	if _, err := client.QueryWithHistory([]Message{{Role: "user", Content: strings.Repeat("word ", 120000)}}); err != nil {
+		t.Fatalf("QueryWithHistory() error = %v", err)
+	}
+	if warning == "" {
+		t.Error("a request close to the context window should trigger a warning")
+	}
+}
+
 func TestQueryAPIError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusUnauthorized)
@@ -471,8 +570,503 @@ func TestKeyRotationOnFailure(t *testing.T) {
 	if resp.GetContent() != "Success with key2" {
 		t.Errorf("Content = %q, want %q", resp.GetContent(), "Success with key2")
 	}
+	if resp.KeyIndex != 2 {
+		t.Errorf("KeyIndex = %d, want the second key", resp.KeyIndex)
+	}
 
 	if requestCount != 2 {
 		t.Errorf("Request count = %d, want 2", requestCount)
 	}
 }
+
+func TestKeyQuotaCallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer key1" {
+			w.Header().Set("X-RateLimit-Remaining-Requests", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"Rate limit exceeded"}}`))
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		json.NewEncoder(w).Encode(ChatResponse{Choices: []StreamChoice{{Message: Message{Content: "OK"}}}})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIURL: server.URL, APIKey: "key1", APIKeys: []string{"key1", "key2"}, Model: "sonar-pro", Timeout: 10 * time.Second}
+	cfg.ResetKeyRotation()
+	client := NewClient(cfg)
+	var reported [][2]int
+	client.SetKeyQuotaCallback(func(index, remaining int) {
+		reported = append(reported, [2]int{index, remaining})
+	})
+
+	if _, err := client.Query("Test"); err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	// The rate limited key reports none left, then again when rotated away from
+	want := [][2]int{{0, 0}, {0, 0}, {1, 42}}
+	if !slices.Equal(reported, want) {
+		t.Errorf("reported quotas = %v, want %v", reported, want)
+	}
+}
+
+func TestQueryHooks(t *testing.T) {
+	var gotContent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		gotContent = req.Messages[len(req.Messages)-1].Content
+
+		resp := ChatResponse{
+			Choices: []StreamChoice{
+				{Message: Message{Content: "Response"}},
+			},
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		APIURL:  server.URL,
+		APIKey:  "test-key",
+		APIKeys: []string{"test-key"},
+		Model:   "sonar-pro",
+		Timeout: 10 * time.Second,
+	}
+
+	client := NewClient(cfg)
+	client.SetPreQueryHook(func(ctx context.Context, messages []Message) ([]Message, error) {
+		messages[len(messages)-1].Content = "rewritten"
+		return messages, nil
+	})
+
+	var postContent string
+	client.SetPostResponseHook(func(ctx context.Context, messages []Message, content string, resp *ChatResponse) {
+		postContent = content
+	})
+
+	if _, err := client.Query("original"); err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if gotContent != "rewritten" {
+		t.Errorf("Server received %q, want pre-query hook rewrite", gotContent)
+	}
+	if postContent != "Response" {
+		t.Errorf("Post-response hook content = %q, want %q", postContent, "Response")
+	}
+
+	// A failing pre-query hook aborts the request
+	client.SetPreQueryHook(func(ctx context.Context, messages []Message) ([]Message, error) {
+		return nil, errors.New("blocked")
+	})
+	if _, err := client.Query("original"); err == nil || err.Error() != "blocked" {
+		t.Errorf("Query() error = %v, want hook error", err)
+	}
+}
+
+func TestQueryAutoModel(t *testing.T) {
+	var gotModel string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		gotModel = req.Model
+
+		resp := ChatResponse{
+			Choices: []StreamChoice{
+				{Message: Message{Content: "Response"}},
+			},
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		APIURL:  server.URL,
+		APIKey:  "test-key",
+		APIKeys: []string{"test-key"},
+		Model:   config.AutoModel,
+		Timeout: 10 * time.Second,
+	}
+
+	client := NewClient(cfg)
+	var selected, reason string
+	client.SetModelSelectedCallback(func(model, why string) {
+		selected, reason = model, why
+	})
+
+	if _, err := client.Query("What is 6 * 7?"); err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if gotModel != "sonar-reasoning" || selected != gotModel {
+		t.Errorf("Server received model %q, callback got %q, want sonar-reasoning", gotModel, selected)
+	}
+	if reason != "math detected" {
+		t.Errorf("reason = %q, want %q", reason, "math detected")
+	}
+
+	cfg.Model = "sonar-pro"
+	selected = ""
+	if _, err := client.Query("What is 6 * 7?"); err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if gotModel != "sonar-pro" || selected != "" {
+		t.Errorf("explicit model should be sent unchanged without callback, got %q", gotModel)
+	}
+}
+
+func TestQueryAutoModelSelectedOncePerQuery(t *testing.T) {
+	var models []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		models = append(models, req.Model)
+		if r.Header.Get("Authorization") == "Bearer key1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(ChatResponse{Choices: []StreamChoice{{Message: Message{Content: "Response"}}}})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		APIURL:  server.URL,
+		APIKey:  "key1",
+		APIKeys: []string{"key1", "key2"},
+		Model:   config.AutoModel,
+		Timeout: 10 * time.Second,
+	}
+	cfg.ResetKeyRotation()
+
+	client := NewClient(cfg)
+	selections := 0
+	client.SetModelSelectedCallback(func(model, why string) { selections++ })
+
+	if _, err := client.Query("What is 6 * 7?"); err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if selections != 1 {
+		t.Errorf("model selected %d times, want once across the key rotation", selections)
+	}
+	if len(models) != 2 || models[0] != models[1] {
+		t.Errorf("models sent = %q, want the same model for both keys", models)
+	}
+}
+
+func TestNewRequestReasoningEffort(t *testing.T) {
+	cfg := &config.Config{Model: "sonar-reasoning-pro", ReasoningEffort: "low"}
+	client := NewClient(cfg)
+
+	req := client.newRequest([]Message{{Role: "user", Content: "hi"}}, client.config.Model, false)
+	if req.ReasoningEffort != "low" {
+		t.Errorf("ReasoningEffort = %q, want low for reasoning model", req.ReasoningEffort)
+	}
+
+	cfg.Model = "sonar-pro"
+	req = client.newRequest([]Message{{Role: "user", Content: "hi"}}, client.config.Model, false)
+	if req.ReasoningEffort != "" {
+		t.Errorf("ReasoningEffort = %q, want it omitted for non-reasoning model", req.ReasoningEffort)
+	}
+
+	data, _ := json.Marshal(req)
+	if strings.Contains(string(data), "reasoning_effort") {
+		t.Errorf("request %s should not contain reasoning_effort", data)
+	}
+}
+
+func TestNewRequestSampling(t *testing.T) {
+	cfg := &config.Config{Model: "sonar"}
+	client := NewClient(cfg)
+
+	data, _ := json.Marshal(client.newRequest([]Message{{Role: "user", Content: "hi"}}, client.config.Model, false))
+	for _, field := range []string{"frequency_penalty", "presence_penalty", "top_k"} {
+		if strings.Contains(string(data), field) {
+			t.Errorf("request %s should not contain unset %s", data, field)
+		}
+	}
+
+	frequency, presence := 0.5, -1.0
+	cfg.FrequencyPenalty = &frequency
+	cfg.PresencePenalty = &presence
+	cfg.TopK = 40
+	data, _ = json.Marshal(client.newRequest([]Message{{Role: "user", Content: "hi"}}, client.config.Model, false))
+	for _, want := range []string{`"frequency_penalty":0.5`, `"presence_penalty":-1`, `"top_k":40`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("request %s should contain %s", data, want)
+		}
+	}
+}
+
+func TestNewRequestSearchRecency(t *testing.T) {
+	cfg := &config.Config{Model: "sonar"}
+	client := NewClient(cfg)
+
+	data, _ := json.Marshal(client.newRequest([]Message{{Role: "user", Content: "hi"}}, client.config.Model, false))
+	if strings.Contains(string(data), "search_recency_filter") {
+		t.Errorf("request %s should not contain search_recency_filter by default", data)
+	}
+
+	cfg.SearchRecency = "day"
+	data, _ = json.Marshal(client.newRequest([]Message{{Role: "user", Content: "hi"}}, client.config.Model, false))
+	if !strings.Contains(string(data), `"search_recency_filter":"day"`) {
+		t.Errorf("request %s should contain the search recency", data)
+	}
+}
+
+func TestNewRequestSearchContext(t *testing.T) {
+	cfg := &config.Config{Model: "sonar"}
+	client := NewClient(cfg)
+
+	data, _ := json.Marshal(client.newRequest([]Message{{Role: "user", Content: "hi"}}, client.config.Model, false))
+	if strings.Contains(string(data), "web_search_options") {
+		t.Errorf("request %s should not contain web_search_options by default", data)
+	}
+
+	cfg.SearchContext = "low"
+	data, _ = json.Marshal(client.newRequest([]Message{{Role: "user", Content: "hi"}}, client.config.Model, false))
+	if !strings.Contains(string(data), `"web_search_options":{"search_context_size":"low"}`) {
+		t.Errorf("request %s should contain the search context size", data)
+	}
+}
+
+func TestNewRequestUserLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		country  string
+		want     string
+	}{
+		{"coordinates", "52.52,13.4", "", `"user_location":{"latitude":52.52,"longitude":13.4}`},
+		{"country", "", "DE", `"user_location":{"country":"DE"}`},
+		{"both", "0,0", "GH", `"user_location":{"latitude":0,"longitude":0,"country":"GH"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(&config.Config{Model: "sonar", Location: tt.location, Country: tt.country})
+			data, _ := json.Marshal(client.newRequest([]Message{{Role: "user", Content: "hi"}}, client.config.Model, false))
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("request %s should contain %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRequestImages(t *testing.T) {
+	cfg := &config.Config{Model: "sonar"}
+	client := NewClient(cfg)
+
+	data, _ := json.Marshal(client.newRequest([]Message{{Role: "user", Content: "hi"}}, client.config.Model, false))
+	if strings.Contains(string(data), "image") {
+		t.Errorf("request %s should not contain image fields by default", data)
+	}
+
+	cfg.Images = true
+	cfg.ImageDomains = []string{"wikimedia.org", "-gettyimages.com"}
+	cfg.ImageFormats = []string{"png"}
+	data, _ = json.Marshal(client.newRequest([]Message{{Role: "user", Content: "hi"}}, client.config.Model, false))
+	for _, want := range []string{`"return_images":true`, `"image_domain_filter":["wikimedia.org","-gettyimages.com"]`, `"image_format_filter":["png"]`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("request %s should contain %s", data, want)
+		}
+	}
+}
+
+func TestQueryStreamRelatedQuestions(t *testing.T) {
+	var reqBody ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&reqBody)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"Hi"}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"related_questions":["What is Go?","Who made Go?"]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		APIURL:           server.URL,
+		APIKey:           "test-key",
+		APIKeys:          []string{"test-key"},
+		Model:            "sonar-pro",
+		Timeout:          10 * time.Second,
+		RelatedQuestions: true,
+	}
+
+	var finalResp *ChatResponse
+	err := NewClient(cfg).QueryStream("Test", func(string) {}, func(resp *ChatResponse) {
+		finalResp = resp
+	})
+	if err != nil {
+		t.Fatalf("QueryStream() error = %v", err)
+	}
+
+	if !reqBody.ReturnRelatedQuestions {
+		t.Error("request should ask for related questions")
+	}
+	if finalResp == nil || len(finalResp.RelatedQuestions) != 2 {
+		t.Fatalf("final response = %+v, want 2 related questions", finalResp)
+	}
+	if finalResp.RelatedQuestions[1] != "Who made Go?" {
+		t.Errorf("RelatedQuestions[1] = %q, want %q", finalResp.RelatedQuestions[1], "Who made Go?")
+	}
+}
+
+func TestQueryStreamSearchCallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"<think>"}}],"usage":{"num_search_queries":3}}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"Done"}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"usage":{"prompt_tokens":5,"completion_tokens":7,"total_tokens":12,"num_search_queries":8}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		APIURL:  server.URL,
+		APIKey:  "test-key",
+		APIKeys: []string{"test-key"},
+		Model:   "sonar-deep-research",
+		Timeout: 10 * time.Second,
+	}
+
+	client := NewClient(cfg)
+	var searches []int
+	client.SetSearchCallback(func(queries int) { searches = append(searches, queries) })
+	var finalResp *ChatResponse
+	err := client.QueryStream("Test", func(string) {}, func(resp *ChatResponse) {
+		finalResp = resp
+	})
+	if err != nil {
+		t.Fatalf("QueryStream() error = %v", err)
+	}
+
+	if len(searches) != 2 || searches[0] != 3 || searches[1] != 8 {
+		t.Errorf("search callback got %v, want the counts of the chunks reporting them", searches)
+	}
+	if finalResp == nil || finalResp.Usage.NumSearchQueries != 8 {
+		t.Errorf("final response = %+v, want 8 searches in its usage", finalResp)
+	}
+}
+
+func TestUsageCost(t *testing.T) {
+	data := `{"prompt_tokens":10,"completion_tokens":5,"total_tokens":35,"citation_tokens":20,` +
+		`"cost":{"input_tokens_cost":0.00001,"output_tokens_cost":0.000005,"citation_tokens_cost":0.00004,"request_cost":0.005,"total_cost":0.005055}}`
+	var usage Usage
+	if err := json.Unmarshal([]byte(data), &usage); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if usage.CitationTokens != 20 {
+		t.Errorf("CitationTokens = %d, want 20", usage.CitationTokens)
+	}
+	if usage.Cost == nil || usage.Cost.TotalCost != 0.005055 || usage.Cost.RequestCost != 0.005 {
+		t.Errorf("Cost = %+v, want the reported cost", usage.Cost)
+	}
+
+	var plain Usage
+	if err := json.Unmarshal([]byte(`{"prompt_tokens":10}`), &plain); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if plain.Cost != nil {
+		t.Errorf("Cost = %+v, want nil when not reported", plain.Cost)
+	}
+}
+
+func TestResponseMetadataLogged(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(`data: {"id":"resp-stream","model":"sonar","created":1700000000,"choices":[{"delta":{"content":"Hi"}}]}` + "\n\n"))
+			w.Write([]byte(`data: {"id":"resp-stream","choices":[{"delta":{},"finish_reason":"length"}]}` + "\n\n"))
+			w.Write([]byte(`data: {"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}` + "\n\n"))
+			w.Write([]byte("data: [DONE]\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"resp-1","model":"sonar","created":1700000000,"choices":[{"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logging.ResetForTesting()
+	logging.Init(logging.Config{Output: &logs, Verbose: true})
+	defer logging.ResetForTesting()
+
+	cfg := &config.Config{APIURL: server.URL, APIKey: "test-key", APIKeys: []string{"test-key"}, Model: "sonar", Timeout: 10 * time.Second}
+	client := NewClient(cfg)
+
+	resp, err := client.Query("Test")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if resp.ID != "resp-1" || resp.FinishReason() != "stop" {
+		t.Errorf("response ID = %q, finish reason = %q", resp.ID, resp.FinishReason())
+	}
+
+	var finalResp *ChatResponse
+	if err := client.QueryStream("Test", func(string) {}, func(r *ChatResponse) { finalResp = r }); err != nil {
+		t.Fatalf("QueryStream() error = %v", err)
+	}
+	if finalResp == nil || finalResp.ID != "resp-stream" || finalResp.Created != 1700000000 || finalResp.FinishReason() != "length" {
+		t.Errorf("final response = %+v, want the metadata of the earlier chunks", finalResp)
+	}
+
+	for _, want := range []string{
+		`msg="Response metadata"`, "response_id=resp-1", "response_model=sonar", "created=2023-11-14T22:13:20Z", "finish_reason=stop",
+		"response_id=resp-stream", "finish_reason=length",
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs should contain %q, got:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestServerErrorRetriedWithoutRotation(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Authorization"))
+		if len(keys) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"message":"Service unavailable"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(ChatResponse{Choices: []StreamChoice{{Message: Message{Content: "Recovered"}}}})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIURL: server.URL, APIKey: "key1", APIKeys: []string{"key1", "key2"}, Model: "sonar", Timeout: 10 * time.Second}
+	cfg.ResetKeyRotation()
+	client := NewClient(cfg)
+	client.SetRetryConfig(retry.Config{MaxRetries: 0, MaxServerRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1})
+	var retries int
+	client.SetRetryCallback(func(retry.RetryInfo) { retries++ })
+
+	resp, err := client.Query("Test")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if resp.GetContent() != "Recovered" || retries != 2 {
+		t.Errorf("Query() = %q after %d retries, want a response after 2", resp.GetContent(), retries)
+	}
+	for _, key := range keys {
+		if key != "Bearer key1" {
+			t.Errorf("requests used keys %q, want server errors retried with the same key", keys)
+			break
+		}
+	}
+
+	// Once the server retries run out, the error is returned without rotating keys
+	keys = nil
+	client.SetRetryConfig(retry.Config{MaxServerRetries: 1, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1})
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	_, err = client.Query("Test")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway || len(keys) != 2 || cfg.CurrentKeyIndex != 0 {
+		t.Errorf("Query() = %v with keys %q, want the 502 after one retry with the same key", err, keys)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dump records the exact requests sent to the API and the raw responses or
+// SSE transcripts, with the API key redacted, for attaching to bug reports.
+// It is safe for concurrent use by several clients.
+type Dump struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewDump returns a Dump that writes to w
+func NewDump(w io.Writer) *Dump {
+	return &Dump{w: w}
+}
+
+// exchange collects one request, its retries and responses, and writes them
+// as one block so concurrent requests don't interleave. A nil exchange
+// records nothing.
+type exchange struct {
+	dump *Dump
+	buf  bytes.Buffer
+}
+
+// begin starts recording the request with the given ID
+func (d *Dump) begin(id string) *exchange {
+	if d == nil {
+		return nil
+	}
+	e := &exchange{dump: d}
+	fmt.Fprintf(&e.buf, "===== Request %s at %s =====\n", id, time.Now().Format(time.RFC3339))
+	return e
+}
+
+// request records an outgoing request and its body
+func (e *exchange) request(req *http.Request, body []byte) {
+	if e == nil {
+		return
+	}
+	fmt.Fprintf(&e.buf, "\n--- Request ---\n%s %s\n", req.Method, req.URL)
+	writeHeaders(&e.buf, req.Header)
+	fmt.Fprintf(&e.buf, "\n%s\n", body)
+}
+
+// response records a response status and headers, and the body if given.
+// Streamed bodies are recorded line by line with line.
+func (e *exchange) response(resp *http.Response, body []byte) {
+	if e == nil {
+		return
+	}
+	fmt.Fprintf(&e.buf, "\n--- Response ---\n%s %s\n", resp.Proto, resp.Status)
+	writeHeaders(&e.buf, resp.Header)
+	e.buf.WriteString("\n")
+	if body != nil {
+		fmt.Fprintf(&e.buf, "%s\n", bytes.TrimRight(body, "\n"))
+	}
+}
+
+// line records one raw line of a streamed response
+func (e *exchange) line(line string) {
+	if e == nil {
+		return
+	}
+	e.buf.WriteString(strings.TrimRight(line, "\r\n") + "\n")
+}
+
+// fail records an error that ended an attempt or the stream
+func (e *exchange) fail(err error) {
+	if e == nil || err == nil {
+		return
+	}
+	fmt.Fprintf(&e.buf, "\n--- Error ---\n%v\n", err)
+}
+
+// end writes the recorded exchange to the dump
+func (e *exchange) end() {
+	if e == nil {
+		return
+	}
+	e.buf.WriteString("\n")
+	e.dump.mu.Lock()
+	defer e.dump.mu.Unlock()
+	_, _ = e.dump.w.Write(e.buf.Bytes())
+}
+
+// secretHeaders are headers whose values are never written to a dump
+var secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// writeHeaders writes headers in a stable order, hiding credentials
+func writeHeaders(w io.Writer, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		for _, value := range header[name] {
+			if slices.Contains(secretHeaders, name) {
+				value = "[REDACTED]"
+			}
+			fmt.Fprintf(w, "%s: %s\n", name, value)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+func TestDumpQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hi"}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		APIURL:  server.URL,
+		APIKey:  "pplx-secret",
+		APIKeys: []string{"pplx-secret"},
+		Model:   "sonar",
+		Timeout: 10 * time.Second,
+	}
+
+	var out bytes.Buffer
+	client := NewClient(cfg)
+	client.SetDump(NewDump(&out))
+	if _, err := client.Query("Hello"); err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	dump := out.String()
+	for _, want := range []string{
+		"--- Request ---\nPOST " + server.URL,
+		"Authorization: [REDACTED]",
+		`"model":"sonar"`,
+		"--- Response ---\nHTTP/1.1 200 OK",
+		`{"choices":[{"message":{"content":"Hi"}}]}`,
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump should contain %q, got:\n%s", want, dump)
+		}
+	}
+	if strings.Contains(dump, "pplx-secret") {
+		t.Error("dump should not contain the API key")
+	}
+}
+
+func TestDumpQueryStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n"))
+		w.Write([]byte(": keep-alive\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		APIURL:  server.URL,
+		APIKey:  "pplx-secret",
+		APIKeys: []string{"pplx-secret"},
+		Model:   "sonar",
+		Timeout: 10 * time.Second,
+	}
+
+	var out bytes.Buffer
+	client := NewClient(cfg)
+	client.SetDump(NewDump(&out))
+	if err := client.QueryStream("Hello", func(string) {}, nil); err != nil {
+		t.Fatalf("QueryStream() error = %v", err)
+	}
+
+	dump := out.String()
+	want := "data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n" +
+		": keep-alive\n\n" +
+		"data: [DONE]\n"
+	if !strings.Contains(dump, want) {
+		t.Errorf("dump should contain the raw SSE transcript %q, got:\n%s", want, dump)
+	}
+}
+
+func TestDumpNil(t *testing.T) {
+	var d *Dump
+	e := d.begin("abc")
+	e.request(httptest.NewRequest(http.MethodPost, "/", nil), []byte("{}"))
+	e.line("data: x")
+	e.end()
+}
@@ -0,0 +1,160 @@
+// Package backup writes and reads the gzipped tar archives holding all the
+// data of perplexity-cli, for backups and moving to another machine.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// maxFileSize limits the size of a file read from an archive
+const maxFileSize = 256 << 20
+
+// Item is a file covered by backups, or a directory whose files are
+type Item struct {
+	Name string // Name of the file in the archive
+	Path string // Location of the file on this machine
+	Dir  bool   // Path is a directory; the files directly in it are covered
+}
+
+// File is a file read from an archive
+type File struct {
+	Item
+	Data []byte
+}
+
+// archiveDir is the directory holding the files in an archive, so that
+// extracting it by hand does not scatter them
+const archiveDir = "perplexity-cli"
+
+// expand replaces the directory items with an item for each file in them
+func expand(items []Item) ([]Item, error) {
+	var files []Item
+	for _, item := range items {
+		if !item.Dir {
+			files = append(files, item)
+			continue
+		}
+		entries, err := os.ReadDir(item.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", item.Path, err)
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				files = append(files, Item{Name: path.Join(item.Name, entry.Name()), Path: filepath.Join(item.Path, entry.Name())})
+			}
+		}
+	}
+	return files, nil
+}
+
+// Create writes the items that exist to w as a gzipped tar archive and
+// returns the files written
+func Create(w io.Writer, items []Item) ([]Item, error) {
+	items, err := expand(items)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	var written []Item
+	for _, item := range items {
+		info, err := os.Stat(item.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		var data []byte
+		if err == nil {
+			data, err = os.ReadFile(item.Path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", item.Path, err)
+		}
+
+		header := &tar.Header{
+			Name:    path.Join(archiveDir, item.Name),
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: info.ModTime(),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+		written = append(written, item)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return written, nil
+}
+
+// Read reads the files of an archive written by Create that match items,
+// located at the item's path. Other files are ignored, so an archive cannot
+// write anywhere else.
+func Read(r io.Reader, items []Item) ([]File, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	known := make(map[string]Item, len(items))
+	for _, item := range items {
+		known[path.Join(archiveDir, item.Name)] = item
+	}
+	// lookup returns the item of a file in the archive: a known file, or a
+	// file directly in a known directory
+	lookup := func(name string) (Item, bool) {
+		if item, ok := known[name]; ok && !item.Dir {
+			return item, true
+		}
+		dir, base := path.Split(name)
+		item, ok := known[path.Clean(dir)]
+		if !ok || !item.Dir || !filepath.IsLocal(base) || filepath.Base(base) != base {
+			return Item{}, false
+		}
+		return Item{Name: path.Join(item.Name, base), Path: filepath.Join(item.Path, base)}, true
+	}
+
+	var files []File
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup archive: %w", err)
+		}
+		item, ok := lookup(path.Clean(header.Name))
+		if !ok || header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if header.Size > maxFileSize {
+			return nil, fmt.Errorf("%s in backup archive is too large", header.Name)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxFileSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from backup archive: %w", header.Name, err)
+		}
+		files = append(files, File{Item: item, Data: data})
+	}
+	return files, nil
+}
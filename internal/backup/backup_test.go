@@ -0,0 +1,125 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateAndRead(t *testing.T) {
+	dir := t.TempDir()
+	items := []Item{
+		{Name: "config.yaml", Path: filepath.Join(dir, "config.yaml")},
+		{Name: "missing.json", Path: filepath.Join(dir, "missing.json")},
+		{Name: "history.json", Path: filepath.Join(dir, "data", "history.json")},
+	}
+	if err := os.WriteFile(items[0].Path, []byte("model: sonar\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(items[2].Path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(items[2].Path, []byte(`{"conversations": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	written, err := Create(&buf, items)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(written) != 2 || written[0].Name != "config.yaml" || written[1].Name != "history.json" {
+		t.Errorf("Create() wrote %+v, want the existing files", written)
+	}
+
+	// Restored elsewhere
+	other := t.TempDir()
+	targets := []Item{
+		{Name: "config.yaml", Path: filepath.Join(other, "config.yaml")},
+		{Name: "history.json", Path: filepath.Join(other, "history.json")},
+	}
+	files, err := Read(bytes.NewReader(buf.Bytes()), targets)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Read() = %+v, want 2 files", files)
+	}
+	if files[0].Path != targets[0].Path || string(files[0].Data) != "model: sonar\n" {
+		t.Errorf("Read() first file = %s %q, want the config at its new location", files[0].Path, files[0].Data)
+	}
+}
+
+func TestReadIgnoresUnknownFiles(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{"../../etc/passwd", "perplexity-cli/../config.yaml", "perplexity-cli/other.json"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: 1}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte("x"))
+	}
+	tw.Close()
+	gz.Close()
+
+	items := []Item{{Name: "config.yaml", Path: filepath.Join(t.TempDir(), "config.yaml")}}
+	files, err := Read(&buf, items)
+	if err != nil || len(files) != 0 {
+		t.Errorf("Read() = %+v, %v, want no files", files, err)
+	}
+
+	if _, err := Read(bytes.NewReader([]byte("not gzip")), items); err == nil {
+		t.Error("Read() should reject a file that is not an archive")
+	}
+}
+
+func TestCreateAndReadDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "prompts")
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"pirate.md", "nested/ignored.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("Answer as a pirate."), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	written, err := Create(&buf, []Item{{Name: "prompts", Path: dir, Dir: true}, {Name: "none", Path: filepath.Join(dir, "missing"), Dir: true}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(written) != 1 || written[0].Name != "prompts/pirate.md" {
+		t.Errorf("Create() wrote %+v, want the file directly in the directory", written)
+	}
+
+	other := filepath.Join(t.TempDir(), "prompts")
+	files, err := Read(bytes.NewReader(buf.Bytes()), []Item{{Name: "prompts", Path: other, Dir: true}})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(files) != 1 || files[0].Path != filepath.Join(other, "pirate.md") || string(files[0].Data) != "Answer as a pirate." {
+		t.Errorf("Read() = %+v, want the prompt at its new location", files)
+	}
+
+	// Files outside the directory are ignored
+	buf.Reset()
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{"perplexity-cli/prompts/../config.yaml", "perplexity-cli/prompts/a/b.md", "perplexity-cli/prompts"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: 1}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte("x"))
+	}
+	tw.Close()
+	gz.Close()
+	files, err = Read(&buf, []Item{{Name: "prompts", Path: other, Dir: true}})
+	if err != nil || len(files) != 0 {
+		t.Errorf("Read() = %+v, %v, want no files", files, err)
+	}
+}
@@ -0,0 +1,144 @@
+// Package bench aggregates latency, throughput and cost measurements
+// of model requests into per-model summaries.
+package bench
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/pricing"
+)
+
+// Sample is the measurement of a single request
+type Sample struct {
+	Model            string
+	Latency          time.Duration // Time until the response was complete
+	TTFB             time.Duration // Time until the first content was received
+	PromptTokens     int
+	CompletionTokens int
+	Err              error
+}
+
+// Summary aggregates the samples of one model
+type Summary struct {
+	Model            string
+	Requests         int
+	Errors           int
+	AvgLatency       time.Duration
+	AvgTTFB          time.Duration
+	TokensPerSecond  float64 // Completion tokens per second of generation time
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+	CostKnown        bool
+}
+
+// ReadPrompts reads one prompt per line, skipping blank lines and lines starting with #
+func ReadPrompts(r io.Reader) ([]string, error) {
+	var prompts []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		prompts = append(prompts, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read prompts: %w", err)
+	}
+	if len(prompts) == 0 {
+		return nil, fmt.Errorf("no prompts found")
+	}
+	return prompts, nil
+}
+
+// Summarize aggregates samples per model, in the order of models.
+// Failed requests only count towards Requests and Errors.
+func Summarize(models []string, samples []Sample) []Summary {
+	summaries := make([]Summary, 0, len(models))
+	for _, model := range models {
+		s := Summary{Model: model}
+		var latency, ttfb, generation time.Duration
+		ok := 0
+
+		for _, sample := range samples {
+			if sample.Model != model {
+				continue
+			}
+			s.Requests++
+			if sample.Err != nil {
+				s.Errors++
+				continue
+			}
+			ok++
+			latency += sample.Latency
+			ttfb += sample.TTFB
+			generation += sample.Latency - sample.TTFB
+			s.PromptTokens += sample.PromptTokens
+			s.CompletionTokens += sample.CompletionTokens
+		}
+
+		if ok > 0 {
+			s.AvgLatency = latency / time.Duration(ok)
+			s.AvgTTFB = ttfb / time.Duration(ok)
+			if generation <= 0 {
+				generation = latency
+			}
+			if generation > 0 {
+				s.TokensPerSecond = float64(s.CompletionTokens) / generation.Seconds()
+			}
+		}
+		s.Cost, s.CostKnown = pricing.Estimate(model, s.PromptTokens, s.CompletionTokens)
+
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+// WriteTable writes the summaries as a markdown table
+func WriteTable(w io.Writer, summaries []Summary) {
+	fmt.Fprintln(w, "| Model | Requests | Errors | Avg latency | Avg TTFB | Tokens/s | Prompt | Completion | Est. cost |")
+	fmt.Fprintln(w, "|-------|----------|--------|-------------|----------|----------|--------|------------|-----------|")
+	for _, s := range summaries {
+		cost := "n/a"
+		if s.CostKnown {
+			cost = pricing.Format(s.Cost)
+		}
+		fmt.Fprintf(w, "| %s | %d | %d | %.2fs | %.2fs | %.1f | %d | %d | %s |\n",
+			s.Model, s.Requests, s.Errors, s.AvgLatency.Seconds(), s.AvgTTFB.Seconds(),
+			s.TokensPerSecond, s.PromptTokens, s.CompletionTokens, cost)
+	}
+}
+
+// WriteCSV writes the summaries as CSV with a header row
+func WriteCSV(w io.Writer, summaries []Summary) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"model", "requests", "errors", "avg_latency_s", "avg_ttfb_s",
+		"tokens_per_second", "prompt_tokens", "completion_tokens", "cost_usd"})
+	for _, s := range summaries {
+		cost := ""
+		if s.CostKnown {
+			cost = strconv.FormatFloat(s.Cost, 'f', 6, 64)
+		}
+		_ = cw.Write([]string{
+			s.Model,
+			strconv.Itoa(s.Requests),
+			strconv.Itoa(s.Errors),
+			strconv.FormatFloat(s.AvgLatency.Seconds(), 'f', 3, 64),
+			strconv.FormatFloat(s.AvgTTFB.Seconds(), 'f', 3, 64),
+			strconv.FormatFloat(s.TokensPerSecond, 'f', 1, 64),
+			strconv.Itoa(s.PromptTokens),
+			strconv.Itoa(s.CompletionTokens),
+			cost,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,101 @@
+package bench
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadPrompts(t *testing.T) {
+	input := "# warm-up\nWhat is Go?\n\n  Explain CRDTs  \n"
+	prompts, err := ReadPrompts(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadPrompts() error = %v", err)
+	}
+	want := []string{"What is Go?", "Explain CRDTs"}
+	if !slices.Equal(prompts, want) {
+		t.Errorf("ReadPrompts() = %q, want %q", prompts, want)
+	}
+
+	if _, err := ReadPrompts(strings.NewReader("# only comments\n\n")); err == nil {
+		t.Error("ReadPrompts() should fail without prompts")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	samples := []Sample{
+		{Model: "sonar", Latency: 2 * time.Second, TTFB: time.Second, PromptTokens: 100, CompletionTokens: 50},
+		{Model: "sonar-pro", Latency: 4 * time.Second, TTFB: 2 * time.Second, PromptTokens: 100, CompletionTokens: 200},
+		{Model: "sonar", Latency: 4 * time.Second, TTFB: time.Second, PromptTokens: 100, CompletionTokens: 150},
+		{Model: "sonar", Latency: time.Second, Err: errors.New("timeout")},
+	}
+
+	summaries := Summarize([]string{"sonar", "sonar-pro", "unknown"}, samples)
+	if len(summaries) != 3 {
+		t.Fatalf("Summarize() returned %d summaries, want 3", len(summaries))
+	}
+
+	s := summaries[0]
+	if s.Model != "sonar" || s.Requests != 3 || s.Errors != 1 {
+		t.Errorf("sonar summary = %+v, want 3 requests and 1 error", s)
+	}
+	if s.AvgLatency != 3*time.Second || s.AvgTTFB != time.Second {
+		t.Errorf("sonar averages = %v/%v, want 3s/1s", s.AvgLatency, s.AvgTTFB)
+	}
+	// 200 completion tokens over 1s + 3s of generation
+	if math.Abs(s.TokensPerSecond-50) > 1e-9 {
+		t.Errorf("TokensPerSecond = %v, want 50", s.TokensPerSecond)
+	}
+	if !s.CostKnown || math.Abs(s.Cost-0.0004) > 1e-9 {
+		t.Errorf("Cost = %v (known %v), want 0.0004", s.Cost, s.CostKnown)
+	}
+
+	if summaries[1].Requests != 1 || summaries[1].PromptTokens != 100 {
+		t.Errorf("sonar-pro summary = %+v", summaries[1])
+	}
+	if summaries[2].Requests != 0 || summaries[2].CostKnown {
+		t.Errorf("unknown model summary = %+v, want empty", summaries[2])
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	var buf bytes.Buffer
+	WriteTable(&buf, []Summary{
+		{Model: "sonar", Requests: 2, AvgLatency: 1500 * time.Millisecond, AvgTTFB: 500 * time.Millisecond,
+			TokensPerSecond: 42, PromptTokens: 10, CompletionTokens: 20, Cost: 0.00003, CostKnown: true},
+		{Model: "custom", Requests: 1, Errors: 1},
+	})
+
+	output := buf.String()
+	for _, want := range []string{"| Model |", "| sonar | 2 | 0 | 1.50s | 0.50s | 42.0 | 10 | 20 | $0.0000 |", "| custom | 1 | 1 |", "n/a"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("WriteTable() should contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, []Summary{
+		{Model: "sonar", Requests: 1, AvgLatency: time.Second, AvgTTFB: 250 * time.Millisecond,
+			TokensPerSecond: 12.5, PromptTokens: 10, CompletionTokens: 20, Cost: 0.00003, CostKnown: true},
+	})
+	if err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("WriteCSV() wrote %d lines, want header and one row", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "model,requests,errors") {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+	if lines[1] != "sonar,1,0,1.000,0.250,12.5,10,20,0.000030" {
+		t.Errorf("unexpected row %q", lines[1])
+	}
+}
@@ -0,0 +1,78 @@
+// Package chunk splits long texts into pieces that fit a model's context
+// window, breaking at paragraph and line boundaries where possible.
+package chunk
+
+import (
+	"strings"
+
+	"github.com/quocvuong92/perplexity-cli/internal/tokens"
+)
+
+// Split breaks text into chunks of about maxTokens tokens at most. Chunks
+// end at paragraph boundaries where possible, else at line boundaries; a
+// single line that is too long is cut between runes. Chunks are trimmed and
+// empty ones dropped.
+func Split(text string, maxTokens int) []string {
+	maxTokens = max(maxTokens, 1)
+	if tokens.Estimate(text) <= maxTokens {
+		if text = strings.TrimSpace(text); text == "" {
+			return nil
+		}
+		return []string{text}
+	}
+
+	var chunks []string
+	var current []string
+	currentTokens := 0
+	flush := func() {
+		if chunk := strings.TrimSpace(strings.Join(current, "")); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current, currentTokens = nil, 0
+	}
+
+	for _, piece := range pieces(text, maxTokens) {
+		n := tokens.Estimate(piece)
+		if currentTokens+n > maxTokens {
+			flush()
+		}
+		current = append(current, piece)
+		currentTokens += n
+	}
+	flush()
+	return chunks
+}
+
+// pieces splits text into paragraphs, splitting the paragraphs over
+// maxTokens into lines and the lines over maxTokens into runs of runes
+func pieces(text string, maxTokens int) []string {
+	var result []string
+	for _, paragraph := range strings.SplitAfter(text, "\n\n") {
+		if tokens.Estimate(paragraph) <= maxTokens {
+			result = append(result, paragraph)
+			continue
+		}
+		for _, line := range strings.SplitAfter(paragraph, "\n") {
+			if tokens.Estimate(line) <= maxTokens {
+				result = append(result, line)
+				continue
+			}
+			result = append(result, cutLine(line, maxTokens)...)
+		}
+	}
+	return result
+}
+
+// cutLine cuts a line into runs of about maxTokens tokens
+func cutLine(line string, maxTokens int) []string {
+	runes := []rune(line)
+	// Size the runs by the line's average characters per token
+	size := max(len(runes)*maxTokens/max(tokens.Estimate(line), 1), 1)
+	var runs []string
+	for len(runes) > 0 {
+		n := min(size, len(runes))
+		runs = append(runs, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return runs
+}
@@ -0,0 +1,50 @@
+package chunk
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/tokens"
+)
+
+func TestSplit(t *testing.T) {
+	if chunks := Split("  short text\n", 100); len(chunks) != 1 || chunks[0] != "short text" {
+		t.Errorf("Split() of a short text = %q, want it trimmed as one chunk", chunks)
+	}
+	if chunks := Split(" \n\n ", 100); chunks != nil {
+		t.Errorf("Split() of blank text = %q, want none", chunks)
+	}
+
+	paragraph := strings.TrimSpace(strings.Repeat("lorem ipsum dolor sit amet ", 10))
+	text := strings.Repeat(paragraph+"\n\n", 9)
+	limit := tokens.Estimate(paragraph+"\n\n")*3 + 1
+	chunks := Split(text, limit)
+	if len(chunks) != 3 {
+		t.Fatalf("Split() returned %d chunks, want 3", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if strings.Count(chunk, paragraph) != 3 {
+			t.Errorf("chunk %d = %q, want three whole paragraphs", i, chunk)
+		}
+	}
+}
+
+func TestSplitLongLines(t *testing.T) {
+	line := strings.Repeat("word ", 200)
+	for _, text := range []string{line, line + "\n" + line} {
+		chunks := Split(text, 50)
+		if len(chunks) < 2 {
+			t.Fatalf("Split() returned %d chunks, want the text cut", len(chunks))
+		}
+		total := 0
+		for i, chunk := range chunks {
+			if n := tokens.Estimate(chunk); n > 50 {
+				t.Errorf("chunk %d is about %d tokens, over the limit", i, n)
+			}
+			total += strings.Count(chunk, "word")
+		}
+		if want := strings.Count(text, "word"); total < want-len(chunks) {
+			t.Errorf("chunks hold %d words, want about %d", total, want)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+// Package clipboard copies text to and reads text from the system clipboard.
+//
+// It uses the platform's clipboard tool (pbcopy, PowerShell, wl-copy, xclip
+// or xsel, or clip.exe under WSL). Inside tmux without one of those, and in SSH
+// sessions, it uses tmux's buffer, which tmux forwards to the outer terminal's
+// clipboard. Otherwise it falls back to the OSC52 terminal escape sequence,
+// which asks the terminal emulator itself to set the clipboard. OSC52 works
+// over SSH and on headless machines without a display server, as long as the
+// terminal supports it. Tools of the machine itself are skipped over SSH,
+// since they would only set the remote machine's clipboard.
+//
+// The tools are run directly rather than through a clipboard library: the
+// common ones wrap the same tools on Linux and macOS, and the native ones need
+// cgo and a display server, which OSC52 does without.
+package clipboard
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	"golang.org/x/term"
+)
+
+// OSC52 is the name of the terminal escape sequence backend
+const OSC52 = "OSC52"
+
+// Error is a clipboard failure with a hint on how to fix it
+type Error struct {
+	OS      string
+	Message string
+	Hint    string
+}
+
+func (e *Error) Error() string {
+	if e.Hint != "" {
+		return fmt.Sprintf("%s. %s", e.Message, e.Hint)
+	}
+	return e.Message
+}
+
+// Tool is a command line program that copies its stdin to the clipboard
+// and prints the clipboard's contents
+type Tool struct {
+	Name     string   // Name shown to users, e.g. xclip
+	Copy     []string // Command and arguments that copy stdin
+	CopyHTML []string // Command and arguments that copy stdin as rich text (empty = unsupported)
+	Paste    []string // Command and arguments that print the clipboard
+}
+
+// Replaced in tests
+var (
+	goos     = runtime.GOOS
+	getenv   = os.Getenv
+	lookPath = exec.LookPath
+	readFile = os.ReadFile
+	terminal = stderrTerminal
+	output   = func(command []string) ([]byte, error) {
+		return exec.Command(command[0], command[1:]...).Output()
+	}
+)
+
+// Copy puts text on the clipboard and returns the name of the backend used:
+// a tool's name, or OSC52 if the text was sent to the terminal
+func Copy(text string) (string, error) {
+	return copyText(text, false)
+}
+
+// CopyHTML puts an HTML fragment on the clipboard as rich text if the tool
+// supports it (wl-copy and xclip), and as plain text otherwise
+func CopyHTML(html string) (string, error) {
+	return copyText(html, true)
+}
+
+func copyText(text string, html bool) (string, error) {
+	tool, toolErr := Detect()
+	if toolErr == nil {
+		command := tool.Copy
+		if html && len(tool.CopyHTML) > 0 {
+			command = tool.CopyHTML
+		}
+		if toolErr = run(command, text); toolErr == nil {
+			return tool.Name, nil
+		}
+	}
+
+	if w := terminal(); w != nil {
+		if _, err := osc52Sequence(text).WriteTo(w); err == nil {
+			return OSC52, nil
+		}
+	}
+	return "", toolErr
+}
+
+// Paste returns the text on the clipboard. Unlike Copy it needs a clipboard
+// tool: terminals rarely allow reading the clipboard with OSC52.
+func Paste() (string, error) {
+	tool, err := Detect()
+	if err != nil {
+		return "", err
+	}
+
+	out, err := output(tool.Paste)
+	if err != nil {
+		return "", &Error{
+			OS:      goos,
+			Message: fmt.Sprintf("failed to read the clipboard: %v", err),
+			Hint:    "Make sure the clipboard tool is working correctly",
+		}
+	}
+	text := strings.ReplaceAll(string(out), "\r\n", "\n")
+	return strings.TrimSuffix(text, "\n"), nil
+}
+
+// Detect returns the clipboard tool to use: the system's own tool, or tmux
+// if there is none or the CLI runs over SSH
+func Detect() (Tool, error) {
+	var err error
+	if remoteSession() {
+		err = &Error{
+			OS:      goos,
+			Message: "cannot reach your local clipboard over SSH",
+			Hint:    "Use a terminal that supports OSC52, run inside tmux, or paste with your terminal's shortcut",
+		}
+	} else {
+		var tool Tool
+		if tool, err = detectSystem(); err == nil {
+			return tool, nil
+		}
+	}
+	if tool, ok := detectTmux(); ok {
+		return tool, nil
+	}
+	return Tool{}, err
+}
+
+// detectTmux returns tmux's buffer as a clipboard when running inside tmux.
+// load-buffer -w also sets the outer terminal's clipboard through OSC52.
+func detectTmux() (Tool, bool) {
+	if getenv("TMUX") == "" {
+		return Tool{}, false
+	}
+	if _, err := lookPath("tmux"); err != nil {
+		return Tool{}, false
+	}
+	return Tool{
+		Name:  "tmux",
+		Copy:  []string{"tmux", "load-buffer", "-w", "-"},
+		Paste: []string{"tmux", "save-buffer", "-"},
+	}, true
+}
+
+// detectSystem returns the clipboard tool of the operating system
+func detectSystem() (Tool, error) {
+	switch goos {
+	case "darwin":
+		if _, err := lookPath("pbcopy"); err != nil {
+			return Tool{}, &Error{
+				OS:      "macOS",
+				Message: "pbcopy command not found",
+				Hint:    "This is unexpected on macOS - pbcopy should be available by default",
+			}
+		}
+		return Tool{Name: "pbcopy", Copy: []string{"pbcopy"}, Paste: []string{"pbpaste"}}, nil
+	case "windows":
+		// clip.exe reads its input in the console's legacy code page, which
+		// garbles anything but ASCII: PowerShell is told to read and write UTF-8.
+		// -Raw keeps the clipboard's line breaks instead of listing its lines.
+		return Tool{
+			Name:  "PowerShell",
+			Copy:  []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", "[Console]::InputEncoding = [Text.Encoding]::UTF8; Set-Clipboard -Value ([Console]::In.ReadToEnd())"},
+			Paste: []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", "[Console]::OutputEncoding = [Text.Encoding]::UTF8; Get-Clipboard -Raw"},
+		}, nil
+	case "linux":
+		if isWSL() {
+			return detectWSL()
+		}
+		return detectUnix()
+	case "freebsd", "openbsd", "netbsd":
+		return detectUnix()
+	default:
+		return Tool{}, &Error{
+			OS:      goos,
+			Message: fmt.Sprintf("clipboard not supported on %s", goos),
+		}
+	}
+}
+
+// isWSL reports whether the CLI runs under the Windows Subsystem for Linux
+func isWSL() bool {
+	if getenv("WSL_DISTRO_NAME") != "" || getenv("WSL_INTEROP") != "" {
+		return true
+	}
+	version, err := readFile("/proc/version")
+	return err == nil && strings.Contains(strings.ToLower(string(version)), "microsoft")
+}
+
+// detectWSL returns the Windows clipboard tools, which WSL can run directly
+func detectWSL() (Tool, error) {
+	if _, err := lookPath("clip.exe"); err != nil {
+		return Tool{}, &Error{
+			OS:      "WSL",
+			Message: "clip.exe not found",
+			Hint:    "Make sure Windows interop is enabled and the Windows system directory is in PATH (appendWindowsPath in /etc/wsl.conf)",
+		}
+	}
+	return Tool{
+		Name:  "clip.exe",
+		Copy:  []string{"clip.exe"},
+		Paste: []string{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"},
+	}, nil
+}
+
+// detectUnix finds a clipboard tool for the running display server
+func detectUnix() (Tool, error) {
+	var candidates []Tool
+	if getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append(candidates, Tool{
+			Name:     "wl-copy",
+			Copy:     []string{"wl-copy"},
+			CopyHTML: []string{"wl-copy", "--type", "text/html"},
+			Paste:    []string{"wl-paste", "--no-newline"},
+		})
+	}
+	if getenv("DISPLAY") != "" {
+		candidates = append(candidates,
+			Tool{
+				Name:     "xclip",
+				Copy:     []string{"xclip", "-selection", "clipboard"},
+				CopyHTML: []string{"xclip", "-selection", "clipboard", "-t", "text/html"},
+				Paste:    []string{"xclip", "-selection", "clipboard", "-out"},
+			},
+			Tool{
+				Name:  "xsel",
+				Copy:  []string{"xsel", "--clipboard", "--input"},
+				Paste: []string{"xsel", "--clipboard", "--output"},
+			},
+		)
+	}
+
+	if len(candidates) == 0 {
+		return Tool{}, &Error{
+			OS:      "Linux",
+			Message: "no display server to hold the clipboard",
+			Hint:    "Use a terminal that supports OSC52, or run inside a graphical session",
+		}
+	}
+	for _, tool := range candidates {
+		if _, err := lookPath(tool.Copy[0]); err == nil {
+			return tool, nil
+		}
+	}
+	return Tool{}, &Error{
+		OS:      "Linux",
+		Message: "no clipboard tool found",
+		Hint:    "Install one of: xclip (sudo apt install xclip), xsel (sudo apt install xsel), or wl-copy for Wayland (sudo apt install wl-clipboard)",
+	}
+}
+
+// Available returns the backend Copy would use, or an error if there is none
+func Available() (string, error) {
+	tool, err := Detect()
+	if err == nil {
+		return tool.Name, nil
+	}
+	if terminal() != nil {
+		return OSC52, nil
+	}
+	return "", err
+}
+
+// remoteSession reports whether the CLI runs over SSH
+func remoteSession() bool {
+	return getenv("SSH_TTY") != "" || getenv("SSH_CONNECTION") != ""
+}
+
+// osc52Sequence returns the escape sequence that sets the clipboard to text,
+// wrapped so terminal multiplexers pass it through to the outer terminal
+func osc52Sequence(text string) osc52.Sequence {
+	seq := osc52.New(text)
+	switch {
+	case getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case strings.HasPrefix(getenv("TERM"), "screen"):
+		seq = seq.Screen()
+	}
+	return seq
+}
+
+// stderrTerminal returns stderr if it is a terminal. Stderr is used so the
+// sequence reaches the terminal even when stdout is redirected.
+func stderrTerminal() io.Writer {
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		return os.Stderr
+	}
+	return nil
+}
+
+// run runs a clipboard tool with text on stdin
+func run(command []string, text string) error {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return &Error{
+			OS:      goos,
+			Message: fmt.Sprintf("failed to copy to clipboard: %v", err),
+			Hint:    "Make sure the clipboard tool is working correctly",
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeEnv replaces the system lookups for one test
+func fakeEnv(t *testing.T, os string, env map[string]string, tools []string, term io.Writer) {
+	t.Helper()
+	oldGOOS, oldGetenv, oldLookPath, oldReadFile, oldTerminal := goos, getenv, lookPath, readFile, terminal
+	t.Cleanup(func() {
+		goos, getenv, lookPath, readFile, terminal = oldGOOS, oldGetenv, oldLookPath, oldReadFile, oldTerminal
+	})
+
+	goos = os
+	getenv = func(key string) string { return env[key] }
+	lookPath = func(file string) (string, error) {
+		if slices.Contains(tools, file) {
+			return "/usr/bin/" + file, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	readFile = func(string) ([]byte, error) { return nil, fs.ErrNotExist }
+	terminal = func() io.Writer {
+		if term == nil {
+			return nil
+		}
+		return term
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		goos    string
+		env     map[string]string
+		tools   []string
+		want    string
+		wantErr string
+	}{
+		{"macOS", "darwin", nil, []string{"pbcopy"}, "pbcopy", ""},
+		{"windows", "windows", nil, nil, "PowerShell", ""},
+		{"X11 xclip", "linux", map[string]string{"DISPLAY": ":0"}, []string{"xclip", "xsel"}, "xclip", ""},
+		{"X11 xsel", "linux", map[string]string{"DISPLAY": ":0"}, []string{"xsel"}, "xsel", ""},
+		{"wayland", "linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, []string{"wl-copy", "xclip"}, "wl-copy", ""},
+		{"no tool", "linux", map[string]string{"DISPLAY": ":0"}, nil, "", "no clipboard tool found"},
+		{"headless", "linux", nil, []string{"xclip"}, "", "no display server"},
+		{"unsupported", "plan9", nil, nil, "", "not supported"},
+		{"WSL", "linux", map[string]string{"WSL_DISTRO_NAME": "Ubuntu"}, []string{"clip.exe", "xclip"}, "clip.exe", ""},
+		{"WSL without interop", "linux", map[string]string{"WSL_DISTRO_NAME": "Ubuntu"}, nil, "", "clip.exe not found"},
+		{"tmux when headless", "linux", map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0"}, []string{"tmux", "xclip"}, "tmux", ""},
+		{"display before tmux", "linux", map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0", "DISPLAY": ":0"}, []string{"tmux", "xclip"}, "xclip", ""},
+		{"tmux over SSH", "linux", map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0", "SSH_TTY": "/dev/pts/1", "DISPLAY": ":0"}, []string{"tmux", "xclip"}, "tmux", ""},
+		{"SSH without tmux", "linux", map[string]string{"SSH_TTY": "/dev/pts/1", "DISPLAY": ":0"}, []string{"xclip"}, "", "over SSH"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeEnv(t, tt.goos, tt.env, tt.tools, nil)
+			tool, err := Detect()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Detect() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if tool.Name != tt.want {
+				t.Errorf("Detect() = %s, want %s", tool.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyOSC52Fallback(t *testing.T) {
+	var term bytes.Buffer
+	fakeEnv(t, "linux", nil, nil, &term)
+
+	backend, err := Copy("hello")
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if backend != OSC52 {
+		t.Errorf("Copy() backend = %s, want %s", backend, OSC52)
+	}
+	if term.String() != "\x1b]52;c;aGVsbG8=\x07" {
+		t.Errorf("terminal output = %q, want the OSC52 sequence", term.String())
+	}
+}
+
+func TestCopyOverSSHUsesOSC52(t *testing.T) {
+	var term bytes.Buffer
+	env := map[string]string{"SSH_TTY": "/dev/pts/0", "DISPLAY": "localhost:10.0"}
+	fakeEnv(t, "linux", env, []string{"xclip"}, &term)
+
+	backend, err := Copy("hello")
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if backend != OSC52 {
+		t.Errorf("Copy() backend = %s, want %s over SSH", backend, OSC52)
+	}
+}
+
+func TestCopyOSC52InTmux(t *testing.T) {
+	var term bytes.Buffer
+	fakeEnv(t, "linux", map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0"}, nil, &term)
+
+	if _, err := Copy("hello"); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if !strings.HasPrefix(term.String(), "\x1bPtmux;") {
+		t.Errorf("terminal output = %q, want the sequence wrapped for tmux", term.String())
+	}
+}
+
+func TestCopyWithoutBackend(t *testing.T) {
+	fakeEnv(t, "linux", map[string]string{"DISPLAY": ":0"}, nil, nil)
+
+	_, err := Copy("hello")
+	var clipErr *Error
+	if !errors.As(err, &clipErr) || clipErr.Hint == "" {
+		t.Errorf("Copy() error = %v, want an Error with a hint", err)
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	fakeEnv(t, "linux", nil, nil, &bytes.Buffer{})
+	if backend, err := Available(); err != nil || backend != OSC52 {
+		t.Errorf("Available() = %s, %v, want %s", backend, err, OSC52)
+	}
+
+	fakeEnv(t, "linux", nil, nil, nil)
+	if _, err := Available(); err == nil {
+		t.Error("Available() should fail without a tool or terminal")
+	}
+}
+
+func TestPaste(t *testing.T) {
+	fakeEnv(t, "linux", map[string]string{"DISPLAY": ":0"}, []string{"xclip"}, nil)
+	oldOutput := output
+	t.Cleanup(func() { output = oldOutput })
+
+	var ran []string
+	output = func(command []string) ([]byte, error) {
+		ran = command
+		return []byte("line 1\r\nline 2\r\n"), nil
+	}
+
+	text, err := Paste()
+	if err != nil {
+		t.Fatalf("Paste() error = %v", err)
+	}
+	if text != "line 1\nline 2" {
+		t.Errorf("Paste() = %q, want normalized line endings without the trailing newline", text)
+	}
+	if strings.Join(ran, " ") != "xclip -selection clipboard -out" {
+		t.Errorf("Paste() ran %q, want xclip's paste command", ran)
+	}
+}
+
+func TestPasteErrors(t *testing.T) {
+	t.Run("over SSH", func(t *testing.T) {
+		fakeEnv(t, "linux", map[string]string{"SSH_CONNECTION": "10.0.0.1 22 10.0.0.2 22", "DISPLAY": ":0"}, []string{"xclip"}, &bytes.Buffer{})
+		if _, err := Paste(); err == nil || !strings.Contains(err.Error(), "over SSH") {
+			t.Errorf("Paste() error = %v, want an SSH error", err)
+		}
+	})
+
+	t.Run("no tool", func(t *testing.T) {
+		fakeEnv(t, "linux", nil, nil, &bytes.Buffer{})
+		if _, err := Paste(); err == nil {
+			t.Error("Paste() should fail without a clipboard tool, even with OSC52")
+		}
+	})
+}
+
+func TestDetectWSLFromProcVersion(t *testing.T) {
+	fakeEnv(t, "linux", nil, []string{"clip.exe"}, nil)
+	readFile = func(string) ([]byte, error) {
+		return []byte("Linux version 5.15.153.1-microsoft-standard-WSL2"), nil
+	}
+
+	tool, err := Detect()
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if tool.Name != "clip.exe" {
+		t.Errorf("Detect() = %s, want clip.exe", tool.Name)
+	}
+}
+
+func TestDetectHTMLSupport(t *testing.T) {
+	fakeEnv(t, "linux", map[string]string{"DISPLAY": ":0"}, []string{"xclip"}, nil)
+	tool, err := Detect()
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if strings.Join(tool.CopyHTML, " ") != "xclip -selection clipboard -t text/html" {
+		t.Errorf("CopyHTML = %q, want xclip's text/html target", tool.CopyHTML)
+	}
+
+	fakeEnv(t, "linux", map[string]string{"DISPLAY": ":0"}, []string{"xsel"}, nil)
+	if tool, _ := Detect(); len(tool.CopyHTML) != 0 {
+		t.Errorf("CopyHTML = %q, want none for xsel", tool.CopyHTML)
+	}
+}
+
+func TestCopyHTMLOSC52(t *testing.T) {
+	var term bytes.Buffer
+	fakeEnv(t, "linux", nil, nil, &term)
+
+	backend, err := CopyHTML("<p>hi</p>")
+	if err != nil || backend != OSC52 {
+		t.Fatalf("CopyHTML() = %s, %v, want %s", backend, err, OSC52)
+	}
+	if term.Len() == 0 {
+		t.Error("CopyHTML() should send the HTML source to the terminal")
+	}
+}
+
+func TestWindowsUnicode(t *testing.T) {
+	fakeEnv(t, "windows", nil, nil, nil)
+
+	tool, err := Detect()
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	// clip.exe would read the text in the legacy code page
+	copyCmd, pasteCmd := strings.Join(tool.Copy, " "), strings.Join(tool.Paste, " ")
+	if !strings.Contains(copyCmd, "InputEncoding = [Text.Encoding]::UTF8") {
+		t.Errorf("Copy = %q, want stdin read as UTF-8", copyCmd)
+	}
+	if !strings.Contains(pasteCmd, "OutputEncoding = [Text.Encoding]::UTF8") || !strings.Contains(pasteCmd, "-Raw") {
+		t.Errorf("Paste = %q, want the raw clipboard written as UTF-8", pasteCmd)
+	}
+}
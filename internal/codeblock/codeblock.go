@@ -0,0 +1,154 @@
+// Package codeblock extracts fenced code blocks from markdown responses.
+package codeblock
+
+import (
+	"slices"
+	"strings"
+)
+
+// Block is a fenced code block
+type Block struct {
+	Language string // Info string language, empty if untagged
+	Code     string // Content without the fences
+}
+
+// Extract returns the fenced code blocks in markdown content, in order.
+// A block left open at the end of the content is included.
+func Extract(content string) []Block {
+	var blocks []Block
+	var fence, indent string
+	var current *Block
+	var lines []string
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+
+		if current == nil {
+			f := fenceOf(trimmed)
+			if f == "" {
+				continue
+			}
+			fence = f
+			indent = line[:len(line)-len(trimmed)]
+			current = &Block{Language: language(strings.TrimPrefix(trimmed, f))}
+			lines = nil
+			continue
+		}
+
+		if isClosingFence(trimmed, fence) {
+			current.Code = strings.Join(lines, "\n")
+			blocks = append(blocks, *current)
+			current = nil
+			continue
+		}
+		// Fences indented in list items indent their content as well
+		lines = append(lines, strings.TrimPrefix(line, indent))
+	}
+
+	if current != nil {
+		current.Code = strings.TrimRight(strings.Join(lines, "\n"), "\n")
+		blocks = append(blocks, *current)
+	}
+	return blocks
+}
+
+// fenceOf returns the opening fence (three or more backticks or tildes) a line starts with
+func fenceOf(line string) string {
+	for _, c := range []byte{'`', '~'} {
+		n := 0
+		for n < len(line) && line[n] == c {
+			n++
+		}
+		if n >= 3 {
+			// Backtick fences cannot have backticks in their info string
+			if c == '`' && strings.Contains(line[n:], "`") {
+				return ""
+			}
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// isClosingFence reports whether a line closes a block opened with fence
+func isClosingFence(line, fence string) bool {
+	line = strings.TrimSpace(line)
+	return len(line) >= len(fence) && strings.Trim(line, fence[:1]) == ""
+}
+
+// language returns the first word of a fence info string, e.g. "go" for "go title=main.go"
+func language(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+// extensions maps code block languages to file extensions
+var extensions = map[string]string{
+	"bash":       ".sh",
+	"sh":         ".sh",
+	"shell":      ".sh",
+	"zsh":        ".zsh",
+	"fish":       ".fish",
+	"powershell": ".ps1",
+	"ps1":        ".ps1",
+	"bat":        ".bat",
+	"go":         ".go",
+	"python":     ".py",
+	"py":         ".py",
+	"javascript": ".js",
+	"js":         ".js",
+	"typescript": ".ts",
+	"ts":         ".ts",
+	"jsx":        ".jsx",
+	"tsx":        ".tsx",
+	"java":       ".java",
+	"kotlin":     ".kt",
+	"c":          ".c",
+	"cpp":        ".cpp",
+	"c++":        ".cpp",
+	"csharp":     ".cs",
+	"cs":         ".cs",
+	"rust":       ".rs",
+	"ruby":       ".rb",
+	"rb":         ".rb",
+	"php":        ".php",
+	"swift":      ".swift",
+	"lua":        ".lua",
+	"sql":        ".sql",
+	"html":       ".html",
+	"css":        ".css",
+	"json":       ".json",
+	"yaml":       ".yaml",
+	"yml":        ".yaml",
+	"toml":       ".toml",
+	"ini":        ".ini",
+	"xml":        ".xml",
+	"markdown":   ".md",
+	"md":         ".md",
+	"dockerfile": ".dockerfile",
+	"makefile":   ".mk",
+	"make":       ".mk",
+	"hcl":        ".tf",
+	"terraform":  ".tf",
+	"nginx":      ".conf",
+}
+
+// Extension guesses the file extension for a code block language,
+// falling back to ".txt"
+func Extension(language string) string {
+	if ext, ok := extensions[strings.ToLower(language)]; ok {
+		return ext
+	}
+	return ".txt"
+}
+
+// shellLanguages are the languages run by /run; untagged blocks are assumed to be shell
+var shellLanguages = []string{"", "bash", "sh", "shell", "zsh", "bat", "cmd"}
+
+// IsShell reports whether a code block language is a shell script
+func IsShell(language string) bool {
+	return slices.Contains(shellLanguages, strings.ToLower(language))
+}
@@ -0,0 +1,107 @@
+package codeblock
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Block
+	}{
+		{
+			name:    "no blocks",
+			content: "Just text with `inline code`.",
+			want:    nil,
+		},
+		{
+			name:    "single block",
+			content: "Run this:\n\n```bash\necho hi\nls -la\n```\n\nDone.",
+			want:    []Block{{Language: "bash", Code: "echo hi\nls -la"}},
+		},
+		{
+			name:    "multiple blocks",
+			content: "```go\nfmt.Println(1)\n```\ntext\n```\nplain\n```",
+			want: []Block{
+				{Language: "go", Code: "fmt.Println(1)"},
+				{Language: "", Code: "plain"},
+			},
+		},
+		{
+			name:    "info string",
+			content: "```Python title=main.py\nprint(1)\n```",
+			want:    []Block{{Language: "python", Code: "print(1)"}},
+		},
+		{
+			name:    "tilde fence containing backticks",
+			content: "~~~markdown\n```go\nx\n```\n~~~",
+			want:    []Block{{Language: "markdown", Code: "```go\nx\n```"}},
+		},
+		{
+			name:    "longer fence",
+			content: "````\n```\n````",
+			want:    []Block{{Language: "", Code: "```"}},
+		},
+		{
+			name:    "indented in list",
+			content: "1. Step:\n   ```sh\n   make\n     nested\n   ```",
+			want:    []Block{{Language: "sh", Code: "make\n  nested"}},
+		},
+		{
+			name:    "unterminated",
+			content: "```js\nconsole.log(1)\n",
+			want:    []Block{{Language: "js", Code: "console.log(1)"}},
+		},
+		{
+			name:    "empty block",
+			content: "```\n```",
+			want:    []Block{{Language: "", Code: ""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Extract(tt.content)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtension(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"bash", ".sh"},
+		{"Go", ".go"},
+		{"python", ".py"},
+		{"yml", ".yaml"},
+		{"", ".txt"},
+		{"brainfuck", ".txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			if got := Extension(tt.language); got != tt.want {
+				t.Errorf("Extension(%q) = %q, want %q", tt.language, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsShell(t *testing.T) {
+	for _, language := range []string{"", "bash", "SH", "zsh"} {
+		if !IsShell(language) {
+			t.Errorf("IsShell(%q) = false, want true", language)
+		}
+	}
+	for _, language := range []string{"go", "python", "json"} {
+		if IsShell(language) {
+			t.Errorf("IsShell(%q) = true, want false", language)
+		}
+	}
+}
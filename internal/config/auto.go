@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// AutoModel is the pseudo-model that picks a model for each query from its content
+const AutoModel = "auto"
+
+// DefaultLongPromptChars is the prompt length from which the auto model treats a prompt as long
+const DefaultLongPromptChars = 1500
+
+// AutoPolicy maps the kind of prompt detected by the auto model to the model used for it.
+// Empty fields fall back to the defaults in DefaultAutoPolicy.
+type AutoPolicy struct {
+	Default         string `yaml:"default,omitempty"`
+	Long            string `yaml:"long,omitempty"`
+	Code            string `yaml:"code,omitempty"`
+	Math            string `yaml:"math,omitempty"`
+	LongPromptChars int    `yaml:"long_prompt_chars,omitempty"`
+}
+
+// DefaultAutoPolicy is used for the auto model unless overridden under "auto_model" in the config file
+var DefaultAutoPolicy = AutoPolicy{
+	Default:         "sonar",
+	Long:            "sonar-pro",
+	Code:            "sonar-pro",
+	Math:            "sonar-reasoning",
+	LongPromptChars: DefaultLongPromptChars,
+}
+
+var (
+	// codePattern matches code fences and common syntax of popular languages
+	codePattern = regexp.MustCompile("(?m)```|^\\s*(func|def|class|import|package|#include|public|private|const|let|var)\\s|[;{}]\\s*$|=>|:=|\\w+\\([^)]*\\)\\s*\\{")
+	// mathPattern matches LaTeX, arithmetic between numbers and common math vocabulary
+	mathPattern = regexp.MustCompile(`(?i)\\(frac|sum|int|sqrt)\b|\$\$|\d\s*[+*^=]\s*\d|\d\s+[-/]\s+\d|\b(integral|derivative|equation|theorem|prove|matrix|probability|solve for)\b`)
+)
+
+// GetAutoPolicy returns the auto model policy with defaults filled in and aliases resolved
+func (c *Config) GetAutoPolicy() AutoPolicy {
+	p := c.AutoPolicy
+	if p.Default == "" {
+		p.Default = DefaultAutoPolicy.Default
+	}
+	if p.Long == "" {
+		p.Long = DefaultAutoPolicy.Long
+	}
+	if p.Code == "" {
+		p.Code = DefaultAutoPolicy.Code
+	}
+	if p.Math == "" {
+		p.Math = DefaultAutoPolicy.Math
+	}
+	if p.LongPromptChars <= 0 {
+		p.LongPromptChars = DefaultAutoPolicy.LongPromptChars
+	}
+	p.Default = c.ResolveModel(p.Default)
+	p.Long = c.ResolveModel(p.Long)
+	p.Code = c.ResolveModel(p.Code)
+	p.Math = c.ResolveModel(p.Math)
+	return p
+}
+
+// SelectModel picks the model the auto model uses for a prompt and returns
+// the reason for the choice. Math takes precedence over code, and code over length.
+func (c *Config) SelectModel(prompt string) (model, reason string) {
+	p := c.GetAutoPolicy()
+	switch {
+	case mathPattern.MatchString(prompt):
+		return p.Math, "math detected"
+	case codePattern.MatchString(prompt):
+		return p.Code, "code detected"
+	case len([]rune(prompt)) >= p.LongPromptChars:
+		return p.Long, "long prompt"
+	default:
+		return p.Default, "default"
+	}
+}
+
+// validateAutoPolicy checks that every model in the auto policy is known
+func (c *Config) validateAutoPolicy() error {
+	p := c.GetAutoPolicy()
+	for _, model := range []string{p.Default, p.Long, p.Code, p.Math} {
+		if model == AutoModel || !c.IsValidModel(model) {
+			return fmt.Errorf("%w in auto_model policy: %s", ErrInvalidModel, model)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSelectModel(t *testing.T) {
+	cfg := NewConfig()
+	tests := []struct {
+		name       string
+		prompt     string
+		wantModel  string
+		wantReason string
+	}{
+		{"short question", "What is the capital of France?", "sonar", "default"},
+		{"date is not math", "What happened on 2024-01-01 in 3-5 cities?", "sonar", "default"},
+		{"code fence", "Why does this fail?\n```go\nx := 1\n```", "sonar-pro", "code detected"},
+		{"code line", "func main() {\n\tfmt.Println(\"hi\")\n}", "sonar-pro", "code detected"},
+		{"arithmetic", "What is 12 * 7?", "sonar-reasoning", "math detected"},
+		{"latex", `Simplify \frac{a}{b} + 1`, "sonar-reasoning", "math detected"},
+		{"math vocabulary", "Prove that there are infinitely many primes", "sonar-reasoning", "math detected"},
+		{"long prompt", strings.Repeat("Tell me more about history. ", 60), "sonar-pro", "long prompt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, reason := cfg.SelectModel(tt.prompt)
+			if model != tt.wantModel || reason != tt.wantReason {
+				t.Errorf("SelectModel() = %s (%s), want %s (%s)", model, reason, tt.wantModel, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestSelectModelPolicy(t *testing.T) {
+	cfg := NewConfig()
+	cfg.ModelAliases = map[string]string{"deep": "sonar-reasoning-pro"}
+	cfg.AutoPolicy = AutoPolicy{Default: "sonar-pro", Math: "deep", LongPromptChars: 10}
+
+	if model, _ := cfg.SelectModel("hi"); model != "sonar-pro" {
+		t.Errorf("SelectModel() default = %s, want sonar-pro", model)
+	}
+	if model, _ := cfg.SelectModel("solve 2 + 2"); model != "sonar-reasoning-pro" {
+		t.Errorf("SelectModel() math = %s, want alias resolved to sonar-reasoning-pro", model)
+	}
+	if model, reason := cfg.SelectModel("a rather long prompt"); model != "sonar-pro" || reason != "long prompt" {
+		t.Errorf("SelectModel() long = %s (%s), want sonar-pro (long prompt)", model, reason)
+	}
+}
+
+func TestValidateAutoModel(t *testing.T) {
+	cfg := NewConfig()
+	cfg.APIKey = "pplx-test-key-12345678901234567890"
+	cfg.Model = AutoModel
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if cfg.Model != AutoModel {
+		t.Errorf("Model = %q, want auto to be kept", cfg.Model)
+	}
+
+	cfg.AutoPolicy.Code = "gpt-4"
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("Validate() error = %v, want ErrInvalidModel for unknown policy model", err)
+	}
+}
@@ -3,13 +3,16 @@ package config
 import (
 	"errors"
 	"fmt"
-	"math/rand/v2"
+	"math"
+	"net/url"
 	"os"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/quocvuong92/perplexity-cli/internal/keyring"
+	"github.com/quocvuong92/perplexity-cli/internal/retry"
 	"github.com/quocvuong92/perplexity-cli/internal/validation"
 )
 
@@ -28,12 +31,12 @@ const DefaultModel = "sonar-pro"
 // DefaultSystemMessage is the default system prompt
 const DefaultSystemMessage = "Be precise and concise."
 
-// FailedResponsePlaceholder is used when API returns empty response
-const FailedResponsePlaceholder = "I apologize, but I couldn't generate a response."
-
 // DefaultAPIURL is the Perplexity API endpoint
 const DefaultAPIURL = "https://api.perplexity.ai/chat/completions"
 
+// DefaultModelsURL is the endpoint listing the models available to the API key
+const DefaultModelsURL = "https://api.perplexity.ai/models"
+
 // DefaultTimeout is the default HTTP client timeout
 const DefaultTimeout = 120 * time.Second
 
@@ -47,24 +50,210 @@ const (
 
 // Config holds the application configuration
 type Config struct {
-	APIURL          string
-	APIKey          string   // Current active API key
-	APIKeys         []string // All available API keys
-	CurrentKeyIndex int      // Index of current key in APIKeys
-	startKeyIndex   int      // Starting index for rotation cycle detection (-1 = not tracking)
-	Model           string
-	Timeout         time.Duration // HTTP client timeout
-	RateLimit       float64       // Requests per minute (0 = disabled)
-	Usage           bool
-	Citations       bool
-	Stream          bool
-	Render          bool   // Render markdown output with colors/formatting
-	Interactive     bool   // Interactive chat mode
-	OutputFile      string // Output file path for saving response
+	APIURL              string
+	APIKey              string   // Current active API key
+	APIKeys             []string // All available API keys
+	CurrentKeyIndex     int      // Index of current key in APIKeys
+	startKeyIndex       int      // Starting index for rotation cycle detection (-1 = not tracking)
+	savedKeyIndex       int      // Index recorded in the key state file (-1 = none)
+	PersistKeyIndex     bool     // Start from the key that last worked, saved between runs
+	KeyStrategy         string   // Which key a run starts with otherwise (empty = random)
+	FileAPIKey          string   // API key from the config file, used without keys in the environment
+	APIKeyStore         string   // Where the key used without keys in the environment is kept (empty = config file)
+	Model               string
+	SystemPrompt        string        // System prompt for new conversations (empty = DefaultSystemMessage)
+	Temperature         *float64      // Sampling temperature (nil = API default)
+	FrequencyPenalty    *float64      // Penalty for frequently repeated tokens (nil = API default)
+	PresencePenalty     *float64      // Penalty for tokens already present (nil = API default)
+	TopK                int           // Top-k sampling (0 = disabled)
+	ReasoningEffort     string        // Reasoning effort for reasoning models (empty = API default)
+	SearchContext       string        // Amount of search context retrieved (empty = API default)
+	SearchRecency       string        // Only search sources from this recent period (empty = any time)
+	Location            string        // "latitude,longitude" to localize search results (empty = none)
+	Country             string        // ISO 3166-1 alpha-2 country code to localize search results
+	Timeout             time.Duration // Limit for a whole query, including streaming (0 = none)
+	RateLimit           float64       // Requests per minute (0 = disabled)
+	Concurrency         int           // Concurrent requests of compare and batch commands (0 = pool default)
+	ChunkTokens         int           // Tokens per chunk of oversized piped input (0 = half the model's context window)
+	ChunkStrategy       string        // How oversized piped input is shortened (empty = map-reduce)
+	Retry               retry.Config  // Retries of failed requests due to network and server errors
+	Usage               bool
+	Citations           bool
+	Stream              bool
+	Render              bool              // Render markdown output with colors/formatting
+	Theme               string            // Markdown rendering theme (empty = auto)
+	HideThinking        bool              // Hide the <think> reasoning of reasoning models
+	HideCitationMarkers bool              // Remove inline [n] citation markers from responses
+	CitationStyle       string            // How inline [n] markers are rendered (empty = markers)
+	RelatedQuestions    bool              // Request related questions and offer them after each answer
+	ShellInterpolation  bool              // Run !{command} placeholders in interactive messages, after confirmation
+	AutoTitle           bool              // Title interactive conversations with a summary generated after the first exchange
+	ResumeSettings      string            // Whether /resume restores the conversation's model and system prompt (empty = restore)
+	Images              bool              // Request images with each answer
+	ImageDomains        []string          // Only return images from these domains ("-" prefix excludes)
+	ImageFormats        []string          // Only return images in these formats, e.g. png
+	Interactive         bool              // Interactive chat mode
+	Quiet               bool              // Suppress spinners and informational notes
+	LogLevel            string            // Level of logs written to stderr (empty = none unless --verbose)
+	LogFormat           string            // Format of logs: text or json (empty = text)
+	OutputFile          string            // Output file path for saving response
+	HistoryRetention    time.Duration     // Conversations not updated for longer are pruned from history (0 = kept)
+	HistoryMaxEntries   int               // Conversations kept in history (0 = history default, negative = no limit)
+	HistorySync         SyncConfig        // Remote for "history sync" (zero = none)
+	Share               ShareConfig       // Service /share uploads conversations to
+	Tools               []ToolConfig      // External tools available in interactive mode
+	Commands            []CommandConfig   // User-defined slash commands of interactive mode
+	Hooks               HooksConfig       // Pre-query and post-response hook commands
+	Presets             map[string]Preset // Preset definitions and overrides from the config file
+	ModelAliases        map[string]string // Short names for models, e.g. "fast" -> "sonar"
+	CustomModels        []string          // Models accepted in addition to AvailableModels
+	RemoteModels        []string          // Models from the cached models endpoint response
+	ModelsURL           string            // Models endpoint or manifest used to refresh RemoteModels
+	AutoPolicy          AutoPolicy        // Models chosen by the auto model (zero fields = defaults)
+	Profile             string            // Config file profile applied (empty = none)
+	ProjectFile         string            // Project config file applied (empty = none)
 }
 
 // ErrAPIKeyNotFound is returned when no API key is available
-var ErrAPIKeyNotFound = errors.New("API key not found. Set PERPLEXITY_API_KEYS or PERPLEXITY_API_KEY environment variable, or use --api-key flag")
+var ErrAPIKeyNotFound = errors.New("API key not found. Run perplexity init, set PERPLEXITY_API_KEYS or PERPLEXITY_API_KEY environment variable, or use --api-key flag")
+
+// ErrInvalidAPIKey is returned when an API key is malformed, wrapping the
+// validation error saying why
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// ErrInvalidTemperature is returned when the temperature is out of range
+var ErrInvalidTemperature = errors.New("temperature must be at least 0 and below 2")
+
+// ErrInvalidTimeout is returned when the timeout is negative
+var ErrInvalidTimeout = errors.New("timeout cannot be negative (use 0 for no timeout)")
+
+// ErrInvalidRetry is returned when the retry settings are out of range
+var ErrInvalidRetry = errors.New("max retries, max server retries and retry backoffs cannot be negative, and the max backoff cannot be below the initial backoff")
+
+// ErrInvalidPenalty is returned when a frequency or presence penalty is out of range
+var ErrInvalidPenalty = errors.New("frequency and presence penalties must be between -2 and 2")
+
+// MaxTopK is the largest accepted top-k value
+const MaxTopK = 2048
+
+// ErrInvalidTopK is returned when top-k is out of range
+var ErrInvalidTopK = errors.New("top-k must be between 0 and 2048")
+
+// ReasoningEfforts lists the accepted reasoning effort levels
+var ReasoningEfforts = []string{"low", "medium", "high"}
+
+// ErrInvalidReasoningEffort is returned when the reasoning effort is not one of ReasoningEfforts
+var ErrInvalidReasoningEffort = errors.New("reasoning effort must be low, medium or high")
+
+// SearchContextSizes lists the accepted search context sizes
+var SearchContextSizes = []string{"low", "medium", "high"}
+
+// ErrInvalidSearchContext is returned when the search context size is not one of SearchContextSizes
+var ErrInvalidSearchContext = errors.New("search context must be low, medium or high")
+
+// SearchRecencies lists the accepted search recency periods
+var SearchRecencies = []string{"hour", "day", "week", "month", "year"}
+
+// ErrInvalidSearchRecency is returned when the search recency is not one of SearchRecencies
+var ErrInvalidSearchRecency = errors.New("search recency must be hour, day, week, month or year")
+
+// Strategies for piped input over the prompt length limit
+const (
+	ChunkStrategyMapReduce = "map-reduce" // Summarize each chunk, then answer over the summaries
+	ChunkStrategyRefine    = "refine"     // Refine a running summary chunk by chunk, then answer over it
+	ChunkStrategyTruncate  = "truncate"   // Answer over the start of the input that fits
+	ChunkStrategyReject    = "reject"     // Fail with "prompt exceeds maximum length"
+)
+
+// ChunkStrategies lists the accepted chunk strategies
+var ChunkStrategies = []string{ChunkStrategyMapReduce, ChunkStrategyRefine, ChunkStrategyTruncate, ChunkStrategyReject}
+
+// ErrInvalidChunkStrategy is returned when the chunk strategy is not one of ChunkStrategies
+var ErrInvalidChunkStrategy = errors.New("chunk strategy must be map-reduce, refine, truncate or reject")
+
+// ErrInvalidChunkTokens is returned when the chunk size is negative
+var ErrInvalidChunkTokens = errors.New("chunk size cannot be negative (use 0 for half the model's context window)")
+
+// ErrInvalidConcurrency is returned when the concurrency is negative
+var ErrInvalidConcurrency = errors.New("concurrency cannot be negative (use 0 for the default)")
+
+// ErrInvalidLocation is returned when the location is not a valid "latitude,longitude" pair
+var ErrInvalidLocation = errors.New(`location must be "latitude,longitude", e.g. "52.52,13.40"`)
+
+// ErrInvalidCountry is returned when the country is not a two-letter code
+var ErrInvalidCountry = errors.New("country must be a two-letter ISO 3166-1 code, e.g. DE")
+
+// ErrImageFiltersWithoutImages is returned when image filters are set but images are not requested
+var ErrImageFiltersWithoutImages = errors.New("image domain and format filters require images to be enabled (--images)")
+
+// LogLevels lists the accepted log levels
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// ErrInvalidLogLevel is returned when the log level is not one of LogLevels
+var ErrInvalidLogLevel = errors.New("log level must be debug, info, warn or error")
+
+// LogFormats lists the accepted log formats
+var LogFormats = []string{"text", "json"}
+
+// ErrInvalidLogFormat is returned when the log format is not one of LogFormats
+var ErrInvalidLogFormat = errors.New("log format must be text or json")
+
+// Citation styles for inline [n] markers
+const (
+	CitationStyleMarkers   = "markers"   // Keep the markers as returned
+	CitationStyleLinks     = "links"     // Link each marker to its source
+	CitationStyleFootnotes = "footnotes" // Turn markers into markdown footnotes
+)
+
+// CitationStyles lists the accepted citation styles
+var CitationStyles = []string{CitationStyleMarkers, CitationStyleLinks, CitationStyleFootnotes}
+
+// ErrInvalidCitationStyle is returned when the citation style is not one of CitationStyles
+var ErrInvalidCitationStyle = errors.New("citation style must be markers, links or footnotes")
+
+// Which of several API keys a run starts with
+const (
+	KeyStrategySticky   = "sticky"   // Always the first key
+	KeyStrategyRandom   = "random"   // A random key, spreading concurrent runs across keys
+	KeyStrategyWeighted = "weighted" // A random key, in proportion to the requests it has left
+)
+
+// KeyStrategies lists the accepted key_strategy values
+var KeyStrategies = []string{KeyStrategySticky, KeyStrategyRandom, KeyStrategyWeighted}
+
+// ErrInvalidKeyStrategy is returned when key_strategy is not one of KeyStrategies
+var ErrInvalidKeyStrategy = errors.New("key strategy must be sticky, random or weighted")
+
+// Where the API key set up by perplexity init is kept
+const (
+	APIKeyStoreConfig  = "config"  // api_key in the config file
+	APIKeyStoreKeyring = "keyring" // The operating system's secret store
+)
+
+// APIKeyStores lists the accepted api_key_store values
+var APIKeyStores = []string{APIKeyStoreConfig, APIKeyStoreKeyring}
+
+// ErrInvalidAPIKeyStore is returned when api_key_store is not one of APIKeyStores
+var ErrInvalidAPIKeyStore = errors.New("API key store must be config or keyring")
+
+// How /resume treats the model and system prompt of a conversation
+const (
+	ResumeSettingsRestore = "restore" // Switch to them, with a note
+	ResumeSettingsAsk     = "ask"     // Ask before switching when they differ
+	ResumeSettingsKeep    = "keep"    // Keep those of the session
+)
+
+// ResumeSettingsModes lists the accepted resume_settings values
+var ResumeSettingsModes = []string{ResumeSettingsRestore, ResumeSettingsAsk, ResumeSettingsKeep}
+
+// ErrInvalidResumeSettings is returned when resume_settings is not one of ResumeSettingsModes
+var ErrInvalidResumeSettings = errors.New("resume settings must be restore, ask or keep")
+
+// Themes lists the accepted markdown rendering themes
+var Themes = []string{"auto", "dark", "light", "dracula", "notty"}
+
+// ErrInvalidTheme is returned when the theme is not one of Themes
+var ErrInvalidTheme = errors.New("theme must be auto, dark, light, dracula or notty")
 
 // ErrNoAvailableKeys is returned when all keys are exhausted
 var ErrNoAvailableKeys = errors.New("all API keys exhausted")
@@ -97,6 +286,17 @@ var CreditExhaustedPatterns = []string{
 	"api key blocked",
 }
 
+// IsReasoningModel reports whether a model accepts the reasoning effort setting
+func IsReasoningModel(model string) bool {
+	return strings.Contains(model, "reasoning") || strings.Contains(model, "deep-research")
+}
+
+// IsDeepResearchModel reports whether a model runs multi-step research, which
+// can take over ten minutes to answer
+func IsDeepResearchModel(model string) bool {
+	return strings.Contains(model, "deep-research")
+}
+
 // ValidateModel checks if the given model is valid
 func ValidateModel(model string) bool {
 	return slices.Contains(AvailableModels, model)
@@ -133,16 +333,46 @@ func GetAPIKeysFromEnv() []string {
 	return nil
 }
 
+// StoredAPIKey returns the API key kept in the keyring if api_key_store is
+// keyring, or else in the config file. It is empty if none is stored.
+func (c *Config) StoredAPIKey() (string, error) {
+	if c.APIKeyStore != APIKeyStoreKeyring {
+		return c.FileAPIKey, nil
+	}
+	key, err := keyring.Get()
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the API key from the keyring: %w", err)
+	}
+	return key, nil
+}
+
 // NewConfig creates a new Config with defaults
 func NewConfig() *Config {
 	return &Config{
 		APIURL:        DefaultAPIURL,
+		ModelsURL:     DefaultModelsURL,
 		Model:         DefaultModel,
 		Timeout:       DefaultTimeout,
+		Retry:         retry.DefaultConfig(),
 		startKeyIndex: -1,
 	}
 }
 
+// ValidateLogging checks the log level and format, which are needed before
+// the rest of the config is validated
+func (c *Config) ValidateLogging() error {
+	if c.LogLevel != "" && !slices.Contains(LogLevels, c.LogLevel) {
+		return fmt.Errorf("%w: got %s", ErrInvalidLogLevel, c.LogLevel)
+	}
+	if c.LogFormat != "" && !slices.Contains(LogFormats, c.LogFormat) {
+		return fmt.Errorf("%w: got %s", ErrInvalidLogFormat, c.LogFormat)
+	}
+	return nil
+}
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	// Load timeout from environment if not already set to non-default
@@ -163,47 +393,207 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.Timeout < 0 {
+		return fmt.Errorf("%w: got %v", ErrInvalidTimeout, c.Timeout)
+	}
+
+	if c.Retry.MaxRetries < 0 || c.Retry.MaxServerRetries < 0 || c.Retry.InitialBackoff < 0 || c.Retry.MaxBackoff < c.Retry.InitialBackoff {
+		return fmt.Errorf("%w: got %d retries, %d server retries, backoff %v, max backoff %v",
+			ErrInvalidRetry, c.Retry.MaxRetries, c.Retry.MaxServerRetries, c.Retry.InitialBackoff, c.Retry.MaxBackoff)
+	}
+
+	if c.Temperature != nil && (!isFinite(*c.Temperature) || *c.Temperature < 0 || *c.Temperature >= 2) {
+		return fmt.Errorf("%w: got %g", ErrInvalidTemperature, *c.Temperature)
+	}
+
+	for _, penalty := range []*float64{c.FrequencyPenalty, c.PresencePenalty} {
+		if penalty != nil && (!isFinite(*penalty) || *penalty < -2 || *penalty > 2) {
+			return fmt.Errorf("%w: got %g", ErrInvalidPenalty, *penalty)
+		}
+	}
+
+	if c.TopK < 0 || c.TopK > MaxTopK {
+		return fmt.Errorf("%w: got %d", ErrInvalidTopK, c.TopK)
+	}
+
+	if c.SearchContext != "" && !slices.Contains(SearchContextSizes, c.SearchContext) {
+		return fmt.Errorf("%w: got %s", ErrInvalidSearchContext, c.SearchContext)
+	}
+
+	if c.SearchRecency != "" && !slices.Contains(SearchRecencies, c.SearchRecency) {
+		return fmt.Errorf("%w: got %s", ErrInvalidSearchRecency, c.SearchRecency)
+	}
+
+	if c.Concurrency < 0 {
+		return fmt.Errorf("%w: got %d", ErrInvalidConcurrency, c.Concurrency)
+	}
+
+	if c.ChunkStrategy != "" && !slices.Contains(ChunkStrategies, c.ChunkStrategy) {
+		return fmt.Errorf("%w: got %s", ErrInvalidChunkStrategy, c.ChunkStrategy)
+	}
+
+	if c.ChunkTokens < 0 {
+		return fmt.Errorf("%w: got %d", ErrInvalidChunkTokens, c.ChunkTokens)
+	}
+
+	if c.Location != "" {
+		if _, _, err := ParseLocation(c.Location); err != nil {
+			return err
+		}
+	}
+
+	if c.Country != "" {
+		if !isCountryCode(c.Country) {
+			return fmt.Errorf("%w: got %s", ErrInvalidCountry, c.Country)
+		}
+		c.Country = strings.ToUpper(c.Country)
+	}
+
+	if !c.Images && (len(c.ImageDomains) > 0 || len(c.ImageFormats) > 0) {
+		return ErrImageFiltersWithoutImages
+	}
+	for i, format := range c.ImageFormats {
+		c.ImageFormats[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(format), "."))
+	}
+
+	if err := c.ValidateLogging(); err != nil {
+		return err
+	}
+
+	if c.CitationStyle != "" && !slices.Contains(CitationStyles, c.CitationStyle) {
+		return fmt.Errorf("%w: got %s", ErrInvalidCitationStyle, c.CitationStyle)
+	}
+
+	if c.KeyStrategy != "" && !slices.Contains(KeyStrategies, c.KeyStrategy) {
+		return fmt.Errorf("%w: got %s", ErrInvalidKeyStrategy, c.KeyStrategy)
+	}
+
+	if c.APIKeyStore != "" && !slices.Contains(APIKeyStores, c.APIKeyStore) {
+		return fmt.Errorf("%w: got %s", ErrInvalidAPIKeyStore, c.APIKeyStore)
+	}
+
+	if c.ResumeSettings != "" && !slices.Contains(ResumeSettingsModes, c.ResumeSettings) {
+		return fmt.Errorf("%w: got %s", ErrInvalidResumeSettings, c.ResumeSettings)
+	}
+
+	if c.Theme != "" && !slices.Contains(Themes, c.Theme) {
+		return fmt.Errorf("%w: got %s", ErrInvalidTheme, c.Theme)
+	}
+
+	if c.ReasoningEffort != "" && !slices.Contains(ReasoningEfforts, c.ReasoningEffort) {
+		return fmt.Errorf("%w: got %s", ErrInvalidReasoningEffort, c.ReasoningEffort)
+	}
+
 	// If API key is provided via flag, use it directly (single key mode)
 	if c.APIKey != "" {
 		// Validate the API key format
 		result := validation.ValidateAPIKey(c.APIKey)
 		if !result.Valid {
-			return fmt.Errorf("invalid API key: %w", result.Error)
+			return fmt.Errorf("%w: %w", ErrInvalidAPIKey, result.Error)
 		}
 		c.APIKeys = []string{c.APIKey}
 		c.CurrentKeyIndex = 0
-		if !ValidateModel(c.Model) {
-			return fmt.Errorf("%w: %s. Available models: %s", ErrInvalidModel, c.Model, GetAvailableModelsString())
-		}
-		return nil
+		return c.validateModel()
 	}
 
-	// Load keys from environment
+	// Load keys from environment, or else the stored key
 	c.APIKeys = GetAPIKeysFromEnv()
 	if len(c.APIKeys) == 0 {
-		return ErrAPIKeyNotFound
+		key, err := c.StoredAPIKey()
+		if err != nil {
+			return err
+		}
+		if key == "" {
+			return ErrAPIKeyNotFound
+		}
+		c.APIKeys = []string{key}
 	}
 
 	// Validate all API keys
 	for i, key := range c.APIKeys {
 		result := validation.ValidateAPIKey(key)
 		if !result.Valid {
-			return fmt.Errorf("invalid API key %d: %w", i+1, result.Error)
+			return fmt.Errorf("%w %d: %w", ErrInvalidAPIKey, i+1, result.Error)
 		}
 	}
 
-	// Random starting key for load balancing across multiple keys.
-	// This distributes requests across keys when multiple CLI instances run concurrently.
-	c.CurrentKeyIndex = rand.IntN(len(c.APIKeys))
+	c.CurrentKeyIndex = c.initialKeyIndex()
 	c.APIKey = c.APIKeys[c.CurrentKeyIndex]
 
-	if !ValidateModel(c.Model) {
-		return fmt.Errorf("%w: %s. Available models: %s", ErrInvalidModel, c.Model, GetAvailableModelsString())
+	return c.validateModel()
+}
+
+// ParseLocation parses a "latitude,longitude" pair
+func ParseLocation(location string) (lat, lon float64, err error) {
+	latStr, lonStr, ok := strings.Cut(location, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: got %s", ErrInvalidLocation, location)
+	}
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	lon, lonErr := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+	if latErr != nil || lonErr != nil || !isFinite(lat) || !isFinite(lon) ||
+		lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("%w: got %s", ErrInvalidLocation, location)
+	}
+	return lat, lon, nil
+}
+
+// Coordinates returns the configured location. ok is false if none is set or it is invalid.
+func (c *Config) Coordinates() (lat, lon float64, ok bool) {
+	if c.Location == "" {
+		return 0, 0, false
 	}
+	lat, lon, err := ParseLocation(c.Location)
+	return lat, lon, err == nil
+}
+
+// isFinite reports whether f is neither NaN nor infinite, which range checks
+// alone let through
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// isCountryCode checks that code is two ASCII letters
+func isCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range strings.ToUpper(code) {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
 
+// validateModel checks that every model alias points to a known model, then
+// resolves the model alias and checks the result is a known model. Aliases
+// are checked here rather than when the config file is loaded so that they
+// may point to models fetched from the provider.
+func (c *Config) validateModel() error {
+	for _, alias := range c.GetModelAliasNames() {
+		if target := c.ModelAliases[alias]; !c.IsValidModel(target) {
+			return fmt.Errorf("%w: model alias %q points to unknown model %q", ErrInvalidModel, alias, target)
+		}
+	}
+	c.Model = c.ResolveModel(c.Model)
+	if !c.IsValidModel(c.Model) {
+		return fmt.Errorf("%w: %s. Available models: %s", ErrInvalidModel, c.Model, c.GetModelsString())
+	}
+	if c.Model == AutoModel {
+		return c.validateAutoPolicy()
+	}
 	return nil
 }
 
+// GetSystemPrompt returns the system prompt for new conversations
+func (c *Config) GetSystemPrompt() string {
+	if c.SystemPrompt != "" {
+		return c.SystemPrompt
+	}
+	return DefaultSystemMessage
+}
+
 // RotateKey moves to the next available API key, wrapping around to try all keys
 // Returns the new key or error if all keys have been tried
 func (c *Config) RotateKey() (string, error) {
@@ -238,3 +628,44 @@ func (c *Config) ResetKeyRotation() {
 func (c *Config) GetKeyCount() int {
 	return len(c.APIKeys)
 }
+
+// MaskKey hides an API key for display, keeping only its last four characters
+func MaskKey(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return "****" + key[len(key)-4:]
+}
+
+// Redacted returns a copy of the config with API keys, passwords, tokens and
+// URL credentials masked, safe to include in diagnostics and bug reports
+func (c *Config) Redacted() Config {
+	redacted := *c
+	redacted.APIKey = MaskKey(c.APIKey)
+	redacted.APIKeys = make([]string, len(c.APIKeys))
+	for i, key := range c.APIKeys {
+		redacted.APIKeys[i] = MaskKey(key)
+	}
+	redacted.FileAPIKey = MaskKey(c.FileAPIKey)
+	redacted.HistorySync.Password = MaskKey(c.HistorySync.Password)
+	redacted.Share.GistToken = MaskKey(c.Share.GistToken)
+
+	redacted.APIURL = RedactURL(c.APIURL)
+	redacted.ModelsURL = RedactURL(c.ModelsURL)
+	redacted.HistorySync.Git = RedactURL(c.HistorySync.Git)
+	redacted.HistorySync.WebDAV = RedactURL(c.HistorySync.WebDAV)
+	redacted.Share.PasteURL = RedactURL(c.Share.PasteURL)
+	return redacted
+}
+
+// RedactURL masks the user information of rawURL, such as the token in
+// https://token@github.com/user/repo.git. Strings that are not URLs with a
+// scheme, like scp-style git remotes, are returned unchanged.
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	u.User = nil
+	return strings.Replace(u.String(), "//", "//****@", 1)
+}
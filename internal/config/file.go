@@ -0,0 +1,489 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ConfigFileName is the name of the config file
+	ConfigFileName = "config.yaml"
+	// EnvConfigPath is the environment variable for a custom config file path
+	EnvConfigPath = "PERPLEXITY_CONFIG"
+	// EnvSyncPassword is the environment variable for the history sync password
+	EnvSyncPassword = "PERPLEXITY_SYNC_PASSWORD"
+)
+
+// ToolConfig describes an external command the assistant may call in interactive mode.
+// The model-supplied input is passed on stdin and in the PERPLEXITY_TOOL_INPUT env var.
+type ToolConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Command     string `yaml:"command"`
+	Timeout     int    `yaml:"timeout,omitempty"` // Seconds (0 = default)
+}
+
+// CommandConfig is a user-defined slash command of interactive mode. It sends
+// Prompt with {input} replaced by the command's arguments, or runs Command
+// and sends its output. Arguments are passed to Command on stdin and in the
+// PERPLEXITY_COMMAND_INPUT env var.
+type CommandConfig struct {
+	Name        string `yaml:"name"` // Without the leading slash
+	Description string `yaml:"description,omitempty"`
+	Prompt      string `yaml:"prompt,omitempty"`
+	Command     string `yaml:"command,omitempty"`
+	Timeout     int    `yaml:"timeout,omitempty"` // Seconds (0 = default)
+}
+
+// HooksConfig lists external executables run around each request.
+// Each hook receives a JSON event on stdin; pre-query hooks may print a modified
+// event on stdout to rewrite the messages, or exit non-zero to abort the query.
+type HooksConfig struct {
+	PreQuery     []string `yaml:"pre_query,omitempty"`
+	PostResponse []string `yaml:"post_response,omitempty"`
+	Timeout      int      `yaml:"timeout,omitempty"` // Seconds (0 = default)
+}
+
+// SyncConfig selects the remote that "history sync" copies conversations
+// to: a git repository or a file on a WebDAV server. The WebDAV password may
+// also be given in the EnvSyncPassword env var.
+type SyncConfig struct {
+	Git      string `yaml:"git,omitempty"`    // Repository URL
+	WebDAV   string `yaml:"webdav,omitempty"` // URL of the history file
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+}
+
+// ShareConfig selects where /share uploads conversations: a paste service
+// when PasteURL is set, or otherwise a GitHub gist created with GistToken
+// (or the GITHUB_TOKEN env var)
+type ShareConfig struct {
+	GistToken  string `yaml:"gist_token,omitempty"`
+	Public     bool   `yaml:"public,omitempty"`      // Create public gists instead of secret ones
+	PasteURL   string `yaml:"paste_url,omitempty"`   // e.g. https://paste.rs/
+	PasteField string `yaml:"paste_field,omitempty"` // Multipart form field of the paste (empty = raw body)
+}
+
+// FileConfig represents the settings that can be stored in the config file.
+// Zero values mean "not set" and leave the built-in defaults untouched.
+type FileConfig struct {
+	APIKey              string            `yaml:"api_key,omitempty"`
+	APIKeyStore         string            `yaml:"api_key_store,omitempty"` // config or keyring
+	Model               string            `yaml:"model,omitempty"`
+	SystemPrompt        string            `yaml:"system_prompt,omitempty"`
+	Temperature         *float64          `yaml:"temperature,omitempty"`
+	FrequencyPenalty    *float64          `yaml:"frequency_penalty,omitempty"`
+	PresencePenalty     *float64          `yaml:"presence_penalty,omitempty"`
+	TopK                int               `yaml:"top_k,omitempty"`
+	ReasoningEffort     string            `yaml:"reasoning_effort,omitempty"`
+	SearchContext       string            `yaml:"search_context,omitempty"`
+	Location            string            `yaml:"location,omitempty"` // "latitude,longitude"
+	Country             string            `yaml:"country,omitempty"`
+	Timeout             int               `yaml:"timeout,omitempty"`    // Seconds
+	RateLimit           float64           `yaml:"rate_limit,omitempty"` // Requests per minute
+	Concurrency         int               `yaml:"concurrency,omitempty"`
+	ChunkTokens         int               `yaml:"chunk_tokens,omitempty"`
+	ChunkStrategy       string            `yaml:"chunk_strategy,omitempty"`
+	MaxRetries          *int              `yaml:"max_retries,omitempty"`
+	MaxServerRetries    *int              `yaml:"max_server_retries,omitempty"`
+	PersistKeyIndex     bool              `yaml:"persist_key_index,omitempty"`
+	KeyStrategy         string            `yaml:"key_strategy,omitempty"`
+	RetryBackoff        time.Duration     `yaml:"retry_backoff,omitempty"`     // e.g. 500ms
+	RetryMaxBackoff     time.Duration     `yaml:"retry_max_backoff,omitempty"` // e.g. 30s
+	Usage               bool              `yaml:"usage,omitempty"`
+	Citations           bool              `yaml:"citations,omitempty"`
+	Stream              bool              `yaml:"stream,omitempty"`
+	Render              bool              `yaml:"render,omitempty"`
+	Theme               string            `yaml:"theme,omitempty"`
+	HideThinking        bool              `yaml:"hide_thinking,omitempty"`
+	HideCitationMarkers bool              `yaml:"hide_citation_markers,omitempty"`
+	CitationStyle       string            `yaml:"citation_style,omitempty"`
+	RelatedQuestions    bool              `yaml:"related_questions,omitempty"`
+	ShellInterpolation  bool              `yaml:"shell_interpolation,omitempty"`
+	AutoTitle           bool              `yaml:"auto_title,omitempty"`
+	ResumeSettings      string            `yaml:"resume_settings,omitempty"`
+	Images              bool              `yaml:"images,omitempty"`
+	ImageDomains        []string          `yaml:"image_domains,omitempty"`
+	ImageFormats        []string          `yaml:"image_formats,omitempty"`
+	Quiet               bool              `yaml:"quiet,omitempty"`
+	LogLevel            string            `yaml:"log_level,omitempty"`
+	LogFormat           string            `yaml:"log_format,omitempty"`
+	HistoryRetention    string            `yaml:"history_retention,omitempty"` // e.g. 90d, 12w or 720h
+	HistoryMaxEntries   int               `yaml:"history_max_entries,omitempty"`
+	HistorySync         SyncConfig        `yaml:"history_sync,omitempty"`
+	Share               ShareConfig       `yaml:"share,omitempty"`
+	Tools               []ToolConfig      `yaml:"tools,omitempty"`
+	Commands            []CommandConfig   `yaml:"commands,omitempty"`
+	Hooks               HooksConfig       `yaml:"hooks,omitempty"`
+	Presets             map[string]Preset `yaml:"presets,omitempty"`
+	ModelAliases        map[string]string `yaml:"model_aliases,omitempty"`
+	CustomModels        []string          `yaml:"custom_models,omitempty"`
+	ModelsURL           string            `yaml:"models_url,omitempty"`
+	AutoModel           AutoPolicy        `yaml:"auto_model,omitempty"`
+
+	// Profile selects one of Profiles, unless PERPLEXITY_PROFILE is set
+	Profile  string                `yaml:"profile,omitempty"`
+	Profiles map[string]FileConfig `yaml:"profiles,omitempty"` // Sections overriding the settings above
+}
+
+// GetConfigPath returns the path to the config file
+func GetConfigPath() string {
+	if customPath := os.Getenv(EnvConfigPath); customPath != "" {
+		return customPath
+	}
+	dir := GetConfigDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, ConfigFileName)
+}
+
+// LoadFileConfig reads the config file at path.
+// A missing file is not an error and yields an empty FileConfig.
+func LoadFileConfig(path string) (*FileConfig, error) {
+	fc := &FileConfig{}
+	if path == "" {
+		return fc, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fc, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, fc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if err := fc.check("config file"); err != nil {
+		return nil, err
+	}
+	for name, profile := range fc.Profiles {
+		where := fmt.Sprintf("profile %s of the config file", name)
+		if profile.Profile != "" || len(profile.Profiles) > 0 {
+			return nil, fmt.Errorf("invalid %s: profiles cannot select or define profiles", where)
+		}
+		if err := profile.check(where); err != nil {
+			return nil, err
+		}
+		fc.Profiles[name] = profile
+	}
+
+	return fc, nil
+}
+
+// SetFileValues sets top-level settings of the config file at path, creating
+// it if needed, and removes those set to "". Comments and other settings are
+// kept. As it may hold API keys, the file is made readable only by its owner.
+func SetFileValues(path string, values map[string]string) error {
+	if path == "" {
+		return fmt.Errorf("config file path not available")
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		// An empty file, or one with only comments
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("failed to update config file %s: it is not a mapping of settings", path)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		setMappingValue(root, key, values[key])
+	}
+
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	// The permission of an existing file is restricted before the secret is
+	// written to it; WriteFile only applies it to a file it creates
+	if err := os.Chmod(path, 0600); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to restrict config file permissions: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// setMappingValue sets key to a string value in a YAML mapping, or removes it
+// if value is ""
+func setMappingValue(mapping *yaml.Node, key, value string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		if value == "" {
+			mapping.Content = slices.Delete(mapping.Content, i, i+2)
+		} else {
+			old := mapping.Content[i+1]
+			mapping.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, LineComment: old.LineComment}
+		}
+		return
+	}
+	if value != "" {
+		mapping.Content = append(mapping.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+	}
+}
+
+// check validates the settings of a config file section, described by where
+// in errors, and normalizes its command names
+func (fc *FileConfig) check(where string) error {
+	if _, err := ParseAge(fc.HistoryRetention); err != nil {
+		return fmt.Errorf("invalid history_retention in %s: %w", where, err)
+	}
+
+	if fc.HistorySync.Git != "" && fc.HistorySync.WebDAV != "" {
+		return fmt.Errorf("invalid history_sync in %s: set either git or webdav", where)
+	}
+
+	if fc.Share.GistToken != "" && fc.Share.PasteURL != "" {
+		return fmt.Errorf("invalid share in %s: set either gist_token or paste_url", where)
+	}
+
+	for i, tool := range fc.Tools {
+		if tool.Name == "" || tool.Command == "" {
+			return fmt.Errorf("invalid tool %d in %s: name and command are required", i+1, where)
+		}
+	}
+	for i := range fc.Commands {
+		command := &fc.Commands[i]
+		command.Name = strings.ToLower(strings.TrimPrefix(command.Name, "/"))
+		if !isCommandName(command.Name) {
+			return fmt.Errorf("invalid command %d in %s: name must be a single word of letters, digits, '-' or '_'", i+1, where)
+		}
+		if (command.Prompt == "") == (command.Command == "") {
+			return fmt.Errorf("invalid command /%s in %s: set either prompt or command", command.Name, where)
+		}
+	}
+	return nil
+}
+
+// ParseAge parses an age such as "90d", "2w" or "36h": a whole number of
+// days or weeks, or a Go duration. An empty age is zero.
+func ParseAge(age string) (time.Duration, error) {
+	age = strings.TrimSpace(age)
+	if age == "" {
+		return 0, nil
+	}
+
+	var d time.Duration
+	unit := 24 * time.Hour
+	switch {
+	case strings.HasSuffix(age, "w"):
+		unit *= 7
+		fallthrough
+	case strings.HasSuffix(age, "d"):
+		n, err := strconv.Atoi(age[:len(age)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q: use e.g. 90d, 12w or 720h", age)
+		}
+		d = time.Duration(n) * unit
+	default:
+		var err error
+		if d, err = time.ParseDuration(age); err != nil {
+			return 0, fmt.Errorf("invalid age %q: use e.g. 90d, 12w or 720h", age)
+		}
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid age %q: cannot be negative", age)
+	}
+	return d, nil
+}
+
+// isCommandName checks that a slash command name is a single word
+func isCommandName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// ApplyFile copies the values set in the config file onto the config.
+// It must be called before command-line flags are parsed so flags take precedence.
+func (c *Config) ApplyFile(fc *FileConfig) {
+	if fc == nil {
+		return
+	}
+	if fc.APIKey != "" {
+		c.FileAPIKey = strings.TrimSpace(fc.APIKey)
+	}
+	if fc.APIKeyStore != "" {
+		c.APIKeyStore = fc.APIKeyStore
+	}
+	if fc.Model != "" {
+		c.Model = fc.Model
+	}
+	if prompt := strings.TrimSpace(fc.SystemPrompt); prompt != "" {
+		c.SystemPrompt = prompt
+	}
+	if fc.Temperature != nil {
+		c.Temperature = fc.Temperature
+	}
+	if fc.FrequencyPenalty != nil {
+		c.FrequencyPenalty = fc.FrequencyPenalty
+	}
+	if fc.PresencePenalty != nil {
+		c.PresencePenalty = fc.PresencePenalty
+	}
+	if fc.TopK > 0 {
+		c.TopK = fc.TopK
+	}
+	if fc.CitationStyle != "" {
+		c.CitationStyle = fc.CitationStyle
+	}
+	if fc.ReasoningEffort != "" {
+		c.ReasoningEffort = fc.ReasoningEffort
+	}
+	if fc.SearchContext != "" {
+		c.SearchContext = fc.SearchContext
+	}
+	if fc.Location != "" {
+		c.Location = fc.Location
+	}
+	if fc.Country != "" {
+		c.Country = fc.Country
+	}
+	if fc.Timeout > 0 {
+		c.Timeout = time.Duration(fc.Timeout) * time.Second
+	}
+	if fc.RateLimit > 0 {
+		c.RateLimit = fc.RateLimit
+	}
+	if fc.Concurrency > 0 {
+		c.Concurrency = fc.Concurrency
+	}
+	if fc.ChunkTokens > 0 {
+		c.ChunkTokens = fc.ChunkTokens
+	}
+	if fc.ChunkStrategy != "" {
+		c.ChunkStrategy = fc.ChunkStrategy
+	}
+	if fc.MaxRetries != nil {
+		c.Retry.MaxRetries = *fc.MaxRetries
+	}
+	if fc.MaxServerRetries != nil {
+		c.Retry.MaxServerRetries = *fc.MaxServerRetries
+	}
+	c.PersistKeyIndex = c.PersistKeyIndex || fc.PersistKeyIndex
+	if fc.KeyStrategy != "" {
+		c.KeyStrategy = fc.KeyStrategy
+	}
+	if fc.RetryBackoff > 0 {
+		c.Retry.InitialBackoff = fc.RetryBackoff
+	}
+	if fc.RetryMaxBackoff > 0 {
+		c.Retry.MaxBackoff = fc.RetryMaxBackoff
+	}
+	c.Usage = c.Usage || fc.Usage
+	c.Citations = c.Citations || fc.Citations
+	c.Stream = c.Stream || fc.Stream
+	c.Render = c.Render || fc.Render
+	if fc.Theme != "" {
+		c.Theme = fc.Theme
+	}
+	c.HideThinking = c.HideThinking || fc.HideThinking
+	c.HideCitationMarkers = c.HideCitationMarkers || fc.HideCitationMarkers
+	c.RelatedQuestions = c.RelatedQuestions || fc.RelatedQuestions
+	c.ShellInterpolation = c.ShellInterpolation || fc.ShellInterpolation
+	c.AutoTitle = c.AutoTitle || fc.AutoTitle
+	if fc.ResumeSettings != "" {
+		c.ResumeSettings = fc.ResumeSettings
+	}
+	c.Images = c.Images || fc.Images
+	if len(fc.ImageDomains) > 0 {
+		c.ImageDomains = fc.ImageDomains
+	}
+	if len(fc.ImageFormats) > 0 {
+		c.ImageFormats = fc.ImageFormats
+	}
+	c.Quiet = c.Quiet || fc.Quiet
+	if fc.LogLevel != "" {
+		c.LogLevel = fc.LogLevel
+	}
+	if fc.LogFormat != "" {
+		c.LogFormat = fc.LogFormat
+	}
+	if retention, err := ParseAge(fc.HistoryRetention); err == nil && retention > 0 {
+		c.HistoryRetention = retention
+	}
+	if fc.HistoryMaxEntries != 0 {
+		c.HistoryMaxEntries = fc.HistoryMaxEntries
+	}
+	if fc.HistorySync != (SyncConfig{}) {
+		c.HistorySync = fc.HistorySync
+	}
+	if fc.Share != (ShareConfig{}) {
+		c.Share = fc.Share
+	}
+	if len(fc.Tools) > 0 {
+		c.Tools = fc.Tools
+	}
+	if len(fc.Commands) > 0 {
+		c.Commands = fc.Commands
+	}
+	if len(fc.Hooks.PreQuery) > 0 || len(fc.Hooks.PostResponse) > 0 {
+		c.Hooks = fc.Hooks
+	}
+	// Presets and aliases of later sections add to those of earlier ones
+	for name, preset := range fc.Presets {
+		if c.Presets == nil {
+			c.Presets = make(map[string]Preset)
+		}
+		c.Presets[name] = preset
+	}
+	for name, model := range fc.ModelAliases {
+		if c.ModelAliases == nil {
+			c.ModelAliases = make(map[string]string)
+		}
+		c.ModelAliases[name] = model
+	}
+	if len(fc.CustomModels) > 0 {
+		c.CustomModels = fc.CustomModels
+	}
+	if fc.ModelsURL != "" {
+		c.ModelsURL = fc.ModelsURL
+	}
+	if fc.AutoModel != (AutoPolicy{}) {
+		c.AutoPolicy = fc.AutoModel
+	}
+}
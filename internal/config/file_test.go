@@ -0,0 +1,315 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/retry"
+)
+
+func TestLoadFileConfig(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		fc, err := LoadFileConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+		if err != nil {
+			t.Fatalf("LoadFileConfig() error = %v, want nil", err)
+		}
+		if fc == nil {
+			t.Fatal("LoadFileConfig() returned nil config")
+		}
+	})
+
+	t.Run("valid file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ConfigFileName)
+		data := `model: sonar
+timeout: 60
+max_retries: 0
+retry_backoff: 2s
+citations: true
+tools:
+  - name: weather
+    description: Get the weather
+    command: curl -s wttr.in
+`
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		fc, err := LoadFileConfig(path)
+		if err != nil {
+			t.Fatalf("LoadFileConfig() error = %v", err)
+		}
+		if fc.Model != "sonar" {
+			t.Errorf("Model = %q, want %q", fc.Model, "sonar")
+		}
+		if fc.Timeout != 60 {
+			t.Errorf("Timeout = %d, want 60", fc.Timeout)
+		}
+		if fc.MaxRetries == nil || *fc.MaxRetries != 0 {
+			t.Errorf("MaxRetries = %v, want 0", fc.MaxRetries)
+		}
+		if fc.RetryBackoff != 2*time.Second {
+			t.Errorf("RetryBackoff = %v, want 2s", fc.RetryBackoff)
+		}
+		if !fc.Citations {
+			t.Error("Citations should be true")
+		}
+		if len(fc.Tools) != 1 || fc.Tools[0].Name != "weather" {
+			t.Errorf("Tools = %+v, want one weather tool", fc.Tools)
+		}
+	})
+
+	t.Run("invalid yaml", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ConfigFileName)
+		if err := os.WriteFile(path, []byte("model: [unclosed"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFileConfig(path); err == nil {
+			t.Error("LoadFileConfig() should fail on invalid YAML")
+		}
+	})
+
+	t.Run("tool without command", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ConfigFileName)
+		if err := os.WriteFile(path, []byte("tools:\n  - name: broken\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFileConfig(path); err == nil {
+			t.Error("LoadFileConfig() should reject tools without a command")
+		}
+	})
+
+	t.Run("commands", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ConfigFileName)
+		data := `commands:
+  - name: /Review
+    description: Review a diff
+    prompt: "Review this diff: {input}"
+  - name: standup
+    command: git log --since=yesterday --oneline
+`
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		fc, err := LoadFileConfig(path)
+		if err != nil {
+			t.Fatalf("LoadFileConfig() error = %v", err)
+		}
+		if len(fc.Commands) != 2 || fc.Commands[0].Name != "review" || fc.Commands[1].Command == "" {
+			t.Errorf("Commands = %+v, want review and standup", fc.Commands)
+		}
+	})
+
+	t.Run("invalid commands", func(t *testing.T) {
+		for _, data := range []string{
+			"commands:\n  - name: two words\n    prompt: hi\n",
+			"commands:\n  - name: empty\n",
+			"commands:\n  - name: both\n    prompt: hi\n    command: echo hi\n",
+		} {
+			path := filepath.Join(t.TempDir(), ConfigFileName)
+			if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadFileConfig(path); err == nil {
+				t.Errorf("LoadFileConfig(%q) should fail", data)
+			}
+		}
+	})
+
+	t.Run("invalid history retention", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ConfigFileName)
+		if err := os.WriteFile(path, []byte("history_retention: 3 months\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFileConfig(path); err == nil || !strings.Contains(err.Error(), "history_retention") {
+			t.Errorf("LoadFileConfig() error = %v, want a history_retention error", err)
+		}
+	})
+
+	t.Run("two sync remotes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ConfigFileName)
+		data := "history_sync:\n  git: git@example.com:me/history.git\n  webdav: https://dav.example.com/history.json\n"
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFileConfig(path); err == nil || !strings.Contains(err.Error(), "history_sync") {
+			t.Errorf("LoadFileConfig() error = %v, want a history_sync error", err)
+		}
+	})
+
+	t.Run("two share services", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ConfigFileName)
+		data := "share:\n  gist_token: ghp_token\n  paste_url: https://paste.rs/\n"
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFileConfig(path); err == nil || !strings.Contains(err.Error(), "share") {
+			t.Errorf("LoadFileConfig() error = %v, want a share error", err)
+		}
+	})
+
+	t.Run("model aliases", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ConfigFileName)
+		data := `custom_models: [sonar-ultra]
+model_aliases:
+  fast: sonar
+  next: sonar-ultra
+`
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		fc, err := LoadFileConfig(path)
+		if err != nil {
+			t.Fatalf("LoadFileConfig() error = %v", err)
+		}
+		if fc.ModelAliases["next"] != "sonar-ultra" || len(fc.CustomModels) != 1 {
+			t.Errorf("ModelAliases = %v, CustomModels = %v", fc.ModelAliases, fc.CustomModels)
+		}
+	})
+}
+
+func TestApplyFile(t *testing.T) {
+	cfg := NewConfig()
+	cfg.ApplyFile(&FileConfig{
+		Model:        "sonar",
+		SystemPrompt: "Answer in German.\n",
+		Timeout:      30,
+		Citations:    true,
+		Tools:        []ToolConfig{{Name: "date", Command: "date"}},
+
+		RelatedQuestions:   true,
+		ShellInterpolation: true,
+		AutoTitle:          true,
+		MaxRetries:         new(int),
+		MaxServerRetries:   new(int),
+		RetryMaxBackoff:    5 * time.Second,
+		HistoryRetention:   "90d",
+		HistoryMaxEntries:  500,
+		ChunkTokens:        8000,
+		ChunkStrategy:      ChunkStrategyRefine,
+	})
+
+	if cfg.Model != "sonar" {
+		t.Errorf("Model = %q, want %q", cfg.Model, "sonar")
+	}
+	if cfg.GetSystemPrompt() != "Answer in German." {
+		t.Errorf("GetSystemPrompt() = %q, want the configured prompt", cfg.GetSystemPrompt())
+	}
+	if cfg.Timeout != 30*time.Second {
+		t.Errorf("Timeout = %v, want 30s", cfg.Timeout)
+	}
+	if !cfg.Citations {
+		t.Error("Citations should be enabled")
+	}
+	if !cfg.RelatedQuestions {
+		t.Error("RelatedQuestions should be enabled")
+	}
+	if !cfg.ShellInterpolation {
+		t.Error("ShellInterpolation should be enabled")
+	}
+	if !cfg.AutoTitle {
+		t.Error("AutoTitle should be enabled")
+	}
+	if cfg.HistoryRetention != 90*24*time.Hour || cfg.HistoryMaxEntries != 500 {
+		t.Errorf("HistoryRetention = %v, HistoryMaxEntries = %d, want 90 days and 500", cfg.HistoryRetention, cfg.HistoryMaxEntries)
+	}
+	if cfg.ChunkTokens != 8000 || cfg.ChunkStrategy != ChunkStrategyRefine {
+		t.Errorf("ChunkTokens = %d, ChunkStrategy = %q, want 8000 and refine", cfg.ChunkTokens, cfg.ChunkStrategy)
+	}
+	if len(cfg.Tools) != 1 {
+		t.Errorf("Tools count = %d, want 1", len(cfg.Tools))
+	}
+	if cfg.Retry.MaxRetries != 0 || cfg.Retry.MaxServerRetries != 0 || cfg.Retry.MaxBackoff != 5*time.Second {
+		t.Errorf("Retry = %+v, want no retries and a 5s max backoff", cfg.Retry)
+	}
+	if cfg.Retry.InitialBackoff != retry.DefaultConfig().InitialBackoff {
+		t.Errorf("Retry.InitialBackoff = %v, want default", cfg.Retry.InitialBackoff)
+	}
+
+	// Nil and empty configs leave defaults untouched
+	cfg = NewConfig()
+	cfg.ApplyFile(nil)
+	cfg.ApplyFile(&FileConfig{})
+	if cfg.Model != DefaultModel || cfg.Timeout != DefaultTimeout || cfg.Retry != retry.DefaultConfig() {
+		t.Error("ApplyFile with empty config should keep defaults")
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		age     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"90d", 90 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"36h", 36 * time.Hour, false},
+		{"d", 0, true},
+		{"1.5d", 0, true},
+		{"-1d", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAge(tt.age)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseAge(%q) = %v, %v, want %v, error %v", tt.age, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGetConfigPath(t *testing.T) {
+	orig := os.Getenv(EnvConfigPath)
+	defer os.Setenv(EnvConfigPath, orig)
+
+	os.Setenv(EnvConfigPath, "/tmp/custom.yaml")
+	if got := GetConfigPath(); got != "/tmp/custom.yaml" {
+		t.Errorf("GetConfigPath() = %q, want custom path", got)
+	}
+
+	os.Setenv(EnvConfigPath, "")
+	if got := GetConfigPath(); filepath.Base(got) != ConfigFileName {
+		t.Errorf("GetConfigPath() = %q, want file named %s", got, ConfigFileName)
+	}
+}
+
+func TestSetFileValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "perplexity-cli", ConfigFileName)
+
+	// A missing file is created
+	if err := SetFileValues(path, map[string]string{"model": "sonar"}); err != nil {
+		t.Fatalf("SetFileValues() error = %v", err)
+	}
+	fc, err := LoadFileConfig(path)
+	if err != nil || fc.Model != "sonar" {
+		t.Fatalf("LoadFileConfig() = %+v, %v, want the model set", fc, err)
+	}
+
+	if err := os.WriteFile(path, []byte("# My settings\nmodel: sonar # fast\nusage: true\napi_key: pplx-old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetFileValues(path, map[string]string{"model": "sonar-pro", "api_key": "", "api_key_store": APIKeyStoreKeyring}); err != nil {
+		t.Fatalf("SetFileValues() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# My settings\nmodel: sonar-pro # fast\nusage: true\napi_key_store: keyring\n"
+	if string(data) != want {
+		t.Errorf("config file = %q, want %q", data, want)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("config file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	if err := os.WriteFile(path, []byte("- not\n- settings\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetFileValues(path, map[string]string{"model": "sonar"}); err == nil {
+		t.Error("SetFileValues() should refuse a file that is not a mapping")
+	}
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// KeyStateFileName is the name of the file recording the last working API key
+	KeyStateFileName = "key-state.json"
+	// EnvKeyStatePath is the environment variable for a custom key state path
+	EnvKeyStatePath = "PERPLEXITY_KEY_STATE"
+	// KeyQuotaTTL is how long the remaining requests of a key are trusted
+	KeyQuotaTTL = time.Hour
+)
+
+// KeyState records the index of the API key that last served a request, so
+// the next run can start from it instead of a key known to fail, and the
+// requests each key has left, for the weighted key strategy
+type KeyState struct {
+	Index int `json:"index"` // Last working key (-1 = none)
+	// Keys identifies the key list the state belongs to, without storing the
+	// keys. A saved state is ignored once the keys change.
+	Keys   string     `json:"keys"`
+	Quotas []KeyQuota `json:"quotas,omitempty"` // By key index
+}
+
+// KeyQuota is the number of requests a key had left when last seen
+type KeyQuota struct {
+	Remaining int       `json:"remaining"`
+	Time      time.Time `json:"time,omitzero"` // When it was seen (zero = unknown)
+}
+
+// GetKeyStatePath returns the path to the key state file
+func GetKeyStatePath() string {
+	if customPath := os.Getenv(EnvKeyStatePath); customPath != "" {
+		return customPath
+	}
+	stateDir := GetStateDir()
+	if stateDir == "" {
+		return ""
+	}
+	return filepath.Join(stateDir, KeyStateFileName)
+}
+
+// keysFingerprint identifies a list of API keys by a hash of them
+func keysFingerprint(keys []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// LoadKeyState returns the state saved for keys, or an empty one if none was
+// saved for them
+func LoadKeyState(path string, keys []string) KeyState {
+	state := KeyState{Index: -1, Keys: keysFingerprint(keys)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state
+	}
+	var saved KeyState
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Keys != state.Keys {
+		return state
+	}
+	if saved.Index >= 0 && saved.Index < len(keys) {
+		state.Index = saved.Index
+	}
+	if len(saved.Quotas) == len(keys) {
+		state.Quotas = saved.Quotas
+	}
+	return state
+}
+
+// SaveKeyState writes state
+func SaveKeyState(path string, state KeyState) error {
+	if path == "" {
+		return fmt.Errorf("key state path not available")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create key state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal key state: %w", err)
+	}
+	if err := replaceFile(path, data); err != nil {
+		return fmt.Errorf("failed to write key state: %w", err)
+	}
+	return nil
+}
+
+// replaceFile replaces the file at path with data by renaming a temporary
+// file over it, so that concurrent runs never read it half written. The
+// file is only readable by the user.
+func replaceFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// LoadKeyIndex returns the saved index of the last working key among keys.
+// ok is false if none was saved for these keys.
+func LoadKeyIndex(path string, keys []string) (index int, ok bool) {
+	state := LoadKeyState(path, keys)
+	return state.Index, state.Index >= 0
+}
+
+// SaveKeyIndex records index as the last working key among keys
+func SaveKeyIndex(path string, keys []string, index int) error {
+	state := LoadKeyState(path, keys)
+	state.Index = index
+	return SaveKeyState(path, state)
+}
+
+// SaveKeyQuota records the requests the key at index had left at time now
+func SaveKeyQuota(path string, keys []string, index, remaining int, now time.Time) error {
+	state := LoadKeyState(path, keys)
+	if len(state.Quotas) != len(keys) {
+		state.Quotas = make([]KeyQuota, len(keys))
+	}
+	state.Quotas[index] = KeyQuota{Remaining: remaining, Time: now}
+	return SaveKeyState(path, state)
+}
+
+// weightedIndex picks one of n keys at random, in proportion to the requests
+// each has left as of now. Keys without a recent count weigh as much as the
+// average known one; if no key is known to have requests left, all weigh the same.
+func (s KeyState) weightedIndex(n int, now time.Time) int {
+	weights := make([]float64, n)
+	known, sum := 0, 0.0
+	for i := range weights {
+		weights[i] = -1 // Unknown
+		if i < len(s.Quotas) && !s.Quotas[i].Time.IsZero() && now.Sub(s.Quotas[i].Time) < KeyQuotaTTL {
+			weights[i] = float64(max(s.Quotas[i].Remaining, 0))
+			known++
+			sum += weights[i]
+		}
+	}
+	if known == 0 || sum == 0 {
+		return rand.IntN(n)
+	}
+
+	unknown := sum / float64(known)
+	total := 0.0
+	for i, w := range weights {
+		if w < 0 {
+			weights[i] = unknown
+		}
+		total += weights[i]
+	}
+	r := rand.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			return i
+		}
+		r -= w
+	}
+	return n - 1
+}
+
+// initialKeyIndex returns the index of the key a run starts with: the last
+// working one if saved, or else the one the key strategy picks
+func (c *Config) initialKeyIndex() int {
+	c.savedKeyIndex = -1
+	var state KeyState
+	if c.PersistKeyIndex || c.KeyStrategy == KeyStrategyWeighted {
+		state = LoadKeyState(GetKeyStatePath(), c.APIKeys)
+	}
+	if c.PersistKeyIndex && state.Index >= 0 {
+		c.savedKeyIndex = state.Index
+		return state.Index
+	}
+
+	switch c.KeyStrategy {
+	case KeyStrategySticky:
+		return 0
+	case KeyStrategyWeighted:
+		return state.weightedIndex(len(c.APIKeys), time.Now())
+	}
+	// Random starting key for load balancing across multiple keys.
+	// This distributes requests across keys when multiple CLI instances run concurrently.
+	return rand.IntN(len(c.APIKeys))
+}
+
+// SaveWorkingKey records the current key as the last working one, if
+// persist_key_index is set and it changed. Only lists of several keys are recorded.
+func (c *Config) SaveWorkingKey() error {
+	if !c.PersistKeyIndex || len(c.APIKeys) <= 1 || c.CurrentKeyIndex == c.savedKeyIndex {
+		return nil
+	}
+	if err := SaveKeyIndex(GetKeyStatePath(), c.APIKeys, c.CurrentKeyIndex); err != nil {
+		return err
+	}
+	c.savedKeyIndex = c.CurrentKeyIndex
+	return nil
+}
+
+// RecordKeyQuota records the requests the key at index has left, for the
+// weighted key strategy. Nothing is recorded with other strategies or a single key.
+func (c *Config) RecordKeyQuota(index, remaining int) error {
+	if c.KeyStrategy != KeyStrategyWeighted || len(c.APIKeys) <= 1 || index < 0 || index >= len(c.APIKeys) {
+		return nil
+	}
+	return SaveKeyQuota(GetKeyStatePath(), c.APIKeys, index, remaining, time.Now())
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyIndexRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", KeyStateFileName)
+	keys := []string{"key1", "key2", "key3"}
+
+	if _, ok := LoadKeyIndex(path, keys); ok {
+		t.Error("LoadKeyIndex() without a saved index should not be ok")
+	}
+	if err := SaveKeyIndex(path, keys, 2); err != nil {
+		t.Fatalf("SaveKeyIndex() error = %v", err)
+	}
+	if index, ok := LoadKeyIndex(path, keys); !ok || index != 2 {
+		t.Errorf("LoadKeyIndex() = %d, %v, want 2, true", index, ok)
+	}
+
+	// The index of other keys is ignored
+	if _, ok := LoadKeyIndex(path, []string{"key1", "key3", "key2"}); ok {
+		t.Error("LoadKeyIndex() of reordered keys should not be ok")
+	}
+	if _, ok := LoadKeyIndex(path, keys[:2]); ok {
+		t.Error("LoadKeyIndex() should not be ok for fewer keys")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if strings.Contains(string(data), key) {
+			t.Errorf("key state %s should not store the key %q", data, key)
+		}
+	}
+
+	// The state is renamed into place, leaving no temporary file behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != KeyStateFileName {
+		t.Errorf("state directory holds %v, want only %s", entries, KeyStateFileName)
+	}
+}
+
+func TestGetKeyStatePath(t *testing.T) {
+	fakeOS(t, "linux", t.TempDir())
+	t.Setenv(EnvKeyStatePath, "")
+	t.Setenv(EnvXDGStateHome, "/xdg/state")
+	if got, want := GetKeyStatePath(), filepath.Join("/xdg/state", AppDirName, KeyStateFileName); got != want {
+		t.Errorf("GetKeyStatePath() = %q, want %q", got, want)
+	}
+
+	t.Setenv(EnvKeyStatePath, "/custom/key-state.json")
+	if got := GetKeyStatePath(); got != "/custom/key-state.json" {
+		t.Errorf("GetKeyStatePath() = %q, want the %s path", got, EnvKeyStatePath)
+	}
+}
+
+func TestPersistKeyIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), KeyStateFileName)
+	t.Setenv(EnvKeyStatePath, path)
+	t.Setenv(EnvAPIKey, "")
+	keys := []string{"pplx-key-one-123456789", "pplx-key-two-123456789", "pplx-key-three-12345678"}
+	t.Setenv(EnvAPIKeys, strings.Join(keys, ","))
+
+	if err := SaveKeyIndex(path, keys, 1); err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewConfig()
+	cfg.PersistKeyIndex = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if cfg.CurrentKeyIndex != 1 || cfg.APIKey != keys[1] {
+		t.Errorf("CurrentKeyIndex = %d, want the saved index 1", cfg.CurrentKeyIndex)
+	}
+
+	// A rotation to a working key is saved for the next run
+	if _, err := cfg.RotateKey(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SaveWorkingKey(); err != nil {
+		t.Fatalf("SaveWorkingKey() error = %v", err)
+	}
+	if index, ok := LoadKeyIndex(path, keys); !ok || index != 2 {
+		t.Errorf("saved index = %d, %v, want 2", index, ok)
+	}
+
+	// Without persist_key_index nothing is saved
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	cfg.PersistKeyIndex = false
+	cfg.CurrentKeyIndex = 0
+	if err := cfg.SaveWorkingKey(); err != nil {
+		t.Fatalf("SaveWorkingKey() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("key state written without persist_key_index: %v", err)
+	}
+}
+
+func TestKeyStrategy(t *testing.T) {
+	t.Setenv(EnvKeyStatePath, filepath.Join(t.TempDir(), KeyStateFileName))
+	t.Setenv(EnvAPIKey, "")
+	t.Setenv(EnvAPIKeys, "pplx-key-one-123456789,pplx-key-two-123456789,pplx-key-three-12345678")
+
+	for range 10 {
+		cfg := NewConfig()
+		cfg.KeyStrategy = KeyStrategySticky
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("Validate() error = %v", err)
+		}
+		if cfg.CurrentKeyIndex != 0 {
+			t.Fatalf("sticky CurrentKeyIndex = %d, want 0", cfg.CurrentKeyIndex)
+		}
+	}
+
+	cfg := NewConfig()
+	cfg.KeyStrategy = "round-robin"
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidKeyStrategy) {
+		t.Errorf("Validate() error = %v, want ErrInvalidKeyStrategy", err)
+	}
+}
+
+func TestWeightedIndex(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		quotas []KeyQuota
+		want   int // -1 = any key
+		never  int // Key never picked (-1 = none)
+	}{
+		{"unknown", nil, -1, -1},
+		{"only one left", []KeyQuota{{0, now}, {50, now}, {0, now}}, 1, -1},
+		{"unknown keys weigh as the known ones", []KeyQuota{{0, now}, {}, {30, now}}, -1, 0},
+		{"stale counts are ignored", []KeyQuota{{100, now.Add(-2 * KeyQuotaTTL)}, {0, now}, {0, now}}, -1, -1},
+		{"none left", []KeyQuota{{0, now}, {0, now}, {0, now}}, -1, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := KeyState{Quotas: tt.quotas}
+			for range 50 {
+				got := state.weightedIndex(3, now)
+				if got < 0 || got >= 3 || (tt.want >= 0 && got != tt.want) || got == tt.never {
+					t.Fatalf("weightedIndex() = %d, want %d, never %d", got, tt.want, tt.never)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordKeyQuota(t *testing.T) {
+	path := filepath.Join(t.TempDir(), KeyStateFileName)
+	t.Setenv(EnvKeyStatePath, path)
+	keys := []string{"key1", "key2"}
+
+	cfg := &Config{APIKeys: keys, KeyStrategy: KeyStrategyRandom}
+	if err := cfg.RecordKeyQuota(1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("quota recorded with the random strategy: %v", err)
+	}
+
+	cfg.KeyStrategy = KeyStrategyWeighted
+	if err := cfg.RecordKeyQuota(1, 10); err != nil {
+		t.Fatalf("RecordKeyQuota() error = %v", err)
+	}
+	state := LoadKeyState(path, keys)
+	if len(state.Quotas) != 2 || state.Quotas[1].Remaining != 10 || state.Quotas[0].Time.IsZero() == state.Quotas[1].Time.IsZero() {
+		t.Errorf("saved state = %+v, want 10 requests left to the second key", state)
+	}
+}
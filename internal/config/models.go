@@ -0,0 +1,46 @@
+package config
+
+import (
+	"slices"
+	"strings"
+)
+
+// GetModels returns the built-in models followed by any fetched and custom models, and the auto model
+func (c *Config) GetModels() []string {
+	models := slices.Clone(AvailableModels)
+	for _, m := range slices.Concat(c.RemoteModels, c.CustomModels) {
+		if !slices.Contains(models, m) {
+			models = append(models, m)
+		}
+	}
+	return append(models, AutoModel)
+}
+
+// GetModelsString returns a formatted string of the models accepted by this config
+func (c *Config) GetModelsString() string {
+	return strings.Join(c.GetModels(), ", ")
+}
+
+// ResolveModel returns the model an alias points to, or name itself if it is not an alias
+func (c *Config) ResolveModel(name string) string {
+	if target, ok := c.ModelAliases[name]; ok {
+		return target
+	}
+	return name
+}
+
+// IsValidModel checks if the given model is built in, fetched from the provider,
+// declared as a custom model or the auto model. Aliases must be resolved with ResolveModel first.
+func (c *Config) IsValidModel(model string) bool {
+	return model == AutoModel || ValidateModel(model) || slices.Contains(c.RemoteModels, model) || slices.Contains(c.CustomModels, model)
+}
+
+// GetModelAliasNames returns the sorted model alias names
+func (c *Config) GetModelAliasNames() []string {
+	names := make([]string, 0, len(c.ModelAliases))
+	for name := range c.ModelAliases {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
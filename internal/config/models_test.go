@@ -0,0 +1,118 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func newModelsConfig() *Config {
+	cfg := NewConfig()
+	cfg.APIKey = "pplx-test-key-12345678901234567890"
+	cfg.ModelAliases = map[string]string{
+		"fast": "sonar",
+		"deep": "sonar-deep-research",
+		"new":  "sonar-ultra",
+	}
+	cfg.CustomModels = []string{"sonar-ultra", "sonar"}
+	cfg.RemoteModels = []string{"sonar-remote"}
+	return cfg
+}
+
+func TestGetModels(t *testing.T) {
+	models := newModelsConfig().GetModels()
+
+	if len(models) != len(AvailableModels)+3 {
+		t.Errorf("GetModels() = %v, want built-in models plus one remote and one custom model and auto", models)
+	}
+	if models[len(models)-2] != "sonar-ultra" || models[len(models)-1] != AutoModel {
+		t.Errorf("GetModels() should list custom models and then auto last, got %v", models)
+	}
+	if len(AvailableModels) != 5 {
+		t.Error("GetModels() should not modify AvailableModels")
+	}
+}
+
+func TestResolveModel(t *testing.T) {
+	cfg := newModelsConfig()
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"fast", "sonar"},
+		{"deep", "sonar-deep-research"},
+		{"sonar-pro", "sonar-pro"},
+		{"unknown", "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.ResolveModel(tt.name); got != tt.want {
+				t.Errorf("ResolveModel(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidModel(t *testing.T) {
+	cfg := newModelsConfig()
+	tests := []struct {
+		model string
+		want  bool
+	}{
+		{"sonar-pro", true},
+		{"sonar-ultra", true},
+		{"sonar-remote", true},
+		{"auto", true},
+		{"fast", false},
+		{"gpt-4", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := cfg.IsValidModel(tt.model); got != tt.want {
+				t.Errorf("IsValidModel(%q) = %v, want %v", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetModelAliasNames(t *testing.T) {
+	got := newModelsConfig().GetModelAliasNames()
+	want := []string{"deep", "fast", "new"}
+	if !slices.Equal(got, want) {
+		t.Errorf("GetModelAliasNames() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateResolvesModelAlias(t *testing.T) {
+	cfg := newModelsConfig()
+	cfg.Model = "new"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if cfg.Model != "sonar-ultra" {
+		t.Errorf("Model = %q, want alias resolved to sonar-ultra", cfg.Model)
+	}
+
+	cfg = newModelsConfig()
+	cfg.Model = "not-a-model"
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("Validate() error = %v, want ErrInvalidModel", err)
+	}
+}
+
+func TestValidateModelAliasTargets(t *testing.T) {
+	cfg := newModelsConfig()
+	cfg.ModelAliases["broken"] = "gpt-4"
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("Validate() error = %v, want ErrInvalidModel for an alias to an unknown model", err)
+	}
+
+	// Aliases may point to models fetched from the provider
+	cfg = newModelsConfig()
+	cfg.ModelAliases["remote"] = "sonar-remote"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want aliases to fetched models accepted", err)
+	}
+}
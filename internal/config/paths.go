@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// AppDirName is the name of the directories the CLI keeps its files in
+const AppDirName = "perplexity-cli"
+
+// XDG base directory environment variables
+const (
+	EnvXDGConfigHome = "XDG_CONFIG_HOME"
+	EnvXDGDataHome   = "XDG_DATA_HOME"
+	EnvXDGStateHome  = "XDG_STATE_HOME"
+)
+
+// Windows application data environment variables
+const (
+	EnvAppData      = "APPDATA"      // Roaming, for settings
+	EnvLocalAppData = "LOCALAPPDATA" // Local to the machine, for data and logs
+)
+
+// Replaced in tests
+var goos = runtime.GOOS
+
+// xdgDir returns the CLI's directory under the base directory set by env, or
+// else under the Windows directory set by windowsEnv on Windows, or else
+// under the home directory's default one. As the XDG base directory
+// specification requires, a relative path in env is ignored.
+func xdgDir(env, windowsEnv string, defaultBase ...string) string {
+	if base := os.Getenv(env); base != "" && filepath.IsAbs(base) {
+		return filepath.Join(base, AppDirName)
+	}
+	if goos == "windows" {
+		if base := os.Getenv(windowsEnv); base != "" {
+			return filepath.Join(base, AppDirName)
+		}
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(append(append([]string{homeDir}, defaultBase...), AppDirName)...)
+}
+
+// GetConfigDir returns the directory holding the config file and other
+// user-managed data: $XDG_CONFIG_HOME/perplexity-cli, by default
+// ~/.config/perplexity-cli, or %APPDATA%\perplexity-cli on Windows
+func GetConfigDir() string {
+	return xdgDir(EnvXDGConfigHome, EnvAppData, ".config")
+}
+
+// GetDataDir returns the directory holding the conversation history:
+// $XDG_DATA_HOME/perplexity-cli, by default ~/.local/share/perplexity-cli,
+// or %LOCALAPPDATA%\perplexity-cli on Windows
+func GetDataDir() string {
+	return xdgDir(EnvXDGDataHome, EnvLocalAppData, ".local", "share")
+}
+
+// GetStateDir returns the directory holding logs such as crash reports and
+// the API key state:
+// $XDG_STATE_HOME/perplexity-cli, by default ~/.local/state/perplexity-cli,
+// or %LOCALAPPDATA%\perplexity-cli on Windows
+func GetStateDir() string {
+	return xdgDir(EnvXDGStateHome, EnvLocalAppData, ".local", "state")
+}
+
+// ExpandHome replaces a leading ~ in path with the home directory. Shells
+// usually do this, but PowerShell passes ~ on to the programs it runs, so
+// "~\answer.md" is expanded too on Windows.
+func ExpandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~")
+	if !ok || (rest != "" && rest[0] != '/' && (goos != "windows" || rest[0] != '\\')) {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return homeDir + rest
+}
+
+// Migration is a directory moved from its former location
+type Migration struct {
+	From string
+	To   string
+}
+
+// MigrateDirs moves the config and data directories from the locations used
+// before the XDG base directories and the Windows application data
+// directories were honored (~/.config/perplexity-cli and
+// ~/.local/share/perplexity-cli) to the current ones. A directory is only
+// moved if its new location does not exist yet, so this runs once.
+func MigrateDirs() ([]Migration, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil
+	}
+	candidates := []Migration{
+		{From: filepath.Join(homeDir, ".config", AppDirName), To: GetConfigDir()},
+		{From: filepath.Join(homeDir, ".local", "share", AppDirName), To: GetDataDir()},
+	}
+
+	var moved []Migration
+	for _, m := range candidates {
+		ok, err := migrateDir(m.From, m.To)
+		if err != nil {
+			return moved, err
+		}
+		if ok {
+			moved = append(moved, m)
+		}
+	}
+	return moved, nil
+}
+
+// migrateDir moves the directory from to the path to, unless either is
+// missing or to already exists. It reports whether the directory was moved.
+func migrateDir(from, to string) (bool, error) {
+	if from == "" || to == "" || filepath.Clean(from) == filepath.Clean(to) {
+		return false, nil
+	}
+	if info, err := os.Stat(from); err != nil || !info.IsDir() {
+		return false, nil
+	}
+	if _, err := os.Lstat(to); !os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(to), 0700); err != nil {
+		return false, fmt.Errorf("failed to migrate %s: %w", from, err)
+	}
+	if err := os.Rename(from, to); err == nil {
+		return true, nil
+	}
+	// Renaming fails across file systems: copy, then remove the original
+	if err := os.CopyFS(to, os.DirFS(from)); err != nil {
+		_ = os.RemoveAll(to)
+		return false, fmt.Errorf("failed to migrate %s to %s: %w", from, to, err)
+	}
+	if err := os.RemoveAll(from); err != nil {
+		return true, fmt.Errorf("migrated %s to %s but failed to remove it: %w", from, to, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeOS makes the path functions behave as on the operating system name,
+// with home as the home directory
+func fakeOS(t *testing.T, name, home string) {
+	t.Helper()
+	saved := goos
+	t.Cleanup(func() { goos = saved })
+	goos = name
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+}
+
+func TestXDGDirs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(EnvAppData, `C:\Users\me\AppData\Roaming`)
+	t.Setenv(EnvLocalAppData, `C:\Users\me\AppData\Local`)
+
+	tests := []struct {
+		name  string
+		goos  string
+		env   string
+		value string
+		dir   func() string
+		want  string
+	}{
+		{"config default", "linux", EnvXDGConfigHome, "", GetConfigDir, filepath.Join(home, ".config", AppDirName)},
+		{"config from XDG", "linux", EnvXDGConfigHome, "/xdg/config", GetConfigDir, filepath.Join("/xdg/config", AppDirName)},
+		{"data default", "linux", EnvXDGDataHome, "", GetDataDir, filepath.Join(home, ".local", "share", AppDirName)},
+		{"data from XDG", "linux", EnvXDGDataHome, "/xdg/data", GetDataDir, filepath.Join("/xdg/data", AppDirName)},
+		{"relative data path ignored", "linux", EnvXDGDataHome, "data", GetDataDir, filepath.Join(home, ".local", "share", AppDirName)},
+		{"state default", "linux", EnvXDGStateHome, "", GetStateDir, filepath.Join(home, ".local", "state", AppDirName)},
+		{"state from XDG", "linux", EnvXDGStateHome, "/xdg/state", GetStateDir, filepath.Join("/xdg/state", AppDirName)},
+		{"macOS uses the same defaults", "darwin", EnvXDGConfigHome, "", GetConfigDir, filepath.Join(home, ".config", AppDirName)},
+		{"Windows config in APPDATA", "windows", EnvXDGConfigHome, "", GetConfigDir, filepath.Join(`C:\Users\me\AppData\Roaming`, AppDirName)},
+		{"Windows data in LOCALAPPDATA", "windows", EnvXDGDataHome, "", GetDataDir, filepath.Join(`C:\Users\me\AppData\Local`, AppDirName)},
+		{"Windows state in LOCALAPPDATA", "windows", EnvXDGStateHome, "", GetStateDir, filepath.Join(`C:\Users\me\AppData\Local`, AppDirName)},
+		{"Windows honors XDG", "windows", EnvXDGDataHome, "/xdg/data", GetDataDir, filepath.Join("/xdg/data", AppDirName)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeOS(t, tt.goos, home)
+			t.Setenv(tt.env, tt.value)
+			if got := tt.dir(); got != tt.want {
+				t.Errorf("dir = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home := t.TempDir()
+	tests := []struct {
+		goos string
+		path string
+		want string
+	}{
+		{"linux", "~/answer.md", home + "/answer.md"},
+		{"linux", "~", home},
+		{"linux", "~user/answer.md", "~user/answer.md"},
+		{"linux", `~\answer.md`, `~\answer.md`},
+		{"linux", "answer~.md", "answer~.md"},
+		{"windows", `~\answer.md`, home + `\answer.md`},
+		{"windows", "~/answer.md", home + "/answer.md"},
+	}
+
+	for _, tt := range tests {
+		fakeOS(t, tt.goos, home)
+		if got := ExpandHome(tt.path); got != tt.want {
+			t.Errorf("ExpandHome(%q) on %s = %q, want %q", tt.path, tt.goos, got, tt.want)
+		}
+	}
+}
+
+func TestMigrateDirs(t *testing.T) {
+	home := t.TempDir()
+	fakeOS(t, "linux", home)
+	t.Setenv(EnvXDGConfigHome, "")
+	t.Setenv(EnvXDGDataHome, filepath.Join(home, "data"))
+
+	legacy := filepath.Join(home, ".local", "share", AppDirName)
+	writeFile(t, filepath.Join(legacy, "conversation-history.json"), `{"conversations":[]}`)
+
+	moved, err := MigrateDirs()
+	if err != nil {
+		t.Fatalf("MigrateDirs() error = %v", err)
+	}
+	// The config directory is already in place and stays there
+	if len(moved) != 1 || moved[0].From != legacy || moved[0].To != GetDataDir() {
+		t.Fatalf("MigrateDirs() = %+v, want the data directory moved", moved)
+	}
+	if _, err := os.Stat(filepath.Join(GetDataDir(), "conversation-history.json")); err != nil {
+		t.Errorf("history not migrated: %v", err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("legacy directory left behind: %v", err)
+	}
+
+	// An existing directory is never overwritten
+	writeFile(t, filepath.Join(legacy, "conversation-history.json"), `{"conversations":[{}]}`)
+	if moved, err := MigrateDirs(); err != nil || len(moved) != 0 {
+		t.Errorf("second MigrateDirs() = %+v, %v, want nothing moved", moved, err)
+	}
+	data, err := os.ReadFile(filepath.Join(GetDataDir(), "conversation-history.json"))
+	if err != nil || string(data) != `{"conversations":[]}` {
+		t.Errorf("migrated history = %s, %v, want it kept", data, err)
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Preset bundles settings for a common kind of task.
+// Unset fields leave the current setting untouched.
+type Preset struct {
+	SystemPrompt string   `yaml:"system_prompt,omitempty"`
+	Model        string   `yaml:"model,omitempty"`
+	Temperature  *float64 `yaml:"temperature,omitempty"`
+	Citations    *bool    `yaml:"citations,omitempty"`
+}
+
+// BuiltinPresets are the presets shipped with the CLI.
+// Fields can be overridden, and new presets added, under "presets" in the config file.
+var BuiltinPresets = map[string]Preset{
+	"code": {
+		SystemPrompt: "You are an expert software engineer. Answer with working, idiomatic code " +
+			"in fenced code blocks tagged with their language, followed by a brief explanation.",
+		Model:       "sonar-pro",
+		Temperature: floatPtr(0.2),
+		Citations:   boolPtr(false),
+	},
+	"research": {
+		SystemPrompt: "You are a meticulous research assistant. Give thorough, well-structured answers, " +
+			"compare sources where they disagree and cite a source for every claim.",
+		Model:       "sonar-reasoning-pro",
+		Temperature: floatPtr(0.5),
+		Citations:   boolPtr(true),
+	},
+	"eli5": {
+		SystemPrompt: "Explain like I'm five: use simple words, short sentences and everyday analogies. Avoid jargon.",
+		Model:        "sonar",
+		Temperature:  floatPtr(0.7),
+		Citations:    boolPtr(false),
+	},
+	"concise": {
+		SystemPrompt: "Answer in as few words as possible. No preamble, no caveats, no repetition of the question.",
+		Model:        "sonar",
+		Temperature:  floatPtr(0.2),
+		Citations:    boolPtr(false),
+	},
+}
+
+// GetPreset returns the named preset, with any config file overrides applied
+func (c *Config) GetPreset(name string) (Preset, error) {
+	builtin, isBuiltin := BuiltinPresets[name]
+	custom, isCustom := c.Presets[name]
+	if !isBuiltin && !isCustom {
+		return Preset{}, fmt.Errorf("unknown preset: %s. Available presets: %s", name, strings.Join(c.GetPresetNames(), ", "))
+	}
+
+	preset := builtin
+	if custom.SystemPrompt != "" {
+		preset.SystemPrompt = custom.SystemPrompt
+	}
+	if custom.Model != "" {
+		preset.Model = custom.Model
+	}
+	if custom.Temperature != nil {
+		preset.Temperature = custom.Temperature
+	}
+	if custom.Citations != nil {
+		preset.Citations = custom.Citations
+	}
+	return preset, nil
+}
+
+// GetPresetNames returns the sorted names of all built-in and config file presets
+func (c *Config) GetPresetNames() []string {
+	names := make([]string, 0, len(BuiltinPresets)+len(c.Presets))
+	for name := range BuiltinPresets {
+		names = append(names, name)
+	}
+	for name := range c.Presets {
+		if _, ok := BuiltinPresets[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
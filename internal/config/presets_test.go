@@ -0,0 +1,76 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGetPreset(t *testing.T) {
+	cfg := NewConfig()
+
+	for name := range BuiltinPresets {
+		t.Run(name, func(t *testing.T) {
+			preset, err := cfg.GetPreset(name)
+			if err != nil {
+				t.Fatalf("GetPreset(%q) error = %v", name, err)
+			}
+			if preset.SystemPrompt == "" {
+				t.Error("built-in preset should have a system prompt")
+			}
+			if !ValidateModel(preset.Model) {
+				t.Errorf("built-in preset uses unknown model %q", preset.Model)
+			}
+		})
+	}
+
+	if _, err := cfg.GetPreset("missing"); err == nil {
+		t.Error("GetPreset() should fail for unknown preset")
+	}
+}
+
+func TestGetPresetOverrides(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Presets = map[string]Preset{
+		"code":   {Model: "sonar-reasoning-pro"},
+		"poetry": {SystemPrompt: "Answer in verse.", Temperature: floatPtr(1.2)},
+	}
+
+	code, err := cfg.GetPreset("code")
+	if err != nil {
+		t.Fatalf("GetPreset() error = %v", err)
+	}
+	if code.Model != "sonar-reasoning-pro" {
+		t.Errorf("Model = %q, want override", code.Model)
+	}
+	if code.SystemPrompt != BuiltinPresets["code"].SystemPrompt {
+		t.Error("fields not overridden should keep built-in values")
+	}
+
+	poetry, err := cfg.GetPreset("poetry")
+	if err != nil {
+		t.Fatalf("GetPreset() error = %v", err)
+	}
+	if poetry.SystemPrompt != "Answer in verse." || *poetry.Temperature != 1.2 {
+		t.Errorf("custom preset = %+v", poetry)
+	}
+
+	names := cfg.GetPresetNames()
+	if !slices.Contains(names, "poetry") || !slices.Contains(names, "eli5") {
+		t.Errorf("GetPresetNames() = %v, want built-in and custom presets", names)
+	}
+	if !slices.IsSorted(names) {
+		t.Errorf("GetPresetNames() = %v, want sorted", names)
+	}
+}
+
+func TestGetSystemPrompt(t *testing.T) {
+	cfg := NewConfig()
+	if got := cfg.GetSystemPrompt(); got != DefaultSystemMessage {
+		t.Errorf("GetSystemPrompt() = %q, want default", got)
+	}
+
+	cfg.SystemPrompt = "Be funny."
+	if got := cfg.GetSystemPrompt(); got != "Be funny." {
+		t.Errorf("GetSystemPrompt() = %q, want custom prompt", got)
+	}
+}
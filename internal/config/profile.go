@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// EnvProfile is the environment variable selecting a profile of the config file
+	EnvProfile = "PERPLEXITY_PROFILE"
+	// ProjectFileName is the name of the project config file, looked up from
+	// the working directory upward
+	ProjectFileName = ".perplexity.yaml"
+)
+
+// Layers are the config file sections that apply to a run
+type Layers struct {
+	Files       []*FileConfig // From lowest to highest precedence
+	Profile     string        // Selected profile (empty = none)
+	ProjectFile string        // Path of the project file found (empty = none)
+}
+
+// LoadLayers loads the config file at path, the profile it defines that is
+// selected, and the project file found from dir upward, in that order of
+// precedence. The profile is selected by PERPLEXITY_PROFILE, or else by the
+// project file's or the config file's profile setting.
+func LoadLayers(path, dir string) (*Layers, error) {
+	fc, err := LoadFileConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	layers := &Layers{Files: []*FileConfig{fc}}
+
+	var project *FileConfig
+	if layers.ProjectFile = FindProjectFile(dir); layers.ProjectFile != "" {
+		if project, err = LoadFileConfig(layers.ProjectFile); err != nil {
+			return nil, err
+		}
+		if err := checkProjectFile(project); err != nil {
+			return nil, fmt.Errorf("invalid project file %s: %w", layers.ProjectFile, err)
+		}
+	}
+
+	layers.Profile = fc.Profile
+	if project != nil && project.Profile != "" {
+		layers.Profile = project.Profile
+	}
+	if env := strings.TrimSpace(os.Getenv(EnvProfile)); env != "" {
+		layers.Profile = env
+	}
+	if layers.Profile != "" {
+		profile, ok := fc.Profiles[layers.Profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q: define it under profiles in %s%s", layers.Profile, path, profileList(fc.Profiles))
+		}
+		layers.Files = append(layers.Files, &profile)
+	}
+
+	if project != nil {
+		layers.Files = append(layers.Files, project)
+	}
+	return layers, nil
+}
+
+// profileList lists the names of profiles for an error message
+func profileList(profiles map[string]FileConfig) string {
+	if len(profiles) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return " (available: " + strings.Join(names, ", ") + ")"
+}
+
+// FindProjectFile returns the path of the project file in dir or the closest
+// of its parents, or "" if there is none
+func FindProjectFile(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ProjectFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// checkProjectFile rejects the settings a project file may not change: a
+// repository could otherwise run commands or send conversations and API keys
+// elsewhere as soon as the CLI is used inside it, or have its users' requests
+// billed to a key committed with it
+func checkProjectFile(fc *FileConfig) error {
+	var unsafe []string
+	if fc.APIKey != "" {
+		unsafe = append(unsafe, "api_key")
+	}
+	if fc.APIKeyStore != "" {
+		unsafe = append(unsafe, "api_key_store")
+	}
+	if len(fc.Tools) > 0 {
+		unsafe = append(unsafe, "tools")
+	}
+	if len(fc.Commands) > 0 {
+		unsafe = append(unsafe, "commands")
+	}
+	if len(fc.Hooks.PreQuery) > 0 || len(fc.Hooks.PostResponse) > 0 {
+		unsafe = append(unsafe, "hooks")
+	}
+	if fc.HistorySync != (SyncConfig{}) {
+		unsafe = append(unsafe, "history_sync")
+	}
+	if fc.Share != (ShareConfig{}) {
+		unsafe = append(unsafe, "share")
+	}
+	if fc.ModelsURL != "" {
+		unsafe = append(unsafe, "models_url")
+	}
+	if len(fc.Profiles) > 0 {
+		unsafe = append(unsafe, "profiles")
+	}
+	if len(unsafe) > 0 {
+		return fmt.Errorf("%s can only be set in the user config file", strings.Join(unsafe, ", "))
+	}
+	return nil
+}
+
+// ApplyLayers applies the config file sections in order, and records the
+// selected profile and project file
+func (c *Config) ApplyLayers(layers *Layers) {
+	for _, fc := range layers.Files {
+		c.ApplyFile(fc)
+	}
+	c.Profile = layers.Profile
+	c.ProjectFile = layers.ProjectFile
+}
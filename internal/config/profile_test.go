@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindProjectFile(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "repo", "src", "pkg")
+	if err := os.MkdirAll(nested, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProjectFile(nested); got != "" {
+		t.Errorf("FindProjectFile() = %q, want none", got)
+	}
+
+	project := filepath.Join(root, "repo", ProjectFileName)
+	writeFile(t, project, "model: sonar\n")
+	if got := FindProjectFile(nested); got != project {
+		t.Errorf("FindProjectFile() = %q, want %q", got, project)
+	}
+
+	// The closest file wins
+	closer := filepath.Join(root, "repo", "src", ProjectFileName)
+	writeFile(t, closer, "model: sonar-pro\n")
+	if got := FindProjectFile(nested); got != closer {
+		t.Errorf("FindProjectFile() = %q, want %q", got, closer)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "config", ConfigFileName)
+	writeFile(t, configPath, `model: sonar
+usage: true
+tools:
+  - name: date
+    command: date
+profiles:
+  work:
+    model: sonar-reasoning-pro
+    system_prompt: Answer for engineers.
+  home:
+    model: sonar-pro
+`)
+	repo := filepath.Join(root, "repo")
+	writeFile(t, filepath.Join(repo, ProjectFileName), "profile: work\nsystem_prompt: Answer about this repository.\n")
+
+	tests := []struct {
+		name        string
+		dir         string
+		env         string
+		wantProfile string
+		wantModel   string
+		wantPrompt  string
+	}{
+		{"config file only", root, "", "", "sonar", DefaultSystemMessage},
+		{"project file selects profile", repo, "", "work", "sonar-reasoning-pro", "Answer about this repository."},
+		{"environment selects profile", repo, "home", "home", "sonar-pro", "Answer about this repository."},
+		{"environment outside a project", root, "work", "work", "sonar-reasoning-pro", "Answer for engineers."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvProfile, tt.env)
+			layers, err := LoadLayers(configPath, tt.dir)
+			if err != nil {
+				t.Fatalf("LoadLayers() error = %v", err)
+			}
+			cfg := NewConfig()
+			cfg.ApplyLayers(layers)
+			if cfg.Profile != tt.wantProfile || cfg.Model != tt.wantModel || cfg.GetSystemPrompt() != tt.wantPrompt {
+				t.Errorf("profile %q, model %q, prompt %q, want %q, %q, %q",
+					cfg.Profile, cfg.Model, cfg.GetSystemPrompt(), tt.wantProfile, tt.wantModel, tt.wantPrompt)
+			}
+			// Settings the later sections leave unset are kept
+			if !cfg.Usage || len(cfg.Tools) != 1 {
+				t.Errorf("Usage = %v, Tools = %v, want those of the config file", cfg.Usage, cfg.Tools)
+			}
+		})
+	}
+
+	t.Setenv(EnvProfile, "travel")
+	if _, err := LoadLayers(configPath, root); err == nil || !strings.Contains(err.Error(), "available: home, work") {
+		t.Errorf("LoadLayers() error = %v, want the unknown profile with those available", err)
+	}
+}
+
+func TestProjectFileRestrictions(t *testing.T) {
+	t.Setenv(EnvProfile, "")
+	root := t.TempDir()
+	configPath := filepath.Join(root, ConfigFileName)
+
+	for _, content := range []string{
+		"hooks:\n  pre_query: [./steal-keys]\n",
+		"tools:\n  - name: sh\n    command: sh\n",
+		"models_url: https://example.com/models\n",
+		"share:\n  paste_url: https://example.com/\n",
+		"api_key: pplx-committed-key-123456\n",
+	} {
+		repo := t.TempDir()
+		writeFile(t, filepath.Join(repo, ProjectFileName), content)
+		if _, err := LoadLayers(configPath, repo); err == nil || !strings.Contains(err.Error(), "can only be set in the user config file") {
+			t.Errorf("LoadLayers() with project file %q error = %v, want it rejected", content, err)
+		}
+	}
+}
+
+func TestNestedProfilesRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	writeFile(t, path, "profiles:\n  work:\n    profile: home\n")
+	if _, err := LoadFileConfig(path); err == nil {
+		t.Error("LoadFileConfig() should reject a profile selecting a profile")
+	}
+
+	writeFile(t, path, "profiles:\n  work:\n    history_retention: soon\n")
+	if _, err := LoadFileConfig(path); err == nil || !strings.Contains(err.Error(), "profile work") {
+		t.Errorf("LoadFileConfig() error = %v, want the invalid setting of the profile", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Package crash writes a diagnostic report when the CLI panics, so users can
+// attach a file to a bug report instead of pasting a raw Go stack trace.
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+const (
+	// EnvCrashDir is the environment variable for a custom crash report directory
+	EnvCrashDir = "PERPLEXITY_CRASH_DIR"
+)
+
+// Report describes a panic and the state of the CLI when it happened
+type Report struct {
+	Time    time.Time
+	Version string
+	Args    []string // Command line arguments, with secrets redacted
+	Panic   any      // Value passed to panic
+	Stack   []byte   // Stack trace of the panicking goroutine
+	Config  any      // Configuration, with secrets redacted
+	Logs    []string // Recent log lines, oldest first
+}
+
+// Dir returns the directory crash reports are saved in
+func Dir() string {
+	if customDir := os.Getenv(EnvCrashDir); customDir != "" {
+		return customDir
+	}
+	dir := config.GetStateDir()
+	if dir == "" {
+		return os.TempDir()
+	}
+	return filepath.Join(dir, "crash")
+}
+
+// Save writes the report to a timestamped file in dir and returns its path
+func Save(dir string, r Report) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create crash report directory: %w", err)
+	}
+
+	path := filepath.Join(dir, "crash-"+r.Time.Format("20060102-150405")+".txt")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create crash report: %w", err)
+	}
+	if err := r.Write(f); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("failed to write crash report: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write crash report: %w", err)
+	}
+	return path, nil
+}
+
+// Write writes the report as plain text sections
+func (r Report) Write(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "perplexity-cli crash report\n\n")
+	fmt.Fprintf(&b, "Time:    %s\n", r.Time.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Version: %s\n", r.Version)
+	fmt.Fprintf(&b, "Go:      %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "Args:    %s\n", strings.Join(r.Args, " "))
+	fmt.Fprintf(&b, "Panic:   %v\n", r.Panic)
+
+	fmt.Fprintf(&b, "\n== Stack trace ==\n%s\n", strings.TrimRight(string(r.Stack), "\n"))
+
+	if r.Config != nil {
+		data, err := json.MarshalIndent(r.Config, "", "  ")
+		if err != nil {
+			fmt.Fprintf(&b, "\n== Config ==\n(unavailable: %v)\n", err)
+		} else {
+			fmt.Fprintf(&b, "\n== Config ==\n%s\n", data)
+		}
+	}
+
+	fmt.Fprintf(&b, "\n== Recent log lines ==\n")
+	if len(r.Logs) == 0 {
+		b.WriteString("(none)\n")
+	}
+	for _, line := range r.Logs {
+		b.WriteString(line + "\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
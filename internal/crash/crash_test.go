@@ -0,0 +1,87 @@
+package crash
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testReport() Report {
+	return Report{
+		Time:    time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC),
+		Version: "1.2.3",
+		Args:    []string{"perplexity", "--api-key", "[REDACTED]", "hello"},
+		Panic:   "index out of range",
+		Stack:   []byte("goroutine 1 [running]:\nmain.main()\n"),
+		Config:  map[string]string{"Model": "sonar"},
+		Logs:    []string{`level=DEBUG msg="Sending request"`},
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"Version: 1.2.3",
+		"Args:    perplexity --api-key [REDACTED] hello",
+		"Panic:   index out of range",
+		"== Stack trace ==\ngoroutine 1 [running]:",
+		`"Model": "sonar"`,
+		"== Recent log lines ==\nlevel=DEBUG",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report should contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestReportWriteWithoutLogs(t *testing.T) {
+	r := testReport()
+	r.Logs = nil
+	r.Config = nil
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "== Config ==") {
+		t.Error("report should omit the config section without a config")
+	}
+	if !strings.Contains(buf.String(), "== Recent log lines ==\n(none)") {
+		t.Errorf("report should note that there are no log lines, got:\n%s", buf.String())
+	}
+}
+
+func TestSave(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "crash")
+
+	path, err := Save(dir, testReport())
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if filepath.Base(path) != "crash-20260102-150405.txt" {
+		t.Errorf("Save() path = %s, want a timestamped file name", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("report permissions = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestDir(t *testing.T) {
+	t.Setenv(EnvCrashDir, "/tmp/crashes")
+	if got := Dir(); got != "/tmp/crashes" {
+		t.Errorf("Dir() = %s, want the %s directory", got, EnvCrashDir)
+	}
+}
@@ -0,0 +1,190 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// citationMarkerPattern matches inline citation markers such as [1] or [2][3],
+// with the space before them
+var citationMarkerPattern = regexp.MustCompile(`[ \t]?(\[\d{1,3}\])+`)
+
+// citationRefPattern matches a single inline citation marker, capturing its number
+var citationRefPattern = regexp.MustCompile(`\[(\d{1,3})\]`)
+
+// StripCitationMarkers removes inline [n] citation markers from the content.
+// Code blocks and inline code are left untouched.
+func StripCitationMarkers(content string) string {
+	return mapOutsideCode(content, func(text string) string {
+		return citationMarkerPattern.ReplaceAllString(text, "")
+	})
+}
+
+// LinkCitationMarkers turns inline [n] citation markers into markdown links
+// to the n-th citation. Markers without a matching citation are left as is.
+func LinkCitationMarkers(content string, citations []string) string {
+	return mapOutsideCode(content, func(text string) string {
+		return citationRefPattern.ReplaceAllStringFunc(text, func(marker string) string {
+			if link, ok := citationURL(marker, citations); ok {
+				return "[" + marker + "](" + link + ")"
+			}
+			return marker
+		})
+	})
+}
+
+// FootnoteCitationMarkers turns inline [n] citation markers into markdown
+// footnotes and appends a definition for each cited source.
+// Markers without a matching citation are left as is.
+func FootnoteCitationMarkers(content string, citations []string) string {
+	return LabeledFootnoteCitationMarkers(content, citations, "")
+}
+
+// LabeledFootnoteCitationMarkers is FootnoteCitationMarkers with footnote
+// labels starting with prefix, e.g. [^2-1] for prefix "2-", so the footnotes
+// of several answers can share a document.
+func LabeledFootnoteCitationMarkers(content string, citations []string, prefix string) string {
+	used := make([]bool, len(citations))
+	content = mapOutsideCode(content, func(text string) string {
+		return citationRefPattern.ReplaceAllStringFunc(text, func(marker string) string {
+			if _, ok := citationURL(marker, citations); !ok {
+				return marker
+			}
+			n := marker[1 : len(marker)-1]
+			i, _ := strconv.Atoi(n)
+			used[i-1] = true
+			return "[^" + prefix + n + "]"
+		})
+	})
+
+	var notes strings.Builder
+	for i, link := range citations {
+		if used[i] {
+			fmt.Fprintf(&notes, "\n[^%s%d]: %s", prefix, i+1, link)
+		}
+	}
+	if notes.Len() == 0 {
+		return content
+	}
+	return content + "\n" + notes.String()
+}
+
+// CitationList formats citations as a numbered list of URLs, numbered like the [n] markers
+func CitationList(citations []string) string {
+	var b strings.Builder
+	for i, link := range citations {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, link)
+	}
+	return b.String()
+}
+
+// CitationLinks formats citations as a numbered list of markdown links
+// titled with each source's site, e.g. "1. [go.dev](https://go.dev/doc)"
+func CitationLinks(citations []string) string {
+	var b strings.Builder
+	for i, link := range citations {
+		target := link
+		if strings.ContainsAny(link, "() ") {
+			// Angle brackets keep parentheses from ending the link early
+			target = "<" + link + ">"
+		}
+		fmt.Fprintf(&b, "%d. [%s](%s)\n", i+1, citationSite(link), target)
+	}
+	return b.String()
+}
+
+// citationSite returns the host of a citation URL without "www.", or the URL itself if it has none
+func citationSite(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// citationURL returns the citation a [n] marker refers to
+func citationURL(marker string, citations []string) (string, bool) {
+	n, err := strconv.Atoi(marker[1 : len(marker)-1])
+	if err != nil || n < 1 || n > len(citations) {
+		return "", false
+	}
+	return citations[n-1], true
+}
+
+// mapOutsideCode applies fn to the parts of the content outside code blocks and inline code
+func mapOutsideCode(content string, fn func(string) string) string {
+	lines := strings.Split(content, "\n")
+	inFence := false
+	for i, line := range lines {
+		lines[i] = mapLineOutsideCode(line, &inFence, fn)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mapLineOutsideCode applies fn to a single line outside code, tracking code fences
+func mapLineOutsideCode(line string, inFence *bool, fn func(string) string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "```") {
+		*inFence = !*inFence
+		return line
+	}
+	if *inFence {
+		return line
+	}
+
+	// Even segments are outside inline code spans
+	segments := strings.Split(line, "`")
+	for i := 0; i < len(segments); i += 2 {
+		segments[i] = fn(segments[i])
+	}
+	return strings.Join(segments, "`")
+}
+
+// stripMarkersLine removes citation markers from a single line, tracking code fences
+func stripMarkersLine(line string, inFence *bool) string {
+	return mapLineOutsideCode(line, inFence, func(text string) string {
+		return citationMarkerPattern.ReplaceAllString(text, "")
+	})
+}
+
+// CitationMarkerWriter removes citation markers from streamed content.
+// Output is written a line at a time, since a marker may span chunks.
+type CitationMarkerWriter struct {
+	out     io.Writer
+	line    strings.Builder
+	inFence bool
+}
+
+// NewCitationMarkerWriter creates a writer that strips citation markers before writing to out
+func NewCitationMarkerWriter(out io.Writer) *CitationMarkerWriter {
+	return &CitationMarkerWriter{out: out}
+}
+
+// Write buffers p and writes every completed line without citation markers
+func (w *CitationMarkerWriter) Write(p []byte) (int, error) {
+	text := string(p)
+	for {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			w.line.WriteString(text)
+			return len(p), nil
+		}
+		w.line.WriteString(text[:i])
+		if _, err := io.WriteString(w.out, stripMarkersLine(w.line.String(), &w.inFence)+"\n"); err != nil {
+			return 0, err
+		}
+		w.line.Reset()
+		text = text[i+1:]
+	}
+}
+
+// Flush writes the last, unterminated line
+func (w *CitationMarkerWriter) Flush() {
+	if w.line.Len() > 0 {
+		_, _ = io.WriteString(w.out, stripMarkersLine(w.line.String(), &w.inFence))
+		w.line.Reset()
+	}
+}
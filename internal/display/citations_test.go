@@ -0,0 +1,111 @@
+package display
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStripCitationMarkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"single marker", "Paris is the capital of France[1].", "Paris is the capital of France."},
+		{"grouped markers with space", "It is large [2][3] and old[4].", "It is large and old."},
+		{"no markers", "Nothing to strip.", "Nothing to strip."},
+		{"markdown link kept", "See [the docs](https://go.dev).", "See [the docs](https://go.dev)."},
+		{"inline code kept", "Use `arr[1]` here[1].", "Use `arr[1]` here."},
+		{"code block kept", "Example[1]:\n```go\nx := arr[1]\n```\nDone[2].", "Example:\n```go\nx := arr[1]\n```\nDone."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripCitationMarkers(tt.content); got != tt.want {
+				t.Errorf("StripCitationMarkers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCitationMarkerWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCitationMarkerWriter(&buf)
+
+	for _, chunk := range []string{"Go is fast [", "1][2].\n```\na[", "1]\n```\nEnd[3", "]"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if buf.String() != "Go is fast.\n```\na[1]\n```\n" {
+		t.Errorf("before Flush() output = %q, want complete lines only", buf.String())
+	}
+
+	w.Flush()
+	if buf.String() != "Go is fast.\n```\na[1]\n```\nEnd" {
+		t.Errorf("output = %q", buf.String())
+	}
+}
+
+func TestLinkCitationMarkers(t *testing.T) {
+	citations := []string{"https://a.example", "https://b.example"}
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"linked", "Fact[1] and more[2].", "Fact[[1]](https://a.example) and more[[2]](https://b.example)."},
+		{"out of range kept", "Unknown[3].", "Unknown[3]."},
+		{"code kept", "Use `a[1]`[1].", "Use `a[1]`[[1]](https://a.example)."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LinkCitationMarkers(tt.content, citations); got != tt.want {
+				t.Errorf("LinkCitationMarkers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFootnoteCitationMarkers(t *testing.T) {
+	citations := []string{"https://a.example", "https://b.example", "https://c.example"}
+
+	got := FootnoteCitationMarkers("Fact[3] and more[1][3]. Unknown[9].", citations)
+	want := "Fact[^3] and more[^1][^3]. Unknown[9].\n\n[^1]: https://a.example\n[^3]: https://c.example"
+	if got != want {
+		t.Errorf("FootnoteCitationMarkers() = %q, want %q", got, want)
+	}
+
+	if got := FootnoteCitationMarkers("No markers.", citations); got != "No markers." {
+		t.Errorf("FootnoteCitationMarkers() without markers = %q", got)
+	}
+
+	got = LabeledFootnoteCitationMarkers("Fact[2].", citations, "3-")
+	want = "Fact[^3-2].\n\n[^3-2]: https://b.example"
+	if got != want {
+		t.Errorf("LabeledFootnoteCitationMarkers() = %q, want %q", got, want)
+	}
+}
+
+func TestCitationList(t *testing.T) {
+	got := CitationList([]string{"https://a.example/x", "https://b.example"})
+	want := "1. https://a.example/x\n2. https://b.example\n"
+	if got != want {
+		t.Errorf("CitationList() = %q, want %q", got, want)
+	}
+}
+
+func TestCitationLinks(t *testing.T) {
+	got := CitationLinks([]string{
+		"https://www.go.dev/doc",
+		"https://en.wikipedia.org/wiki/Go_(programming_language)",
+		"not a url",
+	})
+	want := "1. [go.dev](https://www.go.dev/doc)\n" +
+		"2. [en.wikipedia.org](<https://en.wikipedia.org/wiki/Go_(programming_language)>)\n" +
+		"3. [not a url](<not a url>)\n"
+	if got != want {
+		t.Errorf("CitationLinks() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package display
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/muesli/reflow/wordwrap"
+	"github.com/muesli/reflow/wrap"
+	"golang.org/x/term"
+)
+
+const (
+	// defaultTerminalWidth is used when the terminal size cannot be detected
+	defaultTerminalWidth = 80
+	// minColumnWidth is the narrowest column shown side by side
+	minColumnWidth = 30
+	// columnSeparator is placed between side-by-side columns
+	columnSeparator = " │ "
+)
+
+// Column is a titled block of text displayed side by side with others
+type Column struct {
+	Title string
+	Body  string
+}
+
+// TerminalWidth returns the width of the terminal attached to stdout
+func TerminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return defaultTerminalWidth
+	}
+	return width
+}
+
+// ShowColumns displays the columns side by side, wrapped to fit within width.
+// If the columns would be narrower than minColumnWidth they are shown one after another.
+func ShowColumns(columns []Column, width int) {
+	if len(columns) == 0 {
+		return
+	}
+
+	sepWidth := runewidth.StringWidth(columnSeparator)
+	colWidth := (width - sepWidth*(len(columns)-1)) / len(columns)
+	if colWidth < minColumnWidth {
+		for _, col := range columns {
+			fmt.Printf("## %s\n\n", col.Title)
+			ShowContent(col.Body)
+			fmt.Println()
+		}
+		return
+	}
+
+	lines := make([][]string, len(columns))
+	height := 0
+	for i, col := range columns {
+		lines[i] = wrapLines(col.Body, colWidth)
+		height = max(height, len(lines[i]))
+	}
+
+	titles := make([]string, len(columns))
+	rules := make([]string, len(columns))
+	for i, col := range columns {
+		titles[i] = runewidth.Truncate(col.Title, colWidth, "…")
+		rules[i] = strings.Repeat("─", colWidth)
+	}
+	printRow(titles, colWidth)
+	fmt.Println(strings.Join(rules, "─┼─"))
+
+	row := make([]string, len(columns))
+	for n := 0; n < height; n++ {
+		for i := range columns {
+			row[i] = ""
+			if n < len(lines[i]) {
+				row[i] = lines[i][n]
+			}
+		}
+		printRow(row, colWidth)
+	}
+	fmt.Println()
+}
+
+// wrapLines word-wraps text to width, hard-wrapping words longer than a line
+func wrapLines(text string, width int) []string {
+	wrapped := wrap.String(wordwrap.String(strings.TrimSpace(text), width), width)
+	return strings.Split(strings.ReplaceAll(wrapped, "\t", "    "), "\n")
+}
+
+// printRow prints one line of each column, padded to the column width
+func printRow(cells []string, colWidth int) {
+	padded := make([]string, len(cells))
+	for i, cell := range cells {
+		padded[i] = runewidth.FillRight(runewidth.Truncate(cell, colWidth, ""), colWidth)
+	}
+	fmt.Println(strings.TrimRight(strings.Join(padded, columnSeparator), " "))
+}
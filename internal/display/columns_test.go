@@ -0,0 +1,60 @@
+package display
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShowColumns(t *testing.T) {
+	columns := []Column{
+		{Title: "sonar", Body: "short answer"},
+		{Title: "sonar-pro", Body: strings.Repeat("a much longer answer ", 10)},
+	}
+
+	output := captureStdout(func() {
+		ShowColumns(columns, 80)
+	})
+
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if !strings.HasPrefix(lines[0], "sonar ") || !strings.Contains(lines[0], "│ sonar-pro") {
+		t.Errorf("first line should hold both titles, got %q", lines[0])
+	}
+	if !strings.Contains(lines[2], "short answer") || !strings.Contains(lines[2], "│ a much longer") {
+		t.Errorf("columns should be side by side, got %q", lines[2])
+	}
+	if len(lines) < 5 {
+		t.Errorf("long body should wrap over several lines, got %d lines", len(lines))
+	}
+	for _, line := range lines {
+		if w := len([]rune(line)); w > 80 {
+			t.Errorf("line exceeds width (%d): %q", w, line)
+		}
+	}
+}
+
+func TestShowColumnsNarrow(t *testing.T) {
+	columns := []Column{
+		{Title: "sonar", Body: "first"},
+		{Title: "sonar-pro", Body: "second"},
+	}
+
+	output := captureStdout(func() {
+		ShowColumns(columns, 40)
+	})
+
+	if !strings.Contains(output, "## sonar\n\nfirst") || !strings.Contains(output, "## sonar-pro\n\nsecond") {
+		t.Errorf("narrow terminal should show columns one after another, got:\n%s", output)
+	}
+}
+
+func TestWrapLines(t *testing.T) {
+	lines := wrapLines("averyveryverylongword fits", 10)
+	for _, line := range lines {
+		if len(line) > 10 {
+			t.Errorf("wrapLines() line %q exceeds width", line)
+		}
+	}
+	if len(lines) != 4 || lines[3] != "fits" {
+		t.Errorf("wrapLines() = %q, want long word hard-wrapped", lines)
+	}
+}
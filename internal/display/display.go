@@ -9,6 +9,8 @@ import (
 
 	"github.com/briandowns/spinner"
 	"github.com/charmbracelet/glamour"
+
+	"github.com/quocvuong92/perplexity-cli/internal/pricing"
 )
 
 // renderer is the markdown renderer instance
@@ -18,6 +20,16 @@ var (
 	rendererErr  error
 )
 
+// quiet suppresses spinners and informational notes
+var quiet bool
+
+// SetQuiet enables or disables quiet mode. In quiet mode spinners are not
+// shown and key rotation, retry and model selection notes are suppressed;
+// warnings and errors are still printed.
+func SetQuiet(q bool) {
+	quiet = q
+}
+
 // Spinner wraps the spinner with elapsed time display
 type Spinner struct {
 	s         *spinner.Spinner
@@ -26,6 +38,7 @@ type Spinner struct {
 	stopChan  chan struct{}
 	wg        sync.WaitGroup
 	stopped   bool
+	disabled  bool // Quiet mode: never shown
 	mu        sync.Mutex
 }
 
@@ -38,13 +51,14 @@ func NewSpinner(message string) *Spinner {
 		s:        s,
 		message:  message,
 		stopChan: make(chan struct{}),
+		disabled: quiet,
 	}
 }
 
 // Start begins the spinner animation
 func (sp *Spinner) Start() {
 	sp.mu.Lock()
-	if sp.stopped {
+	if sp.stopped || sp.disabled {
 		sp.mu.Unlock()
 		return
 	}
@@ -69,10 +83,10 @@ func (sp *Spinner) Start() {
 					sp.mu.Unlock()
 					return
 				}
-				elapsed := time.Since(sp.startTime).Seconds()
+				elapsed := time.Since(sp.startTime)
 				message := sp.message
 				sp.mu.Unlock()
-				sp.s.Suffix = fmt.Sprintf(" %s (%.1fs)", message, elapsed)
+				sp.s.Suffix = fmt.Sprintf(" %s (%s)", message, formatElapsed(elapsed))
 			}
 		}
 	}()
@@ -101,15 +115,29 @@ func (sp *Spinner) UpdateMessage(message string) {
 		return
 	}
 	sp.message = message
-	elapsed := time.Since(sp.startTime).Seconds()
-	sp.s.Suffix = fmt.Sprintf(" %s (%.1fs)", message, elapsed)
+	sp.s.Suffix = fmt.Sprintf(" %s (%s)", message, formatElapsed(time.Since(sp.startTime)))
 }
 
-// InitRenderer initializes the markdown renderer
-func InitRenderer() error {
+// formatElapsed formats the time a spinner has run: in tenths of seconds for
+// the first minute, then in minutes and seconds
+func formatElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// InitRenderer initializes the markdown renderer with a glamour style
+// such as "dark" or "dracula". An empty theme detects the terminal background.
+func InitRenderer(theme string) error {
 	rendererOnce.Do(func() {
+		style := glamour.WithAutoStyle()
+		if theme != "" {
+			style = glamour.WithStandardStyle(theme)
+		}
 		r, err := glamour.NewTermRenderer(
-			glamour.WithAutoStyle(),
+			style,
 			glamour.WithWordWrap(100),
 		)
 		if err != nil {
@@ -121,15 +149,88 @@ func InitRenderer() error {
 	return rendererErr
 }
 
-// ShowUsage displays token usage statistics in markdown format
-func ShowUsage(usage map[string]int) {
+// Usage is the token usage of a response and its cost
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	CitationTokens   int     // Shown when the API reports them
+	ReasoningTokens  int     // Shown when the API reports them
+	SearchQueries    int     // Web searches, shown when the API reports them
+	SearchContext    string  // Search depth used, if reported
+	Cost             float64 // US dollars
+	CostKnown        bool    // Cost is set
+	CostEstimated    bool    // Cost is estimated from token prices rather than reported by the API
+	KeyIndex         int     // 1-based index of the API key that served the response
+	KeyCount         int     // API keys configured; the key is shown when there are several
+}
+
+// ShowUsage displays token usage statistics and the cost in markdown format
+func ShowUsage(usage Usage) {
 	fmt.Println("## Tokens")
 	fmt.Println()
 	fmt.Println("| Type | Count |")
 	fmt.Println("|------|-------|")
-	fmt.Printf("| Prompt | %d |\n", usage["prompt_tokens"])
-	fmt.Printf("| Completion | %d |\n", usage["completion_tokens"])
-	fmt.Printf("| **Total** | **%d** |\n", usage["total_tokens"])
+	fmt.Printf("| Prompt | %d |\n", usage.PromptTokens)
+	fmt.Printf("| Completion | %d |\n", usage.CompletionTokens)
+	if usage.CitationTokens > 0 {
+		fmt.Printf("| Citation | %d |\n", usage.CitationTokens)
+	}
+	if usage.ReasoningTokens > 0 {
+		fmt.Printf("| Reasoning | %d |\n", usage.ReasoningTokens)
+	}
+	fmt.Printf("| **Total** | **%d** |\n", usage.TotalTokens)
+	fmt.Println()
+
+	if usage.SearchQueries > 0 {
+		fmt.Printf("Searches: %d", usage.SearchQueries)
+		if usage.SearchContext != "" {
+			fmt.Printf(" (%s search context)", usage.SearchContext)
+		}
+		fmt.Print("\n\n")
+	}
+
+	if usage.KeyCount > 1 && usage.KeyIndex > 0 {
+		fmt.Printf("API key: %d of %d\n\n", usage.KeyIndex, usage.KeyCount)
+	}
+
+	switch {
+	case !usage.CostKnown:
+	case usage.CostEstimated:
+		fmt.Printf("Estimated cost: ~%s (token prices only, excluding search fees)\n\n", pricing.Format(usage.Cost))
+	default:
+		fmt.Printf("Cost: %s\n\n", pricing.Format(usage.Cost))
+	}
+}
+
+// ComparisonRow summarises one model's response in a model comparison
+type ComparisonRow struct {
+	Model            string
+	Duration         time.Duration
+	PromptTokens     int
+	CompletionTokens int
+	Cost             string // Formatted cost, "~" marking an estimate; empty if unknown
+	Err              error
+}
+
+// ShowComparison displays the per-model timing, usage and cost of a comparison in markdown format
+func ShowComparison(rows []ComparisonRow) {
+	fmt.Println("## Comparison")
+	fmt.Println()
+	fmt.Println("| Model | Time | Prompt | Completion | Total | Cost |")
+	fmt.Println("|-------|------|--------|------------|-------|------|")
+	for _, row := range rows {
+		if row.Err != nil {
+			fmt.Printf("| %s | %.1fs | - | - | - | failed |\n", row.Model, row.Duration.Seconds())
+			continue
+		}
+		cost := row.Cost
+		if cost == "" {
+			cost = "n/a"
+		}
+		fmt.Printf("| %s | %.1fs | %d | %d | %d | %s |\n", row.Model, row.Duration.Seconds(),
+			row.PromptTokens, row.CompletionTokens, row.PromptTokens+row.CompletionTokens, cost)
+	}
 	fmt.Println()
 }
 
@@ -143,6 +244,35 @@ func ShowCitations(citations []string) {
 	fmt.Println()
 }
 
+// ShowRelatedQuestions displays the follow-up questions suggested with a response
+func ShowRelatedQuestions(questions []string) {
+	fmt.Println("## Related Questions")
+	fmt.Println()
+	for i, question := range questions {
+		fmt.Printf("%d. %s\n", i+1, question)
+	}
+	fmt.Println()
+}
+
+// ImageLink is an image returned with a response
+type ImageLink struct {
+	URL    string
+	Source string // Page the image was found on, if known
+}
+
+// ShowImages displays the images returned with a response
+func ShowImages(images []ImageLink) {
+	fmt.Println("## Images")
+	fmt.Println()
+	for i, image := range images {
+		fmt.Printf("%d. %s\n", i+1, image.URL)
+		if image.Source != "" {
+			fmt.Printf("   from %s\n", image.Source)
+		}
+	}
+	fmt.Println()
+}
+
 // ShowContent displays the main content response
 func ShowContent(content string) {
 	fmt.Println(strings.TrimSpace(content))
@@ -163,11 +293,42 @@ func ShowContentRendered(content string) {
 	fmt.Print(strings.TrimSuffix(rendered, "\n"))
 }
 
+// RelativeTime describes how long before now t was, e.g. "3 hours ago",
+// falling back to the date for times over a month ago
+func RelativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	case d < 48*time.Hour:
+		return "yesterday"
+	case d < 30*24*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
 // ShowError displays an error message
 func ShowError(message string) {
 	fmt.Fprintf(os.Stderr, "Error: %s\n", message)
 }
 
+// ShowWarning displays a non-fatal warning message
+func ShowWarning(message string) {
+	fmt.Fprintf(os.Stderr, "Warning: %s\n", message)
+}
+
 // ShowFriendlyError displays an error with a user-friendly message and optional hint
 func ShowFriendlyError(message, hint string) {
 	fmt.Fprintf(os.Stderr, "Error: %s\n", message)
@@ -196,7 +357,7 @@ func FormatNetworkError(err error) (message string, hint string) {
 
 	case strings.Contains(errStr, "timeout") || strings.Contains(errStr, "deadline exceeded"):
 		return "Request timed out",
-			"The server took too long to respond. Try again or check your connection"
+			"The server took too long to respond. Try again, or raise the limit with --timeout (0 for none)"
 
 	case strings.Contains(errStr, "certificate") || strings.Contains(errStr, "x509"):
 		return "SSL/TLS certificate error",
@@ -221,12 +382,18 @@ func FormatNetworkError(err error) (message string, hint string) {
 
 // ShowKeyRotation displays a message when API key is rotated
 func ShowKeyRotation(fromIndex, toIndex int, totalKeys int) {
+	if quiet {
+		return
+	}
 	fmt.Fprintf(os.Stderr, "Note: API key %d/%d failed, switching to key %d/%d\n", fromIndex, totalKeys, toIndex, totalKeys)
 }
 
-// ShowRetry displays a message when retrying due to network error
-func ShowRetry(attempt, maxRetries int, nextBackoff time.Duration) {
-	fmt.Fprintf(os.Stderr, "Note: Network error, retrying (%d/%d) in %v...\n", attempt, maxRetries, nextBackoff.Round(time.Millisecond))
+// ShowRetry displays a message when retrying after a network or server error
+func ShowRetry(reason string, attempt, maxRetries int, nextBackoff time.Duration) {
+	if quiet {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Note: %s, retrying (%d/%d) in %v...\n", reason, attempt, maxRetries, nextBackoff.Round(time.Millisecond))
 }
 
 // ShowModels displays available models
@@ -240,3 +407,27 @@ func ShowModels(models []string, currentModel string) {
 		}
 	}
 }
+
+// ShowModelAliases displays model aliases and the models they point to
+func ShowModelAliases(names []string, aliases map[string]string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Println("\nModel aliases:")
+	for _, name := range names {
+		fmt.Printf("    %s -> %s\n", name, aliases[name])
+	}
+}
+
+// ShowModelSelected displays the model picked by the auto model
+func ShowModelSelected(model, reason string) {
+	if quiet {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Note: Using %s (%s)\n", model, reason)
+}
+
+// ShowToolCall displays a message when the assistant invokes an external tool
+func ShowToolCall(name, input string) {
+	fmt.Fprintf(os.Stderr, "Note: Running tool %s with input %q\n", name, input)
+}
@@ -2,10 +2,12 @@ package display
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func captureStdout(f func()) string {
@@ -70,28 +72,87 @@ func TestShowCitations(t *testing.T) {
 	}
 }
 
-func TestShowUsage(t *testing.T) {
-	usage := map[string]int{
-		"prompt_tokens":     100,
-		"completion_tokens": 50,
-		"total_tokens":      150,
+func TestShowRelatedQuestions(t *testing.T) {
+	output := captureStdout(func() {
+		ShowRelatedQuestions([]string{"What is Go?", "Who made Go?"})
+	})
+
+	if !strings.Contains(output, "## Related Questions") {
+		t.Error("ShowRelatedQuestions() should contain header")
+	}
+	if !strings.Contains(output, "2. Who made Go?") {
+		t.Error("ShowRelatedQuestions() should number the questions")
 	}
+}
 
+func TestShowImages(t *testing.T) {
 	output := captureStdout(func() {
-		ShowUsage(usage)
+		ShowImages([]ImageLink{
+			{URL: "https://example.com/a.png", Source: "https://example.com/article"},
+			{URL: "https://example.com/b.jpg"},
+		})
 	})
 
-	if !strings.Contains(output, "## Tokens") {
-		t.Error("ShowUsage() should contain header")
+	for _, want := range []string{"## Images", "1. https://example.com/a.png", "from https://example.com/article", "2. https://example.com/b.jpg"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("ShowImages() output %q should contain %q", output, want)
+		}
 	}
-	if !strings.Contains(output, "| Prompt | 100 |") {
-		t.Error("ShowUsage() should contain prompt tokens")
-	}
-	if !strings.Contains(output, "| Completion | 50 |") {
-		t.Error("ShowUsage() should contain completion tokens")
+}
+
+func TestShowUsage(t *testing.T) {
+	tests := []struct {
+		name  string
+		usage Usage
+		want  []string
+		not   []string
+	}{
+		{
+			name:  "no cost",
+			usage: Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150},
+			want:  []string{"## Tokens", "| Prompt | 100 |", "| Completion | 50 |", "| **Total** | **150** |"},
+			not:   []string{"Citation", "Reasoning", "Searches", "cost"},
+		},
+		{
+			name:  "reported cost",
+			usage: Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 170, CitationTokens: 20, Cost: 0.0061, CostKnown: true},
+			want:  []string{"| Citation | 20 |", "| **Total** | **170** |", "Cost: $0.0061"},
+			not:   []string{"Estimated"},
+		},
+		{
+			name:  "searches",
+			usage: Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 250, ReasoningTokens: 100, SearchQueries: 12, SearchContext: "high"},
+			want:  []string{"| Reasoning | 100 |", "Searches: 12 (high search context)"},
+		},
+		{
+			name:  "estimated cost",
+			usage: Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150, Cost: 0.0002, CostKnown: true, CostEstimated: true},
+			want:  []string{"Estimated cost: ~$0.0002 (token prices only, excluding search fees)"},
+			not:   []string{"API key"},
+		},
+		{
+			name:  "several keys",
+			usage: Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150, KeyIndex: 2, KeyCount: 3},
+			want:  []string{"API key: 2 of 3"},
+		},
 	}
-	if !strings.Contains(output, "| **Total** | **150** |") {
-		t.Error("ShowUsage() should contain total tokens")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := captureStdout(func() {
+				ShowUsage(tt.usage)
+			})
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("ShowUsage() should contain %q, got:\n%s", want, output)
+				}
+			}
+			for _, not := range tt.not {
+				if strings.Contains(output, not) {
+					t.Errorf("ShowUsage() should not contain %q, got:\n%s", not, output)
+				}
+			}
+		})
 	}
 }
 
@@ -118,6 +179,30 @@ func TestShowKeyRotation(t *testing.T) {
 	}
 }
 
+func TestQuietMode(t *testing.T) {
+	SetQuiet(true)
+	defer SetQuiet(false)
+
+	output := captureStderr(func() {
+		ShowKeyRotation(1, 2, 3)
+		ShowRetry("Network error", 1, 3, time.Second)
+		ShowModelSelected("sonar", "default")
+		sp := NewSpinner("Waiting...")
+		sp.Start()
+		sp.Stop()
+	})
+	if output != "" {
+		t.Errorf("quiet mode output = %q, want nothing", output)
+	}
+
+	output = captureStderr(func() {
+		ShowError("boom")
+	})
+	if !strings.Contains(output, "boom") {
+		t.Error("quiet mode should still show errors")
+	}
+}
+
 func TestShowModels(t *testing.T) {
 	models := []string{"model-a", "model-b", "model-c"}
 	currentModel := "model-b"
@@ -140,15 +225,47 @@ func TestShowModels(t *testing.T) {
 	}
 }
 
+func TestShowModelAliases(t *testing.T) {
+	output := captureStdout(func() {
+		ShowModelAliases([]string{"fast"}, map[string]string{"fast": "sonar"})
+	})
+	if !strings.Contains(output, "fast -> sonar") {
+		t.Errorf("ShowModelAliases() = %q, should list alias and target", output)
+	}
+
+	output = captureStdout(func() {
+		ShowModelAliases(nil, nil)
+	})
+	if output != "" {
+		t.Errorf("ShowModelAliases() without aliases should print nothing, got %q", output)
+	}
+}
+
+func TestShowComparison(t *testing.T) {
+	output := captureStdout(func() {
+		ShowComparison([]ComparisonRow{
+			{Model: "sonar", Duration: 1500 * time.Millisecond, PromptTokens: 10, CompletionTokens: 20, Cost: "$0.0001"},
+			{Model: "gpt-4", PromptTokens: 10, CompletionTokens: 20},
+			{Model: "sonar-pro", Err: errors.New("timeout")},
+		})
+	})
+
+	for _, want := range []string{"| sonar | 1.5s | 10 | 20 | 30 | $0.0001 |", "| gpt-4 | 0.0s | 10 | 20 | 30 | n/a |", "| sonar-pro | 0.0s | - | - | - | failed |"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("ShowComparison() should contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
 func TestInitRenderer(t *testing.T) {
 	// Should not error
-	err := InitRenderer()
+	err := InitRenderer("")
 	if err != nil {
 		t.Errorf("InitRenderer() error = %v", err)
 	}
 
 	// Second call should also succeed (using sync.Once)
-	err = InitRenderer()
+	err = InitRenderer("")
 	if err != nil {
 		t.Errorf("InitRenderer() second call error = %v", err)
 	}
@@ -156,7 +273,7 @@ func TestInitRenderer(t *testing.T) {
 
 func TestShowContentRendered(t *testing.T) {
 	// Initialize renderer first
-	InitRenderer()
+	InitRenderer("")
 
 	output := captureStdout(func() {
 		ShowContentRendered("**Bold text**")
@@ -251,3 +368,27 @@ func TestSpinnerRaceCondition(t *testing.T) {
 	<-done
 	sp.Stop()
 }
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{time.Minute, "1 minute ago"},
+		{45 * time.Minute, "45 minutes ago"},
+		{3 * time.Hour, "3 hours ago"},
+		{30 * time.Hour, "yesterday"},
+		{5 * 24 * time.Hour, "5 days ago"},
+		{60 * 24 * time.Hour, "2026-01-14"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := RelativeTime(now.Add(-tt.ago), now); got != tt.want {
+				t.Errorf("RelativeTime(%v ago) = %q, want %q", tt.ago, got, tt.want)
+			}
+		})
+	}
+}
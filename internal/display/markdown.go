@@ -0,0 +1,141 @@
+package display
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// markdown parses and renders GitHub-flavored markdown, as the terminal renderer does
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// MarkdownToHTML converts markdown to an HTML fragment, for pasting into
+// rich-text editors
+func MarkdownToHTML(content string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(content), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// MarkdownToPlain strips markdown syntax, keeping the text, list markers and
+// code. Links keep their URL in parentheses unless it is the link text.
+func MarkdownToPlain(content string) string {
+	source := []byte(content)
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	return strings.TrimRight(plainBlocks(doc, source, "\n\n"), "\n")
+}
+
+// plainBlocks renders the block children of node separated by sep
+func plainBlocks(node ast.Node, source []byte, sep string) string {
+	var blocks []string
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		if block := plainBlock(child, source); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	return strings.Join(blocks, sep)
+}
+
+// plainBlock renders a single block node
+func plainBlock(node ast.Node, source []byte) string {
+	switch n := node.(type) {
+	case *ast.Paragraph, *ast.TextBlock, *ast.Heading:
+		return plainInline(n, source)
+	case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock:
+		return strings.TrimRight(string(linesText(n, source)), "\n")
+	case *ast.List:
+		return plainList(n, source)
+	case *east.Table:
+		var rows []string
+		for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+			var cells []string
+			for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+				cells = append(cells, plainInline(cell, source))
+			}
+			rows = append(rows, strings.Join(cells, "\t"))
+		}
+		return strings.Join(rows, "\n")
+	case *ast.ThematicBreak:
+		return ""
+	default:
+		// Block quotes and other containers
+		return plainBlocks(n, source, "\n\n")
+	}
+}
+
+// plainList renders list items with their markers, indenting continuation lines
+func plainList(list *ast.List, source []byte) string {
+	sep := "\n\n"
+	if list.IsTight {
+		sep = "\n"
+	}
+
+	var items []string
+	number := list.Start
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		marker := "- "
+		if list.IsOrdered() {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		body := plainBlocks(item, source, sep)
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, marker+strings.ReplaceAll(body, "\n", "\n"+indent))
+	}
+	return strings.Join(items, sep)
+}
+
+// plainInline renders the inline children of node as text
+func plainInline(node ast.Node, source []byte) string {
+	var b strings.Builder
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch n := child.(type) {
+		case *ast.Text:
+			b.Write(n.Value(source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				b.WriteString("\n")
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		case *ast.AutoLink:
+			b.Write(n.URL(source))
+		case *ast.Link:
+			label := plainInline(n, source)
+			b.WriteString(label)
+			if dest := string(n.Destination); dest != label {
+				b.WriteString(" (" + dest + ")")
+			}
+		case *east.TaskCheckBox:
+			if n.IsChecked {
+				b.WriteString("[x] ")
+			} else {
+				b.WriteString("[ ] ")
+			}
+		case *ast.RawHTML:
+			// Inline tags carry no text of their own
+		default:
+			// Emphasis, strikethrough, code spans and image alt text
+			b.WriteString(plainInline(n, source))
+		}
+	}
+	return b.String()
+}
+
+// linesText returns the raw source lines of a block
+func linesText(node ast.Node, source []byte) []byte {
+	var buf bytes.Buffer
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		buf.Write(segment.Value(source))
+	}
+	return buf.Bytes()
+}
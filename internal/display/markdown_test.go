@@ -0,0 +1,57 @@
+package display
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownToPlain(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"emphasis", "Some **bold**, *em* and ~~old~~ text.", "Some bold, em and old text."},
+		{"heading", "# Title\n\nBody", "Title\n\nBody"},
+		{"inline code", "Run `go test` now", "Run go test now"},
+		{"link", "See [Go](https://go.dev).", "See Go (https://go.dev)."},
+		{"link to itself", "See [https://go.dev](https://go.dev).", "See https://go.dev."},
+		{"autolink", "See <https://go.dev>.", "See https://go.dev."},
+		{"citation markers kept", "Go is fast[1].", "Go is fast[1]."},
+		{"line breaks", "one\ntwo", "one\ntwo"},
+		{"bullet list", "- one\n- two\n  more", "- one\n- two\n  more"},
+		{"ordered list", "3. c\n4. d", "3. c\n4. d"},
+		{"nested list", "- a\n  - b", "- a\n  - b"},
+		{"task list", "- [x] done\n- [ ] todo", "- [x] done\n- [ ] todo"},
+		{"code block", "```go\nfmt.Println(\"*hi*\")\n```", "fmt.Println(\"*hi*\")"},
+		{"block quote", "> quoted **text**", "quoted text"},
+		{"table", "| A | B |\n|---|---|\n| 1 | 2 |", "A\tB\n1\t2"},
+		{"image", "![a diagram](d.png)", "a diagram"},
+		{"thematic break", "a\n\n---\n\nb", "a\n\nb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkdownToPlain(tt.content); got != tt.want {
+				t.Errorf("MarkdownToPlain(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownToHTML(t *testing.T) {
+	got, err := MarkdownToHTML("# Title\n\n**bold** and [Go](https://go.dev)\n\n| A |\n|---|\n| 1 |")
+	if err != nil {
+		t.Fatalf("MarkdownToHTML() error = %v", err)
+	}
+	for _, want := range []string{
+		"<h1>Title</h1>",
+		"<strong>bold</strong>",
+		`<a href="https://go.dev">Go</a>`,
+		"<table>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("MarkdownToHTML() = %q, want it to contain %q", got, want)
+		}
+	}
+}
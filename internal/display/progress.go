@@ -0,0 +1,148 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultHeartbeat is the interval of the "still researching" lines
+const DefaultHeartbeat = time.Minute
+
+// ResearchProgress shows the progress of a deep research query, which can
+// run for over ten minutes: a spinner with the stage of the research, the
+// elapsed time and the number of searches when the API reports it, and a
+// "still researching" line at every heartbeat, so a long wait is not taken
+// for a hang. The lines are printed even when stderr is not a terminal.
+type ResearchProgress struct {
+	sp        *Spinner
+	out       io.Writer
+	heartbeat time.Duration
+	start     time.Time
+	stage     string
+	searches  int
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+	started   bool
+	stopped   bool
+	disabled  bool // Quiet mode: never shown
+	mu        sync.Mutex
+}
+
+// NewResearchProgress creates a research progress starting at stage, with a
+// heartbeat line at the given interval
+func NewResearchProgress(stage string, heartbeat time.Duration) *ResearchProgress {
+	return &ResearchProgress{
+		sp:        NewSpinner(stage + "..."),
+		out:       os.Stderr,
+		heartbeat: heartbeat,
+		stage:     stage,
+		stopChan:  make(chan struct{}),
+		disabled:  quiet,
+	}
+}
+
+// Start shows the spinner and begins the heartbeat
+func (p *ResearchProgress) Start() {
+	p.mu.Lock()
+	if p.started || p.stopped || p.disabled {
+		p.mu.Unlock()
+		return
+	}
+	p.started = true
+	p.start = time.Now()
+	p.wg.Add(1)
+	p.mu.Unlock()
+
+	p.sp.Start()
+
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stopChan:
+				return
+			case <-ticker.C:
+				p.beat()
+			}
+		}
+	}()
+}
+
+// UpdateMessage moves the research to a new stage
+func (p *ResearchProgress) UpdateMessage(stage string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stage = stage
+	p.sp.UpdateMessage(p.status())
+}
+
+// UpdateSearches sets the number of searches run so far, as reported by the API
+func (p *ResearchProgress) UpdateSearches(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n == p.searches {
+		return
+	}
+	p.searches = n
+	p.sp.UpdateMessage(p.status())
+}
+
+// Stop stops the spinner and the heartbeat
+func (p *ResearchProgress) Stop() {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return
+	}
+	p.stopped = true
+	p.mu.Unlock()
+
+	close(p.stopChan)
+	p.wg.Wait()
+	p.sp.Stop()
+}
+
+// status returns the spinner message of the current stage
+func (p *ResearchProgress) status() string {
+	switch p.searches {
+	case 0:
+		return p.stage + "..."
+	case 1:
+		return p.stage + " (1 search)..."
+	default:
+		return fmt.Sprintf("%s (%d searches)...", p.stage, p.searches)
+	}
+}
+
+// beat prints a heartbeat line above the spinner
+func (p *ResearchProgress) beat() {
+	p.mu.Lock()
+	line := p.heartbeatLine(time.Since(p.start))
+	p.mu.Unlock()
+
+	// Clear the spinner's line first; it is drawn again on its next frame
+	if p.sp.s.Active() {
+		p.sp.s.Lock()
+		defer p.sp.s.Unlock()
+		line = "\r\033[K" + line
+	}
+	fmt.Fprintln(p.out, line)
+}
+
+// heartbeatLine describes the research after elapsed
+func (p *ResearchProgress) heartbeatLine(elapsed time.Duration) string {
+	line := fmt.Sprintf("Still researching: %s elapsed", formatElapsed(elapsed))
+	switch p.searches {
+	case 0:
+	case 1:
+		line += ", 1 search so far"
+	default:
+		line += fmt.Sprintf(", %d searches so far", p.searches)
+	}
+	return line + " (" + p.stage + ")"
+}
@@ -0,0 +1,83 @@
+package display
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatElapsed(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0.0s"},
+		{1500 * time.Millisecond, "1.5s"},
+		{59*time.Second + 900*time.Millisecond, "59.9s"},
+		{time.Minute, "1m00s"},
+		{12*time.Minute + 5*time.Second + 700*time.Millisecond, "12m05s"},
+	}
+	for _, tt := range tests {
+		if got := formatElapsed(tt.d); got != tt.want {
+			t.Errorf("formatElapsed(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestResearchProgressStatus(t *testing.T) {
+	p := NewResearchProgress("Researching", time.Minute)
+	if got := p.status(); got != "Researching..." {
+		t.Errorf("status() = %q", got)
+	}
+	if got := p.heartbeatLine(90 * time.Second); got != "Still researching: 1m30s elapsed (Researching)" {
+		t.Errorf("heartbeatLine() = %q", got)
+	}
+
+	p.UpdateSearches(1)
+	if got := p.status(); got != "Researching (1 search)..." {
+		t.Errorf("status() after a search = %q", got)
+	}
+
+	p.UpdateMessage("Analyzing sources")
+	p.UpdateSearches(14)
+	if got := p.status(); got != "Analyzing sources (14 searches)..." {
+		t.Errorf("status() = %q", got)
+	}
+	if got := p.heartbeatLine(10 * time.Minute); got != "Still researching: 10m00s elapsed, 14 searches so far (Analyzing sources)" {
+		t.Errorf("heartbeatLine() = %q", got)
+	}
+}
+
+func TestResearchProgressHeartbeat(t *testing.T) {
+	var out strings.Builder
+	p := NewResearchProgress("Researching", 20*time.Millisecond)
+	p.out = &out
+	p.Start()
+	time.Sleep(70 * time.Millisecond)
+	p.Stop()
+	p.Stop() // Double stop should not panic
+
+	lines := strings.Count(out.String(), "Still researching:")
+	if lines < 2 {
+		t.Errorf("heartbeat wrote %q, want a line per interval", out.String())
+	}
+	time.Sleep(40 * time.Millisecond)
+	if strings.Count(out.String(), "Still researching:") != lines {
+		t.Error("heartbeat should stop with the progress")
+	}
+}
+
+func TestResearchProgressQuiet(t *testing.T) {
+	SetQuiet(true)
+	defer SetQuiet(false)
+
+	var out strings.Builder
+	p := NewResearchProgress("Researching", 10*time.Millisecond)
+	p.out = &out
+	p.Start()
+	time.Sleep(30 * time.Millisecond)
+	p.Stop()
+	if out.Len() != 0 {
+		t.Errorf("quiet research progress wrote %q, want nothing", out.String())
+	}
+}
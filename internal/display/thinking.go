@@ -0,0 +1,146 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+	ansiFaint     = "\033[2m"
+	ansiReset     = "\033[0m"
+)
+
+// SplitThinking separates the <think> reasoning blocks emitted by reasoning
+// models from the answer. An unterminated block runs to the end of the content.
+func SplitThinking(content string) (thinking, answer string) {
+	var thoughts, rest strings.Builder
+	for {
+		start := strings.Index(content, thinkOpenTag)
+		if start < 0 {
+			rest.WriteString(content)
+			break
+		}
+		rest.WriteString(content[:start])
+		content = content[start+len(thinkOpenTag):]
+
+		end := strings.Index(content, thinkCloseTag)
+		if end < 0 {
+			thoughts.WriteString(strings.TrimSpace(content))
+			break
+		}
+		if thoughts.Len() > 0 {
+			thoughts.WriteString("\n\n")
+		}
+		thoughts.WriteString(strings.TrimSpace(content[:end]))
+		content = content[end+len(thinkCloseTag):]
+	}
+	return strings.TrimSpace(thoughts.String()), strings.TrimSpace(rest.String())
+}
+
+// ShowThinking displays reasoning dimmed, or set apart by a header without color
+func ShowThinking(thinking string, color bool) {
+	if thinking == "" {
+		return
+	}
+	if color {
+		fmt.Println(ansiFaint + thinking + ansiReset)
+	} else {
+		fmt.Println("Thinking:")
+		fmt.Println(thinking)
+		fmt.Println("---")
+	}
+	fmt.Println()
+}
+
+// ThinkingFilter writes streamed content, displaying <think> blocks dimmed
+// or hiding them. Tags split across chunks are handled.
+type ThinkingFilter struct {
+	out      io.Writer
+	show     bool
+	color    bool
+	thinking bool
+	pending  string // Possible start of a tag, held back until the next chunk
+}
+
+// NewThinkingFilter creates a filter writing to out. Reasoning is written only if show is set.
+func NewThinkingFilter(out io.Writer, show, color bool) *ThinkingFilter {
+	return &ThinkingFilter{out: out, show: show, color: color}
+}
+
+// Write processes a streamed chunk
+func (f *ThinkingFilter) Write(chunk string) {
+	text := f.pending + chunk
+	f.pending = ""
+
+	for text != "" {
+		tag := thinkOpenTag
+		if f.thinking {
+			tag = thinkCloseTag
+		}
+
+		if i := strings.Index(text, tag); i >= 0 {
+			f.emit(text[:i])
+			f.toggle()
+			text = text[i+len(tag):]
+			continue
+		}
+
+		// Hold back a suffix that may be the start of a tag split across chunks
+		keep := partialTagSuffix(text, tag)
+		f.emit(text[:len(text)-keep])
+		f.pending = text[len(text)-keep:]
+		return
+	}
+}
+
+// Flush writes any held back content and ends an open reasoning block.
+// The underlying writer is flushed too if it buffers output.
+func (f *ThinkingFilter) Flush() {
+	f.emit(f.pending)
+	f.pending = ""
+	if f.thinking {
+		f.toggle()
+	}
+	if fl, ok := f.out.(interface{ Flush() }); ok {
+		fl.Flush()
+	}
+}
+
+// emit writes text unless it is hidden reasoning
+func (f *ThinkingFilter) emit(text string) {
+	if text == "" || (f.thinking && !f.show) {
+		return
+	}
+	fmt.Fprint(f.out, text)
+}
+
+// toggle switches between reasoning and answer, marking the boundary
+func (f *ThinkingFilter) toggle() {
+	f.thinking = !f.thinking
+	if !f.show {
+		return
+	}
+	switch {
+	case f.color && f.thinking:
+		fmt.Fprint(f.out, ansiFaint)
+	case f.color:
+		fmt.Fprint(f.out, ansiReset)
+	case f.thinking:
+		fmt.Fprintln(f.out, "Thinking:")
+	default:
+		fmt.Fprintln(f.out, "\n---")
+	}
+}
+
+// partialTagSuffix returns the length of the longest suffix of text that is a proper prefix of tag
+func partialTagSuffix(text, tag string) int {
+	for n := min(len(tag)-1, len(text)); n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
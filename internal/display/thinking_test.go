@@ -0,0 +1,86 @@
+package display
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplitThinking(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		wantThinking string
+		wantAnswer   string
+	}{
+		{"no thinking", "Just the answer", "", "Just the answer"},
+		{"leading block", "<think>\nLet me check.\n</think>\n\nThe answer is 42.", "Let me check.", "The answer is 42."},
+		{"two blocks", "<think>a</think>first <think>b</think>second", "a\n\nb", "first second"},
+		{"unterminated", "Answer <think>still thinking", "still thinking", "Answer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thinking, answer := SplitThinking(tt.content)
+			if thinking != tt.wantThinking || answer != tt.wantAnswer {
+				t.Errorf("SplitThinking() = (%q, %q), want (%q, %q)", thinking, answer, tt.wantThinking, tt.wantAnswer)
+			}
+		})
+	}
+}
+
+func TestThinkingFilter(t *testing.T) {
+	chunks := []string{"<thi", "nk>reason", "ing</th", "ink>The ", "answer <", "b>bold</b>"}
+
+	tests := []struct {
+		name  string
+		show  bool
+		color bool
+		want  string
+	}{
+		{"hidden", false, false, "The answer <b>bold</b>"},
+		{"plain", true, false, "Thinking:\nreasoning\n---\nThe answer <b>bold</b>"},
+		{"dimmed", true, true, ansiFaint + "reasoning" + ansiReset + "The answer <b>bold</b>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			f := NewThinkingFilter(&buf, tt.show, tt.color)
+			for _, chunk := range chunks {
+				f.Write(chunk)
+			}
+			f.Flush()
+			if buf.String() != tt.want {
+				t.Errorf("output = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestThinkingFilterUnterminated(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewThinkingFilter(&buf, true, true)
+	f.Write("<think>cut off <")
+	f.Flush()
+
+	if buf.String() != ansiFaint+"cut off <"+ansiReset {
+		t.Errorf("Flush() should write held back text and reset color, got %q", buf.String())
+	}
+}
+
+func TestShowThinking(t *testing.T) {
+	output := captureStdout(func() {
+		ShowThinking("step one", false)
+	})
+	if !strings.Contains(output, "Thinking:\nstep one") {
+		t.Errorf("ShowThinking() = %q", output)
+	}
+
+	output = captureStdout(func() {
+		ShowThinking("", true)
+	})
+	if output != "" {
+		t.Errorf("ShowThinking() without reasoning should print nothing, got %q", output)
+	}
+}
@@ -0,0 +1,83 @@
+// Package document extracts the text of local documents: PDF, Word (.docx),
+// HTML and plain text formats such as markdown.
+package document
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/quocvuong92/perplexity-cli/internal/webpage"
+)
+
+// MaxFileSize limits the size of documents that are read
+const MaxFileSize = 50 << 20
+
+// ErrUnsupportedFormat is returned for binary files of unknown formats
+var ErrUnsupportedFormat = errors.New("unsupported document format; use a PDF, .docx, HTML or text file")
+
+// ErrNoText is returned for documents without extractable text
+var ErrNoText = errors.New("no text found in the document")
+
+// Extract returns the text of the document at path, picking the format by
+// the file extension. Files of other extensions are read as UTF-8 text.
+func Extract(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open document: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > MaxFileSize {
+		return "", fmt.Errorf("document is %d MB, over the %d MB limit", info.Size()>>20, MaxFileSize>>20)
+	}
+
+	var text string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		text, err = extractPDF(path)
+	case ".docx":
+		text, err = extractDOCX(path)
+	case ".html", ".htm", ".xhtml":
+		text, err = extractHTML(path)
+	default:
+		text, err = extractText(path)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", ErrNoText
+	}
+	return text, nil
+}
+
+// extractHTML returns the readable text of an HTML file
+func extractHTML(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open document: %w", err)
+	}
+	defer f.Close()
+	_, text, err := webpage.Extract(f)
+	return text, err
+}
+
+// extractText reads a text file, rejecting binary files
+func extractText(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read document: %w", err)
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return "", ErrUnsupportedFormat
+	}
+	return string(data), nil
+}
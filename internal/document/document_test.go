@@ -0,0 +1,122 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withoutTools makes the external extraction tools unavailable
+func withoutTools(t *testing.T) {
+	t.Helper()
+	old := lookPath
+	lookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+	t.Cleanup(func() { lookPath = old })
+}
+
+// writeFile writes a file in a temporary directory and returns its path
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// buildPDF returns a minimal PDF with a compressed and an uncompressed content stream
+func buildPDF(t *testing.T) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write([]byte("BT /F1 12 Tf 72 720 Td (Quarterly report) Tj 0 -14 Td [(Rev) 20 (enue) -300 (grew\\051) ] TJ ET"))
+	zw.Close()
+	plain := "BT 72 600 Td (Costs fell by 3\\045.) Tj T* <FEFF00E9007400E9> Tj ET"
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	fmt.Fprintf(&b, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	b.Write(compressed.Bytes())
+	b.WriteString("\nendstream\nendobj\n")
+	fmt.Fprintf(&b, "5 0 obj\n<< /Length %d >>\nstream\r\n%s\nendstream\nendobj\n", len(plain), plain)
+	b.WriteString("6 0 obj\n<< /Subtype /Image /Filter /DCTDecode >>\nstream\n(Not text) Tj\nendstream\nendobj\n%%EOF\n")
+	return b.Bytes()
+}
+
+// buildDOCX returns a minimal Word document
+func buildDOCX(t *testing.T) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	w, err := zw.Create(docxBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Project</w:t></w:r><w:r><w:t xml:space="preserve"> plan</w:t></w:r></w:p>
+<w:p><w:r><w:t>Step</w:t><w:tab/><w:t>Owner</w:t><w:br/><w:t>Next line</w:t></w:r></w:p>
+</w:body></w:document>`))
+	zw.Close()
+	return b.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	withoutTools(t)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"notes.md", []byte("# Notes\n\nSome text.\n"), "# Notes\n\nSome text."},
+		{"page.html", []byte("<html><body><nav>Menu</nav><p>Body text.</p></body></html>"), "Body text."},
+		{"plan.docx", buildDOCX(t), "Project plan\nStep\tOwner\nNext line"},
+		{"report.pdf", buildPDF(t), "Quarterly report\nRevenue grew)\n\nCosts fell by 3%.\nété"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(writeFile(t, tt.name, tt.data))
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	withoutTools(t)
+
+	if _, err := Extract(writeFile(t, "image.bin", []byte{0x89, 'P', 'N', 'G', 0, 1})); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Extract() of a binary file error = %v, want ErrUnsupportedFormat", err)
+	}
+	if _, err := Extract(writeFile(t, "empty.txt", []byte("  \n"))); !errors.Is(err, ErrNoText) {
+		t.Errorf("Extract() of an empty file error = %v, want ErrNoText", err)
+	}
+	if _, err := Extract(writeFile(t, "scan.pdf", []byte("%PDF-1.4\n%%EOF\n"))); !errors.Is(err, ErrNoText) {
+		t.Errorf("Extract() of a PDF without text error = %v, want ErrNoText", err)
+	}
+	if _, err := Extract(writeFile(t, "fake.pdf", []byte("hello"))); err == nil || !strings.Contains(err.Error(), "not a PDF") {
+		t.Errorf("Extract() of a fake PDF error = %v", err)
+	}
+	if _, err := Extract(writeFile(t, "broken.docx", []byte("not a zip"))); err == nil {
+		t.Error("Extract() of a broken Word document should fail")
+	}
+	if _, err := Extract(filepath.Join(t.TempDir(), "missing.md")); err == nil {
+		t.Error("Extract() of a missing file should fail")
+	}
+	if _, err := Extract(t.TempDir()); err == nil {
+		t.Error("Extract() of a directory should fail")
+	}
+}
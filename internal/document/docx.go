@@ -0,0 +1,71 @@
+package document
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// docxBody is the part of a Word document holding its main text
+const docxBody = "word/document.xml"
+
+// extractDOCX returns the text of a Word document, one paragraph per line
+func extractDOCX(path string) (string, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open Word document: %w", err)
+	}
+	defer r.Close()
+
+	f, err := r.Open(docxBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to open Word document: %w", err)
+	}
+	defer f.Close()
+	return docxText(io.LimitReader(f, MaxFileSize))
+}
+
+// docxText returns the text of the WordprocessingML document body
+func docxText(r io.Reader) (string, error) {
+	var b strings.Builder
+	inText := false
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read Word document: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteString("\t")
+			case "br", "cr":
+				b.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				b.WriteString("\n")
+			case "tc":
+				b.WriteString("\t")
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+	return b.String(), nil
+}
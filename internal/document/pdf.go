@@ -0,0 +1,336 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// lookPath finds external tools, replaced in tests
+var lookPath = exec.LookPath
+
+// errNoPDFText explains why a PDF may have no extractable text
+var errNoPDFText = fmt.Errorf("%w: the PDF may be scanned or use fonts the built-in reader "+
+	"does not support; installing pdftotext (poppler-utils) may help", ErrNoText)
+
+// extractPDF returns the text of a PDF, with pdftotext from poppler if it is
+// installed and the built-in reader otherwise
+func extractPDF(path string) (string, error) {
+	if tool, err := lookPath("pdftotext"); err == nil {
+		out, err := exec.Command(tool, "-q", "-enc", "UTF-8", path, "-").Output()
+		if err == nil && strings.TrimSpace(string(out)) != "" {
+			return string(out), nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read document: %w", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return "", fmt.Errorf("%s is not a PDF file", path)
+	}
+	text := pdfText(data)
+	if strings.TrimSpace(text) == "" {
+		return "", errNoPDFText
+	}
+	return text, nil
+}
+
+// pdfText extracts the text drawn by the content streams of a PDF. It is a
+// basic reader: it decodes uncompressed and Flate-compressed streams and
+// reads the strings of the text operators, which works for PDFs with simple
+// fonts but not for scanned pages or fonts with custom encodings.
+func pdfText(data []byte) string {
+	var b strings.Builder
+	pos := 0
+	for {
+		i := bytes.Index(data[pos:], []byte("stream"))
+		if i < 0 {
+			break
+		}
+		keyword := pos + i
+		pos = keyword + len("stream")
+		if keyword > 0 && data[keyword-1] == 'd' { // endstream
+			continue
+		}
+
+		start := pos
+		if start < len(data) && data[start] == '\r' {
+			start++
+		}
+		if start < len(data) && data[start] == '\n' {
+			start++
+		}
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		pos = start + end + len("endstream")
+
+		dict := data[:keyword]
+		if obj := bytes.LastIndex(dict, []byte(" obj")); obj >= 0 {
+			dict = dict[obj:]
+		}
+		if content, ok := decodeStream(dict, data[start:start+end]); ok {
+			b.WriteString(contentText(content))
+		}
+	}
+	return normalizeText(b.String())
+}
+
+// decodeStream returns the content of a stream with the given dictionary,
+// or false for images, fonts and filters other than Flate
+func decodeStream(dict, raw []byte) ([]byte, bool) {
+	for _, skip := range []string{"/Image", "/FontFile", "/Length1", "/Length2", "/XRef", "/Metadata"} {
+		if bytes.Contains(dict, []byte(skip)) {
+			return nil, false
+		}
+	}
+	if !bytes.Contains(dict, []byte("/Filter")) {
+		return raw, true
+	}
+	if !bytes.Contains(dict, []byte("/FlateDecode")) || bytes.Count(dict, []byte("Decode")) > 1 {
+		return nil, false
+	}
+	r, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+	// Keep what was decoded of a truncated stream
+	content, _ := io.ReadAll(io.LimitReader(r, MaxFileSize))
+	return content, len(content) > 0
+}
+
+// kerningSpace is the adjustment in a TJ array, in thousandths of a text
+// space unit, beyond which a word space is assumed
+const kerningSpace = -200
+
+// contentText returns the text shown by the operators of a content stream
+func contentText(content []byte) string {
+	var b strings.Builder
+	var operands []string // Decoded strings and numbers since the last operator
+	var array []string    // Text of the TJ array being read, nil outside one
+	inArray := false
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case isPDFSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, next := literalString(content, i)
+			i = next
+			if inArray {
+				array = append(array, s)
+			} else {
+				operands = append(operands, s)
+			}
+		case c == '<' && i+1 < len(content) && content[i+1] == '<', c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			s, next := hexString(content, i)
+			i = next
+			if inArray {
+				array = append(array, s)
+			} else {
+				operands = append(operands, s)
+			}
+		case c == '[':
+			inArray, array = true, nil
+			i++
+		case c == ']':
+			inArray = false
+			operands = append(operands, strings.Join(array, ""))
+			i++
+		default:
+			start := i
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			if i == start {
+				i++ // A stray delimiter
+				continue
+			}
+			token := string(content[start:i])
+			if n, err := strconv.ParseFloat(token, 64); err == nil {
+				if inArray {
+					if n < kerningSpace {
+						array = append(array, " ")
+					}
+				} else {
+					operands = append(operands, token)
+				}
+				continue
+			}
+			if inArray {
+				continue
+			}
+
+			switch token {
+			case "Tj", "TJ":
+				if len(operands) > 0 {
+					b.WriteString(operands[len(operands)-1])
+				}
+			case "'", "\"":
+				b.WriteString("\n")
+				if len(operands) > 0 {
+					b.WriteString(operands[len(operands)-1])
+				}
+			case "T*", "ET":
+				b.WriteString("\n")
+			case "Td", "TD":
+				// A vertical move starts a new line, a horizontal one a new word
+				if len(operands) >= 2 && !isZero(operands[len(operands)-1]) {
+					b.WriteString("\n")
+				} else {
+					b.WriteString(" ")
+				}
+			case "BI":
+				// Skip the data of an inline image
+				if end := bytes.Index(content[i:], []byte("EI")); end >= 0 {
+					i += end + 2
+				} else {
+					i = len(content)
+				}
+			}
+			operands = operands[:0]
+		}
+	}
+	return b.String()
+}
+
+// literalString decodes the literal string starting at content[i], which is
+// '(', and returns it with the index after its closing parenthesis
+func literalString(content []byte, i int) (string, int) {
+	var raw []byte
+	depth := 0
+	for i++; i < len(content); i++ {
+		c := content[i]
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return decodePDFString(raw), i + 1
+			}
+			depth--
+		case '\\':
+			i++
+			if i >= len(content) {
+				return decodePDFString(raw), i
+			}
+			switch e := content[i]; e {
+			case 'n':
+				raw = append(raw, '\n')
+			case 'r':
+				raw = append(raw, '\r')
+			case 't':
+				raw = append(raw, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// A line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					for j := 0; j < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; j++ {
+						n = n*8 + int(content[i]-'0')
+						i++
+					}
+					i--
+					raw = append(raw, byte(n))
+				} else {
+					raw = append(raw, e)
+				}
+			}
+			continue
+		}
+		raw = append(raw, c)
+	}
+	return decodePDFString(raw), i
+}
+
+// hexString decodes the hex string starting at content[i], which is '<',
+// and returns it with the index after its closing '>'
+func hexString(content []byte, i int) (string, int) {
+	var digits []byte
+	for i++; i < len(content) && content[i] != '>'; i++ {
+		if c := content[i]; (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	raw := make([]byte, len(digits)/2)
+	for j := range raw {
+		n, _ := strconv.ParseUint(string(digits[2*j:2*j+2]), 16, 8)
+		raw[j] = byte(n)
+	}
+	return decodePDFString(raw), i + 1
+}
+
+// decodePDFString converts the bytes of a PDF string to text: UTF-16 if it
+// starts with a byte order mark, else Latin-1, dropping control characters
+func decodePDFString(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw)/2)
+		for j := 2; j+1 < len(raw); j += 2 {
+			units = append(units, uint16(raw[j])<<8|uint16(raw[j+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	var b strings.Builder
+	for _, c := range raw {
+		if c >= 0x20 || c == '\n' || c == '\t' {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
+
+// isZero reports whether a number operand is zero
+func isZero(operand string) bool {
+	n, err := strconv.ParseFloat(operand, 64)
+	return err == nil && n == 0
+}
+
+// isPDFSpace reports whether c is PDF whitespace
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+// isPDFDelimiter reports whether c ends a PDF token
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// normalizeText collapses runs of spaces and of blank lines
+func normalizeText(text string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
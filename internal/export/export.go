@@ -0,0 +1,227 @@
+// Package export renders stored conversations as documents for note-taking
+// apps: plain markdown, Obsidian notes with front matter, and Notion-importable
+// HTML or CSV.
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"gopkg.in/yaml.v3"
+
+	"github.com/quocvuong92/perplexity-cli/internal/display"
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+)
+
+// Export formats
+const (
+	FormatMarkdown = "markdown" // Markdown document, the default
+	FormatObsidian = "obsidian" // Markdown note with YAML front matter
+	FormatNotion   = "notion"   // HTML page for Notion's HTML import
+	FormatCSV      = "csv"      // One row per question, for a Notion database
+)
+
+// Formats lists the accepted export formats
+var Formats = []string{FormatMarkdown, FormatObsidian, FormatNotion, FormatCSV}
+
+// ErrInvalidFormat is returned when the format is not one of Formats
+var ErrInvalidFormat = errors.New("export format must be markdown, obsidian, notion or csv")
+
+// dateLayout formats dates shown in documents
+const dateLayout = "2006-01-02 15:04:05"
+
+// Extension returns the file extension of documents in the given format
+func Extension(format string) string {
+	switch format {
+	case FormatNotion:
+		return ".html"
+	case FormatCSV:
+		return ".csv"
+	default:
+		return ".md"
+	}
+}
+
+// Render renders the conversation in the given format
+func Render(format string, conv *history.ConversationEntry) (string, error) {
+	switch format {
+	case "", FormatMarkdown:
+		return Markdown(conv), nil
+	case FormatObsidian:
+		return Obsidian(conv)
+	case FormatNotion:
+		return NotionHTML(conv)
+	case FormatCSV:
+		return CSV(conv)
+	default:
+		return "", fmt.Errorf("%w: got %s", ErrInvalidFormat, format)
+	}
+}
+
+// Markdown renders the conversation as a markdown document. The citations of
+// each answer become footnotes labeled with the answer's number, e.g. [^2-1].
+func Markdown(conv *history.ConversationEntry) string {
+	var b strings.Builder
+	b.WriteString("# Conversation Export\n\n")
+	fmt.Fprintf(&b, "**Date:** %s\n", conv.UpdatedAt.Format(dateLayout))
+	fmt.Fprintf(&b, "**Model:** %s\n\n", conv.Model)
+	b.WriteString("---\n\n")
+	writeTurns(&b, conv)
+	return b.String()
+}
+
+// frontMatter holds the Obsidian properties of a note
+type frontMatter struct {
+	Title   string   `yaml:"title"`
+	Created string   `yaml:"created"`
+	Updated string   `yaml:"updated"`
+	Model   string   `yaml:"model"`
+	Tags    []string `yaml:"tags"`
+}
+
+// obsidianDateLayout formats dates as Obsidian date-time properties
+const obsidianDateLayout = "2006-01-02T15:04:05"
+
+// Obsidian renders the conversation as an Obsidian note: markdown with YAML
+// front matter holding its title, dates, model and tags. Every note is tagged
+// "perplexity".
+func Obsidian(conv *history.ConversationEntry) (string, error) {
+	tags := []string{"perplexity"}
+	for _, tag := range conv.Tags {
+		// Obsidian tags cannot contain spaces
+		if tag = strings.Join(strings.Fields(strings.TrimPrefix(tag, "#")), "-"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	properties, err := yaml.Marshal(frontMatter{
+		Title:   conv.Title(),
+		Created: conv.CreatedAt.Format(obsidianDateLayout),
+		Updated: conv.UpdatedAt.Format(obsidianDateLayout),
+		Model:   conv.Model,
+		Tags:    tags,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	b.Write(properties)
+	b.WriteString("---\n\n")
+	fmt.Fprintf(&b, "# %s\n\n", conv.Title())
+	writeTurns(&b, conv)
+	return b.String(), nil
+}
+
+// writeTurns writes the user and assistant messages under headings
+func writeTurns(b *strings.Builder, conv *history.ConversationEntry) {
+	answers := 0
+	for _, msg := range conv.Messages {
+		switch msg.Role {
+		case "user":
+			b.WriteString("## You\n\n")
+			b.WriteString(msg.Content)
+		case "assistant":
+			answers++
+			b.WriteString("## Assistant\n\n")
+			b.WriteString(display.LabeledFootnoteCitationMarkers(msg.Content, msg.Citations, fmt.Sprintf("%d-", answers)))
+		default:
+			continue
+		}
+		b.WriteString("\n\n")
+	}
+}
+
+// NotionHTML renders the conversation as an HTML page, which Notion imports
+// as a page. Each answer is followed by the footnotes of its citations.
+func NotionHTML(conv *history.ConversationEntry) (string, error) {
+	title := html.EscapeString(conv.Title())
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n</head>\n<body>\n<h1>%s</h1>\n", title, title)
+	fmt.Fprintf(&b, "<p><strong>Date:</strong> %s<br>\n<strong>Model:</strong> %s", conv.UpdatedAt.Format(dateLayout), html.EscapeString(conv.Model))
+	if len(conv.Tags) > 0 {
+		fmt.Fprintf(&b, "<br>\n<strong>Tags:</strong> %s", html.EscapeString(strings.Join(conv.Tags, ", ")))
+	}
+	b.WriteString("</p>\n")
+
+	answers := 0
+	for _, msg := range conv.Messages {
+		var content string
+		switch msg.Role {
+		case "user":
+			b.WriteString("<h2>You</h2>\n")
+			content = msg.Content
+		case "assistant":
+			answers++
+			b.WriteString("<h2>Assistant</h2>\n")
+			content = display.FootnoteCitationMarkers(msg.Content, msg.Citations)
+		default:
+			continue
+		}
+		// Footnote IDs are prefixed with the answer number to stay unique in the page
+		md := goldmark.New(goldmark.WithExtensions(
+			extension.GFM,
+			extension.NewFootnote(extension.WithFootnoteIDPrefix(fmt.Sprintf("a%d-", answers))),
+		))
+		if err := md.Convert([]byte(content), &b); err != nil {
+			return "", err
+		}
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String(), nil
+}
+
+// csvHeader names the CSV columns, which become Notion database properties
+var csvHeader = []string{"Question", "Answer", "Sources", "Model", "Date", "Tags"}
+
+// CSV renders the conversation as CSV with a row per question, its answer and
+// the numbered sources cited by the answer, for importing into a Notion
+// database.
+func CSV(conv *history.ConversationEntry) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(csvHeader); err != nil {
+		return "", err
+	}
+
+	date := conv.UpdatedAt.Format(dateLayout)
+	tags := strings.Join(conv.Tags, ", ")
+	var question string
+	pending := false
+	for _, msg := range conv.Messages {
+		switch msg.Role {
+		case "user":
+			if pending {
+				// A question left without an answer
+				if err := w.Write([]string{question, "", "", conv.Model, date, tags}); err != nil {
+					return "", err
+				}
+			}
+			question, pending = msg.Content, true
+		case "assistant":
+			sources := strings.TrimSuffix(display.CitationList(msg.Citations), "\n")
+			if err := w.Write([]string{question, msg.Content, sources, conv.Model, date, tags}); err != nil {
+				return "", err
+			}
+			question, pending = "", false
+		}
+	}
+	if pending {
+		if err := w.Write([]string{question, "", "", conv.Model, date, tags}); err != nil {
+			return "", err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
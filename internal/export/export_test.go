@@ -0,0 +1,144 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/history"
+)
+
+func testConversation() *history.ConversationEntry {
+	return &history.ConversationEntry{
+		ID:    "id1",
+		Model: "sonar-pro",
+		Messages: []history.Message{
+			{Role: "system", Content: "Be precise."},
+			{Role: "user", Content: "What is Go?"},
+			{Role: "assistant", Content: "A language[1] by Google[2].", Citations: []string{"https://go.dev", "https://google.com"}},
+			{Role: "user", Content: "Who made it?"},
+			{Role: "assistant", Content: "Rob Pike and others[1].", Citations: []string{"https://en.wikipedia.org/wiki/Go"}},
+		},
+		CreatedAt: time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2026, 1, 2, 11, 30, 0, 0, time.UTC),
+		Tags:      []string{"go", "language design"},
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	got := Markdown(testConversation())
+	want := "# Conversation Export\n\n" +
+		"**Date:** 2026-01-02 11:30:00\n" +
+		"**Model:** sonar-pro\n\n" +
+		"---\n\n" +
+		"## You\n\nWhat is Go?\n\n" +
+		"## Assistant\n\nA language[^1-1] by Google[^1-2].\n\n[^1-1]: https://go.dev\n[^1-2]: https://google.com\n\n" +
+		"## You\n\nWho made it?\n\n" +
+		"## Assistant\n\nRob Pike and others[^2-1].\n\n[^2-1]: https://en.wikipedia.org/wiki/Go\n\n"
+	if got != want {
+		t.Errorf("Markdown() = %q, want %q", got, want)
+	}
+}
+
+func TestObsidian(t *testing.T) {
+	got, err := Obsidian(testConversation())
+	if err != nil {
+		t.Fatalf("Obsidian() error = %v", err)
+	}
+	front := "---\n" +
+		"title: What is Go?\n" +
+		"created: 2026-01-02T10:00:00\n" +
+		"updated: 2026-01-02T11:30:00\n" +
+		"model: sonar-pro\n" +
+		"tags:\n    - perplexity\n    - go\n    - language-design\n" +
+		"---\n\n# What is Go?\n\n## You\n\nWhat is Go?\n\n"
+	if !strings.HasPrefix(got, front) {
+		t.Errorf("Obsidian() = %q, want it to start with %q", got, front)
+	}
+	if !strings.Contains(got, "[^2-1]: https://en.wikipedia.org/wiki/Go") {
+		t.Errorf("Obsidian() = %q, want per-answer footnotes", got)
+	}
+}
+
+func TestNotionHTML(t *testing.T) {
+	conv := testConversation()
+	conv.Messages[1].Content = "What is *Go* & <why>?"
+	got, err := NotionHTML(conv)
+	if err != nil {
+		t.Fatalf("NotionHTML() error = %v", err)
+	}
+	for _, want := range []string{
+		"<title>What is *Go* &amp; &lt;why&gt;?</title>",
+		"<strong>Model:</strong> sonar-pro",
+		"<strong>Tags:</strong> go, language design",
+		"<h2>You</h2>\n<p>What is <em>Go</em> &amp; <!-- raw HTML omitted -->?</p>",
+		`href="https://go.dev"`,
+		`id="a1-fn:1"`,
+		`id="a2-fn:1"`,
+		"</body>\n</html>\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("NotionHTML() = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "Be precise") {
+		t.Error("NotionHTML() should leave out the system prompt")
+	}
+}
+
+func TestCSV(t *testing.T) {
+	conv := testConversation()
+	conv.Messages = append(conv.Messages, history.Message{Role: "user", Content: "Unanswered"})
+	got, err := CSV(conv)
+	if err != nil {
+		t.Fatalf("CSV() error = %v", err)
+	}
+	records, err := csv.NewReader(strings.NewReader(got)).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV() = %q is not valid CSV: %v", got, err)
+	}
+
+	want := [][]string{
+		csvHeader,
+		{"What is Go?", "A language[1] by Google[2].", "1. https://go.dev\n2. https://google.com", "sonar-pro", "2026-01-02 11:30:00", "go, language design"},
+		{"Who made it?", "Rob Pike and others[1].", "1. https://en.wikipedia.org/wiki/Go", "sonar-pro", "2026-01-02 11:30:00", "go, language design"},
+		{"Unanswered", "", "", "sonar-pro", "2026-01-02 11:30:00", "go, language design"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("CSV() rows = %q, want %q", records, want)
+	}
+	for i := range want {
+		if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("CSV() row %d = %q, want %q", i, records[i], want[i])
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	conv := testConversation()
+	for _, format := range append(Formats, "") {
+		if _, err := Render(format, conv); err != nil {
+			t.Errorf("Render(%q) error = %v", format, err)
+		}
+	}
+	if _, err := Render("pdf", conv); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Render(pdf) error = %v, want ErrInvalidFormat", err)
+	}
+}
+
+func TestExtension(t *testing.T) {
+	tests := map[string]string{
+		FormatMarkdown: ".md",
+		FormatObsidian: ".md",
+		FormatNotion:   ".html",
+		FormatCSV:      ".csv",
+		"":             ".md",
+	}
+	for format, want := range tests {
+		if got := Extension(format); got != want {
+			t.Errorf("Extension(%q) = %q, want %q", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Package fileref expands @path references in prompts: the reference is
+// replaced by the path, and the file's contents are appended to the prompt
+// as a fenced code block.
+package fileref
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// trailingPunctuation may follow a reference without being part of the path
+const trailingPunctuation = ".,;:!?)]}'\"`"
+
+// Size guards, keeping expanded prompts under validation.MaxPromptLength
+const (
+	// MaxFileBytes is the largest file that can be referenced
+	MaxFileBytes = 64 * 1024
+	// MaxTotalBytes is the most file content added to a single prompt
+	MaxTotalBytes = 96 * 1024
+)
+
+// Reference errors
+var (
+	ErrNotFound = errors.New("file not found")
+	ErrTooLarge = errors.New("file too large")
+	ErrBinary   = errors.New("not a text file")
+)
+
+// File is a file whose contents were added to a prompt
+type File struct {
+	Path string // Path as written in the prompt
+	Size int
+}
+
+// Expand replaces @path references in prompt with the path and appends each
+// file's contents as a fenced code block. Relative paths are resolved
+// against dir. A reference must start the prompt or follow whitespace, so
+// email addresses are left alone, and @words that are not files, such as
+// @alice or @scope/pkg, are kept as they are. The ones that look like paths
+// are returned as missing, to warn about typos. Files that exist but cannot
+// be added are an error.
+func Expand(prompt, dir string) (string, []File, []string, error) {
+	var (
+		files   []File
+		missing []string
+		blocks  []string
+		total   int
+		out     strings.Builder
+	)
+	seen := make(map[string]bool)
+
+	rest := prompt
+	for {
+		i := referenceStart(rest)
+		if i < 0 {
+			out.WriteString(rest)
+			break
+		}
+		out.WriteString(rest[:i])
+		rest = rest[i+1:]
+
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		token := rest[:end]
+
+		path, data, err := read(token, dir)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			if looksLikePath(token) {
+				missing = append(missing, strings.TrimRight(token, trailingPunctuation))
+			}
+			out.WriteString("@" + token)
+			rest = rest[end:]
+			continue
+		case err != nil:
+			return "", nil, nil, err
+		}
+
+		// Keep trailing punctuation that was not part of the path
+		out.WriteString(path)
+		rest = rest[len(path):]
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		if total += len(data); total > MaxTotalBytes {
+			return "", nil, nil, fmt.Errorf("%w: referenced files add up to more than %s", ErrTooLarge, FormatSize(MaxTotalBytes))
+		}
+		files = append(files, File{Path: path, Size: len(data)})
+		blocks = append(blocks, block(path, data))
+	}
+
+	if len(blocks) == 0 {
+		return prompt, nil, missing, nil
+	}
+	return out.String() + "\n\n" + strings.Join(blocks, "\n\n"), files, missing, nil
+}
+
+// Token returns the @reference being typed at the end of text, without the
+// @, and whether there is one
+func Token(text string) (string, bool) {
+	i := strings.LastIndexFunc(text, unicode.IsSpace) + 1
+	if !strings.HasPrefix(text[i:], "@") {
+		return "", false
+	}
+	return text[i+1:], true
+}
+
+// referenceStart returns the index of the next @ that starts a reference, or -1
+func referenceStart(s string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '@' || i+1 == len(s) {
+			continue
+		}
+		if next, _ := utf8.DecodeRuneInString(s[i+1:]); unicode.IsSpace(next) {
+			continue
+		}
+		if prev, _ := utf8.DecodeLastRuneInString(s[:i]); i == 0 || unicode.IsSpace(prev) {
+			return i
+		}
+	}
+	return -1
+}
+
+// read reads the file named by token, retrying without trailing punctuation
+// such as the comma in "see @main.go, then". Returns the path that matched.
+func read(token, dir string) (string, []byte, error) {
+	path := token
+	for {
+		data, err := readFile(path, dir)
+		if !errors.Is(err, ErrNotFound) {
+			return path, data, err
+		}
+		trimmed := strings.TrimRight(path, trailingPunctuation)
+		if trimmed == path || trimmed == "" {
+			return token, nil, fmt.Errorf("%w: %s", ErrNotFound, token)
+		}
+		path = trimmed
+	}
+}
+
+// readFile reads a referenced text file, checking the size guards
+func readFile(path, dir string) ([]byte, error) {
+	full := path
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			full = filepath.Join(home, rest)
+		}
+	}
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(dir, full)
+	}
+
+	info, err := os.Stat(full)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, err
+	case info.IsDir():
+		return nil, fmt.Errorf("%s is a directory; reference the files in it instead", path)
+	case info.Size() > MaxFileBytes:
+		return nil, fmt.Errorf("%w: %s is %s (max: %s)", ErrTooLarge, path, FormatSize(int(info.Size())), FormatSize(MaxFileBytes))
+	}
+
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return nil, fmt.Errorf("%w: %s", ErrBinary, path)
+	}
+	return data, nil
+}
+
+// looksLikePath reports whether a token is meant as a file path rather than,
+// say, a handle
+func looksLikePath(token string) bool {
+	return strings.ContainsAny(strings.TrimRight(token, trailingPunctuation), `/\.`)
+}
+
+// block formats a file as a fenced code block headed by its path, with a
+// fence longer than any backtick run in the file
+func block(path string, data []byte) string {
+	fence := "```"
+	for strings.Contains(string(data), fence) {
+		fence += "`"
+	}
+	lang := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	content := strings.TrimRight(string(data), "\n")
+	return fmt.Sprintf("%s:\n%s%s\n%s\n%s", path, fence, lang, content, fence)
+}
+
+// FormatSize formats a byte count for messages
+func FormatSize(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%.1f KB", float64(n)/1024)
+}
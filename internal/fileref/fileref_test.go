@@ -0,0 +1,166 @@
+package fileref
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files with the given contents under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":      "package main\n",
+		"docs/README":  "Read me\n",
+		"fence.md":     "```go\nx\n```\n",
+		"binary.bin":   "a\x00b",
+		"large.txt":    strings.Repeat("a", MaxFileBytes+1),
+		"sub/file.txt": "text",
+	})
+
+	tests := []struct {
+		name    string
+		prompt  string
+		want    string
+		files   []string
+		missing []string
+		wantErr error
+	}{
+		{
+			name:   "no references",
+			prompt: "mail me@example.com about @alice",
+			want:   "mail me@example.com about @alice",
+		},
+		{
+			name:   "file",
+			prompt: "Explain @main.go",
+			want:   "Explain main.go\n\nmain.go:\n```go\npackage main\n```",
+			files:  []string{"main.go"},
+		},
+		{
+			name:   "trailing punctuation",
+			prompt: "Is @main.go, right?",
+			want:   "Is main.go, right?\n\nmain.go:\n```go\npackage main\n```",
+			files:  []string{"main.go"},
+		},
+		{
+			name:   "repeated file",
+			prompt: "@docs/README and @docs/README",
+			want:   "docs/README and docs/README\n\ndocs/README:\n```\nRead me\n```",
+			files:  []string{"docs/README"},
+		},
+		{
+			name:   "file with fences",
+			prompt: "@fence.md",
+			want:   "fence.md\n\nfence.md:\n````md\n```go\nx\n```\n````",
+			files:  []string{"fence.md"},
+		},
+		{
+			name:    "missing path",
+			prompt:  "Explain @missing.go, then @main.go",
+			want:    "Explain @missing.go, then main.go\n\nmain.go:\n```go\npackage main\n```",
+			files:   []string{"main.go"},
+			missing: []string{"missing.go"},
+		},
+		{
+			name:    "package and repository names",
+			prompt:  "Compare @types/node with @golang/go",
+			want:    "Compare @types/node with @golang/go",
+			missing: []string{"types/node", "golang/go"},
+		},
+		{
+			name:    "binary",
+			prompt:  "@binary.bin",
+			wantErr: ErrBinary,
+		},
+		{
+			name:    "too large",
+			prompt:  "@large.txt",
+			wantErr: ErrTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, files, missing, err := Expand(tt.prompt, dir)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expand() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expand() = %q, want %q", got, tt.want)
+			}
+			var paths []string
+			for _, f := range files {
+				paths = append(paths, f.Path)
+			}
+			if !slices.Equal(paths, tt.files) {
+				t.Errorf("files = %q, want %q", paths, tt.files)
+			}
+			if !slices.Equal(missing, tt.missing) {
+				t.Errorf("missing = %q, want %q", missing, tt.missing)
+			}
+		})
+	}
+}
+
+func TestExpandDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"sub/file.txt": "text"})
+
+	if _, _, _, err := Expand("@sub", dir); err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("Expand() error = %v, want a directory error", err)
+	}
+}
+
+func TestExpandTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	half := strings.Repeat("a", MaxTotalBytes/2+1)
+	writeFiles(t, dir, map[string]string{"a.txt": half, "b.txt": half})
+
+	if _, _, _, err := Expand("@a.txt @b.txt", dir); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expand() error = %v, want %v", err, ErrTooLarge)
+	}
+}
+
+func TestToken(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   string
+		wantOK bool
+	}{
+		{"Explain @cmd/ro", "cmd/ro", true},
+		{"@", "", true},
+		{"Explain cmd", "", false},
+		{"me@example", "", false},
+		{"@main.go and ", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Token(tt.text)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Token(%q) = %q, %v, want %q, %v", tt.text, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
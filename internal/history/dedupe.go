@@ -0,0 +1,82 @@
+package history
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+)
+
+// ReadFile reads the conversations of another history file, such as a
+// backup, upgrading older schema versions. The file is left untouched.
+func ReadFile(path string) ([]ConversationEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	conversations, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse history %s: %w", path, err)
+	}
+	return conversations, nil
+}
+
+// Parse returns the conversations of a history file's contents, upgrading
+// older schema versions
+func Parse(data []byte) ([]ConversationEntry, error) {
+	var other History
+	if _, err := other.decode(data); err != nil {
+		return nil, err
+	}
+	return other.Conversations, nil
+}
+
+// contentHash identifies the messages of a conversation
+func contentHash(conv ConversationEntry) [sha256.Size]byte {
+	h := sha256.New()
+	for _, msg := range conv.Messages {
+		// Length-prefixed, so that no two message lists hash alike
+		fmt.Fprintf(h, "%d:%s%d:%s", len(msg.Role), msg.Role, len(msg.Content), msg.Content)
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// Dedupe removes the conversations with the same messages as another one
+// under a different ID, as left by restoring copies of a history. The most
+// recently updated copy is kept, with the tags of all copies. It returns how
+// many conversations were removed.
+func (h *History) Dedupe() int {
+	kept := make(map[[sha256.Size]byte]int, len(h.Conversations))
+	remove := make(map[int]bool)
+	for i, conv := range h.Conversations {
+		sum := contentHash(conv)
+		j, ok := kept[sum]
+		if !ok {
+			kept[sum] = i
+			continue
+		}
+		newer, older := i, j
+		if h.Conversations[j].UpdatedAt.After(conv.UpdatedAt) {
+			newer, older = j, i
+		}
+		for _, tag := range h.Conversations[older].Tags {
+			if !h.Conversations[newer].HasTag(tag) {
+				h.Conversations[newer].Tags = append(h.Conversations[newer].Tags, tag)
+			}
+		}
+		kept[sum] = newer
+		remove[older] = true
+	}
+
+	deduped := make([]ConversationEntry, 0, len(h.Conversations)-len(remove))
+	for i, conv := range h.Conversations {
+		if remove[i] {
+			h.markDeleted(conv.ID)
+			continue
+		}
+		deduped = append(deduped, conv)
+	}
+	h.Conversations = deduped
+	return len(remove)
+}
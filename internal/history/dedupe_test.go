@@ -0,0 +1,54 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDedupe(t *testing.T) {
+	now := time.Now()
+	messages := []Message{{Role: "user", Content: "Hello"}, {Role: "assistant", Content: "Hi"}}
+	h := &History{Conversations: []ConversationEntry{
+		{ID: "a", Messages: messages, UpdatedAt: now.Add(-time.Hour), Tags: []string{"old"}},
+		{ID: "b", Messages: []Message{{Role: "user", Content: "Hello"}}, UpdatedAt: now},
+		{ID: "c", Messages: messages, UpdatedAt: now, Tags: []string{"new"}},
+		{ID: "d", Messages: []Message{{Role: "user", Content: "Hell"}, {Role: "user", Content: "oHi"}}, UpdatedAt: now},
+	}}
+
+	if removed := h.Dedupe(); removed != 1 {
+		t.Errorf("Dedupe() = %d, want 1", removed)
+	}
+	if ids := conversationIDs(h); !slices.Equal(ids, []string{"b", "c", "d"}) {
+		t.Errorf("conversations = %q, want the older copy removed", ids)
+	}
+	if tags := h.GetConversation("c").Tags; !slices.Equal(tags, []string{"new", "old"}) {
+		t.Errorf("tags = %q, want the tags of both copies", tags)
+	}
+	if h.Dedupe() != 0 {
+		t.Error("Dedupe() should find nothing left to remove")
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backup.json")
+	if err := os.WriteFile(path, []byte(`{"conversations": [{"id": "a"}, {"id": "b"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	conversations, err := ReadFile(path)
+	if err != nil || len(conversations) != 2 {
+		t.Errorf("ReadFile() = %v, %v, want 2 conversations", conversations, err)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFile(path); err == nil {
+		t.Error("ReadFile() should fail on a damaged file")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("ReadFile() should leave the file in place: %v", err)
+	}
+}
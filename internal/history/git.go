@@ -0,0 +1,120 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+// GitBackend syncs the history file through a git repository. The repository
+// is cloned into a local directory, and each push commits the history file
+// and pushes it.
+type GitBackend struct {
+	Remote string // URL of the repository
+	Dir    string // Local clone of the repository
+}
+
+// NewGitBackend creates a git backend for the repository at remote, cloned into dir
+func NewGitBackend(remote, dir string) *GitBackend {
+	return &GitBackend{Remote: remote, Dir: dir}
+}
+
+// Name describes the remote in messages, without its credentials
+func (b *GitBackend) Name() string {
+	return config.RedactURL(b.Remote)
+}
+
+// Download updates the local clone and returns its history file, or nil if
+// the repository has none yet
+func (b *GitBackend) Download(ctx context.Context) ([]byte, error) {
+	if err := b.update(ctx); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(b.file())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Upload commits the history file to the local clone updated by Download and
+// pushes it. A push rejected because the repository changed since returns
+// ErrSyncConflict.
+func (b *GitBackend) Upload(ctx context.Context, data []byte) error {
+	if err := os.WriteFile(b.file(), data, 0600); err != nil {
+		return err
+	}
+	if _, err := b.git(ctx, "add", HistoryFileName); err != nil {
+		return err
+	}
+	// Nothing to commit when the history is unchanged
+	if _, err := b.git(ctx, "diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+	if _, err := b.git(ctx, "commit", "-m", "Update conversation history"); err != nil {
+		return err
+	}
+	if _, err := b.git(ctx, "push", "--quiet", "origin", "HEAD"); err != nil {
+		if strings.Contains(err.Error(), "[rejected]") {
+			return fmt.Errorf("%w: %v", ErrSyncConflict, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// file returns the path of the history file in the local clone
+func (b *GitBackend) file() string {
+	return filepath.Join(b.Dir, HistoryFileName)
+}
+
+// update clones the repository, or resets the local clone to its latest
+// version. The clone only holds copies of the history file, merged before
+// each upload, so a commit left behind by a failed push is dropped rather
+// than merged; it would otherwise keep every later pull from fast-forwarding.
+func (b *GitBackend) update(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(b.Dir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(b.Dir), 0700); err != nil {
+			return err
+		}
+		_, err := run(ctx, "", "git", "clone", "--quiet", b.Remote, b.Dir)
+		return err
+	}
+
+	// A freshly created remote has no branch to pull yet
+	if out, err := b.git(ctx, "ls-remote", "--heads", "origin"); err != nil || strings.TrimSpace(out) == "" {
+		return err
+	}
+	if _, err := b.git(ctx, "fetch", "--quiet", "origin", "HEAD"); err != nil {
+		return err
+	}
+	_, err := b.git(ctx, "reset", "--quiet", "--hard", "FETCH_HEAD")
+	return err
+}
+
+// git runs a git command in the local clone
+func (b *GitBackend) git(ctx context.Context, args ...string) (string, error) {
+	return run(ctx, b.Dir, "git", args...)
+}
+
+// run runs a command and returns its output, with its stderr in the error
+func run(ctx context.Context, dir, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s %s: %w: %s", name, args[0], err, msg)
+		}
+		return "", fmt.Errorf("%s %s: %w", name, args[0], err)
+	}
+	return stdout.String(), nil
+}
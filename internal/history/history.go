@@ -3,27 +3,70 @@ package history
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/logging"
 )
 
 const (
 	// HistoryFileName is the name of the history file
 	HistoryFileName = "conversation-history.json"
-	// MaxHistoryEntries limits the number of conversations stored
-	MaxHistoryEntries = 50
+	// ArchiveFileName is the name of the archive file, next to the history file
+	ArchiveFileName = "conversation-archive.json"
+	// DefaultMaxEntries limits the number of conversations stored when no
+	// other limit is configured
+	DefaultMaxEntries = 50
 	// EnvHistoryPath is the environment variable for custom history path
 	EnvHistoryPath = "PERPLEXITY_HISTORY_PATH"
+	// BackupSuffix is appended to the history path to name the backup of
+	// its previous version
+	BackupSuffix = ".bak"
+	// DamagedSuffix is appended to the history path to name a history file
+	// set aside because it could not be parsed
+	DamagedSuffix = ".corrupt"
+	// deletedMaxAge is how long deletions are remembered. A copy of the
+	// history not synced for longer may bring deleted conversations back.
+	deletedMaxAge = 180 * 24 * time.Hour
 )
 
 // Message represents a chat message for history storage.
 // This is a local type to avoid circular dependencies with the api package.
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content,omitempty"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content,omitempty"`
+	Citations []string  `json:"citations,omitempty"` // Sources of an assistant message
+	Time      time.Time `json:"time,omitzero"`       // When the message was sent or received (zero in older conversations)
+	// Status of an assistant message that is not a complete answer
+	Status ResponseStatus `json:"status,omitempty"`
+}
+
+// ResponseStatus tells why an assistant message is not a complete answer
+type ResponseStatus string
+
+const (
+	// StatusEmpty is a response without content
+	StatusEmpty ResponseStatus = "empty"
+	// StatusFailed is a request that failed; the message has no content
+	StatusFailed ResponseStatus = "failed"
+	// StatusCancelled is a request interrupted by the user; the message holds
+	// what was received before
+	StatusCancelled ResponseStatus = "cancelled"
+	// StatusTruncated is an answer cut off at the token limit
+	StatusTruncated ResponseStatus = "truncated"
+)
+
+// Unanswered reports whether the message that prompted a response with this
+// status got no answer worth keeping. Such exchanges are left out of the
+// conversation sent to the API, and can be retried.
+func (s ResponseStatus) Unanswered() bool {
+	return s == StatusEmpty || s == StatusFailed || s == StatusCancelled
 }
 
 // ConversationEntry represents a saved conversation
@@ -33,12 +76,82 @@ type ConversationEntry struct {
 	Messages  []Message `json:"messages"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	Tags      []string  `json:"tags,omitempty"`
+	Pinned    bool      `json:"pinned,omitempty"` // Listed first by /history and never pruned
+	// GeneratedTitle summarizes the conversation, in place of its first
+	// message, when titles are generated
+	GeneratedTitle string `json:"title,omitempty"`
+	Usage          Usage  `json:"usage,omitzero"` // Summed over the requests of the conversation
+}
+
+// Usage is the token usage of requests and their cost, as reported by the API
+// or estimated from token prices
+type Usage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost,omitempty"` // US dollars, for the requests with a reported cost or a known price
+}
+
+// TotalTokens returns the prompt and completion tokens together
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Add adds the usage of more requests
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.Cost += other.Cost
+}
+
+// HasTag reports whether the conversation is tagged with tag, ignoring case
+func (e ConversationEntry) HasTag(tag string) bool {
+	return slices.ContainsFunc(e.Tags, func(t string) bool { return strings.EqualFold(t, tag) })
+}
+
+// maxTitleRunes limits the length of a conversation title
+const maxTitleRunes = 60
+
+// Title returns a short title for the conversation: its generated title, or
+// the first line of its first user message, shortened if needed
+func (e ConversationEntry) Title() string {
+	if e.GeneratedTitle != "" {
+		return e.GeneratedTitle
+	}
+	for _, msg := range e.Messages {
+		if msg.Role != "user" {
+			continue
+		}
+		line, _, _ := strings.Cut(strings.TrimSpace(msg.Content), "\n")
+		if runes := []rune(strings.TrimSpace(line)); len(runes) > maxTitleRunes {
+			return string(runes[:maxTitleRunes-1]) + "…"
+		}
+		return strings.TrimSpace(line)
+	}
+	return ""
+}
+
+// Retention limits the conversations kept in the history
+type Retention struct {
+	MaxAge     time.Duration // Conversations not updated for longer are removed (0 = no limit)
+	MaxEntries int           // Most recent conversations kept (0 = DefaultMaxEntries, negative = no limit)
 }
 
 // History manages conversation history persistence
 type History struct {
+	Version       int                 `json:"version"` // SchemaVersion of the file
 	Conversations []ConversationEntry `json:"conversations"`
-	path          string
+	// Deleted holds when each conversation deleted by the user was deleted,
+	// so that merging another copy of the history does not bring it back
+	Deleted   map[string]time.Time `json:"deleted,omitempty"`
+	path      string
+	retention Retention
+	// saved holds the update time of each conversation as last read from or
+	// written to the file, to tell the ones deleted by another process
+	saved map[string]time.Time
+	// deleted holds the IDs of the conversations deleted since, kept out of
+	// the file when it is merged on save
+	deleted map[string]bool
 }
 
 // NewHistory creates a new History manager
@@ -49,19 +162,87 @@ func NewHistory() *History {
 	}
 }
 
+// NewArchive creates a History manager for archived conversations. The
+// archive is kept next to the history file and is never pruned.
+func NewArchive() *History {
+	h := NewHistory()
+	if h.path != "" {
+		h.path = filepath.Join(filepath.Dir(h.path), ArchiveFileName)
+	}
+	h.retention = Retention{MaxEntries: -1}
+	return h
+}
+
 // getHistoryPath returns the path to the history file
 func getHistoryPath() string {
 	if customPath := os.Getenv(EnvHistoryPath); customPath != "" {
 		return customPath
 	}
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
+	dir := config.GetDataDir()
+	if dir == "" {
 		return ""
 	}
-	return filepath.Join(homeDir, ".local", "share", "perplexity-cli", HistoryFileName)
+	return filepath.Join(dir, HistoryFileName)
+}
+
+// Path returns the path of the history file (empty if unavailable)
+func (h *History) Path() string {
+	return h.path
+}
+
+// SetRetention sets the limits enforced when the history is loaded and saved
+func (h *History) SetRetention(r Retention) {
+	h.retention = r
 }
 
-// Load reads the history from disk
+// Prune removes the conversations outside the retention limits and returns
+// how many were removed. Pinned conversations are kept and not counted
+// against MaxEntries, and conversations without an update time are kept
+// regardless of their age.
+func (h *History) Prune(now time.Time) int {
+	before := len(h.Conversations)
+	if h.retention.MaxAge > 0 {
+		cutoff := now.Add(-h.retention.MaxAge)
+		kept := h.Conversations[:0]
+		for _, conv := range h.Conversations {
+			if conv.Pinned || conv.UpdatedAt.IsZero() || !conv.UpdatedAt.Before(cutoff) {
+				kept = append(kept, conv)
+			}
+		}
+		h.Conversations = kept
+	}
+
+	maxEntries := h.retention.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	if maxEntries > 0 {
+		// Drop the oldest unpinned conversations beyond the limit
+		excess := -maxEntries
+		for _, conv := range h.Conversations {
+			if !conv.Pinned {
+				excess++
+			}
+		}
+		kept := h.Conversations[:0]
+		for _, conv := range h.Conversations {
+			if !conv.Pinned && excess > 0 {
+				excess--
+				continue
+			}
+			kept = append(kept, conv)
+		}
+		h.Conversations = kept
+	}
+	for id, at := range h.Deleted {
+		if now.Sub(at) > deletedMaxAge {
+			delete(h.Deleted, id)
+		}
+	}
+	return before - len(h.Conversations)
+}
+
+// Load reads the history from disk and prunes it to the retention limits
 func (h *History) Load() error {
 	if h.path == "" {
 		return fmt.Errorf("history path not available")
@@ -76,14 +257,53 @@ func (h *History) Load() error {
 		return fmt.Errorf("failed to read history: %w", err)
 	}
 
-	if err := json.Unmarshal(data, h); err != nil {
-		return fmt.Errorf("failed to parse history: %w", err)
+	migrated, err := h.decode(data)
+	if errors.Is(err, ErrNewerSchema) {
+		return fmt.Errorf("failed to load history: %w", err)
+	}
+	if err != nil {
+		if err := h.restoreBackup(err); err != nil {
+			return err
+		}
+	}
+
+	h.Prune(time.Now())
+	h.markSaved()
+	if migrated {
+		// Upgrade the file in place, keeping the previous version as backup
+		if err := h.Save(); err != nil {
+			return fmt.Errorf("failed to upgrade history: %w", err)
+		}
+	}
+	return nil
+}
+
+// restoreBackup loads the backup of a history file that could not be
+// parsed, after setting the damaged file aside so it is not overwritten
+func (h *History) restoreBackup(parseErr error) error {
+	damaged := h.path + DamagedSuffix
+	if err := os.Rename(h.path, damaged); err != nil {
+		return fmt.Errorf("failed to parse history: %w", parseErr)
 	}
 
+	h.Conversations = make([]ConversationEntry, 0)
+	backup, err := os.ReadFile(h.path + BackupSuffix)
+	if err == nil {
+		_, err = h.decode(backup)
+	}
+	if err != nil {
+		h.Conversations = make([]ConversationEntry, 0)
+		return fmt.Errorf("failed to parse history, moved to %s: %w", damaged, parseErr)
+	}
+	logging.Warn("History file could not be parsed, restored its backup",
+		"damaged", damaged, logging.Err(parseErr))
 	return nil
 }
 
-// Save writes the history to disk
+// Save prunes the history to the retention limits and writes it to disk.
+// The file is locked while saving, and the changes other processes saved
+// to it since it was loaded are merged in rather than overwritten, so
+// concurrent sessions keep each other's conversations.
 func (h *History) Save() error {
 	if h.path == "" {
 		return fmt.Errorf("history path not available")
@@ -95,23 +315,151 @@ func (h *History) Save() error {
 		return fmt.Errorf("failed to create history directory: %w", err)
 	}
 
-	// Trim to max entries
-	if len(h.Conversations) > MaxHistoryEntries {
-		h.Conversations = h.Conversations[len(h.Conversations)-MaxHistoryEntries:]
+	unlock, err := lockFile(h.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	previous, _ := os.ReadFile(h.path)
+	if err := h.mergeFile(previous); err != nil {
+		return err
 	}
+	h.Prune(time.Now())
 
-	data, err := json.MarshalIndent(h, "", "  ")
+	data, err := h.encode()
 	if err != nil {
-		return fmt.Errorf("failed to marshal history: %w", err)
+		return err
 	}
 
-	if err := os.WriteFile(h.path, data, 0600); err != nil {
+	// Keep the previous version, unless it is damaged
+	if len(previous) > 0 && json.Valid(previous) {
+		if err := writeFile(h.path+BackupSuffix, previous); err != nil {
+			return fmt.Errorf("failed to back up history: %w", err)
+		}
+	}
+	if err := writeFile(h.path, data); err != nil {
 		return fmt.Errorf("failed to write history: %w", err)
 	}
 
+	h.markSaved()
+	return nil
+}
+
+// writeFile replaces the file at path with data by renaming a temporary
+// file over it, so that a crash while writing never leaves it truncated
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// mergeFile merges the conversations other processes saved to the file,
+// given its contents: new and more recently updated conversations are taken
+// from it, and the ones deleted from it are deleted here unless updated
+// since. The conversations deleted here stay deleted. An unreadable file is
+// left to be overwritten, but not one written by a newer version.
+func (h *History) mergeFile(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	var saved History
+	if _, err := saved.decode(data); errors.Is(err, ErrNewerSchema) {
+		return fmt.Errorf("failed to save history: %w", err)
+	} else if err != nil {
+		return nil
+	}
+
+	onDisk := make(map[string]bool, len(saved.Conversations))
+	for _, conv := range saved.Conversations {
+		onDisk[conv.ID] = true
+	}
+	kept := h.Conversations[:0]
+	for _, conv := range h.Conversations {
+		if seen, ok := h.saved[conv.ID]; ok && !onDisk[conv.ID] && conv.UpdatedAt.Equal(seen) {
+			continue
+		}
+		kept = append(kept, conv)
+	}
+	h.Conversations = kept
+
+	h.mergeDeleted(saved.Deleted)
+	h.merge(slices.DeleteFunc(saved.Conversations, func(conv ConversationEntry) bool {
+		return h.deleted[conv.ID] || h.isDeleted(conv)
+	}))
 	return nil
 }
 
+// markSaved records the conversations as they are in the file
+func (h *History) markSaved() {
+	h.saved = make(map[string]time.Time, len(h.Conversations))
+	for _, conv := range h.Conversations {
+		h.saved[conv.ID] = conv.UpdatedAt
+	}
+	h.deleted = nil
+}
+
+// markDeleted records the deletion of a conversation, to keep it out of the
+// file when merging it and out of the other copies of the history when
+// syncing
+func (h *History) markDeleted(id string) {
+	if h.deleted == nil {
+		h.deleted = make(map[string]bool)
+	}
+	h.deleted[id] = true
+	if h.Deleted == nil {
+		h.Deleted = make(map[string]time.Time)
+	}
+	h.Deleted[id] = time.Now()
+}
+
+// isDeleted reports whether conv was deleted, and not updated since
+func (h *History) isDeleted(conv ConversationEntry) bool {
+	at, ok := h.Deleted[conv.ID]
+	return ok && !conv.UpdatedAt.After(at)
+}
+
+// mergeDeleted adds the deletions recorded in another copy of the history
+// and removes the conversations they delete. Returns how many were removed.
+func (h *History) mergeDeleted(deleted map[string]time.Time) int {
+	for id, at := range deleted {
+		if at.After(h.Deleted[id]) {
+			if h.Deleted == nil {
+				h.Deleted = make(map[string]time.Time)
+			}
+			h.Deleted[id] = at
+		}
+	}
+	before := len(h.Conversations)
+	h.Conversations = slices.DeleteFunc(h.Conversations, h.isDeleted)
+	return before - len(h.Conversations)
+}
+
+// encode returns the history file contents
+func (h *History) encode() ([]byte, error) {
+	h.Version = SchemaVersion
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal history: %w", err)
+	}
+	return data, nil
+}
+
 // AddConversation adds a new conversation to history
 func (h *History) AddConversation(id, model string, messages []Message) {
 	entry := ConversationEntry{
@@ -156,9 +504,23 @@ func (h *History) GetLastConversation() *ConversationEntry {
 
 // Clear removes all conversation history
 func (h *History) Clear() {
+	for _, conv := range h.Conversations {
+		h.markDeleted(conv.ID)
+	}
 	h.Conversations = make([]ConversationEntry, 0)
 }
 
+// PinnedConversations returns the pinned conversations, in history order
+func (h *History) PinnedConversations() []ConversationEntry {
+	var pinned []ConversationEntry
+	for _, conv := range h.Conversations {
+		if conv.Pinned {
+			pinned = append(pinned, conv)
+		}
+	}
+	return pinned
+}
+
 // GetRecentConversations returns the N most recent conversations
 func (h *History) GetRecentConversations(n int) []ConversationEntry {
 	if n <= 0 || len(h.Conversations) == 0 {
@@ -170,24 +532,6 @@ func (h *History) GetRecentConversations(n int) []ConversationEntry {
 	return h.Conversations[len(h.Conversations)-n:]
 }
 
-// SearchConversations searches for conversations containing the keyword
-func (h *History) SearchConversations(keyword string) []ConversationEntry {
-	if keyword == "" || len(h.Conversations) == 0 {
-		return nil
-	}
-	keyword = strings.ToLower(keyword)
-	var results []ConversationEntry
-	for _, conv := range h.Conversations {
-		for _, msg := range conv.Messages {
-			if strings.Contains(strings.ToLower(msg.Content), keyword) {
-				results = append(results, conv)
-				break
-			}
-		}
-	}
-	return results
-}
-
 // DeleteConversation removes a conversation by index (1-based from recent list)
 func (h *History) DeleteConversation(index int) bool {
 	recent := h.GetRecentConversations(10)
@@ -197,9 +541,30 @@ func (h *History) DeleteConversation(index int) bool {
 	targetID := recent[index-1].ID
 	for i := range h.Conversations {
 		if h.Conversations[i].ID == targetID {
+			h.markDeleted(targetID)
 			h.Conversations = append(h.Conversations[:i], h.Conversations[i+1:]...)
 			return true
 		}
 	}
 	return false
 }
+
+// DeleteConversations removes the conversations with the given IDs and
+// returns how many were removed
+func (h *History) DeleteConversations(ids []string) int {
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	kept := h.Conversations[:0]
+	for _, conv := range h.Conversations {
+		if !remove[conv.ID] {
+			kept = append(kept, conv)
+		} else {
+			h.markDeleted(conv.ID)
+		}
+	}
+	removed := len(h.Conversations) - len(kept)
+	h.Conversations = kept
+	return removed
+}
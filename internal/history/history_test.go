@@ -1,8 +1,12 @@
 package history
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -155,45 +159,6 @@ func TestGetRecentConversations(t *testing.T) {
 	}
 }
 
-func TestSearchConversations(t *testing.T) {
-	h := NewHistory()
-
-	h.AddConversation("id1", "model", []Message{
-		{Role: "user", Content: "How do I use Go?"},
-		{Role: "assistant", Content: "Go is a programming language..."},
-	})
-	h.AddConversation("id2", "model", []Message{
-		{Role: "user", Content: "What is Python?"},
-		{Role: "assistant", Content: "Python is a language..."},
-	})
-	h.AddConversation("id3", "model", []Message{
-		{Role: "user", Content: "Tell me about Go modules"},
-	})
-
-	tests := []struct {
-		keyword string
-		want    int
-	}{
-		{"Go", 2},       // Found in id1 and id3
-		{"go", 2},       // Case insensitive
-		{"Python", 1},   // Found in id2
-		{"Java", 0},     // Not found
-		{"language", 2}, // Found in id1 and id2
-		{"", 0},         // Empty keyword
-	}
-
-	for _, tt := range tests {
-		results := h.SearchConversations(tt.keyword)
-		got := 0
-		if results != nil {
-			got = len(results)
-		}
-		if got != tt.want {
-			t.Errorf("SearchConversations(%q) returned %d results, want %d", tt.keyword, got, tt.want)
-		}
-	}
-}
-
 func TestDeleteConversation(t *testing.T) {
 	h := NewHistory()
 
@@ -304,7 +269,7 @@ func TestLoadNonExistentFile(t *testing.T) {
 	}
 }
 
-func TestMaxHistoryEntries(t *testing.T) {
+func TestDefaultMaxEntries(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "history_test")
 	if err != nil {
 		t.Fatalf("Failed to create temp dir: %v", err)
@@ -318,8 +283,8 @@ func TestMaxHistoryEntries(t *testing.T) {
 		path:          testPath,
 	}
 
-	// Add more than MaxHistoryEntries
-	for i := 0; i < MaxHistoryEntries+10; i++ {
+	// Add more than DefaultMaxEntries
+	for i := 0; i < DefaultMaxEntries+10; i++ {
 		h.Conversations = append(h.Conversations, ConversationEntry{
 			ID:        string(rune(i)),
 			Model:     "model",
@@ -343,8 +308,8 @@ func TestMaxHistoryEntries(t *testing.T) {
 		t.Fatalf("Load() error: %v", err)
 	}
 
-	if len(h2.Conversations) > MaxHistoryEntries {
-		t.Errorf("After save/load, have %d conversations, want <= %d", len(h2.Conversations), MaxHistoryEntries)
+	if len(h2.Conversations) > DefaultMaxEntries {
+		t.Errorf("After save/load, have %d conversations, want <= %d", len(h2.Conversations), DefaultMaxEntries)
 	}
 }
 
@@ -373,3 +338,272 @@ func TestConversationTimestamps(t *testing.T) {
 		t.Error("UpdatedAt should be after CreatedAt after update")
 	}
 }
+
+func TestConversationTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []Message
+		want     string
+	}{
+		{"first user message", []Message{{Role: "system", Content: "Be brief"}, {Role: "user", Content: "  What is Go?\nMore details"}}, "What is Go?"},
+		{"no user message", []Message{{Role: "system", Content: "Be brief"}}, ""},
+		{"long message", []Message{{Role: "user", Content: strings.Repeat("a", 100)}}, strings.Repeat("a", 59) + "…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (ConversationEntry{Messages: tt.messages}).Title(); got != tt.want {
+				t.Errorf("Title() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConversationGeneratedTitle(t *testing.T) {
+	conv := ConversationEntry{
+		Messages:       []Message{{Role: "user", Content: "How do goroutines differ from threads?"}},
+		GeneratedTitle: "Goroutines Versus Threads",
+	}
+	if got := conv.Title(); got != "Goroutines Versus Threads" {
+		t.Errorf("Title() = %q, want the generated title", got)
+	}
+}
+
+func TestResponseStatusUnanswered(t *testing.T) {
+	tests := []struct {
+		status ResponseStatus
+		want   bool
+	}{
+		{"", false},
+		{StatusEmpty, true},
+		{StatusFailed, true},
+		{StatusCancelled, true},
+		{StatusTruncated, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.status.Unanswered(); got != tt.want {
+			t.Errorf("%q.Unanswered() = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteConversations(t *testing.T) {
+	h := NewHistory()
+	for _, id := range []string{"a", "b", "c", "d"} {
+		h.AddConversation(id, "model", []Message{})
+	}
+
+	if n := h.DeleteConversations([]string{"b", "d", "missing"}); n != 2 {
+		t.Errorf("DeleteConversations() = %d, want 2", n)
+	}
+	if len(h.Conversations) != 2 || h.Conversations[0].ID != "a" || h.Conversations[1].ID != "c" {
+		t.Errorf("After delete, have %+v, want a and c", h.Conversations)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		retention Retention
+		count     int
+		want      int
+	}{
+		{"default entry limit", Retention{}, DefaultMaxEntries + 5, DefaultMaxEntries},
+		{"entry limit", Retention{MaxEntries: 3}, 10, 3},
+		{"no entry limit", Retention{MaxEntries: -1}, DefaultMaxEntries + 5, DefaultMaxEntries + 5},
+		{"max age", Retention{MaxAge: 5 * day}, 10, 6},
+		{"max age and entries", Retention{MaxAge: 5 * day, MaxEntries: 2}, 10, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHistory()
+			h.SetRetention(tt.retention)
+			// Oldest first, conversation i updated count-1-i days ago
+			for i := 0; i < tt.count; i++ {
+				h.Conversations = append(h.Conversations, ConversationEntry{
+					ID:        fmt.Sprint(i),
+					UpdatedAt: now.Add(-time.Duration(tt.count-1-i) * day),
+				})
+			}
+
+			removed := h.Prune(now)
+			if len(h.Conversations) != tt.want || removed != tt.count-tt.want {
+				t.Errorf("Prune() kept %d and removed %d, want %d kept", len(h.Conversations), removed, tt.want)
+			}
+			if last := h.Conversations[len(h.Conversations)-1].ID; last != fmt.Sprint(tt.count-1) {
+				t.Errorf("Prune() removed the most recent conversation, last is %s", last)
+			}
+		})
+	}
+}
+
+func TestPruneKeepsUndated(t *testing.T) {
+	h := NewHistory()
+	h.SetRetention(Retention{MaxAge: time.Hour})
+	h.Conversations = []ConversationEntry{{ID: "legacy"}}
+
+	if removed := h.Prune(time.Now()); removed != 0 {
+		t.Errorf("Prune() removed %d conversations without an update time", removed)
+	}
+}
+
+func TestNewArchive(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvHistoryPath, filepath.Join(dir, "history.json"))
+
+	archive := NewArchive()
+	if archive.Path() != filepath.Join(dir, ArchiveFileName) {
+		t.Errorf("Path() = %q, want the archive next to the history file", archive.Path())
+	}
+	for i := 0; i < DefaultMaxEntries+5; i++ {
+		archive.AddConversation(fmt.Sprint(i), "model", nil)
+	}
+	if archive.Prune(time.Now()) != 0 {
+		t.Error("the archive should not be pruned")
+	}
+}
+
+func TestSaveKeepsBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	h := &History{path: path}
+	h.AddConversation("first", "sonar", []Message{{Role: "user", Content: "Hello"}})
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + BackupSuffix); !os.IsNotExist(err) {
+		t.Errorf("the first save should not create a backup, stat error = %v", err)
+	}
+
+	h.AddConversation("second", "sonar", []Message{{Role: "user", Content: "Again"}})
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+	backup := &History{path: path + BackupSuffix}
+	if err := backup.Load(); err != nil {
+		t.Fatalf("Load() backup error = %v", err)
+	}
+	if len(backup.Conversations) != 1 || backup.Conversations[0].ID != "first" {
+		t.Errorf("backup = %+v, want the previous version", backup.Conversations)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") || strings.HasSuffix(entry.Name(), ".lock") {
+			t.Errorf("%s should not be left behind", entry.Name())
+		}
+	}
+}
+
+func TestLoadRestoresBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	h := &History{path: path}
+	h.AddConversation("first", "sonar", []Message{{Role: "user", Content: "Hello"}})
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A write cut short
+	damaged := []byte(`{"conversations": [{"id": "fir`)
+	if err := os.WriteFile(path, damaged, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &History{path: path}
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded.Conversations) != 1 || loaded.Conversations[0].ID != "first" {
+		t.Errorf("loaded %+v, want the backup", loaded.Conversations)
+	}
+	if data, err := os.ReadFile(path + DamagedSuffix); err != nil || string(data) != string(damaged) {
+		t.Errorf("damaged file = %q, %v, want it set aside", data, err)
+	}
+
+	// Saving the restored history keeps the backup intact
+	if err := loaded.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&History{path: path}).Load(); err != nil {
+		t.Errorf("Load() after saving error = %v", err)
+	}
+}
+
+func TestLoadDamagedWithoutBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &History{path: path}
+	err := h.Load()
+	if err == nil || !strings.Contains(err.Error(), DamagedSuffix) {
+		t.Errorf("Load() error = %v, want the damaged file to be set aside", err)
+	}
+	if len(h.Conversations) != 0 {
+		t.Errorf("Load() kept %d conversations, want none", len(h.Conversations))
+	}
+	if _, err := os.Stat(path + DamagedSuffix); err != nil {
+		t.Errorf("the damaged file should be kept: %v", err)
+	}
+}
+
+func TestPruneKeepsPinned(t *testing.T) {
+	now := time.Now()
+	h := NewHistory()
+	h.SetRetention(Retention{MaxAge: time.Hour, MaxEntries: 1})
+	h.Conversations = []ConversationEntry{
+		{ID: "old-pinned", Pinned: true, UpdatedAt: now.Add(-48 * time.Hour)},
+		{ID: "old", UpdatedAt: now.Add(-48 * time.Hour)},
+		{ID: "pinned", Pinned: true, UpdatedAt: now.Add(-2 * time.Minute)},
+		{ID: "older", UpdatedAt: now.Add(-time.Minute)},
+		{ID: "newest", UpdatedAt: now},
+	}
+
+	if removed := h.Prune(now); removed != 2 {
+		t.Errorf("Prune() removed %d conversations, want 2", removed)
+	}
+	if ids := conversationIDs(h); !slices.Equal(ids, []string{"old-pinned", "pinned", "newest"}) {
+		t.Errorf("Prune() kept %q, want the pinned conversations and the newest", ids)
+	}
+	if pinned := h.PinnedConversations(); len(pinned) != 2 || pinned[0].ID != "old-pinned" || pinned[1].ID != "pinned" {
+		t.Errorf("PinnedConversations() = %+v, want old-pinned and pinned", pinned)
+	}
+}
+
+func TestMessageTimeOmittedWhenUnknown(t *testing.T) {
+	data, err := json.Marshal([]Message{
+		{Role: "user", Content: "Old"},
+		{Role: "user", Content: "New", Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"role":"user","content":"Old"},{"role":"user","content":"New","time":"2026-01-02T03:04:05Z"}]`
+	if string(data) != want {
+		t.Errorf("messages = %s, want %s", data, want)
+	}
+}
+
+func TestUsageAdd(t *testing.T) {
+	usage := Usage{PromptTokens: 10, CompletionTokens: 5, Cost: 0.5}
+	usage.Add(Usage{PromptTokens: 1, CompletionTokens: 2, Cost: 0.25})
+	if usage != (Usage{PromptTokens: 11, CompletionTokens: 7, Cost: 0.75}) || usage.TotalTokens() != 18 {
+		t.Errorf("usage = %+v, total %d, want both summed", usage, usage.TotalTokens())
+	}
+
+	data, err := json.Marshal(ConversationEntry{ID: "a"})
+	if err != nil || strings.Contains(string(data), "usage") {
+		t.Errorf("conversation without usage = %s, %v, want no usage field", data, err)
+	}
+}
@@ -0,0 +1,43 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// lockTimeout bounds the wait for another process to finish saving
+	lockTimeout = 5 * time.Second
+	// staleLockAge is the age after which a lock is considered left behind
+	// by a crashed process and broken
+	staleLockAge = 30 * time.Second
+	// lockRetryInterval is the delay between attempts to take the lock
+	lockRetryInterval = 20 * time.Millisecond
+)
+
+// lockFile takes the advisory lock guarding the file at path, a ".lock" file
+// next to it, and returns a function releasing it
+func lockFile(path string) (func(), error) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock history: %w", err)
+		}
+
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("history is locked by another process (remove %s if none is running)", lock)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
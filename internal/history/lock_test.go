@@ -0,0 +1,142 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("lockFile() error = %v", err)
+	}
+
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(released)
+		unlock()
+	}()
+	unlock2, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("lockFile() while locked error = %v", err)
+	}
+	select {
+	case <-released:
+	default:
+		t.Error("lockFile() should wait for the lock to be released")
+	}
+	unlock2()
+
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("the lock file should be removed, stat error = %v", err)
+	}
+}
+
+func TestLockFileBreaksStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	if err := os.WriteFile(path+".lock", nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * staleLockAge)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("lockFile() with a stale lock error = %v", err)
+	}
+	unlock()
+}
+
+// loadHistory returns the history saved at path
+func loadHistory(t *testing.T, path string) *History {
+	t.Helper()
+	h := &History{path: path}
+	if err := h.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return h
+}
+
+func TestSaveMergesConcurrentSessions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	base := &History{path: path}
+	base.AddConversation("shared", "sonar", []Message{{Role: "user", Content: "Hello"}})
+	base.AddConversation("old", "sonar", []Message{{Role: "user", Content: "Old"}})
+	if err := base.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two sessions started from the same file
+	a := loadHistory(t, path)
+	b := loadHistory(t, path)
+
+	a.AddConversation("a", "sonar", []Message{{Role: "user", Content: "From a"}})
+	a.DeleteConversations([]string{"old"})
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	b.AddConversation("b", "sonar", []Message{{Role: "user", Content: "From b"}})
+	b.UpdateConversation("shared", []Message{{Role: "user", Content: "Hello again"}})
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := loadHistory(t, path)
+	if ids := conversationIDs(saved); !slices.Equal(ids, []string{"shared", "b", "a"}) {
+		t.Errorf("saved conversations = %q, want both sessions' conversations without the deleted one", ids)
+	}
+	if conv := saved.GetConversation("shared"); conv.Messages[0].Content != "Hello again" {
+		t.Errorf("shared conversation = %q, want the latest update", conv.Messages[0].Content)
+	}
+
+	// A conversation deleted here stays deleted, even if updated elsewhere
+	b.DeleteConversations([]string{"a"})
+	a.UpdateConversation("a", []Message{{Role: "user", Content: "From a, again"}})
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if ids := conversationIDs(loadHistory(t, path)); slices.Contains(ids, "a") {
+		t.Errorf("saved conversations = %q, want a deleted", ids)
+	}
+}
+
+func TestSaveConcurrently(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	const sessions = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, sessions)
+	for i := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h := &History{path: path}
+			h.AddConversation(strings.Repeat("x", i+1), "sonar", []Message{{Role: "user", Content: "Hi"}})
+			errs <- h.Save()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	if got := len(loadHistory(t, path).Conversations); got != sessions {
+		t.Errorf("saved %d conversations, want %d", got, sessions)
+	}
+}
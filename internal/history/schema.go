@@ -0,0 +1,91 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SchemaVersion is the version of the history file layout. A change to the
+// layout that older files cannot be read with bumps it, together with a
+// migration upgrading files of the previous version.
+const SchemaVersion = 2
+
+// ErrNewerSchema is returned for history files written by a newer version of
+// perplexity-cli, which are neither read nor overwritten
+var ErrNewerSchema = errors.New("history file was written by a newer version of perplexity-cli, upgrade to use it")
+
+// migration upgrades a history file, decoded as generic JSON, from one schema
+// version to the next
+type migration func(file map[string]any) error
+
+// migrations[v] upgrades a history file from version v to version v+1
+var migrations = []migration{
+	// Version 0, files written before the version field, share the layout
+	// of version 1
+	func(map[string]any) error { return nil },
+	// Version 2 records failed responses with a status instead of a
+	// placeholder answer
+	migrateFailedResponses,
+}
+
+// legacyFailedResponse is the answer stored in place of a failed response
+// before version 2
+const legacyFailedResponse = "I apologize, but I couldn't generate a response."
+
+// migrateFailedResponses replaces the placeholder answers of failed
+// responses with StatusFailed
+func migrateFailedResponses(file map[string]any) error {
+	conversations, _ := file["conversations"].([]any)
+	for _, c := range conversations {
+		conv, _ := c.(map[string]any)
+		messages, _ := conv["messages"].([]any)
+		for _, m := range messages {
+			msg, _ := m.(map[string]any)
+			if msg["role"] == "assistant" && msg["content"] == legacyFailedResponse {
+				delete(msg, "content")
+				msg["status"] = string(StatusFailed)
+			}
+		}
+	}
+	return nil
+}
+
+// decode parses a history file into h, upgrading it from older schema
+// versions. It returns whether the file was upgraded.
+func (h *History) decode(data []byte) (bool, error) {
+	var file map[string]any
+	if err := json.Unmarshal(data, &file); err != nil {
+		return false, err
+	}
+	if file == nil {
+		file = make(map[string]any)
+	}
+
+	version := 0
+	if v, ok := file["version"]; ok {
+		n, ok := v.(float64)
+		if !ok || n != float64(int(n)) || n < 0 {
+			return false, fmt.Errorf("invalid history version %v", v)
+		}
+		version = int(n)
+	}
+	if version > SchemaVersion {
+		return false, fmt.Errorf("%w (version %d, this one reads up to %d)", ErrNewerSchema, version, SchemaVersion)
+	}
+	if version == SchemaVersion {
+		return false, json.Unmarshal(data, h)
+	}
+
+	for v := version; v < SchemaVersion; v++ {
+		if err := migrations[v](file); err != nil {
+			return false, fmt.Errorf("failed to upgrade history from version %d: %w", v, err)
+		}
+	}
+	file["version"] = SchemaVersion
+	upgraded, err := json.Marshal(file)
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(upgraded, h)
+}
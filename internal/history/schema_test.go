@@ -0,0 +1,122 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	if len(migrations) != SchemaVersion {
+		t.Errorf("%d migrations for schema version %d, want one per version", len(migrations), SchemaVersion)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantMigrated bool
+		wantErr      error
+	}{
+		{"unversioned", `{"conversations": [{"id": "a"}]}`, true, nil},
+		{"version 1", `{"version": 1, "conversations": [{"id": "a"}]}`, true, nil},
+		{"current", `{"version": 2, "conversations": [{"id": "a"}]}`, false, nil},
+		{"newer", `{"version": 3, "conversations": [{"id": "a"}]}`, false, ErrNewerSchema},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h History
+			migrated, err := h.decode([]byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if migrated != tt.wantMigrated {
+				t.Errorf("decode() migrated = %v, want %v", migrated, tt.wantMigrated)
+			}
+			if err == nil && (h.Version != SchemaVersion || len(h.Conversations) != 1) {
+				t.Errorf("decode() = %+v, want version %d and the conversation", h, SchemaVersion)
+			}
+		})
+	}
+
+	var h History
+	if _, err := h.decode([]byte(`{"version": "one"}`)); err == nil {
+		t.Error("decode() should reject a version that is not a number")
+	}
+}
+
+func TestMigrateFailedResponses(t *testing.T) {
+	data := `{"version": 1, "conversations": [{"id": "a", "messages": [
+		{"role": "user", "content": "Hi"},
+		{"role": "assistant", "content": "I apologize, but I couldn't generate a response."},
+		{"role": "user", "content": "Hi again"},
+		{"role": "assistant", "content": "Hello"}
+	]}]}`
+
+	var h History
+	if _, err := h.decode([]byte(data)); err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+	messages := h.Conversations[0].Messages
+	if messages[1].Status != StatusFailed || messages[1].Content != "" {
+		t.Errorf("failed response = %+v, want StatusFailed without content", messages[1])
+	}
+	if messages[3].Status != "" || messages[3].Content != "Hello" {
+		t.Errorf("answer = %+v, want it unchanged", messages[3])
+	}
+}
+
+func TestLoadUpgradesInPlace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	old := `{"conversations": [{"id": "a", "model": "sonar", "messages": [{"role": "user", "content": "Hi"}]}]}`
+	if err := os.WriteFile(path, []byte(old), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &History{path: path}
+	if err := h.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(h.Conversations) != 1 || h.Conversations[0].Title() != "Hi" {
+		t.Errorf("Load() = %+v, want the old conversation", h.Conversations)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != SchemaVersion {
+		t.Errorf("upgraded file version = %d, %v, want %d", file.Version, err, SchemaVersion)
+	}
+	if backup, err := os.ReadFile(path + BackupSuffix); err != nil || string(backup) != old {
+		t.Errorf("backup = %q, %v, want the old file", backup, err)
+	}
+}
+
+func TestNewerSchemaIsLeftAlone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	newer := `{"version": 99, "conversations": [], "projects": []}`
+	if err := os.WriteFile(path, []byte(newer), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &History{path: path}
+	if err := h.Load(); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("Load() error = %v, want ErrNewerSchema", err)
+	}
+	h.AddConversation("a", "sonar", []Message{{Role: "user", Content: "Hi"}})
+	if err := h.Save(); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("Save() error = %v, want ErrNewerSchema", err)
+	}
+
+	if data, err := os.ReadFile(path); err != nil || string(data) != newer {
+		t.Errorf("history file = %q, %v, want it untouched", data, err)
+	}
+}
@@ -0,0 +1,154 @@
+package history
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// snippetContext is the number of characters kept on each side of a match
+// in a search snippet
+const snippetContext = 40
+
+// Snippet is the text around a search match, on a single line
+type Snippet struct {
+	Before string
+	Match  string
+	After  string
+}
+
+// String returns the snippet as plain text
+func (s Snippet) String() string {
+	return s.Before + s.Match + s.After
+}
+
+// SearchResult is a conversation matching a search
+type SearchResult struct {
+	Conversation ConversationEntry
+	Matches      int     // Number of matches in the whole conversation
+	Role         string  // Role of the message the snippet is taken from
+	Snippet      Snippet // Text around the first match
+}
+
+// Query describes a search of the conversations
+type Query struct {
+	Text  string    // Keyword, matched ignoring case, or a regular expression with Regex
+	Regex bool      // Text is a regular expression, case-sensitive unless it starts with (?i)
+	Role  string    // Only search messages with this role (empty = all)
+	Model string    // Only search conversations with this model (empty = all)
+	Since time.Time // Only search conversations updated since then (zero = all)
+	Tag   string    // Only search conversations with this tag (empty = all)
+}
+
+// filtered reports whether the query filters conversations by anything
+// other than their text
+func (q Query) filtered() bool {
+	return q.Model != "" || !q.Since.IsZero() || q.Tag != ""
+}
+
+// accepts reports whether a conversation passes the filters of the query
+func (q Query) accepts(conv ConversationEntry) bool {
+	return (q.Model == "" || strings.EqualFold(conv.Model, q.Model)) &&
+		(q.Since.IsZero() || !conv.UpdatedAt.Before(q.Since)) &&
+		(q.Tag == "" || conv.HasTag(q.Tag))
+}
+
+// SearchConversations searches for conversations containing the keyword,
+// ignoring case. Results are ranked as by Search.
+func (h *History) SearchConversations(keyword string) []SearchResult {
+	results, _ := h.Search(Query{Text: keyword})
+	return results
+}
+
+// Search returns the conversations with messages matching the query. Results
+// are ranked by their number of matches, and the most recently updated
+// first among equals. A query with filters but no text returns every
+// conversation passing the filters; one with neither matches nothing.
+func (h *History) Search(q Query) ([]SearchResult, error) {
+	if q.Text == "" {
+		if !q.filtered() {
+			return nil, nil
+		}
+		return h.search(nil, q), nil
+	}
+	pattern := "(?i)" + regexp.QuoteMeta(q.Text)
+	if q.Regex {
+		pattern = q.Text
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return h.search(re, q), nil
+}
+
+// search returns the conversations passing the query filters with messages
+// matching re, or all of them if re is nil, ranked
+func (h *History) search(re *regexp.Regexp, q Query) []SearchResult {
+	var results []SearchResult
+	for _, conv := range h.Conversations {
+		if !q.accepts(conv) {
+			continue
+		}
+		result := SearchResult{Conversation: conv}
+		if re == nil {
+			results = append(results, result)
+			continue
+		}
+		for _, msg := range conv.Messages {
+			if q.Role != "" && msg.Role != q.Role {
+				continue
+			}
+			matches := re.FindAllStringIndex(msg.Content, -1)
+			if len(matches) == 0 {
+				continue
+			}
+			if result.Matches == 0 {
+				result.Role = msg.Role
+				result.Snippet = newSnippet(msg.Content, matches[0][0], matches[0][1])
+			}
+			result.Matches += len(matches)
+		}
+		if result.Matches > 0 {
+			results = append(results, result)
+		}
+	}
+
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		if a.Matches != b.Matches {
+			return b.Matches - a.Matches
+		}
+		return b.Conversation.UpdatedAt.Compare(a.Conversation.UpdatedAt)
+	})
+	return results
+}
+
+// newSnippet returns the text around content[start:end], with whitespace
+// collapsed and an ellipsis where the text is cut
+func newSnippet(content string, start, end int) Snippet {
+	before := []rune(singleLine(content[:start]))
+	after := []rune(singleLine(content[end:]))
+
+	s := Snippet{Match: singleLine(content[start:end])}
+	if len(before) > snippetContext {
+		s.Before = "…" + strings.TrimLeft(string(before[len(before)-snippetContext:]), " ")
+	} else {
+		s.Before = strings.TrimLeft(string(before), " ")
+	}
+	if len(after) > snippetContext {
+		s.After = strings.TrimRight(string(after[:snippetContext]), " ") + "…"
+	} else {
+		s.After = strings.TrimRight(string(after), " ")
+	}
+	return s
+}
+
+// whitespace matches runs of whitespace, including newlines
+var whitespace = regexp.MustCompile(`\s+`)
+
+// singleLine collapses whitespace runs into single spaces
+func singleLine(s string) string {
+	return whitespace.ReplaceAllString(s, " ")
+}
@@ -0,0 +1,180 @@
+package history
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSearchConversations(t *testing.T) {
+	h := NewHistory()
+
+	h.AddConversation("id1", "model", []Message{
+		{Role: "user", Content: "How do I use Go?"},
+		{Role: "assistant", Content: "Go is a programming language..."},
+	})
+	h.AddConversation("id2", "model", []Message{
+		{Role: "user", Content: "What is Python?"},
+		{Role: "assistant", Content: "Python is a language..."},
+	})
+	h.AddConversation("id3", "model", []Message{
+		{Role: "user", Content: "Tell me about Go modules"},
+	})
+
+	tests := []struct {
+		keyword string
+		want    int
+	}{
+		{"Go", 2},       // Found in id1 and id3
+		{"go", 2},       // Case insensitive
+		{"Python", 1},   // Found in id2
+		{"Java", 0},     // Not found
+		{"language", 2}, // Found in id1 and id2
+		{"", 0},         // Empty keyword
+	}
+
+	for _, tt := range tests {
+		results := h.SearchConversations(tt.keyword)
+		got := 0
+		if results != nil {
+			got = len(results)
+		}
+		if got != tt.want {
+			t.Errorf("SearchConversations(%q) returned %d results, want %d", tt.keyword, got, tt.want)
+		}
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	h := NewHistory()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, content := range []string{"retry once", "retry, then retry again", "no match", "retry here"} {
+		h.Conversations = append(h.Conversations, ConversationEntry{
+			ID:        string(rune('a' + i)),
+			Messages:  []Message{{Role: "user", Content: content}},
+			UpdatedAt: base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	var ids []string
+	for _, result := range h.SearchConversations("RETRY") {
+		ids = append(ids, result.Conversation.ID)
+	}
+	// Most matches first, then most recently updated
+	if want := []string{"b", "d", "a"}; !slices.Equal(ids, want) {
+		t.Errorf("SearchConversations() order = %q, want %q", ids, want)
+	}
+}
+
+func TestSearchSnippet(t *testing.T) {
+	h := NewHistory()
+	long := "Some background that goes on for quite a while before the actual\nPANIC: nil map\nwrite happens in the handler, followed by much more text after it"
+	h.AddConversation("id", "model", []Message{
+		{Role: "user", Content: "Why?"},
+		{Role: "assistant", Content: long},
+	})
+
+	results := h.SearchConversations("panic")
+	if len(results) != 1 {
+		t.Fatalf("SearchConversations() returned %d results, want 1", len(results))
+	}
+	got := results[0]
+	if got.Role != "assistant" || got.Matches != 1 {
+		t.Errorf("Role = %q, Matches = %d, want assistant and 1", got.Role, got.Matches)
+	}
+	want := Snippet{
+		Before: "…on for quite a while before the actual ",
+		Match:  "PANIC",
+		After:  ": nil map write happens in the handler,…",
+	}
+	if got.Snippet != want {
+		t.Errorf("Snippet = %+v, want %+v", got.Snippet, want)
+	}
+
+	short := newSnippet("a\n\tkey  b", 3, 6)
+	if short.String() != "a key b" {
+		t.Errorf("newSnippet() = %q, want %q", short.String(), "a key b")
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	h := NewHistory()
+	h.AddConversation("crash", "model", []Message{
+		{Role: "user", Content: "Why do I get panic: assignment to entry in nil map?"},
+		{Role: "assistant", Content: "The map was never initialized."},
+	})
+	h.AddConversation("docs", "model", []Message{
+		{Role: "user", Content: "Explain how maps work"},
+		{Role: "assistant", Content: "A Panic: happens when... the map is nil"},
+	})
+	h.Conversations[1].UpdatedAt = h.Conversations[0].UpdatedAt.Add(time.Minute)
+
+	tests := []struct {
+		name    string
+		query   Query
+		want    []string
+		wantErr bool
+	}{
+		{name: "regex", query: Query{Text: `panic: \w+ to`, Regex: true}, want: []string{"crash"}},
+		{name: "regex is case-sensitive", query: Query{Text: "Panic:", Regex: true}, want: []string{"docs"}},
+		{name: "case-insensitive regex", query: Query{Text: "(?i)panic:", Regex: true}, want: []string{"docs", "crash"}},
+		{name: "role", query: Query{Text: "map", Role: "user"}, want: []string{"docs", "crash"}},
+		{name: "role without matches", query: Query{Text: "initialized", Role: "user"}},
+		{name: "keyword is not a regex", query: Query{Text: "nil map?"}, want: []string{"crash"}},
+		{name: "invalid regex", query: Query{Text: "panic: (", Regex: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := h.Search(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Search() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var ids []string
+			for _, result := range results {
+				ids = append(ids, result.Conversation.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("Search() = %q, want %q", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	h := NewHistory()
+	h.Conversations = []ConversationEntry{
+		{ID: "old", Model: "sonar", UpdatedAt: now.Add(-30 * 24 * time.Hour), Messages: []Message{{Role: "user", Content: "deploy"}}},
+		{ID: "pro", Model: "sonar-pro", UpdatedAt: now.Add(-time.Hour), Messages: []Message{{Role: "user", Content: "deploy"}}, Tags: []string{"work"}},
+		{ID: "new", Model: "sonar", UpdatedAt: now, Messages: []Message{{Role: "user", Content: "lunch"}}, Tags: []string{"Work"}},
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"model", Query{Text: "deploy", Model: "SONAR-PRO"}, []string{"pro"}},
+		{"since", Query{Text: "deploy", Since: now.Add(-7 * 24 * time.Hour)}, []string{"pro"}},
+		{"tag", Query{Text: "deploy", Tag: "work"}, []string{"pro"}},
+		{"filters only", Query{Tag: "work"}, []string{"new", "pro"}},
+		{"nothing", Query{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := h.Search(tt.query)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			var ids []string
+			for _, result := range results {
+				ids = append(ids, result.Conversation.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("Search() = %q, want %q", ids, tt.want)
+			}
+		})
+	}
+}
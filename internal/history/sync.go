@@ -0,0 +1,124 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// syncAttempts is how many times Push merges and uploads the history when
+// the remote history changes in between
+const syncAttempts = 3
+
+// ErrSyncConflict is returned by SyncBackend.Upload when the remote history
+// changed since it was downloaded
+var ErrSyncConflict = errors.New("the remote history changed since it was downloaded")
+
+// SyncBackend stores a copy of the history file on a remote, so
+// conversations can follow the user across machines
+type SyncBackend interface {
+	// Name describes the remote in messages
+	Name() string
+	// Download returns the remote history file, or nil if there is none yet
+	Download(ctx context.Context) ([]byte, error)
+	// Upload replaces the remote history file last downloaded. It returns
+	// ErrSyncConflict if the remote file changed since.
+	Upload(ctx context.Context, data []byte) error
+}
+
+// Merge adds the conversations missing from the history and replaces those
+// updated more recently elsewhere, keeping the history ordered by update
+// time. No conversation is ever removed. The conversations deleted from the
+// history are restored: their update time is moved past their deletion, so
+// that syncing does not delete them again. It returns how many
+// conversations were added or replaced.
+func (h *History) Merge(conversations []ConversationEntry) int {
+	conversations = slices.Clone(conversations)
+	for i, conv := range conversations {
+		if h.isDeleted(conv) {
+			conversations[i].UpdatedAt = h.Deleted[conv.ID].Add(time.Nanosecond)
+		}
+	}
+	changed := h.merge(conversations)
+	slices.SortStableFunc(h.Conversations, func(a, b ConversationEntry) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	})
+	return changed
+}
+
+// merge adds the conversations missing from the history, after the others,
+// and replaces those updated more recently. Returns how many were added or
+// replaced.
+func (h *History) merge(conversations []ConversationEntry) int {
+	index := make(map[string]int, len(h.Conversations))
+	for i, conv := range h.Conversations {
+		index[conv.ID] = i
+	}
+
+	changed := 0
+	for _, conv := range conversations {
+		i, ok := index[conv.ID]
+		switch {
+		case !ok:
+			index[conv.ID] = len(h.Conversations)
+			h.Conversations = append(h.Conversations, conv)
+		case conv.UpdatedAt.After(h.Conversations[i].UpdatedAt):
+			h.Conversations[i] = conv
+		default:
+			continue
+		}
+		changed++
+	}
+	return changed
+}
+
+// Pull merges the remote history into the history and returns how many
+// conversations were added, replaced or deleted. Conversations deleted
+// on either side stay deleted, unless updated since. The history is not
+// saved.
+func (h *History) Pull(ctx context.Context, backend SyncBackend) (int, error) {
+	data, err := backend.Download(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download history from %s: %w", backend.Name(), err)
+	}
+	if data == nil {
+		return 0, nil
+	}
+
+	var remote History
+	if _, err := remote.decode(data); err != nil {
+		return 0, fmt.Errorf("failed to parse history from %s: %w", backend.Name(), err)
+	}
+	deleted := h.mergeDeleted(remote.Deleted)
+	return deleted + h.Merge(slices.DeleteFunc(remote.Conversations, h.isDeleted)), nil
+}
+
+// Push merges the remote history into the history, so conversations only
+// on the remote are kept, then uploads the result. If the remote history
+// changes in between, it is merged again. It returns how many conversations
+// the merges added, replaced or deleted. The history is not saved.
+func (h *History) Push(ctx context.Context, backend SyncBackend) (int, error) {
+	total := 0
+	for attempt := 1; ; attempt++ {
+		merged, err := h.Pull(ctx, backend)
+		if err != nil {
+			return 0, err
+		}
+		total += merged
+
+		data, err := h.encode()
+		if err != nil {
+			return 0, err
+		}
+		err = backend.Upload(ctx, data)
+		if errors.Is(err, ErrSyncConflict) && attempt < syncAttempts {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to upload history to %s: %w", backend.Name(), err)
+		}
+		return total, nil
+	}
+}
@@ -0,0 +1,202 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// memoryBackend is a SyncBackend keeping the remote history in memory
+type memoryBackend struct {
+	data []byte
+}
+
+func (b *memoryBackend) Name() string { return "memory" }
+
+func (b *memoryBackend) Download(ctx context.Context) ([]byte, error) { return b.data, nil }
+
+func (b *memoryBackend) Upload(ctx context.Context, data []byte) error {
+	b.data = data
+	return nil
+}
+
+// conflictBackend is a memoryBackend whose remote history is changed
+// elsewhere between each download and upload, until changes runs out
+type conflictBackend struct {
+	memoryBackend
+	changes []ConversationEntry
+}
+
+func (b *conflictBackend) Upload(ctx context.Context, data []byte) error {
+	if len(b.changes) == 0 {
+		return b.memoryBackend.Upload(ctx, data)
+	}
+	var remote History
+	if len(b.data) > 0 {
+		if err := json.Unmarshal(b.data, &remote); err != nil {
+			return err
+		}
+	}
+	remote.Conversations = append(remote.Conversations, b.changes[0])
+	b.changes = b.changes[1:]
+	b.data, _ = json.Marshal(&remote)
+	return ErrSyncConflict
+}
+
+// conversationIDs returns the IDs of the conversations of a history
+func conversationIDs(h *History) []string {
+	var ids []string
+	for _, conv := range h.Conversations {
+		ids = append(ids, conv.ID)
+	}
+	return ids
+}
+
+func TestMerge(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+
+	h := NewHistory()
+	h.Conversations = []ConversationEntry{
+		{ID: "a", UpdatedAt: at(1), Model: "local"},
+		{ID: "b", UpdatedAt: at(3), Model: "local"},
+		{ID: "c", UpdatedAt: at(5), Model: "local"},
+	}
+
+	changed := h.Merge([]ConversationEntry{
+		{ID: "a", UpdatedAt: at(6), Model: "remote"}, // newer remotely
+		{ID: "b", UpdatedAt: at(2), Model: "remote"}, // older remotely
+		{ID: "d", UpdatedAt: at(4), Model: "remote"}, // remote only
+	})
+
+	if changed != 2 {
+		t.Errorf("Merge() = %d, want 2", changed)
+	}
+	if ids := conversationIDs(h); !slices.Equal(ids, []string{"b", "d", "c", "a"}) {
+		t.Errorf("conversations = %q, want ordered by update time", ids)
+	}
+	if a := h.GetConversation("a"); a.Model != "remote" {
+		t.Error("the newer remote version of a should replace the local one")
+	}
+	if b := h.GetConversation("b"); b.Model != "local" {
+		t.Error("the newer local version of b should be kept")
+	}
+}
+
+func TestPushPull(t *testing.T) {
+	ctx := context.Background()
+	remote := &memoryBackend{}
+
+	laptop := NewHistory()
+	laptop.AddConversation("laptop", "sonar", []Message{{Role: "user", Content: "hi"}})
+	if n, err := laptop.Push(ctx, remote); err != nil || n != 0 {
+		t.Fatalf("Push() = %d, %v, want 0, nil", n, err)
+	}
+
+	desktop := NewHistory()
+	desktop.AddConversation("desktop", "sonar", []Message{{Role: "user", Content: "hello"}})
+	if n, err := desktop.Push(ctx, remote); err != nil || n != 1 {
+		t.Fatalf("Push() = %d, %v, want 1 merged, nil", n, err)
+	}
+
+	if n, err := laptop.Pull(ctx, remote); err != nil || n != 1 {
+		t.Fatalf("Pull() = %d, %v, want 1, nil", n, err)
+	}
+	if ids := conversationIDs(laptop); !slices.Equal(ids, []string{"laptop", "desktop"}) {
+		t.Errorf("laptop conversations = %q, want both", ids)
+	}
+
+	var uploaded History
+	if err := json.Unmarshal(remote.data, &uploaded); err != nil || len(uploaded.Conversations) != 2 {
+		t.Errorf("remote has %d conversations, err %v, want 2", len(uploaded.Conversations), err)
+	}
+
+	remote.data = []byte("not json")
+	if _, err := laptop.Pull(ctx, remote); err == nil {
+		t.Error("Pull() should fail on a corrupt remote history")
+	}
+}
+
+func TestPushConflict(t *testing.T) {
+	ctx := context.Background()
+	h := NewHistory()
+	h.AddConversation("local", "sonar", []Message{{Role: "user", Content: "hi"}})
+
+	// The history uploaded elsewhere in between is merged, not overwritten
+	remote := &conflictBackend{changes: []ConversationEntry{{ID: "other", UpdatedAt: time.Now()}}}
+	if n, err := h.Push(ctx, remote); err != nil || n != 1 {
+		t.Fatalf("Push() = %d, %v, want 1 merged, nil", n, err)
+	}
+	var uploaded History
+	if err := json.Unmarshal(remote.data, &uploaded); err != nil || !slices.Equal(conversationIDs(&uploaded), []string{"local", "other"}) {
+		t.Errorf("remote conversations = %q, err %v, want both", conversationIDs(&uploaded), err)
+	}
+
+	// A remote that keeps changing is given up on
+	busy := &conflictBackend{changes: make([]ConversationEntry, syncAttempts)}
+	for i := range busy.changes {
+		busy.changes[i] = ConversationEntry{ID: fmt.Sprintf("busy-%d", i), UpdatedAt: time.Now()}
+	}
+	if _, err := h.Push(ctx, busy); !errors.Is(err, ErrSyncConflict) {
+		t.Errorf("Push() error = %v, want ErrSyncConflict after %d attempts", err, syncAttempts)
+	}
+}
+
+func TestSyncKeepsDeletions(t *testing.T) {
+	ctx := context.Background()
+	remote := &memoryBackend{}
+
+	laptop := NewHistory()
+	laptop.AddConversation("kept", "sonar", []Message{{Role: "user", Content: "hi"}})
+	laptop.AddConversation("deleted", "sonar", []Message{{Role: "user", Content: "bye"}})
+	if _, err := laptop.Push(ctx, remote); err != nil {
+		t.Fatal(err)
+	}
+	desktop := NewHistory()
+	if _, err := desktop.Pull(ctx, remote); err != nil {
+		t.Fatal(err)
+	}
+
+	// The deletion on the laptop reaches the desktop
+	laptop.DeleteConversations([]string{"deleted"})
+	if _, err := laptop.Push(ctx, remote); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := desktop.Pull(ctx, remote); err != nil || n != 1 {
+		t.Fatalf("Pull() = %d, %v, want 1 deleted, nil", n, err)
+	}
+	if ids := conversationIDs(desktop); !slices.Equal(ids, []string{"kept"}) {
+		t.Errorf("desktop conversations = %q, want the deleted one gone", ids)
+	}
+
+	// A copy that still has it does not bring it back
+	stale := NewHistory()
+	stale.AddConversation("deleted", "sonar", []Message{{Role: "user", Content: "bye"}})
+	stale.Conversations[0].UpdatedAt = time.Now().Add(-time.Hour)
+	backup := slices.Clone(stale.Conversations)
+	if _, err := stale.Push(ctx, remote); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := laptop.Pull(ctx, remote); err != nil {
+		t.Fatal(err)
+	}
+	if ids := conversationIDs(laptop); !slices.Equal(ids, []string{"kept"}) {
+		t.Errorf("laptop conversations = %q, want the deleted one kept out", ids)
+	}
+
+	// Importing it from a backup restores it
+	laptop.Merge(backup)
+	if _, err := laptop.Push(ctx, remote); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := desktop.Pull(ctx, remote); err != nil {
+		t.Fatal(err)
+	}
+	if desktop.GetConversation("deleted") == nil {
+		t.Error("a restored conversation should reach the other copies")
+	}
+}
@@ -0,0 +1,112 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+// webdavTimeout bounds each WebDAV request
+const webdavTimeout = 60 * time.Second
+
+// WebDAVBackend syncs the history file to a URL on a WebDAV server, such
+// as Nextcloud, using GET and PUT. Uploads are conditional on the ETag of
+// the last download, so that a history uploaded from elsewhere in between
+// is not overwritten.
+type WebDAVBackend struct {
+	URL      string // URL of the history file, e.g. https://dav.example.com/perplexity/history.json
+	Username string // Basic auth user (empty = no auth)
+	Password string
+	client   *http.Client
+	etag     string // ETag of the last downloaded file (empty = none sent)
+	missing  bool   // Whether the last download found no file
+}
+
+// NewWebDAVBackend creates a WebDAV backend for the history file at url
+func NewWebDAVBackend(url, username, password string) *WebDAVBackend {
+	return &WebDAVBackend{
+		URL:      url,
+		Username: username,
+		Password: password,
+		client:   &http.Client{Timeout: webdavTimeout},
+	}
+}
+
+// Name describes the remote in messages, without its credentials
+func (b *WebDAVBackend) Name() string {
+	return config.RedactURL(b.URL)
+}
+
+// Download returns the remote history file, or nil if there is none yet
+func (b *WebDAVBackend) Download(ctx context.Context) ([]byte, error) {
+	b.etag, b.missing = "", false
+	resp, err := b.do(ctx, http.MethodGet, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		b.missing = true
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET returned %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	b.etag = resp.Header.Get("ETag")
+	return data, nil
+}
+
+// Upload replaces the remote history file, provided it is still the one last
+// downloaded, and returns ErrSyncConflict otherwise
+func (b *WebDAVBackend) Upload(ctx context.Context, data []byte) error {
+	header := make(http.Header)
+	switch {
+	case b.etag != "":
+		header.Set("If-Match", b.etag)
+	case b.missing:
+		header.Set("If-None-Match", "*")
+	}
+	resp, err := b.do(ctx, http.MethodPut, data, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return fmt.Errorf("%w: PUT returned %s", ErrSyncConflict, resp.Status)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("PUT returned %s", resp.Status)
+	}
+	// The file now differs from the one downloaded
+	b.etag, b.missing = resp.Header.Get("ETag"), false
+	return nil
+}
+
+// do sends a request for the history file with the given extra headers
+func (b *WebDAVBackend) do(ctx context.Context, method string, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, b.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.Username != "" {
+		req.SetBasicAuth(b.Username, b.Password)
+	}
+	return b.client.Do(req)
+}
@@ -0,0 +1,142 @@
+// Package hooks runs user-configured executables before each query and after each
+// response. Hooks receive a JSON event on stdin, which makes it possible to implement
+// redaction, logging or notifications without modifying the CLI.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/shell"
+)
+
+const (
+	// EventPreQuery is sent before a request; hooks may rewrite the messages
+	EventPreQuery = "pre_query"
+	// EventPostResponse is sent after a successful response
+	EventPostResponse = "post_response"
+	// DefaultTimeout is the default execution timeout for a hook
+	DefaultTimeout = 10 * time.Second
+	// EnvHookEvent is the environment variable holding the event name
+	EnvHookEvent = "PERPLEXITY_HOOK_EVENT"
+)
+
+// Event is the JSON document written to a hook's stdin
+type Event struct {
+	Event     string         `json:"event"`
+	Model     string         `json:"model"`
+	Messages  []api.Message  `json:"messages"`
+	Response  string         `json:"response,omitempty"`
+	Citations []string       `json:"citations,omitempty"`
+	Usage     map[string]int `json:"usage,omitempty"`
+}
+
+// Runner executes the configured hooks
+type Runner struct {
+	cfg     *config.Config
+	timeout time.Duration
+}
+
+// NewRunner creates a hook runner. Returns nil if no hooks are configured.
+func NewRunner(cfg *config.Config) *Runner {
+	if len(cfg.Hooks.PreQuery) == 0 && len(cfg.Hooks.PostResponse) == 0 {
+		return nil
+	}
+	timeout := DefaultTimeout
+	if cfg.Hooks.Timeout > 0 {
+		timeout = time.Duration(cfg.Hooks.Timeout) * time.Second
+	}
+	return &Runner{cfg: cfg, timeout: timeout}
+}
+
+// PreQuery runs the pre-query hooks in order. A hook that prints an event on
+// stdout replaces the messages for the following hooks and the request.
+// A hook exiting with a non-zero status aborts the query.
+func (r *Runner) PreQuery(ctx context.Context, messages []api.Message) ([]api.Message, error) {
+	for _, command := range r.cfg.Hooks.PreQuery {
+		event := Event{
+			Event:    EventPreQuery,
+			Model:    r.cfg.Model,
+			Messages: messages,
+		}
+
+		output, err := r.run(ctx, command, event)
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(output)) == 0 {
+			continue
+		}
+
+		var modified Event
+		if err := json.Unmarshal(output, &modified); err != nil {
+			return nil, fmt.Errorf("pre-query hook %q printed invalid JSON: %w", command, err)
+		}
+		if len(modified.Messages) == 0 {
+			return nil, fmt.Errorf("pre-query hook %q returned no messages", command)
+		}
+		messages = modified.Messages
+	}
+	return messages, nil
+}
+
+// PostResponse runs the post-response hooks in order. Their output is ignored.
+// All hooks are run even if one fails; the first error is returned.
+func (r *Runner) PostResponse(ctx context.Context, messages []api.Message, content string, resp *api.ChatResponse) error {
+	event := Event{
+		Event:    EventPostResponse,
+		Model:    r.cfg.Model,
+		Messages: messages,
+		Response: content,
+	}
+	if resp != nil {
+		event.Citations = resp.Citations
+		event.Usage = resp.GetUsageMap()
+	}
+
+	var firstErr error
+	for _, command := range r.cfg.Hooks.PostResponse {
+		if _, err := r.run(ctx, command, event); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// run executes a single hook with the event on stdin and returns its stdout
+func (r *Runner) run(ctx context.Context, command string, event Event) ([]byte, error) {
+	input, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal hook event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	cmd := shell.Command(ctx, command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(), EnvHookEvent+"="+event.Event)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("%s hook %q timed out after %v", event.Event, command, r.timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s hook %q failed: %s", event.Event, command, msg)
+		}
+		return nil, fmt.Errorf("%s hook %q failed: %w", event.Event, command, err)
+	}
+
+	return stdout.Bytes(), nil
+}
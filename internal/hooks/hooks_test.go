@@ -0,0 +1,112 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/api"
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+func skipOnWindows(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts use sh syntax")
+	}
+}
+
+func TestNewRunner(t *testing.T) {
+	if r := NewRunner(&config.Config{}); r != nil {
+		t.Error("NewRunner() should return nil without hooks")
+	}
+
+	cfg := &config.Config{Hooks: config.HooksConfig{PostResponse: []string{"true"}}}
+	r := NewRunner(cfg)
+	if r == nil {
+		t.Fatal("NewRunner() returned nil with hooks configured")
+	}
+	if r.timeout != DefaultTimeout {
+		t.Errorf("timeout = %v, want %v", r.timeout, DefaultTimeout)
+	}
+}
+
+func TestPreQuery(t *testing.T) {
+	skipOnWindows(t)
+
+	messages := []api.Message{
+		{Role: "system", Content: "Be precise"},
+		{Role: "user", Content: "my password is hunter2"},
+	}
+
+	t.Run("no output keeps messages", func(t *testing.T) {
+		r := NewRunner(&config.Config{Hooks: config.HooksConfig{PreQuery: []string{"cat > /dev/null"}}})
+		got, err := r.PreQuery(context.Background(), messages)
+		if err != nil {
+			t.Fatalf("PreQuery() error = %v", err)
+		}
+		if len(got) != 2 || got[1].Content != messages[1].Content {
+			t.Errorf("PreQuery() = %+v, want unchanged messages", got)
+		}
+	})
+
+	t.Run("output rewrites messages", func(t *testing.T) {
+		r := NewRunner(&config.Config{Hooks: config.HooksConfig{PreQuery: []string{"sed 's/hunter2/[REDACTED]/'"}}})
+		got, err := r.PreQuery(context.Background(), messages)
+		if err != nil {
+			t.Fatalf("PreQuery() error = %v", err)
+		}
+		if got[1].Content != "my password is [REDACTED]" {
+			t.Errorf("PreQuery() content = %q, want redacted", got[1].Content)
+		}
+	})
+
+	t.Run("non-zero exit aborts", func(t *testing.T) {
+		r := NewRunner(&config.Config{Hooks: config.HooksConfig{PreQuery: []string{"echo blocked >&2; exit 1"}}})
+		_, err := r.PreQuery(context.Background(), messages)
+		if err == nil || !strings.Contains(err.Error(), "blocked") {
+			t.Errorf("PreQuery() error = %v, want hook stderr", err)
+		}
+	})
+
+	t.Run("invalid output", func(t *testing.T) {
+		r := NewRunner(&config.Config{Hooks: config.HooksConfig{PreQuery: []string{"echo not-json"}}})
+		if _, err := r.PreQuery(context.Background(), messages); err == nil {
+			t.Error("PreQuery() should fail on invalid JSON output")
+		}
+	})
+}
+
+func TestPostResponse(t *testing.T) {
+	skipOnWindows(t)
+
+	out := filepath.Join(t.TempDir(), "event.json")
+	cfg := &config.Config{
+		Model: "sonar",
+		Hooks: config.HooksConfig{PostResponse: []string{"cat > " + out}},
+	}
+	r := NewRunner(cfg)
+
+	resp := &api.ChatResponse{Citations: []string{"https://example.com"}}
+	err := r.PostResponse(context.Background(), []api.Message{{Role: "user", Content: "hi"}}, "hello", resp)
+	if err != nil {
+		t.Fatalf("PostResponse() error = %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not write event: %v", err)
+	}
+	for _, want := range []string{`"event":"post_response"`, `"model":"sonar"`, `"response":"hello"`, "https://example.com"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("event %s should contain %s", data, want)
+		}
+	}
+
+	cfg.Hooks.PostResponse = []string{"exit 2"}
+	if err := r.PostResponse(context.Background(), nil, "hello", nil); err == nil {
+		t.Error("PostResponse() should return error from failing hook")
+	}
+}
@@ -0,0 +1,143 @@
+// Package keyring stores the API key in the operating system's secret store,
+// so it is neither kept in a plain text file nor exported in every shell.
+//
+// It uses the platform's command line tool: security for the macOS keychain,
+// and secret-tool for the Secret Service (GNOME Keyring, KWallet) elsewhere.
+// Windows has no built-in tool that reads a stored credential back, so the
+// keyring is not available there.
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const (
+	// Service is the name the key is stored under
+	Service = "perplexity-cli"
+	// Account is the account name the key is stored under
+	Account = "api-key"
+)
+
+// ErrNotFound is returned when the keyring holds no API key
+var ErrNotFound = errors.New("no API key in the keyring")
+
+// Error is a keyring failure with a hint on how to fix it
+type Error struct {
+	Message string
+	Hint    string
+}
+
+func (e *Error) Error() string {
+	if e.Hint != "" {
+		return fmt.Sprintf("%s. %s", e.Message, e.Hint)
+	}
+	return e.Message
+}
+
+// tool holds the commands that read and write the key. The secret is
+// passed on stdin so it never shows in the process list.
+type tool struct {
+	name  string
+	get   []string
+	set   []string
+	input func(secret string) string // stdin of set
+}
+
+// Replaced in tests
+var (
+	goos     = runtime.GOOS
+	lookPath = exec.LookPath
+	run      = func(command []string, stdin string) (string, error) {
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = strings.NewReader(stdin)
+		out, err := cmd.Output()
+		return string(out), err
+	}
+)
+
+// detect returns the keyring tool of the operating system
+func detect() (tool, error) {
+	switch goos {
+	case "darwin":
+		if _, err := lookPath("security"); err != nil {
+			return tool{}, &Error{Message: "security command not found"}
+		}
+		return tool{
+			name: "macOS keychain",
+			get:  []string{"security", "find-generic-password", "-s", Service, "-a", Account, "-w"},
+			// security -i reads commands from stdin
+			set: []string{"security", "-i"},
+			input: func(secret string) string {
+				return fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", Service, Account, secret)
+			},
+		}, nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		if _, err := lookPath("secret-tool"); err != nil {
+			return tool{}, &Error{
+				Message: "secret-tool not found",
+				Hint:    "Install it (sudo apt install libsecret-tools) or store the key in the config file",
+			}
+		}
+		return tool{
+			name:  "Secret Service",
+			get:   []string{"secret-tool", "lookup", "service", Service, "account", Account},
+			set:   []string{"secret-tool", "store", "--label=Perplexity CLI API key", "service", Service, "account", Account},
+			input: func(secret string) string { return secret },
+		}, nil
+	default:
+		return tool{}, &Error{
+			Message: fmt.Sprintf("keyring not supported on %s", goos),
+			Hint:    "Store the key in the config file or an environment variable",
+		}
+	}
+}
+
+// Available returns the name of the keyring, or an error if there is none
+func Available() (string, error) {
+	t, err := detect()
+	if err != nil {
+		return "", err
+	}
+	return t.name, nil
+}
+
+// Get returns the API key stored in the keyring, or ErrNotFound
+func Get() (string, error) {
+	t, err := detect()
+	if err != nil {
+		return "", err
+	}
+	out, err := run(t.get, "")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// Both tools exit with an error when there is no such item
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the keyring: %w", err)
+	}
+	key := strings.TrimSpace(out)
+	if key == "" {
+		return "", ErrNotFound
+	}
+	return key, nil
+}
+
+// Set stores key in the keyring, replacing any stored before
+func Set(key string) error {
+	t, err := detect()
+	if err != nil {
+		return err
+	}
+	if _, err := run(t.set, t.input(key)); err != nil {
+		return &Error{
+			Message: fmt.Sprintf("failed to store the API key in the %s: %v", t.name, err),
+			Hint:    "Make sure the keyring is unlocked",
+		}
+	}
+	return nil
+}
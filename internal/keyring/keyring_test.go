@@ -0,0 +1,98 @@
+package keyring
+
+import (
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeKeyring makes the keyring run on the operating system name with the
+// given tools, storing the secret in memory. It returns the commands run.
+func fakeKeyring(t *testing.T, name string, tools []string) *[]string {
+	t.Helper()
+	savedGOOS, savedLookPath, savedRun := goos, lookPath, run
+	t.Cleanup(func() { goos, lookPath, run = savedGOOS, savedLookPath, savedRun })
+
+	goos = name
+	lookPath = func(file string) (string, error) {
+		if slices.Contains(tools, file) {
+			return "/usr/bin/" + file, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	var stored string
+	var ran []string
+	run = func(command []string, stdin string) (string, error) {
+		ran = append(ran, strings.Join(command, " ")+" <"+stdin)
+		switch command[1] {
+		case "find-generic-password", "lookup":
+			if stored == "" {
+				return "", &exec.ExitError{}
+			}
+			return stored + "\n", nil
+		case "-i":
+			fields := strings.Fields(stdin)
+			stored = fields[len(fields)-1]
+		default:
+			stored = stdin
+		}
+		return "", nil
+	}
+	return &ran
+}
+
+func TestKeyring(t *testing.T) {
+	tests := []struct {
+		name     string
+		goos     string
+		tools    []string
+		want     string
+		wantErr  string
+		setInput string // Expected stdin of the set command
+	}{
+		{"macOS", "darwin", []string{"security"}, "macOS keychain", "", "add-generic-password -U -s perplexity-cli -a api-key -w pplx-secret\n"},
+		{"linux", "linux", []string{"secret-tool"}, "Secret Service", "", "pplx-secret"},
+		{"linux without secret-tool", "linux", nil, "", "secret-tool not found", ""},
+		{"windows", "windows", nil, "", "not supported on windows", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran := fakeKeyring(t, tt.goos, tt.tools)
+			name, err := Available()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Available() error = %v, want %q", err, tt.wantErr)
+				}
+				if err := Set("pplx-secret"); err == nil {
+					t.Error("Set() should fail without a keyring")
+				}
+				return
+			}
+			if err != nil || name != tt.want {
+				t.Fatalf("Available() = %q, %v, want %q", name, err, tt.want)
+			}
+
+			if _, err := Get(); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() of an empty keyring error = %v, want ErrNotFound", err)
+			}
+			if err := Set("pplx-secret"); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if key, err := Get(); err != nil || key != "pplx-secret" {
+				t.Errorf("Get() = %q, %v, want the stored key", key, err)
+			}
+			// The key is only ever passed on stdin
+			for _, command := range *ran {
+				if before, _, _ := strings.Cut(command, " <"); strings.Contains(before, "pplx-secret") {
+					t.Errorf("command %q has the key in its arguments", command)
+				}
+			}
+			if set := (*ran)[1]; !strings.HasSuffix(set, " <"+tt.setInput) {
+				t.Errorf("set command = %q, want stdin %q", set, tt.setInput)
+			}
+		})
+	}
+}
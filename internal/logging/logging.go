@@ -2,6 +2,9 @@ package logging
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
@@ -26,11 +29,27 @@ const (
 	LevelError = slog.LevelError
 )
 
+// ParseLevel parses a level name: debug, info, warn or error
+func ParseLevel(name string) (Level, error) {
+	var level Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return LevelInfo, fmt.Errorf("log level must be debug, info, warn or error, got %q", name)
+	}
+	return level, nil
+}
+
+// Log formats
+const (
+	FormatText = "text" // key=value lines, for reading in a terminal
+	FormatJSON = "json" // One JSON object per line, for log collectors
+)
+
 // Config holds logging configuration
 type Config struct {
 	Level   Level
 	Output  io.Writer
 	Verbose bool
+	Format  string // FormatText (default) or FormatJSON
 }
 
 // DefaultConfig returns the default logging configuration
@@ -61,11 +80,18 @@ func Init(cfg Config) {
 			Level: level,
 		}
 
-		handler := slog.NewTextHandler(output, opts)
-		logger = slog.New(handler)
+		logger = slog.New(withRecent(newHandler(output, cfg.Format, opts)))
 	})
 }
 
+// newHandler returns the handler for the given log format
+func newHandler(output io.Writer, format string, opts *slog.HandlerOptions) slog.Handler {
+	if format == FormatJSON {
+		return slog.NewJSONHandler(output, opts)
+	}
+	return slog.NewTextHandler(output, opts)
+}
+
 // ResetForTesting resets the logger state for testing purposes.
 // This should only be used in tests, not in production code.
 func ResetForTesting() {
@@ -74,6 +100,7 @@ func ResetForTesting() {
 	logger = nil
 	loggerOnce = sync.Once{}
 	getOnce = sync.Once{}
+	recent.reset()
 }
 
 // InitDiscardLogger initializes a logger that discards all output
@@ -102,7 +129,7 @@ func Get() *slog.Logger {
 				}
 				opts := &slog.HandlerOptions{Level: level}
 				handler := slog.NewTextHandler(output, opts)
-				logger = slog.New(handler)
+				logger = slog.New(withRecent(handler))
 			})
 		}
 	})
@@ -188,3 +215,10 @@ func Err(err error) slog.Attr {
 func Duration(key string, value any) slog.Attr {
 	return slog.Any(key, value)
 }
+
+// NewRequestID returns a short random ID that ties together the log lines of one request
+func NewRequestID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
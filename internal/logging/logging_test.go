@@ -191,3 +191,65 @@ func TestResetForTesting(t *testing.T) {
 		t.Error("buf2 should contain message2")
 	}
 }
+
+func TestInitFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatText, `msg="query sent" model=sonar`},
+		{"", `msg="query sent" model=sonar`},
+		{FormatJSON, `"msg":"query sent","model":"sonar"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			ResetForTesting()
+			defer ResetForTesting()
+
+			var buf bytes.Buffer
+			Init(Config{Level: LevelInfo, Output: &buf, Format: tt.format})
+			Info("query sent", String("model", "sonar"))
+
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("output %q should contain %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Level
+		wantErr bool
+	}{
+		{"debug", LevelDebug, false},
+		{"info", LevelInfo, false},
+		{"warn", LevelWarn, false},
+		{"ERROR", LevelError, false},
+		{"trace", LevelInfo, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLevel(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	id := NewRequestID()
+	if len(id) != 8 {
+		t.Errorf("NewRequestID() = %q, want 8 hex characters", id)
+	}
+	if NewRequestID() == id {
+		t.Error("NewRequestID() should return a different ID each time")
+	}
+}
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// RecentLines is the number of log lines kept in memory for crash reports
+const RecentLines = 100
+
+// recent keeps the latest log lines at debug level, whatever the configured
+// level and output, so a crash report can show what led up to a panic
+var recent = &recentWriter{max: RecentLines}
+
+// Recent returns the latest log lines, oldest first
+func Recent() []string {
+	return recent.lines()
+}
+
+// recentWriter is a ring buffer of log lines. The slog handlers write each
+// record with a single Write call.
+type recentWriter struct {
+	mu    sync.Mutex
+	max   int
+	buf   []string
+	start int
+}
+
+func (w *recentWriter) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), "\n")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) < w.max {
+		w.buf = append(w.buf, line)
+	} else {
+		w.buf[w.start] = line
+		w.start = (w.start + 1) % w.max
+	}
+	return len(p), nil
+}
+
+func (w *recentWriter) lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	lines := make([]string, 0, len(w.buf))
+	lines = append(lines, w.buf[w.start:]...)
+	return append(lines, w.buf[:w.start]...)
+}
+
+func (w *recentWriter) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = nil
+	w.start = 0
+}
+
+// teeHandler sends each record to every handler that accepts its level
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, h := range t {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// withRecent wraps handler so records are also kept in the recent lines
+func withRecent(handler slog.Handler) slog.Handler {
+	return teeHandler{handler, slog.NewTextHandler(recent, &slog.HandlerOptions{Level: LevelDebug})}
+}
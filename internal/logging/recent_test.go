@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRecent(t *testing.T) {
+	ResetForTesting()
+	defer ResetForTesting()
+
+	var buf bytes.Buffer
+	Init(Config{Level: LevelWarn, Output: &buf})
+	Debug("sending request", String("model", "sonar"))
+	Warn("request failed")
+
+	if strings.Contains(buf.String(), "sending request") {
+		t.Errorf("output %q should not contain debug lines below the configured level", buf.String())
+	}
+
+	lines := Recent()
+	if len(lines) != 2 {
+		t.Fatalf("Recent() = %q, want 2 lines", lines)
+	}
+	if !strings.Contains(lines[0], `msg="sending request" model=sonar`) {
+		t.Errorf("Recent()[0] = %q, want the debug line", lines[0])
+	}
+	if !strings.Contains(lines[1], `msg="request failed"`) {
+		t.Errorf("Recent()[1] = %q, want the warning", lines[1])
+	}
+}
+
+func TestRecentKeepsLatestLines(t *testing.T) {
+	w := &recentWriter{max: 3}
+	for i := 1; i <= 5; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+
+	got := w.lines()
+	want := []string{"line 3", "line 4", "line 5"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("lines() = %q, want %q", got, want)
+	}
+}
+
+func TestRecentWithAttrs(t *testing.T) {
+	ResetForTesting()
+	defer ResetForTesting()
+
+	Init(Config{Output: &bytes.Buffer{}})
+	With(String("request_id", "abc123")).Debug("first chunk")
+
+	lines := Recent()
+	if len(lines) != 1 || !strings.Contains(lines[0], "request_id=abc123") {
+		t.Errorf("Recent() = %q, want the line with its logger attributes", lines)
+	}
+}
@@ -0,0 +1,173 @@
+// Package models fetches the list of available models from the provider
+// and caches it, so new models can be used without a new release.
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// CacheFileName is the name of the model cache file
+	CacheFileName = "models.json"
+	// EnvCachePath is the environment variable for a custom model cache path
+	EnvCachePath = "PERPLEXITY_MODELS_CACHE"
+	// CacheTTL is how long a fetched model list is considered fresh
+	CacheTTL = 24 * time.Hour
+	// FetchTimeout bounds a single request to the models endpoint
+	FetchTimeout = 10 * time.Second
+	// maxResponseBytes limits how much of the models response is read
+	maxResponseBytes = 1 << 20
+)
+
+// Cache holds the last fetched model list
+type Cache struct {
+	Models    []string  `json:"models"`
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetched_at"`
+	path      string
+}
+
+// NewCache creates a new model cache
+func NewCache() *Cache {
+	return &Cache{path: getCachePath()}
+}
+
+// getCachePath returns the path to the model cache file
+func getCachePath() string {
+	if customPath := os.Getenv(EnvCachePath); customPath != "" {
+		return customPath
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cacheDir, "perplexity-cli", CacheFileName)
+}
+
+// Load reads the cache from disk. A missing cache is not an error.
+func (c *Cache) Load() error {
+	if c.path == "" {
+		return fmt.Errorf("model cache path not available")
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read model cache: %w", err)
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse model cache: %w", err)
+	}
+
+	return nil
+}
+
+// Save writes the cache to disk
+func (c *Cache) Save() error {
+	if c.path == "" {
+		return fmt.Errorf("model cache path not available")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return fmt.Errorf("failed to create model cache directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal model cache: %w", err)
+	}
+
+	if err := os.WriteFile(c.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write model cache: %w", err)
+	}
+
+	return nil
+}
+
+// IsStale reports whether the cache is empty or older than ttl
+func (c *Cache) IsStale(ttl time.Duration) bool {
+	return len(c.Models) == 0 || time.Since(c.FetchedAt) > ttl
+}
+
+// Update replaces the cached models and records where they came from
+func (c *Cache) Update(models []string, source string) {
+	c.Models = models
+	c.Source = source
+	c.FetchedAt = time.Now()
+}
+
+// modelsResponse covers the OpenAI-style {"data": [{"id": ...}]} shape
+// returned by models endpoints, and the {"models": [...]} manifest shape.
+type modelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+	Models []string `json:"models"`
+}
+
+// Fetch retrieves the model names from url, which may be a provider models
+// endpoint or a static manifest. apiKey is sent as a bearer token when set.
+func Fetch(ctx context.Context, client *http.Client, url, apiKey string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch models: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read models response: %w", err)
+	}
+
+	return parseModels(body)
+}
+
+// parseModels extracts model names from a models response or manifest,
+// which may also be a plain JSON array of names
+func parseModels(body []byte) ([]string, error) {
+	var names []string
+	if err := json.Unmarshal(body, &names); err != nil {
+		var parsed modelsResponse
+		if err := json.Unmarshal(body, &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse models response: %w", err)
+		}
+		names = parsed.Models
+		for _, m := range parsed.Data {
+			names = append(names, m.ID)
+		}
+	}
+
+	models := make([]string, 0, len(names))
+	for _, name := range names {
+		if name != "" {
+			models = append(models, name)
+		}
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("models response contains no models")
+	}
+	return models, nil
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseModels(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		wantErr bool
+	}{
+		{"models endpoint", `{"object":"list","data":[{"id":"sonar"},{"id":"sonar-pro"}]}`, []string{"sonar", "sonar-pro"}, false},
+		{"manifest", `{"models":["sonar","sonar-ultra"]}`, []string{"sonar", "sonar-ultra"}, false},
+		{"plain list", `["sonar","","sonar-pro"]`, []string{"sonar", "sonar-pro"}, false},
+		{"empty", `{"data":[]}`, nil, true},
+		{"invalid", `<html>`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseModels([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseModels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseModels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"sonar-ultra"}]}`))
+	}))
+	defer server.Close()
+
+	got, err := Fetch(context.Background(), server.Client(), server.URL, "test-key")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if !slices.Equal(got, []string{"sonar-ultra"}) {
+		t.Errorf("Fetch() = %v, want [sonar-ultra]", got)
+	}
+
+	if _, err := Fetch(context.Background(), server.Client(), server.URL, ""); err == nil {
+		t.Error("Fetch() should fail on non-200 status")
+	}
+}
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", CacheFileName)
+	t.Setenv(EnvCachePath, path)
+
+	cache := NewCache()
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Load() on missing cache error = %v", err)
+	}
+	if !cache.IsStale(CacheTTL) {
+		t.Error("empty cache should be stale")
+	}
+
+	cache.Update([]string{"sonar", "sonar-ultra"}, "https://example.com/models")
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded := NewCache()
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !slices.Equal(loaded.Models, cache.Models) || loaded.Source != cache.Source {
+		t.Errorf("loaded cache = %+v, want %+v", loaded, cache)
+	}
+	if loaded.IsStale(CacheTTL) {
+		t.Error("freshly saved cache should not be stale")
+	}
+
+	loaded.FetchedAt = time.Now().Add(-2 * CacheTTL)
+	if !loaded.IsStale(CacheTTL) {
+		t.Error("old cache should be stale")
+	}
+}
@@ -0,0 +1,92 @@
+// Package pool runs independent requests concurrently on a bounded number of
+// workers, paced by a rate limiter, and keeps the error of each item.
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/quocvuong92/perplexity-cli/internal/ratelimit"
+)
+
+// DefaultWorkers is the number of concurrent items when none is configured
+const DefaultWorkers = 4
+
+// Options configures a pool run
+type Options struct {
+	Workers  int                       // Concurrent items (0 = DefaultWorkers)
+	Limiter  *ratelimit.Limiter        // Paces the start of the items (nil = no limit)
+	Progress func(finished, total int) // Called after each item, from one goroutine at a time
+}
+
+// Run calls fn for the items 0 to n-1 on up to opts.Workers goroutines and
+// returns the error of each item by index. Every item waits for the limiter
+// before it starts, so the items together keep to its rate. Items not
+// started when ctx is cancelled fail with ctx's error.
+func Run(ctx context.Context, n int, opts Options, fn func(ctx context.Context, i int) error) []error {
+	errs := make([]error, n)
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	workers = min(workers, n)
+
+	items := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		finished int
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range items {
+				err := ctx.Err()
+				if err == nil {
+					err = opts.Limiter.Wait(ctx)
+				}
+				if err == nil {
+					err = fn(ctx, i)
+				}
+				errs[i] = err
+
+				if opts.Progress != nil {
+					mu.Lock()
+					finished++
+					opts.Progress(finished, n)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range n {
+		if ctx.Err() != nil {
+			errs[i] = ctx.Err()
+			continue
+		}
+		select {
+		case items <- i:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+		}
+	}
+	close(items)
+	wg.Wait()
+	return errs
+}
+
+// Join combines the errors of the failed items into one, prefixing each
+// with the item's label, or returns nil if no item failed
+func Join(errs []error, label func(i int) string) error {
+	var failed []error
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", label(i), err))
+		}
+	}
+	return errors.Join(failed...)
+}
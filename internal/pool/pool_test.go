@@ -0,0 +1,104 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/ratelimit"
+)
+
+func TestRun(t *testing.T) {
+	var running, peak atomic.Int32
+	var progress []int
+	errs := Run(context.Background(), 10, Options{
+		Workers:  3,
+		Progress: func(finished, total int) { progress = append(progress, finished) },
+	}, func(ctx context.Context, i int) error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		if i%4 == 0 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	if len(errs) != 10 {
+		t.Fatalf("Run() returned %d errors, want one per item", len(errs))
+	}
+	for i, err := range errs {
+		if (err != nil) != (i%4 == 0) {
+			t.Errorf("item %d error = %v", i, err)
+		}
+	}
+	if p := peak.Load(); p > 3 || p < 2 {
+		t.Errorf("peak concurrency = %d, want up to 3 workers", p)
+	}
+	if len(progress) != 10 || progress[9] != 10 {
+		t.Errorf("progress = %v, want a call per item", progress)
+	}
+}
+
+func TestRunDefaultWorkersAndEmpty(t *testing.T) {
+	if errs := Run(context.Background(), 0, Options{}, nil); len(errs) != 0 {
+		t.Errorf("Run() of no items = %v", errs)
+	}
+
+	var calls atomic.Int32
+	Run(context.Background(), 2, Options{}, func(context.Context, int) error {
+		calls.Add(1)
+		return nil
+	})
+	if calls.Load() != 2 {
+		t.Errorf("Run() called fn %d times, want 2", calls.Load())
+	}
+}
+
+func TestRunLimiter(t *testing.T) {
+	// 1200 requests per minute is one every 50ms
+	start := time.Now()
+	Run(context.Background(), 3, Options{Workers: 3, Limiter: ratelimit.NewLimiter(1200)}, func(context.Context, int) error {
+		return nil
+	})
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Run() took %v, want the limiter to pace the items", elapsed)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := Run(ctx, 5, Options{Workers: 1}, func(ctx context.Context, i int) error {
+		if i == 1 {
+			cancel()
+		}
+		return nil
+	})
+	if errs[0] != nil || errs[1] != nil {
+		t.Errorf("started items errors = %v, %v, want none", errs[0], errs[1])
+	}
+	for _, err := range errs[2:] {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("item not started error = %v, want context.Canceled", err)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	if err := Join([]error{nil, nil}, nil); err != nil {
+		t.Errorf("Join() of no failures = %v", err)
+	}
+	labels := []string{"golang", "rust", "zig"}
+	err := Join([]error{nil, errors.New("timeout"), context.Canceled}, func(i int) string { return labels[i] })
+	if err == nil || err.Error() != "rust: timeout\nzig: context canceled" || !errors.Is(err, context.Canceled) {
+		t.Errorf("Join() = %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+// Package pricing estimates the cost of a request from its token usage.
+package pricing
+
+import "fmt"
+
+// Price is the cost of a model in US dollars per million tokens
+type Price struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// Prices lists the published token prices of the built-in models.
+// Per-request search fees are not included, so estimates are a lower bound.
+var Prices = map[string]Price{
+	"sonar":               {InputPerMillion: 1, OutputPerMillion: 1},
+	"sonar-pro":           {InputPerMillion: 3, OutputPerMillion: 15},
+	"sonar-reasoning":     {InputPerMillion: 1, OutputPerMillion: 5},
+	"sonar-reasoning-pro": {InputPerMillion: 2, OutputPerMillion: 8},
+	"sonar-deep-research": {InputPerMillion: 2, OutputPerMillion: 8},
+}
+
+// Estimate returns the token cost of a request in US dollars.
+// Returns false if the model's price is unknown.
+func Estimate(model string, promptTokens, completionTokens int) (float64, bool) {
+	price, ok := Prices[model]
+	if !ok {
+		return 0, false
+	}
+	cost := float64(promptTokens)*price.InputPerMillion/1e6 +
+		float64(completionTokens)*price.OutputPerMillion/1e6
+	return cost, true
+}
+
+// Format returns a cost formatted in US dollars
+func Format(cost float64) string {
+	return fmt.Sprintf("$%.4f", cost)
+}
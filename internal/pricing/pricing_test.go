@@ -0,0 +1,40 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      string
+		prompt     int
+		completion int
+		want       float64
+		wantOK     bool
+	}{
+		{"sonar", "sonar", 1_000_000, 1_000_000, 2, true},
+		{"sonar-pro", "sonar-pro", 1000, 2000, 0.033, true},
+		{"zero usage", "sonar-reasoning", 0, 0, 0, true},
+		{"unknown model", "gpt-4", 1000, 1000, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Estimate(tt.model, tt.prompt, tt.completion)
+			if ok != tt.wantOK {
+				t.Fatalf("Estimate() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Estimate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if got := Format(0.03312); got != "$0.0331" {
+		t.Errorf("Format() = %q, want %q", got, "$0.0331")
+	}
+}
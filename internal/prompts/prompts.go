@@ -0,0 +1,142 @@
+// Package prompts provides a library of named system prompts, stored as
+// files under the config directory, so that personas can be saved once and
+// loaded by name.
+package prompts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+const (
+	// PromptsDirName is the name of the directory holding the prompts
+	PromptsDirName = "prompts"
+	// EnvPromptsDir is the environment variable for a custom prompts directory
+	EnvPromptsDir = "PERPLEXITY_PROMPTS_DIR"
+	// Extension is the extension of prompt files
+	Extension = ".md"
+)
+
+// ErrInvalidName is returned when a prompt name is not a single word
+var ErrInvalidName = errors.New("prompt name must be a single word of letters, digits, '-' or '_'")
+
+// ErrNotFound is returned when no prompt is saved under a name
+var ErrNotFound = errors.New("no saved system prompt with that name")
+
+// Library manages the saved system prompts, one file per prompt
+type Library struct {
+	dir string
+}
+
+// NewLibrary creates a prompt library in the default directory
+func NewLibrary() *Library {
+	return &Library{dir: getPromptsDir()}
+}
+
+// getPromptsDir returns the directory of the prompt files
+func getPromptsDir() string {
+	if customDir := os.Getenv(EnvPromptsDir); customDir != "" {
+		return customDir
+	}
+	dir := config.GetConfigDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, PromptsDirName)
+}
+
+// Dir returns the directory of the prompt files (empty if unavailable)
+func (l *Library) Dir() string {
+	return l.dir
+}
+
+// path returns the file of a prompt, checking its name
+func (l *Library) path(name string) (string, error) {
+	if l.dir == "" {
+		return "", fmt.Errorf("prompts directory not available")
+	}
+	if !isValidName(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return filepath.Join(l.dir, name+Extension), nil
+}
+
+// Save stores a prompt under a name, replacing any prompt saved before
+func (l *Library) Save(name, prompt string) error {
+	path, err := l.path(name)
+	if err != nil {
+		return err
+	}
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return fmt.Errorf("system prompt cannot be empty")
+	}
+
+	if err := os.MkdirAll(l.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create prompts directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(prompt+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write prompt: %w", err)
+	}
+	return nil
+}
+
+// Load returns the prompt saved under a name
+func (l *Library) Load(name string) (string, error) {
+	path, err := l.path(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Names returns the sorted names of the saved prompts
+func (l *Library) Names() ([]string, error) {
+	if l.dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(l.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompts: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), Extension)
+		if ok && entry.Type().IsRegular() && isValidName(name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// isValidName reports whether name is a single word usable as a file name
+func isValidName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package prompts
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func newTestLibrary(t *testing.T) *Library {
+	return &Library{dir: filepath.Join(t.TempDir(), PromptsDirName)}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	l := newTestLibrary(t)
+
+	if err := l.Save("pirate", "  Answer as a pirate.\n"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	prompt, err := l.Load("pirate")
+	if err != nil || prompt != "Answer as a pirate." {
+		t.Errorf("Load() = %q, %v, want the saved prompt", prompt, err)
+	}
+
+	if err := l.Save("pirate", "Answer as a parrot."); err != nil {
+		t.Fatalf("Save() replacing error = %v", err)
+	}
+	if prompt, _ := l.Load("pirate"); prompt != "Answer as a parrot." {
+		t.Errorf("Load() = %q, want the replaced prompt", prompt)
+	}
+
+	if _, err := l.Load("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load(missing) error = %v, want ErrNotFound", err)
+	}
+	for _, name := range []string{"", "two words", "../escape", "path/like"} {
+		if err := l.Save(name, "prompt"); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Save(%q) error = %v, want ErrInvalidName", name, err)
+		}
+	}
+	if err := l.Save("empty", "  "); err == nil {
+		t.Error("Save() should reject an empty prompt")
+	}
+}
+
+func TestNames(t *testing.T) {
+	l := newTestLibrary(t)
+	if names, err := l.Names(); err != nil || len(names) != 0 {
+		t.Errorf("Names() without a directory = %q, %v, want none", names, err)
+	}
+
+	for _, name := range []string{"reviewer", "pirate"} {
+		if err := l.Save(name, "Prompt of "+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(l.dir, "notes.txt"), []byte("not a prompt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := l.Names()
+	if err != nil || !slices.Equal(names, []string{"pirate", "reviewer"}) {
+		t.Errorf("Names() = %q, %v, want pirate and reviewer", names, err)
+	}
+}
+
+func TestNewLibraryFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvPromptsDir, dir)
+	if got := NewLibrary().Dir(); got != dir {
+		t.Errorf("Dir() = %q, want %q", got, dir)
+	}
+}
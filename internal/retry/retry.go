@@ -7,6 +7,7 @@ import (
 	"math/rand/v2"
 	"net"
 	"net/url"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
@@ -14,24 +15,36 @@ import (
 
 // Config holds retry configuration
 type Config struct {
-	MaxRetries     int           // Maximum number of retry attempts
-	InitialBackoff time.Duration // Initial backoff duration
-	MaxBackoff     time.Duration // Maximum backoff duration
-	Multiplier     float64       // Backoff multiplier (e.g., 2.0 for exponential)
-	Jitter         float64       // Jitter factor (0.0-1.0) to add randomness
+	MaxRetries       int           // Maximum number of retry attempts after network errors
+	MaxServerRetries int           // Maximum number of retry attempts after 5xx server errors
+	InitialBackoff   time.Duration // Initial backoff duration
+	MaxBackoff       time.Duration // Maximum backoff duration
+	Multiplier       float64       // Backoff multiplier (e.g., 2.0 for exponential)
+	Jitter           float64       // Jitter factor (0.0-1.0) to add randomness
 }
 
 // DefaultConfig returns the default retry configuration
 func DefaultConfig() Config {
 	return Config{
-		MaxRetries:     3,
-		InitialBackoff: 500 * time.Millisecond,
-		MaxBackoff:     30 * time.Second,
-		Multiplier:     2.0,
-		Jitter:         0.2,
+		MaxRetries:       3,
+		MaxServerRetries: 2,
+		InitialBackoff:   500 * time.Millisecond,
+		MaxBackoff:       30 * time.Second,
+		Multiplier:       2.0,
+		Jitter:           0.2,
 	}
 }
 
+// serverErrorCodes are the status codes of transient server errors
+var serverErrorCodes = []int{500, 502, 503, 504}
+
+// IsServerError reports whether err is a transient server error response,
+// that is an error with an HTTPStatus method returning 500, 502, 503 or 504
+func IsServerError(err error) bool {
+	var status interface{ HTTPStatus() int }
+	return errors.As(err, &status) && slices.Contains(serverErrorCodes, status.HTTPStatus())
+}
+
 // IsRetryableError checks if an error is a transient network error that can be retried
 func IsRetryableError(err error) bool {
 	if err == nil {
@@ -153,11 +166,12 @@ type RetryInfo struct {
 // OnRetryFunc is a callback function called before each retry attempt
 type OnRetryFunc func(info RetryInfo)
 
-// Do executes a function with retry logic
+// Do executes a function with retry logic. Network errors are retried up to
+// cfg.MaxRetries times and server errors up to cfg.MaxServerRetries times,
+// each with its own backoff.
 func Do(ctx context.Context, cfg Config, fn func() error, onRetry OnRetryFunc) error {
-	var lastErr error
-
-	for attempt := 0; attempt <= cfg.MaxRetries; attempt++ {
+	retries, serverRetries := 0, 0
+	for {
 		// Check context before attempting
 		if ctx.Err() != nil {
 			return ctx.Err()
@@ -168,36 +182,37 @@ func Do(ctx context.Context, cfg Config, fn func() error, onRetry OnRetryFunc) e
 			return nil
 		}
 
-		lastErr = err
-
 		// Check if error is retryable
-		if !IsRetryableError(err) {
+		attempt, maxRetries := &retries, cfg.MaxRetries
+		switch {
+		case IsServerError(err):
+			attempt, maxRetries = &serverRetries, cfg.MaxServerRetries
+		case !IsRetryableError(err):
 			return err
 		}
 
 		// Don't wait after the last attempt
-		if attempt == cfg.MaxRetries {
-			break
+		if *attempt >= maxRetries {
+			return err
 		}
 
 		// Calculate next backoff
-		nextBackoff := cfg.CalculateBackoff(attempt)
+		nextBackoff := cfg.CalculateBackoff(*attempt)
 
 		// Call retry callback if provided
 		if onRetry != nil {
 			onRetry(RetryInfo{
-				Attempt:     attempt,
-				MaxRetries:  cfg.MaxRetries,
+				Attempt:     *attempt,
+				MaxRetries:  maxRetries,
 				Error:       err,
 				NextBackoff: nextBackoff,
 			})
 		}
 
 		// Wait before next attempt
-		if err := cfg.Wait(ctx, attempt); err != nil {
+		if err := cfg.Wait(ctx, *attempt); err != nil {
 			return err
 		}
+		*attempt++
 	}
-
-	return lastErr
 }
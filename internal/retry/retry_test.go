@@ -3,6 +3,7 @@ package retry
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"net/url"
 	"syscall"
@@ -323,6 +324,80 @@ func TestDoOnRetryCallback(t *testing.T) {
 	}
 }
 
+// statusError is an error response with a status code
+type statusError int
+
+func (e statusError) Error() string   { return fmt.Sprintf("status code %d", int(e)) }
+func (e statusError) HTTPStatus() int { return int(e) }
+
+func TestIsServerError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{statusError(500), true},
+		{statusError(502), true},
+		{statusError(503), true},
+		{fmt.Errorf("query failed: %w", statusError(504)), true},
+		{statusError(429), false},
+		{statusError(501), false},
+		{syscall.ECONNREFUSED, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsServerError(tt.err); got != tt.want {
+			t.Errorf("IsServerError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestDoServerErrors(t *testing.T) {
+	cfg := Config{
+		MaxRetries:       1,
+		MaxServerRetries: 2,
+		InitialBackoff:   1 * time.Millisecond,
+		MaxBackoff:       10 * time.Millisecond,
+		Multiplier:       2.0,
+	}
+
+	// Server errors have their own budget, separate from network errors
+	errs := []error{statusError(503), syscall.ECONNRESET, statusError(502), nil}
+	attempts := 0
+	var infos []RetryInfo
+	err := Do(context.Background(), cfg, func() error {
+		err := errs[attempts]
+		attempts++
+		return err
+	}, func(info RetryInfo) {
+		infos = append(infos, info)
+	})
+	if err != nil || attempts != 4 {
+		t.Fatalf("Do() = %v after %d attempts, want success after 4", err, attempts)
+	}
+	if infos[0].MaxRetries != 2 || infos[1].MaxRetries != 1 || infos[2].Attempt != 1 {
+		t.Errorf("retry infos = %+v, want separate server and network budgets", infos)
+	}
+
+	attempts = 0
+	err = Do(context.Background(), cfg, func() error {
+		attempts++
+		return statusError(500)
+	}, nil)
+	if !IsServerError(err) || attempts != 3 {
+		t.Errorf("Do() = %v after %d attempts, want the server error after 3", err, attempts)
+	}
+
+	cfg.MaxServerRetries = 0
+	attempts = 0
+	_ = Do(context.Background(), cfg, func() error {
+		attempts++
+		return statusError(503)
+	}, nil)
+	if attempts != 1 {
+		t.Errorf("Do() made %d attempts with server retries off, want 1", attempts)
+	}
+}
+
 func TestDefaultConfig(t *testing.T) {
 	cfg := DefaultConfig()
 
@@ -330,6 +405,10 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("DefaultConfig MaxRetries = %d, expected 3", cfg.MaxRetries)
 	}
 
+	if cfg.MaxServerRetries != 2 {
+		t.Errorf("DefaultConfig MaxServerRetries = %d, expected 2", cfg.MaxServerRetries)
+	}
+
 	if cfg.InitialBackoff != 500*time.Millisecond {
 		t.Errorf("DefaultConfig InitialBackoff = %v, expected 500ms", cfg.InitialBackoff)
 	}
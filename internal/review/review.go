@@ -0,0 +1,218 @@
+// Package review splits diffs into chunks that fit a review request and
+// parses the structured review comments of the responses.
+package review
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/quocvuong92/perplexity-cli/internal/tokens"
+)
+
+// Severities of review comments
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Severities lists the severities from most to least severe
+var Severities = []string{SeverityError, SeverityWarning, SeverityInfo}
+
+// ErrNoComments is returned when a response holds no JSON array of comments
+var ErrNoComments = errors.New("response has no JSON array of review comments")
+
+// truncatedMarker ends a hunk that was cut to fit a chunk
+const truncatedMarker = "[hunk truncated]"
+
+// Comment is a review comment on a line of a file
+type Comment struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Split breaks a unified diff into chunks of about maxTokens tokens at most,
+// keeping the changes of a file together where possible. A file too large
+// for a chunk is split between its hunks, each chunk repeating the file
+// header, and a hunk too large on its own is truncated.
+func Split(diff string, maxTokens int) []string {
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+	}
+	add := func(part string) {
+		if current.Len() > 0 && tokens.Estimate(current.String()+part) > maxTokens {
+			flush()
+		}
+		current.WriteString(part)
+	}
+
+	for _, file := range splitFiles(diff) {
+		if tokens.Estimate(file) <= maxTokens {
+			add(file)
+			continue
+		}
+		header, hunks := splitHunks(file)
+		if len(hunks) == 0 {
+			add(truncate(header, maxTokens))
+			continue
+		}
+		for _, hunk := range hunks {
+			add(header + truncate(hunk, maxTokens-tokens.Estimate(header)))
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitFiles splits a diff into the sections of each file. A section starts
+// at a "diff" line, or at a "---" line followed by "+++" outside a git header.
+func splitFiles(diff string) []string {
+	lines := strings.SplitAfter(diff, "\n")
+	var files []string
+	var current strings.Builder
+	inHeader := false
+	for i, line := range lines {
+		startsFile := strings.HasPrefix(line, "diff ")
+		if !startsFile && !inHeader && strings.HasPrefix(line, "--- ") &&
+			i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			startsFile = true
+		}
+		if startsFile && current.Len() > 0 {
+			files = append(files, current.String())
+			current.Reset()
+		}
+		switch {
+		case strings.HasPrefix(line, "diff "):
+			inHeader = true
+		case strings.HasPrefix(line, "@@"):
+			inHeader = false
+		}
+		current.WriteString(line)
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		files = append(files, current.String())
+	}
+	return files
+}
+
+// splitHunks splits the section of a file into its header, the lines
+// before the first hunk, and its hunks
+func splitHunks(file string) (header string, hunks []string) {
+	var current strings.Builder
+	seenHunk := false
+	for _, line := range strings.SplitAfter(file, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			if seenHunk {
+				hunks = append(hunks, current.String())
+			} else {
+				header = current.String()
+			}
+			current.Reset()
+			seenHunk = true
+		}
+		current.WriteString(line)
+	}
+	if seenHunk {
+		hunks = append(hunks, current.String())
+	} else {
+		header = current.String()
+	}
+	return header, hunks
+}
+
+// truncate cuts text to about maxTokens tokens, at a line boundary unless a
+// single line is too long
+func truncate(text string, maxTokens int) string {
+	if tokens.Estimate(text) <= maxTokens {
+		return text
+	}
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if tokens.Estimate(b.String()+line) > maxTokens {
+			if b.Len() == 0 {
+				// Keep the share of the line that fits
+				runes := []rune(line)
+				b.WriteString(string(runes[:max(0, len(runes)*maxTokens/tokens.Estimate(line))]) + "\n")
+			}
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String() + truncatedMarker + "\n"
+}
+
+// Parse extracts the comments from a review response holding a JSON array of
+// comments, possibly surrounded by prose or a code fence. Paths lose the
+// a/ and b/ prefixes of git diffs, unknown severities become info, and
+// comments without a message are dropped.
+func Parse(content string) ([]Comment, error) {
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end < start {
+		return nil, ErrNoComments
+	}
+
+	var raw []Comment
+	if err := json.Unmarshal([]byte(content[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoComments, err)
+	}
+
+	comments := make([]Comment, 0, len(raw))
+	for _, c := range raw {
+		c.Message = strings.TrimSpace(c.Message)
+		if c.Message == "" {
+			continue
+		}
+		c.File = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(c.File), "b/"), "a/")
+		c.Severity = strings.ToLower(strings.TrimSpace(c.Severity))
+		if !slices.Contains(Severities, c.Severity) {
+			c.Severity = SeverityInfo
+		}
+		comments = append(comments, c)
+	}
+	return comments, nil
+}
+
+// Sort orders comments by file and line
+func Sort(comments []Comment) {
+	slices.SortStableFunc(comments, func(a, b Comment) int {
+		return cmp.Or(strings.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line))
+	})
+}
+
+// WriteText writes one "file:line: severity: message" line per comment
+func WriteText(w io.Writer, comments []Comment) {
+	if len(comments) == 0 {
+		fmt.Fprintln(w, "No issues found.")
+		return
+	}
+	for _, c := range comments {
+		location := c.File
+		if c.Line > 0 {
+			location = fmt.Sprintf("%s:%d", c.File, c.Line)
+		}
+		fmt.Fprintf(w, "%s: %s: %s\n", location, c.Severity, c.Message)
+	}
+}
+
+// WriteJSON writes the comments as an indented JSON array
+func WriteJSON(w io.Writer, comments []Comment) error {
+	if comments == nil {
+		comments = []Comment{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(comments)
+}
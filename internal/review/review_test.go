@@ -0,0 +1,118 @@
+package review
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/tokens"
+)
+
+// fileDiff returns the git diff of a file adding the given lines in one
+// hunk per line
+func fileDiff(name string, lines ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\nindex 1111111..2222222 100644\n--- a/%s\n+++ b/%s\n", name, name, name, name)
+	for i, line := range lines {
+		fmt.Fprintf(&b, "@@ -%d,0 +%d,1 @@\n+%s\n", i*10, i*10+1, line)
+	}
+	return b.String()
+}
+
+func TestSplit(t *testing.T) {
+	small := fileDiff("a.go", "x := 1") + fileDiff("b.go", "y := 2")
+	if chunks := Split(small, 1000); len(chunks) != 1 || chunks[0] != small {
+		t.Errorf("Split() of a small diff = %q, want it as one chunk", chunks)
+	}
+
+	long := strings.Repeat("word ", 60)
+	diff := fileDiff("a.go", long, long) + fileDiff("b.go", long)
+	chunks := Split(diff, 150)
+	if len(chunks) != 3 {
+		t.Fatalf("Split() returned %d chunks, want 3: %q", len(chunks), chunks)
+	}
+	for i, chunk := range chunks {
+		if tokens.Estimate(chunk) > 150 {
+			t.Errorf("chunk %d is about %d tokens, over the limit", i, tokens.Estimate(chunk))
+		}
+		if !strings.HasPrefix(chunk, "diff --git ") || !strings.Contains(chunk, "+++ b/") {
+			t.Errorf("chunk %d = %q, want it to start with the file header", i, chunk)
+		}
+	}
+
+	huge := fileDiff("c.go", strings.Repeat("word\n+", 400))
+	chunks = Split(huge, 100)
+	if len(chunks) != 1 || !strings.Contains(chunks[0], truncatedMarker) {
+		t.Errorf("Split() of an oversized hunk = %q, want it truncated", chunks)
+	}
+}
+
+func TestSplitFilesPlainDiff(t *testing.T) {
+	diff := "--- a.txt\n+++ a.txt\n@@ -1 +1 @@\n-old\n+new\n--- b.txt\n+++ b.txt\n@@ -1 +1 @@\n-x\n+y\n"
+	files := splitFiles(diff)
+	if len(files) != 2 || !strings.HasPrefix(files[1], "--- b.txt") {
+		t.Errorf("splitFiles() = %q, want the two files", files)
+	}
+}
+
+func TestParse(t *testing.T) {
+	content := "Here is my review:\n```json\n[\n" +
+		`{"file": "b/cmd/root.go", "line": 12, "severity": "Warning", "message": " Unchecked error. "},` +
+		`{"file": "main.go", "severity": "nitpick", "message": "Typo in comment"},` +
+		`{"file": "main.go", "line": 3, "severity": "error", "message": ""}` +
+		"\n]\n```"
+
+	comments, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []Comment{
+		{File: "cmd/root.go", Line: 12, Severity: SeverityWarning, Message: "Unchecked error."},
+		{File: "main.go", Severity: SeverityInfo, Message: "Typo in comment"},
+	}
+	if fmt.Sprint(comments) != fmt.Sprint(want) {
+		t.Errorf("Parse() = %+v, want %+v", comments, want)
+	}
+
+	if comments, err := Parse("[]"); err != nil || len(comments) != 0 {
+		t.Errorf("Parse(\"[]\") = %v, %v, want no comments", comments, err)
+	}
+	for _, content := range []string{"Looks good to me.", "[not json]"} {
+		if _, err := Parse(content); !errors.Is(err, ErrNoComments) {
+			t.Errorf("Parse(%q) error = %v, want ErrNoComments", content, err)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	comments := []Comment{
+		{File: "main.go", Line: 9, Severity: SeverityInfo, Message: "Rename x"},
+		{File: "cmd/root.go", Severity: SeverityError, Message: "Missing test"},
+		{File: "main.go", Line: 2, Severity: SeverityWarning, Message: "Shadowed err"},
+	}
+	Sort(comments)
+
+	var text bytes.Buffer
+	WriteText(&text, comments)
+	want := "cmd/root.go: error: Missing test\nmain.go:2: warning: Shadowed err\nmain.go:9: info: Rename x\n"
+	if text.String() != want {
+		t.Errorf("WriteText() = %q, want %q", text.String(), want)
+	}
+
+	text.Reset()
+	WriteText(&text, nil)
+	if text.String() != "No issues found.\n" {
+		t.Errorf("WriteText(nil) = %q", text.String())
+	}
+
+	var out bytes.Buffer
+	if err := WriteJSON(&out, nil); err != nil || strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("WriteJSON(nil) = %q, %v, want an empty array", out.String(), err)
+	}
+	out.Reset()
+	if err := WriteJSON(&out, comments[:1]); err != nil || !strings.Contains(out.String(), `"severity": "error"`) {
+		t.Errorf("WriteJSON() = %q, %v", out.String(), err)
+	}
+}
@@ -0,0 +1,218 @@
+// Package script loads scripted multi-turn conversations from YAML files:
+// user turns with {name} template variables and assertions on the responses,
+// which are checked and reported after the script is run.
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Script is a conversation of user turns sent one after another
+type Script struct {
+	Name   string            `yaml:"name,omitempty"`
+	Model  string            `yaml:"model,omitempty"`  // Model of the script (empty = configured model)
+	System string            `yaml:"system,omitempty"` // System prompt (empty = configured one)
+	Vars   map[string]string `yaml:"vars,omitempty"`   // Values of the {name} placeholders
+	Turns  []Turn            `yaml:"turns"`
+}
+
+// Turn is a user message and the assertions on its response
+type Turn struct {
+	Prompt string `yaml:"prompt"`
+	Expect Expect `yaml:"expect,omitempty"`
+}
+
+// Expect holds the assertions on a response. Text comparisons ignore case.
+type Expect struct {
+	Contains     []string `yaml:"contains,omitempty"`
+	NotContains  []string `yaml:"not_contains,omitempty"`
+	Matches      string   `yaml:"matches,omitempty"` // Regular expression
+	MinCitations int      `yaml:"min_citations,omitempty"`
+}
+
+// ErrUndefinedVar is returned when a placeholder has no value
+var ErrUndefinedVar = errors.New("undefined variable")
+
+// placeholder matches the {name} template variables of prompts
+var placeholder = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Load reads and validates a script. Unknown fields are rejected, so a
+// misspelled assertion is not silently skipped.
+func Load(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+
+	var s Script
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse script %s: %w", path, err)
+	}
+
+	if len(s.Turns) == 0 {
+		return nil, fmt.Errorf("script %s has no turns", path)
+	}
+	for i, turn := range s.Turns {
+		if strings.TrimSpace(turn.Prompt) == "" {
+			return nil, fmt.Errorf("turn %d of script %s has no prompt", i+1, path)
+		}
+		if turn.Expect.Matches != "" {
+			if _, err := regexp.Compile(turn.Expect.Matches); err != nil {
+				return nil, fmt.Errorf("invalid matches of turn %d: %w", i+1, err)
+			}
+		}
+		if turn.Expect.MinCitations < 0 {
+			return nil, fmt.Errorf("min_citations of turn %d cannot be negative", i+1)
+		}
+	}
+	return &s, nil
+}
+
+// ParseVars parses name=value pairs, as given by --var flags
+func ParseVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || !placeholder.MatchString("{"+name+"}") {
+			return nil, fmt.Errorf("invalid variable %q: want name=value", pair)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// Expand replaces the {name} placeholders of the system prompt and the turn
+// prompts with the script's variables, overridden by vars
+func (s *Script) Expand(vars map[string]string) error {
+	values := make(map[string]string, len(s.Vars)+len(vars))
+	for name, value := range s.Vars {
+		values[name] = value
+	}
+	for name, value := range vars {
+		values[name] = value
+	}
+
+	var undefined []string
+	expand := func(text string) string {
+		return placeholder.ReplaceAllStringFunc(text, func(match string) string {
+			name := match[1 : len(match)-1]
+			value, ok := values[name]
+			if !ok {
+				undefined = append(undefined, name)
+				return match
+			}
+			return value
+		})
+	}
+
+	s.System = expand(s.System)
+	for i := range s.Turns {
+		s.Turns[i].Prompt = expand(s.Turns[i].Prompt)
+	}
+	if len(undefined) > 0 {
+		return fmt.Errorf("%w: %s (set it under vars or with --var %s=...)", ErrUndefinedVar, undefined[0], undefined[0])
+	}
+	return nil
+}
+
+// Check is the outcome of one assertion
+type Check struct {
+	Assertion string `json:"assertion"`
+	Passed    bool   `json:"passed"`
+}
+
+// Check checks a response and its number of citations against the assertions
+func (e Expect) Check(response string, citations int) []Check {
+	var checks []Check
+	lower := strings.ToLower(response)
+	for _, s := range e.Contains {
+		checks = append(checks, Check{fmt.Sprintf("contains %q", s), strings.Contains(lower, strings.ToLower(s))})
+	}
+	for _, s := range e.NotContains {
+		checks = append(checks, Check{fmt.Sprintf("does not contain %q", s), !strings.Contains(lower, strings.ToLower(s))})
+	}
+	if e.Matches != "" {
+		re := regexp.MustCompile("(?i)" + e.Matches)
+		checks = append(checks, Check{fmt.Sprintf("matches /%s/", e.Matches), re.MatchString(response)})
+	}
+	if e.MinCitations > 0 {
+		checks = append(checks, Check{fmt.Sprintf("has at least %d citations (got %d)", e.MinCitations, citations), citations >= e.MinCitations})
+	}
+	return checks
+}
+
+// TurnResult is the response to a turn and its checks
+type TurnResult struct {
+	Prompt    string   `json:"prompt"`
+	Response  string   `json:"response,omitempty"`
+	Citations []string `json:"citations,omitempty"`
+	Checks    []Check  `json:"checks,omitempty"`
+	Error     string   `json:"error,omitempty"` // Set if the request failed
+}
+
+// Report is the outcome of a script run
+type Report struct {
+	Name   string       `json:"name,omitempty"`
+	Model  string       `json:"model"`
+	Turns  []TurnResult `json:"turns"`
+	Passed int          `json:"passed"`
+	Failed int          `json:"failed"`
+}
+
+// Add adds a turn result to the report, counting its checks
+func (r *Report) Add(result TurnResult) {
+	for _, c := range result.Checks {
+		if c.Passed {
+			r.Passed++
+		} else {
+			r.Failed++
+		}
+	}
+	r.Turns = append(r.Turns, result)
+}
+
+// WriteText writes the responses with their checks, and a summary line
+func WriteText(w io.Writer, r *Report) {
+	for i, turn := range r.Turns {
+		fmt.Fprintf(w, "## Turn %d: %s\n\n", i+1, turn.Prompt)
+		if turn.Error != "" {
+			fmt.Fprintf(w, "Error: %s\n\n", turn.Error)
+			continue
+		}
+		fmt.Fprintf(w, "%s\n\n", turn.Response)
+		for _, c := range turn.Checks {
+			status := "[ok]"
+			if !c.Passed {
+				status = "[FAIL]"
+			}
+			fmt.Fprintf(w, "%-6s %s\n", status, c.Assertion)
+		}
+		if len(turn.Checks) > 0 {
+			fmt.Fprintln(w)
+		}
+	}
+
+	summary := fmt.Sprintf("%d turns, %d of %d assertions passed.", len(r.Turns), r.Passed, r.Passed+r.Failed)
+	if r.Name != "" {
+		summary = r.Name + ": " + summary
+	}
+	fmt.Fprintln(w, summary)
+}
+
+// WriteJSON writes the report as indented JSON
+func WriteJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
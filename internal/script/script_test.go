@@ -0,0 +1,150 @@
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeScript writes a script file and returns its path
+func writeScript(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "flow.yaml")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeScript(t, `name: Generics
+model: sonar-pro
+system: Be brief about {lang}.
+vars:
+  lang: Go
+turns:
+  - prompt: What did {lang} {version} add?
+    expect:
+      contains: [generics]
+      min_citations: 1
+  - prompt: Show an example.
+`)
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.Name != "Generics" || s.Model != "sonar-pro" || len(s.Turns) != 2 || s.Turns[0].Expect.MinCitations != 1 {
+		t.Errorf("Load() = %+v", s)
+	}
+
+	if err := s.Expand(map[string]string{}); !errors.Is(err, ErrUndefinedVar) || !strings.Contains(err.Error(), "version") {
+		t.Errorf("Expand() error = %v, want the undefined version", err)
+	}
+
+	s, _ = Load(path)
+	if err := s.Expand(map[string]string{"version": "1.18", "lang": "Golang"}); err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if s.System != "Be brief about Golang." || s.Turns[0].Prompt != "What did Golang 1.18 add?" {
+		t.Errorf("Expand() = %q, %q, want the overrides applied", s.System, s.Turns[0].Prompt)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty", "", "no turns"},
+		{"blank prompt", "turns:\n  - prompt: ' '\n", "no prompt"},
+		{"misspelled assertion", "turns:\n  - prompt: hi\n    expect:\n      contain: [x]\n", "contain"},
+		{"bad regexp", "turns:\n  - prompt: hi\n    expect:\n      matches: '(('\n", "invalid matches"},
+		{"negative citations", "turns:\n  - prompt: hi\n    expect:\n      min_citations: -1\n", "negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(writeScript(t, tt.data)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVars(t *testing.T) {
+	vars, err := ParseVars([]string{"topic=Go generics", "year=2022=ok"})
+	if err != nil || vars["topic"] != "Go generics" || vars["year"] != "2022=ok" {
+		t.Errorf("ParseVars() = %v, %v", vars, err)
+	}
+	for _, pair := range []string{"novalue", "bad name=x", "=x"} {
+		if _, err := ParseVars([]string{pair}); err == nil {
+			t.Errorf("ParseVars(%q) should fail", pair)
+		}
+	}
+}
+
+func TestExpectCheck(t *testing.T) {
+	e := Expect{
+		Contains:     []string{"Generics", "iterators"},
+		NotContains:  []string{"not sure"},
+		Matches:      `go 1\.\d+`,
+		MinCitations: 2,
+	}
+	checks := e.Check("Go 1.18 added generics.", 1)
+	want := []Check{
+		{`contains "Generics"`, true},
+		{`contains "iterators"`, false},
+		{`does not contain "not sure"`, true},
+		{`matches /go 1\.\d+/`, true},
+		{"has at least 2 citations (got 1)", false},
+	}
+	if len(checks) != len(want) {
+		t.Fatalf("Check() = %+v, want %+v", checks, want)
+	}
+	for i := range want {
+		if checks[i] != want[i] {
+			t.Errorf("Check()[%d] = %+v, want %+v", i, checks[i], want[i])
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	r := &Report{Name: "Generics", Model: "sonar"}
+	r.Add(TurnResult{Prompt: "What is new?", Response: "Generics.", Checks: []Check{{`contains "generics"`, true}, {"matches /x/", false}}})
+	r.Add(TurnResult{Prompt: "Example?", Error: "timeout"})
+	if r.Passed != 1 || r.Failed != 1 {
+		t.Errorf("Add() counted %d passed and %d failed, want 1 and 1", r.Passed, r.Failed)
+	}
+
+	var b bytes.Buffer
+	WriteText(&b, r)
+	want := `## Turn 1: What is new?
+
+Generics.
+
+[ok]   contains "generics"
+[FAIL] matches /x/
+
+## Turn 2: Example?
+
+Error: timeout
+
+Generics: 2 turns, 1 of 2 assertions passed.
+`
+	if b.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	if err := WriteJSON(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil || decoded.Turns[1].Error != "timeout" || decoded.Failed != 1 {
+		t.Errorf("WriteJSON() = %s, %v", b.String(), err)
+	}
+}
@@ -0,0 +1,183 @@
+// Package share uploads exported conversations to GitHub Gist or a paste
+// service and returns their URL.
+package share
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// GistAPIURL is the GitHub endpoint creating gists
+	GistAPIURL = "https://api.github.com/gists"
+	// EnvGitHubToken is the environment variable for the gist token
+	EnvGitHubToken = "GITHUB_TOKEN"
+)
+
+// uploadTimeout bounds an upload
+const uploadTimeout = 60 * time.Second
+
+// maxResponseBytes limits the response read from a paste service
+const maxResponseBytes = 64 * 1024
+
+// Uploader uploads a document and returns the URL it can be read at
+type Uploader interface {
+	// Name describes the service in messages
+	Name() string
+	// Upload uploads content as a file with the given name
+	Upload(ctx context.Context, filename, content string) (string, error)
+}
+
+// Gist uploads documents as GitHub gists
+type Gist struct {
+	Token  string
+	Public bool // Create public gists instead of secret ones
+	URL    string
+	client *http.Client
+}
+
+// NewGist creates a gist uploader authenticated with a GitHub token that
+// has the gist scope
+func NewGist(token string, public bool) *Gist {
+	return &Gist{Token: token, Public: public, URL: GistAPIURL, client: &http.Client{Timeout: uploadTimeout}}
+}
+
+// Name describes the service in messages
+func (g *Gist) Name() string {
+	if g.Public {
+		return "a public GitHub gist"
+	}
+	return "a secret GitHub gist"
+}
+
+// gistFile is a file of a gist
+type gistFile struct {
+	Content string `json:"content"`
+}
+
+// gistRequest is the body creating a gist
+type gistRequest struct {
+	Description string              `json:"description"`
+	Public      bool                `json:"public"`
+	Files       map[string]gistFile `json:"files"`
+}
+
+// Upload creates a gist holding content and returns its URL
+func (g *Gist) Upload(ctx context.Context, filename, content string) (string, error) {
+	body, err := json.Marshal(gistRequest{
+		Description: "Perplexity conversation",
+		Public:      g.Public,
+		Files:       map[string]gistFile{filename: {Content: content}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+g.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to create gist: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&apiErr)
+		if apiErr.Message != "" {
+			return "", fmt.Errorf("failed to create gist: %s: %s", resp.Status, apiErr.Message)
+		}
+		return "", fmt.Errorf("failed to create gist: %s", resp.Status)
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil || created.HTMLURL == "" {
+		return "", fmt.Errorf("failed to read the gist URL from the response")
+	}
+	return created.HTMLURL, nil
+}
+
+// Paste uploads documents to a paste service that answers a POST with the
+// URL of the paste, such as paste.rs or 0x0.st
+type Paste struct {
+	URL    string
+	Field  string // Multipart form field holding the document (empty = raw request body)
+	client *http.Client
+}
+
+// NewPaste creates an uploader for the paste service at url. With field set,
+// the document is sent as a file in that multipart form field; otherwise it
+// is the request body.
+func NewPaste(url, field string) *Paste {
+	return &Paste{URL: url, Field: field, client: &http.Client{Timeout: uploadTimeout}}
+}
+
+// Name describes the service in messages
+func (p *Paste) Name() string {
+	return p.URL
+}
+
+// Upload posts content to the paste service and returns the URL it answers
+func (p *Paste) Upload(ctx context.Context, filename, content string) (string, error) {
+	body := &bytes.Buffer{}
+	contentType := "text/markdown; charset=utf-8"
+	if p.Field != "" {
+		form := multipart.NewWriter(body)
+		part, err := form.CreateFormFile(p.Field, filename)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.WriteString(part, content); err != nil {
+			return "", err
+		}
+		if err := form.Close(); err != nil {
+			return "", err
+		}
+		contentType = form.FormDataContentType()
+	} else {
+		body.WriteString(content)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload to %s: %w", p.URL, err)
+	}
+	defer resp.Body.Close()
+
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the response of %s: %w", p.URL, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("failed to upload to %s: %s", p.URL, resp.Status)
+	}
+
+	url := strings.TrimSpace(string(answer))
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return "", fmt.Errorf("%s did not answer with a URL", p.URL)
+	}
+	return url, nil
+}
@@ -0,0 +1,81 @@
+package share
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGistUpload(t *testing.T) {
+	var got gistRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Bad credentials"}`))
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"html_url":"https://gist.github.com/me/abc"}`))
+	}))
+	defer server.Close()
+
+	gist := NewGist("token", false)
+	gist.URL = server.URL
+	url, err := gist.Upload(context.Background(), "chat.md", "# Chat")
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if url != "https://gist.github.com/me/abc" {
+		t.Errorf("Upload() = %q", url)
+	}
+	if got.Public || got.Files["chat.md"].Content != "# Chat" {
+		t.Errorf("request = %+v, want a secret gist with chat.md", got)
+	}
+
+	gist.Token = "wrong"
+	if _, err := gist.Upload(context.Background(), "chat.md", "# Chat"); err == nil || !strings.Contains(err.Error(), "Bad credentials") {
+		t.Errorf("Upload() error = %v, want the API message", err)
+	}
+}
+
+func TestPasteUpload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content := ""
+		if file, _, err := r.FormFile("file"); err == nil {
+			data, _ := io.ReadAll(file)
+			content = string(data)
+		} else {
+			data, _ := io.ReadAll(r.Body)
+			content = string(data)
+		}
+		switch content {
+		case "# Chat":
+			w.Write([]byte("https://paste.example.com/xyz\n"))
+		case "fail":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.Write([]byte("Thanks!"))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	for _, field := range []string{"", "file"} {
+		url, err := NewPaste(server.URL, field).Upload(ctx, "chat.md", "# Chat")
+		if err != nil || url != "https://paste.example.com/xyz" {
+			t.Errorf("Upload() with field %q = %q, %v", field, url, err)
+		}
+	}
+
+	if _, err := NewPaste(server.URL, "").Upload(ctx, "chat.md", "fail"); err == nil {
+		t.Error("Upload() should fail on an error status")
+	}
+	if _, err := NewPaste(server.URL, "").Upload(ctx, "chat.md", "other"); err == nil || !strings.Contains(err.Error(), "URL") {
+		t.Errorf("Upload() error = %v, want a missing URL error", err)
+	}
+}
@@ -0,0 +1,74 @@
+package shell
+
+import "strings"
+
+// placeholderStart opens a !{command} placeholder
+const placeholderStart = "!{"
+
+// placeholder is a !{command} found in a text, at text[start:end]
+type placeholder struct {
+	start, end int
+	command    string
+}
+
+// placeholders finds the !{command} placeholders in text. Braces inside the
+// command must be balanced, as in !{awk '{print $1}' log}; an unclosed
+// placeholder is left as text, as are empty ones.
+func placeholders(text string) []placeholder {
+	var found []placeholder
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], placeholderStart)
+		if i < 0 {
+			return found
+		}
+		start := offset + i
+		depth := 1
+		end := -1
+		for j := start + len(placeholderStart); j < len(text) && end < 0; j++ {
+			switch text[j] {
+			case '{':
+				depth++
+			case '}':
+				if depth--; depth == 0 {
+					end = j + 1
+				}
+			}
+		}
+		if end < 0 {
+			return found
+		}
+
+		command := strings.TrimSpace(text[start+len(placeholderStart) : end-1])
+		if command != "" {
+			found = append(found, placeholder{start: start, end: end, command: command})
+		}
+		offset = end
+	}
+}
+
+// Placeholders returns the commands of the !{command} placeholders in text, in order
+func Placeholders(text string) []string {
+	var commands []string
+	for _, p := range placeholders(text) {
+		commands = append(commands, p.command)
+	}
+	return commands
+}
+
+// Interpolate replaces each !{command} placeholder in text with the output
+// of run for its command, stopping at the first error
+func Interpolate(text string, run func(command string) (string, error)) (string, error) {
+	var b strings.Builder
+	last := 0
+	for _, p := range placeholders(text) {
+		output, err := run(p.command)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(text[last:p.start])
+		b.WriteString(output)
+		last = p.end
+	}
+	b.WriteString(text[last:])
+	return b.String(), nil
+}
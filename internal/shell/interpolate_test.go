@@ -0,0 +1,51 @@
+package shell
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPlaceholders(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"none", "explain this error", nil},
+		{"one", "explain !{go build ./...}", []string{"go build ./..."}},
+		{"several", "!{git diff --stat} and !{ git log -1 }", []string{"git diff --stat", "git log -1"}},
+		{"nested braces", "!{awk '{print $1}' log}", []string{"awk '{print $1}' log"}},
+		{"unclosed", "!{git diff", nil},
+		{"empty", "!{ } hi", nil},
+		{"bang without brace", "wow! {not a command}", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Placeholders(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Placeholders(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	run := func(command string) (string, error) {
+		return strings.ToUpper(command), nil
+	}
+
+	got, err := Interpolate("a !{b} c !{awk '{d}'} e !{", run)
+	if err != nil {
+		t.Fatalf("Interpolate() error = %v", err)
+	}
+	if want := "a B c AWK '{D}' e !{"; got != want {
+		t.Errorf("Interpolate() = %q, want %q", got, want)
+	}
+
+	errRun := errors.New("failed")
+	if _, err := Interpolate("!{x}", func(string) (string, error) { return "", errRun }); !errors.Is(err, errRun) {
+		t.Errorf("Interpolate() error = %v, want %v", err, errRun)
+	}
+}
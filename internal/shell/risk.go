@@ -0,0 +1,60 @@
+package shell
+
+import (
+	"regexp"
+	"strings"
+)
+
+// risk is a pattern of a risky operation in a command line
+type risk struct {
+	pattern *regexp.Regexp
+	reason  string
+}
+
+// risks lists the risky operations that are flagged whatever the model says
+var risks = []risk{
+	{regexp.MustCompile(`\brm\s+(-\w*[rR]\w*\s+-?\w*f|-\w*f\w*\s+-?\w*[rR]|-\w*[rR]\w*f|-\w*f\w*[rR]|--recursive\b.*--force|--force\b.*--recursive)`),
+		"deletes files recursively without asking"},
+	{regexp.MustCompile(`\bdd\b.*\bof=/dev/`), "writes directly to a device"},
+	{regexp.MustCompile(`\bmkfs(\.\w+)?\b`), "formats a file system"},
+	{regexp.MustCompile(`>\s*/dev/(sd|nvme|hd|disk)`), "overwrites a disk device"},
+	{regexp.MustCompile(`\bchmod\s+(-\w+\s+)*0?777\b`), "makes files writable by everyone"},
+	{regexp.MustCompile(`\b(chmod|chown)\s+(-\w*R\w*|--recursive)\b.*\s/(\s|$)`), "changes permissions of the whole file system"},
+	{regexp.MustCompile(`\b(curl|wget)\b[^|]*\|\s*(sudo\s+)?(ba|z|da)?sh\b`), "runs a script downloaded from the internet"},
+	{regexp.MustCompile(`:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`), "is a fork bomb that exhausts the system"},
+	{regexp.MustCompile(`\bgit\s+push\b.*(\s--force\b|\s-f\b)`), "overwrites the history of a remote branch"},
+	{regexp.MustCompile(`\bgit\s+(reset\s+--hard|clean\s+-\w*f)`), "discards uncommitted changes"},
+	{regexp.MustCompile(`(?i)\b(drop\s+(table|database)|truncate\s+table)\b`), "deletes database data"},
+	{regexp.MustCompile(`\b(shutdown|reboot|halt|poweroff)\b`), "shuts down or restarts the machine"},
+	{regexp.MustCompile(`\bkill\s+-9\s+-1\b`), "kills every process of the user"},
+}
+
+// Risks returns the reasons a command line is risky to run, in the order of
+// the checks. It is a quick heuristic, not a complete analysis.
+func Risks(command string) []string {
+	var reasons []string
+	for _, r := range risks {
+		if r.pattern.MatchString(command) {
+			reasons = append(reasons, r.reason)
+		}
+	}
+	return reasons
+}
+
+// Join joins arguments into a command line, single-quoting the arguments
+// that contain spaces or shell metacharacters
+func Join(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quote single-quotes an argument for sh if it is empty or not plain
+func quote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`&;|<>(){}*?[]#~!") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,55 @@
+package shell
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRisks(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"ls -la", nil},
+		{"rm file.txt", nil},
+		{"rm -rf build", []string{"deletes files recursively without asking"}},
+		{"rm -r -f build", []string{"deletes files recursively without asking"}},
+		{"sudo rm -fR /tmp/x", []string{"deletes files recursively without asking"}},
+		{"dd if=image.iso of=/dev/sdb bs=4M", []string{"writes directly to a device"}},
+		{"mkfs.ext4 /dev/sdb1", []string{"formats a file system"}},
+		{"chmod 777 script.sh", []string{"makes files writable by everyone"}},
+		{"curl -fsSL https://example.com/install.sh | sh", []string{"runs a script downloaded from the internet"}},
+		{"curl https://example.com/data.json | jq .", nil},
+		{"git push --force origin main", []string{"overwrites the history of a remote branch"}},
+		{"git reset --hard HEAD~1 && git clean -fd", []string{"discards uncommitted changes"}},
+		{`psql -c "DROP TABLE users"`, []string{"deletes database data"}},
+		{":(){ :|:& };:", []string{"is a fork bomb that exhausts the system"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if got := Risks(tt.command); !slices.Equal(got, tt.want) {
+				t.Errorf("Risks(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"ls", "-la"}, "ls -la"},
+		{[]string{"find", ".", "-name", "*.go"}, "find . -name '*.go'"},
+		{[]string{"echo", "it's here"}, `echo 'it'\''s here'`},
+		{[]string{"sh", "-c", "a | b"}, "sh -c 'a | b'"},
+		{[]string{"printf", ""}, "printf ''"},
+	}
+
+	for _, tt := range tests {
+		if got := Join(tt.args); got != tt.want {
+			t.Errorf("Join(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// Package shell runs user-supplied command lines through the platform shell.
+package shell
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// WaitDelay bounds how long to wait for output pipes after the command exits,
+// so background processes holding them open can't block the caller forever
+const WaitDelay = time.Second
+
+// Command returns an exec.Cmd running the command line through the system shell
+// (sh -c on Unix, cmd /C on Windows)
+func Command(ctx context.Context, command string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.WaitDelay = WaitDelay
+	return cmd
+}
@@ -0,0 +1,342 @@
+// Package textdiff compares texts line by line and writes the differences as
+// a unified diff, optionally colorized with the changed words highlighted.
+package textdiff
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Op is the kind of an edit
+type Op int
+
+const (
+	// Equal keeps a token of both texts
+	Equal Op = iota
+	// Delete removes a token of the old text
+	Delete
+	// Insert adds a token of the new text
+	Insert
+)
+
+// Edit is one token of a diff
+type Edit struct {
+	Op   Op
+	Text string
+}
+
+// ANSI escape codes of colorized diffs
+const (
+	ansiReset   = "\033[0m"
+	ansiRed     = "\033[31m"
+	ansiGreen   = "\033[32m"
+	ansiCyan    = "\033[36m"
+	ansiBold    = "\033[1m"
+	ansiReverse = "\033[7m"
+)
+
+// DefaultContext is the number of unchanged lines shown around changes
+const DefaultContext = 3
+
+// Diff returns the shortest edit script turning a into b, using Myers'
+// algorithm
+func Diff(a, b []string) []Edit {
+	// Common prefixes and suffixes need no search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, s := range a[:prefix] {
+		edits = append(edits, Edit{Equal, s})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, s := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{Equal, s})
+	}
+	return edits
+}
+
+// myers finds the shortest edit script by searching the furthest reaching
+// path of each diagonal for increasing numbers of edits, then walks the
+// recorded paths back from the end
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	limit := n + m
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset, d)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack rebuilds the edit script from the paths recorded by myers
+func backtrack(a, b []string, trace [][]int, offset, d int) []Edit {
+	var edits []Edit
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, Edit{Equal, a[x]})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, Edit{Insert, b[y]})
+		} else {
+			x--
+			edits = append(edits, Edit{Delete, a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		edits = append(edits, Edit{Equal, a[x]})
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// Lines splits text into lines without their line endings
+func Lines(text string) []string {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// wordPattern matches the tokens of a word diff: words, runs of spaces and
+// single other characters
+var wordPattern = regexp.MustCompile(`\w+|\s+|[^\w\s]`)
+
+// Words splits a line into words, spaces and punctuation
+func Words(line string) []string {
+	return wordPattern.FindAllString(line, -1)
+}
+
+// hunk is a range of a line diff with the changes and their context
+type hunk struct {
+	edits            []Edit
+	oldStart, oldLen int
+	newStart, newLen int
+}
+
+// hunks groups the changes of a line diff with context unchanged lines
+// around them, merging changes closer than twice the context
+func hunks(edits []Edit, context int) []hunk {
+	var result []hunk
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(edits); {
+		if edits[i].Op == Equal {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// Start the hunk up to context lines before the change
+		start := i
+		for start > 0 && i-start < context && edits[start-1].Op == Equal {
+			start--
+		}
+		h := hunk{oldStart: oldLine - (i - start), newStart: newLine - (i - start)}
+
+		// Extend it until more than twice the context of unchanged lines follows
+		end := i
+		for end < len(edits) {
+			if edits[end].Op != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].Op == Equal {
+				run++
+			}
+			if run == len(edits) || run-end > 2*context {
+				end = min(end+context, run)
+				break
+			}
+			end = run
+		}
+
+		h.edits = edits[start:end]
+		for _, e := range h.edits {
+			if e.Op != Insert {
+				h.oldLen++
+			}
+			if e.Op != Delete {
+				h.newLen++
+			}
+		}
+		for _, e := range edits[i:end] {
+			if e.Op != Insert {
+				oldLine++
+			}
+			if e.Op != Delete {
+				newLine++
+			}
+		}
+		result = append(result, h)
+		i = end
+	}
+	return result
+}
+
+// Unified writes the differences between the old and new texts as a unified
+// diff with context unchanged lines around the changes. With color, removed
+// lines are red and added ones green, and where lines were replaced the
+// changed words are highlighted. It reports whether the texts differ.
+func Unified(w io.Writer, oldName, newName, oldText, newText string, context int, color bool) (bool, error) {
+	edits := Diff(Lines(oldText), Lines(newText))
+	changes := hunks(edits, context)
+	if len(changes) == 0 {
+		return false, nil
+	}
+
+	var b strings.Builder
+	for _, header := range []string{"--- " + oldName, "+++ " + newName} {
+		if color {
+			header = ansiBold + header + ansiReset
+		}
+		b.WriteString(header + "\n")
+	}
+
+	for _, h := range changes {
+		ranges := fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.oldStart, h.oldLen), hunkRange(h.newStart, h.newLen))
+		if color {
+			ranges = ansiCyan + ranges + ansiReset
+		}
+		b.WriteString(ranges + "\n")
+		writeEdits(&b, h.edits, color)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return true, err
+}
+
+// hunkRange formats the start and length of a hunk range, where an empty
+// range starts at the line before it
+func hunkRange(start, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, length)
+}
+
+// writeEdits writes the lines of a hunk. Runs of removed lines followed by
+// as many added ones are taken as replaced lines, whose changed words are
+// highlighted when colorized.
+func writeEdits(b *strings.Builder, edits []Edit, color bool) {
+	for i := 0; i < len(edits); {
+		if edits[i].Op == Equal {
+			b.WriteString(" " + edits[i].Text + "\n")
+			i++
+			continue
+		}
+
+		deleted := i
+		for deleted < len(edits) && edits[deleted].Op == Delete {
+			deleted++
+		}
+		inserted := deleted
+		for inserted < len(edits) && edits[inserted].Op == Insert {
+			inserted++
+		}
+		removed, added := edits[i:deleted], edits[deleted:inserted]
+		i = inserted
+
+		if !color {
+			for _, e := range removed {
+				b.WriteString("-" + e.Text + "\n")
+			}
+			for _, e := range added {
+				b.WriteString("+" + e.Text + "\n")
+			}
+			continue
+		}
+
+		if len(removed) == len(added) {
+			oldLines := make([]string, len(removed))
+			newLines := make([]string, len(added))
+			for j := range removed {
+				oldLines[j], newLines[j] = highlightWords(removed[j].Text, added[j].Text)
+			}
+			for _, line := range oldLines {
+				b.WriteString(ansiRed + "-" + line + ansiReset + "\n")
+			}
+			for _, line := range newLines {
+				b.WriteString(ansiGreen + "+" + line + ansiReset + "\n")
+			}
+			continue
+		}
+		for _, e := range removed {
+			b.WriteString(ansiRed + "-" + e.Text + ansiReset + "\n")
+		}
+		for _, e := range added {
+			b.WriteString(ansiGreen + "+" + e.Text + ansiReset + "\n")
+		}
+	}
+}
+
+// highlightWords diffs a replaced line word by word and returns both lines
+// with the changed words in reverse video. The lines keep their red and
+// green, which the highlights restore after each changed word.
+func highlightWords(oldLine, newLine string) (string, string) {
+	var oldOut, newOut strings.Builder
+	for _, e := range Diff(Words(oldLine), Words(newLine)) {
+		switch e.Op {
+		case Equal:
+			oldOut.WriteString(e.Text)
+			newOut.WriteString(e.Text)
+		case Delete:
+			oldOut.WriteString(ansiReverse + e.Text + ansiReset + ansiRed)
+		case Insert:
+			newOut.WriteString(ansiReverse + e.Text + ansiReset + ansiGreen)
+		}
+	}
+	return oldOut.String(), newOut.String()
+}
@@ -0,0 +1,148 @@
+package textdiff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// apply rebuilds both texts from an edit script
+func apply(edits []Edit) (oldTokens, newTokens []string) {
+	for _, e := range edits {
+		if e.Op != Insert {
+			oldTokens = append(oldTokens, e.Text)
+		}
+		if e.Op != Delete {
+			newTokens = append(newTokens, e.Text)
+		}
+	}
+	return oldTokens, newTokens
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		edits int
+	}{
+		{"equal", "a b c", "a b c", 0},
+		{"both empty", "", "", 0},
+		{"insert into empty", "", "a b", 2},
+		{"delete all", "a b", "", 2},
+		{"replace middle", "a b c", "a x c", 2},
+		{"classic", "a b c a b b a", "c b a b a c", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+			edits := Diff(a, b)
+			oldTokens, newTokens := apply(edits)
+			if !slices.Equal(oldTokens, a) || !slices.Equal(newTokens, b) {
+				t.Fatalf("Diff() = %+v does not turn %q into %q", edits, a, b)
+			}
+			changes := 0
+			for _, e := range edits {
+				if e.Op != Equal {
+					changes++
+				}
+			}
+			if changes != tt.edits {
+				t.Errorf("Diff() made %d changes, want %d", changes, tt.edits)
+			}
+		})
+	}
+}
+
+func TestLines(t *testing.T) {
+	if got := Lines("one\r\ntwo\n"); !slices.Equal(got, []string{"one", "two"}) {
+		t.Errorf("Lines() = %q", got)
+	}
+	if got := Lines(""); got != nil {
+		t.Errorf("Lines() of empty text = %q, want none", got)
+	}
+}
+
+func TestWords(t *testing.T) {
+	if got := Words("Its a dog's  life."); !slices.Equal(got, []string{"Its", " ", "a", " ", "dog", "'", "s", "  ", "life", "."}) {
+		t.Errorf("Words() = %q", got)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	oldText := strings.Join(lines, "\n") + "\n"
+	lines[1] = "line two"
+	lines[17] = "line eighteen"
+	newText := strings.Join(lines, "\n") + "\n"
+
+	var b strings.Builder
+	changed, err := Unified(&b, "a.md", "b.md", oldText, newText, DefaultContext, false)
+	if err != nil || !changed {
+		t.Fatalf("Unified() = %v, %v", changed, err)
+	}
+	want := `--- a.md
++++ b.md
+@@ -1,5 +1,5 @@
+ line 1
+-line 2
++line two
+ line 3
+ line 4
+ line 5
+@@ -15,6 +15,6 @@
+ line 15
+ line 16
+ line 17
+-line 18
++line eighteen
+ line 19
+ line 20
+`
+	if b.String() != want {
+		t.Errorf("Unified() =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	if changed, _ := Unified(&b, "a", "b", oldText, oldText, DefaultContext, false); changed || b.Len() != 0 {
+		t.Errorf("Unified() of equal texts = %v, %q, want no diff", changed, b.String())
+	}
+}
+
+func TestUnifiedMergesCloseChanges(t *testing.T) {
+	var b strings.Builder
+	Unified(&b, "a", "b", "1\n2\n3\n4\n5\n6\n7\n8\n", "1\nx\n3\n4\n5\n6\ny\n8\n", 2, false)
+	if got := strings.Count(b.String(), "@@ -"); got != 1 {
+		t.Errorf("Unified() wrote %d hunks, want the changes merged into one:\n%s", got, b.String())
+	}
+	if !strings.Contains(b.String(), "@@ -1,8 +1,8 @@") {
+		t.Errorf("Unified() =\n%s\nwant one hunk over all lines", b.String())
+	}
+}
+
+func TestUnifiedInsertAtStart(t *testing.T) {
+	var b strings.Builder
+	Unified(&b, "a", "b", "", "new\n", DefaultContext, false)
+	if want := "--- a\n+++ b\n@@ -0,0 +1 @@\n+new\n"; b.String() != want {
+		t.Errorf("Unified() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestUnifiedColor(t *testing.T) {
+	var b strings.Builder
+	Unified(&b, "a", "b", "Its a nice day.\n", "It's a nice day.\n", DefaultContext, true)
+	out := b.String()
+	for _, want := range []string{
+		ansiBold + "--- a" + ansiReset,
+		ansiCyan + "@@ -1 +1 @@" + ansiReset,
+		ansiRed + "-" + ansiReverse + "Its" + ansiReset + ansiRed + " a nice day." + ansiReset,
+		ansiGreen + "+" + ansiReverse + "It" + ansiReset + ansiGreen + ansiReverse + "'" + ansiReset + ansiGreen + ansiReverse + "s" + ansiReset + ansiGreen,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Unified() = %q, want it to contain %q", out, want)
+		}
+	}
+}
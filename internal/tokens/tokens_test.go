@@ -0,0 +1,52 @@
+package tokens
+
+import "testing"
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"short words", "What is Go?", 4},
+		{"long word", "internationalization", 5},
+		{"number", "1234567", 3},
+		{"punctuation", "a, b.", 4},
+		{"whitespace only", " \n\t ", 0},
+		{"CJK", "你好世界", 4},
+		{"accented", "café", 2},
+		{"code", "fmt.Println(x)", 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Estimate(tt.text); got != tt.want {
+				t.Errorf("Estimate(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateProse(t *testing.T) {
+	// About 50 tokens with common BPE tokenizers
+	text := "The quick brown fox jumps over the lazy dog. Perplexity answers questions " +
+		"with up-to-date information from the web, and cites the sources it used, so " +
+		"that readers can check every claim for themselves."
+
+	got := Estimate(text)
+	if got < 40 || got > 60 {
+		t.Errorf("Estimate() = %d, want about 50", got)
+	}
+}
+
+func TestEstimateMessages(t *testing.T) {
+	got := EstimateMessages([]string{"Be precise.", "What is Go?"})
+	want := PerRequest + 2*PerMessage + Estimate("Be precise.") + Estimate("What is Go?")
+	if got != want {
+		t.Errorf("EstimateMessages() = %d, want %d", got, want)
+	}
+	if EstimateMessages(nil) != PerRequest {
+		t.Errorf("EstimateMessages(nil) = %d, want %d", EstimateMessages(nil), PerRequest)
+	}
+}
@@ -0,0 +1,160 @@
+// Package tools implements ReAct-style tool calling on top of plain chat completions.
+// Tools are external shell commands declared in the config file; the assistant requests
+// one by replying with an Action/Action Input pair and receives the command output back
+// as an Observation message.
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+	"github.com/quocvuong92/perplexity-cli/internal/shell"
+)
+
+const (
+	// MaxIterations limits the number of tool calls per user message
+	MaxIterations = 5
+	// MaxOutputBytes limits the amount of tool output fed back to the model
+	MaxOutputBytes = 16 * 1024
+	// DefaultTimeout is the default execution timeout for a tool command
+	DefaultTimeout = 30 * time.Second
+	// EnvToolInput is the environment variable holding the tool input
+	EnvToolInput = "PERPLEXITY_TOOL_INPUT"
+)
+
+const (
+	actionPrefix      = "Action:"
+	actionInputPrefix = "Action Input:"
+	observationPrefix = "Observation:"
+)
+
+// Call represents a tool invocation requested by the assistant
+type Call struct {
+	Name  string
+	Input string
+}
+
+// SystemPrompt returns the instructions describing the available tools.
+// It is appended to the system message when tools are configured.
+func SystemPrompt(tools []config.ToolConfig) string {
+	if len(tools) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("You can use the following tools:\n\n")
+	for _, tool := range tools {
+		fmt.Fprintf(&b, "- %s: %s\n", tool.Name, tool.Description)
+	}
+	b.WriteString("\nTo use a tool, reply with exactly these two lines and nothing after them:\n")
+	b.WriteString(actionPrefix + " <tool name>\n")
+	b.WriteString(actionInputPrefix + " <input for the tool>\n\n")
+	b.WriteString("The tool output will be sent back to you in a message starting with \"" + observationPrefix + "\". ")
+	b.WriteString("When you no longer need a tool, answer the user normally without an " + actionPrefix + " line.")
+	return b.String()
+}
+
+// ParseCall extracts the first tool call from an assistant response.
+// Returns false if the response does not request a tool.
+func ParseCall(content string) (*Call, bool) {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, actionPrefix) {
+			continue
+		}
+		name := strings.TrimSpace(strings.TrimPrefix(trimmed, actionPrefix))
+		if name == "" {
+			continue
+		}
+
+		// Collect the input, which may span several lines, up to a
+		// hallucinated observation or the end of the response
+		var input []string
+		found := false
+		for _, next := range lines[i+1:] {
+			nextTrimmed := strings.TrimSpace(next)
+			if !found {
+				if strings.HasPrefix(nextTrimmed, actionInputPrefix) {
+					found = true
+					input = append(input, strings.TrimSpace(strings.TrimPrefix(nextTrimmed, actionInputPrefix)))
+				}
+				continue
+			}
+			if strings.HasPrefix(nextTrimmed, observationPrefix) {
+				break
+			}
+			input = append(input, next)
+		}
+
+		return &Call{
+			Name:  strings.Trim(name, "`*"),
+			Input: strings.TrimSpace(strings.Join(input, "\n")),
+		}, true
+	}
+	return nil, false
+}
+
+// Find returns the tool with the given name
+func Find(tools []config.ToolConfig, name string) (config.ToolConfig, bool) {
+	for _, tool := range tools {
+		if strings.EqualFold(tool.Name, name) {
+			return tool, true
+		}
+	}
+	return config.ToolConfig{}, false
+}
+
+// Run executes the tool command through the system shell.
+// The input is provided on stdin and in the PERPLEXITY_TOOL_INPUT environment variable
+// rather than interpolated into the command line, so model output is never parsed by the shell.
+func Run(ctx context.Context, tool config.ToolConfig, input string) (string, error) {
+	timeout := DefaultTimeout
+	if tool.Timeout > 0 {
+		timeout = time.Duration(tool.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := shell.Command(ctx, tool.Command)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Env = append(os.Environ(), EnvToolInput+"="+input)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := cmd.Run()
+	output := out.String()
+	if len(output) > MaxOutputBytes {
+		output = output[:MaxOutputBytes] + "\n[output truncated]"
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return output, fmt.Errorf("tool %s timed out after %v", tool.Name, timeout)
+	}
+	if err != nil {
+		return output, fmt.Errorf("tool %s failed: %w", tool.Name, err)
+	}
+	return output, nil
+}
+
+// FormatObservation builds the message sent back to the assistant after a tool call
+func FormatObservation(name, output string, err error) string {
+	var b strings.Builder
+	b.WriteString(observationPrefix + " ")
+	if err != nil {
+		fmt.Fprintf(&b, "%v\n", err)
+	}
+	output = strings.TrimSpace(output)
+	if output == "" {
+		output = "(no output)"
+	}
+	fmt.Fprintf(&b, "Output of %s:\n%s", name, output)
+	return b.String()
+}
@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/quocvuong92/perplexity-cli/internal/config"
+)
+
+func TestSystemPrompt(t *testing.T) {
+	if got := SystemPrompt(nil); got != "" {
+		t.Errorf("SystemPrompt(nil) = %q, want empty", got)
+	}
+
+	prompt := SystemPrompt([]config.ToolConfig{
+		{Name: "weather", Description: "Get the weather for a city", Command: "echo sunny"},
+	})
+	if !strings.Contains(prompt, "weather: Get the weather for a city") {
+		t.Error("SystemPrompt should list tool name and description")
+	}
+	if !strings.Contains(prompt, actionPrefix) || !strings.Contains(prompt, actionInputPrefix) {
+		t.Error("SystemPrompt should describe the action format")
+	}
+}
+
+func TestParseCall(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantOK    bool
+		wantName  string
+		wantInput string
+	}{
+		{
+			name:    "no tool call",
+			content: "Paris is the capital of France.",
+			wantOK:  false,
+		},
+		{
+			name:      "simple call",
+			content:   "Let me check.\nAction: weather\nAction Input: Paris",
+			wantOK:    true,
+			wantName:  "weather",
+			wantInput: "Paris",
+		},
+		{
+			name:      "multiline input",
+			content:   "Action: grep\nAction Input: line one\nline two",
+			wantOK:    true,
+			wantName:  "grep",
+			wantInput: "line one\nline two",
+		},
+		{
+			name:      "stops at hallucinated observation",
+			content:   "Action: weather\nAction Input: Paris\nObservation: sunny",
+			wantOK:    true,
+			wantName:  "weather",
+			wantInput: "Paris",
+		},
+		{
+			name:      "markdown formatted name",
+			content:   "Action: `weather`\nAction Input: Berlin",
+			wantOK:    true,
+			wantName:  "weather",
+			wantInput: "Berlin",
+		},
+		{
+			name:      "missing input",
+			content:   "Action: date",
+			wantOK:    true,
+			wantName:  "date",
+			wantInput: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, ok := ParseCall(tt.content)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseCall() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if call.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", call.Name, tt.wantName)
+			}
+			if call.Input != tt.wantInput {
+				t.Errorf("Input = %q, want %q", call.Input, tt.wantInput)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	tools := []config.ToolConfig{
+		{Name: "weather", Command: "echo sunny"},
+	}
+
+	if _, ok := Find(tools, "Weather"); !ok {
+		t.Error("Find should match tool names case-insensitively")
+	}
+	if _, ok := Find(tools, "missing"); ok {
+		t.Error("Find should not match unknown tools")
+	}
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands differ on Windows")
+	}
+
+	t.Run("input on stdin and env", func(t *testing.T) {
+		tool := config.ToolConfig{Name: "echo", Command: `cat; echo "$PERPLEXITY_TOOL_INPUT"`}
+		output, err := Run(context.Background(), tool, "hello")
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if strings.Count(output, "hello") != 2 {
+			t.Errorf("Run() output = %q, want input twice", output)
+		}
+	})
+
+	t.Run("input is not interpreted by the shell", func(t *testing.T) {
+		tool := config.ToolConfig{Name: "echo", Command: `echo "$PERPLEXITY_TOOL_INPUT"`}
+		output, err := Run(context.Background(), tool, "$(echo injected)")
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if !strings.Contains(output, "$(echo injected)") {
+			t.Errorf("Run() output = %q, want literal input", output)
+		}
+	})
+
+	t.Run("failing command", func(t *testing.T) {
+		tool := config.ToolConfig{Name: "fail", Command: "echo oops; exit 3"}
+		output, err := Run(context.Background(), tool, "")
+		if err == nil {
+			t.Error("Run() should return error for failing command")
+		}
+		if !strings.Contains(output, "oops") {
+			t.Errorf("Run() should return output of failing command, got %q", output)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		tool := config.ToolConfig{Name: "slow", Command: "sleep 5", Timeout: 1}
+		_, err := Run(context.Background(), tool, "")
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("Run() error = %v, want timeout", err)
+		}
+	})
+}
+
+func TestFormatObservation(t *testing.T) {
+	obs := FormatObservation("weather", "sunny\n", nil)
+	if !strings.HasPrefix(obs, observationPrefix) {
+		t.Errorf("FormatObservation() should start with %q, got %q", observationPrefix, obs)
+	}
+	if !strings.Contains(obs, "sunny") {
+		t.Error("FormatObservation() should include output")
+	}
+
+	obs = FormatObservation("weather", "", context.DeadlineExceeded)
+	if !strings.Contains(obs, "(no output)") || !strings.Contains(obs, "deadline exceeded") {
+		t.Errorf("FormatObservation() = %q, want error and placeholder", obs)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 	"unicode/utf8"
+
+	"github.com/quocvuong92/perplexity-cli/internal/tokens"
 )
 
 // Validation errors
@@ -14,8 +16,22 @@ var (
 	ErrInvalidAPIKey      = errors.New("invalid API key format")
 	ErrAPIKeyTooShort     = errors.New("API key is too short")
 	ErrAPIKeyInvalidChars = errors.New("API key contains invalid characters")
+	ErrContextTooLong     = errors.New("request likely exceeds the model's context window")
 )
 
+// ContextWindows lists the context window, in tokens, of the built-in models
+var ContextWindows = map[string]int{
+	"sonar":               128000,
+	"sonar-pro":           200000,
+	"sonar-reasoning":     128000,
+	"sonar-reasoning-pro": 128000,
+	"sonar-deep-research": 128000,
+}
+
+// ContextWarnRatio is the share of the context window above which a request
+// gets a warning, as the estimate may be off and the answer needs room too
+const ContextWarnRatio = 0.9
+
 // Limits for validation
 const (
 	// MaxPromptLength is the maximum allowed prompt length in characters.
@@ -70,6 +86,46 @@ func ValidatePrompt(prompt string) PromptResult {
 	}
 }
 
+// ContextResult contains the result of context size validation
+type ContextResult struct {
+	Valid   bool
+	Error   error
+	Warning string // Non-fatal warning message
+	Tokens  int    // Estimated prompt tokens
+	Window  int    // Context window of the model (0 = unknown)
+}
+
+// ValidateContext estimates the prompt tokens of a request with the given
+// message contents and checks they fit the model's context window.
+// Models with an unknown context window are not checked.
+func ValidateContext(model string, contents []string) ContextResult {
+	count := tokens.EstimateMessages(contents)
+	window, ok := ContextWindows[model]
+	if !ok {
+		return ContextResult{Valid: true, Tokens: count}
+	}
+
+	if count > window {
+		return ContextResult{
+			Valid:  false,
+			Error:  fmt.Errorf("%w: about %d tokens, %s accepts %d. Shorten the prompt or start a new conversation", ErrContextTooLong, count, model, window),
+			Tokens: count,
+			Window: window,
+		}
+	}
+
+	var warning string
+	if float64(count) > ContextWarnRatio*float64(window) {
+		warning = fmt.Sprintf("request is about %d tokens, close to the %d token context window of %s; the answer may be cut short", count, window, model)
+	}
+	return ContextResult{
+		Valid:   true,
+		Warning: warning,
+		Tokens:  count,
+		Window:  window,
+	}
+}
+
 // APIKeyResult contains the result of API key validation
 type APIKeyResult struct {
 	Valid   bool
@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -83,6 +84,42 @@ func TestValidatePromptCleaned(t *testing.T) {
 	}
 }
 
+func TestValidateContext(t *testing.T) {
+	// Each "word " is one token
+	prompt := func(tokens int) string {
+		return strings.Repeat("word ", tokens)
+	}
+
+	tests := []struct {
+		name        string
+		model       string
+		prompt      string
+		wantValid   bool
+		wantWarning bool
+	}{
+		{"small", "sonar", prompt(100), true, false},
+		{"near the window", "sonar", prompt(120000), true, true},
+		{"over the window", "sonar", prompt(130000), false, false},
+		{"larger window", "sonar-pro", prompt(130000), true, false},
+		{"unknown model", "sonar-ultra", prompt(300000), true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ValidateContext(tt.model, []string{"Be precise.", tt.prompt})
+			if result.Valid != tt.wantValid {
+				t.Errorf("ValidateContext() valid = %v, want %v (tokens %d)", result.Valid, tt.wantValid, result.Tokens)
+			}
+			if !tt.wantValid && !errors.Is(result.Error, ErrContextTooLong) {
+				t.Errorf("ValidateContext() error = %v, want ErrContextTooLong", result.Error)
+			}
+			if (result.Warning != "") != tt.wantWarning {
+				t.Errorf("ValidateContext() warning = %q, want warning %v", result.Warning, tt.wantWarning)
+			}
+		})
+	}
+}
+
 func TestValidateAPIKey(t *testing.T) {
 	tests := []struct {
 		name        string
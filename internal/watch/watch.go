@@ -0,0 +1,53 @@
+// Package watch decides whether the answer to a repeated query has changed.
+package watch
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MinInterval is the shortest interval a query can be re-run at
+const MinInterval = 10 * time.Second
+
+// DefaultThreshold is the word similarity below which two answers differ materially
+const DefaultThreshold = 0.8
+
+// citationMarker matches inline [n] citation markers, which shift between runs
+var citationMarker = regexp.MustCompile(`\[\d+\]`)
+
+// Similarity returns the Jaccard similarity (0-1) of the words in two answers,
+// ignoring case, punctuation and citation markers
+func Similarity(a, b string) float64 {
+	wordsA, wordsB := words(a), words(b)
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 1
+	}
+
+	shared := 0
+	for w := range wordsA {
+		if wordsB[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
+
+// Changed reports whether next differs materially from previous
+func Changed(previous, next string) bool {
+	return Similarity(previous, next) < DefaultThreshold
+}
+
+// words returns the set of normalised words in text
+func words(text string) map[string]bool {
+	text = citationMarker.ReplaceAllString(strings.ToLower(text), " ")
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	set := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		set[f] = true
+	}
+	return set
+}
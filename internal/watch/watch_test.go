@@ -0,0 +1,39 @@
+package watch
+
+import (
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{"identical", "Go 1.24 was released.", "Go 1.24 was released.", 1},
+		{"both empty", "", "", 1},
+		{"one empty", "Go", "", 0},
+		{"case, punctuation and markers", "Go 1.24 was released[1].", "go 1.24 was RELEASED [2]", 1},
+		{"disjoint", "alpha beta", "gamma delta", 0},
+		{"half", "a b c", "a b d", 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Similarity(tt.a, tt.b); got != tt.want {
+				t.Errorf("Similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChanged(t *testing.T) {
+	previous := "The latest Go release is 1.24, published in February with iterator improvements."
+
+	if Changed(previous, "The latest Go release is 1.24, published in February with iterator improvements [3].") {
+		t.Error("Changed() should ignore citation marker differences")
+	}
+	if !Changed(previous, "Go 1.25 is now out, adding a new garbage collector and JSON v2.") {
+		t.Error("Changed() should detect a different answer")
+	}
+}
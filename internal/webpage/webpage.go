@@ -0,0 +1,253 @@
+// Package webpage fetches web pages and extracts their readable text,
+// leaving out scripts, navigation, sidebars and other page furniture.
+package webpage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// MaxBodySize limits the bytes of a page that are read
+const MaxBodySize = 5 << 20
+
+// userAgent identifies the requests for pages
+const userAgent = "perplexity-cli (+https://github.com/quocvuong92/perplexity-cli)"
+
+// ErrUnsupportedContent is returned for pages that are neither HTML nor plain text
+var ErrUnsupportedContent = errors.New("page is not HTML or plain text")
+
+// Page is the readable content of a web page
+type Page struct {
+	URL   string // URL the page was fetched from, after redirects
+	Title string
+	Text  string
+}
+
+// Fetch downloads an http or https URL and extracts the readable text of the page
+func Fetch(ctx context.Context, client *http.Client, rawURL string) (*Page, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q: only http and https links are supported", rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to fetch page: %s", resp.Status)
+	}
+
+	page := &Page{URL: resp.Request.URL.String()}
+	body := io.LimitReader(resp.Body, MaxBodySize)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/html", "application/xhtml+xml", "":
+		page.Title, page.Text, err = Extract(body)
+		if err != nil {
+			return nil, err
+		}
+	case "text/plain", "text/markdown":
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page: %w", err)
+		}
+		page.Text = strings.TrimSpace(string(data))
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContent, mediaType)
+	}
+	return page, nil
+}
+
+// skippedElements never hold readable content
+var skippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Form: true, atom.Button: true, atom.Svg: true, atom.Iframe: true,
+	atom.Select: true, atom.Dialog: true,
+}
+
+// boilerplate matches the classes and IDs of page furniture
+var boilerplate = regexp.MustCompile(`(?i)\b(nav|navbar|menu|sidebar|footer|cookie|banner|breadcrumbs?|comments?|advert|ads|share|social|related|newsletter|popup|modal)\b`)
+
+// blockElements start a new line in the extracted text
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Table: true, atom.Tr: true, atom.Pre: true, atom.Blockquote: true, atom.Figure: true,
+	atom.Figcaption: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.H5: true, atom.H6: true, atom.Hr: true,
+}
+
+// headingLevels maps heading elements to their markdown level
+var headingLevels = map[atom.Atom]int{
+	atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+}
+
+// minMainShare is the share of the page's text the longest article or main
+// element needs to be taken as the content instead of the whole body
+const minMainShare = 0.5
+
+// Extract returns the title and readable text of an HTML page. The text of
+// the main article is preferred over the whole body, and headings and list
+// items are kept as markdown.
+func Extract(r io.Reader) (title, text string, err error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	var body *html.Node
+	var mains []*html.Node
+	for n := range doc.Descendants() {
+		switch n.DataAtom {
+		case atom.Title:
+			if title == "" {
+				title = strings.Join(strings.Fields(nodeText(n)), " ")
+			}
+		case atom.Body:
+			body = n
+		case atom.Article, atom.Main:
+			mains = append(mains, n)
+		default:
+			if n.Type == html.ElementNode && attr(n, "role") == "main" {
+				mains = append(mains, n)
+			}
+		}
+	}
+	if body == nil {
+		return title, "", nil
+	}
+
+	text = render(body)
+	var best string
+	for _, n := range mains {
+		if t := render(n); len(t) > len(best) {
+			best = t
+		}
+	}
+	if float64(len(best)) >= minMainShare*float64(len(text)) {
+		text = best
+	}
+	return title, text, nil
+}
+
+// attr returns the value of an attribute of n
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// nodeText returns the raw text inside n
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	for d := range n.Descendants() {
+		if d.Type == html.TextNode {
+			b.WriteString(d.Data)
+		}
+	}
+	return b.String()
+}
+
+// isSkipped reports whether n holds no readable content
+func isSkipped(n *html.Node) bool {
+	if n.Type == html.CommentNode {
+		return true
+	}
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if skippedElements[n.DataAtom] || attr(n, "hidden") != "" || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	// Only containers are judged by their class, as inline elements such as
+	// a "share" link inside a paragraph are still part of the text
+	return (n.DataAtom == atom.Div || n.DataAtom == atom.Section || n.DataAtom == atom.Ul) &&
+		boilerplate.MatchString(attr(n, "class")+" "+attr(n, "id"))
+}
+
+// render returns the readable text of n, one block per line
+func render(n *html.Node) string {
+	var b strings.Builder
+	inPre := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if isSkipped(n) {
+			return
+		}
+		switch {
+		case n.Type == html.TextNode && inPre > 0:
+			b.WriteString(n.Data)
+			return
+		case n.Type == html.TextNode:
+			b.WriteString(strings.ReplaceAll(n.Data, "\n", " "))
+			return
+		case n.DataAtom == atom.Br:
+			b.WriteString("\n")
+			return
+		}
+
+		block := blockElements[n.DataAtom]
+		if block {
+			b.WriteString("\n\n")
+		}
+		if level := headingLevels[n.DataAtom]; level > 0 {
+			b.WriteString(strings.Repeat("#", level) + " ")
+		} else if n.DataAtom == atom.Li {
+			b.WriteString("- ")
+		}
+		if n.DataAtom == atom.Pre {
+			inPre++
+			defer func() { inPre-- }()
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			b.WriteString("\n\n")
+		}
+	}
+	walk(n)
+	return normalize(b.String())
+}
+
+// normalize collapses the whitespace within lines and the blank lines between them
+func normalize(text string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" || line == "-" || strings.Trim(line, "#") == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
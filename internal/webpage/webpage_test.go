@@ -0,0 +1,103 @@
+package webpage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html><head><title> Go 1.24
+ Released </title><style>body { color: red }</style></head>
+<body>
+<header><a href="/">Home</a></header>
+<nav><ul><li>Blog</li><li>Docs</li></ul></nav>
+<div class="cookie-banner">We use cookies.</div>
+<article>
+  <h1>Go 1.24 is released</h1>
+  <p>The Go team is happy to   announce
+  Go 1.24.</p>
+  <h2>Highlights</h2>
+  <ul><li>Generic type aliases</li><li>Faster maps</li></ul>
+  <script>track()</script>
+</article>
+<aside>Related posts</aside>
+<footer>Copyright</footer>
+</body></html>`
+
+func TestExtract(t *testing.T) {
+	title, text, err := Extract(strings.NewReader(articlePage))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if title != "Go 1.24 Released" {
+		t.Errorf("title = %q", title)
+	}
+
+	want := "# Go 1.24 is released\n\nThe Go team is happy to announce Go 1.24.\n\n## Highlights\n\n- Generic type aliases\n\n- Faster maps"
+	if text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+}
+
+func TestExtractWithoutArticle(t *testing.T) {
+	page := `<html><body><nav>Menu</nav><div id="content"><p>First paragraph.</p><p>Second<br>line.</p></div>
+<div class="sidebar">Ads</div><article><p>Tiny teaser.</p></article></body></html>`
+	_, text, err := Extract(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	want := "First paragraph.\n\nSecond\nline.\n\nTiny teaser."
+	if text != want {
+		t.Errorf("text = %q, want %q (a short article should not replace the body)", text, want)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(articlePage))
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("  plain notes\n"))
+		case "/old":
+			http.Redirect(w, r, "/post", http.StatusMovedPermanently)
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	page, err := Fetch(ctx, server.Client(), server.URL+"/old")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if page.URL != server.URL+"/post" || page.Title != "Go 1.24 Released" || !strings.Contains(page.Text, "Faster maps") {
+		t.Errorf("Fetch() = %+v, want the redirected article", page)
+	}
+
+	page, err = Fetch(ctx, server.Client(), server.URL+"/notes.txt")
+	if err != nil || page.Text != "plain notes" {
+		t.Errorf("Fetch() of plain text = %+v, %v", page, err)
+	}
+
+	if _, err := Fetch(ctx, server.Client(), server.URL+"/image.png"); !errors.Is(err, ErrUnsupportedContent) {
+		t.Errorf("Fetch() of an image error = %v, want ErrUnsupportedContent", err)
+	}
+	if _, err := Fetch(ctx, server.Client(), server.URL+"/missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Fetch() of a missing page error = %v, want the status", err)
+	}
+	for _, rawURL := range []string{"file:///etc/passwd", "example.com/page", "https://"} {
+		if _, err := Fetch(ctx, server.Client(), rawURL); err == nil || !strings.Contains(err.Error(), "invalid URL") {
+			t.Errorf("Fetch(%q) error = %v, want invalid URL", rawURL, err)
+		}
+	}
+}